@@ -0,0 +1,296 @@
+// Package backfill walks a range of historical ledgers through the same
+// processing path as live ingestion, with per-ledger retry.
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// Policy says what Run does when a single ledger still fails after
+// exhausting its retries.
+type Policy int
+
+const (
+	// StopOnFailure aborts the whole run on the first unrecoverable
+	// ledger.
+	StopOnFailure Policy = iota
+	// SkipOnFailure records the ledger as failed and continues with the
+	// next one.
+	SkipOnFailure
+)
+
+// ParsePolicy maps the -backfill-on-failure flag values to a Policy.
+func ParsePolicy(s string) (Policy, error) {
+	switch s {
+	case "stop":
+		return StopOnFailure, nil
+	case "skip":
+		return SkipOnFailure, nil
+	}
+	return 0, fmt.Errorf("backfill: unknown failure policy %q (want stop or skip)", s)
+}
+
+// Config tunes the per-ledger retry loop.
+type Config struct {
+	// MaxRetries is how many times a failed ledger is retried before
+	// giving up on it.
+	MaxRetries int
+	// RetryBase is the delay before the first retry; each further retry
+	// doubles it.
+	RetryBase time.Duration
+	// MaxBackoff caps the exponential delay.
+	MaxBackoff time.Duration
+	// OnFailure picks between aborting and skipping once retries are
+	// exhausted.
+	OnFailure Policy
+
+	// MaxRate caps processing at this many ledgers per second; zero
+	// leaves the loop unthrottled. When live ingestion shares the
+	// database, an unthrottled backfill can push live ledger processing
+	// from tens of milliseconds to seconds.
+	MaxRate float64
+	// LiveP95 reports the live path's recent 95th-percentile processing
+	// duration, zero when live is idle; nil disables adaptation. While
+	// it exceeds LiveP95Threshold the rate halves per ledger, down to a
+	// floor; once healthy again it ramps back toward MaxRate.
+	LiveP95          func() time.Duration
+	LiveP95Threshold time.Duration
+}
+
+func (c *Config) setDefaults() {
+	if c.MaxRetries <= 0 {
+		c.MaxRetries = 3
+	}
+	if c.RetryBase <= 0 {
+		c.RetryBase = time.Second
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 30 * time.Second
+	}
+}
+
+// delay computes the sleep before retry attempt n (0-based): exponential in
+// n, capped at MaxBackoff, with uniform jitter in [d/2, d) so that parallel
+// workers hitting the same fault don't retry in lockstep.
+func (c Config) delay(retry int, rng *rand.Rand) time.Duration {
+	d := c.RetryBase
+	for i := 0; i < retry && d < c.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > c.MaxBackoff {
+		d = c.MaxBackoff
+	}
+	half := d / 2
+	return half + time.Duration(rng.Int63n(int64(half)+1))
+}
+
+// FetchFunc fetches one validated ledger with its transactions.
+type FetchFunc func(ctx context.Context, index uint64) (*xrpl.Ledger, error)
+
+// ProcessFunc runs one fetched ledger through the indexing pipeline.
+type ProcessFunc func(*xrpl.Ledger) error
+
+// Runner drives a backfill over a ledger range.
+type Runner struct {
+	cfg     Config
+	fetch   FetchFunc
+	process ProcessFunc
+	rng     *rand.Rand
+	sleep   func(time.Duration) // test seam; nil means context-aware sleep
+	logf    func(format string, args ...any)
+	record  func(index uint64, err error)
+
+	from, to  uint64
+	processed int
+	failed    []uint64
+
+	// rate is the current ledgers-per-second budget, meaningful only
+	// when cfg.MaxRate is set; throttled means live pressure has pushed
+	// it below the ceiling.
+	rate      float64
+	throttled bool
+	onRate    func(rate float64, throttled bool)
+}
+
+// New returns a Runner with the given configuration and pipeline hooks.
+func New(cfg Config, fetch FetchFunc, process ProcessFunc) *Runner {
+	cfg.setDefaults()
+	return &Runner{
+		cfg:     cfg,
+		fetch:   fetch,
+		process: process,
+		rng:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		logf:    log.Printf,
+	}
+}
+
+// OnFailedLedger installs a hook called for every ledger given up on under
+// SkipOnFailure — typically store.RecordFailedLedger, so a later run can
+// target exactly the ledgers this one could not complete.
+func (r *Runner) OnFailedLedger(fn func(index uint64, err error)) { r.record = fn }
+
+// OnRate installs a hook called after every paced ledger with the
+// current rate budget and whether live pressure is holding it below the
+// ceiling — typically a pair of gauges.
+func (r *Runner) OnRate(fn func(rate float64, throttled bool)) { r.onRate = fn }
+
+// Run processes ledgers from..to inclusive, stopping early when ctx is
+// cancelled. With StopOnFailure it returns the first unrecoverable error;
+// with SkipOnFailure it records failed ledgers and keeps going, returning
+// nil so one bad ledger cannot waste the rest of the catch-up.
+func (r *Runner) Run(ctx context.Context, from, to uint64) error {
+	r.from, r.to = from, to
+	r.rate = r.cfg.MaxRate
+	for index := from; index <= to; index++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := r.one(ctx, index)
+		if err == nil {
+			r.processed++
+			if err := r.pace(ctx); err != nil {
+				return err
+			}
+			continue
+		}
+		if r.cfg.OnFailure == StopOnFailure {
+			return fmt.Errorf("backfill: ledger %d failed after %d retries: %w", index, r.cfg.MaxRetries, err)
+		}
+		r.logf("backfill: skipping ledger %d after %d retries: %v", index, r.cfg.MaxRetries, err)
+		r.failed = append(r.failed, index)
+		if r.record != nil {
+			r.record(index, err)
+		}
+	}
+	return nil
+}
+
+// Failed returns the ledgers skipped under SkipOnFailure, in order.
+func (r *Runner) Failed() []uint64 { return r.failed }
+
+// Summary describes what a finished run achieved.
+type Summary struct {
+	From, To  uint64
+	Processed int
+	Failed    []uint64
+}
+
+// Summary reports the outcome of the last Run.
+func (r *Runner) Summary() Summary {
+	return Summary{From: r.from, To: r.to, Processed: r.processed, Failed: r.failed}
+}
+
+// String renders the summary for the end-of-run log line.
+func (s Summary) String() string {
+	if len(s.Failed) == 0 {
+		return fmt.Sprintf("backfill %d..%d complete: %d ledgers processed", s.From, s.To, s.Processed)
+	}
+	return fmt.Sprintf("backfill %d..%d finished: %d ledgers processed, %d failed: %v",
+		s.From, s.To, s.Processed, len(s.Failed), s.Failed)
+}
+
+func (r *Runner) one(ctx context.Context, index uint64) error {
+	var err error
+	for retry := 0; retry <= r.cfg.MaxRetries; retry++ {
+		if retry > 0 {
+			if err := r.sleepCtx(ctx, r.cfg.delay(retry-1, r.rng)); err != nil {
+				return err
+			}
+		}
+		var lg *xrpl.Ledger
+		if lg, err = r.fetch(ctx, index); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// A terminal rippled error (pruned ledger, malformed request)
+			// cannot improve with repetition; burn no retries on it.
+			var rerr *xrpl.RippledError
+			if errors.As(err, &rerr) && !rerr.Retryable() {
+				return err
+			}
+			continue
+		}
+		if err = verifyFetched(lg, index); err != nil {
+			continue
+		}
+		if err = r.process(lg); err != nil {
+			continue
+		}
+		return nil
+	}
+	return err
+}
+
+// verifyFetched rejects a fetch that answered with the wrong ledger or
+// with no hash. Processing such a response would checkpoint work under
+// the wrong index; a cheap check here turns silent corruption into an
+// ordinary retryable failure.
+func verifyFetched(lg *xrpl.Ledger, index uint64) error {
+	if lg == nil {
+		return fmt.Errorf("backfill: fetch for ledger %d returned nothing", index)
+	}
+	if lg.Index != index {
+		return fmt.Errorf("backfill: asked for ledger %d, got %d", index, lg.Index)
+	}
+	if lg.Hash == "" {
+		return fmt.Errorf("backfill: ledger %d arrived without a hash", index)
+	}
+	return nil
+}
+
+// rateFloor divides MaxRate into the slowest the throttle will go; even
+// a suffering live path deserves some backfill progress.
+const rateFloor = 16
+
+// pace spends this ledger's share of the rate budget, adapting the
+// budget to live pressure first. Halving on a bad p95 sheds load fast;
+// the 10% ramp back up keeps recovery from re-triggering the problem.
+func (r *Runner) pace(ctx context.Context) error {
+	if r.cfg.MaxRate <= 0 {
+		return nil
+	}
+	if r.cfg.LiveP95 != nil && r.cfg.LiveP95Threshold > 0 {
+		if p95 := r.cfg.LiveP95(); p95 > r.cfg.LiveP95Threshold {
+			if !r.throttled {
+				r.logf("backfill: live p95 %s over %s, throttling", p95, r.cfg.LiveP95Threshold)
+			}
+			r.throttled = true
+			if r.rate /= 2; r.rate < r.cfg.MaxRate/rateFloor {
+				r.rate = r.cfg.MaxRate / rateFloor
+			}
+		} else if r.throttled {
+			if r.rate *= 1.1; r.rate >= r.cfg.MaxRate {
+				r.rate = r.cfg.MaxRate
+				r.throttled = false
+				r.logf("backfill: live healthy again, back to %.1f ledgers/s", r.rate)
+			}
+		}
+	}
+	if r.onRate != nil {
+		r.onRate(r.rate, r.throttled)
+	}
+	return r.sleepCtx(ctx, time.Duration(float64(time.Second)/r.rate))
+}
+
+// sleepCtx waits for d or until ctx is cancelled, whichever comes first.
+func (r *Runner) sleepCtx(ctx context.Context, d time.Duration) error {
+	if r.sleep != nil {
+		r.sleep(d)
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,396 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func TestDelayExponentialWithJitterBounds(t *testing.T) {
+	cfg := Config{RetryBase: time.Second, MaxBackoff: 30 * time.Second}
+	cfg.setDefaults()
+	rng := rand.New(rand.NewSource(1))
+
+	for retry := 0; retry < 10; retry++ {
+		want := time.Second << retry
+		if want > cfg.MaxBackoff {
+			want = cfg.MaxBackoff
+		}
+		for i := 0; i < 200; i++ {
+			d := cfg.delay(retry, rng)
+			if d < want/2 || d > want {
+				t.Fatalf("delay(retry=%d) = %v, want within [%v, %v]", retry, d, want/2, want)
+			}
+		}
+	}
+}
+
+func TestDelayCappedAtMaxBackoff(t *testing.T) {
+	cfg := Config{RetryBase: time.Second, MaxBackoff: 4 * time.Second}
+	cfg.setDefaults()
+	rng := rand.New(rand.NewSource(2))
+	for retry := 0; retry < 40; retry++ {
+		if d := cfg.delay(retry, rng); d > cfg.MaxBackoff {
+			t.Fatalf("delay(retry=%d) = %v exceeds cap %v", retry, d, cfg.MaxBackoff)
+		}
+	}
+}
+
+func newTestRunner(cfg Config, fetch FetchFunc, process ProcessFunc) *Runner {
+	r := New(cfg, fetch, process)
+	r.sleep = func(time.Duration) {}
+	r.logf = func(string, ...any) {}
+	return r
+}
+
+func TestRunStopOnFailure(t *testing.T) {
+	boom := errors.New("boom")
+	var attempts int
+	r := newTestRunner(Config{MaxRetries: 2, OnFailure: StopOnFailure},
+		func(ctx context.Context, index uint64) (*xrpl.Ledger, error) {
+			if index == 5 {
+				attempts++
+				return nil, boom
+			}
+			return &xrpl.Ledger{Index: index, Hash: "LH"}, nil
+		},
+		func(*xrpl.Ledger) error { return nil })
+
+	err := r.Run(context.Background(), 3, 8)
+	if !errors.Is(err, boom) {
+		t.Fatalf("Run = %v, want wrapped boom", err)
+	}
+	if attempts != 3 {
+		t.Errorf("ledger 5 attempted %d times, want 3 (1 try + 2 retries)", attempts)
+	}
+}
+
+func TestRunSkipOnFailure(t *testing.T) {
+	boom := errors.New("boom")
+	var processed []uint64
+	r := newTestRunner(Config{MaxRetries: 1, OnFailure: SkipOnFailure},
+		func(ctx context.Context, index uint64) (*xrpl.Ledger, error) {
+			if index == 5 || index == 7 {
+				return nil, boom
+			}
+			return &xrpl.Ledger{Index: index, Hash: "LH"}, nil
+		},
+		func(lg *xrpl.Ledger) error {
+			processed = append(processed, lg.Index)
+			return nil
+		})
+
+	if err := r.Run(context.Background(), 3, 8); err != nil {
+		t.Fatalf("Run = %v, want nil under SkipOnFailure", err)
+	}
+	if len(processed) != 4 {
+		t.Errorf("processed %v, want the 4 healthy ledgers", processed)
+	}
+	failed := r.Failed()
+	if len(failed) != 2 || failed[0] != 5 || failed[1] != 7 {
+		t.Errorf("Failed() = %v, want [5 7]", failed)
+	}
+}
+
+func TestRunRecordsFailuresAndSummarizes(t *testing.T) {
+	boom := errors.New("boom")
+	recorded := map[uint64]error{}
+	r := newTestRunner(Config{MaxRetries: 1, OnFailure: SkipOnFailure},
+		func(ctx context.Context, index uint64) (*xrpl.Ledger, error) {
+			if index%2 == 0 {
+				return nil, boom
+			}
+			return &xrpl.Ledger{Index: index, Hash: "LH"}, nil
+		},
+		func(*xrpl.Ledger) error { return nil })
+	r.OnFailedLedger(func(index uint64, err error) { recorded[index] = err })
+
+	if err := r.Run(context.Background(), 10, 15); err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range []uint64{10, 12, 14} {
+		if !errors.Is(recorded[index], boom) {
+			t.Errorf("ledger %d not recorded as failed", index)
+		}
+	}
+	sum := r.Summary()
+	if sum.Processed != 3 || len(sum.Failed) != 3 || sum.From != 10 || sum.To != 15 {
+		t.Errorf("summary = %+v", sum)
+	}
+	if got := sum.String(); got != "backfill 10..15 finished: 3 ledgers processed, 3 failed: [10 12 14]" {
+		t.Errorf("summary string = %q", got)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	if p, err := ParsePolicy("stop"); err != nil || p != StopOnFailure {
+		t.Errorf("ParsePolicy(stop) = %v, %v", p, err)
+	}
+	if p, err := ParsePolicy("skip"); err != nil || p != SkipOnFailure {
+		t.Errorf("ParsePolicy(skip) = %v, %v", p, err)
+	}
+	if _, err := ParsePolicy("retry-forever"); err == nil {
+		t.Error("ParsePolicy accepted an unknown policy")
+	}
+}
+
+func TestRunTerminalRippledErrorSkipsRetries(t *testing.T) {
+	pruned := &xrpl.RippledError{Code: "lgrNotFound", Message: "ledgerNotFound"}
+	attempts := map[uint64]int{}
+	r := newTestRunner(Config{MaxRetries: 3, OnFailure: SkipOnFailure},
+		func(ctx context.Context, index uint64) (*xrpl.Ledger, error) {
+			attempts[index]++
+			switch index {
+			case 5:
+				return nil, fmt.Errorf("fetch: %w", pruned)
+			case 6:
+				return nil, &xrpl.RippledError{Code: "tooBusy"}
+			}
+			return &xrpl.Ledger{Index: index, Hash: "LH"}, nil
+		},
+		func(*xrpl.Ledger) error { return nil })
+
+	if err := r.Run(context.Background(), 4, 7); err != nil {
+		t.Fatal(err)
+	}
+	// lgrNotFound is terminal: one attempt, no retries burned.
+	if attempts[5] != 1 {
+		t.Errorf("pruned ledger attempted %d times, want 1", attempts[5])
+	}
+	// tooBusy is transient: the full retry budget applies.
+	if attempts[6] != 4 {
+		t.Errorf("busy ledger attempted %d times, want 4 (1 try + 3 retries)", attempts[6])
+	}
+	if got := r.Failed(); len(got) != 2 || got[0] != 5 || got[1] != 6 {
+		t.Errorf("failed ledgers = %v, want [5 6]", got)
+	}
+}
+
+func TestRunRejectsMismatchedFetch(t *testing.T) {
+	// A client answering with the wrong ledger, or one without a hash,
+	// must never reach the processor: checkpointing it would corrupt the
+	// index silently.
+	var processed []uint64
+	attempts := map[uint64]int{}
+	r := newTestRunner(Config{MaxRetries: 1, OnFailure: SkipOnFailure},
+		func(ctx context.Context, index uint64) (*xrpl.Ledger, error) {
+			attempts[index]++
+			switch index {
+			case 5:
+				return &xrpl.Ledger{Index: index + 1, Hash: "LH"}, nil
+			case 6:
+				return &xrpl.Ledger{Index: index}, nil // no hash
+			}
+			return &xrpl.Ledger{Index: index, Hash: "LH"}, nil
+		},
+		func(lg *xrpl.Ledger) error {
+			processed = append(processed, lg.Index)
+			return nil
+		})
+
+	if err := r.Run(context.Background(), 4, 7); err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range processed {
+		if index == 5 || index == 6 {
+			t.Errorf("bad fetch for ledger %d was processed", index)
+		}
+	}
+	// The mismatch is treated like any transient failure: retried, then
+	// given up on under the failure policy.
+	if attempts[5] != 2 || attempts[6] != 2 {
+		t.Errorf("attempts = %v, want 2 each for the bad ledgers", attempts)
+	}
+	if failed := r.Failed(); len(failed) != 2 || failed[0] != 5 || failed[1] != 6 {
+		t.Errorf("Failed() = %v, want [5 6]", failed)
+	}
+}
+
+func TestRunThrottlesUnderLivePressure(t *testing.T) {
+	fetch := func(ctx context.Context, index uint64) (*xrpl.Ledger, error) {
+		return &xrpl.Ledger{Index: index, Hash: "LH"}, nil
+	}
+	// Live reports a bad p95 for the first half of the run, then
+	// recovers; the rate should halve down to the floor, then ramp back
+	// to the ceiling.
+	processed := 0
+	slow := true
+	cfg := Config{
+		MaxRate: 10,
+		LiveP95: func() time.Duration {
+			if slow {
+				return time.Second
+			}
+			return 10 * time.Millisecond
+		},
+		LiveP95Threshold: 500 * time.Millisecond,
+	}
+	var rates []float64
+	var throttledStates []bool
+	r := newTestRunner(cfg, fetch, func(*xrpl.Ledger) error {
+		processed++
+		if processed == 20 {
+			slow = false
+		}
+		return nil
+	})
+	var sleeps []time.Duration
+	r.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	r.OnRate(func(rate float64, throttled bool) {
+		rates = append(rates, rate)
+		throttledStates = append(throttledStates, throttled)
+	})
+
+	if err := r.Run(context.Background(), 1, 60); err != nil {
+		t.Fatal(err)
+	}
+	if len(sleeps) != 60 {
+		t.Fatalf("paced %d ledgers, want every processed one", len(sleeps))
+	}
+	if !throttledStates[0] {
+		t.Error("bad p95 did not throttle immediately")
+	}
+	floor := cfg.MaxRate / rateFloor
+	minRate := rates[0]
+	for _, rate := range rates[:20] {
+		if rate < minRate {
+			minRate = rate
+		}
+		if rate < floor {
+			t.Fatalf("rate %v fell through the floor %v", rate, floor)
+		}
+	}
+	if minRate != floor {
+		t.Errorf("sustained pressure bottomed at %v, want the floor %v", minRate, floor)
+	}
+	last := len(rates) - 1
+	if rates[last] != cfg.MaxRate || throttledStates[last] {
+		t.Errorf("end state rate %v throttled %v, want back at the ceiling", rates[last], throttledStates[last])
+	}
+}
+
+func TestRunStopsOnCancelDuringBackoff(t *testing.T) {
+	boom := errors.New("boom")
+	r := New(Config{MaxRetries: 3, RetryBase: 10 * time.Minute},
+		func(ctx context.Context, index uint64) (*xrpl.Ledger, error) { return nil, boom },
+		func(*xrpl.Ledger) error { return nil })
+	r.logf = func(string, ...any) {}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Run(ctx, 1, 100) }()
+	// Give the run time to fail the first fetch and park in the retry
+	// backoff, then pull the plug.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Run = %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run still sleeping out the backoff after cancellation")
+	}
+}
+
+func TestRunAbortsInFlightFetchOnCancel(t *testing.T) {
+	// The handler holds every request open until the client gives up, so
+	// only a cancelled request context lets the run finish. It must drain
+	// the body first: the server only watches for a dropped connection
+	// once the request body has been consumed.
+	fetchAborted := make(chan struct{}, 1)
+	testDone := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+			fetchAborted <- struct{}{}
+		case <-testDone:
+		}
+	}))
+	defer srv.Close()
+	defer close(testDone)
+
+	client := xrpl.NewClient(srv.URL)
+	r := New(Config{MaxRetries: 1},
+		func(ctx context.Context, index uint64) (*xrpl.Ledger, error) {
+			return client.FetchLedger(ctx, index)
+		},
+		func(*xrpl.Ledger) error { return nil })
+	r.logf = func(string, ...any) {}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.Run(ctx, 1, 1) }()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Run = %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not abandon the in-flight fetch")
+	}
+	select {
+	case <-fetchAborted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("cancellation never reached the server: connection left open")
+	}
+}
+
+func TestRunLeavesNoGoroutinesBehind(t *testing.T) {
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	r := New(Config{MaxRetries: 2, RetryBase: time.Minute},
+		func(ctx context.Context, index uint64) (*xrpl.Ledger, error) {
+			if index == 3 {
+				return nil, errors.New("boom")
+			}
+			return &xrpl.Ledger{Index: index, Hash: "LH"}, nil
+		},
+		func(*xrpl.Ledger) error { return nil })
+	r.logf = func(string, ...any) {}
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx, 1, 10)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	// Runtime bookkeeping can lag the cancellation briefly; poll before
+	// declaring a leak.
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutines after Run: %d, started with %d", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunUnthrottledByDefault(t *testing.T) {
+	fetch := func(ctx context.Context, index uint64) (*xrpl.Ledger, error) {
+		return &xrpl.Ledger{Index: index, Hash: "LH"}, nil
+	}
+	r := newTestRunner(Config{}, fetch, func(*xrpl.Ledger) error { return nil })
+	var sleeps int
+	r.sleep = func(time.Duration) { sleeps++ }
+	if err := r.Run(context.Background(), 1, 10); err != nil {
+		t.Fatal(err)
+	}
+	if sleeps != 0 {
+		t.Errorf("unthrottled run slept %d times", sleeps)
+	}
+}
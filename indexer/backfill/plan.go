@@ -0,0 +1,36 @@
+package backfill
+
+import "github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+
+// Plan intersects the desired backfill range with what the server reports
+// as complete. It returns the fetchable sub-ranges plus the sub-ranges
+// the server does not have; retrying those would fail every ledger, so
+// callers record them as gaps instead.
+func Plan(from, to uint64, available xrpl.LedgerRanges) (runs, gaps []xrpl.LedgerRange) {
+	cursor := from
+	for _, r := range available {
+		if r.To < cursor {
+			continue
+		}
+		if r.From > to {
+			break
+		}
+		if r.From > cursor {
+			gaps = append(gaps, xrpl.LedgerRange{From: cursor, To: r.From - 1})
+			cursor = r.From
+		}
+		end := r.To
+		if end > to {
+			end = to
+		}
+		runs = append(runs, xrpl.LedgerRange{From: cursor, To: end})
+		cursor = end + 1
+		if cursor > to {
+			return runs, gaps
+		}
+	}
+	if cursor <= to {
+		gaps = append(gaps, xrpl.LedgerRange{From: cursor, To: to})
+	}
+	return runs, gaps
+}
@@ -0,0 +1,65 @@
+package backfill
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func TestPlan(t *testing.T) {
+	available := xrpl.LedgerRanges{{From: 100, To: 200}, {From: 300, To: 400}}
+	cases := []struct {
+		name       string
+		from, to   uint64
+		runs, gaps []xrpl.LedgerRange
+	}{
+		{
+			name: "fully available",
+			from: 120, to: 180,
+			runs: []xrpl.LedgerRange{{From: 120, To: 180}},
+		},
+		{
+			name: "spans a hole",
+			from: 150, to: 350,
+			runs: []xrpl.LedgerRange{{From: 150, To: 200}, {From: 300, To: 350}},
+			gaps: []xrpl.LedgerRange{{From: 201, To: 299}},
+		},
+		{
+			name: "starts before history",
+			from: 50, to: 150,
+			runs: []xrpl.LedgerRange{{From: 100, To: 150}},
+			gaps: []xrpl.LedgerRange{{From: 50, To: 99}},
+		},
+		{
+			name: "ends after history",
+			from: 350, to: 500,
+			runs: []xrpl.LedgerRange{{From: 350, To: 400}},
+			gaps: []xrpl.LedgerRange{{From: 401, To: 500}},
+		},
+		{
+			name: "entirely missing",
+			from: 500, to: 600,
+			gaps: []xrpl.LedgerRange{{From: 500, To: 600}},
+		},
+	}
+	for _, c := range cases {
+		runs, gaps := Plan(c.from, c.to, available)
+		if !reflect.DeepEqual(runs, c.runs) {
+			t.Errorf("%s: runs = %v, want %v", c.name, runs, c.runs)
+		}
+		if !reflect.DeepEqual(gaps, c.gaps) {
+			t.Errorf("%s: gaps = %v, want %v", c.name, gaps, c.gaps)
+		}
+	}
+}
+
+func TestPlanNoServerHistory(t *testing.T) {
+	runs, gaps := Plan(10, 20, nil)
+	if runs != nil {
+		t.Errorf("runs = %v against an empty server", runs)
+	}
+	if !reflect.DeepEqual(gaps, []xrpl.LedgerRange{{From: 10, To: 20}}) {
+		t.Errorf("gaps = %v", gaps)
+	}
+}
@@ -0,0 +1,326 @@
+// Package balancechanges extracts per-account balance deltas from
+// transaction metadata. The metadata's AffectedNodes are the authoritative
+// record of what a transaction moved: AccountRoot balance deltas are XRP,
+// RippleState balance deltas are IOUs held against a counterparty, and AMM
+// nodes carry the pool's outstanding LP token supply. Reconciliation and
+// partner reporting read these instead of re-deriving movement from the
+// transaction's own fields.
+package balancechanges
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// BalanceChange is one account's delta in one asset caused by a single
+// transaction. Delta is a signed decimal string; XRP deltas have currency
+// "XRP" and an empty issuer, IOU deltas name the counterparty holding the
+// other side of the trust line.
+type BalanceChange struct {
+	Account  string `json:"account"`
+	Currency string `json:"currency"`
+	Issuer   string `json:"issuer,omitempty"`
+	Delta    string `json:"delta"`
+}
+
+// Record is a stored balance change with the ledger context it happened in.
+type Record struct {
+	BalanceChange
+	LedgerIndex uint64 `json:"ledger_index"`
+	CloseTime   int64  `json:"close_time"`
+	TxHash      string `json:"tx_hash"`
+}
+
+// Extract walks a transaction's metadata and returns every balance change
+// it caused, in node order. Failed (tec) transactions are walked like any
+// other: their metadata still deducts the fee from the sender's
+// AccountRoot, and that deduction must be attributed. A transaction
+// without metadata yields nothing.
+func Extract(tx *xrpl.Transaction) ([]BalanceChange, error) {
+	if tx.Meta == nil {
+		return nil, nil
+	}
+	var changes []BalanceChange
+	for i, an := range tx.Meta.AffectedNodes {
+		node, kind := an.Node()
+		if node == nil {
+			continue
+		}
+		var (
+			got []BalanceChange
+			err error
+		)
+		switch node.LedgerEntryType {
+		case "AccountRoot":
+			got, err = accountRootChanges(node, kind)
+		case "RippleState":
+			got, err = rippleStateChanges(node, kind)
+		case "AMM":
+			got, err = ammChanges(node, kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("balancechanges: tx %s node %d: %w", tx.Hash, i, err)
+		}
+		changes = append(changes, got...)
+	}
+	return changes, nil
+}
+
+// accountRootFields is the slice of an AccountRoot entry the walk reads;
+// Balance arrives as a drops string, which xrpl.Amount converts to XRP.
+type accountRootFields struct {
+	Account string          `json:"Account"`
+	Balance json.RawMessage `json:"Balance"`
+}
+
+func accountRootChanges(node *xrpl.NodeData, kind xrpl.NodeKind) ([]BalanceChange, error) {
+	var next, prev accountRootFields
+	if err := decodeFields(currentFields(node, kind), &next); err != nil {
+		return nil, err
+	}
+	if err := decodeFields(node.PreviousFields, &prev); err != nil {
+		return nil, err
+	}
+	delta, err := balanceDelta(next.Balance, prev.Balance, kind)
+	if err != nil {
+		return nil, err
+	}
+	if delta == nil || delta.Sign() == 0 {
+		return nil, nil
+	}
+	return []BalanceChange{{
+		Account:  next.Account,
+		Currency: "XRP",
+		Delta:    ratDecimal(delta),
+	}}, nil
+}
+
+// rippleStateFields mirrors xrpl.RippleStateFields but keeps Balance raw so
+// an unchanged balance (absent from PreviousFields) is distinguishable from
+// a zero one.
+type rippleStateFields struct {
+	Balance   json.RawMessage `json:"Balance"`
+	LowLimit  xrpl.Amount     `json:"LowLimit"`
+	HighLimit xrpl.Amount     `json:"HighLimit"`
+}
+
+func rippleStateChanges(node *xrpl.NodeData, kind xrpl.NodeKind) ([]BalanceChange, error) {
+	var next, prev rippleStateFields
+	if err := decodeFields(currentFields(node, kind), &next); err != nil {
+		return nil, err
+	}
+	if err := decodeFields(node.PreviousFields, &prev); err != nil {
+		return nil, err
+	}
+	delta, err := balanceDelta(next.Balance, prev.Balance, kind)
+	if err != nil {
+		return nil, err
+	}
+	if delta == nil || delta.Sign() == 0 {
+		return nil, nil
+	}
+	var bal xrpl.Amount
+	if err := json.Unmarshal(next.Balance, &bal); err != nil {
+		return nil, err
+	}
+	// The stored balance is from the low account's perspective: a positive
+	// delta means the low side gained and the high side lost exactly as
+	// much, each holding the asset against the other as issuer.
+	low, high := next.LowLimit.Issuer, next.HighLimit.Issuer
+	return []BalanceChange{
+		{Account: low, Currency: bal.Currency, Issuer: high, Delta: ratDecimal(delta)},
+		{Account: high, Currency: bal.Currency, Issuer: low, Delta: ratDecimal(new(big.Rat).Neg(delta))},
+	}, nil
+}
+
+// ammFields is the slice of an AMM entry the walk reads: the pool account
+// and its outstanding LP token supply.
+type ammFields struct {
+	Account        string          `json:"Account"`
+	LPTokenBalance json.RawMessage `json:"LPTokenBalance"`
+}
+
+func ammChanges(node *xrpl.NodeData, kind xrpl.NodeKind) ([]BalanceChange, error) {
+	var next, prev ammFields
+	if err := decodeFields(currentFields(node, kind), &next); err != nil {
+		return nil, err
+	}
+	if err := decodeFields(node.PreviousFields, &prev); err != nil {
+		return nil, err
+	}
+	delta, err := balanceDelta(next.LPTokenBalance, prev.LPTokenBalance, kind)
+	if err != nil {
+		return nil, err
+	}
+	if delta == nil || delta.Sign() == 0 {
+		return nil, nil
+	}
+	var bal xrpl.Amount
+	if err := json.Unmarshal(next.LPTokenBalance, &bal); err != nil {
+		return nil, err
+	}
+	return []BalanceChange{{
+		Account:  next.Account,
+		Currency: bal.Currency,
+		Issuer:   bal.Issuer,
+		Delta:    ratDecimal(delta),
+	}}, nil
+}
+
+// currentFields picks the field set holding the entry's state after the
+// transaction: NewFields for created entries, FinalFields otherwise.
+func currentFields(node *xrpl.NodeData, kind xrpl.NodeKind) json.RawMessage {
+	if kind == xrpl.NodeCreated {
+		return node.NewFields
+	}
+	return node.FinalFields
+}
+
+func decodeFields(raw json.RawMessage, out any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// balanceDelta computes next - prev over the raw balance fields. A created
+// entry's whole balance is the delta; a deleted entry's delta is measured
+// against its previous balance like any modification. nil means the
+// transaction did not touch the balance: a modified entry omits unchanged
+// fields from PreviousFields.
+func balanceDelta(next, prev json.RawMessage, kind xrpl.NodeKind) (*big.Rat, error) {
+	if len(next) == 0 && len(prev) == 0 {
+		return nil, nil
+	}
+	if kind == xrpl.NodeModified && len(prev) == 0 {
+		return nil, nil
+	}
+	after, err := ratOf(next)
+	if err != nil {
+		return nil, err
+	}
+	before, err := ratOf(prev)
+	if err != nil {
+		return nil, err
+	}
+	return after.Sub(after, before), nil
+}
+
+func ratOf(raw json.RawMessage) (*big.Rat, error) {
+	if len(raw) == 0 {
+		return new(big.Rat), nil
+	}
+	var a xrpl.Amount
+	if err := json.Unmarshal(raw, &a); err != nil {
+		return nil, err
+	}
+	return a.Rat()
+}
+
+// AccountBalance is one account's absolute holding of one asset as a
+// transaction's metadata left it: the post-transaction AccountRoot or
+// RippleState balance, zero for deleted entries.
+type AccountBalance struct {
+	Account  string
+	Currency string
+	Issuer   string
+	Balance  string
+}
+
+// FinalBalances walks a transaction's metadata and returns the absolute
+// post-transaction balances it exposes: every touched AccountRoot's XRP
+// balance and both perspectives of every touched RippleState. Unlike
+// Extract it reports entries whose balance the transaction merely
+// carried along unchanged, because an absolute observation is useful
+// either way. Funding enrichment consumes these instead of replaying
+// deltas.
+func FinalBalances(tx *xrpl.Transaction) ([]AccountBalance, error) {
+	if tx.Meta == nil {
+		return nil, nil
+	}
+	var balances []AccountBalance
+	for i, an := range tx.Meta.AffectedNodes {
+		node, kind := an.Node()
+		if node == nil {
+			continue
+		}
+		var (
+			got []AccountBalance
+			err error
+		)
+		switch node.LedgerEntryType {
+		case "AccountRoot":
+			got, err = accountRootBalance(node, kind)
+		case "RippleState":
+			got, err = rippleStateBalances(node, kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("balancechanges: tx %s node %d: %w", tx.Hash, i, err)
+		}
+		balances = append(balances, got...)
+	}
+	return balances, nil
+}
+
+func accountRootBalance(node *xrpl.NodeData, kind xrpl.NodeKind) ([]AccountBalance, error) {
+	var fields accountRootFields
+	if err := decodeFields(currentFields(node, kind), &fields); err != nil {
+		return nil, err
+	}
+	if fields.Account == "" || len(fields.Balance) == 0 {
+		return nil, nil
+	}
+	balance := "0"
+	if kind != xrpl.NodeDeleted {
+		var a xrpl.Amount
+		if err := json.Unmarshal(fields.Balance, &a); err != nil {
+			return nil, err
+		}
+		balance = a.Value
+	}
+	return []AccountBalance{{Account: fields.Account, Currency: "XRP", Balance: balance}}, nil
+}
+
+func rippleStateBalances(node *xrpl.NodeData, kind xrpl.NodeKind) ([]AccountBalance, error) {
+	var fields rippleStateFields
+	if err := decodeFields(currentFields(node, kind), &fields); err != nil {
+		return nil, err
+	}
+	low, high := fields.LowLimit.Issuer, fields.HighLimit.Issuer
+	if low == "" || high == "" || len(fields.Balance) == 0 {
+		return nil, nil
+	}
+	var amt xrpl.Amount
+	if err := json.Unmarshal(fields.Balance, &amt); err != nil {
+		return nil, err
+	}
+	balance := new(big.Rat)
+	if kind != xrpl.NodeDeleted {
+		var err error
+		if balance, err = ratOf(fields.Balance); err != nil {
+			return nil, err
+		}
+	}
+	// The stored balance is the low account's; the high account holds its
+	// negation, each against the other as issuer.
+	return []AccountBalance{
+		{Account: low, Currency: amt.Currency, Issuer: high, Balance: ratDecimal(balance)},
+		{Account: high, Currency: amt.Currency, Issuer: low, Balance: ratDecimal(new(big.Rat).Neg(balance))},
+	}, nil
+}
+
+// ratDecimal renders a rational delta as a plain signed decimal; balance
+// arithmetic only ever divides by powers of ten, so 30 fractional digits
+// lose nothing.
+func ratDecimal(r *big.Rat) string {
+	s := strings.TrimRight(r.FloatString(30), "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "" || s == "-" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,202 @@
+package balancechanges
+
+import (
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func change(t *testing.T, changes []BalanceChange, account, currency string) BalanceChange {
+	t.Helper()
+	for _, c := range changes {
+		if c.Account == account && c.Currency == currency {
+			return c
+		}
+	}
+	t.Fatalf("no change for %s in %s; all = %+v", account, currency, changes)
+	return BalanceChange{}
+}
+
+func TestExtractPaymentChanges(t *testing.T) {
+	// An IOU payment: the sender pays a 10-drop fee, the trust line between
+	// issuer and receiver moves 5 USD, and the issuer's AMM pool burns some
+	// LP tokens along the way.
+	tx := &xrpl.Transaction{
+		Hash: "BCTX1",
+		Meta: &xrpl.Meta{TransactionResult: "tesSUCCESS", AffectedNodes: []xrpl.AffectedNode{
+			{Modified: &xrpl.NodeData{
+				LedgerEntryType: "AccountRoot",
+				FinalFields:     []byte(`{"Account":"rSender","Balance":"99999990"}`),
+				PreviousFields:  []byte(`{"Balance":"100000000"}`),
+			}},
+			{Modified: &xrpl.NodeData{
+				LedgerEntryType: "RippleState",
+				FinalFields: []byte(`{
+					"Balance": {"currency":"USD","issuer":"rrrrrrrrrrrrrrrrrrrrBZbvji","value":"15"},
+					"LowLimit": {"currency":"USD","issuer":"rLow","value":"0"},
+					"HighLimit": {"currency":"USD","issuer":"rHigh","value":"100"}}`),
+				PreviousFields: []byte(`{"Balance": {"currency":"USD","issuer":"rrrrrrrrrrrrrrrrrrrrBZbvji","value":"10"}}`),
+			}},
+			{Modified: &xrpl.NodeData{
+				LedgerEntryType: "AMM",
+				FinalFields: []byte(`{
+					"Account": "rPool",
+					"LPTokenBalance": {"currency":"03AB","issuer":"rPool","value":"900"}}`),
+				PreviousFields: []byte(`{"LPTokenBalance": {"currency":"03AB","issuer":"rPool","value":"1000"}}`),
+			}},
+			// An untouched balance: Sequence changed but Balance did not.
+			{Modified: &xrpl.NodeData{
+				LedgerEntryType: "AccountRoot",
+				FinalFields:     []byte(`{"Account":"rUntouched","Balance":"5000000","Sequence":8}`),
+				PreviousFields:  []byte(`{"Sequence":7}`),
+			}},
+		}},
+	}
+	changes, err := Extract(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 4 {
+		t.Fatalf("changes = %+v, want 4", changes)
+	}
+	if got := change(t, changes, "rSender", "XRP"); got.Delta != "-0.00001" || got.Issuer != "" {
+		t.Errorf("sender fee = %+v", got)
+	}
+	if got := change(t, changes, "rLow", "USD"); got.Delta != "5" || got.Issuer != "rHigh" {
+		t.Errorf("low side = %+v", got)
+	}
+	if got := change(t, changes, "rHigh", "USD"); got.Delta != "-5" || got.Issuer != "rLow" {
+		t.Errorf("high side = %+v", got)
+	}
+	if got := change(t, changes, "rPool", "03AB"); got.Delta != "-100" || got.Issuer != "rPool" {
+		t.Errorf("lp supply = %+v", got)
+	}
+}
+
+func TestExtractCreatedTrustLine(t *testing.T) {
+	tx := &xrpl.Transaction{
+		Hash: "BCTX2",
+		Meta: &xrpl.Meta{TransactionResult: "tesSUCCESS", AffectedNodes: []xrpl.AffectedNode{
+			{Created: &xrpl.NodeData{
+				LedgerEntryType: "RippleState",
+				NewFields: []byte(`{
+					"Balance": {"currency":"EUR","issuer":"rrrrrrrrrrrrrrrrrrrrBZbvji","value":"-7"},
+					"LowLimit": {"currency":"EUR","issuer":"rIssuer","value":"0"},
+					"HighLimit": {"currency":"EUR","issuer":"rHolder","value":"500"}}`),
+			}},
+		}},
+	}
+	changes, err := Extract(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A freshly created line's whole balance is the delta: the high side
+	// (the holder) gained 7 EUR against the issuer on the low side.
+	if got := change(t, changes, "rHolder", "EUR"); got.Delta != "7" || got.Issuer != "rIssuer" {
+		t.Errorf("holder = %+v", got)
+	}
+	if got := change(t, changes, "rIssuer", "EUR"); got.Delta != "-7" {
+		t.Errorf("issuer = %+v", got)
+	}
+}
+
+func TestExtractFailedTransactionKeepsFee(t *testing.T) {
+	// A tec transaction changes nothing but still burns the sender's fee;
+	// that deduction must be attributed to the sender.
+	tx := &xrpl.Transaction{
+		Hash: "BCTX3",
+		Meta: &xrpl.Meta{TransactionResult: "tecUNFUNDED_OFFER", AffectedNodes: []xrpl.AffectedNode{
+			{Modified: &xrpl.NodeData{
+				LedgerEntryType: "AccountRoot",
+				FinalFields:     []byte(`{"Account":"rSender","Balance":"49999988"}`),
+				PreviousFields:  []byte(`{"Balance":"50000000"}`),
+			}},
+		}},
+	}
+	changes, err := Extract(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("changes = %+v, want the fee only", changes)
+	}
+	if got := changes[0]; got.Account != "rSender" || got.Currency != "XRP" || got.Delta != "-0.000012" {
+		t.Errorf("fee change = %+v", got)
+	}
+}
+
+func TestExtractNoMeta(t *testing.T) {
+	changes, err := Extract(&xrpl.Transaction{Hash: "BCTX4"})
+	if err != nil || changes != nil {
+		t.Fatalf("changes = %+v, err = %v", changes, err)
+	}
+}
+
+func balanceOf(t *testing.T, balances []AccountBalance, account, currency string) AccountBalance {
+	t.Helper()
+	for _, b := range balances {
+		if b.Account == account && b.Currency == currency {
+			return b
+		}
+	}
+	t.Fatalf("no balance for %s in %s; all = %+v", account, currency, balances)
+	return AccountBalance{}
+}
+
+func TestFinalBalances(t *testing.T) {
+	tx := &xrpl.Transaction{
+		Hash: "BCTX5",
+		Meta: &xrpl.Meta{TransactionResult: "tesSUCCESS", AffectedNodes: []xrpl.AffectedNode{
+			{Modified: &xrpl.NodeData{
+				LedgerEntryType: "AccountRoot",
+				FinalFields:     []byte(`{"Account":"rSender","Balance":"99999990"}`),
+				PreviousFields:  []byte(`{"Balance":"100000000"}`),
+			}},
+			// An untouched balance is still an observation.
+			{Modified: &xrpl.NodeData{
+				LedgerEntryType: "AccountRoot",
+				FinalFields:     []byte(`{"Account":"rUntouched","Balance":"5000000","Sequence":8}`),
+				PreviousFields:  []byte(`{"Sequence":7}`),
+			}},
+			{Modified: &xrpl.NodeData{
+				LedgerEntryType: "RippleState",
+				FinalFields: []byte(`{
+					"Balance": {"currency":"USD","issuer":"rrrrrrrrrrrrrrrrrrrrBZbvji","value":"-15"},
+					"LowLimit": {"currency":"USD","issuer":"rLow","value":"0"},
+					"HighLimit": {"currency":"USD","issuer":"rHigh","value":"100"}}`),
+				PreviousFields: []byte(`{"Balance": {"currency":"USD","issuer":"rrrrrrrrrrrrrrrrrrrrBZbvji","value":"-10"}}`),
+			}},
+			// A deleted trust line leaves both sides at zero.
+			{Deleted: &xrpl.NodeData{
+				LedgerEntryType: "RippleState",
+				FinalFields: []byte(`{
+					"Balance": {"currency":"EUR","issuer":"rrrrrrrrrrrrrrrrrrrrBZbvji","value":"0"},
+					"LowLimit": {"currency":"EUR","issuer":"rLow","value":"0"},
+					"HighLimit": {"currency":"EUR","issuer":"rHigh","value":"0"}}`),
+			}},
+		}},
+	}
+	balances, err := FinalBalances(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(balances) != 6 {
+		t.Fatalf("balances = %+v, want 6", balances)
+	}
+	if got := balanceOf(t, balances, "rSender", "XRP"); got.Balance != "99.99999" || got.Issuer != "" {
+		t.Errorf("sender = %+v", got)
+	}
+	if got := balanceOf(t, balances, "rUntouched", "XRP"); got.Balance != "5" {
+		t.Errorf("untouched = %+v", got)
+	}
+	// A negative low-side balance means the high side holds the asset.
+	if got := balanceOf(t, balances, "rHigh", "USD"); got.Balance != "15" || got.Issuer != "rLow" {
+		t.Errorf("high side = %+v", got)
+	}
+	if got := balanceOf(t, balances, "rLow", "USD"); got.Balance != "-15" {
+		t.Errorf("low side = %+v", got)
+	}
+	if got := balanceOf(t, balances, "rHigh", "EUR"); got.Balance != "0" {
+		t.Errorf("deleted line = %+v", got)
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/store"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// bookDiscrepancy is one way the assembled book disagrees with the
+// ledger's book_offers view of the same market.
+type bookDiscrepancy struct {
+	// Kind is "extra" (we hold it open, the ledger does not), "missing"
+	// (the ledger holds it, we do not), or the amount field that drifted.
+	Kind     string
+	Account  string
+	Sequence uint32
+	Stored   string
+	Actual   string
+	// Live carries the ledger's offer for missing and amount rows so
+	// -fix can write the ledger's view; nil for extra rows.
+	Live *xrpl.BookOffer
+}
+
+// checkBooks is the order-book analog of verify-pools: for each tracked
+// market it fetches both directions of the live book via book_offers and
+// diffs the (account, sequence, remaining amounts) set against our
+// active offers. Both sides are pinned to our latest checkpoint's ledger
+// so a racing validation cannot fake a mismatch. Findings land in
+// book_discrepancies; -fix additionally reconciles our rows to the
+// ledger's view, with the fixed flag as the audit trail.
+func checkBooks(args []string) {
+	fs := flag.NewFlagSet("indexer check-books", flag.ExitOnError)
+	market := fs.String("market", "", "one pair to check, as base/quote asset keys; empty checks every tracked market")
+	depth := fs.Int("depth", 500, "offers fetched per book direction; deeper books are checked only this far down")
+	tolerance := fs.String("tolerance", "0.0001", "relative amount tolerance before a mismatch counts")
+	fix := fs.Bool("fix", false, "reconcile mismatched rows to the ledger's view")
+	cfg := loadConfig(fs, args)
+
+	if cfg.DBDSN == "" {
+		log.Fatal("indexer check-books: a database is required")
+	}
+	tol, ok := new(big.Rat).SetString(*tolerance)
+	if !ok || tol.Sign() < 0 {
+		log.Fatalf("indexer check-books: bad -tolerance %q", *tolerance)
+	}
+	ctx := context.Background()
+	st, err := store.Open(cfg.DBDriver, cfg.DBDSN)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer st.Close()
+	if err := st.Migrate(ctx); err != nil {
+		log.Fatal(err)
+	}
+	st.SetNetwork(cfg.Network)
+	if err := st.VerifyNetwork(ctx); err != nil {
+		log.Fatal(err)
+	}
+	client := xrpl.NewClient(cfg.RippledURL)
+	client.SetTimeout(cfg.RequestTimeout)
+
+	latest, err := st.LatestLedger(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if latest == 0 {
+		log.Fatal("indexer check-books: nothing indexed yet")
+	}
+	pinned := uint64(latest)
+
+	var pairs []string
+	if *market != "" {
+		pairs = []string{*market}
+	} else {
+		tracked, err := st.ListTrackedMarkets(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, m := range tracked {
+			if !m.Demoted {
+				pairs = append(pairs, m.Pair)
+			}
+		}
+	}
+	if len(pairs) == 0 {
+		log.Fatal("indexer check-books: no tracked markets; give -market")
+	}
+
+	var checked, drifted, fixed int
+	for _, pair := range pairs {
+		ds, err := checkOneBook(ctx, st, client, pair, pinned, *depth, tol)
+		if err != nil {
+			log.Printf("indexer check-books: %s: %v", pair, err)
+			continue
+		}
+		checked++
+		if len(ds) > 0 {
+			drifted++
+		}
+		for _, d := range ds {
+			log.Printf("indexer check-books: %s: %s offer %s/%d stored %q, ledger %q",
+				pair, d.Kind, d.Account, d.Sequence, d.Stored, d.Actual)
+			didFix := false
+			if *fix {
+				if err := fixBookDiscrepancy(ctx, st, d, pinned); err != nil {
+					log.Printf("indexer check-books: fixing %s %s/%d: %v", d.Kind, d.Account, d.Sequence, err)
+				} else {
+					didFix = true
+					fixed++
+				}
+			}
+			if err := st.RecordBookDiscrepancy(ctx, pair, d.Kind, d.Account, d.Sequence,
+				d.Stored, d.Actual, pinned, didFix); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+	log.Printf("indexer check-books: ledger %d: %d markets checked, %d with drift, %d rows fixed",
+		pinned, checked, drifted, fixed)
+}
+
+// checkOneBook diffs one market at the pinned ledger.
+func checkOneBook(ctx context.Context, st *store.Store, client *xrpl.Client, pair string, pinned uint64, depth int, tol *big.Rat) ([]bookDiscrepancy, error) {
+	base, quote, err := model.ParsePairKey(pair)
+	if err != nil {
+		return nil, err
+	}
+	var live []*xrpl.BookOffer
+	truncated := false
+	for _, dir := range [][2]xrpl.Asset{{base, quote}, {quote, base}} {
+		offers, err := client.BookOffersAt(ctx, dir[0], dir[1], pinned, depth)
+		if err != nil {
+			return nil, err
+		}
+		if len(offers) >= depth {
+			truncated = true
+		}
+		live = append(live, offers...)
+	}
+	baseKey, quoteKey, _ := strings.Cut(pair, "/")
+	stored, err := st.ListBookOffers(ctx, baseKey, quoteKey)
+	if err != nil {
+		return nil, err
+	}
+	return compareBooks(stored, live, truncated, tol), nil
+}
+
+// compareBooks diffs the stored active offers against the ledger's book.
+// When the live fetch hit its depth limit the tail of the real book is
+// unknown, so stored offers absent from the truncated view are not
+// reported as extra — only amount drift and missing offers survive a
+// truncated comparison.
+func compareBooks(stored []*model.Offer, live []*xrpl.BookOffer, truncated bool, tol *big.Rat) []bookDiscrepancy {
+	type key struct {
+		account  string
+		sequence uint32
+	}
+	liveByKey := make(map[key]*xrpl.BookOffer, len(live))
+	for _, bo := range live {
+		liveByKey[key{bo.Account, bo.Sequence}] = bo
+	}
+	storedByKey := make(map[key]bool, len(stored))
+	var out []bookDiscrepancy
+	for _, o := range stored {
+		storedByKey[key{o.Account, o.Sequence}] = true
+		bo, ok := liveByKey[key{o.Account, o.Sequence}]
+		if !ok {
+			if !truncated {
+				out = append(out, bookDiscrepancy{Kind: "extra", Account: o.Account, Sequence: o.Sequence,
+					Stored: o.Status})
+			}
+			continue
+		}
+		if !withinTolerance(o.TakerGets.Value, bo.TakerGets.Value, tol) {
+			out = append(out, bookDiscrepancy{Kind: "taker_gets", Account: o.Account, Sequence: o.Sequence,
+				Stored: o.TakerGets.Value, Actual: bo.TakerGets.Value, Live: bo})
+		}
+		if !withinTolerance(o.TakerPays.Value, bo.TakerPays.Value, tol) {
+			out = append(out, bookDiscrepancy{Kind: "taker_pays", Account: o.Account, Sequence: o.Sequence,
+				Stored: o.TakerPays.Value, Actual: bo.TakerPays.Value, Live: bo})
+		}
+	}
+	for _, bo := range live {
+		if !storedByKey[key{bo.Account, bo.Sequence}] {
+			out = append(out, bookDiscrepancy{Kind: "missing", Account: bo.Account, Sequence: bo.Sequence,
+				Actual: fmt.Sprintf("%s/%s", bo.TakerGets.Value, bo.TakerPays.Value), Live: bo})
+		}
+	}
+	return out
+}
+
+// fixBookDiscrepancy reconciles one row to the ledger's view: extra
+// offers are closed, missing ones seeded as snapshot-status rows (their
+// history before now is unknown, exactly what snapshot means), and
+// drifted amounts overwritten.
+func fixBookDiscrepancy(ctx context.Context, st *store.Store, d bookDiscrepancy, pinned uint64) error {
+	switch d.Kind {
+	case "extra":
+		_, err := st.CancelOffer(ctx, d.Account, d.Sequence, pinned, 0, "")
+		return err
+	case "missing":
+		return st.UpsertOffer(ctx, &model.Offer{
+			TxHash:      d.Live.Index,
+			Account:     d.Live.Account,
+			Sequence:    d.Live.Sequence,
+			Flags:       d.Live.Flags,
+			TakerGets:   d.Live.TakerGets,
+			TakerPays:   d.Live.TakerPays,
+			Status:      model.OfferStatusSnapshot,
+			LedgerIndex: pinned,
+		})
+	case "taker_gets", "taker_pays":
+		return st.ReconcileOfferAmounts(ctx, d.Account, d.Sequence,
+			d.Live.TakerGets.Value, d.Live.TakerPays.Value, pinned)
+	}
+	return fmt.Errorf("unknown discrepancy kind %q", d.Kind)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func TestCompareBooks(t *testing.T) {
+	tol := big.NewRat(1, 10000)
+	stored := []*model.Offer{
+		{Account: "rMatch", Sequence: 1,
+			TakerGets: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "100"},
+			TakerPays: xrpl.Amount{Currency: "XRP", Value: "50000000"}},
+		{Account: "rDrift", Sequence: 2,
+			TakerGets: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "100"},
+			TakerPays: xrpl.Amount{Currency: "XRP", Value: "50000000"}},
+		{Account: "rExtra", Sequence: 3, Status: model.OfferStatusOpen,
+			TakerGets: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "10"},
+			TakerPays: xrpl.Amount{Currency: "XRP", Value: "5000000"}},
+	}
+	live := []*xrpl.BookOffer{
+		{Account: "rMatch", Sequence: 1,
+			TakerGets: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "100.000001"},
+			TakerPays: xrpl.Amount{Currency: "XRP", Value: "50000000"}},
+		{Account: "rDrift", Sequence: 2,
+			// Half filled on-ledger; our row never saw the fill.
+			TakerGets: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "50"},
+			TakerPays: xrpl.Amount{Currency: "XRP", Value: "25000000"}},
+		{Account: "rMissing", Sequence: 9, Index: "ABCD",
+			TakerGets: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "7"},
+			TakerPays: xrpl.Amount{Currency: "XRP", Value: "3500000"}},
+	}
+
+	ds := compareBooks(stored, live, false, tol)
+	kinds := map[string]int{}
+	for _, d := range ds {
+		kinds[d.Kind]++
+	}
+	// rMatch is within tolerance; rDrift drifts on both amounts; rExtra
+	// and rMissing disagree on existence.
+	if len(ds) != 4 || kinds["taker_gets"] != 1 || kinds["taker_pays"] != 1 ||
+		kinds["extra"] != 1 || kinds["missing"] != 1 {
+		t.Fatalf("discrepancies = %+v", ds)
+	}
+	for _, d := range ds {
+		switch d.Kind {
+		case "extra":
+			if d.Account != "rExtra" || d.Sequence != 3 {
+				t.Errorf("extra = %s/%d, want rExtra/3", d.Account, d.Sequence)
+			}
+		case "missing":
+			if d.Account != "rMissing" || d.Live == nil || d.Live.Index != "ABCD" {
+				t.Errorf("missing = %+v, want rMissing with its live offer attached", d)
+			}
+		case "taker_gets":
+			if d.Stored != "100" || d.Actual != "50" || d.Live == nil {
+				t.Errorf("taker_gets = %+v", d)
+			}
+		}
+	}
+}
+
+func TestCompareBooksTruncatedSuppressesExtra(t *testing.T) {
+	tol := big.NewRat(1, 10000)
+	stored := []*model.Offer{
+		{Account: "rDeep", Sequence: 4,
+			TakerGets: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "1"},
+			TakerPays: xrpl.Amount{Currency: "XRP", Value: "500000"}},
+	}
+	// A truncated live book cannot prove rDeep is gone — it may just sit
+	// below the fetched depth.
+	if ds := compareBooks(stored, nil, true, tol); len(ds) != 0 {
+		t.Errorf("truncated comparison reported %+v", ds)
+	}
+	if ds := compareBooks(stored, nil, false, tol); len(ds) != 1 || ds[0].Kind != "extra" {
+		t.Errorf("complete comparison = %+v, want one extra", ds)
+	}
+}
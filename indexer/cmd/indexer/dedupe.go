@@ -0,0 +1,54 @@
+package main
+
+// Redelivery guard for live ingestion. After a reconnect-and-resubscribe
+// rippled can replay the ledgerClosed message for a ledger the pipeline
+// already finished, and nothing upstream promises it won't. The
+// checkpoint comparison in the ingest loop catches most of that, but a
+// reprocess flag or an unlucky race can get a ledger past it, so the
+// pipeline keeps its own short memory of transaction hashes and refuses
+// to run one twice. The database uniqueness constraints remain the
+// final arbiter; this guard just keeps the common case cheap and
+// counted.
+
+// seenTxWindow is how many ledgers of transaction hashes the guard
+// remembers. Redelivery happens within seconds of a reconnect, so a few
+// minutes of ledgers is plenty; anything older is an intentional replay
+// (backfill, reprocess of history) that must not be suppressed.
+const seenTxWindow = 64
+
+// seenTxs is a bounded set of recently processed transaction hashes.
+// The pipeline is single-threaded, so no locking.
+type seenTxs struct {
+	byHash map[string]uint64 // hash -> ledger it was processed in
+}
+
+func newSeenTxs() *seenTxs {
+	return &seenTxs{byHash: map[string]uint64{}}
+}
+
+// has reports whether the transaction was processed in a recent ledger.
+func (s *seenTxs) has(hash string) bool {
+	_, ok := s.byHash[hash]
+	return ok
+}
+
+// observe records a fully processed ledger's transactions and forgets
+// ledgers that have fallen out of the window. It must only be called
+// once the ledger has checkpointed: a failed ledger is retried, and the
+// retry must not find its own hashes here.
+func (s *seenTxs) observe(index uint64, hashes []string) {
+	for _, hash := range hashes {
+		if hash != "" {
+			s.byHash[hash] = index
+		}
+	}
+	if index < seenTxWindow {
+		return
+	}
+	cutoff := index - seenTxWindow
+	for hash, ledger := range s.byHash {
+		if ledger <= cutoff {
+			delete(s.byHash, hash)
+		}
+	}
+}
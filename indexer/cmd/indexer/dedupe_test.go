@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/metrics"
+	"github.com/2pk03/lucendex-validator-verification/indexer/store"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func TestSeenTxsWindow(t *testing.T) {
+	s := newSeenTxs()
+	s.observe(100, []string{"A", "B", ""})
+	if !s.has("A") || !s.has("B") {
+		t.Fatal("fresh hashes not remembered")
+	}
+	if s.has("") {
+		t.Error("empty hash remembered")
+	}
+	// Still inside the window after a few ledgers.
+	s.observe(100+seenTxWindow, []string{"C"})
+	if s.has("A") {
+		t.Error("hash survived past the window")
+	}
+	if !s.has("C") {
+		t.Error("newest ledger forgotten by the prune")
+	}
+}
+
+// dedupeLedger builds a ledger whose one OfferCreate both places an
+// offer and fills a resting one, so a replay would double-insert the
+// trade if nothing stopped it.
+func dedupeLedger(index uint64) *xrpl.Ledger {
+	return &xrpl.Ledger{Index: index, Hash: fmt.Sprintf("DUPLG%d", index), CloseTime: 700000500,
+		Transactions: []*xrpl.Transaction{{
+			Hash: fmt.Sprintf("DUPTX%d", index), TransactionType: "OfferCreate",
+			Account: "rDupTaker", Sequence: 21,
+			TakerGets: xrpl.Amount{Value: "50000000"},
+			TakerPays: xrpl.Amount{Currency: "USD", Issuer: "rDupIssuer", Value: "100"},
+			Meta: &xrpl.Meta{TransactionResult: "tesSUCCESS", AffectedNodes: []xrpl.AffectedNode{
+				{Modified: &xrpl.NodeData{
+					LedgerEntryType: "Offer",
+					FinalFields: []byte(`{"Account":"rDupMaker","Sequence":311,
+						"TakerGets":{"currency":"USD","issuer":"rDupIssuer","value":"20"},
+						"TakerPays":"2000000"}`),
+					PreviousFields: []byte(`{
+						"TakerGets":{"currency":"USD","issuer":"rDupIssuer","value":"40"},
+						"TakerPays":"10000000"}`),
+				}},
+				{Created: &xrpl.NodeData{
+					LedgerEntryType: "Offer",
+					NewFields: []byte(`{"Account":"rDupTaker","Sequence":21,
+						"TakerGets":"42000000",
+						"TakerPays":{"currency":"USD","issuer":"rDupIssuer","value":"80"}}`),
+				}},
+			}},
+		}},
+	}
+}
+
+func TestReplayedLedgerSuppressed(t *testing.T) {
+	m := store.NewMemoryStore()
+	pipe := &pipeline{st: m, m: newPipelineMetrics(metrics.NewRegistry())}
+
+	if err := pipe.process(dedupeLedger(90007000)); err != nil {
+		t.Fatal(err)
+	}
+	trades, offers := len(m.Trades()), 0
+	if _, ok := m.GetOffer("rDupTaker", 21); ok {
+		offers++
+	}
+	if trades != 1 || offers != 1 {
+		t.Fatalf("first pass wrote %d trades, %d offers", trades, offers)
+	}
+
+	// A re-subscribe redelivers the identical ledgerClosed; the row
+	// counts must not move.
+	if err := pipe.process(dedupeLedger(90007000)); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(m.Trades()); got != trades {
+		t.Errorf("replay grew trades to %d, want %d", got, trades)
+	}
+	if pipe.m.duplicateTxs.Value() != 1 {
+		t.Errorf("duplicate metric = %v, want 1", pipe.m.duplicateTxs.Value())
+	}
+	// The redelivered ledger still checkpoints, so lag tracking stays
+	// honest.
+	cp, err := m.GetLastCheckpoint(context.Background())
+	if err != nil || cp == nil || cp.LedgerIndex != 90007000 {
+		t.Fatalf("checkpoint = %+v, %v", cp, err)
+	}
+
+	// Even if the in-memory guard were bypassed, the store's fill key is
+	// the final arbiter.
+	fresh := &pipeline{st: m, m: newPipelineMetrics(metrics.NewRegistry())}
+	if err := fresh.process(dedupeLedger(90007000)); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(m.Trades()); got != trades {
+		t.Errorf("fresh pipeline replay grew trades to %d, want %d", got, trades)
+	}
+	if fresh.m.duplicateTxs.Value() != 0 {
+		t.Error("fresh pipeline's guard fired without history")
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/store"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// discoveryStore is the slice of the store market discovery writes
+// through; *store.Store satisfies it.
+type discoveryStore interface {
+	TrackMarket(ctx context.Context, m *model.TrackedMarket) (bool, error)
+	TouchMarkets(ctx context.Context, pairs []string, ledgerIndex uint64, closeTime int64) error
+	DemoteInactiveMarkets(ctx context.Context, cutoffCloseTime int64) ([]string, error)
+}
+
+// pairActivity is one ledger's activity on one pair, kept in the sliding
+// discovery window.
+type pairActivity struct {
+	ledger    uint64
+	closeTime int64
+	offers    int
+	volume    float64
+}
+
+// marketDiscovery promotes a pair to a tracked market once its recent
+// activity crosses a threshold: offerThreshold offer writes or
+// volumeThreshold XRP of volume inside the window, measured over ledger
+// close times. Promotion fires onPromote once — the book snapshot and
+// the optional books subscription — and demotion is purely a flag flip:
+// every pair keeps being indexed either way, discovery only decides
+// which ones get the extra treatment.
+//
+// The discovery runs on the pipeline goroutine and is not safe for
+// concurrent use. Its window is in memory only: a restart starts
+// counting afresh, which at worst delays a promotion by one window.
+type marketDiscovery struct {
+	st              discoveryStore
+	offerThreshold  int
+	volumeThreshold float64
+	window          time.Duration
+	demoteAfter     time.Duration
+
+	// onPromote (may be nil) runs once per promotion with the pair and
+	// the ledger that tipped it over the threshold.
+	onPromote func(pair string, ledgerIndex uint64)
+
+	activity map[string][]pairActivity
+	// tracked caches actively tracked pairs so steady-state ledgers cost
+	// one TouchMarkets instead of a TrackMarket per active pair.
+	tracked    map[string]bool
+	lastDemote int64
+}
+
+func newMarketDiscovery(st discoveryStore, offerThreshold, volumeThreshold int, window, demoteAfter time.Duration) *marketDiscovery {
+	return &marketDiscovery{
+		st:              st,
+		offerThreshold:  offerThreshold,
+		volumeThreshold: float64(volumeThreshold),
+		window:          window,
+		demoteAfter:     demoteAfter,
+		activity:        map[string][]pairActivity{},
+		tracked:         map[string]bool{},
+	}
+}
+
+// observe folds one processed ledger into the discovery window:
+// offerCounts is the ledger's offer writes per pair, fills its trades.
+// Store errors fail the ledger so the usual retry replays them; a
+// replayed ledger overwrites its own window entry instead of counting
+// twice.
+func (d *marketDiscovery) observe(ctx context.Context, lg *xrpl.Ledger, offerCounts map[string]int, fills []*model.Trade) error {
+	volumes := map[string]float64{}
+	for _, t := range fills {
+		pair := model.PairKey(t.Gets, t.Pays)
+		volumes[pair] += xrpEquivalent(t)
+	}
+	active := map[string]bool{}
+	for pair := range offerCounts {
+		active[pair] = true
+	}
+	for pair := range volumes {
+		active[pair] = true
+	}
+
+	var touch []string
+	for pair := range active {
+		pts := d.activity[pair]
+		if n := len(pts); n > 0 && pts[n-1].ledger == lg.Index {
+			pts = pts[:n-1]
+		}
+		pts = append(pts, pairActivity{lg.Index, lg.CloseTime, offerCounts[pair], volumes[pair]})
+		cutoff := lg.CloseTime - int64(d.window/time.Second)
+		for len(pts) > 0 && pts[0].closeTime < cutoff {
+			pts = pts[1:]
+		}
+		d.activity[pair] = pts
+
+		if d.tracked[pair] {
+			touch = append(touch, pair)
+			continue
+		}
+		var offers int
+		var volume float64
+		for _, p := range pts {
+			offers += p.offers
+			volume += p.volume
+		}
+		if (d.offerThreshold <= 0 || offers < d.offerThreshold) &&
+			(d.volumeThreshold <= 0 || volume < d.volumeThreshold) {
+			continue
+		}
+		promoted, err := d.st.TrackMarket(ctx, &model.TrackedMarket{
+			Pair:                pair,
+			DiscoveredLedger:    lg.Index,
+			DiscoveredCloseTime: lg.CloseTime,
+			LastActiveLedger:    lg.Index,
+			LastActiveCloseTime: lg.CloseTime,
+		})
+		if err != nil {
+			return err
+		}
+		d.tracked[pair] = true
+		if promoted {
+			log.Printf("indexer: discovery: tracking market %s (%d offers, %.0f XRP volume in window)",
+				pair, offers, volume)
+			if d.onPromote != nil {
+				d.onPromote(pair, lg.Index)
+			}
+		}
+	}
+	sort.Strings(touch)
+	if err := d.st.TouchMarkets(ctx, touch, lg.Index, lg.CloseTime); err != nil {
+		return err
+	}
+
+	// Demotion sweeps at most once per hour of ledger time; inactivity
+	// is not urgent.
+	if d.demoteAfter > 0 && lg.CloseTime-d.lastDemote >= 3600 {
+		d.lastDemote = lg.CloseTime
+		demoted, err := d.st.DemoteInactiveMarkets(ctx, lg.CloseTime-int64(d.demoteAfter/time.Second))
+		if err != nil {
+			return err
+		}
+		for _, pair := range demoted {
+			delete(d.tracked, pair)
+			log.Printf("indexer: discovery: demoting inactive market %s", pair)
+		}
+	}
+	return nil
+}
+
+// discoveryState is the gob-serializable image of the discovery window
+// for the cold-start snapshot; its fields are exported for encoding only.
+type discoveryState struct {
+	Activity   map[string][]activityPoint
+	Tracked    []string
+	LastDemote int64
+}
+
+type activityPoint struct {
+	Ledger    uint64
+	CloseTime int64
+	Offers    int
+	Volume    float64
+}
+
+// snapshot copies the window into its serializable form. It runs on the
+// pipeline goroutine, like observe.
+func (d *marketDiscovery) snapshot() discoveryState {
+	st := discoveryState{Activity: map[string][]activityPoint{}, LastDemote: d.lastDemote}
+	for pair, pts := range d.activity {
+		points := make([]activityPoint, len(pts))
+		for i, p := range pts {
+			points[i] = activityPoint{p.ledger, p.closeTime, p.offers, p.volume}
+		}
+		st.Activity[pair] = points
+	}
+	for pair := range d.tracked {
+		st.Tracked = append(st.Tracked, pair)
+	}
+	sort.Strings(st.Tracked)
+	return st
+}
+
+// restore replaces the window with a snapshot's contents. It must run
+// before the pipeline starts feeding ledgers in.
+func (d *marketDiscovery) restore(st discoveryState) {
+	d.activity = map[string][]pairActivity{}
+	for pair, points := range st.Activity {
+		pts := make([]pairActivity, len(points))
+		for i, p := range points {
+			pts[i] = pairActivity{p.Ledger, p.CloseTime, p.Offers, p.Volume}
+		}
+		d.activity[pair] = pts
+	}
+	d.tracked = map[string]bool{}
+	for _, pair := range st.Tracked {
+		d.tracked[pair] = true
+	}
+	d.lastDemote = st.LastDemote
+}
+
+// xrpEquivalent values a trade in XRP using whichever side is native;
+// IOU/IOU trades contribute nothing to volume and count through the
+// offer threshold instead.
+func xrpEquivalent(t *model.Trade) float64 {
+	var side string
+	switch {
+	case t.Gets.Currency == "XRP":
+		side = t.Gets.Value
+	case t.Pays.Currency == "XRP":
+		side = t.Pays.Value
+	default:
+		return 0
+	}
+	v, err := strconv.ParseFloat(side, 64)
+	if err != nil || v < 0 {
+		return 0
+	}
+	return v
+}
+
+// snapshotBook seeds a freshly promoted market with the top of its
+// validated order book, both directions, as snapshot-status offers. The
+// lifecycle guards keep the seed from clobbering anything the stream
+// already knows more about.
+func snapshotBook(ctx context.Context, client *xrpl.Client, st sink, pair string, ledgerIndex uint64) error {
+	base, quote, err := model.ParsePairKey(pair)
+	if err != nil {
+		return fmt.Errorf("discovery: snapshot %s: %w", pair, err)
+	}
+	var seeded int
+	for _, dir := range [][2]xrpl.Asset{{base, quote}, {quote, base}} {
+		offers, err := client.BookOffers(ctx, dir[0], dir[1], 250)
+		if err != nil {
+			return fmt.Errorf("discovery: snapshot %s: %w", pair, err)
+		}
+		for _, bo := range offers {
+			if err := st.UpsertOffer(ctx, &model.Offer{
+				TxHash:      bo.Index,
+				Account:     bo.Account,
+				Sequence:    bo.Sequence,
+				Flags:       bo.Flags,
+				TakerGets:   bo.TakerGets,
+				TakerPays:   bo.TakerPays,
+				Status:      model.OfferStatusSnapshot,
+				LedgerIndex: ledgerIndex,
+			}); err != nil {
+				return fmt.Errorf("discovery: snapshot %s: %w", pair, err)
+			}
+			seeded++
+		}
+	}
+	log.Printf("indexer: discovery: seeded %d book offers for %s", seeded, pair)
+	return nil
+}
+
+// subscribeBooks adds both directions of a pair's order book to the live
+// subscription. The messages arrive as ordinary transaction stream
+// messages, so only the transactions ingest mode gains latency from
+// them; in ledger mode the subscription is harmless but redundant.
+func subscribeBooks(ctx context.Context, sub *xrpl.Subscriber, pair string) error {
+	base, quote, err := model.ParsePairKey(pair)
+	if err != nil {
+		return fmt.Errorf("discovery: subscribe %s: %w", pair, err)
+	}
+	_, err = sub.Call(ctx, "subscribe", map[string]any{
+		"books": []map[string]any{
+			{"taker_gets": base, "taker_pays": quote, "both": true},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("discovery: subscribe %s: %w", pair, err)
+	}
+	return nil
+}
+
+var _ discoveryStore = (*store.Store)(nil)
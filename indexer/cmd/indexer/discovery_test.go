@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+type fakeDiscoveryStore struct {
+	tracked     map[string]*model.TrackedMarket
+	touched     map[string]uint64
+	demoteCalls int
+}
+
+func newFakeDiscoveryStore() *fakeDiscoveryStore {
+	return &fakeDiscoveryStore{tracked: map[string]*model.TrackedMarket{}, touched: map[string]uint64{}}
+}
+
+func (f *fakeDiscoveryStore) TrackMarket(_ context.Context, m *model.TrackedMarket) (bool, error) {
+	if prev, ok := f.tracked[m.Pair]; ok && !prev.Demoted {
+		return false, nil
+	}
+	f.tracked[m.Pair] = m
+	return true, nil
+}
+
+func (f *fakeDiscoveryStore) TouchMarkets(_ context.Context, pairs []string, ledgerIndex uint64, _ int64) error {
+	for _, pair := range pairs {
+		f.touched[pair] = ledgerIndex
+	}
+	return nil
+}
+
+func (f *fakeDiscoveryStore) DemoteInactiveMarkets(_ context.Context, cutoff int64) ([]string, error) {
+	f.demoteCalls++
+	var demoted []string
+	for pair, m := range f.tracked {
+		if !m.Demoted && m.LastActiveCloseTime < cutoff {
+			m.Demoted = true
+			demoted = append(demoted, pair)
+		}
+	}
+	return demoted, nil
+}
+
+func TestDiscoveryPromotesOnOfferThreshold(t *testing.T) {
+	st := newFakeDiscoveryStore()
+	d := newMarketDiscovery(st, 5, 0, time.Hour, 0)
+	var promotions []string
+	d.onPromote = func(pair string, _ uint64) { promotions = append(promotions, pair) }
+
+	pair := "USD.rIss/XRP"
+	lg := func(index uint64, closeTime int64) *xrpl.Ledger {
+		return &xrpl.Ledger{Index: index, CloseTime: closeTime}
+	}
+	// Three ledgers with two offers each: the third crosses the
+	// threshold of five.
+	for i := uint64(0); i < 3; i++ {
+		if err := d.observe(context.Background(), lg(90001000+i, 700008000+int64(i*4)),
+			map[string]int{pair: 2}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(promotions) != 1 || promotions[0] != pair {
+		t.Fatalf("promotions = %v, want exactly one for %s", promotions, pair)
+	}
+	if m := st.tracked[pair]; m == nil || m.DiscoveredLedger != 90001002 {
+		t.Errorf("tracked = %+v", st.tracked[pair])
+	}
+
+	// Continued activity touches, never re-promotes.
+	if err := d.observe(context.Background(), lg(90001003, 700008012),
+		map[string]int{pair: 3}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(promotions) != 1 {
+		t.Errorf("promotion fired again: %v", promotions)
+	}
+	if st.touched[pair] != 90001003 {
+		t.Errorf("touch = %d, want 90001003", st.touched[pair])
+	}
+}
+
+func TestDiscoveryReplayedLedgerCountsOnce(t *testing.T) {
+	st := newFakeDiscoveryStore()
+	d := newMarketDiscovery(st, 4, 0, time.Hour, 0)
+
+	pair := "EUR.rIss/XRP"
+	// The same ledger observed twice — a retry — must not double its
+	// contribution to the window.
+	for i := 0; i < 2; i++ {
+		if err := d.observe(context.Background(), &xrpl.Ledger{Index: 90001010, CloseTime: 700008100},
+			map[string]int{pair: 3}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(st.tracked) != 0 {
+		t.Fatalf("three offers promoted a pair with threshold four: %v", st.tracked)
+	}
+}
+
+func TestDiscoveryVolumeThresholdAndWindow(t *testing.T) {
+	st := newFakeDiscoveryStore()
+	d := newMarketDiscovery(st, 0, 100, time.Minute, 0)
+
+	trade := func(value string) []*model.Trade {
+		return []*model.Trade{{
+			Gets: xrpl.Amount{Currency: "XRP", Value: value},
+			Pays: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "1"},
+		}}
+	}
+	// 60 XRP, then 70 more — but only after the first fell out of the
+	// one-minute window, so no promotion.
+	if err := d.observe(context.Background(), &xrpl.Ledger{Index: 90001020, CloseTime: 700008200},
+		nil, trade("60")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.observe(context.Background(), &xrpl.Ledger{Index: 90001021, CloseTime: 700008300},
+		nil, trade("70")); err != nil {
+		t.Fatal(err)
+	}
+	if len(st.tracked) != 0 {
+		t.Fatalf("expired volume promoted a pair: %v", st.tracked)
+	}
+	// Another 40 XRP inside the window tips it over.
+	if err := d.observe(context.Background(), &xrpl.Ledger{Index: 90001022, CloseTime: 700008310},
+		nil, trade("40")); err != nil {
+		t.Fatal(err)
+	}
+	if len(st.tracked) != 1 {
+		t.Fatalf("tracked = %v, want the volume pair", st.tracked)
+	}
+}
+
+func TestDiscoveryDemotesAndRepromotes(t *testing.T) {
+	st := newFakeDiscoveryStore()
+	d := newMarketDiscovery(st, 1, 0, time.Hour, time.Hour)
+	var promotions int
+	d.onPromote = func(string, uint64) { promotions++ }
+
+	pair := "GBP.rIss/XRP"
+	if err := d.observe(context.Background(), &xrpl.Ledger{Index: 90001030, CloseTime: 700010000},
+		map[string]int{pair: 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if promotions != 1 {
+		t.Fatalf("promotions = %d", promotions)
+	}
+	// Two hours of ledger time later the demotion sweep runs and the
+	// idle market is demoted; fresh activity then re-promotes it.
+	if err := d.observe(context.Background(), &xrpl.Ledger{Index: 90001031, CloseTime: 700017300},
+		map[string]int{"OTHER.rIss/XRP": 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if st.demoteCalls == 0 || !st.tracked[pair].Demoted {
+		t.Fatalf("market not demoted after inactivity: %+v", st.tracked[pair])
+	}
+	if err := d.observe(context.Background(), &xrpl.Ledger{Index: 90001032, CloseTime: 700017310},
+		map[string]int{pair: 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if promotions != 3 {
+		// OTHER promoted once too; the re-promotion is the third.
+		t.Errorf("promotions = %d, want 3 including the re-promotion", promotions)
+	}
+	if st.tracked[pair].Demoted {
+		t.Error("re-promoted market still demoted")
+	}
+}
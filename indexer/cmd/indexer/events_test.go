@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/internal/eventsink"
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/store"
+)
+
+func TestOutboxPublishesEachEntityOncePerLedger(t *testing.T) {
+	m := store.NewMemoryStore()
+	lg := dedupeLedger(90008000) // one placed offer, one fill
+
+	// Two pipelines replaying the same ledger model the worst case: a
+	// second process without the in-memory redelivery guard's history.
+	// The outbox converges on (ledger, seq), so the bus still sees one
+	// copy of each entity.
+	for i := 0; i < 2; i++ {
+		pipe := &pipeline{st: m, emitEvents: true}
+		if err := pipe.process(lg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sink := eventsink.NewMemory()
+	for _, ev := range m.OutboxEvents() {
+		if err := sink.Publish(context.Background(), ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events := sink.Events()
+	if len(events) != 2 {
+		t.Fatalf("published %d events, want 2 (one offer, one trade)", len(events))
+	}
+	// Fixed per-ledger order: offers before trades.
+	if events[0].Topic != "offer" || events[1].Topic != "trade" {
+		t.Fatalf("topics = %s, %s, want offer then trade", events[0].Topic, events[1].Topic)
+	}
+	var offer model.Offer
+	if err := json.Unmarshal([]byte(events[0].Payload), &offer); err != nil {
+		t.Fatal(err)
+	}
+	if offer.Account != "rDupTaker" || offer.Sequence != 21 {
+		t.Errorf("offer event = %+v", offer)
+	}
+	var trade model.Trade
+	if err := json.Unmarshal([]byte(events[1].Payload), &trade); err != nil {
+		t.Fatal(err)
+	}
+	if trade.Maker != "rDupMaker" {
+		t.Errorf("trade event = %+v", trade)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/metrics"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// feePoller samples the fee command on an interval into fee_history and
+// flags congestion: an open-ledger fee at or above spikeMultiple times
+// the base fee logs a warning and counts a metric.
+type feePoller struct {
+	interval      time.Duration
+	spikeMultiple int
+
+	fetch  func(ctx context.Context) (*xrpl.FeeInfo, error)
+	record func(ctx context.Context, fee *xrpl.FeeInfo) error // nil without a database
+	logf   func(format string, args ...any)
+
+	spikes  *metrics.Counter
+	openFee *metrics.Gauge
+}
+
+func newFeePoller(reg *metrics.Registry, interval time.Duration, spikeMultiple int,
+	fetch func(context.Context) (*xrpl.FeeInfo, error)) *feePoller {
+	return &feePoller{
+		interval:      interval,
+		spikeMultiple: spikeMultiple,
+		fetch:         fetch,
+		logf:          log.Printf,
+		spikes:        reg.Counter("indexer_fee_spikes_total", "open-ledger fee spikes above the configured multiple of the base fee"),
+		openFee:       reg.Gauge("indexer_open_ledger_fee_drops", "last sampled open-ledger fee in drops"),
+	}
+}
+
+// run polls until ctx ends; a non-positive interval disables the poller.
+func (p *feePoller) run(ctx context.Context) {
+	if p.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *feePoller) poll(ctx context.Context) {
+	fee, err := p.fetch(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			p.logf("indexer: fee poll: %v", err)
+		}
+		return
+	}
+	p.openFee.Set(float64(fee.OpenLedgerFee))
+	if p.spikeMultiple > 0 && fee.BaseFee > 0 && fee.OpenLedgerFee >= uint64(p.spikeMultiple)*fee.BaseFee {
+		p.spikes.Add(1)
+		p.logf("indexer: fee spike: open-ledger fee %d drops is %dx the base fee %d (load factor %.1f, ledger %d)",
+			fee.OpenLedgerFee, fee.OpenLedgerFee/fee.BaseFee, fee.BaseFee, fee.LoadFactor, fee.LedgerIndex)
+	}
+	if p.record == nil {
+		return
+	}
+	if err := p.record(ctx, fee); err != nil && ctx.Err() == nil {
+		p.logf("indexer: fee poll: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/metrics"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func TestFeePollerSpikeDetection(t *testing.T) {
+	reg := metrics.NewRegistry()
+	fee := &xrpl.FeeInfo{LedgerIndex: 100, BaseFee: 10, OpenLedgerFee: 2653937, LoadFactor: 265219.4}
+	fp := newFeePoller(reg, 0, 10, func(ctx context.Context) (*xrpl.FeeInfo, error) {
+		return fee, nil
+	})
+	var logged []string
+	fp.logf = func(format string, args ...any) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+	var recorded []*xrpl.FeeInfo
+	fp.record = func(ctx context.Context, f *xrpl.FeeInfo) error {
+		recorded = append(recorded, f)
+		return nil
+	}
+
+	fp.poll(context.Background())
+	if got := fp.spikes.Value(); got != 1 {
+		t.Errorf("spike count after spike = %v, want 1", got)
+	}
+	if len(logged) != 1 {
+		t.Errorf("log lines = %d, want 1 spike warning", len(logged))
+	}
+	if got := fp.openFee.Value(); got != 2653937 {
+		t.Errorf("open fee gauge = %v, want 2653937", got)
+	}
+
+	// A calm open ledger neither counts nor warns, but still records.
+	fee = &xrpl.FeeInfo{LedgerIndex: 101, BaseFee: 10, OpenLedgerFee: 10, LoadFactor: 1}
+	fp.poll(context.Background())
+	if got := fp.spikes.Value(); got != 1 {
+		t.Errorf("spike count after calm poll = %v, want 1", got)
+	}
+	if len(recorded) != 2 || recorded[1].LedgerIndex != 101 {
+		t.Fatalf("recorded = %v, want both samples", recorded)
+	}
+}
+
+func TestFeePollerWithoutDatabase(t *testing.T) {
+	fp := newFeePoller(metrics.NewRegistry(), 0, 10, func(ctx context.Context) (*xrpl.FeeInfo, error) {
+		return &xrpl.FeeInfo{BaseFee: 10, OpenLedgerFee: 10, LoadFactor: 1}, nil
+	})
+	fp.logf = func(format string, args ...any) {
+		t.Errorf("unexpected log: "+format, args...)
+	}
+	fp.poll(context.Background())
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// forkWindow is how long a ledger index collects validations before its
+// votes are compared. Validations for one ledger trickle in over a
+// couple of seconds; by ten the set is as complete as it will get.
+const forkWindow = 10 * time.Second
+
+// forkPendingCap bounds the open windows; a stream replaying deep
+// history would otherwise pile up an unbounded map before anything
+// expired.
+const forkPendingCap = 1000
+
+// forkDetector groups full validations by ledger index and, once an
+// index's window closes, checks that every validator signed the same
+// hash. A split vote — two hashes for one sequence — is validator
+// disagreement or a fork in progress, and goes to onFork with the
+// validators on each side. It runs on the ingest goroutine and is not
+// safe for concurrent use.
+type forkDetector struct {
+	onFork func(index uint64, sides []*model.ValidationDisagreement)
+	now    func() time.Time
+
+	pending map[uint64]*voteGroup
+}
+
+type voteGroup struct {
+	firstSeen time.Time
+	// votes is hash → the set of validators that signed it.
+	votes map[string]map[string]bool
+}
+
+func newForkDetector(onFork func(uint64, []*model.ValidationDisagreement)) *forkDetector {
+	return &forkDetector{onFork: onFork, now: time.Now, pending: map[uint64]*voteGroup{}}
+}
+
+// observe folds one validation into its ledger's open window and closes
+// every window past its deadline. A repeated vote from the same
+// validator for the same hash deduplicates; the same validator on two
+// hashes counts on both sides, which is itself worth seeing.
+func (f *forkDetector) observe(v *xrpl.ValidationMessage) {
+	key := v.PublicKey
+	if v.MasterKey != "" {
+		key = v.MasterKey
+	}
+	g := f.pending[v.LedgerIndex]
+	if g == nil {
+		g = &voteGroup{firstSeen: f.now(), votes: map[string]map[string]bool{}}
+		f.pending[v.LedgerIndex] = g
+	}
+	if g.votes[v.LedgerHash] == nil {
+		g.votes[v.LedgerHash] = map[string]bool{}
+	}
+	g.votes[v.LedgerHash][key] = true
+	f.sweep()
+}
+
+func (f *forkDetector) sweep() {
+	deadline := f.now().Add(-forkWindow)
+	for index, g := range f.pending {
+		if !g.firstSeen.After(deadline) {
+			f.close(index, g)
+		}
+	}
+	// A stream replaying deep history opens windows faster than they
+	// expire; close the oldest ones early rather than growing without
+	// bound.
+	for len(f.pending) > forkPendingCap {
+		var oldestIndex uint64
+		var oldest *voteGroup
+		for index, g := range f.pending {
+			if oldest == nil || g.firstSeen.Before(oldest.firstSeen) {
+				oldestIndex, oldest = index, g
+			}
+		}
+		f.close(oldestIndex, oldest)
+	}
+}
+
+// close evaluates one window and drops it.
+func (f *forkDetector) close(index uint64, g *voteGroup) {
+	delete(f.pending, index)
+	if len(g.votes) < 2 {
+		return
+	}
+	sides := make([]*model.ValidationDisagreement, 0, len(g.votes))
+	for hash, voters := range g.votes {
+		side := &model.ValidationDisagreement{
+			LedgerIndex: index,
+			LedgerHash:  hash,
+			Votes:       len(voters),
+		}
+		for voter := range voters {
+			side.Validators = append(side.Validators, voter)
+		}
+		sort.Strings(side.Validators)
+		sides = append(sides, side)
+	}
+	// Majority side first, ties by hash, so readers see the likely
+	// canonical ledger before the dissenters.
+	sort.Slice(sides, func(i, j int) bool {
+		if sides[i].Votes != sides[j].Votes {
+			return sides[i].Votes > sides[j].Votes
+		}
+		return sides[i].LedgerHash < sides[j].LedgerHash
+	})
+	f.onFork(index, sides)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func TestForkDetectorFlagsSplitVote(t *testing.T) {
+	var forks map[uint64][]*model.ValidationDisagreement
+	d := newForkDetector(func(index uint64, sides []*model.ValidationDisagreement) {
+		if forks == nil {
+			forks = map[uint64][]*model.ValidationDisagreement{}
+		}
+		forks[index] = sides
+	})
+	now := time.Unix(1_700_000_000, 0)
+	d.now = func() time.Time { return now }
+
+	vote := func(index uint64, hash, key string) {
+		d.observe(&xrpl.ValidationMessage{
+			PublicKey: key, LedgerHash: hash, LedgerIndex: index, Full: true,
+		})
+	}
+	// Two validators on HASHA, one dissenter on HASHB; a repeated vote
+	// must not inflate the count. The next index agrees unanimously.
+	vote(90002000, "HASHA", "nHOne")
+	vote(90002000, "HASHA", "nHTwo")
+	vote(90002000, "HASHA", "nHTwo")
+	vote(90002000, "HASHB", "nHThree")
+	vote(90002001, "HASHC", "nHOne")
+	vote(90002001, "HASHC", "nHTwo")
+	if forks != nil {
+		t.Fatalf("fork reported before the window closed: %v", forks)
+	}
+
+	now = now.Add(forkWindow + time.Second)
+	vote(90002002, "HASHD", "nHOne")
+	if len(forks) != 1 {
+		t.Fatalf("forks = %v, want only the split index", forks)
+	}
+	sides := forks[90002000]
+	if len(sides) != 2 {
+		t.Fatalf("sides = %+v", sides)
+	}
+	if sides[0].LedgerHash != "HASHA" || sides[0].Votes != 2 {
+		t.Errorf("majority side = %+v", sides[0])
+	}
+	if len(sides[0].Validators) != 2 || sides[0].Validators[0] != "nHOne" || sides[0].Validators[1] != "nHTwo" {
+		t.Errorf("majority validators = %v", sides[0].Validators)
+	}
+	if sides[1].LedgerHash != "HASHB" || sides[1].Votes != 1 || sides[1].Validators[0] != "nHThree" {
+		t.Errorf("minority side = %+v", sides[1])
+	}
+	if len(d.pending) != 1 {
+		t.Errorf("%d windows still open, want only the fresh one", len(d.pending))
+	}
+}
+
+func TestForkDetectorPrefersMasterKey(t *testing.T) {
+	fired := 0
+	d := newForkDetector(func(uint64, []*model.ValidationDisagreement) { fired++ })
+	now := time.Unix(1_700_000_000, 0)
+	d.now = func() time.Time { return now }
+
+	// The same validator under rotating signing keys resolves to one
+	// identity, so two votes for one hash are no disagreement.
+	d.observe(&xrpl.ValidationMessage{
+		PublicKey: "n9SignA", MasterKey: "nHSame", LedgerHash: "HASHE", LedgerIndex: 90002010, Full: true,
+	})
+	d.observe(&xrpl.ValidationMessage{
+		PublicKey: "n9SignB", MasterKey: "nHSame", LedgerHash: "HASHE", LedgerIndex: 90002010, Full: true,
+	})
+	now = now.Add(forkWindow + time.Second)
+	d.sweep()
+	if fired != 0 {
+		t.Errorf("unanimous ledger reported as fork %d times", fired)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/store"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// identityStore is the slice of the store identity enrichment works
+// through; *store.Store satisfies it.
+type identityStore interface {
+	IdentitySubjectsDue(ctx context.Context, refreshAfter time.Duration, limit int) ([]string, error)
+	UpsertIdentity(ctx context.Context, id *model.Identity) error
+}
+
+const (
+	// identityConcurrency caps outbound HTTP fetches in flight at once;
+	// enrichment is a background nicety and must never look like a crawl.
+	identityConcurrency = 4
+	// identitySweepLimit bounds one sweep; the rest waits for the next.
+	identitySweepLimit = 100
+	// identitySweepInterval is how often the job polls for due subjects.
+	// Due-ness itself is governed by -identity-refresh-interval.
+	identitySweepInterval = 15 * time.Minute
+
+	identityTomlPath = "/.well-known/xrp-ledger.toml"
+	// identityTomlMaxBytes caps how much of a toml is read; the files in
+	// the wild are a few KB.
+	identityTomlMaxBytes = 1 << 20
+)
+
+// identityEnricher resolves issuers and validator master keys to
+// human-readable identities: the subject's on-ledger Domain, then the
+// domain's xrp-ledger.toml. Every outcome is written back, negative
+// ones included, so the store's TTLs decide when a subject is worth
+// another look.
+type identityEnricher struct {
+	st identityStore
+	// domain resolves a subject to its on-ledger Domain; normally
+	// (*xrpl.Client).AccountDomain.
+	domain func(ctx context.Context, account string) (string, error)
+	http   *http.Client
+	// tomlURL builds the fetch URL for a domain; tests point it at a
+	// local server.
+	tomlURL      func(domain string) string
+	refreshAfter time.Duration
+}
+
+func newIdentityEnricher(st identityStore, client *xrpl.Client, refreshAfter time.Duration) *identityEnricher {
+	return &identityEnricher{
+		st:           st,
+		domain:       client.AccountDomain,
+		http:         &http.Client{Timeout: 15 * time.Second},
+		tomlURL:      func(domain string) string { return "https://" + domain + identityTomlPath },
+		refreshAfter: refreshAfter,
+	}
+}
+
+// sweep resolves every subject currently due, identityConcurrency at a
+// time. Per-subject failures are logged and skipped — one dead domain
+// must not starve the rest of the batch — so the only error returned is
+// the due query's own.
+func (e *identityEnricher) sweep(ctx context.Context) error {
+	subjects, err := e.st.IdentitySubjectsDue(ctx, e.refreshAfter, identitySweepLimit)
+	if err != nil {
+		return err
+	}
+	sem := make(chan struct{}, identityConcurrency)
+	var wg sync.WaitGroup
+	for _, subject := range subjects {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(subject string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			id, err := e.resolve(ctx, subject)
+			if err != nil {
+				log.Printf("indexer: identity: %s: %v", subject, err)
+				return
+			}
+			if err := e.st.UpsertIdentity(ctx, id); err != nil {
+				log.Printf("indexer: identity: %s: %v", subject, err)
+			}
+		}(subject)
+	}
+	wg.Wait()
+	return nil
+}
+
+// resolve works out one subject's identity. An account_info failure
+// other than actNotFound is returned as an error — that is rippled
+// trouble, not a dead domain, and writing it would poison the cache.
+func (e *identityEnricher) resolve(ctx context.Context, account string) (*model.Identity, error) {
+	domain, err := e.domain(ctx, account)
+	switch {
+	case errors.Is(err, xrpl.ErrAccountNotFound):
+		// Validator master keys have no backing account; same outcome as
+		// an account that never set a Domain.
+		return &model.Identity{Account: account, Status: model.IdentityNoDomain}, nil
+	case err != nil:
+		return nil, err
+	case domain == "":
+		return &model.Identity{Account: account, Status: model.IdentityNoDomain}, nil
+	}
+	id := &model.Identity{Account: account, Domain: domain}
+	doc, err := e.fetchToml(ctx, domain)
+	if err != nil {
+		// A negative row; the store's daily floor keeps dead domains
+		// from being re-fetched every sweep.
+		id.Status = model.IdentityUnreachable
+		return id, nil
+	}
+	entry := doc.entryFor(account)
+	if entry == nil {
+		id.Status = model.IdentityUnverified
+		return id, nil
+	}
+	id.Status = model.IdentityVerified
+	id.Name = entry["name"]
+	if id.Name == "" {
+		id.Name = entry["desc"]
+	}
+	id.Logo = entry["icon"]
+	id.Twitter = entry["twitter"]
+	// The toml's principals fill whatever the claiming entry left blank.
+	if len(doc.Principals) > 0 {
+		if id.Name == "" {
+			id.Name = doc.Principals[0]["name"]
+		}
+		if id.Twitter == "" {
+			id.Twitter = doc.Principals[0]["twitter"]
+		}
+	}
+	return id, nil
+}
+
+func (e *identityEnricher) fetchToml(ctx context.Context, domain string) (*xrplToml, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.tomlURL(domain), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := e.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching xrp-ledger.toml: HTTP %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, identityTomlMaxBytes))
+	if err != nil {
+		return nil, err
+	}
+	return parseXRPLToml(data), nil
+}
+
+// tomlEntry is one [[SECTION]] table's string key/values.
+type tomlEntry map[string]string
+
+// xrplToml is the slice of an xrp-ledger.toml the enrichment reads.
+type xrplToml struct {
+	Accounts   []tomlEntry
+	Validators []tomlEntry
+	Principals []tomlEntry
+}
+
+// entryFor finds the ACCOUNTS entry claiming the address, or the
+// VALIDATORS entry claiming the master key; that claim is what upgrades
+// an identity to verified.
+func (t *xrplToml) entryFor(subject string) tomlEntry {
+	for _, e := range t.Accounts {
+		if e["address"] == subject {
+			return e
+		}
+	}
+	for _, e := range t.Validators {
+		if e["public_key"] == subject {
+			return e
+		}
+	}
+	return nil
+}
+
+// parseXRPLToml reads the subset of TOML xrp-ledger.toml files actually
+// use: [[SECTION]] array-of-table headers and key = "value" string
+// pairs. Unknown sections, non-string values and malformed lines are
+// skipped rather than rejected — the files in the wild are messy, and a
+// bad line should not cost the good ones.
+func parseXRPLToml(data []byte) *xrplToml {
+	t := &xrplToml{}
+	var current *[]tomlEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[[") && strings.HasSuffix(line, "]]") {
+			switch strings.ToUpper(strings.TrimSpace(line[2 : len(line)-2])) {
+			case "ACCOUNTS":
+				current = &t.Accounts
+			case "VALIDATORS":
+				current = &t.Validators
+			case "PRINCIPALS":
+				current = &t.Principals
+			default:
+				current = nil
+				continue
+			}
+			*current = append(*current, tomlEntry{})
+			continue
+		}
+		if current == nil || len(*current) == 0 {
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		val = strings.TrimSpace(val)
+		if len(val) < 2 || val[0] != '"' {
+			continue
+		}
+		end := strings.Index(val[1:], `"`)
+		if end < 0 {
+			continue
+		}
+		(*current)[len(*current)-1][key] = val[1 : 1+end]
+	}
+	return t
+}
+
+var _ identityStore = (*store.Store)(nil)
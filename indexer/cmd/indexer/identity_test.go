@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func TestParseXRPLToml(t *testing.T) {
+	doc := parseXRPLToml([]byte(`
+# issuer metadata
+[METADATA]
+modified = "2026-01-01"
+
+[[PRINCIPALS]]
+name = "Example Labs"
+twitter = "examplelabs"
+
+[[ACCOUNTS]]
+address = "rIssuer1"
+name = "Example USD"
+icon = "https://example.com/logo.png"
+
+[[ACCOUNTS]]
+address = "rIssuer2"
+desc = "secondary account"
+not_a_string = 42
+
+[[VALIDATORS]]
+public_key = "nHValidator1"
+`))
+	if len(doc.Accounts) != 2 || len(doc.Validators) != 1 || len(doc.Principals) != 1 {
+		t.Fatalf("sections = %d/%d/%d accounts/validators/principals",
+			len(doc.Accounts), len(doc.Validators), len(doc.Principals))
+	}
+	if e := doc.entryFor("rIssuer1"); e == nil || e["name"] != "Example USD" || e["icon"] != "https://example.com/logo.png" {
+		t.Errorf("rIssuer1 entry = %v", e)
+	}
+	if e := doc.entryFor("rIssuer2"); e == nil || e["desc"] != "secondary account" || e["not_a_string"] != "" {
+		t.Errorf("rIssuer2 entry = %v", e)
+	}
+	if e := doc.entryFor("nHValidator1"); e == nil {
+		t.Error("validator entry not found by public key")
+	}
+	if doc.entryFor("rUnknown") != nil {
+		t.Error("unknown subject matched an entry")
+	}
+}
+
+// identityStoreStub serves a canned due list and records upserts.
+type identityStoreStub struct {
+	mu  sync.Mutex
+	due []string
+	ids map[string]*model.Identity
+}
+
+func (s *identityStoreStub) IdentitySubjectsDue(context.Context, time.Duration, int) ([]string, error) {
+	return s.due, nil
+}
+
+func (s *identityStoreStub) UpsertIdentity(_ context.Context, id *model.Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ids == nil {
+		s.ids = map[string]*model.Identity{}
+	}
+	s.ids[id.Account] = id
+	return nil
+}
+
+func TestIdentityResolve(t *testing.T) {
+	// The test server stands in for every domain at once: the domain
+	// being "fetched" rides in the path prefix via the tomlURL override.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/good.example/"):
+			w.Write([]byte("[[ACCOUNTS]]\naddress = \"rVerified\"\nname = \"Good Issuer\"\n" +
+				"[[PRINCIPALS]]\ntwitter = \"goodissuer\"\n"))
+		case strings.HasPrefix(r.URL.Path, "/other.example/"):
+			w.Write([]byte("[[ACCOUNTS]]\naddress = \"rSomebodyElse\"\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	domains := map[string]string{
+		"rVerified":   "good.example",
+		"rUnverified": "other.example",
+		"rDead":       "dead.example",
+		"rPlain":      "",
+	}
+	e := &identityEnricher{
+		http:    srv.Client(),
+		tomlURL: func(domain string) string { return srv.URL + "/" + domain + identityTomlPath },
+		domain: func(_ context.Context, account string) (string, error) {
+			d, ok := domains[account]
+			if !ok {
+				return "", xrpl.ErrAccountNotFound
+			}
+			return d, nil
+		},
+	}
+
+	cases := []struct {
+		account, status, name, twitter string
+	}{
+		{"rVerified", model.IdentityVerified, "Good Issuer", "goodissuer"},
+		{"rUnverified", model.IdentityUnverified, "", ""},
+		{"rDead", model.IdentityUnreachable, "", ""},
+		{"rPlain", model.IdentityNoDomain, "", ""},
+		{"nHNotAnAccount", model.IdentityNoDomain, "", ""},
+	}
+	for _, tc := range cases {
+		id, err := e.resolve(context.Background(), tc.account)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.account, err)
+		}
+		if id.Status != tc.status || id.Name != tc.name || id.Twitter != tc.twitter {
+			t.Errorf("%s = %+v, want status %s name %q twitter %q",
+				tc.account, id, tc.status, tc.name, tc.twitter)
+		}
+	}
+}
+
+func TestIdentitySweepCapsConcurrency(t *testing.T) {
+	var inFlight, peak int32
+	st := &identityStoreStub{}
+	for i := 0; i < 3*identityConcurrency; i++ {
+		st.due = append(st.due, "rIssuer"+string(rune('A'+i)))
+	}
+	e := &identityEnricher{
+		st: st,
+		domain: func(context.Context, string) (string, error) {
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+			return "", nil // no_domain: no HTTP fetch needed
+		},
+	}
+	if err := e.sweep(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&peak); got > identityConcurrency {
+		t.Errorf("peak concurrency = %d, cap is %d", got, identityConcurrency)
+	}
+	if len(st.ids) != len(st.due) {
+		t.Errorf("stored %d identities, want %d", len(st.ids), len(st.due))
+	}
+	for _, id := range st.ids {
+		if id.Status != model.IdentityNoDomain {
+			t.Errorf("%s status = %s", id.Account, id.Status)
+		}
+	}
+}
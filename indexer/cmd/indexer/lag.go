@@ -0,0 +1,113 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/metrics"
+)
+
+// lagTracker measures how far live ingestion runs behind the stream:
+// every fetched ledger counts on arrival and clears when processed, so
+// after a reconnect the reorder backlog shows up as lag. Crossing the
+// high-water mark flips the tracker into catch-up mode, which the
+// pipeline reads to defer non-essential per-ledger work; the mode only
+// clears again below the low-water mark so the boundary cannot flap.
+type lagTracker struct {
+	high, low int
+
+	mu      sync.Mutex
+	pending map[uint64]time.Time
+	catchup bool
+
+	// notify, when set, hears every catch-up flip. It is called with the
+	// tracker's lock held, so it must not call back in.
+	notify func(catchup bool)
+
+	countGauge *metrics.Gauge
+	ageGauge   *metrics.Gauge
+}
+
+func newLagTracker(high, low int) *lagTracker {
+	return &lagTracker{high: high, low: low, pending: map[uint64]time.Time{}}
+}
+
+// bindMetrics publishes the backlog depth and the wall-clock age of the
+// oldest unprocessed ledger as gauges.
+func (l *lagTracker) bindMetrics(reg *metrics.Registry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.countGauge = reg.Gauge("indexer_ledger_lag_count", "fetched ledgers not yet processed")
+	l.ageGauge = reg.Gauge("indexer_ledger_lag_oldest_seconds", "wall-clock age of the oldest unprocessed ledger")
+}
+
+// arrived records a fetched ledger awaiting processing.
+func (l *lagTracker) arrived(index uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.pending[index]; !ok {
+		l.pending[index] = time.Now()
+	}
+	l.updateLocked()
+}
+
+// processed clears one ledger from the backlog.
+func (l *lagTracker) processed(index uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.pending, index)
+	l.updateLocked()
+}
+
+func (l *lagTracker) updateLocked() {
+	switch {
+	case l.high > 0 && !l.catchup && len(l.pending) >= l.high:
+		l.catchup = true
+		log.Printf("indexer: lag reached %d ledgers, deferring non-essential work", len(l.pending))
+		if l.notify != nil {
+			l.notify(true)
+		}
+	case l.catchup && len(l.pending) <= l.low:
+		l.catchup = false
+		log.Printf("indexer: lag down to %d ledgers, resuming deferred work", len(l.pending))
+		if l.notify != nil {
+			l.notify(false)
+		}
+	}
+	if l.countGauge != nil {
+		l.countGauge.Set(float64(len(l.pending)))
+		l.ageGauge.Set(l.oldestLocked(time.Now()).Seconds())
+	}
+}
+
+func (l *lagTracker) oldestLocked(now time.Time) time.Duration {
+	var oldest time.Duration
+	for _, at := range l.pending {
+		if age := now.Sub(at); age > oldest {
+			oldest = age
+		}
+	}
+	return oldest
+}
+
+// count is the current backlog depth.
+func (l *lagTracker) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.pending)
+}
+
+// oldestAge is how long the oldest unprocessed ledger has been waiting.
+func (l *lagTracker) oldestAge() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.oldestLocked(time.Now())
+}
+
+// inCatchup reports whether the pipeline should defer non-essential work.
+func (l *lagTracker) inCatchup() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.catchup
+}
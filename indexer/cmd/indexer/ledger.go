@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/parser"
+	"github.com/2pk03/lucendex-validator-verification/indexer/store"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// ledgerReport is the machine-readable result of a single-ledger run;
+// its JSON is stable so runs can be diffed across parser versions.
+type ledgerReport struct {
+	LedgerIndex    uint64                   `json:"ledger_index"`
+	LedgerHash     string                   `json:"ledger_hash"`
+	CloseTime      int64                    `json:"close_time"`
+	CloseTimeHuman string                   `json:"close_time_human"`
+	ParserVersion  int                      `json:"parser_version"`
+	Transactions   int                      `json:"transactions"`
+	Offers         []*model.Offer           `json:"offers"`
+	Trades         []*model.Trade           `json:"trades"`
+	Cancels        []map[string]any         `json:"cancels"`
+	NFTOffers      []*model.NFTOffer        `json:"nft_offers"`
+	NFTTrades      []*model.NFTTrade        `json:"nft_trades"`
+	NFTCancels     []string                 `json:"nft_cancels"`
+	TrustLines     []*model.TrustLine       `json:"trust_lines"`
+	TrustRemovals  []model.TrustLineRemoval `json:"trust_removals"`
+	PoolCreates    []*model.Pool            `json:"pool_creates"`
+	Pools          []*model.PoolAdjustment  `json:"pool_adjustments"`
+	FeeChanges     []*model.FeeChange       `json:"fee_changes"`
+	AssetFlags     []*model.AssetFlagChange `json:"asset_flags"`
+	Amendments     []*model.Amendment       `json:"amendments"`
+	DomainChanges  []string                 `json:"domain_changes"`
+	Errors         []string                 `json:"errors"`
+}
+
+// reportSink collects handler writes into the report instead of a store.
+type reportSink struct{ r *ledgerReport }
+
+func (s reportSink) UpsertOffer(o *model.Offer) error { s.r.Offers = append(s.r.Offers, o); return nil }
+func (s reportSink) CancelOffer(account string, sequence uint32, ledgerIndex uint64, closeTime int64, txHash string) error {
+	s.r.Cancels = append(s.r.Cancels, map[string]any{"account": account, "sequence": sequence})
+	return nil
+}
+func (s reportSink) MarkOfferReplaced(account string, sequence, replacedBy uint32, ledgerIndex uint64, closeTime int64, txHash string) error {
+	s.r.Cancels = append(s.r.Cancels, map[string]any{
+		"account": account, "sequence": sequence, "replaced_by": replacedBy,
+	})
+	return nil
+}
+func (s reportSink) InsertTrade(t *model.Trade) error          { s.r.Trades = append(s.r.Trades, t); return nil }
+func (s reportSink) UpdateOfferFill(*model.Trade, int64) error { return nil }
+func (s reportSink) UpsertNFTOffer(o *model.NFTOffer) error {
+	s.r.NFTOffers = append(s.r.NFTOffers, o)
+	return nil
+}
+func (s reportSink) AcceptNFTOffer(string, uint64, int64, string) error { return nil }
+func (s reportSink) CancelNFTOffer(offerIndex string, _ uint64, _ int64, _ string) error {
+	s.r.NFTCancels = append(s.r.NFTCancels, offerIndex)
+	return nil
+}
+func (s reportSink) InsertNFTTrade(t *model.NFTTrade) error {
+	s.r.NFTTrades = append(s.r.NFTTrades, t)
+	return nil
+}
+func (s reportSink) UpsertTrustLine(l *model.TrustLine) error {
+	s.r.TrustLines = append(s.r.TrustLines, l)
+	return nil
+}
+func (s reportSink) DeleteTrustLine(account, currency, issuer string, _ uint64) error {
+	s.r.TrustRemovals = append(s.r.TrustRemovals, model.TrustLineRemoval{Account: account, Currency: currency, Issuer: issuer})
+	return nil
+}
+func (s reportSink) UpsertPool(p *model.Pool) error {
+	s.r.PoolCreates = append(s.r.PoolCreates, p)
+	return nil
+}
+func (s reportSink) AdjustPoolReserve(adj *model.PoolAdjustment) error {
+	s.r.Pools = append(s.r.Pools, adj)
+	return nil
+}
+func (s reportSink) ApplyFeeChange(fc *model.FeeChange) error {
+	s.r.FeeChanges = append(s.r.FeeChanges, fc)
+	return nil
+}
+func (s reportSink) ApplyAssetFlag(c *model.AssetFlagChange) error {
+	s.r.AssetFlags = append(s.r.AssetFlags, c)
+	return nil
+}
+func (s reportSink) UpsertAmendment(a *model.Amendment) error {
+	s.r.Amendments = append(s.r.Amendments, a)
+	return nil
+}
+func (s reportSink) InvalidateIdentity(account string) error {
+	s.r.DomainChanges = append(s.r.DomainChanges, account)
+	return nil
+}
+func (s reportSink) IsPoolAccount(string) bool { return false }
+
+// ledgerOnce fetches exactly one ledger, runs it through the handler
+// registry, and prints everything the parsers extracted as JSON. Nothing
+// is written unless -commit is also given — it is a reproduction tool,
+// not a small backfill.
+func ledgerOnce(args []string) {
+	fs := flag.NewFlagSet("indexer ledger", flag.ExitOnError)
+	index := fs.Uint64("index", 0, "ledger index to fetch and parse")
+	commit := fs.Bool("commit", false, "also write the results to the configured database")
+	cfg := loadConfig(fs, args)
+
+	if *index == 0 {
+		log.Fatal("indexer ledger: -index is required")
+	}
+	client := xrpl.NewClient(cfg.RippledURL)
+	client.SetTimeout(cfg.RequestTimeout)
+	lg, err := client.FetchLedgerSync(*index)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report := buildLedgerReport(lg)
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+
+	if !*commit {
+		return
+	}
+	if cfg.DBDSN == "" {
+		log.Fatal("indexer ledger: -commit needs a database")
+	}
+	ctx := context.Background()
+	st, err := store.Open(cfg.DBDriver, cfg.DBDSN)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer st.Close()
+	if err := st.Migrate(ctx); err != nil {
+		log.Fatal(err)
+	}
+	st.SetNetwork(cfg.Network)
+	if err := st.VerifyNetwork(ctx); err != nil {
+		log.Fatal(err)
+	}
+	pipe := &pipeline{
+		st:      st,
+		filter:  parser.NewIssuerFilter(cfg.IssuerAllow, cfg.IssuerDeny, cfg.WatchIssuers),
+		timeout: cfg.LedgerTimeout,
+
+		trackBalances: cfg.TrackBalances,
+	}
+	if err := pipe.process(lg); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("indexer ledger: ledger %d committed", lg.Index)
+}
+
+// buildLedgerReport runs the ledger through the registry into a report
+// sink; empty slices stay non-nil so the JSON shape is constant.
+func buildLedgerReport(lg *xrpl.Ledger) *ledgerReport {
+	report := &ledgerReport{
+		LedgerIndex:    lg.Index,
+		LedgerHash:     lg.Hash,
+		CloseTime:      lg.CloseTime,
+		CloseTimeHuman: xrpl.CloseTimeHuman(lg.CloseTime),
+		ParserVersion:  parser.Version,
+		Transactions:   len(lg.Transactions),
+		Offers:         []*model.Offer{},
+		Trades:         []*model.Trade{},
+		Cancels:        []map[string]any{},
+		NFTOffers:      []*model.NFTOffer{},
+		NFTTrades:      []*model.NFTTrade{},
+		NFTCancels:     []string{},
+		TrustLines:     []*model.TrustLine{},
+		TrustRemovals:  []model.TrustLineRemoval{},
+		PoolCreates:    []*model.Pool{},
+		Pools:          []*model.PoolAdjustment{},
+		FeeChanges:     []*model.FeeChange{},
+		AssetFlags:     []*model.AssetFlagChange{},
+		Amendments:     []*model.Amendment{},
+		DomainChanges:  []string{},
+		Errors:         []string{},
+	}
+	reg := parser.NewRegistry(parser.DefaultHandlers()...)
+	for _, tx := range lg.Transactions {
+		if err := reg.Dispatch(tx, lg, reportSink{r: report}); err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		}
+	}
+	return report
+}
@@ -0,0 +1,1904 @@
+// Command indexer ingests XRPL DEX activity from a rippled server.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/backfill"
+	"github.com/2pk03/lucendex-validator-verification/indexer/balancechanges"
+	"github.com/2pk03/lucendex-validator-verification/indexer/config"
+	"github.com/2pk03/lucendex-validator-verification/indexer/ingest"
+	"github.com/2pk03/lucendex-validator-verification/indexer/internal/api"
+	"github.com/2pk03/lucendex-validator-verification/indexer/internal/eventsink"
+	"github.com/2pk03/lucendex-validator-verification/indexer/internal/faultinject"
+	"github.com/2pk03/lucendex-validator-verification/indexer/metrics"
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/parser"
+	"github.com/2pk03/lucendex-validator-verification/indexer/rpcapi"
+	"github.com/2pk03/lucendex-validator-verification/indexer/store"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// loadConfig builds the effective configuration with file < env < flag
+// precedence: flag defaults come from config.Default, a -config file
+// overlays those, INDEXER_* variables overlay the file, and explicitly set
+// flags win over everything. Subcommands register their own flags on fs
+// before calling; non-config flags are left alone.
+func loadConfig(fs *flag.FlagSet, args []string) config.Config {
+	defaults := config.Default()
+	configPath := fs.String("config", "", "optional config file (JSON or YAML)")
+
+	_ = fs.String("rippled", defaults.RippledURL, "rippled JSON-RPC URL")
+	_ = fs.String("rippled-ws", defaults.RippledWS, "rippled WebSocket URL for live ingestion")
+	_ = fs.Bool("live", defaults.Live, "follow validated ledgers after any backfill")
+	_ = fs.String("ingest-mode", defaults.IngestMode, "live ingestion mode: ledger (fetch each closed ledger) or transactions (stream txs with metadata)")
+	_ = fs.Int("stream-buffer", defaults.StreamBuffer, "stream messages buffered per live WebSocket connection before the reader blocks")
+	_ = fs.String("db-driver", defaults.DBDriver, "database/sql driver name")
+	_ = fs.String("db-dsn", defaults.DBDSN, "database DSN; empty runs without persistence")
+	_ = fs.String("archive-dsn", defaults.ArchiveDSN, "second database receiving an async append-only copy of checkpoints, trades, and the connection audit; empty disables")
+	_ = fs.Duration("request-timeout", defaults.RequestTimeout, "per-request timeout for rippled calls; 0 disables")
+	_ = fs.Duration("ledger-timeout", defaults.LedgerTimeout, "processing timeout per ledger including database writes; 0 disables")
+	_ = fs.Duration("slow-query-threshold", defaults.SlowQueryThreshold, "log store statements at least this slow, with name and duration; 0 disables")
+	_ = fs.String("metrics-addr", defaults.MetricsAddr, "serve Prometheus metrics on this address; empty disables")
+	_ = fs.String("pprof-addr", defaults.PprofAddr, "serve net/http/pprof on this address; empty disables. Profiles expose internals — keep it on localhost unless firewalled")
+	_ = fs.String("fault-config", defaults.FaultConfig, "fault-injection spec file for resilience testing; needs a -tags faultinject build")
+	_ = fs.String("partner-api-addr", defaults.PartnerAPIAddr, "serve the read-only Partner API on this address; empty disables")
+	_ = fs.Bool("partner-api-auth", defaults.PartnerAPIAuth, "require an API key on every Partner API request")
+	_ = fs.Int("partner-api-rate-limit", defaults.PartnerAPIRateLimit, "requests per minute for API keys without their own limit; 0 leaves them unlimited")
+	_ = fs.String("rpc-addr", defaults.RPCAddr, "serve the typed RPC API for internal services on this address; empty disables")
+	_ = fs.String("rpc-cert", defaults.RPCCert, "TLS certificate for the RPC API; set together with -rpc-key")
+	_ = fs.String("rpc-key", defaults.RPCKey, "TLS key for the RPC API")
+	_ = fs.String("rpc-client-ca", defaults.RPCClientCA, "CA bundle for mutual TLS on the RPC API; clients must present a certificate it signed")
+	_ = fs.String("network", defaults.Network, "XRPL network: mainnet, testnet, devnet, or custom")
+	_ = fs.Uint("network-id", uint(defaults.NetworkID), "expected network_id when -network=custom")
+	_ = fs.String("issuer-allow", strings.Join(defaults.IssuerAllow, ","), "comma-separated issuers to index; empty indexes all")
+	_ = fs.String("issuer-deny", strings.Join(defaults.IssuerDeny, ","), "comma-separated issuers to skip")
+	_ = fs.String("watch-issuers", strings.Join(defaults.WatchIssuers, ","), "comma-separated issuers to focus on; writes involving none of them are skipped, empty indexes everything")
+	_ = fs.Bool("track-balances", defaults.TrackBalances, "extract per-account balance changes from metadata")
+	_ = fs.Bool("track-validations", defaults.TrackValidations, "subscribe to the validations stream and record validator votes")
+	_ = fs.Bool("compute-funded", defaults.ComputeFunded, "refresh open offers' funded amounts from observed owner balances")
+	_ = fs.Bool("emit-events", defaults.EmitEvents, "write publishable events into the transactional outbox as ledgers are processed")
+	_ = fs.Int("outbox-limit", defaults.OutboxLimit, "pending outbox rows past which ledger processing fails and retries; 0 is unbounded")
+	_ = fs.String("event-sink-url", defaults.EventSinkURL, "external event bus for outbox events (nats://host:port); empty logs them instead")
+	_ = fs.String("event-subject-prefix", defaults.EventSubjectPrefix, "bus subject prefix for published events")
+	_ = fs.String("record-fixtures", defaults.RecordFixtures, "write every live ledger to this directory as gzipped JSON for the replay command; empty disables")
+	_ = fs.Int("cache-checkpoints", defaults.CacheCheckpoints, "checkpoints kept in the store's in-process cache; 0 disables")
+	_ = fs.Int("cache-offers", defaults.CacheOffers, "recently touched offers kept in the store's in-process cache; 0 disables")
+	_ = fs.String("snapshot-dir", defaults.SnapshotDir, "directory for periodic cold-start snapshots of derived runtime state; empty disables")
+	_ = fs.Int("snapshot-every", defaults.SnapshotEvery, "ledgers between cold-start snapshots")
+	_ = fs.Int("snapshot-keep", defaults.SnapshotKeep, "snapshot files kept before the oldest is pruned")
+	_ = fs.Bool("discover-markets", defaults.DiscoverMarkets, "promote active token pairs to tracked markets automatically")
+	_ = fs.Int("discover-offer-threshold", defaults.DiscoverOfferThreshold, "offer writes within the discovery window that promote a pair; 0 disables this leg")
+	_ = fs.Int("discover-volume-threshold", defaults.DiscoverVolumeThreshold, "XRP volume within the discovery window that promotes a pair; 0 disables this leg")
+	_ = fs.Duration("discover-window", defaults.DiscoverWindow, "sliding window of ledger time discovery thresholds are measured over")
+	_ = fs.Duration("discover-demote-after", defaults.DiscoverDemoteAfter, "demote a tracked market after this long without activity; 0 never demotes")
+	_ = fs.Bool("discover-subscribe-books", defaults.DiscoverSubscribeBooks, "subscribe promoted markets' order books on the live connection")
+	_ = fs.Duration("pool-history-downsample-after", defaults.PoolHistoryDownsampleAfter, "thin pool reserve history older than this to hourly rows; 0 keeps everything")
+	_ = fs.Duration("maintenance-interval", defaults.MaintenanceInterval, "run in-process database upkeep (VACUUM ANALYZE, audit pruning) this often; 0 disables")
+	_ = fs.Duration("maintenance-jitter", defaults.MaintenanceJitter, "random extra delay added to each maintenance pass")
+	_ = fs.Duration("maintenance-audit-keep", defaults.MaintenanceAuditKeep, "connection-audit rows older than this are pruned by maintenance")
+	_ = fs.Int("offer-retention-ledgers", defaults.OfferRetentionLedgers, "maintenance prunes cancelled/filled offers this many ledgers behind the tip; 0 keeps all")
+	_ = fs.String("rippled-ws-failover", strings.Join(defaults.RippledWSFailover, ","), "comma-separated fallback WebSocket endpoints for the watchdog's failover action")
+	_ = fs.Duration("watchdog-warn-after", defaults.WatchdogWarnAfter, "warn and mark unready after this long without a processed ledger; 0 disables the watchdog")
+	_ = fs.Duration("watchdog-act-after", defaults.WatchdogActAfter, "run the watchdog action after this long without a processed ledger; 0 never acts")
+	_ = fs.String("watchdog-action", defaults.WatchdogAction, "what a stalled live ingestion triggers: reconnect, failover, or exit")
+	_ = fs.Int("catchup-lag-high", defaults.CatchupLagHigh, "backlog of unprocessed ledgers that enters catch-up mode; 0 disables")
+	_ = fs.Int("catchup-lag-low", defaults.CatchupLagLow, "backlog at which catch-up mode ends and deferred work drains")
+	_ = fs.Bool("priority-catchup", defaults.PriorityCatchup, "during catch-up, apply partner accounts' writes first and replay the full parse after")
+	_ = fs.Duration("validator-score-window", defaults.ValidatorScoreWindow, "sliding window validator agreement is scored over")
+	_ = fs.Duration("validator-score-interval", defaults.ValidatorScoreInterval, "how often the validator scoring job runs; 0 disables it")
+	_ = fs.Duration("identity-refresh-interval", defaults.IdentityRefreshInterval, "how long a resolved issuer/validator identity stays fresh; 0 disables enrichment")
+	_ = fs.Duration("fee-poll-interval", defaults.FeePollInterval, "how often the fee command is sampled; 0 disables it")
+	_ = fs.Int("fee-spike-multiple", defaults.FeeSpikeMultiple, "flag open-ledger fees at or above this multiple of the base fee; 0 disables")
+
+	_ = fs.Uint64("backfill-from", defaults.BackfillFrom, "first ledger index to backfill")
+	_ = fs.Uint64("backfill-to", defaults.BackfillTo, "last ledger index to backfill (inclusive)")
+	_ = fs.Int("backfill-max-retries", defaults.BackfillMaxRetries, "retries per ledger before the failure policy applies")
+	_ = fs.Duration("backfill-retry-base", defaults.BackfillRetryBase, "delay before the first retry; doubles per retry")
+	_ = fs.Duration("backfill-max-backoff", defaults.BackfillMaxBackoff, "cap on the exponential retry delay")
+	_ = fs.String("backfill-on-failure", defaults.BackfillOnFailure, "what to do when a ledger fails after all retries: stop or skip")
+	_ = fs.Float64("backfill-max-rate", defaults.BackfillMaxRate, "backfill ceiling in ledgers per second, adapted down under live pressure; 0 is unthrottled")
+
+	fs.Parse(args)
+
+	cfg := defaults
+	cfg.ConfigFile = *configPath
+	if *configPath != "" {
+		if err := cfg.LoadFile(*configPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := cfg.ApplyEnv(os.LookupEnv); err != nil {
+		log.Fatal(err)
+	}
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		if !config.Known(f.Name) || flagErr != nil {
+			return
+		}
+		flagErr = cfg.Set(f.Name, f.Value.String())
+	})
+	if flagErr != nil {
+		log.Fatal(flagErr)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	return cfg
+}
+
+func main() {
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "reprocess":
+			reprocess(args[1:])
+		case "ledger":
+			ledgerOnce(args[1:])
+		case "verify-pools":
+			verifyPools(args[1:])
+		case "check-books":
+			checkBooks(args[1:])
+		case "status":
+			statusCmd(args[1:])
+		case "export":
+			exportCmd(args[1:])
+		case "replay":
+			replayCmd(args[1:])
+		case "unl-report":
+			unlReportCmd(args[1:])
+		case "export-proof":
+			exportProofCmd(args[1:])
+		case "verify-proof":
+			verifyProofCmd(args[1:])
+		case "api-keys":
+			apiKeysCmd(args[1:])
+		case "partner":
+			partnerCmd(args[1:])
+		case "selftest":
+			selftestCmd(args[1:])
+		default:
+			log.Fatalf("indexer: unknown command %q", args[0])
+		}
+		return
+	}
+	run(args)
+}
+
+func run(args []string) {
+	cfg := loadConfig(flag.NewFlagSet("indexer", flag.ExitOnError), args)
+
+	if cfg.FaultConfig != "" {
+		spec, err := os.ReadFile(cfg.FaultConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// A binary without the faultinject tag refuses the spec here, so
+		// a chaos config can never ride along into production unnoticed.
+		if err := faultinject.Load(string(spec)); err != nil {
+			log.Fatalf("indexer: %v", err)
+		}
+		log.Printf("indexer: fault injection armed from %s", cfg.FaultConfig)
+	}
+
+	// Shutdown signals cancel every in-flight request and stop the
+	// backfill between ledgers.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	policy, err := backfill.ParsePolicy(cfg.BackfillOnFailure)
+	if err != nil {
+		log.Fatal(err)
+	}
+	wantBackfill := cfg.BackfillFrom != 0
+	if !wantBackfill && !cfg.Live {
+		log.Fatal("indexer: nothing to do: give a backfill range and/or -live")
+	}
+
+	var st *store.Store
+	if cfg.DBDSN != "" {
+		if st, err = store.Open(cfg.DBDriver, cfg.DBDSN); err != nil {
+			log.Fatal(err)
+		}
+		defer st.Close()
+		if err := st.Migrate(ctx); err != nil {
+			log.Fatal(err)
+		}
+		st.SetNetwork(cfg.Network)
+		if err := st.VerifyNetwork(ctx); err != nil {
+			log.Fatal(err)
+		}
+		// Only the long-running ingest command caches; one-shot commands
+		// and reprocessing read the database directly.
+		st.SetCacheSizes(cfg.CacheCheckpoints, cfg.CacheOffers)
+		if cfg.SlowQueryThreshold > 0 {
+			st.SetSlowQueryLog(cfg.SlowQueryThreshold, log.Printf)
+		}
+
+		// HA: only the replica holding the leader advisory lock
+		// processes; the others wait and keep retrying so one takes
+		// over when the leader's database session dies.
+		for {
+			leader, err := st.TryAcquireLeaderLock(ctx)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if leader {
+				break
+			}
+			log.Print("indexer: standby: another replica holds the leader lock")
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+		defer st.ReleaseLeaderLock(context.Background())
+	}
+	reg := metrics.NewRegistry()
+	wd := newWatchdog(cfg.WatchdogWarnAfter, cfg.WatchdogActAfter)
+	lag := newLagTracker(cfg.CatchupLagHigh, cfg.CatchupLagLow)
+	lag.bindMetrics(reg)
+	// The mode tracker folds the lag and watchdog verdicts (and, once
+	// live ingestion starts, the connection state) into one legible
+	// operational mode for /readyz and the logs.
+	mode := newModeTracker()
+	mode.bindMetrics(reg)
+	lag.notify = mode.setLagging
+	wd.notify = mode.setStalled
+	// The live path records its per-ledger processing durations here so
+	// a concurrent backfill can shed rate when live latency degrades.
+	liveDurations := newDurationWindow(100)
+	filter := parser.NewIssuerFilter(cfg.IssuerAllow, cfg.IssuerDeny, cfg.WatchIssuers)
+	pipe := &pipeline{m: newPipelineMetrics(reg), filter: filter, base: ctx,
+		timeout: cfg.LedgerTimeout, trackBalances: cfg.TrackBalances,
+		computeFunded: cfg.ComputeFunded, lag: lag,
+		emitEvents: cfg.EmitEvents, outboxLimit: cfg.OutboxLimit}
+	if st != nil {
+		pipe.st = st
+		cacheGauges := map[string][2]*metrics.Gauge{
+			"checkpoints": {
+				reg.Gauge("indexer_cache_checkpoint_hits_total", "checkpoint cache hits"),
+				reg.Gauge("indexer_cache_checkpoint_misses_total", "checkpoint cache misses"),
+			},
+			"offers": {
+				reg.Gauge("indexer_cache_offer_hits_total", "offer cache hits"),
+				reg.Gauge("indexer_cache_offer_misses_total", "offer cache misses"),
+			},
+		}
+		go func() {
+			ticker := time.NewTicker(15 * time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+				for name, cs := range st.CacheStats() {
+					cacheGauges[name][0].Set(float64(cs.Hits))
+					cacheGauges[name][1].Set(float64(cs.Misses))
+				}
+			}
+		}()
+	}
+
+	// Priority catch-up watches the accounts partners registered plus the
+	// configured issuer lists; the set is loaded once at startup, so a
+	// freshly mapped account joins the fast path on the next restart.
+	if cfg.PriorityCatchup {
+		if st == nil {
+			log.Fatal("indexer: -priority-catchup needs a database")
+		}
+		accounts, err := st.AllPartnerAccounts(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		pipe.priority = newPriorityCatchup(accounts,
+			append(append([]string{}, cfg.IssuerAllow...), cfg.WatchIssuers...))
+		log.Printf("indexer: priority catch-up armed for %d partner accounts", len(accounts))
+	}
+
+	// The compliance archive tails the primary by checkpoint from its own
+	// goroutine: a slow or dead archive only grows the forwarding lag, it
+	// never blocks ledger processing.
+	if st != nil && cfg.ArchiveDSN != "" {
+		arch, err := store.NewArchiver(st, cfg.DBDriver, cfg.ArchiveDSN)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer arch.Close()
+		archRows := reg.Counter("indexer_archive_rows_total", "rows forwarded to the archive database")
+		archFails := reg.Counter("indexer_archive_failures_total", "archive forwarding failures")
+		archLast := reg.Gauge("indexer_archive_last_ledger", "last ledger fully copied to the archive")
+		arch.OnProgress(func(rows int, lastLedger uint64) {
+			archRows.Add(float64(rows))
+			archLast.Set(float64(lastLedger))
+		})
+		arch.OnError(func(err error) {
+			archFails.Add(1)
+			log.Printf("indexer: archive: %v", err)
+		})
+		go arch.Run(ctx, 5*time.Second)
+	}
+
+	// The outbox dispatcher drains publishable events once their ledger
+	// is checkpointed. With -event-sink-url set the events go to the
+	// external bus; otherwise the built-in log emitter consumes the
+	// stream, which also keeps the outbox prunable.
+	if st != nil && cfg.EmitEvents {
+		disp := store.NewOutboxDispatcher(st)
+		if cfg.EventSinkURL != "" {
+			bus, err := eventsink.NewNATS(cfg.EventSinkURL, cfg.EventSubjectPrefix)
+			if err != nil {
+				log.Fatal(err)
+			}
+			go func() {
+				<-ctx.Done()
+				bus.Close()
+			}()
+			disp.Register("nats", bus.Publish)
+			log.Printf("indexer: publishing events to %s", cfg.EventSinkURL)
+		} else {
+			disp.Register("log", func(_ context.Context, ev *model.OutboxEvent) error {
+				log.Printf("indexer: event %s ledger %d: %s", ev.Topic, ev.LedgerIndex, ev.Payload)
+				return nil
+			})
+		}
+		sentCounter := reg.Counter("indexer_outbox_sent_total", "outbox events handed to emitters")
+		failCounter := reg.Counter("indexer_outbox_failures_total", "outbox dispatch failures")
+		pendingGauge := reg.Gauge("indexer_outbox_pending", "events waiting in the outbox")
+		disp.OnSent(func(emitter string, n int) {
+			sentCounter.Add(float64(n))
+			if pending, err := st.OutboxPending(ctx); err == nil {
+				pendingGauge.Set(float64(pending))
+			}
+		})
+		disp.OnError(func(err error) {
+			failCounter.Add(1)
+			log.Printf("indexer: outbox: %v", err)
+		})
+		go disp.Run(ctx, time.Second)
+	}
+
+	// cfgSnap is the live config: goroutines that honor reloadable
+	// settings read a whole snapshot from it, so a SIGHUP swap is atomic
+	// and nobody sees a half-applied config.
+	cfgSnap := &atomic.Pointer[config.Config]{}
+	cfgSnap.Store(&cfg)
+
+	// Old pool history decays to hourly resolution in the background; the
+	// recent window keeps every update for fine-grained depth charts. The
+	// window is re-read every pass so a reload can retune or pause it.
+	if st != nil {
+		go func() {
+			ticker := time.NewTicker(time.Hour)
+			defer ticker.Stop()
+			for {
+				after := cfgSnap.Load().PoolHistoryDownsampleAfter
+				if after <= 0 {
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+					}
+					continue
+				}
+				cutoff := xrpl.UnixToRippleTime(time.Now().Add(-after))
+				if n, err := st.PrunePoolHistory(ctx, cutoff); err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					log.Printf("indexer: pool history prune: %v", err)
+				} else if n > 0 {
+					log.Printf("indexer: pool history prune: %d rows thinned to hourly", n)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+	// In-process database upkeep for deployments without external cron;
+	// opt-in because managed Postgres often schedules its own.
+	if st != nil && cfg.MaintenanceInterval > 0 {
+		tasks := []maintenanceTask{
+			{name: "vacuum-analyze", run: st.VacuumAnalyze},
+		}
+		if cfg.MaintenanceAuditKeep > 0 {
+			tasks = append(tasks, maintenanceTask{name: "prune-connection-audit",
+				run: func(ctx context.Context) error {
+					n, err := st.PruneConnectionAudit(ctx, cfg.MaintenanceAuditKeep)
+					if err == nil && n > 0 {
+						log.Printf("indexer: maintenance: pruned %d connection-audit rows", n)
+					}
+					return err
+				}})
+		}
+		if cfg.OfferRetentionLedgers > 0 {
+			tasks = append(tasks, maintenanceTask{name: "prune-closed-offers",
+				run: func(ctx context.Context) error {
+					latest, err := st.LatestLedger(ctx)
+					if err != nil || latest <= int64(cfg.OfferRetentionLedgers) {
+						return err
+					}
+					n, err := st.DeleteOffersBefore(ctx, uint64(latest)-uint64(cfg.OfferRetentionLedgers), true)
+					if err == nil && n > 0 {
+						log.Printf("indexer: maintenance: pruned %d closed offers", n)
+					}
+					return err
+				}})
+		}
+		go newMaintenance(cfg.MaintenanceInterval, cfg.MaintenanceJitter, tasks).run(ctx)
+	}
+
+	// Validator agreement decays with time, so the scoring job recomputes
+	// the sliding window on a schedule rather than per ledger.
+	if st != nil && cfg.ValidatorScoreInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(cfg.ValidatorScoreInterval)
+			defer ticker.Stop()
+			for {
+				end := xrpl.UnixToRippleTime(time.Now())
+				start := end - int64(cfg.ValidatorScoreWindow/time.Second)
+				if err := st.ComputeValidatorScores(ctx, start, end); err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					log.Printf("indexer: validator scoring: %v", err)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+	if cfg.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", reg)
+		mux.HandleFunc("/v1/fees", func(w http.ResponseWriter, r *http.Request) {
+			if st == nil {
+				http.Error(w, "no database configured", http.StatusServiceUnavailable)
+				return
+			}
+			fee, err := st.GetLatestNetworkFee(r.Context())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if fee == nil {
+				http.Error(w, "no fee sample recorded yet", http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(fee)
+		})
+		mux.HandleFunc("/v1/fees/history", func(w http.ResponseWriter, r *http.Request) {
+			from, _ := strconv.ParseUint(r.URL.Query().Get("from"), 10, 64)
+			to, _ := strconv.ParseUint(r.URL.Query().Get("to"), 10, 64)
+			fees := []*xrpl.FeeInfo{}
+			if st != nil {
+				list, err := st.GetNetworkFeeHistory(r.Context(), from, to, 0)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				if list != nil {
+					fees = list
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(fees)
+		})
+		mux.HandleFunc("/v1/validators", func(w http.ResponseWriter, r *http.Request) {
+			scores := []*model.ValidatorScore{}
+			if st != nil {
+				list, err := st.GetValidatorScores(r.Context())
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				if list != nil {
+					scores = list
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(scores)
+		})
+		mux.HandleFunc("/v1/amendments", func(w http.ResponseWriter, r *http.Request) {
+			amendments := []*model.Amendment{}
+			if st != nil {
+				list, err := st.ListAmendments(r.Context())
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				if list != nil {
+					amendments = list
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(amendments)
+		})
+		mux.HandleFunc("/v1/accounts/", func(w http.ResponseWriter, r *http.Request) {
+			account, tail, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/accounts/"), "/")
+			if !ok || account == "" || tail != "balance-changes" {
+				http.NotFound(w, r)
+				return
+			}
+			records := []*balancechanges.Record{}
+			if st != nil {
+				list, err := st.GetBalanceChanges(r.Context(), account, 0, 0, 0)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				if list != nil {
+					records = list
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(records)
+		})
+		mux.HandleFunc("/v1/nft/offers", func(w http.ResponseWriter, r *http.Request) {
+			offers := []*model.NFTOffer{}
+			if st != nil {
+				list, err := st.GetNFTOffers(r.Context(), r.URL.Query().Get("owner"), r.URL.Query().Get("token"), 0, 0)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				if list != nil {
+					offers = list
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(offers)
+		})
+		mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+			ready := map[string]any{"status": "ok", "network": cfg.Network}
+			if st != nil {
+				if latest, err := st.LatestLedger(r.Context()); err == nil {
+					ready["latest_ledger"] = latest
+				}
+			}
+			ready["lag_ledgers"] = lag.count()
+			ready["lag_oldest_seconds"] = lag.oldestAge().Seconds()
+			if lag.inCatchup() {
+				ready["catchup"] = true
+			}
+			if cfg.Live {
+				ready["mode"] = string(mode.mode())
+			}
+			w.Header().Set("Content-Type", "application/json")
+			switch {
+			case !wd.healthy():
+				ready["status"] = "stalled"
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case cfg.Live && mode.mode() != modeLive:
+				// Ready means "safe to route reads here": an indexer that
+				// is catching up, reconnecting, or degraded serves stale
+				// data, so the probe says no until the mode clears.
+				ready["status"] = string(mode.mode())
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			json.NewEncoder(w).Encode(ready)
+		})
+		go func() {
+			if err := http.ListenAndServe(cfg.MetricsAddr, mux); err != nil {
+				log.Printf("indexer: metrics server: %v", err)
+			}
+		}()
+	}
+	// pprof gets its own listener instead of riding the metrics mux so
+	// the scrape endpoint can stay exposed while profiling stays private.
+	if cfg.PprofAddr != "" {
+		if host, _, err := net.SplitHostPort(cfg.PprofAddr); err != nil || (host != "localhost" && host != "127.0.0.1" && host != "::1") {
+			log.Printf("indexer: pprof on %s is not localhost-only; profiles expose internals, make sure a firewall covers it", cfg.PprofAddr)
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		go func() {
+			log.Printf("indexer: pprof listening on %s", cfg.PprofAddr)
+			if err := http.ListenAndServe(cfg.PprofAddr, mux); err != nil {
+				log.Printf("indexer: pprof server: %v", err)
+			}
+		}()
+	}
+	// The Partner API is the consumer-facing read surface; it runs beside
+	// ingestion so partners never need database credentials.
+	var keyAuth *api.KeyAuth
+	if cfg.PartnerAPIAddr != "" {
+		if st == nil {
+			log.Print("indexer: partner API disabled: no database configured")
+		} else {
+			apiSrv := api.NewServer(st)
+			pipe.stream = apiSrv.Stream()
+			// The key middleware always wraps the API so /admin/ stays
+			// behind the admin scope; without -partner-api-auth the public
+			// market data endpoints skip the key requirement.
+			keyAuth = api.RequireKey(st, cfg.PartnerAPIRateLimit, apiSrv)
+			if !cfg.PartnerAPIAuth {
+				keyAuth.AllowAnonymousPublic()
+			}
+			var srv http.Handler = keyAuth
+			go func() {
+				log.Printf("indexer: partner API listening on %s", cfg.PartnerAPIAddr)
+				if err := http.ListenAndServe(cfg.PartnerAPIAddr, srv); err != nil {
+					log.Printf("indexer: partner API: %v", err)
+				}
+			}()
+		}
+	}
+	// The RPC API serves the same read surface as typed Go values for
+	// internal services; see package rpcapi.
+	if cfg.RPCAddr != "" {
+		if st == nil {
+			log.Print("indexer: rpc API disabled: no database configured")
+		} else {
+			ln, err := net.Listen("tcp", cfg.RPCAddr)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if cfg.RPCCert != "" {
+				tlsConf, err := rpcapi.ServerTLS(cfg.RPCCert, cfg.RPCKey, cfg.RPCClientCA)
+				if err != nil {
+					log.Fatal(err)
+				}
+				ln = tls.NewListener(ln, tlsConf)
+			}
+			go func() {
+				log.Printf("indexer: rpc API listening on %s", cfg.RPCAddr)
+				if err := rpcapi.NewServer(st).Serve(ctx, ln); err != nil {
+					log.Printf("indexer: rpc API: %v", err)
+				}
+			}()
+		}
+	}
+
+	// SIGHUP re-reads the config file and applies the reloadable subset
+	// — issuer filters, watchdog thresholds, retention, the Partner API
+	// default rate limit — through one atomic snapshot swap. Immutable
+	// settings are refused with their current values kept; a restart (and
+	// the backfill that follows it) stays reserved for those.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			next := config.Default()
+			if cfg.ConfigFile != "" {
+				if err := next.LoadFile(cfg.ConfigFile); err != nil {
+					log.Printf("indexer: SIGHUP: config reload failed, keeping the current config: %v", err)
+					continue
+				}
+			}
+			cur := cfgSnap.Load()
+			merged, changed, rejected := cur.ApplyReloadable(next)
+			for _, key := range rejected {
+				log.Printf("indexer: SIGHUP: %s cannot change at runtime, keeping the current value", key)
+			}
+			if len(changed) == 0 {
+				log.Print("indexer: SIGHUP: no reloadable settings changed")
+				continue
+			}
+			if err := merged.Validate(); err != nil {
+				log.Printf("indexer: SIGHUP: rejecting reload: %v", err)
+				continue
+			}
+			filter.Update(merged.IssuerAllow, merged.IssuerDeny, merged.WatchIssuers)
+			wd.setThresholds(merged.WatchdogWarnAfter, merged.WatchdogActAfter)
+			if keyAuth != nil {
+				keyAuth.SetDefaultLimit(merged.PartnerAPIRateLimit)
+			}
+			cfgSnap.Store(&merged)
+			for _, line := range changed {
+				log.Printf("indexer: SIGHUP: %s", line)
+			}
+		}
+	}()
+
+	client := xrpl.NewClient(cfg.RippledURL)
+	client.SetTimeout(cfg.RequestTimeout)
+
+	// Refuse to mix chains: the connected server must report the id of
+	// the configured network before anything is written.
+	si, err := client.GetServerInfo(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		log.Fatalf("indexer: cannot verify network against %s: %v", cfg.RippledURL, err)
+	}
+	if si.NetworkID != cfg.ExpectedNetworkID() {
+		log.Fatalf("indexer: server reports network_id %d, configured network %s expects %d",
+			si.NetworkID, cfg.Network, cfg.ExpectedNetworkID())
+	}
+	log.Printf("indexer: connected to %s (%s %s, network %s id %d, ledgers %s)",
+		cfg.RippledURL, si.ServerType(), si.Version(), cfg.Network, si.NetworkID, si.CompleteLedgers)
+
+	// Issuer and validator identities go stale slowly, so enrichment
+	// runs on its own schedule: a periodic sweep over whatever subjects
+	// the store says are due, a few domain fetches at a time.
+	if st != nil && cfg.IdentityRefreshInterval > 0 {
+		enricher := newIdentityEnricher(st, client, cfg.IdentityRefreshInterval)
+		go func() {
+			ticker := time.NewTicker(identitySweepInterval)
+			defer ticker.Stop()
+			for {
+				if err := enricher.sweep(ctx); err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					log.Printf("indexer: identity: %v", err)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+	if st != nil {
+		if err := st.RecordConnection(ctx, cfg.RippledURL, si.ServerType(), si.Version(), si.NetworkID); err != nil {
+			log.Print(err)
+		}
+	}
+
+	if st != nil && cfg.DiscoverMarkets {
+		disc := newMarketDiscovery(st, cfg.DiscoverOfferThreshold, cfg.DiscoverVolumeThreshold,
+			cfg.DiscoverWindow, cfg.DiscoverDemoteAfter)
+		disc.onPromote = func(pair string, ledgerIndex uint64) {
+			// The seed is best-effort: a failed snapshot costs depth
+			// until the stream refills the book, never the ledger.
+			if err := snapshotBook(ctx, client, st, pair, ledgerIndex); err != nil {
+				log.Printf("indexer: %v", err)
+			}
+		}
+		pipe.discovery = disc
+	}
+
+	// The cold-start snapshot restores the discovery window and the store
+	// caches before the first ledger arrives; once armed, the pipeline
+	// captures a fresh one every -snapshot-every ledgers.
+	if st != nil && cfg.SnapshotDir != "" {
+		if err := os.MkdirAll(cfg.SnapshotDir, 0o755); err != nil {
+			log.Fatal(err)
+		}
+		snap := newSnapshotter(cfg.SnapshotDir, uint64(cfg.SnapshotEvery), cfg.SnapshotKeep, st, pipe.discovery)
+		snap.restore(ctx)
+		pipe.snap = snap
+	}
+
+	fp := newFeePoller(reg, cfg.FeePollInterval, cfg.FeeSpikeMultiple, client.GetFee)
+	if st != nil {
+		fp.record = st.InsertNetworkFee
+	}
+	go fp.run(ctx)
+
+	// Seed amendment history from the admin feature command when the
+	// server allows it; activations seen later on the stream refine the
+	// seeded rows with their real activation ledgers.
+	if st != nil {
+		if features, err := client.Features(ctx); err != nil {
+			log.Printf("indexer: feature command unavailable, skipping amendment seed: %v", err)
+		} else {
+			for hash, f := range features {
+				if !f.Enabled {
+					continue
+				}
+				name := f.Name
+				if name == "" {
+					name = parser.AmendmentName(hash)
+				}
+				a := &model.Amendment{Hash: strings.ToUpper(hash), Name: name}
+				if err := st.UpsertAmendment(ctx, a); err != nil {
+					log.Print(err)
+				}
+			}
+		}
+	}
+	if wantBackfill && cfg.BackfillFrom < cfg.StartLedger() {
+		log.Printf("indexer: WARNING: backfill start %d predates %s's first useful ledger %d",
+			cfg.BackfillFrom, cfg.Network, cfg.StartLedger())
+	}
+
+	if wantBackfill {
+		// Plan around the server's actual history so missing ranges
+		// become recorded gaps instead of per-ledger retry storms.
+		runs := []xrpl.LedgerRange{{From: cfg.BackfillFrom, To: cfg.BackfillTo}}
+		if available, err := si.Ranges(); err != nil {
+			log.Fatal(err)
+		} else {
+			if earliest := available.Earliest(); cfg.BackfillFrom < earliest {
+				log.Printf("indexer: WARNING: backfill start %d predates the server's earliest complete ledger %d",
+					cfg.BackfillFrom, earliest)
+			}
+			var gaps []xrpl.LedgerRange
+			runs, gaps = backfill.Plan(cfg.BackfillFrom, cfg.BackfillTo, available)
+			for _, gap := range gaps {
+				log.Printf("indexer: server has no history for %s, recording gap", gap)
+				if st != nil {
+					if err := st.RecordGap(ctx, gap.From, gap.To, "outside server complete_ledgers"); err != nil {
+						log.Print(err)
+					}
+				}
+			}
+		}
+
+		runner := backfill.New(backfill.Config{
+			MaxRetries:       cfg.BackfillMaxRetries,
+			RetryBase:        cfg.BackfillRetryBase,
+			MaxBackoff:       cfg.BackfillMaxBackoff,
+			OnFailure:        policy,
+			MaxRate:          cfg.BackfillMaxRate,
+			LiveP95:          liveDurations.p95,
+			LiveP95Threshold: liveP95Threshold,
+		}, client.FetchLedger, pipe.process)
+		rateGauge := reg.Gauge("indexer_backfill_rate", "current backfill budget in ledgers per second; 0 when unthrottled")
+		throttledGauge := reg.Gauge("indexer_backfill_throttled", "1 while live pressure holds backfill below its ceiling")
+		wasThrottled := false
+		runner.OnRate(func(rate float64, throttled bool) {
+			rateGauge.Set(rate)
+			if throttled {
+				throttledGauge.Set(1)
+			} else {
+				throttledGauge.Set(0)
+			}
+			// Throttle flips land on the connection audit trail so an
+			// incident review can line them up with live latency.
+			if st != nil && throttled != wasThrottled {
+				event := "throttled"
+				if !throttled {
+					event = "recovered"
+				}
+				if err := st.LogConnectionEvent(ctx, "backfill", event,
+					fmt.Sprintf("%.1f ledgers/s", rate)); err != nil {
+					log.Print(err)
+				}
+			}
+			wasThrottled = throttled
+		})
+		if st != nil {
+			runner.OnFailedLedger(func(index uint64, cause error) {
+				if err := st.RecordFailedLedger(ctx, index, cause); err != nil {
+					log.Print(err)
+				}
+			})
+		}
+		for _, run := range runs {
+			if err := runner.Run(ctx, run.From, run.To); err != nil {
+				log.Fatal(err)
+			}
+			log.Printf("indexer: %s", runner.Summary())
+		}
+	}
+
+	if cfg.Live {
+		// No connection yet: the mode starts at reconnecting and flips to
+		// live (or catching_up) once the first dial succeeds.
+		mode.setConnected(false)
+		endpoints := append([]string{cfg.RippledWS}, cfg.RippledWSFailover...)
+		var (
+			liveMu   sync.Mutex
+			liveSub  *xrpl.Subscriber
+			endpoint int
+			redial   bool
+		)
+		// The watchdog's reconnect and failover actions both kill the
+		// current connection; the ingest loop below notices and redials,
+		// with failover first advancing to the next endpoint. exit hands
+		// the problem to the orchestrator.
+		wd.setAction(func(age time.Duration) {
+			switch cfg.WatchdogAction {
+			case "exit":
+				log.Printf("indexer: watchdog: no ledger for %s, exiting for a restart", age.Round(time.Second))
+				os.Exit(2)
+			case "failover":
+				liveMu.Lock()
+				endpoint++
+				redial = true
+				sub := liveSub
+				liveMu.Unlock()
+				log.Printf("indexer: watchdog: no ledger for %s, failing over to the next endpoint", age.Round(time.Second))
+				if sub != nil {
+					sub.Close()
+				}
+			default: // reconnect
+				liveMu.Lock()
+				redial = true
+				sub := liveSub
+				liveMu.Unlock()
+				log.Printf("indexer: watchdog: no ledger for %s, reconnecting", age.Round(time.Second))
+				if sub != nil {
+					sub.Close()
+				}
+			}
+		})
+		go wd.run(ctx)
+
+		if pipe.discovery != nil && cfg.DiscoverSubscribeBooks {
+			seed := pipe.discovery.onPromote
+			pipe.discovery.onPromote = func(pair string, ledgerIndex uint64) {
+				seed(pair, ledgerIndex)
+				liveMu.Lock()
+				sub := liveSub
+				liveMu.Unlock()
+				if sub == nil {
+					return
+				}
+				// Book subscriptions belong to one connection and die
+				// with it; a market promoted while disconnected simply
+				// waits for its next threshold crossing.
+				if err := subscribeBooks(ctx, sub, pair); err != nil {
+					log.Printf("indexer: %v", err)
+				}
+			}
+		}
+
+		// Buffer gauges are sampled rather than event-driven: occupancy
+		// moves on every message, and a one-second sample is plenty to
+		// watch backpressure build. The high-water mark belongs to the
+		// current connection; a reconnect starts it over.
+		occGauge := reg.Gauge("indexer_stream_buffer_occupancy", "stream messages waiting in the live subscription buffer")
+		hwGauge := reg.Gauge("indexer_stream_buffer_high_water", "most stream messages ever waiting in the live subscription buffer")
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+				liveMu.Lock()
+				sub := liveSub
+				liveMu.Unlock()
+				if sub == nil {
+					continue
+				}
+				occupied, _ := sub.StreamBacklog()
+				occGauge.Set(float64(occupied))
+				hwGauge.Set(float64(sub.StreamHighWater()))
+			}
+		}()
+
+		fetch := func(index uint64) (*xrpl.Ledger, error) {
+			lg, err := client.FetchLedger(ctx, index)
+			if err == nil {
+				lag.arrived(index)
+			}
+			return lg, err
+		}
+		proc := func(lg *xrpl.Ledger) error {
+			if cfg.RecordFixtures != "" {
+				// A failed recording costs a fixture, never a ledger.
+				if err := recordFixture(cfg.RecordFixtures, lg); err != nil {
+					log.Printf("indexer: live: %v", err)
+				}
+			}
+			start := time.Now()
+			if err := pipe.process(lg); err != nil {
+				return err
+			}
+			liveDurations.observe(time.Since(start))
+			lag.processed(lg.Index)
+			wd.ledgerProcessed()
+			return nil
+		}
+		onGap := func(index uint64, cause error) {
+			if st == nil {
+				return
+			}
+			if err := st.RecordGap(ctx, index, index, cause.Error()); err != nil {
+				log.Print(err)
+			}
+		}
+		var onValidation func(*xrpl.ValidationMessage) error
+		if cfg.TrackValidations && st != nil {
+			forkCounter := reg.Counter("indexer_validation_forks_total", "ledger indexes where validators signed competing hashes")
+			forks := newForkDetector(func(index uint64, sides []*model.ValidationDisagreement) {
+				forkCounter.Add(1)
+				log.Printf("indexer: validators split on ledger %d: %d competing hashes, majority %s with %d votes",
+					index, len(sides), sides[0].LedgerHash, sides[0].Votes)
+				for _, side := range sides {
+					if err := st.RecordDisagreement(ctx, side); err != nil {
+						log.Print(err)
+					}
+				}
+			})
+			onValidation = func(v *xrpl.ValidationMessage) error {
+				// Partial validations assert nothing about the hash, so
+				// they carry no signal for agreement scoring.
+				if !v.Full {
+					return nil
+				}
+				// Prefer the master key rippled already resolved; the
+				// store falls back to its manifests table otherwise. A
+				// lost vote is logged, never fatal: validations are an
+				// overlay on ledger ingestion, not part of it.
+				key := v.PublicKey
+				if v.MasterKey != "" {
+					key = v.MasterKey
+				}
+				if err := st.InsertValidation(ctx, &model.Validation{
+					Validator:   key,
+					LedgerIndex: v.LedgerIndex,
+					LedgerHash:  v.LedgerHash,
+					SigningTime: v.SigningTime,
+				}); err != nil {
+					log.Print(err)
+				}
+				forks.observe(v)
+				return nil
+			}
+		}
+		for ctx.Err() == nil {
+			liveMu.Lock()
+			wsURL := endpoints[endpoint%len(endpoints)]
+			liveMu.Unlock()
+			sub, err := xrpl.DialSubscriberBuffered(wsURL, ingest.Streams(cfg.IngestMode, cfg.TrackValidations), cfg.StreamBuffer)
+			if err != nil {
+				liveMu.Lock()
+				wasRedial := redial
+				liveMu.Unlock()
+				if !wasRedial {
+					log.Fatal(err)
+				}
+				log.Printf("indexer: live: dial %s: %v, retrying", wsURL, err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(5 * time.Second):
+				}
+				continue
+			}
+			liveMu.Lock()
+			liveSub = sub
+			liveMu.Unlock()
+			go func() {
+				// Unblock the stream reader on shutdown.
+				<-ctx.Done()
+				sub.Close()
+			}()
+			log.Printf("indexer: live ingestion started in %s mode via %s", cfg.IngestMode, wsURL)
+			wd.resume()
+			mode.setConnected(true)
+			err = ingest.Run(cfg.IngestMode, sub, fetch, proc, onGap, onValidation)
+			mode.setConnected(false)
+			wd.pause()
+			sub.Close()
+			if ctx.Err() != nil {
+				return
+			}
+			liveMu.Lock()
+			wasRedial := redial
+			redial = false
+			liveMu.Unlock()
+			if !wasRedial {
+				log.Fatal(err)
+			}
+		}
+	}
+}
+
+// pipelineMetrics are the dimensioned activity counters. Label
+// cardinality is capped per counter (plus the "other" bucket) because
+// pairs, issuers, and pools are unbounded inputs; see the metrics
+// package for the folding rules.
+type pipelineMetrics struct {
+	ledgers        *metrics.Counter
+	offersByPair   *metrics.LabeledCounter
+	volumeByIssuer *metrics.LabeledCounter
+	poolUpdates    *metrics.LabeledCounter
+	handlerEvents  *metrics.LabeledCounter
+	invalidOffers  *metrics.LabeledCounter
+	filteredWrites *metrics.LabeledCounter
+	unknownCancels *metrics.Counter
+	closeAnomalies *metrics.LabeledCounter
+	txByType       *metrics.LabeledCounter
+	txByResult     *metrics.LabeledCounter
+	duplicateTxs   *metrics.Counter
+	txnMismatches  *metrics.Counter
+}
+
+func newPipelineMetrics(reg *metrics.Registry) *pipelineMetrics {
+	return &pipelineMetrics{
+		ledgers:        reg.Counter("indexer_ledgers_total", "ledgers processed"),
+		offersByPair:   reg.LabeledCounter("indexer_offers_by_pair_total", "offers created per trading pair", "pair", 50),
+		volumeByIssuer: reg.LabeledCounter("indexer_volume_by_issuer_total", "traded IOU volume per issuer", "issuer", 50),
+		poolUpdates:    reg.LabeledCounter("indexer_pool_updates_total", "state updates per AMM pool", "pool", 100),
+		handlerEvents:  reg.LabeledCounter("indexer_handler_events_total", "handler outcomes (matched/succeeded/failed)", "handler_outcome", 50),
+		invalidOffers:  reg.LabeledCounter("indexer_invalid_parse_total", "offers stored as invalid_parse per error reason", "reason", 20),
+		filteredWrites: reg.LabeledCounter("indexer_filtered_total", "writes skipped by the issuer filter", "issuer", 50),
+		unknownCancels: reg.Counter("indexer_offer_cancel_unknown_total", "OfferCancels for offers the store never held"),
+		closeAnomalies: reg.LabeledCounter("indexer_close_time_anomalies_total", "ledgers with implausible close times per anomaly kind", "kind", 5),
+		txByType:       reg.LabeledCounter("indexer_tx_by_type_total", "transactions seen per TransactionType", "type", 50),
+		txByResult:     reg.LabeledCounter("indexer_tx_by_result_total", "transactions seen per engine result", "result", 30),
+		duplicateTxs:   reg.Counter("indexer_duplicate_tx_total", "redelivered transactions suppressed by the dedupe guard"),
+		txnMismatches:  reg.Counter("indexer_ledger_txn_count_mismatch_total", "ledgers whose header transaction count disagrees with what arrived"),
+	}
+}
+
+// handler feeds the registry observer; the handler set is small, so name
+// and outcome fold into one label.
+func (m *pipelineMetrics) handler(name, outcome string) {
+	if m != nil {
+		m.handlerEvents.Add(name+":"+outcome, 1)
+	}
+}
+
+func (m *pipelineMetrics) closeTimeAnomaly(kind string) {
+	if m != nil {
+		m.closeAnomalies.Add(kind, 1)
+	}
+}
+
+func (m *pipelineMetrics) invalid(reason string) {
+	if m != nil {
+		m.invalidOffers.Add(reason, 1)
+	}
+}
+
+// unknownCancel counts an OfferCancel whose target offer the store never
+// held — typically one placed before the backfill cutoff. A steadily
+// growing count right after a cutoff start is the signal to backfill
+// further history; the first occurrence is logged so operators notice
+// without watching the counter.
+func (m *pipelineMetrics) unknownCancel() {
+	if m == nil {
+		return
+	}
+	if m.unknownCancels.Value() == 0 {
+		log.Printf("indexer: OfferCancel for an offer never indexed; coverage may start after the offer was placed (see indexer_offer_cancel_unknown_total)")
+	}
+	m.unknownCancels.Add(1)
+}
+
+func (m *pipelineMetrics) filtered(issuer string) {
+	if m != nil {
+		m.filteredWrites.Add(issuer, 1)
+	}
+}
+
+func (m *pipelineMetrics) tx(txType, result string) {
+	if m != nil {
+		m.txByType.Add(txType, 1)
+		m.txByResult.Add(result, 1)
+	}
+}
+
+// duplicateTx counts a transaction redelivered by a re-subscribe and
+// suppressed before any writes.
+func (m *pipelineMetrics) duplicateTx() {
+	if m != nil {
+		m.duplicateTxs.Add(1)
+	}
+}
+
+func (m *pipelineMetrics) txnCountMismatch() {
+	if m != nil {
+		m.txnMismatches.Add(1)
+	}
+}
+
+func (m *pipelineMetrics) ledger() {
+	if m != nil {
+		m.ledgers.Add(1)
+	}
+}
+
+func (m *pipelineMetrics) offer(o *model.Offer) {
+	if m != nil {
+		m.offersByPair.Add(model.PairKey(o.TakerGets, o.TakerPays), 1)
+	}
+}
+
+func (m *pipelineMetrics) trade(tr *model.Trade) {
+	if m == nil {
+		return
+	}
+	for _, leg := range []xrpl.Amount{tr.Gets, tr.Pays} {
+		if leg.IsXRP() {
+			continue
+		}
+		if v, err := strconv.ParseFloat(leg.Value, 64); err == nil {
+			m.volumeByIssuer.Add(leg.Issuer, v)
+		}
+	}
+}
+
+func (m *pipelineMetrics) pool(account string) {
+	if m != nil {
+		m.poolUpdates.Add(account, 1)
+	}
+}
+
+// sink is the slice of the store the pipeline writes to; tests substitute
+// a recording implementation. Every write takes the per-ledger context so
+// a stuck database cannot stall the pipeline past -ledger-timeout;
+// handlers see only the context-free parser.Sink subset, bound to the
+// ledger's context by metricSink.
+type sink interface {
+	UpsertOffer(context.Context, *model.Offer) error
+	CancelOffer(ctx context.Context, account string, sequence uint32, ledgerIndex uint64, closeTime int64, txHash string) (bool, error)
+	MarkOfferReplaced(ctx context.Context, account string, sequence, replacedBy uint32, ledgerIndex uint64, closeTime int64, txHash string) error
+	InsertTrade(context.Context, *model.Trade) error
+	UpdateOfferFill(context.Context, *model.Trade, int64) error
+	UpsertNFTOffer(context.Context, *model.NFTOffer) error
+	AcceptNFTOffer(ctx context.Context, offerIndex string, ledgerIndex uint64, closeTime int64, txHash string) error
+	CancelNFTOffer(ctx context.Context, offerIndex string, ledgerIndex uint64, closeTime int64, txHash string) error
+	InsertNFTTrade(context.Context, *model.NFTTrade) error
+	UpsertTrustLine(context.Context, *model.TrustLine) error
+	DeleteTrustLine(ctx context.Context, account, currency, issuer string, ledgerIndex uint64) error
+	UpsertPool(context.Context, *model.Pool) error
+	AdjustPoolReserve(context.Context, *model.PoolAdjustment) error
+	ApplyFeeChange(context.Context, *model.FeeChange) error
+	ApplyAssetFlag(context.Context, *model.AssetFlagChange) error
+	UpsertAmendment(context.Context, *model.Amendment) error
+	InvalidateIdentity(ctx context.Context, account string) error
+	IsPoolAccount(context.Context, string) bool
+	SaveCheckpoint(context.Context, *model.Checkpoint) error
+	SaveRawTransaction(context.Context, uint64, *xrpl.Transaction) error
+	ClearFailedLedger(context.Context, uint64) error
+	InsertBalanceChanges(ctx context.Context, ledgerIndex uint64, closeTime int64, txHash string, changes []balancechanges.BalanceChange) error
+	RefreshFundedOffers(ctx context.Context, owner, currency, issuer, balance string, ledgerIndex uint64) error
+	ApplyMarketStats(context.Context, *model.MarketStatsUpdate) error
+	AppendOutbox(ctx context.Context, ledgerIndex uint64, events []model.OutboxEvent, limit int) error
+}
+
+// pipeline runs parsed ledgers into the store. A nil store means dry-run:
+// parse and log only.
+type pipeline struct {
+	st     sink
+	m      *pipelineMetrics
+	reg    *parser.Registry
+	filter *parser.IssuerFilter
+
+	// stream, when set, tees offer, trade, and pool writes to the
+	// Partner API's WebSocket subscribers as each ledger is processed.
+	stream *api.Stream
+
+	// base is the context ledgers are processed under; nil means
+	// context.Background. timeout, when positive, bounds each ledger.
+	base    context.Context
+	timeout time.Duration
+
+	// trackBalances extracts per-account balance changes from each
+	// transaction's metadata into the balance_changes table.
+	trackBalances bool
+
+	// computeFunded refreshes the funded amounts of open offers whenever
+	// a ledger exposes their owner's balance in the offered asset.
+	computeFunded bool
+
+	// emitEvents writes each ledger's publishable events into the
+	// transactional outbox before the ledger checkpoints; outboxLimit is
+	// the backpressure bound handed to the store.
+	emitEvents  bool
+	outboxLimit int
+
+	// discovery, when set, watches each ledger's offer and trade
+	// activity and promotes busy pairs to tracked markets.
+	discovery *marketDiscovery
+
+	// lag, when set, drives catch-up mode: while the live backlog sits
+	// past its high-water mark, non-essential per-ledger work (currently
+	// balance-change extraction) is queued on deferred instead of running
+	// inline, and drained once the backlog clears.
+	lag      *lagTracker
+	deferred []*xrpl.Ledger
+
+	// prevCloseTime is the last processed ledger's close time, kept to
+	// flag ledgers whose clocks run backwards.
+	prevCloseTime int64
+
+	// seen suppresses transactions redelivered by a re-subscribe; see
+	// dedupe.go. Built lazily, like the registry.
+	seen *seenTxs
+
+	// priority, when set, turns catch-up mode into the two-phase scheme
+	// in priority.go: watched accounts' writes first, the full parse and
+	// the checkpoints once the backlog clears.
+	priority *priorityCatchup
+
+	// snap, when set, periodically serializes derived runtime state for a
+	// faster next start; see snapshot.go.
+	snap *snapshotter
+}
+
+// registry returns the handler registry, building the default set on
+// first use and wiring its outcomes into the metrics.
+func (p *pipeline) registry() *parser.Registry {
+	if p.reg == nil {
+		p.reg = parser.NewRegistry(parser.DefaultHandlers()...)
+		p.reg.SetObserver(p.m.handler)
+	}
+	return p.reg
+}
+
+// process runs a ledger's transactions through the handler registry and
+// persists the results, under a per-ledger deadline when -ledger-timeout
+// is set: one pathological ledger or a stuck database write fails that
+// ledger instead of stalling the pipeline indefinitely.
+func (p *pipeline) process(lg *xrpl.Ledger) error {
+	// Ledgers queued by priority catch-up replay before anything newer is
+	// processed; the drain re-enters process so every replayed ledger
+	// gets its own deadline, not a slice of this one's.
+	if p.priority != nil && !p.priority.draining && len(p.priority.queue) > 0 &&
+		(p.lag == nil || !p.lag.inCatchup()) {
+		if err := p.priorityDrain(); err != nil {
+			return err
+		}
+	}
+	ctx := p.base
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	err := p.processCtx(ctx, lg)
+	if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("pipeline: ledger %d exceeded ledger-timeout %s: %w", lg.Index, p.timeout, err)
+	}
+	return err
+}
+
+func (p *pipeline) processCtx(ctx context.Context, lg *xrpl.Ledger) error {
+	// Phase one of priority catch-up: while the backlog is deep, advance
+	// only the transactions partners are waiting on and queue the ledger
+	// for the full parse. Nothing below — anomaly checks, stats, the
+	// checkpoint — runs until phase two replays it.
+	if p.priority != nil && !p.priority.draining && p.st != nil &&
+		p.lag != nil && p.lag.inCatchup() {
+		return p.priorityScan(ctx, lg)
+	}
+	// A nonsensical close time is worth an alarm but never a gap in the
+	// data: the ledger is indexed as received.
+	if kind := xrpl.CloseTimeAnomaly(lg.CloseTime, p.prevCloseTime, time.Now()); kind != "" {
+		log.Printf("pipeline: ledger %d close time %d (%s) is %s; indexing anyway",
+			lg.Index, lg.CloseTime, xrpl.CloseTimeHuman(lg.CloseTime), kind)
+		p.m.closeTimeAnomaly(kind)
+	}
+	p.prevCloseTime = lg.CloseTime
+	// Reconcile against the header's own transaction count: fewer
+	// transactions than the header promised means a truncated or partial
+	// ledger. Zero means the source stated no count, so nothing to check.
+	if lg.TxnCount > 0 && lg.TxnCount != len(lg.Transactions) {
+		log.Printf("pipeline: ledger %d header says %d transactions, received %d",
+			lg.Index, lg.TxnCount, len(lg.Transactions))
+		p.m.txnCountMismatch()
+	}
+	clock := newStageClock()
+	if p.seen == nil {
+		p.seen = newSeenTxs()
+	}
+
+	deferring := p.lag != nil && p.lag.inCatchup()
+	if !deferring && len(p.deferred) > 0 {
+		if err := p.flushDeferred(ctx); err != nil {
+			return err
+		}
+	}
+	// Empty ledgers are normal — most carry no DEX activity at all. They
+	// must still advance the checkpoint so lag and gap tracking see them
+	// as indexed, so only the transaction loop is skipped.
+	if len(lg.Transactions) == 0 {
+		return p.finish(ctx, lg, 0, 0, clock, nil)
+	}
+	reg := p.registry()
+	ms := &metricSink{ctx: ctx, st: p.st, m: p.m, collectEvents: p.emitEvents && p.st != nil}
+	if p.discovery != nil {
+		ms.pairOffers = map[string]int{}
+	}
+	var dst parser.Sink = ms
+	if p.stream != nil {
+		dst = &streamTee{Sink: dst, stream: p.stream}
+	}
+	if p.filter != nil {
+		dst = parser.NewFilteredSink(dst, p.filter, p.m.filtered)
+	}
+	var errs []error
+	var funding map[fundingKey]string
+	stats := &model.TxStats{Types: map[string]int64{}, Results: map[string]int64{}}
+	for _, tx := range lg.Transactions {
+		// The composition counts are taken before any filtering or
+		// dispatch so they describe the ledger as received.
+		txType, result := tx.TransactionType, "unknown"
+		if txType == "" {
+			txType = "unknown"
+		}
+		if tx.Meta != nil && tx.Meta.TransactionResult != "" {
+			result = tx.Meta.TransactionResult
+		}
+		stats.Types[txType]++
+		stats.Results[result]++
+		p.m.tx(txType, result)
+		// A transaction processed in a recent ledger is a redelivery
+		// (a re-subscribe replaying ledgerClosed); its writes are
+		// already in, so it is counted and skipped rather than run
+		// again.
+		if p.seen.has(tx.Hash) {
+			p.m.duplicateTx()
+			continue
+		}
+		if p.st != nil {
+			start := time.Now()
+			err := p.st.SaveRawTransaction(ctx, lg.Index, tx)
+			clock.add("raw", start)
+			if err != nil {
+				return err
+			}
+		}
+		if p.trackBalances && p.st != nil && !deferring {
+			// An extraction failure is collected like a handler failure:
+			// the ledger fails and is retried, but the remaining
+			// transactions still get their turn.
+			start := time.Now()
+			changes, err := balancechanges.Extract(tx)
+			if err != nil {
+				errs = append(errs, err)
+				err = nil
+			} else {
+				err = p.st.InsertBalanceChanges(ctx, lg.Index, lg.CloseTime, tx.Hash, changes)
+			}
+			clock.add("balances", start)
+			if err != nil {
+				return err
+			}
+		}
+		if p.computeFunded && p.st != nil {
+			// Absolute balances ride along in the same metadata the
+			// delta extraction walks; the last observation in the
+			// ledger wins, so one refresh per touched owner/asset
+			// suffices.
+			start := time.Now()
+			balances, err := balancechanges.FinalBalances(tx)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			for _, b := range balances {
+				if funding == nil {
+					funding = map[fundingKey]string{}
+				}
+				funding[fundingKey{b.Account, b.Currency, b.Issuer}] = b.Balance
+			}
+			clock.add("funded", start)
+		}
+		// Dispatch isolates handler failures from each other; a failure
+		// still fails the ledger so it is retried, but only after every
+		// other handler had its turn.
+		start := time.Now()
+		err := reg.Dispatch(tx, lg, dst)
+		clock.add("dispatch", start)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	fundedStart := time.Now()
+	for key, balance := range funding {
+		// Only markets the issuer filter admits get the enrichment; XRP
+		// is never filtered.
+		if key.issuer != "" && p.filter != nil &&
+			p.filter.Blocked(xrpl.Amount{Currency: key.currency, Issuer: key.issuer}) != "" {
+			continue
+		}
+		if err := p.st.RefreshFundedOffers(ctx, key.account, key.currency, key.issuer, balance, lg.Index); err != nil {
+			return err
+		}
+	}
+	clock.add("funded", fundedStart)
+	if p.st != nil {
+		start := time.Now()
+		for _, u := range foldMarketStats(ms.fills, lg) {
+			if err := p.st.ApplyMarketStats(ctx, u); err != nil {
+				return err
+			}
+		}
+		clock.add("stats", start)
+	}
+	if p.discovery != nil {
+		start := time.Now()
+		if err := p.discovery.observe(ctx, lg, ms.pairOffers, ms.fills); err != nil {
+			return err
+		}
+		clock.add("discovery", start)
+	}
+	if p.emitEvents && p.st != nil {
+		// Events must be in the outbox before the checkpoint in finish:
+		// the dispatcher only releases events whose ledger is
+		// checkpointed, so a crash between the two re-appends on replay
+		// instead of emitting data nobody can read yet. Offers, then
+		// trades, then pools — a fixed order, so a replay regenerates
+		// the same (ledger, seq) numbering and converges on one copy.
+		events := make([]model.OutboxEvent, 0, len(ms.offerEvents)+len(ms.fills)+len(ms.poolEvents))
+		appendEvent := func(topic string, v any) error {
+			payload, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("pipeline: outbox %s payload in ledger %d: %w", topic, lg.Index, err)
+			}
+			events = append(events, model.OutboxEvent{Topic: topic, Payload: string(payload)})
+			return nil
+		}
+		for _, o := range ms.offerEvents {
+			if err := appendEvent("offer", o); err != nil {
+				return err
+			}
+		}
+		for _, t := range ms.fills {
+			if err := appendEvent("trade", t); err != nil {
+				return err
+			}
+		}
+		for _, pl := range ms.poolEvents {
+			if err := appendEvent("pool", pl); err != nil {
+				return err
+			}
+		}
+		start := time.Now()
+		if err := p.st.AppendOutbox(ctx, lg.Index, events, p.outboxLimit); err != nil {
+			return err
+		}
+		clock.add("outbox", start)
+	}
+	if deferring && p.trackBalances && p.st != nil {
+		p.deferred = append(p.deferred, lg)
+	}
+	if err := p.finish(ctx, lg, ms.offers, ms.trades, clock, stats); err != nil {
+		return err
+	}
+	// Only a checkpointed ledger feeds the redelivery guard: a failed
+	// ledger is retried, and the retry must not be mistaken for a
+	// duplicate.
+	hashes := make([]string, 0, len(lg.Transactions))
+	for _, tx := range lg.Transactions {
+		hashes = append(hashes, tx.Hash)
+	}
+	p.seen.observe(lg.Index, hashes)
+	return nil
+}
+
+// foldMarketStats reduces a ledger's fills to one hourly-bucket update
+// per market, so the rollup table sees a single guarded upsert per pair
+// per ledger — the unit the replay guard works at. Volume is the
+// base-side amount (the lexically first asset of the pair) and prices
+// are quote per base. Fills whose amounts don't parse are skipped; they
+// already failed louder on insert.
+func foldMarketStats(fills []*model.Trade, lg *xrpl.Ledger) []*model.MarketStatsUpdate {
+	folded := map[string]*marketFold{}
+	for _, t := range fills {
+		getsKey, paysKey := model.AssetKey(t.Gets), model.AssetKey(t.Pays)
+		if getsKey == paysKey {
+			continue
+		}
+		base, quote := t.Gets, t.Pays
+		if paysKey < getsKey {
+			base, quote = t.Pays, t.Gets
+		}
+		baseAmt, err := base.Rat()
+		if err != nil {
+			continue
+		}
+		pair := model.PairKey(t.Gets, t.Pays)
+		f := folded[pair]
+		if f == nil {
+			f = &marketFold{volume: new(big.Rat)}
+			folded[pair] = f
+		}
+		f.volume.Add(f.volume, baseAmt)
+		f.count++
+		if quoteAmt, err := quote.Rat(); err == nil && baseAmt.Sign() > 0 {
+			price := new(big.Rat).Quo(quoteAmt, baseAmt)
+			if f.high == nil || price.Cmp(f.high) > 0 {
+				f.high = price
+			}
+			if f.low == nil || price.Cmp(f.low) < 0 {
+				f.low = price
+			}
+			f.last = price
+		}
+	}
+	updates := make([]*model.MarketStatsUpdate, 0, len(folded))
+	for pair, f := range folded {
+		u := &model.MarketStatsUpdate{
+			Pair:        pair,
+			Bucket:      lg.CloseTime / 3600,
+			Volume:      model.RatDecimal(f.volume),
+			TradeCount:  f.count,
+			LedgerIndex: lg.Index,
+		}
+		if f.last != nil {
+			u.High = model.RatDecimal(f.high)
+			u.Low = model.RatDecimal(f.low)
+			u.Last = model.RatDecimal(f.last)
+		}
+		updates = append(updates, u)
+	}
+	return updates
+}
+
+// marketFold accumulates one market's fills while a ledger is folded.
+type marketFold struct {
+	volume          *big.Rat
+	high, low, last *big.Rat
+	count           int64
+}
+
+// fundingKey identifies one owner's balance in one asset; processCtx
+// folds a ledger's balance observations onto it so each touched
+// owner/asset pair triggers one funding refresh.
+type fundingKey struct {
+	account  string
+	currency string
+	issuer   string
+}
+
+// flushDeferred runs the balance-change extraction queued during
+// catch-up, attributing every row to the ledger it came from. The
+// inserts are idempotent upserts, so a crash mid-flush only repeats
+// work; an extraction failure is logged and skipped because its ledger
+// already committed and will not come around again.
+func (p *pipeline) flushDeferred(ctx context.Context) error {
+	for len(p.deferred) > 0 {
+		lg := p.deferred[0]
+		for _, tx := range lg.Transactions {
+			changes, err := balancechanges.Extract(tx)
+			if err != nil {
+				log.Printf("pipeline: deferred ledger %d tx %s: %v", lg.Index, tx.Hash, err)
+				continue
+			}
+			if err := p.st.InsertBalanceChanges(ctx, lg.Index, lg.CloseTime, tx.Hash, changes); err != nil {
+				return err
+			}
+		}
+		p.deferred = p.deferred[1:]
+	}
+	return nil
+}
+
+// metricSink hands handler writes to the store (or drops them in dry-run
+// mode) while feeding the activity counters and the per-ledger summary
+// counts. It carries the ledger's context so handler-triggered writes
+// honor the processing deadline without parser.Sink growing a context.
+type metricSink struct {
+	ctx context.Context
+	st  sink
+	m   *pipelineMetrics
+
+	offers, trades int
+
+	// fills keeps the ledger's trades so processCtx can fold them into
+	// the per-market statistics after the transaction loop. pairOffers,
+	// allocated only when market discovery is on, counts the ledger's
+	// offer writes per pair for the discovery window.
+	fills      []*model.Trade
+	pairOffers map[string]int
+
+	// collectEvents, set when the outbox is on, keeps the ledger's
+	// publishable offer and pool writes in arrival order; fills already
+	// ride along for the market fold.
+	collectEvents bool
+	offerEvents   []*model.Offer
+	poolEvents    []*model.Pool
+}
+
+func (ms *metricSink) UpsertOffer(o *model.Offer) error {
+	if o.Status == model.OfferStatusInvalidParse {
+		ms.m.invalid(o.ParseError)
+	} else {
+		ms.offers++
+		ms.m.offer(o)
+		if ms.pairOffers != nil {
+			ms.pairOffers[model.PairKey(o.TakerGets, o.TakerPays)]++
+		}
+		if ms.collectEvents {
+			ms.offerEvents = append(ms.offerEvents, o)
+		}
+	}
+	if ms.st == nil {
+		return nil
+	}
+	return ms.st.UpsertOffer(ms.ctx, o)
+}
+
+func (ms *metricSink) CancelOffer(account string, sequence uint32, ledgerIndex uint64, closeTime int64, txHash string) error {
+	if ms.st == nil {
+		return nil
+	}
+	cancelled, err := ms.st.CancelOffer(ms.ctx, account, sequence, ledgerIndex, closeTime, txHash)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		// The offer likely predates the indexed range — a coverage gap
+		// worth counting, not an error.
+		ms.m.unknownCancel()
+	}
+	return nil
+}
+
+func (ms *metricSink) MarkOfferReplaced(account string, sequence, replacedBy uint32, ledgerIndex uint64, closeTime int64, txHash string) error {
+	if ms.st == nil {
+		return nil
+	}
+	return ms.st.MarkOfferReplaced(ms.ctx, account, sequence, replacedBy, ledgerIndex, closeTime, txHash)
+}
+
+func (ms *metricSink) InsertTrade(t *model.Trade) error {
+	ms.trades++
+	ms.m.trade(t)
+	ms.fills = append(ms.fills, t)
+	if ms.st == nil {
+		return nil
+	}
+	return ms.st.InsertTrade(ms.ctx, t)
+}
+
+func (ms *metricSink) UpdateOfferFill(t *model.Trade, closeTime int64) error {
+	if ms.st == nil {
+		return nil
+	}
+	return ms.st.UpdateOfferFill(ms.ctx, t, closeTime)
+}
+
+func (ms *metricSink) UpsertNFTOffer(o *model.NFTOffer) error {
+	if ms.st == nil {
+		return nil
+	}
+	return ms.st.UpsertNFTOffer(ms.ctx, o)
+}
+
+func (ms *metricSink) AcceptNFTOffer(offerIndex string, ledgerIndex uint64, closeTime int64, txHash string) error {
+	if ms.st == nil {
+		return nil
+	}
+	return ms.st.AcceptNFTOffer(ms.ctx, offerIndex, ledgerIndex, closeTime, txHash)
+}
+
+func (ms *metricSink) CancelNFTOffer(offerIndex string, ledgerIndex uint64, closeTime int64, txHash string) error {
+	if ms.st == nil {
+		return nil
+	}
+	return ms.st.CancelNFTOffer(ms.ctx, offerIndex, ledgerIndex, closeTime, txHash)
+}
+
+func (ms *metricSink) InsertNFTTrade(t *model.NFTTrade) error {
+	if ms.st == nil {
+		return nil
+	}
+	return ms.st.InsertNFTTrade(ms.ctx, t)
+}
+
+func (ms *metricSink) UpsertTrustLine(l *model.TrustLine) error {
+	if ms.st == nil {
+		return nil
+	}
+	return ms.st.UpsertTrustLine(ms.ctx, l)
+}
+
+func (ms *metricSink) DeleteTrustLine(account, currency, issuer string, ledgerIndex uint64) error {
+	if ms.st == nil {
+		return nil
+	}
+	return ms.st.DeleteTrustLine(ms.ctx, account, currency, issuer, ledgerIndex)
+}
+
+func (ms *metricSink) UpsertPool(p *model.Pool) error {
+	ms.m.pool(p.Account)
+	if ms.collectEvents {
+		ms.poolEvents = append(ms.poolEvents, p)
+	}
+	if ms.st == nil {
+		return nil
+	}
+	return ms.st.UpsertPool(ms.ctx, p)
+}
+
+func (ms *metricSink) AdjustPoolReserve(adj *model.PoolAdjustment) error {
+	ms.m.pool(adj.Account)
+	if ms.st == nil {
+		return nil
+	}
+	return ms.st.AdjustPoolReserve(ms.ctx, adj)
+}
+
+func (ms *metricSink) ApplyFeeChange(fc *model.FeeChange) error {
+	ms.m.pool(fc.PoolAccount)
+	if ms.st == nil {
+		return nil
+	}
+	return ms.st.ApplyFeeChange(ms.ctx, fc)
+}
+
+func (ms *metricSink) ApplyAssetFlag(c *model.AssetFlagChange) error {
+	if ms.st == nil {
+		return nil
+	}
+	return ms.st.ApplyAssetFlag(ms.ctx, c)
+}
+
+func (ms *metricSink) UpsertAmendment(a *model.Amendment) error {
+	if ms.st == nil {
+		return nil
+	}
+	return ms.st.UpsertAmendment(ms.ctx, a)
+}
+
+func (ms *metricSink) InvalidateIdentity(account string) error {
+	if ms.st == nil {
+		return nil
+	}
+	return ms.st.InvalidateIdentity(ms.ctx, account)
+}
+
+func (ms *metricSink) IsPoolAccount(account string) bool {
+	if ms.st == nil {
+		return false
+	}
+	return ms.st.IsPoolAccount(ms.ctx, account)
+}
+
+// finish checkpoints a processed ledger and logs the outcome with the
+// clock's per-stage breakdown. The checkpoint row carries that
+// breakdown and the ledger's transaction composition; the stored
+// breakdown cannot include the checkpoint write that stores it, so that
+// stage appears only in the log line.
+func (p *pipeline) finish(ctx context.Context, lg *xrpl.Ledger, offers, trades int, clock *stageClock, stats *model.TxStats) error {
+	p.m.ledger()
+	if p.st != nil {
+		start := time.Now()
+		if err := p.st.SaveCheckpoint(ctx, &model.Checkpoint{
+			LedgerIndex: lg.Index,
+			LedgerHash:  lg.Hash,
+			CloseTime:   lg.CloseTime,
+			Timings:     clock.breakdown(),
+			TxStats:     stats,
+		}); err != nil {
+			return err
+		}
+		// A successful pass supersedes any earlier recorded failure for
+		// this ledger.
+		if err := p.st.ClearFailedLedger(ctx, lg.Index); err != nil {
+			return err
+		}
+		clock.add("checkpoint", start)
+	}
+	log.Printf("indexer: ledger %d: %d txs, %d offers, %d trades (%s)",
+		lg.Index, len(lg.Transactions), offers, trades, clock)
+	if p.snap != nil {
+		p.snap.maybe(lg)
+	}
+	return nil
+}
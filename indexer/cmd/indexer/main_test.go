@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/balancechanges"
+	"github.com/2pk03/lucendex-validator-verification/indexer/metrics"
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/store"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// fakeSink records pipeline writes.
+type fakeSink struct {
+	checkpoints []model.Checkpoint
+	cleared     []uint64
+	offers      int
+}
+
+func (f *fakeSink) UpsertOffer(context.Context, *model.Offer) error { f.offers++; return nil }
+func (f *fakeSink) CancelOffer(context.Context, string, uint32, uint64, int64, string) (bool, error) {
+	return true, nil
+}
+func (f *fakeSink) MarkOfferReplaced(context.Context, string, uint32, uint32, uint64, int64, string) error {
+	return nil
+}
+func (f *fakeSink) UpdateOfferFill(context.Context, *model.Trade, int64) error { return nil }
+func (f *fakeSink) InsertTrade(context.Context, *model.Trade) error            { return nil }
+func (f *fakeSink) UpsertNFTOffer(context.Context, *model.NFTOffer) error      { return nil }
+func (f *fakeSink) AcceptNFTOffer(context.Context, string, uint64, int64, string) error {
+	return nil
+}
+func (f *fakeSink) CancelNFTOffer(context.Context, string, uint64, int64, string) error {
+	return nil
+}
+func (f *fakeSink) InsertNFTTrade(context.Context, *model.NFTTrade) error   { return nil }
+func (f *fakeSink) UpsertTrustLine(context.Context, *model.TrustLine) error { return nil }
+func (f *fakeSink) DeleteTrustLine(context.Context, string, string, string, uint64) error {
+	return nil
+}
+func (f *fakeSink) UpsertPool(context.Context, *model.Pool) error                  { return nil }
+func (f *fakeSink) AdjustPoolReserve(context.Context, *model.PoolAdjustment) error { return nil }
+func (f *fakeSink) ApplyFeeChange(context.Context, *model.FeeChange) error         { return nil }
+func (f *fakeSink) ApplyAssetFlag(context.Context, *model.AssetFlagChange) error   { return nil }
+func (f *fakeSink) SaveCheckpoint(_ context.Context, cp *model.Checkpoint) error {
+	f.checkpoints = append(f.checkpoints, *cp)
+	return nil
+}
+func (f *fakeSink) ClearFailedLedger(_ context.Context, index uint64) error {
+	f.cleared = append(f.cleared, index)
+	return nil
+}
+func (f *fakeSink) SaveRawTransaction(context.Context, uint64, *xrpl.Transaction) error { return nil }
+func (f *fakeSink) UpsertAmendment(context.Context, *model.Amendment) error             { return nil }
+func (f *fakeSink) InvalidateIdentity(context.Context, string) error                    { return nil }
+func (f *fakeSink) InsertBalanceChanges(context.Context, uint64, int64, string, []balancechanges.BalanceChange) error {
+	return nil
+}
+func (f *fakeSink) RefreshFundedOffers(context.Context, string, string, string, string, uint64) error {
+	return nil
+}
+func (f *fakeSink) ApplyMarketStats(context.Context, *model.MarketStatsUpdate) error { return nil }
+func (f *fakeSink) AppendOutbox(context.Context, uint64, []model.OutboxEvent, int) error {
+	return nil
+}
+func (f *fakeSink) IsPoolAccount(context.Context, string) bool { return false }
+
+// stuckSink simulates a database write that only returns when the
+// per-ledger context expires.
+type stuckSink struct {
+	fakeSink
+}
+
+func (s *stuckSink) SaveCheckpoint(ctx context.Context, cp *model.Checkpoint) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestProcessEmptyLedgerStillCheckpoints(t *testing.T) {
+	sink := &fakeSink{}
+	pipe := &pipeline{st: sink}
+	lg := &xrpl.Ledger{Index: 90000040, Hash: "CAFE", CloseTime: 700000100}
+	if err := pipe.process(lg); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.checkpoints) != 1 {
+		t.Fatalf("checkpoints = %d, want 1", len(sink.checkpoints))
+	}
+	cp := sink.checkpoints[0]
+	if cp.LedgerIndex != 90000040 || cp.LedgerHash != "CAFE" || cp.CloseTime != 700000100 {
+		t.Errorf("checkpoint = %+v", cp)
+	}
+	if len(sink.cleared) != 1 || sink.cleared[0] != 90000040 {
+		t.Errorf("cleared = %v, want the processed ledger", sink.cleared)
+	}
+	if sink.offers != 0 {
+		t.Errorf("offers written for an empty ledger: %d", sink.offers)
+	}
+}
+
+func TestProcessNilStoreDryRun(t *testing.T) {
+	pipe := &pipeline{}
+	if err := pipe.process(&xrpl.Ledger{Index: 90000041}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcessLedgerTimeout(t *testing.T) {
+	pipe := &pipeline{st: &stuckSink{}, timeout: 20 * time.Millisecond}
+	start := time.Now()
+	err := pipe.process(&xrpl.Ledger{Index: 90000042})
+	if err == nil || !strings.Contains(err.Error(), "ledger-timeout") {
+		t.Fatalf("process error = %v, want ledger-timeout failure", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("timeout took %v to fire", elapsed)
+	}
+}
+
+func TestBuildLedgerReport(t *testing.T) {
+	lg := &xrpl.Ledger{Index: 90000060, Hash: "FEED", CloseTime: 700000200,
+		Transactions: []*xrpl.Transaction{{
+			Hash: "RPTTX", TransactionType: "OfferCreate",
+			Account: "rReport", Sequence: 8,
+			TakerGets: xrpl.Amount{Value: "1"},
+			TakerPays: xrpl.Amount{Currency: "USD", Issuer: "rI", Value: "2"},
+			Meta:      &xrpl.Meta{TransactionResult: "tesSUCCESS"},
+		}},
+	}
+	report := buildLedgerReport(lg)
+	if report.LedgerIndex != 90000060 || report.Transactions != 1 {
+		t.Fatalf("report header = %+v", report)
+	}
+	if len(report.Offers) != 1 || report.Offers[0].Account != "rReport" {
+		t.Fatalf("report offers = %+v", report.Offers)
+	}
+	// The JSON shape must be stable for diffing: no null slices.
+	raw, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), "null") {
+		t.Fatalf("report JSON carries nulls: %s", raw)
+	}
+}
+
+// The in-memory store must keep satisfying the pipeline's write surface.
+var _ sink = (*store.MemoryStore)(nil)
+
+func TestProcessLedgerAgainstMemoryStore(t *testing.T) {
+	m := store.NewMemoryStore()
+	pipe := &pipeline{st: m}
+	lg := &xrpl.Ledger{Index: 90000070, Hash: "MEMLG", CloseTime: 700000300,
+		Transactions: []*xrpl.Transaction{{
+			Hash: "MEMCREATE", TransactionType: "OfferCreate",
+			Account: "rMemMaker", Sequence: 11,
+			TakerGets: xrpl.Amount{Currency: "USD", Issuer: "rI", Value: "10"},
+			TakerPays: xrpl.Amount{Value: "5"},
+			Meta: &xrpl.Meta{TransactionResult: "tesSUCCESS", AffectedNodes: []xrpl.AffectedNode{{
+				Created: &xrpl.NodeData{
+					LedgerEntryType: "Offer",
+					NewFields: []byte(`{"Account":"rMemMaker","Sequence":11,
+						"TakerGets":{"currency":"USD","issuer":"rI","value":"10"},
+						"TakerPays":"5000000"}`),
+				},
+			}}},
+		}},
+	}
+	if err := pipe.process(lg); err != nil {
+		t.Fatal(err)
+	}
+	if offer, ok := m.GetOffer("rMemMaker", 11); !ok || offer.Status != model.OfferStatusOpen {
+		t.Fatalf("offer = %+v, %v", offer, ok)
+	}
+	cp, err := m.GetLastCheckpoint(context.Background())
+	if err != nil || cp == nil || cp.LedgerIndex != 90000070 {
+		t.Fatalf("checkpoint = %+v, %v", cp, err)
+	}
+}
+
+func TestComputeFundedRefreshesOffers(t *testing.T) {
+	m := store.NewMemoryStore()
+	pipe := &pipeline{st: m, computeFunded: true}
+	lg := &xrpl.Ledger{Index: 90000080, Hash: "FNDLG", CloseTime: 700000400,
+		Transactions: []*xrpl.Transaction{{
+			Hash: "FNDCREATE", TransactionType: "OfferCreate",
+			Account: "rFunded", Sequence: 12,
+			TakerGets: xrpl.Amount{Currency: "USD", Issuer: "rI", Value: "10"},
+			TakerPays: xrpl.Amount{Value: "5"},
+			Meta: &xrpl.Meta{TransactionResult: "tesSUCCESS", AffectedNodes: []xrpl.AffectedNode{
+				{Created: &xrpl.NodeData{
+					LedgerEntryType: "Offer",
+					NewFields: []byte(`{"Account":"rFunded","Sequence":12,
+						"TakerGets":{"currency":"USD","issuer":"rI","value":"10"},
+						"TakerPays":"5000000"}`),
+				}},
+				// The owner holds 4 USD against the issuer, so only 4 of
+				// the offered 10 are deliverable.
+				{Modified: &xrpl.NodeData{
+					LedgerEntryType: "RippleState",
+					FinalFields: []byte(`{
+						"Balance": {"currency":"USD","issuer":"rrrrrrrrrrrrrrrrrrrrBZbvji","value":"-4"},
+						"LowLimit": {"currency":"USD","issuer":"rI","value":"0"},
+						"HighLimit": {"currency":"USD","issuer":"rFunded","value":"100"}}`),
+					PreviousFields: []byte(`{"Balance": {"currency":"USD","issuer":"rrrrrrrrrrrrrrrrrrrrBZbvji","value":"-6"}}`),
+				}},
+			}},
+		}},
+	}
+	if err := pipe.process(lg); err != nil {
+		t.Fatal(err)
+	}
+	offer, ok := m.GetOffer("rFunded", 12)
+	if !ok || offer.FundedTakerGets != "4" || offer.Unfunded || offer.FundedCheckedLedger != 90000080 {
+		t.Fatalf("offer = %+v, %v", offer, ok)
+	}
+}
+
+// unknownCancelSink reports every cancel as touching no rows.
+type unknownCancelSink struct{ fakeSink }
+
+func (unknownCancelSink) CancelOffer(context.Context, string, uint32, uint64, int64, string) (bool, error) {
+	return false, nil
+}
+
+func TestCancelUnknownOfferCounted(t *testing.T) {
+	m := newPipelineMetrics(metrics.NewRegistry())
+
+	ms := &metricSink{ctx: context.Background(), st: &fakeSink{}, m: m}
+	if err := ms.CancelOffer("rKnown", 5, 90000900, 0, "CANCELKNOWN"); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.unknownCancels.Value(); got != 0 {
+		t.Fatalf("unknown cancels after a real cancel = %v", got)
+	}
+
+	ms = &metricSink{ctx: context.Background(), st: &unknownCancelSink{}, m: m}
+	if err := ms.CancelOffer("rUnknown", 6, 90000901, 0, "CANCELUNKNOWN"); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.unknownCancels.Value(); got != 1 {
+		t.Fatalf("unknown cancels = %v, want 1", got)
+	}
+}
+
+func TestTxStatsRecorded(t *testing.T) {
+	m := newPipelineMetrics(metrics.NewRegistry())
+	sink := &fakeSink{}
+	pipe := &pipeline{st: sink, m: m}
+	lg := &xrpl.Ledger{Index: 90000095, Hash: "MIXED", CloseTime: 700000500,
+		Transactions: []*xrpl.Transaction{
+			{Hash: "MIX1", TransactionType: "Payment", Meta: &xrpl.Meta{TransactionResult: "tesSUCCESS"}},
+			{Hash: "MIX2", TransactionType: "Payment", Meta: &xrpl.Meta{TransactionResult: "tecPATH_DRY"}},
+			{Hash: "MIX3", TransactionType: "OfferCreate", Meta: &xrpl.Meta{TransactionResult: "tesSUCCESS"}},
+			// A transaction without metadata still counts, under "unknown".
+			{Hash: "MIX4", TransactionType: "TrustSet"},
+		},
+	}
+	if err := pipe.process(lg); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.checkpoints) != 1 || sink.checkpoints[0].TxStats == nil {
+		t.Fatalf("checkpoints = %+v, want one with TxStats", sink.checkpoints)
+	}
+	stats := sink.checkpoints[0].TxStats
+	if stats.Types["Payment"] != 2 || stats.Types["OfferCreate"] != 1 || stats.Types["TrustSet"] != 1 {
+		t.Errorf("type counts = %v", stats.Types)
+	}
+	if stats.Results["tesSUCCESS"] != 2 || stats.Results["tecPATH_DRY"] != 1 || stats.Results["unknown"] != 1 {
+		t.Errorf("result counts = %v", stats.Results)
+	}
+
+	if got := m.txByType.Value("Payment"); got != 2 {
+		t.Errorf("tx_by_type{Payment} = %v, want 2", got)
+	}
+	if got := m.txByResult.Value("tecPATH_DRY"); got != 1 {
+		t.Errorf("tx_by_result{tecPATH_DRY} = %v, want 1", got)
+	}
+
+	// An empty ledger records no composition, so its checkpoint stays
+	// NULL instead of carrying empty maps.
+	sink.checkpoints = nil
+	if err := pipe.process(&xrpl.Ledger{Index: 90000096, Hash: "EMPTY", CloseTime: 700000501}); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.checkpoints) != 1 || sink.checkpoints[0].TxStats != nil {
+		t.Errorf("empty-ledger checkpoint = %+v, want nil TxStats", sink.checkpoints)
+	}
+}
+
+func TestTxnCountMismatchFlagged(t *testing.T) {
+	m := newPipelineMetrics(metrics.NewRegistry())
+	pipe := &pipeline{st: &fakeSink{}, m: m}
+
+	// The header promised three transactions but only one arrived: a
+	// truncated ledger, flagged but still indexed.
+	lg := &xrpl.Ledger{Index: 90000097, Hash: "SHORT", CloseTime: 700000502, TxnCount: 3,
+		Transactions: []*xrpl.Transaction{
+			{Hash: "SHORT1", TransactionType: "Payment", Meta: &xrpl.Meta{TransactionResult: "tesSUCCESS"}},
+		},
+	}
+	if err := pipe.process(lg); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.txnMismatches.Value(); got != 1 {
+		t.Errorf("mismatch metric = %v, want 1", got)
+	}
+
+	// A count that agrees, and a ledger whose source stated none, both
+	// leave the metric alone.
+	lg = &xrpl.Ledger{Index: 90000098, Hash: "FULL", CloseTime: 700000503, TxnCount: 1,
+		Transactions: []*xrpl.Transaction{{Hash: "FULL1", TransactionType: "Payment"}}}
+	if err := pipe.process(lg); err != nil {
+		t.Fatal(err)
+	}
+	if err := pipe.process(&xrpl.Ledger{Index: 90000099, Hash: "NOHDR", CloseTime: 700000504}); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.txnMismatches.Value(); got != 1 {
+		t.Errorf("mismatch metric after clean ledgers = %v, want still 1", got)
+	}
+}
+
+func TestFoldMarketStats(t *testing.T) {
+	lg := &xrpl.Ledger{Index: 90000090, CloseTime: 700003700}
+	usd := xrpl.Amount{Currency: "USD", Issuer: "rI"}
+	// Two fills in opposite directions on the same market: both count
+	// toward the base (USD) side's volume, and prices land on the same
+	// orientation regardless of which way each fill went.
+	fills := []*model.Trade{
+		{Gets: xrpl.Amount{Value: "10", Currency: "XRP"}, Pays: amountWith(usd, "5")},
+		{Gets: amountWith(usd, "3"), Pays: xrpl.Amount{Value: "4", Currency: "XRP"}},
+	}
+	updates := foldMarketStats(fills, lg)
+	if len(updates) != 1 {
+		t.Fatalf("updates = %+v, want one market", updates)
+	}
+	u := updates[0]
+	if u.Pair != "USD.rI/XRP" {
+		t.Errorf("pair = %q", u.Pair)
+	}
+	if u.Volume != "8" || u.TradeCount != 2 {
+		t.Errorf("volume/count = %s/%d, want 8/2", u.Volume, u.TradeCount)
+	}
+	// 10 XRP for 5 USD is 2 XRP per USD; 3 USD for 4 XRP is 4/3.
+	if u.High != "2" || u.Low != "1.33333333333333333333" || u.Last != "1.33333333333333333333" {
+		t.Errorf("high/low/last = %s/%s/%s", u.High, u.Low, u.Last)
+	}
+	if u.Bucket != 700003700/3600 || u.LedgerIndex != 90000090 {
+		t.Errorf("bucket/ledger = %d/%d", u.Bucket, u.LedgerIndex)
+	}
+}
+
+func amountWith(a xrpl.Amount, value string) xrpl.Amount {
+	a.Value = value
+	return a
+}
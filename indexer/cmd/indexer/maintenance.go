@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// maintenanceTask is one named unit of background database upkeep.
+type maintenanceTask struct {
+	name string
+	run  func(context.Context) error
+}
+
+// maintenance runs its tasks sequentially on a jittered interval, an
+// in-process stand-in for external cron. The jitter keeps a fleet of
+// deployments started together from vacuuming in lockstep. A pass
+// already in flight makes the next trigger a no-op rather than queueing
+// behind it, so a slow VACUUM can never stack.
+type maintenance struct {
+	interval time.Duration
+	jitter   time.Duration
+	tasks    []maintenanceTask
+
+	running atomic.Bool
+}
+
+func newMaintenance(interval, jitter time.Duration, tasks []maintenanceTask) *maintenance {
+	return &maintenance{interval: interval, jitter: jitter, tasks: tasks}
+}
+
+func (m *maintenance) run(ctx context.Context) {
+	for {
+		wait := m.interval
+		if m.jitter > 0 {
+			wait += time.Duration(rand.Int63n(int64(m.jitter)))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		m.runOnce(ctx)
+	}
+}
+
+// runOnce executes one maintenance pass, reporting whether it ran;
+// false means another pass still holds the slot.
+func (m *maintenance) runOnce(ctx context.Context) bool {
+	if !m.running.CompareAndSwap(false, true) {
+		log.Print("indexer: maintenance: previous pass still running, skipping")
+		return false
+	}
+	defer m.running.Store(false)
+	for _, t := range m.tasks {
+		if ctx.Err() != nil {
+			return true
+		}
+		start := time.Now()
+		if err := t.run(ctx); err != nil {
+			if ctx.Err() != nil {
+				return true
+			}
+			log.Printf("indexer: maintenance: %s: %v", t.name, err)
+			continue
+		}
+		log.Printf("indexer: maintenance: %s done in %s", t.name, time.Since(start).Round(time.Millisecond))
+	}
+	return true
+}
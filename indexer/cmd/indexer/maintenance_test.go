@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceFiresOnSchedule(t *testing.T) {
+	var passes atomic.Int32
+	m := newMaintenance(time.Millisecond, 0, []maintenanceTask{
+		{name: "count", run: func(context.Context) error {
+			passes.Add(1)
+			return nil
+		}},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.run(ctx)
+		close(done)
+	}()
+
+	deadline := time.After(2 * time.Second)
+	for passes.Load() < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("only %d passes before the deadline", passes.Load())
+		case <-time.After(time.Millisecond):
+		}
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop on cancellation")
+	}
+}
+
+func TestMaintenanceSkipsOverlappingRuns(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	m := newMaintenance(time.Hour, 0, []maintenanceTask{
+		{name: "slow", run: func(context.Context) error {
+			started <- struct{}{}
+			<-release // closed after the overlap check; later passes sail through
+			return nil
+		}},
+	})
+	ctx := context.Background()
+	first := make(chan bool)
+	go func() { first <- m.runOnce(ctx) }()
+	<-started
+
+	// A trigger landing while the slow pass holds the slot is dropped.
+	if m.runOnce(ctx) {
+		t.Error("overlapping pass was not skipped")
+	}
+	close(release)
+	if !<-first {
+		t.Error("first pass reported skipped")
+	}
+	// With the slot free again the next trigger runs.
+	if !m.runOnce(ctx) {
+		t.Error("pass after release was skipped")
+	}
+}
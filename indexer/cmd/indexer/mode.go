@@ -0,0 +1,97 @@
+package main
+
+import (
+	"log"
+	"sync"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/metrics"
+)
+
+// indexerMode names what live ingestion is doing right now, for the
+// readiness probe and the logs.
+type indexerMode string
+
+const (
+	// modeLive: connected and within a few ledgers of the stream.
+	modeLive indexerMode = "live"
+	// modeCatchingUp: connected but working off a backlog; readiness
+	// serves 503 so load balancers stop routing reads here.
+	modeCatchingUp indexerMode = "catching_up"
+	// modeReconnecting: no healthy stream connection at the moment.
+	modeReconnecting indexerMode = "reconnecting"
+	// modeDegraded: connected but the watchdog stopped seeing ledgers.
+	modeDegraded indexerMode = "degraded"
+)
+
+// modeValue maps each mode onto the indexer_mode gauge: zero is good,
+// anything else is a reason the probe is unready.
+var modeValue = map[indexerMode]float64{
+	modeLive:         0,
+	modeCatchingUp:   1,
+	modeReconnecting: 2,
+	modeDegraded:     3,
+}
+
+// modeTracker derives the operational mode from three independent
+// signals — connection state, the watchdog's stall verdict, and the lag
+// tracker's catch-up flag — and logs every transition. It holds no
+// thresholds of its own: the hysteresis lives in the signals (catch-up
+// only clears below the low-water mark, the stall verdict only clears on
+// a processed ledger), so the mode cannot flap faster than they allow.
+type modeTracker struct {
+	mu                             sync.Mutex
+	disconnected, stalled, lagging bool
+	current                        indexerMode
+
+	gauge *metrics.Gauge
+}
+
+func newModeTracker() *modeTracker {
+	return &modeTracker{current: modeLive}
+}
+
+// bindMetrics publishes the mode as a gauge; see modeValue for the
+// encoding.
+func (m *modeTracker) bindMetrics(reg *metrics.Registry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gauge = reg.Gauge("indexer_mode", "operational mode (0 live, 1 catching up, 2 reconnecting, 3 degraded)")
+}
+
+// setConnected, setStalled and setLagging feed the three signals; each
+// may flip the derived mode.
+func (m *modeTracker) setConnected(v bool) { m.apply(func() { m.disconnected = !v }) }
+func (m *modeTracker) setStalled(v bool)   { m.apply(func() { m.stalled = v }) }
+func (m *modeTracker) setLagging(v bool)   { m.apply(func() { m.lagging = v }) }
+
+func (m *modeTracker) apply(set func()) {
+	m.mu.Lock()
+	set()
+	// Severity order: without a connection nothing else matters, a stall
+	// outranks a mere backlog, and only a clean board is live.
+	next := modeLive
+	switch {
+	case m.disconnected:
+		next = modeReconnecting
+	case m.stalled:
+		next = modeDegraded
+	case m.lagging:
+		next = modeCatchingUp
+	}
+	changed := next != m.current
+	m.current = next
+	if m.gauge != nil {
+		m.gauge.Set(modeValue[next])
+	}
+	m.mu.Unlock()
+	if changed {
+		log.Printf("indexer: mode is now %s", next)
+	}
+}
+
+// mode is the current derived mode.
+func (m *modeTracker) mode() indexerMode {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.current
+}
@@ -0,0 +1,85 @@
+package main
+
+import "testing"
+
+func TestModePriorities(t *testing.T) {
+	m := newModeTracker()
+	if m.mode() != modeLive {
+		t.Fatalf("initial mode = %s", m.mode())
+	}
+
+	m.setLagging(true)
+	if m.mode() != modeCatchingUp {
+		t.Errorf("lagging mode = %s, want catching_up", m.mode())
+	}
+	// A stall outranks the backlog, and a lost connection outranks both.
+	m.setStalled(true)
+	if m.mode() != modeDegraded {
+		t.Errorf("stalled mode = %s, want degraded", m.mode())
+	}
+	m.setConnected(false)
+	if m.mode() != modeReconnecting {
+		t.Errorf("disconnected mode = %s, want reconnecting", m.mode())
+	}
+
+	// Signals clear independently; the mode falls back down the ladder.
+	m.setConnected(true)
+	if m.mode() != modeDegraded {
+		t.Errorf("reconnected mode = %s, want degraded", m.mode())
+	}
+	m.setStalled(false)
+	if m.mode() != modeCatchingUp {
+		t.Errorf("unstalled mode = %s, want catching_up", m.mode())
+	}
+	m.setLagging(false)
+	if m.mode() != modeLive {
+		t.Errorf("caught-up mode = %s, want live", m.mode())
+	}
+}
+
+// The lag tracker's water marks drive the catch-up flag, so the mode
+// inherits their hysteresis: between the marks the mode keeps whatever
+// side it last crossed.
+func TestModeLagHysteresis(t *testing.T) {
+	m := newModeTracker()
+	lag := newLagTracker(5, 2)
+	lag.notify = m.setLagging
+
+	for i := uint64(0); i < 5; i++ {
+		lag.arrived(90000500 + i)
+	}
+	if m.mode() != modeCatchingUp {
+		t.Fatalf("mode at high water = %s, want catching_up", m.mode())
+	}
+	// Draining to between the marks must not flip back yet.
+	lag.processed(90000500)
+	lag.processed(90000501)
+	if m.mode() != modeCatchingUp {
+		t.Errorf("mode between marks = %s, want catching_up still", m.mode())
+	}
+	lag.processed(90000502)
+	if m.mode() != modeLive {
+		t.Errorf("mode at low water = %s, want live", m.mode())
+	}
+	// Climbing back between the marks must not flip again either.
+	lag.arrived(90000505)
+	if m.mode() != modeLive {
+		t.Errorf("mode after one arrival = %s, want live still", m.mode())
+	}
+}
+
+func TestModeWatchdogVerdict(t *testing.T) {
+	m := newModeTracker()
+	wd := newWatchdog(1, 0)
+	wd.notify = m.setStalled
+	wd.resume()
+
+	wd.check(wd.last.Add(2))
+	if m.mode() != modeDegraded {
+		t.Fatalf("mode after stall = %s, want degraded", m.mode())
+	}
+	wd.ledgerProcessed()
+	if m.mode() != modeLive {
+		t.Errorf("mode after recovery = %s, want live", m.mode())
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/store"
+)
+
+// partnerCmd manages which XRPL accounts a Partner API key may query:
+// "add-account" and "remove-account" edit the mapping, "accounts" lists
+// it. The mapping is consulted on every key validation, so changes take
+// effect on the partner's next request — no restart anywhere.
+func partnerCmd(args []string) {
+	if len(args) == 0 {
+		log.Fatal("indexer partner: want an action: add-account, remove-account or accounts")
+	}
+	action, args := args[0], args[1:]
+	fs := flag.NewFlagSet("indexer partner "+action, flag.ExitOnError)
+	var (
+		key     = fs.String("key", "", "the plaintext API key the mapping belongs to")
+		account = fs.String("account", "", "the XRPL account to map (add-account, remove-account)")
+	)
+	cfg := loadConfig(fs, args)
+
+	if cfg.DBDSN == "" {
+		log.Fatal("indexer partner: a database is required")
+	}
+	if *key == "" {
+		log.Fatal("indexer partner: -key is required")
+	}
+	ctx := context.Background()
+	st, err := store.Open(cfg.DBDriver, cfg.DBDSN)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer st.Close()
+	st.SetNetwork(cfg.Network)
+	if err := st.VerifyNetwork(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	switch action {
+	case "add-account":
+		if *account == "" {
+			log.Fatal("indexer partner add-account: -account is required")
+		}
+		if err := st.AddPartnerAccount(ctx, *key, *account); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("indexer partner: %s mapped", *account)
+	case "remove-account":
+		if *account == "" {
+			log.Fatal("indexer partner remove-account: -account is required")
+		}
+		if err := st.RemovePartnerAccount(ctx, *key, *account); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("indexer partner: %s unmapped", *account)
+	case "accounts":
+		accounts, err := st.ListPartnerAccounts(ctx, *key)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, a := range accounts {
+			fmt.Fprintln(os.Stdout, a)
+		}
+	default:
+		log.Fatalf("indexer partner: unknown action %q (want add-account, remove-account or accounts)", action)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/parser"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// priorityCatchup (-priority-catchup) front-runs a deep backlog for the
+// data partners are waiting on. While the lag tracker reports catch-up,
+// every ledger goes through two phases: phase one dispatches just the
+// transactions touching a watched account or issuer — their offers and
+// fills land immediately, marked provisional — and queues the ledger;
+// phase two, once the backlog clears, replays the queue through the full
+// parse in ledger order, which converges on the provisional rows (the
+// offer upsert and trade fill keys are the same), clears the marks and
+// writes each ledger's checkpoint for the first time. Because nothing
+// checkpoints in phase one, a crash mid-backlog resumes exactly as it
+// would have without the option.
+type priorityCatchup struct {
+	accounts map[string]bool
+	issuers  map[string]bool
+
+	// reg is a second handler registry without the metrics observer, so a
+	// transaction dispatched in both phases counts once.
+	reg *parser.Registry
+
+	queue    []*xrpl.Ledger
+	draining bool
+}
+
+func newPriorityCatchup(accounts, issuers []string) *priorityCatchup {
+	pc := &priorityCatchup{accounts: map[string]bool{}, issuers: map[string]bool{}}
+	for _, a := range accounts {
+		if a != "" {
+			pc.accounts[a] = true
+		}
+	}
+	for _, i := range issuers {
+		if i != "" {
+			pc.issuers[i] = true
+		}
+	}
+	return pc
+}
+
+// touches reports whether a transaction involves any watched account or
+// issuer. Beyond the sender and the amounts' issuers, the metadata names
+// every account whose ledger entries moved — including makers whose
+// resting offers the transaction consumed — so the raw node fields are
+// scanned for the quoted account. The scan deliberately over-matches: a
+// false positive costs one early dispatch that phase two reconciles
+// anyway, while a miss would defeat the point.
+func (pc *priorityCatchup) touches(tx *xrpl.Transaction) bool {
+	if pc.accounts[tx.Account] {
+		return true
+	}
+	for _, amt := range []xrpl.Amount{tx.TakerGets, tx.TakerPays, tx.Amount, tx.Amount2} {
+		if amt.Issuer != "" && pc.issuers[amt.Issuer] {
+			return true
+		}
+	}
+	if tx.Meta == nil || len(pc.accounts) == 0 {
+		return false
+	}
+	for _, n := range tx.Meta.AffectedNodes {
+		nd, _ := n.Node()
+		if nd == nil {
+			continue
+		}
+		for _, raw := range [][]byte{nd.NewFields, nd.FinalFields} {
+			for acct := range pc.accounts {
+				if bytes.Contains(raw, []byte(`"`+acct+`"`)) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// registry is the observer-less handler set phase one dispatches through.
+func (pc *priorityCatchup) registry() *parser.Registry {
+	if pc.reg == nil {
+		pc.reg = parser.NewRegistry(parser.DefaultHandlers()...)
+	}
+	return pc.reg
+}
+
+// provisionalSink is phase one's write surface: offers land marked
+// provisional and fills land under their natural key. Every other write —
+// incremental fill arithmetic, cancels, pool deltas, trust lines — waits
+// for phase two, which replays the ledger in full and must not find that
+// work half-applied.
+type provisionalSink struct {
+	parser.Sink // a store-less metricSink; everything not named below is a no-op
+	ctx         context.Context
+	st          sink
+}
+
+func (ps *provisionalSink) UpsertOffer(o *model.Offer) error {
+	o.Provisional = true
+	return ps.st.UpsertOffer(ps.ctx, o)
+}
+
+func (ps *provisionalSink) InsertTrade(t *model.Trade) error {
+	return ps.st.InsertTrade(ps.ctx, t)
+}
+
+// priorityScan is phase one for a single backlog ledger: dispatch the
+// watched transactions through the provisional sink and queue the ledger
+// for the phase-two replay. A dispatch failure fails the ledger so ingest
+// retries it, exactly like the full path.
+func (p *pipeline) priorityScan(ctx context.Context, lg *xrpl.Ledger) error {
+	var dst parser.Sink = &provisionalSink{Sink: &metricSink{ctx: ctx}, ctx: ctx, st: p.st}
+	if p.filter != nil {
+		dst = parser.NewFilteredSink(dst, p.filter, nil)
+	}
+	var errs []error
+	matched := 0
+	for _, tx := range lg.Transactions {
+		if !p.priority.touches(tx) {
+			continue
+		}
+		matched++
+		if err := p.priority.registry().Dispatch(tx, lg, dst); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	p.priority.queue = append(p.priority.queue, lg)
+	if matched > 0 {
+		log.Printf("pipeline: priority: ledger %d advanced %d watched transactions ahead of a %d-ledger backlog",
+			lg.Index, matched, len(p.priority.queue))
+	}
+	return nil
+}
+
+// priorityDrain is phase two: the queued backlog replayed through the
+// full parse in ledger order, each ledger under its own deadline. A
+// failed replay stays at the head of the queue and comes around again
+// with the next live ledger's retry.
+func (p *pipeline) priorityDrain() error {
+	p.priority.draining = true
+	defer func() { p.priority.draining = false }()
+	log.Printf("pipeline: priority: backlog cleared, replaying %d ledgers in full", len(p.priority.queue))
+	for len(p.priority.queue) > 0 {
+		if err := p.process(p.priority.queue[0]); err != nil {
+			return err
+		}
+		p.priority.queue = p.priority.queue[1:]
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/metrics"
+	"github.com/2pk03/lucendex-validator-verification/indexer/store"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// priorityTx builds an OfferCreate placing one offer, with the created
+// node in the metadata the way the stream delivers it.
+func priorityTx(index uint64, account string, seq uint32) *xrpl.Transaction {
+	return &xrpl.Transaction{
+		Hash: fmt.Sprintf("PRITX%d-%s", index, account), TransactionType: "OfferCreate",
+		Account: account, Sequence: seq,
+		TakerGets: xrpl.Amount{Value: "1000000"},
+		TakerPays: xrpl.Amount{Currency: "USD", Issuer: "rPriIssuer", Value: "1"},
+		Meta: &xrpl.Meta{TransactionResult: "tesSUCCESS", AffectedNodes: []xrpl.AffectedNode{
+			{Created: &xrpl.NodeData{
+				LedgerEntryType: "Offer",
+				NewFields: []byte(fmt.Sprintf(`{"Account":%q,"Sequence":%d,
+					"TakerGets":"1000000",
+					"TakerPays":{"currency":"USD","issuer":"rPriIssuer","value":"1"}}`, account, seq)),
+			}},
+		}},
+	}
+}
+
+func TestPriorityCatchupTouches(t *testing.T) {
+	pc := newPriorityCatchup([]string{"rWatchedPartner"}, []string{"rWatchedIssuer"})
+
+	if !pc.touches(priorityTx(1, "rWatchedPartner", 1)) {
+		t.Error("watched sender not matched")
+	}
+	if pc.touches(priorityTx(1, "rBystander", 1)) {
+		t.Error("unrelated transaction matched")
+	}
+	// A fill of the watched account's resting offer only names it in the
+	// metadata; the scan must still catch it.
+	tx := priorityTx(2, "rBystander", 2)
+	tx.Meta.AffectedNodes = append(tx.Meta.AffectedNodes, xrpl.AffectedNode{
+		Modified: &xrpl.NodeData{LedgerEntryType: "Offer",
+			FinalFields: []byte(`{"Account":"rWatchedPartner","Sequence":9}`)},
+	})
+	if !pc.touches(tx) {
+		t.Error("fill touching the watched maker not matched")
+	}
+	// A watched issuer on either leg is enough.
+	tx = priorityTx(3, "rBystander", 3)
+	tx.TakerPays.Issuer = "rWatchedIssuer"
+	if !pc.touches(tx) {
+		t.Error("watched issuer not matched")
+	}
+}
+
+func TestPriorityCatchupAdvancesWatchedOffers(t *testing.T) {
+	const n = 500
+	base := uint64(90009000)
+	m := store.NewMemoryStore()
+	lag := newLagTracker(10, 2)
+	pipe := &pipeline{st: m, m: newPipelineMetrics(metrics.NewRegistry()), lag: lag,
+		priority: newPriorityCatchup([]string{"rWatchedPartner"}, nil)}
+	ctx := context.Background()
+
+	// A reconnect dumps the whole backlog at once.
+	for i := uint64(0); i <= n; i++ {
+		lag.arrived(base + i)
+	}
+	if !lag.inCatchup() {
+		t.Fatal("backlog past the high-water mark but not in catch-up mode")
+	}
+
+	// The first backlog ledger carries the partner's offer beside an
+	// unrelated one.
+	first := &xrpl.Ledger{Index: base, Hash: "PRI0", CloseTime: 700001000,
+		Transactions: []*xrpl.Transaction{
+			priorityTx(base, "rWatchedPartner", 7),
+			priorityTx(base, "rBystander", 8),
+		}}
+	if err := pipe.process(first); err != nil {
+		t.Fatal(err)
+	}
+	lag.processed(base)
+
+	// The partner's offer is queryable with 500 ledgers still in the
+	// backlog, marked provisional; the bystander's is not, and nothing
+	// has checkpointed.
+	o, ok := m.GetOffer("rWatchedPartner", 7)
+	if !ok || !o.Provisional {
+		t.Fatalf("watched offer mid-backlog = %+v, %v; want a provisional row", o, ok)
+	}
+	if _, ok := m.GetOffer("rBystander", 8); ok {
+		t.Fatal("unrelated offer written during phase one")
+	}
+	if cp, err := m.GetLastCheckpoint(ctx); err != nil || cp != nil {
+		t.Fatalf("checkpoint mid-backlog = %+v, %v; want none", cp, err)
+	}
+
+	// Drain the rest of the backlog; catch-up ends near its tail and the
+	// phase-two replay runs before the next ledger.
+	for i := uint64(1); i <= n; i++ {
+		lg := &xrpl.Ledger{Index: base + i, Hash: fmt.Sprintf("PRI%d", i), CloseTime: 700001000 + int64(i)}
+		if err := pipe.process(lg); err != nil {
+			t.Fatalf("ledger %d: %v", lg.Index, err)
+		}
+		lag.processed(lg.Index)
+	}
+
+	// Phase two reconciled the provisional row, wrote the bystander's
+	// offer, and checkpointed every ledger in order.
+	o, ok = m.GetOffer("rWatchedPartner", 7)
+	if !ok || o.Provisional {
+		t.Fatalf("watched offer after the drain = %+v, %v; want the mark cleared", o, ok)
+	}
+	if _, ok := m.GetOffer("rBystander", 8); !ok {
+		t.Fatal("unrelated offer missing after the full parse")
+	}
+	cp, err := m.GetLastCheckpoint(ctx)
+	if err != nil || cp == nil || cp.LedgerIndex != base+n {
+		t.Fatalf("checkpoint after the drain = %+v, %v", cp, err)
+	}
+	if len(pipe.priority.queue) != 0 {
+		t.Errorf("%d ledgers still queued after the drain", len(pipe.priority.queue))
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testProofRecords() []proofRecord {
+	return []proofRecord{
+		{LedgerIndex: 90003000, LedgerHash: "PH0", CloseTime: 700002000, Offers: 3, Trades: 1},
+		{LedgerIndex: 90003001, LedgerHash: "PH1", CloseTime: 700002004, Pools: 2},
+		{LedgerIndex: 90003002, LedgerHash: "PH2", CloseTime: 700002008},
+	}
+}
+
+func TestProofRoundTrip(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := buildProof("testnet", 90003000, 90003002, testProofRecords(), priv, time.Unix(1_700_000_000, 0))
+	if err := checkProof(doc); err != nil {
+		t.Fatalf("fresh proof rejected: %v", err)
+	}
+	if doc.Header.Records != 3 || doc.Header.Fingerprint == "" {
+		t.Errorf("header = %+v", doc.Header)
+	}
+
+	// The document survives serialization, the way a partner receives it.
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back proofDocument
+	if err := json.Unmarshal(raw, &back); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkProof(&back); err != nil {
+		t.Fatalf("decoded proof rejected: %v", err)
+	}
+}
+
+func TestProofTamperDetection(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh := func() *proofDocument {
+		return buildProof("testnet", 90003000, 90003002, testProofRecords(), priv, time.Unix(1_700_000_000, 0))
+	}
+
+	doc := fresh()
+	doc.Records[1].Trades++
+	if err := checkProof(doc); err == nil || !strings.Contains(err.Error(), "chain breaks") {
+		t.Errorf("doctored count passed: %v", err)
+	}
+
+	// Re-chaining the doctored records without the key breaks the
+	// signature instead.
+	doc = fresh()
+	doc.Records[1].Offers++
+	var chain []byte
+	for i := range doc.Records {
+		chain = chainRecord(chain, &doc.Records[i])
+		doc.Records[i].Chain = "" // filled below
+	}
+	other := buildProof("testnet", 90003000, 90003002, doc.Records, priv, time.Unix(1_700_000_000, 0))
+	other.Signature = fresh().Signature
+	if err := checkProof(other); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Errorf("re-chained records passed with the old signature: %v", err)
+	}
+
+	doc = fresh()
+	doc.Header.To++
+	if err := checkProof(doc); err == nil {
+		t.Error("doctored header passed")
+	}
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	dir := t.TempDir()
+	seedPath := filepath.Join(dir, "seed.hex")
+	seed := strings.Repeat("ab", ed25519.SeedSize)
+	if err := os.WriteFile(seedPath, []byte(seed+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fromSeed, err := loadSigningKey(seedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The full private key form loads to the same key.
+	privPath := filepath.Join(dir, "priv.hex")
+	if err := os.WriteFile(privPath, []byte(strings.Repeat("ab", ed25519.SeedSize)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	again, err := loadSigningKey(privPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fromSeed.Equal(again) {
+		t.Error("same seed loaded to different keys")
+	}
+
+	badPath := filepath.Join(dir, "bad.hex")
+	if err := os.WriteFile(badPath, []byte("abcd"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadSigningKey(badPath); err == nil {
+		t.Error("short key accepted")
+	}
+	if err := os.WriteFile(badPath, []byte("not hex at all"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadSigningKey(badPath); err == nil {
+		t.Error("non-hex key accepted")
+	}
+}
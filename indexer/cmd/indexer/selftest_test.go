@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelftestSummary(t *testing.T) {
+	steps := []selftestStep{
+		{Name: "database", Detail: "connected via postgres", Elapsed: 12 * time.Millisecond},
+		{Name: "rippled", Err: errors.New("dial tcp: connection refused"), Elapsed: 103 * time.Millisecond},
+		{Name: "ledger fetch", Detail: "rippled unreachable", Skipped: true},
+	}
+	var buf bytes.Buffer
+	if printSelftest(&buf, steps, false) {
+		t.Error("printSelftest = true with a failed step")
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"ok   database            12ms  connected via postgres",
+		"FAIL rippled            103ms  dial tcp: connection refused",
+		"skip ledger fetch           -  rippled unreachable",
+		"self-test failed: 1 of 3 steps",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "\x1b[") {
+		t.Error("uncolored summary carries ANSI escapes")
+	}
+}
+
+func TestSelftestSummaryPassedAndColored(t *testing.T) {
+	steps := []selftestStep{
+		{Name: "database", Detail: "connected via postgres", Elapsed: 9 * time.Millisecond},
+		{Name: "schema", Detail: "all tables present", Elapsed: 4 * time.Millisecond},
+	}
+	var buf bytes.Buffer
+	if !printSelftest(&buf, steps, true) {
+		t.Error("printSelftest = false with every step green")
+	}
+	out := buf.String()
+	if !strings.Contains(out, "self-test passed (2 steps)") {
+		t.Errorf("missing verdict:\n%s", out)
+	}
+	if !strings.Contains(out, "\x1b[32mok  \x1b[0m") {
+		t.Errorf("colored summary missing green mark:\n%s", out)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+// Cold-start snapshots. Rebuilding derived runtime state after a restart
+// costs a burst of database queries — warming the checkpoint and offer
+// caches — and for the discovery window is not possible at all: it lives
+// in memory only, so a restart starts counting afresh. With -snapshot-dir
+// set the pipeline serializes that state to a compact gob file every
+// -snapshot-every ledgers, keeps the last -snapshot-keep files, and the
+// next start restores the newest snapshot whose ledger is still
+// checkpointed in the database under the same hash, falling back to the
+// usual cold reconstruction when none qualifies.
+//
+// The capture runs on the pipeline goroutine — the state is small and
+// must not be read while it mutates — but the file write happens on its
+// own goroutine, off the hot path. Writes go temp-then-rename with the
+// payload checksum embedded, so a crash mid-write leaves either the
+// previous snapshots or a file that fails verification, never a
+// half-readable one.
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/store"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// snapshotVersion is bumped whenever the payload layout changes; an old
+// file then fails decoding and is skipped like a corrupt one.
+const snapshotVersion = 1
+
+// snapshotStore is the slice of the store the snapshotter needs;
+// *store.Store and *store.MemoryStore both satisfy it.
+type snapshotStore interface {
+	GetCheckpoint(ctx context.Context, index uint64) (*model.Checkpoint, error)
+	SnapshotCaches() *store.CacheSnapshot
+	RestoreCaches(*store.CacheSnapshot)
+}
+
+// snapshotPayload is the state one snapshot file carries.
+type snapshotPayload struct {
+	LedgerIndex uint64
+	LedgerHash  string
+	TakenAt     time.Time
+	Discovery   discoveryState
+	Caches      *store.CacheSnapshot
+}
+
+// snapshotEnvelope wraps the pre-encoded payload with its checksum, so
+// the checksum covers exactly the bytes decoded later.
+type snapshotEnvelope struct {
+	Version  int
+	Checksum [sha256.Size]byte
+	Payload  []byte
+}
+
+type snapshotter struct {
+	dir   string
+	every uint64
+	keep  int
+	st    snapshotStore
+	disc  *marketDiscovery // may be nil when discovery is off
+
+	last    uint64      // ledger of the last snapshot taken (or restored)
+	writing atomic.Bool // one file write in flight at a time
+}
+
+func newSnapshotter(dir string, every uint64, keep int, st snapshotStore, disc *marketDiscovery) *snapshotter {
+	return &snapshotter{dir: dir, every: every, keep: keep, st: st, disc: disc}
+}
+
+// maybe captures a snapshot when the ledger is due and hands the bytes to
+// a background write. It runs on the pipeline goroutine after the
+// ledger's checkpoint landed; the first processed ledger only sets the
+// baseline. A capture due while the previous write is still in flight is
+// skipped — the next due ledger takes it instead.
+func (s *snapshotter) maybe(lg *xrpl.Ledger) {
+	if s.last == 0 {
+		s.last = lg.Index
+		return
+	}
+	if lg.Index < s.last+s.every {
+		return
+	}
+	if !s.writing.CompareAndSwap(false, true) {
+		return
+	}
+	payload, err := s.capture(lg)
+	if err != nil {
+		s.writing.Store(false)
+		log.Printf("indexer: snapshot: encoding ledger %d: %v", lg.Index, err)
+		return
+	}
+	s.last = lg.Index
+	go func() {
+		defer s.writing.Store(false)
+		if err := s.write(lg.Index, payload); err != nil {
+			log.Printf("indexer: snapshot: %v", err)
+		}
+	}()
+}
+
+// capture gob-encodes the current state; it is the only part of a
+// snapshot that reads pipeline-owned data, so it stays synchronous.
+func (s *snapshotter) capture(lg *xrpl.Ledger) ([]byte, error) {
+	p := snapshotPayload{
+		LedgerIndex: lg.Index,
+		LedgerHash:  lg.Hash,
+		TakenAt:     time.Now(),
+		Caches:      s.st.SnapshotCaches(),
+	}
+	if s.disc != nil {
+		p.Discovery = s.disc.snapshot()
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// write lands one snapshot file crash-safely and prunes old ones.
+func (s *snapshotter) write(index uint64, payload []byte) error {
+	env := snapshotEnvelope{
+		Version:  snapshotVersion,
+		Checksum: sha256.Sum256(payload),
+		Payload:  payload,
+	}
+	final := filepath.Join(s.dir, snapshotName(index))
+	tmp := final + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(&env); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, final); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return s.prune()
+}
+
+func snapshotName(index uint64) string {
+	return fmt.Sprintf("snapshot-%012d.gob", index)
+}
+
+// snapshotFiles lists the directory's snapshot files in ascending ledger
+// order, ignoring temp files and anything else.
+func (s *snapshotter) snapshotFiles() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, "snapshot-") && strings.HasSuffix(name, ".gob") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// prune removes all but the newest keep snapshots.
+func (s *snapshotter) prune() error {
+	names, err := s.snapshotFiles()
+	if err != nil {
+		return err
+	}
+	for len(names) > s.keep {
+		if err := os.Remove(filepath.Join(s.dir, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// restore loads the newest usable snapshot into the discovery window and
+// the store caches, newest first: a corrupt, outdated, or no longer
+// checkpointed file is skipped in favor of the next older one. It
+// reports whether anything was restored; false means the usual cold
+// start.
+func (s *snapshotter) restore(ctx context.Context) bool {
+	start := time.Now()
+	names, err := s.snapshotFiles()
+	if err != nil {
+		log.Printf("indexer: snapshot: reading %s: %v", s.dir, err)
+		return false
+	}
+	for i := len(names) - 1; i >= 0; i-- {
+		p, err := readSnapshot(filepath.Join(s.dir, names[i]))
+		if err != nil {
+			log.Printf("indexer: snapshot: skipping %s: %v", names[i], err)
+			continue
+		}
+		cp, err := s.st.GetCheckpoint(ctx, p.LedgerIndex)
+		if err != nil {
+			log.Printf("indexer: snapshot: skipping %s: %v", names[i], err)
+			continue
+		}
+		if cp == nil || cp.LedgerHash != p.LedgerHash {
+			log.Printf("indexer: snapshot: skipping %s: ledger %d no longer checkpointed under its hash",
+				names[i], p.LedgerIndex)
+			continue
+		}
+		if s.disc != nil {
+			s.disc.restore(p.Discovery)
+		}
+		s.st.RestoreCaches(p.Caches)
+		s.last = p.LedgerIndex
+		log.Printf("indexer: snapshot: restored state at ledger %d (taken %s) in %s",
+			p.LedgerIndex, p.TakenAt.Format(time.RFC3339), time.Since(start).Round(time.Millisecond))
+		return true
+	}
+	log.Print("indexer: snapshot: no usable snapshot, starting cold")
+	return false
+}
+
+// readSnapshot decodes and checksum-verifies one snapshot file.
+func readSnapshot(path string) (*snapshotPayload, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var env snapshotEnvelope
+	if err := gob.NewDecoder(f).Decode(&env); err != nil {
+		return nil, fmt.Errorf("decoding envelope: %w", err)
+	}
+	if env.Version != snapshotVersion {
+		return nil, fmt.Errorf("version %d, want %d", env.Version, snapshotVersion)
+	}
+	if sha256.Sum256(env.Payload) != env.Checksum {
+		return nil, fmt.Errorf("checksum mismatch")
+	}
+	var p snapshotPayload
+	if err := gob.NewDecoder(bytes.NewReader(env.Payload)).Decode(&p); err != nil {
+		return nil, fmt.Errorf("decoding payload: %w", err)
+	}
+	return &p, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/store"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func seededDiscovery() *marketDiscovery {
+	disc := newMarketDiscovery(nil, 20, 1000, time.Hour, 0)
+	disc.activity["XRP|USD/rSnapIssuer"] = []pairActivity{
+		{ledger: 90011000, closeTime: 700002000, offers: 5, volume: 120},
+		{ledger: 90011001, closeTime: 700002010, offers: 3, volume: 80},
+	}
+	disc.tracked["XRP|EUR/rSnapIssuer"] = true
+	disc.lastDemote = 700001000
+	return disc
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	m := store.NewMemoryStore()
+	ctx := context.Background()
+	lg := &xrpl.Ledger{Index: 90011002, Hash: "SNAPA", CloseTime: 700002020}
+	if err := m.SaveCheckpoint(ctx, &model.Checkpoint{
+		LedgerIndex: lg.Index, LedgerHash: lg.Hash, CloseTime: lg.CloseTime,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	disc := seededDiscovery()
+	snap := newSnapshotter(dir, 10, 3, m, disc)
+	payload, err := snap.capture(lg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := snap.write(lg.Index, payload); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh process restores the window it would otherwise have lost.
+	fresh := newMarketDiscovery(nil, 20, 1000, time.Hour, 0)
+	snap2 := newSnapshotter(dir, 10, 3, m, fresh)
+	if !snap2.restore(ctx) {
+		t.Fatal("restore found no usable snapshot")
+	}
+	if snap2.last != lg.Index {
+		t.Errorf("restored baseline = %d, want %d", snap2.last, lg.Index)
+	}
+	if !fresh.tracked["XRP|EUR/rSnapIssuer"] {
+		t.Error("tracked pair lost across the round trip")
+	}
+	if pts := fresh.activity["XRP|USD/rSnapIssuer"]; len(pts) != 2 || pts[1].volume != 80 {
+		t.Errorf("activity window after restore = %+v", pts)
+	}
+	if fresh.lastDemote != 700001000 {
+		t.Errorf("lastDemote after restore = %d", fresh.lastDemote)
+	}
+}
+
+func TestSnapshotRejectsStaleAndCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	m := store.NewMemoryStore()
+	ctx := context.Background()
+	lg := &xrpl.Ledger{Index: 90011010, Hash: "SNAPB", CloseTime: 700002100}
+	snap := newSnapshotter(dir, 10, 3, m, seededDiscovery())
+	payload, err := snap.capture(lg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := snap.write(lg.Index, payload); err != nil {
+		t.Fatal(err)
+	}
+
+	// The snapshot's ledger was never checkpointed (say, a rollback): the
+	// file is skipped rather than priming state the database disowned.
+	fresh := newMarketDiscovery(nil, 20, 1000, time.Hour, 0)
+	if newSnapshotter(dir, 10, 3, m, fresh).restore(ctx) {
+		t.Fatal("restored a snapshot with no matching checkpoint")
+	}
+	if len(fresh.tracked) != 0 {
+		t.Error("skipped snapshot still primed the discovery window")
+	}
+
+	// Same hash mismatch when the ledger was re-indexed from a fork.
+	if err := m.SaveCheckpoint(ctx, &model.Checkpoint{
+		LedgerIndex: lg.Index, LedgerHash: "FORKED", CloseTime: lg.CloseTime,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if newSnapshotter(dir, 10, 3, m, nil).restore(ctx) {
+		t.Fatal("restored a snapshot whose ledger hash changed")
+	}
+
+	// With the checkpoint matching, a flipped byte must fail the checksum.
+	if err := m.SaveCheckpoint(ctx, &model.Checkpoint{
+		LedgerIndex: lg.Index, LedgerHash: lg.Hash, CloseTime: lg.CloseTime,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, snapshotName(lg.Index))
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[len(raw)-1] ^= 0xff
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if newSnapshotter(dir, 10, 3, m, nil).restore(ctx) {
+		t.Fatal("restored a snapshot that fails its checksum")
+	}
+}
+
+func TestSnapshotPruneKeepsNewest(t *testing.T) {
+	dir := t.TempDir()
+	m := store.NewMemoryStore()
+	snap := newSnapshotter(dir, 10, 2, m, nil)
+	for i := uint64(0); i < 5; i++ {
+		lg := &xrpl.Ledger{Index: 90011100 + i*10, Hash: fmt.Sprintf("SNAPC%d", i)}
+		payload, err := snap.capture(lg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := snap.write(lg.Index, payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names, err := snap.snapshotFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{snapshotName(90011130), snapshotName(90011140)}
+	if len(names) != 2 || names[0] != want[0] || names[1] != want[1] {
+		t.Errorf("snapshots after pruning = %v, want %v", names, want)
+	}
+}
+
+func TestSnapshotCadence(t *testing.T) {
+	dir := t.TempDir()
+	m := store.NewMemoryStore()
+	snap := newSnapshotter(dir, 10, 3, m, nil)
+
+	// The first processed ledger only sets the baseline; the ledgers
+	// before the interval elapses write nothing.
+	for i := uint64(0); i < 10; i++ {
+		snap.maybe(&xrpl.Ledger{Index: 90011200 + i, Hash: "SNAPD"})
+	}
+	if names, _ := snap.snapshotFiles(); len(names) != 0 {
+		t.Fatalf("snapshots before the interval = %v", names)
+	}
+
+	// The tenth ledger past the baseline is due; the write is async, so
+	// poll for the file.
+	snap.maybe(&xrpl.Ledger{Index: 90011210, Hash: "SNAPD"})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if names, _ := snap.snapshotFiles(); len(names) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("due ledger never produced a snapshot file")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if snap.last != 90011210 {
+		t.Errorf("baseline after capture = %d, want 90011210", snap.last)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/store"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// statusReport is the one-shot health summary the status subcommand
+// prints: where the indexer is, how far behind the chain, and what it
+// knows it is missing.
+type statusReport struct {
+	Checkpoint      *checkpointStatus `json:"checkpoint"`
+	ValidatedLedger uint64            `json:"validated_ledger,omitempty"`
+	// LagLedgers is validated minus checkpoint; only meaningful when
+	// ValidatedLedger is set.
+	LagLedgers       uint64       `json:"lag_ledgers"`
+	EarliestIndexed  int64        `json:"earliest_indexed"`
+	Gaps             []*model.Gap `json:"gaps"`
+	MissingLedgers   uint64       `json:"missing_ledgers"`
+	OpenOffers       int64        `json:"open_offers"`
+	Pools            int64        `json:"pools"`
+	FailedLedgers24h int64        `json:"failed_ledgers_24h"`
+	// SlowLedgers ranks the recorded checkpoints by processing time,
+	// worst first; empty when no checkpoint carries a timing breakdown.
+	SlowLedgers []*model.SlowLedger `json:"slow_ledgers"`
+}
+
+type checkpointStatus struct {
+	LedgerIndex uint64  `json:"ledger_index"`
+	LedgerHash  string  `json:"ledger_hash"`
+	CloseTime   string  `json:"close_time"`
+	AgeSeconds  float64 `json:"age_seconds"`
+}
+
+// statusCmd answers "is the indexer healthy and how far behind is it"
+// without grepping logs: it reads the store, asks rippled for the
+// current validated ledger when reachable, and prints a summary. With a
+// -lag-threshold it exits 1 on excessive lag, so cron can alert on it.
+func statusCmd(args []string) {
+	fs := flag.NewFlagSet("indexer status", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	lagThreshold := fs.Uint64("lag-threshold", 0, "exit 1 when lag exceeds this many ledgers; 0 disables")
+	cfg := loadConfig(fs, args)
+
+	if cfg.DBDSN == "" {
+		log.Fatal("indexer status: a database is required")
+	}
+	ctx := context.Background()
+	st, err := store.Open(cfg.DBDriver, cfg.DBDSN)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer st.Close()
+	st.SetNetwork(cfg.Network)
+	if err := st.VerifyNetwork(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	cp, err := st.GetLastCheckpoint(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	earliest, err := st.EarliestLedger(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	gaps, err := st.ListGaps(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	offers, err := st.CountOpenOffers(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pools, err := st.CountPools(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	failed, err := st.CountFailedLedgersSince(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		log.Fatal(err)
+	}
+	slow, err := st.GetSlowLedgers(ctx, 5)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var validated uint64
+	client := xrpl.NewClient(cfg.RippledURL)
+	client.SetTimeout(cfg.RequestTimeout)
+	if si, err := client.GetServerInfo(ctx); err != nil {
+		log.Printf("indexer status: rippled unreachable, lag unknown: %v", err)
+	} else {
+		validated = si.ValidatedLedger.Seq
+	}
+
+	r := buildStatus(cp, validated, earliest, gaps, offers, pools, failed, slow, time.Now())
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(r)
+	} else {
+		printStatus(os.Stdout, r)
+	}
+	if *lagThreshold > 0 && r.ValidatedLedger > 0 && r.LagLedgers > *lagThreshold {
+		os.Exit(1)
+	}
+}
+
+// buildStatus assembles the report from already-fetched pieces; the
+// split from statusCmd keeps the arithmetic testable.
+func buildStatus(cp *model.Checkpoint, validated uint64, earliest int64, gaps []*model.Gap,
+	offers, pools, failed int64, slow []*model.SlowLedger, now time.Time) *statusReport {
+	r := &statusReport{
+		ValidatedLedger:  validated,
+		EarliestIndexed:  earliest,
+		Gaps:             gaps,
+		OpenOffers:       offers,
+		Pools:            pools,
+		FailedLedgers24h: failed,
+		SlowLedgers:      slow,
+	}
+	if r.Gaps == nil {
+		r.Gaps = []*model.Gap{}
+	}
+	if r.SlowLedgers == nil {
+		r.SlowLedgers = []*model.SlowLedger{}
+	}
+	for _, g := range gaps {
+		r.MissingLedgers += g.Size()
+	}
+	if cp != nil {
+		r.Checkpoint = &checkpointStatus{
+			LedgerIndex: cp.LedgerIndex,
+			LedgerHash:  cp.LedgerHash,
+			CloseTime:   xrpl.CloseTimeHuman(cp.CloseTime),
+			AgeSeconds:  now.Sub(xrpl.RippleTimeToUnix(cp.CloseTime)).Seconds(),
+		}
+		if validated > cp.LedgerIndex {
+			r.LagLedgers = validated - cp.LedgerIndex
+		}
+	}
+	return r
+}
+
+func printStatus(w io.Writer, r *statusReport) {
+	if r.Checkpoint == nil {
+		fmt.Fprintln(w, "checkpoint: none (nothing indexed yet)")
+	} else {
+		fmt.Fprintf(w, "checkpoint: ledger %d %s, closed %s (%.0fs ago)\n",
+			r.Checkpoint.LedgerIndex, r.Checkpoint.LedgerHash, r.Checkpoint.CloseTime, r.Checkpoint.AgeSeconds)
+	}
+	if r.ValidatedLedger == 0 {
+		fmt.Fprintln(w, "lag: unknown (rippled unreachable)")
+	} else {
+		fmt.Fprintf(w, "lag: %d ledgers behind validated ledger %d\n", r.LagLedgers, r.ValidatedLedger)
+	}
+	if r.EarliestIndexed > 0 {
+		fmt.Fprintf(w, "indexed from: ledger %d\n", r.EarliestIndexed)
+	}
+	if len(r.Gaps) == 0 {
+		fmt.Fprintln(w, "gaps: none")
+	} else {
+		fmt.Fprintf(w, "gaps: %d ranges, %d ledgers missing\n", len(r.Gaps), r.MissingLedgers)
+		for _, g := range r.Gaps {
+			fmt.Fprintf(w, "  %d..%d (%d ledgers): %s\n", g.From, g.To, g.Size(), g.Reason)
+		}
+	}
+	fmt.Fprintf(w, "open offers: %d\n", r.OpenOffers)
+	fmt.Fprintf(w, "pools: %d\n", r.Pools)
+	fmt.Fprintf(w, "failed ledgers (24h): %d\n", r.FailedLedgers24h)
+	if len(r.SlowLedgers) > 0 {
+		fmt.Fprintln(w, "slowest ledgers:")
+		for _, s := range r.SlowLedgers {
+			fmt.Fprintf(w, "  ledger %d: %.1fms%s\n", s.LedgerIndex, s.TotalMS, slowStages(s.Stages))
+		}
+	}
+}
+
+// slowStages renders a breakdown's stages slowest-first, so the report
+// shows at a glance where a bad ledger spent its time.
+func slowStages(stages map[string]float64) string {
+	if len(stages) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(stages))
+	for name := range stages {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if stages[names[i]] != stages[names[j]] {
+			return stages[names[i]] > stages[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %.1fms", name, stages[name])
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func TestBuildStatus(t *testing.T) {
+	cp := &model.Checkpoint{LedgerIndex: 1000, LedgerHash: "ABCD", CloseTime: 700000000}
+	now := xrpl.RippleTimeToUnix(700000000).Add(12 * time.Second)
+	gaps := []*model.Gap{{From: 10, To: 19, Reason: "no history"}, {From: 50, To: 50, Reason: "fetch failed"}}
+
+	r := buildStatus(cp, 1025, 5, gaps, 7, 3, 1, nil, now)
+	if r.LagLedgers != 25 {
+		t.Errorf("LagLedgers = %d, want 25", r.LagLedgers)
+	}
+	if r.MissingLedgers != 11 {
+		t.Errorf("MissingLedgers = %d, want 11", r.MissingLedgers)
+	}
+	if r.Checkpoint.AgeSeconds != 12 {
+		t.Errorf("AgeSeconds = %v, want 12", r.Checkpoint.AgeSeconds)
+	}
+	if r.Checkpoint.CloseTime != "2022-03-07T20:26:40Z" {
+		t.Errorf("CloseTime = %q", r.Checkpoint.CloseTime)
+	}
+
+	// Ahead of (or equal to) the validated ledger is zero lag, not a
+	// wrapped-around huge number.
+	if r := buildStatus(cp, 999, 5, nil, 0, 0, 0, nil, now); r.LagLedgers != 0 {
+		t.Errorf("LagLedgers ahead of validated = %d, want 0", r.LagLedgers)
+	}
+
+	// An empty store still renders.
+	r = buildStatus(nil, 0, 0, nil, 0, 0, 0, nil, now)
+	if r.Checkpoint != nil || r.LagLedgers != 0 || len(r.Gaps) != 0 {
+		t.Errorf("empty store report = %+v", r)
+	}
+}
+
+func TestPrintStatus(t *testing.T) {
+	cp := &model.Checkpoint{LedgerIndex: 1000, LedgerHash: "ABCD", CloseTime: 700000000}
+	now := xrpl.RippleTimeToUnix(700000000)
+	r := buildStatus(cp, 1025, 5, []*model.Gap{{From: 10, To: 19, Reason: "no history"}}, 7, 3, 1, nil, now)
+
+	var sb strings.Builder
+	printStatus(&sb, r)
+	out := sb.String()
+	for _, want := range []string{
+		"checkpoint: ledger 1000 ABCD",
+		"lag: 25 ledgers behind validated ledger 1025",
+		"10..19 (10 ledgers): no history",
+		"open offers: 7",
+		"failed ledgers (24h): 1",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	sb.Reset()
+	printStatus(&sb, buildStatus(nil, 0, 0, nil, 0, 0, 0, nil, now))
+	out = sb.String()
+	if !strings.Contains(out, "checkpoint: none") || !strings.Contains(out, "lag: unknown") {
+		t.Errorf("empty-store output:\n%s", out)
+	}
+}
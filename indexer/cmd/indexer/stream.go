@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/2pk03/lucendex-validator-verification/indexer/internal/api"
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/parser"
+)
+
+// streamTee forwards the sink writes partners stream — offers, fills,
+// and pool reserve changes — to the Partner API's WebSocket fan-out,
+// then hands them to the wrapped sink. It sits inside the issuer
+// filter, so filtered-out events are never streamed either.
+type streamTee struct {
+	parser.Sink
+	stream *api.Stream
+}
+
+func (t *streamTee) UpsertOffer(o *model.Offer) error {
+	t.stream.PublishOffer(o)
+	return t.Sink.UpsertOffer(o)
+}
+
+func (t *streamTee) InsertTrade(tr *model.Trade) error {
+	t.stream.PublishTrade(tr)
+	return t.Sink.InsertTrade(tr)
+}
+
+func (t *streamTee) AdjustPoolReserve(adj *model.PoolAdjustment) error {
+	t.stream.PublishPool(adj)
+	return t.Sink.AdjustPoolReserve(adj)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// liveP95Threshold is the live per-ledger processing duration past which
+// a concurrent backfill starts shedding its own rate. Healthy live
+// processing sits well under 100ms; sustained excursions past half a
+// second mean something is contending for the database.
+const liveP95Threshold = 500 * time.Millisecond
+
+// durationWindow shares the live path's recent per-ledger processing
+// durations with the backfill throttle: live records into it, the
+// backfill loop reads its p95. A fixed ring keeps it O(1) per ledger
+// and naturally recency-weighted.
+type durationWindow struct {
+	mu   sync.Mutex
+	durs []time.Duration
+	next int
+	full bool
+}
+
+func newDurationWindow(size int) *durationWindow {
+	return &durationWindow{durs: make([]time.Duration, size)}
+}
+
+func (w *durationWindow) observe(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.durs[w.next] = d
+	if w.next++; w.next == len(w.durs) {
+		w.next, w.full = 0, true
+	}
+}
+
+// p95 returns the 95th-percentile duration over the window, zero while
+// the window is empty — an idle live path never throttles anyone.
+func (w *durationWindow) p95() time.Duration {
+	w.mu.Lock()
+	n := w.next
+	if w.full {
+		n = len(w.durs)
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, w.durs[:n])
+	w.mu.Unlock()
+	if n == 0 {
+		return 0
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := n * 95 / 100
+	if idx >= n {
+		idx = n - 1
+	}
+	return sorted[idx]
+}
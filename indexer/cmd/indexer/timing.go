@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// stageClock accumulates wall time per pipeline stage while one ledger
+// is processed. Stages interleave per transaction (raw save, balance
+// extraction, dispatch), so each measured section reports its start to
+// add and the clock sums the slices. A nil clock ignores everything, so
+// callers never guard.
+type stageClock struct {
+	start  time.Time
+	stages map[string]time.Duration
+}
+
+func newStageClock() *stageClock {
+	return &stageClock{start: time.Now(), stages: map[string]time.Duration{}}
+}
+
+// add charges the time since start to the named stage.
+func (c *stageClock) add(stage string, start time.Time) {
+	if c == nil {
+		return
+	}
+	c.stages[stage] += time.Since(start)
+}
+
+// breakdown returns the measured stages in milliseconds plus a total_ms
+// key covering the whole ledger so far, in the shape the checkpoint row
+// stores. Nil when nothing was measured, so untimed paths keep storing
+// NULL.
+func (c *stageClock) breakdown() map[string]float64 {
+	if c == nil || len(c.stages) == 0 {
+		return nil
+	}
+	out := make(map[string]float64, len(c.stages)+1)
+	for stage, d := range c.stages {
+		out[stage] = ms(d)
+	}
+	out["total_ms"] = ms(time.Since(c.start))
+	return out
+}
+
+// String renders the breakdown for the per-ledger log line: total first,
+// then stages sorted slowest-first.
+func (c *stageClock) String() string {
+	if c == nil {
+		return ""
+	}
+	type entry struct {
+		stage string
+		d     time.Duration
+	}
+	entries := make([]entry, 0, len(c.stages))
+	for stage, d := range c.stages {
+		entries = append(entries, entry{stage, d})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].d != entries[j].d {
+			return entries[i].d > entries[j].d
+		}
+		return entries[i].stage < entries[j].stage
+	})
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%.1fms", ms(time.Since(c.start)))
+	for _, e := range entries {
+		fmt.Fprintf(&sb, ", %s %.1fms", e.stage, ms(e.d))
+	}
+	return sb.String()
+}
+
+// ms converts a duration to fractional milliseconds, the unit the
+// timings column uses.
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
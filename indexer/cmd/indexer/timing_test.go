@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStageClock(t *testing.T) {
+	c := newStageClock()
+	c.start = time.Now().Add(-100 * time.Millisecond)
+	c.add("dispatch", time.Now().Add(-40*time.Millisecond))
+	c.add("raw", time.Now().Add(-5*time.Millisecond))
+	// Stages interleave per transaction, so repeated charges accumulate.
+	c.add("raw", time.Now().Add(-5*time.Millisecond))
+
+	b := c.breakdown()
+	if b["dispatch"] < 40 || b["raw"] < 10 {
+		t.Errorf("breakdown = %v, want dispatch >= 40ms and raw >= 10ms", b)
+	}
+	if b["total_ms"] < b["dispatch"] {
+		t.Errorf("total %v is less than the dispatch stage %v", b["total_ms"], b["dispatch"])
+	}
+
+	s := c.String()
+	if !strings.Contains(s, "dispatch") || !strings.Contains(s, "raw") {
+		t.Errorf("String() = %q, want both stages", s)
+	}
+	if di, ri := strings.Index(s, "dispatch"), strings.Index(s, "raw"); di > ri {
+		t.Errorf("String() = %q, want slowest stage first", s)
+	}
+}
+
+func TestStageClockNilAndEmpty(t *testing.T) {
+	var c *stageClock
+	c.add("raw", time.Now()) // must not panic
+	if b := c.breakdown(); b != nil {
+		t.Errorf("nil clock breakdown = %v, want nil", b)
+	}
+	if b := newStageClock().breakdown(); b != nil {
+		t.Errorf("empty clock breakdown = %v, want nil so the row stores NULL", b)
+	}
+}
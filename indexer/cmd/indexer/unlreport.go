@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/store"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// unlReportCmd answers "did the UNL actually validate the ledgers we
+// indexed": for each validator on the expected UNL it reports how much
+// of a checkpointed ledger range the validator covered, flagging the
+// laggards. The UNL comes from -unl (a file of master keys, one per
+// line, # comments) or, absent that, from the connected rippled's own
+// trusted list. Exits 1 when any validator falls below -threshold, so
+// cron can alert on it.
+func unlReportCmd(args []string) {
+	fs := flag.NewFlagSet("indexer unl-report", flag.ExitOnError)
+	unlPath := fs.String("unl", "", "file of expected validator master keys; empty asks rippled for its trusted list")
+	from := fs.Uint64("from", 0, "first ledger of the range; 0 means the earliest indexed")
+	to := fs.Uint64("to", 0, "last ledger of the range; 0 means the last checkpoint")
+	threshold := fs.Float64("threshold", 0.9, "participation below this flags the validator and fails the command")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	cfg := loadConfig(fs, args)
+
+	if cfg.DBDSN == "" {
+		log.Fatal("indexer unl-report: a database is required")
+	}
+	ctx := context.Background()
+	st, err := store.Open(cfg.DBDriver, cfg.DBDSN)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer st.Close()
+	st.SetNetwork(cfg.Network)
+	if err := st.VerifyNetwork(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	var unl []string
+	if *unlPath != "" {
+		if unl, err = loadUNL(*unlPath); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		client := xrpl.NewClient(cfg.RippledURL)
+		client.SetTimeout(cfg.RequestTimeout)
+		if unl, err = client.TrustedValidators(ctx); err != nil {
+			log.Fatalf("indexer unl-report: no -unl file and rippled's trusted list unavailable: %v", err)
+		}
+	}
+	if len(unl) == 0 {
+		log.Fatal("indexer unl-report: the UNL is empty")
+	}
+
+	if *to == 0 {
+		latest, err := st.LatestLedger(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if latest == 0 {
+			log.Fatal("indexer unl-report: nothing indexed yet")
+		}
+		*to = uint64(latest)
+	}
+	if *from == 0 {
+		earliest, err := st.EarliestLedger(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		*from = uint64(earliest)
+	}
+	if *from > *to {
+		log.Fatalf("indexer unl-report: range %d..%d is backwards", *from, *to)
+	}
+
+	parts, err := st.UNLParticipation(ctx, unl, *from, *to)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r := buildUNLReport(parts, *from, *to, *threshold)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(r)
+	} else {
+		printUNLReport(os.Stdout, r)
+	}
+	if len(r.Below) > 0 {
+		os.Exit(1)
+	}
+}
+
+// loadUNL reads one validator master key per line; blank lines and
+// #-comments are skipped.
+func loadUNL(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("indexer unl-report: %w", err)
+	}
+	defer f.Close()
+	var unl []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		unl = append(unl, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("indexer unl-report: reading %s: %w", path, err)
+	}
+	return unl, nil
+}
+
+// unlReport is the participation summary the unl-report subcommand
+// prints: every UNL validator's coverage of the range, worst first, and
+// the subset below the threshold.
+type unlReport struct {
+	From       uint64                    `json:"from"`
+	To         uint64                    `json:"to"`
+	Threshold  float64                   `json:"threshold"`
+	Validators []*model.UNLParticipation `json:"validators"`
+	// Below lists the validators under the threshold; non-empty fails
+	// the command.
+	Below []string `json:"below"`
+}
+
+// buildUNLReport orders the participation rows worst first and collects
+// the laggards; the split from unlReportCmd keeps it testable.
+func buildUNLReport(parts []*model.UNLParticipation, from, to uint64, threshold float64) *unlReport {
+	r := &unlReport{From: from, To: to, Threshold: threshold, Validators: parts}
+	if r.Validators == nil {
+		r.Validators = []*model.UNLParticipation{}
+	}
+	sort.SliceStable(r.Validators, func(i, j int) bool {
+		if r.Validators[i].Participation != r.Validators[j].Participation {
+			return r.Validators[i].Participation < r.Validators[j].Participation
+		}
+		return r.Validators[i].Validator < r.Validators[j].Validator
+	})
+	for _, p := range r.Validators {
+		if p.Participation < threshold {
+			r.Below = append(r.Below, p.Validator)
+		}
+	}
+	return r
+}
+
+func printUNLReport(w io.Writer, r *unlReport) {
+	fmt.Fprintf(w, "UNL participation over ledgers %d..%d (%d validators, threshold %.1f%%)\n",
+		r.From, r.To, len(r.Validators), r.Threshold*100)
+	for _, p := range r.Validators {
+		mark := ""
+		if p.Participation < r.Threshold {
+			mark = "  LOW"
+		}
+		fmt.Fprintf(w, "  %s  %6.2f%%  agreed %d  missed %d  disagreed %d%s\n",
+			p.Validator, p.Participation*100, p.Agreed, p.Missed, p.Disagreed, mark)
+	}
+	if len(r.Below) == 0 {
+		fmt.Fprintln(w, "all validators at or above the threshold")
+	} else {
+		fmt.Fprintf(w, "%d validator(s) below the threshold\n", len(r.Below))
+	}
+}
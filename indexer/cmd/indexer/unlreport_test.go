@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+func TestLoadUNL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "unl.txt")
+	content := "# production UNL\nnHAlpha\n\n  nHBeta  \n# retired\nnHGamma\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	unl, err := loadUNL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"nHAlpha", "nHBeta", "nHGamma"}; !reflect.DeepEqual(unl, want) {
+		t.Errorf("unl = %v, want %v", unl, want)
+	}
+	if _, err := loadUNL(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("missing file did not error")
+	}
+}
+
+func TestBuildUNLReport(t *testing.T) {
+	parts := []*model.UNLParticipation{
+		{Validator: "nHSteady", Expected: 10, Agreed: 10, Participation: 1.0},
+		{Validator: "nHLagging", Expected: 10, Agreed: 7, Missed: 3, Participation: 0.7},
+		{Validator: "nHGone", Expected: 10, Missed: 10},
+	}
+	r := buildUNLReport(parts, 100, 200, 0.9)
+
+	// Worst first, and exactly the sub-threshold validators flagged.
+	var order []string
+	for _, p := range r.Validators {
+		order = append(order, p.Validator)
+	}
+	if want := []string{"nHGone", "nHLagging", "nHSteady"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+	if want := []string{"nHGone", "nHLagging"}; !reflect.DeepEqual(r.Below, want) {
+		t.Errorf("below = %v, want %v", r.Below, want)
+	}
+
+	var out strings.Builder
+	printUNLReport(&out, r)
+	text := out.String()
+	if !strings.Contains(text, "nHGone") || !strings.Contains(text, "LOW") {
+		t.Errorf("report missing laggard marking:\n%s", text)
+	}
+	if strings.Count(text, "LOW") != 2 {
+		t.Errorf("LOW marks = %d, want 2:\n%s", strings.Count(text, "LOW"), text)
+	}
+
+	if r := buildUNLReport(nil, 1, 2, 0.5); len(r.Validators) != 0 || len(r.Below) != 0 {
+		t.Errorf("empty report = %+v", r)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/store"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// poolDiscrepancy is one field where stored pool state disagrees with the
+// live AMM object beyond tolerance.
+type poolDiscrepancy struct {
+	Field  string
+	Stored string
+	Actual string
+}
+
+// verifyPools is the AMM analog of the single-ledger reproduction tool: it
+// samples stored pools, fetches each one's live AMM object from rippled,
+// and compares reserves and fee. Mismatches beyond -tolerance are logged
+// and appended to the reconciliation table — catching parser drift against
+// the chain instead of waiting for a partner to.
+func verifyPools(args []string) {
+	fs := flag.NewFlagSet("indexer verify-pools", flag.ExitOnError)
+	sample := fs.Int("sample", 25, "how many of the most recently updated pools to check")
+	tolerance := fs.String("tolerance", "0.0001", "relative reserve tolerance before a mismatch counts")
+	cfg := loadConfig(fs, args)
+
+	if cfg.DBDSN == "" {
+		log.Fatal("indexer verify-pools: a database is required")
+	}
+	tol, ok := new(big.Rat).SetString(*tolerance)
+	if !ok || tol.Sign() < 0 {
+		log.Fatalf("indexer verify-pools: bad -tolerance %q", *tolerance)
+	}
+	ctx := context.Background()
+	st, err := store.Open(cfg.DBDriver, cfg.DBDSN)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer st.Close()
+	if err := st.Migrate(ctx); err != nil {
+		log.Fatal(err)
+	}
+	st.SetNetwork(cfg.Network)
+	if err := st.VerifyNetwork(ctx); err != nil {
+		log.Fatal(err)
+	}
+	client := xrpl.NewClient(cfg.RippledURL)
+	client.SetTimeout(cfg.RequestTimeout)
+
+	pools, err := st.ListPools(ctx, *sample)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var checked, mismatched int
+	for _, pool := range pools {
+		info, err := client.GetAMMInfo(ctx, pool.Asset1, pool.Asset2)
+		if err != nil {
+			log.Printf("indexer verify-pools: pool %s: %v", pool.Account, err)
+			continue
+		}
+		checked++
+		discrepancies := comparePool(pool, info, tol)
+		if len(discrepancies) == 0 {
+			continue
+		}
+		mismatched++
+		for _, d := range discrepancies {
+			log.Printf("indexer verify-pools: pool %s: %s stored %s, rippled reports %s",
+				pool.Account, d.Field, d.Stored, d.Actual)
+			if err := st.RecordPoolDiscrepancy(ctx, pool.Account, d.Field, d.Stored, d.Actual, pool.LedgerIndex); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+	log.Printf("indexer verify-pools: %d pools checked, %d with discrepancies", checked, mismatched)
+}
+
+// comparePool diffs stored pool state against the live AMM object.
+// Reserves match when their relative difference stays within tol; the
+// account and fee must match exactly. Legs are matched by asset, so the
+// two sides may arrive in either order.
+func comparePool(pool *model.Pool, info *xrpl.AMMInfo, tol *big.Rat) []poolDiscrepancy {
+	var out []poolDiscrepancy
+	if info.Account != pool.Account {
+		out = append(out, poolDiscrepancy{"account", pool.Account, info.Account})
+	}
+	for _, leg := range []struct {
+		name   string
+		stored xrpl.Amount
+	}{{"asset1_value", pool.Asset1}, {"asset2_value", pool.Asset2}} {
+		actual, ok := matchLeg(leg.stored, info)
+		if !ok {
+			out = append(out, poolDiscrepancy{leg.name, leg.stored.String(), "asset missing from amm_info"})
+			continue
+		}
+		if !withinTolerance(leg.stored.Value, actual.Value, tol) {
+			out = append(out, poolDiscrepancy{leg.name, leg.stored.Value, actual.Value})
+		}
+	}
+	if pool.TradingFee != info.TradingFee {
+		out = append(out, poolDiscrepancy{"trading_fee",
+			fmt.Sprint(pool.TradingFee), fmt.Sprint(info.TradingFee)})
+	}
+	return out
+}
+
+// matchLeg finds the amm_info side holding the same asset as the stored
+// leg.
+func matchLeg(stored xrpl.Amount, info *xrpl.AMMInfo) (xrpl.Amount, bool) {
+	for _, actual := range []xrpl.Amount{info.Amount, info.Amount2} {
+		if actual.Currency == stored.Currency && actual.Issuer == stored.Issuer {
+			return actual, true
+		}
+	}
+	return xrpl.Amount{}, false
+}
+
+// withinTolerance reports whether |stored - actual| <= tol * |actual|.
+// Unparseable values never match, so they surface as discrepancies.
+func withinTolerance(stored, actual string, tol *big.Rat) bool {
+	s, ok := new(big.Rat).SetString(stored)
+	if !ok {
+		return false
+	}
+	a, ok := new(big.Rat).SetString(actual)
+	if !ok {
+		return false
+	}
+	diff := new(big.Rat).Sub(s, a)
+	diff.Abs(diff)
+	bound := new(big.Rat).Abs(a)
+	bound.Mul(bound, tol)
+	return diff.Cmp(bound) <= 0
+}
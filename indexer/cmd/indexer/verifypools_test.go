@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl/xrpltest"
+)
+
+func TestComparePool(t *testing.T) {
+	pool := &model.Pool{
+		Account:    "rPoolCheck",
+		Asset1:     xrpl.Amount{Currency: "XRP", Value: "1000"},
+		Asset2:     xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "500"},
+		TradingFee: 600,
+	}
+	tol, _ := new(big.Rat).SetString("0.001")
+
+	// Legs may arrive swapped; values inside tolerance are clean.
+	clean := &xrpl.AMMInfo{
+		Account:    "rPoolCheck",
+		Amount:     xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "500.0004"},
+		Amount2:    xrpl.Amount{Currency: "XRP", Value: "1000"},
+		TradingFee: 600,
+	}
+	if got := comparePool(pool, clean, tol); len(got) != 0 {
+		t.Fatalf("discrepancies = %+v, want none", got)
+	}
+
+	// A drifted reserve and a stale fee are both flagged.
+	drifted := &xrpl.AMMInfo{
+		Account:    "rPoolCheck",
+		Amount:     xrpl.Amount{Currency: "XRP", Value: "1002"},
+		Amount2:    xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "500"},
+		TradingFee: 700,
+	}
+	got := comparePool(pool, drifted, tol)
+	if len(got) != 2 {
+		t.Fatalf("discrepancies = %+v, want reserve and fee", got)
+	}
+	if got[0].Field != "asset1_value" || got[0].Stored != "1000" || got[0].Actual != "1002" {
+		t.Errorf("reserve discrepancy = %+v", got[0])
+	}
+	if got[1].Field != "trading_fee" || got[1].Actual != "700" {
+		t.Errorf("fee discrepancy = %+v", got[1])
+	}
+
+	// An asset missing from amm_info entirely is a discrepancy, not a pass.
+	missing := &xrpl.AMMInfo{
+		Account:    "rPoolCheck",
+		Amount:     xrpl.Amount{Currency: "XRP", Value: "1000"},
+		Amount2:    xrpl.Amount{Currency: "EUR", Issuer: "rIss", Value: "500"},
+		TradingFee: 600,
+	}
+	got = comparePool(pool, missing, tol)
+	if len(got) != 1 || got[0].Field != "asset2_value" {
+		t.Fatalf("discrepancies = %+v, want the missing leg", got)
+	}
+}
+
+func TestGetAMMInfo(t *testing.T) {
+	srv := xrpltest.NewServer()
+	defer srv.Close()
+	srv.HandleResult("amm_info", map[string]any{
+		"amm": map[string]any{
+			"account": "rPoolLive",
+			"amount":  "1000000000",
+			"amount2": map[string]any{"currency": "USD", "issuer": "rIss", "value": "500"},
+			"lp_token": map[string]any{
+				"currency": "03AB", "issuer": "rPoolLive", "value": "700",
+			},
+			"trading_fee": 600,
+		},
+	})
+
+	client := xrpl.NewClient(srv.URL())
+	info, err := client.GetAMMInfo(context.Background(),
+		xrpl.Amount{Currency: "XRP"},
+		xrpl.Amount{Currency: "USD", Issuer: "rIss"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Account != "rPoolLive" || info.TradingFee != 600 {
+		t.Fatalf("info = %+v", info)
+	}
+	if info.Amount.Currency != "XRP" || info.Amount.Value != "1000" {
+		t.Errorf("xrp leg = %+v, want drops converted", info.Amount)
+	}
+	if info.Amount2.Value != "500" || info.LPToken.Value != "700" {
+		t.Errorf("legs = %+v / %+v", info.Amount2, info.LPToken)
+	}
+}
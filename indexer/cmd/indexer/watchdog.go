@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// watchdog notices when live ingestion stops seeing ledgers while the
+// connection still looks healthy — an amendment-blocked rippled keeps the
+// socket open but never validates again. Past warnAfter it logs and turns
+// /readyz unhealthy; past actAfter it runs the configured action. It
+// starts paused and is only armed while ledgers are actually expected, so
+// a deliberate backfill-only phase never trips it.
+type watchdog struct {
+	warnAfter time.Duration
+	actAfter  time.Duration
+
+	// tick is the check interval; tests shorten it.
+	tick time.Duration
+
+	mu     sync.Mutex
+	act    func(age time.Duration)
+	last   time.Time
+	paused bool
+	warned bool
+
+	// notify, when set, hears every stall-verdict flip. It is called
+	// with the watchdog's lock held, so it must not call back in.
+	notify func(stalled bool)
+}
+
+func newWatchdog(warnAfter, actAfter time.Duration) *watchdog {
+	return &watchdog{
+		warnAfter: warnAfter,
+		actAfter:  actAfter,
+		tick:      time.Second,
+		last:      time.Now(),
+		paused:    true,
+	}
+}
+
+// setAction installs the stall action; the live loop wires it up once the
+// subscriber it operates on exists.
+func (w *watchdog) setAction(act func(age time.Duration)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.act = act
+}
+
+// setThresholds swaps the stall thresholds at runtime (SIGHUP reload).
+// A watchdog started with a zero warnAfter never ran its loop and stays
+// off regardless.
+func (w *watchdog) setThresholds(warnAfter, actAfter time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.warnAfter = warnAfter
+	w.actAfter = actAfter
+}
+
+// ledgerProcessed resets the stall clock.
+func (w *watchdog) ledgerProcessed() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.last = time.Now()
+	if w.warned {
+		log.Print("indexer: watchdog: ledgers flowing again")
+		if w.notify != nil {
+			w.notify(false)
+		}
+	}
+	w.warned = false
+}
+
+// pause suspends stall detection while no ledgers are expected.
+func (w *watchdog) pause() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.paused = true
+}
+
+// resume re-arms the watchdog with a fresh clock.
+func (w *watchdog) resume() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.paused = false
+	if w.warned && w.notify != nil {
+		w.notify(false)
+	}
+	w.warned = false
+	w.last = time.Now()
+}
+
+// healthy reports whether live ingestion is keeping up; /readyz serves 503
+// while it is not. A paused watchdog is healthy by definition.
+func (w *watchdog) healthy() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.paused || !w.warned
+}
+
+// run checks the stall clock until ctx ends. A zero warnAfter disables
+// the watchdog entirely.
+func (w *watchdog) run(ctx context.Context) {
+	if w.warnAfter <= 0 {
+		return
+	}
+	ticker := time.NewTicker(w.tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.check(time.Now())
+		}
+	}
+}
+
+func (w *watchdog) check(now time.Time) {
+	w.mu.Lock()
+	if w.paused {
+		w.mu.Unlock()
+		return
+	}
+	age := now.Sub(w.last)
+	warn := !w.warned && age >= w.warnAfter
+	if warn {
+		w.warned = true
+		if w.notify != nil {
+			w.notify(true)
+		}
+	}
+	acting := w.actAfter > 0 && age >= w.actAfter
+	act := w.act
+	if acting {
+		// Re-arm so a stall that survives the action gets acted on again a
+		// full actAfter later, not on every tick.
+		w.last = now
+		if w.warned && w.notify != nil {
+			w.notify(false)
+		}
+		w.warned = false
+	}
+	w.mu.Unlock()
+
+	if warn {
+		log.Printf("indexer: watchdog: no ledger processed for %s, marking unready", age.Round(time.Second))
+	}
+	if acting && act != nil {
+		act(age)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWatchdogWarnsThenActs(t *testing.T) {
+	wd := newWatchdog(20*time.Millisecond, 60*time.Millisecond)
+	wd.tick = 5 * time.Millisecond
+	acted := make(chan time.Duration, 1)
+	wd.setAction(func(age time.Duration) { acted <- age })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wd.resume()
+	go wd.run(ctx)
+
+	deadline := time.After(2 * time.Second)
+	for wd.healthy() {
+		select {
+		case <-deadline:
+			t.Fatal("watchdog never warned")
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	select {
+	case age := <-acted:
+		if age < 60*time.Millisecond {
+			t.Errorf("acted after %v, before the act threshold", age)
+		}
+	case <-deadline:
+		t.Fatal("watchdog never acted")
+	}
+
+	// A processed ledger restores health and re-arms the clock.
+	wd.ledgerProcessed()
+	if !wd.healthy() {
+		t.Error("watchdog unhealthy right after a processed ledger")
+	}
+}
+
+func TestWatchdogPausedNeverFires(t *testing.T) {
+	wd := newWatchdog(10*time.Millisecond, 20*time.Millisecond)
+	wd.tick = 5 * time.Millisecond
+	acted := make(chan time.Duration, 1)
+	wd.setAction(func(age time.Duration) { acted <- age })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Never resumed: a backfill-only run must not trip the watchdog.
+	go wd.run(ctx)
+
+	select {
+	case <-acted:
+		t.Fatal("paused watchdog acted")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if !wd.healthy() {
+		t.Error("paused watchdog reports unhealthy")
+	}
+}
@@ -0,0 +1,733 @@
+// Package config centralizes the indexer's runtime settings. Values come
+// from three layers with a fixed precedence: a config file (lowest), then
+// INDEXER_* environment variables, then explicitly set command-line flags
+// (highest). Keys are the flag names; the file may be JSON or a flat
+// key: value YAML subset.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds every tunable the indexer accepts.
+type Config struct {
+	RippledURL string
+	RippledWS  string
+
+	// RippledWSFailover lists further WebSocket endpoints the watchdog's
+	// failover action rotates through when the primary stops validating.
+	RippledWSFailover []string
+
+	DBDriver string
+	DBDSN    string
+
+	// ArchiveDSN, when non-empty, names a second database that receives
+	// an append-only copy of checkpoints, trades, and the connection
+	// audit trail, forwarded asynchronously behind the primary.
+	ArchiveDSN string
+
+	// RequestTimeout bounds each rippled call; zero disables it.
+	RequestTimeout time.Duration
+
+	// LedgerTimeout bounds the processing of one ledger, including its
+	// database writes; zero disables it.
+	LedgerTimeout time.Duration
+
+	// SlowQueryThreshold logs any store statement that takes at least
+	// this long, with its name and duration; zero disables the logging.
+	SlowQueryThreshold time.Duration
+
+	// MetricsAddr serves Prometheus metrics when non-empty,
+	// e.g. ":9090".
+	MetricsAddr string
+
+	// FaultConfig names a fault-injection spec file for resilience
+	// testing; it only works in binaries built with -tags faultinject
+	// and is refused elsewhere. Never set it in production.
+	FaultConfig string
+
+	// PprofAddr serves net/http/pprof when non-empty, e.g.
+	// "localhost:6060". Profiles expose internals and cost CPU to take,
+	// so bind it to localhost unless a firewall says otherwise.
+	PprofAddr string
+
+	// PartnerAPIAddr serves the read-only Partner API when non-empty,
+	// e.g. ":8080". It needs a configured database.
+	PartnerAPIAddr string
+
+	// PartnerAPIAuth requires an API key from the api_keys table on
+	// every Partner API request; without it the public market data
+	// endpoints are open, but /admin/ always needs a key with the admin
+	// scope.
+	PartnerAPIAuth bool
+
+	// PartnerAPIRateLimit is the requests-per-minute limit for keys
+	// without their own; 0 leaves such keys unlimited.
+	PartnerAPIRateLimit int
+
+	// RPCAddr serves the typed RPC API for internal services (package
+	// rpcapi) when non-empty; it needs a configured database. RPCCert
+	// and RPCKey enable TLS on it, and RPCClientCA additionally demands
+	// client certificates signed by that CA — mutual TLS for
+	// service-to-service calls.
+	RPCAddr     string
+	RPCCert     string
+	RPCKey      string
+	RPCClientCA string
+
+	// Network names the chain this indexer belongs to: mainnet, testnet,
+	// devnet, or custom. Startup refuses servers and databases from a
+	// different network. NetworkID is only consulted for "custom".
+	Network   string
+	NetworkID uint32
+
+	Live       bool
+	IngestMode string
+
+	// StreamBuffer is how many subscription messages the live WebSocket
+	// connection buffers before its read loop blocks; messages are never
+	// dropped. Zero or negative falls back to the library default.
+	StreamBuffer int
+
+	// TrackBalances extracts per-account balance changes from transaction
+	// metadata into the balance_changes table.
+	TrackBalances bool
+
+	// TrackValidations subscribes live ingestion to the validations
+	// stream and records which validators voted for which ledgers,
+	// feeding the validator scoring job. It is chatty — one message per
+	// validator per ledger — so it is off by default.
+	TrackValidations bool
+
+	// ComputeFunded enriches open offers with the amount their owner's
+	// observed balance could actually deliver, refreshed whenever a
+	// balance change for that owner appears in metadata. It only touches
+	// offers the issuer filter lets through.
+	ComputeFunded bool
+
+	// EmitEvents writes publishable events into the transactional outbox
+	// as each ledger is processed; a dispatcher drains them to registered
+	// emitters once the ledger is checkpointed. OutboxLimit bounds the
+	// outbox: past it, ledger processing fails and retries instead of
+	// growing the table without bound. Zero means unbounded.
+	EmitEvents  bool
+	OutboxLimit int
+
+	// EventSinkURL connects an external event sink to the outbox
+	// dispatcher in place of the built-in log emitter; currently a
+	// nats://host:port bus. Empty (the default) keeps the log emitter.
+	// Only meaningful with EmitEvents. EventSubjectPrefix prefixes the
+	// bus subject of every event ("<prefix>.<topic>").
+	EventSinkURL       string
+	EventSubjectPrefix string
+
+	// RecordFixtures names a directory that receives every ledger the
+	// live stream delivers, one gzipped JSON file each, for the replay
+	// command to feed back through the pipeline later. Empty disables
+	// recording.
+	RecordFixtures string
+
+	// CacheCheckpoints and CacheOffers size the store's in-process
+	// caches for the ingest command: the last K checkpoints, and an LRU
+	// of recently touched offers invalidated on write. Zero disables a
+	// cache. One-shot commands and reprocessing always read the database
+	// directly, so a cache filled before a parser fix cannot mask the
+	// correction.
+	CacheCheckpoints int
+	CacheOffers      int
+
+	// SnapshotDir names a directory for periodic snapshots of derived
+	// runtime state — the market discovery window and the in-process
+	// caches — written every SnapshotEvery ledgers, keeping the last
+	// SnapshotKeep files. A restart restores the newest snapshot that
+	// still matches a checkpoint instead of rebuilding that state cold.
+	// Empty disables snapshotting.
+	SnapshotDir   string
+	SnapshotEvery int
+	SnapshotKeep  int
+
+	// DiscoverMarkets promotes a pair to a tracked market once its
+	// recent activity crosses either threshold: DiscoverOfferThreshold
+	// offer writes or DiscoverVolumeThreshold XRP of volume within
+	// DiscoverWindow of ledger time. Tracked markets get a one-time
+	// book_offers seed and, with DiscoverSubscribeBooks, a live books
+	// subscription. Markets idle for DiscoverDemoteAfter are demoted —
+	// still indexed, no longer special. A zero threshold disables that
+	// leg of the test.
+	DiscoverMarkets         bool
+	DiscoverOfferThreshold  int
+	DiscoverVolumeThreshold int
+	DiscoverWindow          time.Duration
+	DiscoverDemoteAfter     time.Duration
+	DiscoverSubscribeBooks  bool
+
+	// PoolHistoryDownsampleAfter is the age beyond which pool reserve
+	// history is thinned to hourly rows; zero keeps everything forever.
+	PoolHistoryDownsampleAfter time.Duration
+
+	// MaintenanceInterval schedules in-process database upkeep — VACUUM
+	// ANALYZE over the hot tables and trimming the connection audit —
+	// with up to MaintenanceJitter added per pass so a fleet does not
+	// vacuum in lockstep. Zero disables the scheduler (the default;
+	// deployments with external cron keep it off). MaintenanceAuditKeep
+	// is how long connection-audit rows are retained.
+	MaintenanceInterval  time.Duration
+	MaintenanceJitter    time.Duration
+	MaintenanceAuditKeep time.Duration
+
+	// OfferRetentionLedgers has maintenance prune cancelled and fully
+	// filled offers more than this many ledgers behind the latest
+	// checkpoint; zero (the default) keeps full offer history. Open
+	// offers are never pruned whatever the setting.
+	OfferRetentionLedgers int
+
+	// WatchdogWarnAfter is how long live ingestion may go without a
+	// processed ledger before the indexer logs a warning and turns
+	// /readyz unhealthy; zero disables the watchdog. WatchdogActAfter is
+	// the second threshold past which WatchdogAction runs: "reconnect"
+	// re-dials the current endpoint, "failover" moves to the next endpoint
+	// in rippled-ws-failover, and "exit" terminates non-zero so the
+	// orchestrator restarts the process.
+	WatchdogWarnAfter time.Duration
+	WatchdogActAfter  time.Duration
+	WatchdogAction    string
+
+	// CatchupLagHigh is the backlog of fetched-but-unprocessed ledgers
+	// past which live ingestion enters catch-up mode and defers
+	// non-essential per-ledger work; CatchupLagLow is the backlog at which
+	// it leaves again and the deferred work is drained. A zero high-water
+	// mark disables catch-up mode.
+	CatchupLagHigh int
+	CatchupLagLow  int
+
+	// PriorityCatchup turns catch-up mode into a two-phase scheme:
+	// transactions touching partner-mapped accounts or the allow/watch
+	// issuer lists apply immediately (their offers marked provisional),
+	// and the full parse plus checkpoints follow once the backlog
+	// clears. Needs a catchup-lag-high mark and a database.
+	PriorityCatchup bool
+
+	// ValidatorScoreWindow is the sliding window validator agreement is
+	// scored over; ValidatorScoreInterval is how often the scoring job
+	// runs. A zero interval disables the job.
+	ValidatorScoreWindow   time.Duration
+	ValidatorScoreInterval time.Duration
+
+	// IdentityRefreshInterval is how long a resolved issuer or validator
+	// identity stays fresh before the enrichment job re-resolves it; zero
+	// disables enrichment entirely. Negative results (no domain, dead
+	// domain) refresh at most daily regardless.
+	IdentityRefreshInterval time.Duration
+
+	// FeePollInterval is how often the fee command is polled into
+	// fee_history; zero disables polling. FeeSpikeMultiple warns (and
+	// counts a metric) when the open-ledger fee reaches this multiple of
+	// the base fee; zero disables the warning.
+	FeePollInterval  time.Duration
+	FeeSpikeMultiple int
+
+	// IssuerAllow restricts indexing to these IOU issuers when non-empty;
+	// IssuerDeny always excludes its issuers. XRP is never filtered.
+	IssuerAllow []string
+	IssuerDeny  []string
+
+	// WatchIssuers, when non-empty, skips writes whose assets involve no
+	// watched issuer at all. Unlike the allowlist, which demands every
+	// IOU leg be listed, one watched leg keeps the write — including its
+	// XRP-paired markets — so a focused deployment indexes just the
+	// watched issuers' tokens and whatever trades against them.
+	WatchIssuers []string
+
+	BackfillFrom       uint64
+	BackfillTo         uint64
+	BackfillMaxRetries int
+	BackfillRetryBase  time.Duration
+	BackfillMaxBackoff time.Duration
+	BackfillOnFailure  string
+	// BackfillMaxRate caps backfill at this many ledgers per second;
+	// under live pressure the rate adapts downward from this ceiling.
+	// Zero keeps the historical unthrottled behavior.
+	BackfillMaxRate float64
+
+	// ConfigFile remembers the -config path so SIGHUP can re-read it; it
+	// is set by the flag loader, not a config key itself.
+	ConfigFile string
+}
+
+// Default returns the built-in defaults, which double as the flag
+// defaults.
+func Default() Config {
+	return Config{
+		RippledURL:         "http://localhost:5005",
+		RippledWS:          "ws://localhost:6006",
+		DBDriver:           "postgres",
+		RequestTimeout:     10 * time.Second,
+		LedgerTimeout:      30 * time.Second,
+		IngestMode:         "ledger",
+		StreamBuffer:       64,
+		BackfillMaxRetries: 3,
+		BackfillRetryBase:  time.Second,
+		BackfillMaxBackoff: 30 * time.Second,
+		BackfillOnFailure:  "skip",
+		Network:            "mainnet",
+
+		PoolHistoryDownsampleAfter: 30 * 24 * time.Hour,
+
+		MaintenanceJitter:    10 * time.Minute,
+		MaintenanceAuditKeep: 90 * 24 * time.Hour,
+
+		WatchdogWarnAfter: 60 * time.Second,
+		WatchdogActAfter:  3 * time.Minute,
+		WatchdogAction:    "reconnect",
+
+		CatchupLagHigh: 20,
+		CatchupLagLow:  5,
+
+		ValidatorScoreWindow:   time.Hour,
+		ValidatorScoreInterval: 10 * time.Minute,
+
+		FeePollInterval:  30 * time.Second,
+		FeeSpikeMultiple: 10,
+
+		PartnerAPIRateLimit: 60,
+
+		OutboxLimit: 10000,
+
+		EventSubjectPrefix: "lucendex",
+
+		CacheCheckpoints: 64,
+		CacheOffers:      4096,
+
+		SnapshotEvery: 256,
+		SnapshotKeep:  3,
+
+		DiscoverOfferThreshold:  20,
+		DiscoverVolumeThreshold: 1000,
+		DiscoverWindow:          time.Hour,
+		DiscoverDemoteAfter:     7 * 24 * time.Hour,
+	}
+}
+
+// Well-known XRPL network ids.
+const (
+	MainnetID uint32 = 0
+	TestnetID uint32 = 1
+	DevnetID  uint32 = 2
+)
+
+// ExpectedNetworkID returns the network id the connected server must
+// report; for "custom" it is the explicitly configured id.
+func (c *Config) ExpectedNetworkID() uint32 {
+	switch c.Network {
+	case "testnet":
+		return TestnetID
+	case "devnet":
+		return DevnetID
+	case "custom":
+		return c.NetworkID
+	default:
+		return MainnetID
+	}
+}
+
+// StartLedger returns the network's well-known first useful ledger: the
+// earliest ledger mainnet full-history servers carry, or the genesis
+// ledger on the resettable test chains.
+func (c *Config) StartLedger() uint64 {
+	if c.Network == "mainnet" {
+		return 32570
+	}
+	return 1
+}
+
+// keys lists every config key, in flag-name form.
+var keys = []string{
+	"rippled", "rippled-ws", "db-driver", "db-dsn", "archive-dsn",
+	"request-timeout", "ledger-timeout", "slow-query-threshold",
+	"metrics-addr", "pprof-addr", "fault-config", "partner-api-addr", "partner-api-auth", "partner-api-rate-limit",
+	"rpc-addr", "rpc-cert", "rpc-key", "rpc-client-ca",
+	"network", "network-id", "live", "ingest-mode", "stream-buffer",
+	"issuer-allow", "issuer-deny", "watch-issuers", "track-balances", "track-validations", "compute-funded",
+	"emit-events", "outbox-limit", "event-sink-url", "event-subject-prefix",
+	"record-fixtures",
+	"cache-checkpoints", "cache-offers",
+	"snapshot-dir", "snapshot-every", "snapshot-keep",
+	"discover-markets", "discover-offer-threshold", "discover-volume-threshold",
+	"discover-window", "discover-demote-after", "discover-subscribe-books",
+	"pool-history-downsample-after",
+	"maintenance-interval", "maintenance-jitter", "maintenance-audit-keep",
+	"offer-retention-ledgers",
+	"rippled-ws-failover", "watchdog-warn-after", "watchdog-act-after", "watchdog-action",
+	"catchup-lag-high", "catchup-lag-low", "priority-catchup",
+	"validator-score-window", "validator-score-interval",
+	"identity-refresh-interval",
+	"fee-poll-interval", "fee-spike-multiple",
+	"backfill-from", "backfill-to", "backfill-max-retries",
+	"backfill-retry-base", "backfill-max-backoff", "backfill-on-failure",
+	"backfill-max-rate",
+}
+
+// Known reports whether key is a config key; subcommands use it to skip
+// their own flags when overlaying parsed flag values.
+func Known(key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Set applies one key/value pair; values are in their flag string form.
+func (c *Config) Set(key, value string) error {
+	var err error
+	switch key {
+	case "rippled":
+		c.RippledURL = value
+	case "rippled-ws":
+		c.RippledWS = value
+	case "db-driver":
+		c.DBDriver = value
+	case "db-dsn":
+		c.DBDSN = value
+	case "archive-dsn":
+		c.ArchiveDSN = value
+	case "request-timeout":
+		c.RequestTimeout, err = time.ParseDuration(value)
+	case "ledger-timeout":
+		c.LedgerTimeout, err = time.ParseDuration(value)
+	case "slow-query-threshold":
+		c.SlowQueryThreshold, err = time.ParseDuration(value)
+	case "metrics-addr":
+		c.MetricsAddr = value
+	case "pprof-addr":
+		c.PprofAddr = value
+	case "fault-config":
+		c.FaultConfig = value
+	case "partner-api-addr":
+		c.PartnerAPIAddr = value
+	case "partner-api-auth":
+		c.PartnerAPIAuth, err = strconv.ParseBool(value)
+	case "partner-api-rate-limit":
+		c.PartnerAPIRateLimit, err = strconv.Atoi(value)
+	case "rpc-addr":
+		c.RPCAddr = value
+	case "rpc-cert":
+		c.RPCCert = value
+	case "rpc-key":
+		c.RPCKey = value
+	case "rpc-client-ca":
+		c.RPCClientCA = value
+	case "network":
+		c.Network = value
+	case "network-id":
+		var id uint64
+		id, err = strconv.ParseUint(value, 10, 32)
+		c.NetworkID = uint32(id)
+	case "live":
+		c.Live, err = strconv.ParseBool(value)
+	case "ingest-mode":
+		c.IngestMode = value
+	case "stream-buffer":
+		c.StreamBuffer, err = strconv.Atoi(value)
+	case "issuer-allow":
+		c.IssuerAllow = splitList(value)
+	case "issuer-deny":
+		c.IssuerDeny = splitList(value)
+	case "watch-issuers":
+		c.WatchIssuers = splitList(value)
+	case "track-balances":
+		c.TrackBalances, err = strconv.ParseBool(value)
+	case "track-validations":
+		c.TrackValidations, err = strconv.ParseBool(value)
+	case "compute-funded":
+		c.ComputeFunded, err = strconv.ParseBool(value)
+	case "emit-events":
+		c.EmitEvents, err = strconv.ParseBool(value)
+	case "outbox-limit":
+		c.OutboxLimit, err = strconv.Atoi(value)
+	case "event-sink-url":
+		c.EventSinkURL = value
+	case "event-subject-prefix":
+		c.EventSubjectPrefix = value
+	case "record-fixtures":
+		c.RecordFixtures = value
+	case "cache-checkpoints":
+		c.CacheCheckpoints, err = strconv.Atoi(value)
+	case "cache-offers":
+		c.CacheOffers, err = strconv.Atoi(value)
+	case "snapshot-dir":
+		c.SnapshotDir = value
+	case "snapshot-every":
+		c.SnapshotEvery, err = strconv.Atoi(value)
+	case "snapshot-keep":
+		c.SnapshotKeep, err = strconv.Atoi(value)
+	case "discover-markets":
+		c.DiscoverMarkets, err = strconv.ParseBool(value)
+	case "discover-offer-threshold":
+		c.DiscoverOfferThreshold, err = strconv.Atoi(value)
+	case "discover-volume-threshold":
+		c.DiscoverVolumeThreshold, err = strconv.Atoi(value)
+	case "discover-window":
+		c.DiscoverWindow, err = time.ParseDuration(value)
+	case "discover-demote-after":
+		c.DiscoverDemoteAfter, err = time.ParseDuration(value)
+	case "discover-subscribe-books":
+		c.DiscoverSubscribeBooks, err = strconv.ParseBool(value)
+	case "pool-history-downsample-after":
+		c.PoolHistoryDownsampleAfter, err = time.ParseDuration(value)
+	case "maintenance-interval":
+		c.MaintenanceInterval, err = time.ParseDuration(value)
+	case "maintenance-jitter":
+		c.MaintenanceJitter, err = time.ParseDuration(value)
+	case "maintenance-audit-keep":
+		c.MaintenanceAuditKeep, err = time.ParseDuration(value)
+	case "offer-retention-ledgers":
+		c.OfferRetentionLedgers, err = strconv.Atoi(value)
+	case "rippled-ws-failover":
+		c.RippledWSFailover = splitList(value)
+	case "watchdog-warn-after":
+		c.WatchdogWarnAfter, err = time.ParseDuration(value)
+	case "watchdog-act-after":
+		c.WatchdogActAfter, err = time.ParseDuration(value)
+	case "watchdog-action":
+		c.WatchdogAction = value
+	case "catchup-lag-high":
+		c.CatchupLagHigh, err = strconv.Atoi(value)
+	case "catchup-lag-low":
+		c.CatchupLagLow, err = strconv.Atoi(value)
+	case "priority-catchup":
+		c.PriorityCatchup, err = strconv.ParseBool(value)
+	case "validator-score-window":
+		c.ValidatorScoreWindow, err = time.ParseDuration(value)
+	case "validator-score-interval":
+		c.ValidatorScoreInterval, err = time.ParseDuration(value)
+	case "identity-refresh-interval":
+		c.IdentityRefreshInterval, err = time.ParseDuration(value)
+	case "fee-poll-interval":
+		c.FeePollInterval, err = time.ParseDuration(value)
+	case "fee-spike-multiple":
+		c.FeeSpikeMultiple, err = strconv.Atoi(value)
+	case "backfill-from":
+		c.BackfillFrom, err = strconv.ParseUint(value, 10, 64)
+	case "backfill-to":
+		c.BackfillTo, err = strconv.ParseUint(value, 10, 64)
+	case "backfill-max-retries":
+		c.BackfillMaxRetries, err = strconv.Atoi(value)
+	case "backfill-retry-base":
+		c.BackfillRetryBase, err = time.ParseDuration(value)
+	case "backfill-max-backoff":
+		c.BackfillMaxBackoff, err = time.ParseDuration(value)
+	case "backfill-on-failure":
+		c.BackfillOnFailure = value
+	case "backfill-max-rate":
+		c.BackfillMaxRate, err = strconv.ParseFloat(value, 64)
+	default:
+		return fmt.Errorf("config: unknown key %q", key)
+	}
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", key, err)
+	}
+	return nil
+}
+
+// splitList parses a comma-separated flag value, dropping empty entries
+// so "a,,b" and "a, b" both yield two issuers.
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// LoadFile overlays settings from a JSON or YAML file onto c.
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	var pairs map[string]string
+	if strings.HasSuffix(path, ".json") {
+		pairs, err = parseJSON(data)
+	} else {
+		pairs, err = parseYAML(data)
+	}
+	if err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	for key, value := range pairs {
+		if err := c.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyEnv overlays INDEXER_* environment variables onto c; the variable
+// for key "backfill-from" is INDEXER_BACKFILL_FROM.
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	for _, key := range keys {
+		name := "INDEXER_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		if value, ok := lookup(name); ok {
+			if err := c.Set(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Validate rejects configurations that cannot run.
+func (c *Config) Validate() error {
+	if c.RippledURL == "" {
+		return fmt.Errorf("config: rippled URL must not be empty")
+	}
+	if c.ArchiveDSN != "" && c.DBDSN == "" {
+		return fmt.Errorf("config: archive-dsn needs a primary db-dsn to forward from")
+	}
+	if c.BackfillTo != 0 && c.BackfillFrom == 0 {
+		return fmt.Errorf("config: backfill-to without backfill-from")
+	}
+	if c.BackfillFrom != 0 && c.BackfillTo < c.BackfillFrom {
+		return fmt.Errorf("config: backfill range %d..%d is empty", c.BackfillFrom, c.BackfillTo)
+	}
+	if c.BackfillMaxRetries < 0 {
+		return fmt.Errorf("config: backfill-max-retries must be >= 0")
+	}
+	if c.RequestTimeout < 0 {
+		return fmt.Errorf("config: request-timeout must not be negative")
+	}
+	if c.LedgerTimeout < 0 {
+		return fmt.Errorf("config: ledger-timeout must not be negative")
+	}
+	if c.SlowQueryThreshold < 0 {
+		return fmt.Errorf("config: slow-query-threshold must not be negative")
+	}
+	if c.PoolHistoryDownsampleAfter < 0 {
+		return fmt.Errorf("config: pool-history-downsample-after must not be negative")
+	}
+	if c.WatchdogWarnAfter < 0 || c.WatchdogActAfter < 0 {
+		return fmt.Errorf("config: watchdog thresholds must not be negative")
+	}
+	if c.WatchdogWarnAfter > 0 && c.WatchdogActAfter > 0 && c.WatchdogActAfter < c.WatchdogWarnAfter {
+		return fmt.Errorf("config: watchdog-act-after must not come before watchdog-warn-after")
+	}
+	switch c.WatchdogAction {
+	case "reconnect", "failover", "exit":
+	default:
+		return fmt.Errorf("config: unknown watchdog-action %q", c.WatchdogAction)
+	}
+	if c.CatchupLagHigh < 0 || c.CatchupLagLow < 0 {
+		return fmt.Errorf("config: catch-up lag marks must not be negative")
+	}
+	if c.CatchupLagHigh > 0 && c.CatchupLagLow >= c.CatchupLagHigh {
+		return fmt.Errorf("config: catchup-lag-low must be below catchup-lag-high")
+	}
+	if c.PriorityCatchup && c.CatchupLagHigh == 0 {
+		return fmt.Errorf("config: priority-catchup needs a catchup-lag-high mark")
+	}
+	if c.SnapshotDir != "" && (c.SnapshotEvery <= 0 || c.SnapshotKeep <= 0) {
+		return fmt.Errorf("config: snapshot-dir needs positive snapshot-every and snapshot-keep")
+	}
+	if c.ValidatorScoreWindow < 0 || c.ValidatorScoreInterval < 0 {
+		return fmt.Errorf("config: validator score settings must not be negative")
+	}
+	if c.ValidatorScoreInterval > 0 && c.ValidatorScoreWindow <= 0 {
+		return fmt.Errorf("config: validator-score-interval needs a positive validator-score-window")
+	}
+	if c.IdentityRefreshInterval < 0 {
+		return fmt.Errorf("config: identity-refresh-interval must not be negative")
+	}
+	if c.OfferRetentionLedgers < 0 {
+		return fmt.Errorf("config: offer-retention-ledgers must not be negative")
+	}
+	if c.EventSinkURL != "" && !strings.HasPrefix(c.EventSinkURL, "nats://") {
+		return fmt.Errorf("config: event-sink-url must be a nats:// URL")
+	}
+	if c.FeePollInterval < 0 {
+		return fmt.Errorf("config: fee-poll-interval must not be negative")
+	}
+	if c.FeeSpikeMultiple < 0 {
+		return fmt.Errorf("config: fee-spike-multiple must not be negative")
+	}
+	if c.PartnerAPIRateLimit < 0 {
+		return fmt.Errorf("config: partner-api-rate-limit must not be negative")
+	}
+	if (c.RPCCert == "") != (c.RPCKey == "") {
+		return fmt.Errorf("config: rpc-cert and rpc-key must be set together")
+	}
+	if c.RPCClientCA != "" && c.RPCCert == "" {
+		return fmt.Errorf("config: rpc-client-ca needs rpc-cert and rpc-key")
+	}
+	if c.BackfillRetryBase <= 0 || c.BackfillMaxBackoff <= 0 {
+		return fmt.Errorf("config: backfill retry delays must be positive")
+	}
+	switch c.Network {
+	case "mainnet", "testnet", "devnet", "custom":
+	default:
+		return fmt.Errorf("config: unknown network %q", c.Network)
+	}
+	switch c.IngestMode {
+	case "ledger", "transactions":
+	default:
+		return fmt.Errorf("config: unknown ingest-mode %q", c.IngestMode)
+	}
+	switch c.BackfillOnFailure {
+	case "stop", "skip":
+	default:
+		return fmt.Errorf("config: unknown backfill-on-failure %q", c.BackfillOnFailure)
+	}
+	return nil
+}
+
+func parseJSON(data []byte) (map[string]string, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	pairs := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case string:
+			pairs[key] = v
+		case bool:
+			pairs[key] = strconv.FormatBool(v)
+		case float64:
+			pairs[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return nil, fmt.Errorf("key %q has unsupported type %T", key, value)
+		}
+	}
+	return pairs, nil
+}
+
+// parseYAML handles the flat "key: value" subset the indexer's config
+// files use — no nesting, lists, or multi-line values.
+func parseYAML(data []byte) (map[string]string, error) {
+	pairs := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing ':'", i+1)
+		}
+		value = strings.TrimSpace(value)
+		if comment := strings.Index(value, " #"); comment >= 0 {
+			value = strings.TrimSpace(value[:comment])
+		}
+		value = strings.Trim(value, `"'`)
+		pairs[strings.TrimSpace(key)] = value
+	}
+	return pairs, nil
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadYAMLFile(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+# production settings
+rippled: "https://xrplcluster.com:5005"
+live: true
+backfill-from: 80000000
+backfill-to: 80001000  # one chunk
+backfill-retry-base: 2s
+`)
+	cfg := Default()
+	if err := cfg.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RippledURL != "https://xrplcluster.com:5005" {
+		t.Errorf("RippledURL = %q", cfg.RippledURL)
+	}
+	if !cfg.Live || cfg.BackfillFrom != 80000000 || cfg.BackfillTo != 80001000 {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if cfg.BackfillRetryBase != 2*time.Second {
+		t.Errorf("BackfillRetryBase = %v", cfg.BackfillRetryBase)
+	}
+	// Untouched keys keep their defaults.
+	if cfg.IngestMode != "ledger" {
+		t.Errorf("IngestMode = %q, want default", cfg.IngestMode)
+	}
+}
+
+func TestLoadJSONFile(t *testing.T) {
+	path := writeFile(t, "config.json", `{"db-dsn": "host=db", "backfill-max-retries": 5, "live": true}`)
+	cfg := Default()
+	if err := cfg.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DBDSN != "host=db" || cfg.BackfillMaxRetries != 5 || !cfg.Live {
+		t.Errorf("cfg = %+v", cfg)
+	}
+}
+
+func TestPrecedenceFileEnvFlag(t *testing.T) {
+	path := writeFile(t, "config.yaml", "rippled: http://from-file\ndb-dsn: file-dsn\ningest-mode: transactions\n")
+	cfg := Default()
+	if err := cfg.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"INDEXER_RIPPLED": "http://from-env",
+		"INDEXER_DB_DSN":  "env-dsn",
+	}
+	if err := cfg.ApplyEnv(func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// An explicitly set flag wins over both.
+	if err := cfg.Set("rippled", "http://from-flag"); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.RippledURL != "http://from-flag" {
+		t.Errorf("RippledURL = %q, want flag value", cfg.RippledURL)
+	}
+	if cfg.DBDSN != "env-dsn" {
+		t.Errorf("DBDSN = %q, want env value over file", cfg.DBDSN)
+	}
+	if cfg.IngestMode != "transactions" {
+		t.Errorf("IngestMode = %q, want file value", cfg.IngestMode)
+	}
+}
+
+func TestIssuerLists(t *testing.T) {
+	cfg := Default()
+	if err := cfg.Set("issuer-allow", "rA, rB,,rC"); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.IssuerAllow) != 3 || cfg.IssuerAllow[1] != "rB" {
+		t.Errorf("IssuerAllow = %v", cfg.IssuerAllow)
+	}
+	if err := cfg.Set("issuer-deny", ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.IssuerDeny) != 0 {
+		t.Errorf("IssuerDeny = %v, want empty", cfg.IssuerDeny)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	good := Default()
+	if err := good.Validate(); err != nil {
+		t.Errorf("default config invalid: %v", err)
+	}
+
+	cases := []func(*Config){
+		func(c *Config) { c.RippledURL = "" },
+		func(c *Config) { c.BackfillTo = 10 },                      // to without from
+		func(c *Config) { c.BackfillFrom = 20; c.BackfillTo = 10 }, // empty range
+		func(c *Config) { c.BackfillMaxRetries = -1 },
+		func(c *Config) { c.BackfillRetryBase = 0 },
+		func(c *Config) { c.IngestMode = "firehose" },
+		func(c *Config) { c.BackfillOnFailure = "panic" },
+	}
+	for i, mutate := range cases {
+		cfg := Default()
+		mutate(&cfg)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("case %d: invalid config passed validation: %+v", i, cfg)
+		}
+	}
+}
+
+func TestEveryKeyIsSettable(t *testing.T) {
+	// Every key the env/flag overlay iterates must have a Set case;
+	// a key missing from the list is silently dropped instead, which is
+	// how the partner-api and catch-up keys once became unreachable from
+	// flags and the environment.
+	for _, key := range keys {
+		cfg := Default()
+		if err := cfg.Set(key, "nonsense"); err != nil &&
+			strings.Contains(err.Error(), "unknown key") {
+			t.Errorf("listed key %q is not settable: %v", key, err)
+		}
+	}
+}
+
+func TestSetUnknownKey(t *testing.T) {
+	cfg := Default()
+	if err := cfg.Set("warp-speed", "9"); err == nil {
+		t.Error("unknown key accepted")
+	}
+}
+
+func TestNetworkDefaults(t *testing.T) {
+	cfg := Default()
+	if cfg.Network != "mainnet" || cfg.ExpectedNetworkID() != MainnetID || cfg.StartLedger() != 32570 {
+		t.Errorf("mainnet defaults = %s/%d/%d", cfg.Network, cfg.ExpectedNetworkID(), cfg.StartLedger())
+	}
+	cfg.Network = "testnet"
+	if cfg.ExpectedNetworkID() != TestnetID || cfg.StartLedger() != 1 {
+		t.Errorf("testnet = %d/%d", cfg.ExpectedNetworkID(), cfg.StartLedger())
+	}
+	cfg.Network = "custom"
+	cfg.NetworkID = 21337
+	if cfg.ExpectedNetworkID() != 21337 {
+		t.Errorf("custom id = %d", cfg.ExpectedNetworkID())
+	}
+	cfg.Network = "ropsten"
+	if err := cfg.Validate(); err == nil {
+		t.Error("unknown network accepted")
+	}
+}
@@ -0,0 +1,79 @@
+package config
+
+import "fmt"
+
+// ApplyReloadable merges the settings that are safe to change at
+// runtime from next into a copy of c: issuer filters, watchdog
+// thresholds, the pool history retention window, and the Partner API
+// default rate limit. Everything else keeps its current value; changed
+// describes what was taken (for the reload log) and rejected names
+// immutable keys next tried to change. Callers must Validate the
+// merged config before adopting it.
+func (c Config) ApplyReloadable(next Config) (merged Config, changed, rejected []string) {
+	merged = c
+	note := func(key string, old, new any) {
+		changed = append(changed, fmt.Sprintf("%s: %v -> %v", key, old, new))
+	}
+	if !stringSlicesEqual(c.IssuerAllow, next.IssuerAllow) {
+		merged.IssuerAllow = next.IssuerAllow
+		note("issuer-allow", len(c.IssuerAllow), len(next.IssuerAllow))
+	}
+	if !stringSlicesEqual(c.IssuerDeny, next.IssuerDeny) {
+		merged.IssuerDeny = next.IssuerDeny
+		note("issuer-deny", len(c.IssuerDeny), len(next.IssuerDeny))
+	}
+	if c.WatchdogWarnAfter != next.WatchdogWarnAfter {
+		merged.WatchdogWarnAfter = next.WatchdogWarnAfter
+		note("watchdog-warn-after", c.WatchdogWarnAfter, next.WatchdogWarnAfter)
+	}
+	if c.WatchdogActAfter != next.WatchdogActAfter {
+		merged.WatchdogActAfter = next.WatchdogActAfter
+		note("watchdog-act-after", c.WatchdogActAfter, next.WatchdogActAfter)
+	}
+	if c.PoolHistoryDownsampleAfter != next.PoolHistoryDownsampleAfter {
+		merged.PoolHistoryDownsampleAfter = next.PoolHistoryDownsampleAfter
+		note("pool-history-downsample-after", c.PoolHistoryDownsampleAfter, next.PoolHistoryDownsampleAfter)
+	}
+	if c.PartnerAPIRateLimit != next.PartnerAPIRateLimit {
+		merged.PartnerAPIRateLimit = next.PartnerAPIRateLimit
+		note("partner-api-rate-limit", c.PartnerAPIRateLimit, next.PartnerAPIRateLimit)
+	}
+
+	immutable := []struct {
+		key  string
+		same bool
+	}{
+		{"rippled-url", c.RippledURL == next.RippledURL},
+		{"rippled-ws", c.RippledWS == next.RippledWS},
+		{"db-driver", c.DBDriver == next.DBDriver},
+		{"db-dsn", c.DBDSN == next.DBDSN},
+		{"network", c.Network == next.Network},
+		{"network-id", c.NetworkID == next.NetworkID},
+		{"live", c.Live == next.Live},
+		{"ingest-mode", c.IngestMode == next.IngestMode},
+		{"track-balances", c.TrackBalances == next.TrackBalances},
+		{"metrics-addr", c.MetricsAddr == next.MetricsAddr},
+		{"partner-api-addr", c.PartnerAPIAddr == next.PartnerAPIAddr},
+		{"partner-api-auth", c.PartnerAPIAuth == next.PartnerAPIAuth},
+		{"backfill-from", c.BackfillFrom == next.BackfillFrom},
+		{"backfill-to", c.BackfillTo == next.BackfillTo},
+	}
+	for _, im := range immutable {
+		if !im.same {
+			rejected = append(rejected, im.key)
+		}
+	}
+	return merged, changed, rejected
+}
+
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyReloadable(t *testing.T) {
+	cur := Default()
+	cur.DBDSN = "postgres://indexer"
+
+	next := Default()
+	next.DBDSN = "postgres://indexer"
+	next.IssuerAllow = []string{"rIssuer"}
+	next.WatchdogWarnAfter = 2 * time.Minute
+	next.PartnerAPIRateLimit = 120
+
+	merged, changed, rejected := cur.ApplyReloadable(next)
+	if len(rejected) != 0 {
+		t.Errorf("rejected = %v, want none", rejected)
+	}
+	if len(changed) != 3 {
+		t.Errorf("changed = %v, want 3 entries", changed)
+	}
+	if len(merged.IssuerAllow) != 1 || merged.WatchdogWarnAfter != 2*time.Minute || merged.PartnerAPIRateLimit != 120 {
+		t.Errorf("merged did not take reloadable values: %+v", merged)
+	}
+	if cur.PartnerAPIRateLimit != Default().PartnerAPIRateLimit {
+		t.Error("ApplyReloadable mutated the receiver")
+	}
+}
+
+func TestApplyReloadableRejectsImmutable(t *testing.T) {
+	cur := Default()
+	next := Default()
+	next.DBDSN = "postgres://elsewhere"
+	next.Network = "testnet"
+	next.LedgerTimeout = time.Minute // unlisted: silently kept
+
+	merged, changed, rejected := cur.ApplyReloadable(next)
+	if len(changed) != 0 {
+		t.Errorf("changed = %v, want none", changed)
+	}
+	if strings.Join(rejected, ",") != "db-dsn,network" {
+		t.Errorf("rejected = %v, want [db-dsn network]", rejected)
+	}
+	if merged.DBDSN != cur.DBDSN || merged.Network != cur.Network || merged.LedgerTimeout != cur.LedgerTimeout {
+		t.Errorf("merged took immutable values: %+v", merged)
+	}
+}
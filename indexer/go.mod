@@ -0,0 +1,5 @@
+module github.com/2pk03/lucendex-validator-verification/indexer
+
+go 1.21
+
+require github.com/lib/pq v1.10.9
@@ -0,0 +1,137 @@
+// Package ingest assembles live stream messages into whole ledgers so the
+// rest of the pipeline processes live and backfilled data identically.
+package ingest
+
+import (
+	"log"
+	"sort"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// Grouper reassembles per-transaction stream messages into complete
+// ledgers. Transactions for ledger N may arrive before or after the
+// ledgerClosed message for N; the grouper buffers until it has both the
+// header and txn_count transactions. If a later ledger closes while N is
+// still incomplete, N is fetched synchronously instead so nothing is
+// emitted with missing transactions.
+type Grouper struct {
+	fetch func(index uint64) (*xrpl.Ledger, error)
+	emit  func(*xrpl.Ledger) error
+	logf  func(format string, args ...any)
+
+	pending     map[uint64]*pendingLedger
+	lastEmitted uint64
+}
+
+type pendingLedger struct {
+	header   *xrpl.Ledger
+	txnCount int
+	txs      []*xrpl.Transaction
+}
+
+// NewGrouper returns a Grouper that emits complete ledgers and uses fetch
+// as the fallback for incomplete ones.
+func NewGrouper(fetch func(index uint64) (*xrpl.Ledger, error), emit func(*xrpl.Ledger) error) *Grouper {
+	return &Grouper{
+		fetch:   fetch,
+		emit:    emit,
+		logf:    log.Printf,
+		pending: make(map[uint64]*pendingLedger),
+	}
+}
+
+// OnTransaction buffers one validated transaction from the stream,
+// emitting its ledger if that completes it.
+func (g *Grouper) OnTransaction(m *xrpl.TxMessage) error {
+	if !m.Validated || m.LedgerIndex <= g.lastEmitted {
+		return nil
+	}
+	p := g.pendingFor(m.LedgerIndex)
+	p.txs = append(p.txs, m.Tx)
+	return g.maybeEmit(m.LedgerIndex)
+}
+
+// OnLedgerClosed records a ledger header. It also resolves every older
+// pending ledger: complete ones are emitted, incomplete ones fall back to
+// a synchronous fetch.
+func (g *Grouper) OnLedgerClosed(lc *xrpl.LedgerClosed) error {
+	p := g.pendingFor(lc.Index)
+	p.header = &xrpl.Ledger{Index: lc.Index, Hash: lc.Hash, CloseTime: lc.CloseTime, TxnCount: lc.TxnCount}
+	p.txnCount = lc.TxnCount
+
+	for _, index := range g.pendingBefore(lc.Index) {
+		stale := g.pending[index]
+		if stale.header != nil && len(stale.txs) == stale.txnCount {
+			if err := g.emitPending(index); err != nil {
+				return err
+			}
+			continue
+		}
+		g.logf("ingest: ledger %d incomplete on stream (%d/%d txs), fetching", index, len(stale.txs), stale.txnCount)
+		delete(g.pending, index)
+		lg, err := g.fetch(index)
+		if err != nil {
+			return err
+		}
+		if err := g.emitLedger(lg); err != nil {
+			return err
+		}
+	}
+	return g.maybeEmit(lc.Index)
+}
+
+func (g *Grouper) pendingFor(index uint64) *pendingLedger {
+	p, ok := g.pending[index]
+	if !ok {
+		p = &pendingLedger{}
+		g.pending[index] = p
+	}
+	return p
+}
+
+// pendingBefore returns pending ledger indexes below the given one, in
+// ascending order.
+func (g *Grouper) pendingBefore(index uint64) []uint64 {
+	var older []uint64
+	for i := range g.pending {
+		if i < index {
+			older = append(older, i)
+		}
+	}
+	sort.Slice(older, func(a, b int) bool { return older[a] < older[b] })
+	return older
+}
+
+func (g *Grouper) maybeEmit(index uint64) error {
+	p, ok := g.pending[index]
+	if !ok || p.header == nil || len(p.txs) != p.txnCount {
+		return nil
+	}
+	return g.emitPending(index)
+}
+
+func (g *Grouper) emitPending(index uint64) error {
+	p := g.pending[index]
+	delete(g.pending, index)
+	lg := p.header
+	sort.SliceStable(p.txs, func(a, b int) bool {
+		am, bm := p.txs[a].Meta, p.txs[b].Meta
+		if am == nil || bm == nil {
+			return false
+		}
+		return am.TransactionIndex < bm.TransactionIndex
+	})
+	lg.Transactions = p.txs
+	return g.emitLedger(lg)
+}
+
+func (g *Grouper) emitLedger(lg *xrpl.Ledger) error {
+	if err := g.emit(lg); err != nil {
+		return err
+	}
+	if lg.Index > g.lastEmitted {
+		g.lastEmitted = lg.Index
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package ingest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func testGrouper(t *testing.T) (*Grouper, *[]uint64, *[]uint64) {
+	t.Helper()
+	var emitted, fetched []uint64
+	g := NewGrouper(
+		func(index uint64) (*xrpl.Ledger, error) {
+			fetched = append(fetched, index)
+			return &xrpl.Ledger{Index: index}, nil
+		},
+		func(lg *xrpl.Ledger) error {
+			emitted = append(emitted, lg.Index)
+			return nil
+		})
+	g.logf = func(string, ...any) {}
+	return g, &emitted, &fetched
+}
+
+func txMsg(ledger uint64, txIndex uint32) *xrpl.TxMessage {
+	return &xrpl.TxMessage{
+		Tx: &xrpl.Transaction{
+			Hash: fmt.Sprintf("TX-%d-%d", ledger, txIndex),
+			Meta: &xrpl.Meta{TransactionIndex: txIndex, TransactionResult: "tesSUCCESS"},
+		},
+		LedgerIndex: ledger,
+		Validated:   true,
+	}
+}
+
+func TestGrouperTxsBeforeClose(t *testing.T) {
+	g, emitted, fetched := testGrouper(t)
+	if err := g.OnTransaction(txMsg(100, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.OnTransaction(txMsg(100, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if len(*emitted) != 0 {
+		t.Fatal("emitted before ledgerClosed")
+	}
+	if err := g.OnLedgerClosed(&xrpl.LedgerClosed{Index: 100, TxnCount: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*emitted) != 1 || (*emitted)[0] != 100 {
+		t.Fatalf("emitted = %v, want [100]", *emitted)
+	}
+	if len(*fetched) != 0 {
+		t.Fatalf("unexpected fallback fetches %v", *fetched)
+	}
+}
+
+func TestGrouperTxsAfterClose(t *testing.T) {
+	g, emitted, _ := testGrouper(t)
+	if err := g.OnLedgerClosed(&xrpl.LedgerClosed{Index: 101, TxnCount: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*emitted) != 0 {
+		t.Fatal("emitted an incomplete ledger")
+	}
+	if err := g.OnTransaction(txMsg(101, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if len(*emitted) != 1 || (*emitted)[0] != 101 {
+		t.Fatalf("emitted = %v, want [101]", *emitted)
+	}
+}
+
+func TestGrouperFallsBackWhenCountMismatch(t *testing.T) {
+	g, emitted, fetched := testGrouper(t)
+	// Ledger 102 closes claiming 2 txs but only 1 arrives.
+	if err := g.OnTransaction(txMsg(102, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.OnLedgerClosed(&xrpl.LedgerClosed{Index: 102, TxnCount: 2}); err != nil {
+		t.Fatal(err)
+	}
+	// The next close forces 102 to resolve via fetch.
+	if err := g.OnLedgerClosed(&xrpl.LedgerClosed{Index: 103, TxnCount: 0}); err != nil {
+		t.Fatal(err)
+	}
+	if len(*fetched) != 1 || (*fetched)[0] != 102 {
+		t.Fatalf("fetched = %v, want [102]", *fetched)
+	}
+	if len(*emitted) != 2 || (*emitted)[0] != 102 || (*emitted)[1] != 103 {
+		t.Fatalf("emitted = %v, want [102 103]", *emitted)
+	}
+}
+
+func TestGrouperOrdersTxsAndDropsStale(t *testing.T) {
+	g, emitted, _ := testGrouper(t)
+	if err := g.OnTransaction(txMsg(104, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.OnTransaction(txMsg(104, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.OnTransaction(txMsg(104, 1)); err != nil {
+		t.Fatal(err)
+	}
+	var got *xrpl.Ledger
+	g.emit = func(lg *xrpl.Ledger) error {
+		got = lg
+		*emitted = append(*emitted, lg.Index)
+		return nil
+	}
+	if err := g.OnLedgerClosed(&xrpl.LedgerClosed{Index: 104, TxnCount: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if got == nil {
+		t.Fatal("ledger 104 not emitted")
+	}
+	for i, tx := range got.Transactions {
+		if int(tx.Meta.TransactionIndex) != i {
+			t.Fatalf("tx %d has TransactionIndex %d", i, tx.Meta.TransactionIndex)
+		}
+	}
+	// A late duplicate for an already-emitted ledger is dropped.
+	if err := g.OnTransaction(txMsg(104, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.pending) != 0 {
+		t.Fatalf("stale tx buffered: %v", g.pending)
+	}
+}
@@ -0,0 +1,114 @@
+package ingest
+
+import (
+	"fmt"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/internal/faultinject"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// Ingestion modes selectable with -ingest-mode.
+const (
+	// ModeLedger subscribes to the ledger stream and fetches each closed
+	// ledger in full — one extra round trip per ledger.
+	ModeLedger = "ledger"
+	// ModeTransactions subscribes to the transactions stream, which
+	// already carries metadata, and reassembles ledgers locally.
+	ModeTransactions = "transactions"
+)
+
+// Run consumes a subscription until it fails, feeding each completed
+// ledger to process. fetch supplies the full-ledger fallback. onGap (may
+// be nil) hears about ledgers the stream skipped that could not be
+// recovered by fetching either. onValidation (may be nil) receives each
+// message from the validations stream, when subscribed.
+func Run(mode string, sub *xrpl.Subscriber, fetch func(index uint64) (*xrpl.Ledger, error), process func(*xrpl.Ledger) error, onGap func(index uint64, cause error), onValidation func(*xrpl.ValidationMessage) error) error {
+	switch mode {
+	case ModeLedger:
+		return runLedgerMode(sub, fetch, process, onGap, onValidation)
+	case ModeTransactions:
+		return runTransactionsMode(sub, fetch, process, onValidation)
+	}
+	return fmt.Errorf("ingest: unknown mode %q (want %s or %s)", mode, ModeLedger, ModeTransactions)
+}
+
+// Streams returns the rippled streams a mode needs to subscribe to;
+// validations adds the validations stream on top of the mode's own.
+func Streams(mode string, validations bool) []string {
+	streams := []string{"ledger"}
+	if mode == ModeTransactions {
+		streams = append(streams, "transactions")
+	}
+	if validations {
+		streams = append(streams, "validations")
+	}
+	return streams
+}
+
+func runLedgerMode(sub *xrpl.Subscriber, fetch func(uint64) (*xrpl.Ledger, error), process func(*xrpl.Ledger) error, onGap func(uint64, error), onValidation func(*xrpl.ValidationMessage) error) error {
+	// The stream occasionally delivers ledgerClosed messages out of order
+	// after a reconnect; the reorder buffer restores continuity before
+	// anything reaches process.
+	reorder := NewReorder(fetch, process)
+	if onGap != nil {
+		reorder.OnGap(onGap)
+	}
+	for {
+		msg, err := sub.Next()
+		if err != nil {
+			return err
+		}
+		if msg.Validation != nil && onValidation != nil {
+			if err := onValidation(msg.Validation); err != nil {
+				return err
+			}
+			continue
+		}
+		if msg.LedgerClosed == nil {
+			continue
+		}
+		if faultinject.Fire(faultinject.LedgerDrop) {
+			continue
+		}
+		deliveries := 1
+		if faultinject.Fire(faultinject.LedgerDuplicate) {
+			deliveries = 2
+		}
+		for ; deliveries > 0; deliveries-- {
+			lg, err := fetch(msg.LedgerClosed.Index)
+			if err != nil {
+				return err
+			}
+			if err := reorder.Offer(lg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func runTransactionsMode(sub *xrpl.Subscriber, fetch func(uint64) (*xrpl.Ledger, error), process func(*xrpl.Ledger) error, onValidation func(*xrpl.ValidationMessage) error) error {
+	g := NewGrouper(fetch, process)
+	for {
+		msg, err := sub.Next()
+		if err != nil {
+			return err
+		}
+		switch {
+		case msg.Transaction != nil:
+			err = g.OnTransaction(msg.Transaction)
+		case msg.LedgerClosed != nil:
+			if faultinject.Fire(faultinject.LedgerDrop) {
+				continue
+			}
+			err = g.OnLedgerClosed(msg.LedgerClosed)
+			if err == nil && faultinject.Fire(faultinject.LedgerDuplicate) {
+				err = g.OnLedgerClosed(msg.LedgerClosed)
+			}
+		case msg.Validation != nil && onValidation != nil:
+			err = onValidation(msg.Validation)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package ingest
+
+import (
+	"log"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// Reorder defaults: how far ahead the stream may run before a missing
+// predecessor is fetched explicitly.
+const (
+	DefaultMaxHold = 3
+	DefaultMaxWait = 15 * time.Second
+)
+
+// Reorder restores sequential continuity when stream messages arrive out
+// of order, e.g. after a brief disconnect. Ledgers ahead of the expected
+// index are held until their predecessors have been processed; once the
+// buffer holds too many ledgers or the oldest has waited too long, the
+// missing predecessors are fetched explicitly and processed first. A
+// predecessor that cannot be fetched either is handed to the gap callback
+// and skipped rather than stalling the stream forever.
+type Reorder struct {
+	fetch   func(uint64) (*xrpl.Ledger, error)
+	process func(*xrpl.Ledger) error
+
+	maxHold int
+	maxWait time.Duration
+	onGap   func(index uint64, cause error)
+	logf    func(format string, args ...any)
+	now     func() time.Time // test seam
+
+	next      uint64 // next expected index; zero before the first ledger
+	held      map[uint64]*xrpl.Ledger
+	heldSince time.Time
+}
+
+// NewReorder builds a reorder buffer with the default hold limits.
+func NewReorder(fetch func(uint64) (*xrpl.Ledger, error), process func(*xrpl.Ledger) error) *Reorder {
+	return &Reorder{
+		fetch:   fetch,
+		process: process,
+		maxHold: DefaultMaxHold,
+		maxWait: DefaultMaxWait,
+		onGap:   func(uint64, error) {},
+		logf:    log.Printf,
+		now:     time.Now,
+		held:    map[uint64]*xrpl.Ledger{},
+	}
+}
+
+// SetLimits overrides how many ledgers (and for how long) the buffer
+// holds before force-fetching a missing predecessor.
+func (r *Reorder) SetLimits(maxHold int, maxWait time.Duration) {
+	if maxHold > 0 {
+		r.maxHold = maxHold
+	}
+	if maxWait > 0 {
+		r.maxWait = maxWait
+	}
+}
+
+// OnGap registers a callback for ledgers given up on after fetch also
+// failed.
+func (r *Reorder) OnGap(fn func(index uint64, cause error)) { r.onGap = fn }
+
+// Offer hands the buffer one ledger in arrival order. In-order ledgers
+// are processed immediately, followed by any held successors; out-of-
+// order ledgers are held within the configured limits.
+func (r *Reorder) Offer(lg *xrpl.Ledger) error {
+	if r.next != 0 && lg.Index < r.next {
+		// Already processed (or recorded as a gap): a stale duplicate.
+		return nil
+	}
+	if r.next == 0 || lg.Index == r.next {
+		if err := r.emit(lg); err != nil {
+			return err
+		}
+		return r.drain()
+	}
+
+	if len(r.held) == 0 {
+		r.heldSince = r.now()
+	}
+	r.held[lg.Index] = lg
+	if len(r.held) < r.maxHold && r.now().Sub(r.heldSince) < r.maxWait {
+		return nil
+	}
+
+	// Limits exceeded: recover the missing predecessors explicitly.
+	for r.next < r.lowestHeld() {
+		missing := r.next
+		fetched, err := r.fetch(missing)
+		if err != nil {
+			r.logf("ingest: ledger %d unrecoverable, recording gap: %v", missing, err)
+			r.onGap(missing, err)
+			r.next = missing + 1
+			continue
+		}
+		if err := r.emit(fetched); err != nil {
+			return err
+		}
+	}
+	return r.drain()
+}
+
+func (r *Reorder) emit(lg *xrpl.Ledger) error {
+	if err := r.process(lg); err != nil {
+		return err
+	}
+	r.next = lg.Index + 1
+	return nil
+}
+
+// drain processes consecutively held successors of the last emitted
+// ledger.
+func (r *Reorder) drain() error {
+	for {
+		lg, ok := r.held[r.next]
+		if !ok {
+			if len(r.held) == 0 {
+				r.heldSince = time.Time{}
+			}
+			return nil
+		}
+		delete(r.held, r.next)
+		if err := r.emit(lg); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *Reorder) lowestHeld() uint64 {
+	var lowest uint64
+	for index := range r.held {
+		if lowest == 0 || index < lowest {
+			lowest = index
+		}
+	}
+	return lowest
+}
@@ -0,0 +1,112 @@
+package ingest
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+type reorderHarness struct {
+	*Reorder
+	processed []uint64
+	fetched   []uint64
+	gaps      []uint64
+	fetchErr  map[uint64]error
+	clock     time.Time
+}
+
+func newReorderHarness() *reorderHarness {
+	h := &reorderHarness{fetchErr: map[uint64]error{}, clock: time.Unix(1000, 0)}
+	h.Reorder = NewReorder(
+		func(index uint64) (*xrpl.Ledger, error) {
+			h.fetched = append(h.fetched, index)
+			if err := h.fetchErr[index]; err != nil {
+				return nil, err
+			}
+			return &xrpl.Ledger{Index: index}, nil
+		},
+		func(lg *xrpl.Ledger) error {
+			h.processed = append(h.processed, lg.Index)
+			return nil
+		},
+	)
+	h.Reorder.logf = func(string, ...any) {}
+	h.Reorder.now = func() time.Time { return h.clock }
+	h.Reorder.OnGap(func(index uint64, _ error) { h.gaps = append(h.gaps, index) })
+	return h
+}
+
+func (h *reorderHarness) offer(t *testing.T, indexes ...uint64) {
+	t.Helper()
+	for _, index := range indexes {
+		if err := h.Offer(&xrpl.Ledger{Index: index}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReorderScriptedDelivery(t *testing.T) {
+	// The order from the request: 100, 102, 101, 105. 102 is held until
+	// 101 arrives; 105 is held because 103/104 are still missing and the
+	// hold limits aren't hit yet.
+	h := newReorderHarness()
+	h.offer(t, 100, 102, 101, 105)
+	if want := []uint64{100, 101, 102}; !reflect.DeepEqual(h.processed, want) {
+		t.Errorf("processed = %v, want %v", h.processed, want)
+	}
+	if len(h.gaps) != 0 || len(h.fetched) != 0 {
+		t.Errorf("gaps = %v, fetched = %v before limits hit", h.gaps, h.fetched)
+	}
+
+	// 103 and 104 eventually arrive; everything drains in order.
+	h.offer(t, 104, 103)
+	if want := []uint64{100, 101, 102, 103, 104, 105}; !reflect.DeepEqual(h.processed, want) {
+		t.Errorf("processed = %v, want %v", h.processed, want)
+	}
+}
+
+func TestReorderForceFetchAfterMaxHold(t *testing.T) {
+	h := newReorderHarness()
+	h.offer(t, 100, 102, 103, 104)
+	// Holding 102-104 hits DefaultMaxHold: 101 is fetched and processed
+	// first, then the buffer drains.
+	if want := []uint64{101}; !reflect.DeepEqual(h.fetched, want) {
+		t.Errorf("fetched = %v, want %v", h.fetched, want)
+	}
+	if want := []uint64{100, 101, 102, 103, 104}; !reflect.DeepEqual(h.processed, want) {
+		t.Errorf("processed = %v, want %v", h.processed, want)
+	}
+}
+
+func TestReorderMaxWaitTriggersFetch(t *testing.T) {
+	h := newReorderHarness()
+	h.offer(t, 100, 102)
+	h.clock = h.clock.Add(DefaultMaxWait)
+	h.offer(t, 103)
+	if want := []uint64{101}; !reflect.DeepEqual(h.fetched, want) {
+		t.Errorf("fetched = %v, want %v", h.fetched, want)
+	}
+	if want := []uint64{100, 101, 102, 103}; !reflect.DeepEqual(h.processed, want) {
+		t.Errorf("processed = %v, want %v", h.processed, want)
+	}
+}
+
+func TestReorderRecordsGapWhenFetchFails(t *testing.T) {
+	h := newReorderHarness()
+	h.fetchErr[101] = fmt.Errorf("ledger not found")
+	h.offer(t, 100, 102, 103, 104)
+	if want := []uint64{101}; !reflect.DeepEqual(h.gaps, want) {
+		t.Errorf("gaps = %v, want %v", h.gaps, want)
+	}
+	if want := []uint64{100, 102, 103, 104}; !reflect.DeepEqual(h.processed, want) {
+		t.Errorf("processed = %v, want %v", h.processed, want)
+	}
+	// A late arrival of the gapped ledger is stale and dropped.
+	h.offer(t, 101)
+	if h.processed[len(h.processed)-1] != 104 {
+		t.Errorf("stale ledger 101 was processed: %v", h.processed)
+	}
+}
@@ -0,0 +1,453 @@
+// Package api is the Partner API: a read-only HTTP surface over the
+// store for consumers who should never touch the database directly. It
+// only exposes indexed state — offers, pools, trades — and validates
+// every parameter before the store sees it.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// Store is the slice of the persistent store the Partner API reads.
+type Store interface {
+	GetOpenOffers(ctx context.Context, base, quote, side string, funded bool, limit, offset int) ([]*model.Offer, error)
+	GetAMMPools(ctx context.Context, limit, offset int) ([]*model.Pool, error)
+	GetPoolByAssets(ctx context.Context, asset1, asset2 string) (*model.Pool, error)
+	GetTrades(ctx context.Context, base, quote string, limit, offset int) ([]*model.Trade, error)
+	GetHolderCount(ctx context.Context, currency, issuer string) (int64, error)
+	ListMarketStats(ctx context.Context) ([]*model.MarketStats, error)
+	GetOffersByAccount(ctx context.Context, account, status string, fromLedger uint64, cursor string, limit int) (*model.AccountOffers, error)
+	ListTrackedMarkets(ctx context.Context) ([]*model.TrackedMarket, error)
+	APIKeyUsage(ctx context.Context, days int) ([]*model.APIKeyUsage, error)
+	GetIdentities(ctx context.Context, accounts []string) (map[string]*model.Identity, error)
+	GetValidatorScores(ctx context.Context) ([]*model.ValidatorScore, error)
+}
+
+// Server serves the Partner API endpoints. It is an http.Handler, so
+// callers choose the listener and middleware.
+type Server struct {
+	st     Store
+	mux    *http.ServeMux
+	stream *Stream
+}
+
+// NewServer returns a server reading from st.
+func NewServer(st Store) *Server {
+	s := &Server{st: st, mux: http.NewServeMux(), stream: newStream()}
+	s.mux.HandleFunc("/offers", s.handleOffers)
+	s.mux.HandleFunc("/pools", s.handlePools)
+	s.mux.HandleFunc("/pools/", s.handlePool)
+	s.mux.HandleFunc("/trades", s.handleTrades)
+	s.mux.HandleFunc("/markets", s.handleMarkets)
+	s.mux.HandleFunc("/markets/tracked", s.handleTrackedMarkets)
+	s.mux.HandleFunc("/validators", s.handleValidators)
+	s.mux.HandleFunc("/accounts/", s.handleAccountOffers)
+	s.mux.HandleFunc("/stream", s.handleStream)
+	// Everything under /admin/ is reserved for keys carrying the admin
+	// scope; the KeyAuth middleware enforces that by path prefix.
+	s.mux.HandleFunc("/admin/usage", s.handleAdminUsage)
+	return s
+}
+
+// Stream is where the pipeline publishes per-ledger changes for the
+// /stream WebSocket subscribers.
+func (s *Server) Stream() *Stream { return s.stream }
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// page reads limit/offset, defaulting to 100 rows and capping at 1000 so
+// one request cannot drag the whole table over the wire.
+func page(r *http.Request) (limit, offset int, err error) {
+	limit = 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > 1000 {
+			return 0, 0, fmt.Errorf("limit must be 1..1000")
+		}
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be >= 0")
+		}
+	}
+	return limit, offset, nil
+}
+
+// asset validates one asset-key parameter ("XRP" or "CUR.issuer").
+func asset(r *http.Request, name string) (string, error) {
+	key := r.URL.Query().Get(name)
+	if key == "" {
+		return "", fmt.Errorf("%s is required", name)
+	}
+	if _, err := model.ParseAssetKey(key); err != nil {
+		return "", fmt.Errorf("%s: %v", name, err)
+	}
+	return key, nil
+}
+
+func (s *Server) handleOffers(w http.ResponseWriter, r *http.Request) {
+	base, err := asset(r, "base")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	quote, err := asset(r, "quote")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	side := r.URL.Query().Get("side")
+	switch side {
+	case "", "buy", "sell":
+	default:
+		http.Error(w, "side must be buy or sell", http.StatusBadRequest)
+		return
+	}
+	// funded=true keeps only offers whose owner's balance was observed to
+	// cover them; each row's FundedCheckedLedger is the staleness bound of
+	// that observation.
+	funded := false
+	if v := r.URL.Query().Get("funded"); v != "" {
+		var err error
+		if funded, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "funded must be a boolean", http.StatusBadRequest)
+			return
+		}
+	}
+	limit, offset, err := page(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	offers, err := s.st.GetOpenOffers(r.Context(), base, quote, side, funded, limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if offers == nil {
+		offers = []*model.Offer{}
+	}
+	writeJSON(w, offers)
+}
+
+func (s *Server) handlePools(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := page(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pools, err := s.st.GetAMMPools(r.Context(), limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if pools == nil {
+		pools = []*model.Pool{}
+	}
+	writeJSON(w, pools)
+}
+
+func (s *Server) handlePool(w http.ResponseWriter, r *http.Request) {
+	asset1, asset2, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/pools/"), "/")
+	if !ok || asset1 == "" || asset2 == "" {
+		http.NotFound(w, r)
+		return
+	}
+	for _, key := range []string{asset1, asset2} {
+		if _, err := model.ParseAssetKey(key); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	pool, err := s.st.GetPoolByAssets(r.Context(), asset1, asset2)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if pool == nil {
+		http.Error(w, "no pool for this pair", http.StatusNotFound)
+		return
+	}
+	view := poolView{Pool: pool}
+	if !pool.Asset1.IsXRP() {
+		if view.Asset1Holders, err = s.st.GetHolderCount(r.Context(), pool.Asset1.Currency, pool.Asset1.Issuer); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if !pool.Asset2.IsXRP() {
+		if view.Asset2Holders, err = s.st.GetHolderCount(r.Context(), pool.Asset2.Currency, pool.Asset2.Issuer); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	ids, err := s.identities(r.Context(), pool.Asset1.Issuer, pool.Asset2.Issuer)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	view.Asset1Identity = ids[pool.Asset1.Issuer]
+	view.Asset2Identity = ids[pool.Asset2.Issuer]
+	writeJSON(w, view)
+}
+
+// poolView decorates the single-pool lookup with per-leg holder counts
+// and issuer identities. The list endpoint stays plain — one count query
+// per pool per row would not scale — so partners who need the extras
+// fetch the pool directly.
+type poolView struct {
+	*model.Pool
+	// Asset1Holders and Asset2Holders count trust lines to each leg's
+	// issuer; an XRP leg has no issuer and reports zero.
+	Asset1Holders int64
+	Asset2Holders int64
+	// Asset1Identity and Asset2Identity are each leg's resolved issuer
+	// identity; null for XRP legs and issuers not yet enriched.
+	Asset1Identity *model.Identity
+	Asset2Identity *model.Identity
+}
+
+// identities looks up the cached identities of the given subjects in
+// one store call, dropping empty keys (XRP legs have no issuer).
+func (s *Server) identities(ctx context.Context, subjects ...string) (map[string]*model.Identity, error) {
+	var keys []string
+	for _, subject := range subjects {
+		if subject != "" {
+			keys = append(keys, subject)
+		}
+	}
+	if len(keys) == 0 {
+		return map[string]*model.Identity{}, nil
+	}
+	return s.st.GetIdentities(ctx, keys)
+}
+
+// handleMarkets lists every market's sliding 24h statistics, busiest
+// first — the store includes quiet pairs that still have open offers or
+// a pool, so the list doubles as market discovery.
+func (s *Server) handleMarkets(w http.ResponseWriter, r *http.Request) {
+	markets, err := s.st.ListMarketStats(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// One identity lookup covers every issuer on the list; pairs that
+	// fail to parse (or were never enriched) just stay unadorned.
+	var issuers []string
+	for _, m := range markets {
+		if base, quote, err := model.ParsePairKey(m.Pair); err == nil {
+			issuers = append(issuers, base.Issuer, quote.Issuer)
+		}
+	}
+	ids, err := s.identities(r.Context(), issuers...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	views := make([]marketView, 0, len(markets))
+	for _, m := range markets {
+		v := marketView{MarketStats: m}
+		if base, quote, err := model.ParsePairKey(m.Pair); err == nil {
+			v.BaseIdentity = ids[base.Issuer]
+			v.QuoteIdentity = ids[quote.Issuer]
+		}
+		views = append(views, v)
+	}
+	writeJSON(w, views)
+}
+
+// marketView decorates one market's 24h stats with the resolved
+// identities of the pair's issuers; null for XRP legs and issuers not
+// yet enriched.
+type marketView struct {
+	*model.MarketStats
+	BaseIdentity  *model.Identity
+	QuoteIdentity *model.Identity
+}
+
+// handleValidators lists each validator's most recent agreement score
+// together with its resolved operator identity, when the validator's
+// domain verification produced one.
+func (s *Server) handleValidators(w http.ResponseWriter, r *http.Request) {
+	scores, err := s.st.GetValidatorScores(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	keys := make([]string, 0, len(scores))
+	for _, sc := range scores {
+		keys = append(keys, sc.Validator)
+	}
+	ids, err := s.identities(r.Context(), keys...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	views := make([]validatorView, 0, len(scores))
+	for _, sc := range scores {
+		views = append(views, validatorView{ValidatorScore: sc, Identity: ids[sc.Validator]})
+	}
+	writeJSON(w, views)
+}
+
+// validatorView pairs a validator's score with its operator identity.
+type validatorView struct {
+	*model.ValidatorScore
+	Identity *model.Identity
+}
+
+// handleTrackedMarkets lists the markets discovery has promoted, with
+// their discovery timestamps; demoted markets stay listed so consumers
+// can tell "was tracked" from "never seen".
+func (s *Server) handleTrackedMarkets(w http.ResponseWriter, r *http.Request) {
+	markets, err := s.st.ListTrackedMarkets(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if markets == nil {
+		markets = []*model.TrackedMarket{}
+	}
+	writeJSON(w, markets)
+}
+
+// handleAccountOffers serves /accounts/{account}/offers: the account's
+// offers with the lifecycle behind each — status transitions and fills
+// with counterparties — plus per-status totals. Pages follow the cursor
+// echoed in each response rather than an offset, so accounts with tens
+// of thousands of offers page in constant time.
+func (s *Server) handleAccountOffers(w http.ResponseWriter, r *http.Request) {
+	account, rest, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/accounts/"), "/")
+	if !ok || rest != "offers" {
+		http.NotFound(w, r)
+		return
+	}
+	if !strings.HasPrefix(account, "r") || len(account) < 25 {
+		http.Error(w, "account must be an XRPL address", http.StatusBadRequest)
+		return
+	}
+	if !accountAllowed(r.Context(), account) {
+		http.Error(w, "account not in this key's scope", http.StatusForbidden)
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", model.OfferStatusOpen, model.OfferStatusPartiallyFilled, model.OfferStatusFilled,
+		model.OfferStatusCancelled, model.OfferStatusExpired, model.OfferStatusRemovedUnfunded,
+		model.OfferStatusSnapshot, model.OfferStatusInvalidParse:
+	default:
+		http.Error(w, "unknown status", http.StatusBadRequest)
+		return
+	}
+	var fromLedger uint64
+	if v := r.URL.Query().Get("from"); v != "" {
+		var err error
+		if fromLedger, err = strconv.ParseUint(v, 10, 64); err != nil {
+			http.Error(w, "from must be a ledger index", http.StatusBadRequest)
+			return
+		}
+	}
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > 1000 {
+			http.Error(w, "limit must be 1..1000", http.StatusBadRequest)
+			return
+		}
+	}
+	cursor := r.URL.Query().Get("cursor")
+	if !validCursor(cursor) {
+		http.Error(w, "cursor must be one returned by a previous page", http.StatusBadRequest)
+		return
+	}
+	page, err := s.st.GetOffersByAccount(r.Context(), account, status, fromLedger, cursor, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, page)
+}
+
+// validCursor accepts the empty cursor or the "<ledger>-<sequence>" form
+// the store hands out, so a mangled cursor is a 400 rather than a 500.
+func validCursor(cursor string) bool {
+	if cursor == "" {
+		return true
+	}
+	l, s, ok := strings.Cut(cursor, "-")
+	if !ok {
+		return false
+	}
+	_, errL := strconv.ParseUint(l, 10, 64)
+	_, errS := strconv.ParseUint(s, 10, 32)
+	return errL == nil && errS == nil
+}
+
+func (s *Server) handleTrades(w http.ResponseWriter, r *http.Request) {
+	pair := r.URL.Query().Get("pair")
+	base, quote, ok := strings.Cut(pair, "/")
+	if !ok {
+		http.Error(w, "pair must be two asset keys joined by /", http.StatusBadRequest)
+		return
+	}
+	for _, key := range []string{base, quote} {
+		if _, err := model.ParseAssetKey(key); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	limit, offset, err := page(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	trades, err := s.st.GetTrades(r.Context(), base, quote, limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if trades == nil {
+		trades = []*model.Trade{}
+	}
+	writeJSON(w, trades)
+}
+
+// handleAdminUsage reports per-partner daily request counts, 30 days
+// back by default. Keys appear by partner name only; the hashes never
+// leave the store.
+func (s *Server) handleAdminUsage(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		var err error
+		if days, err = strconv.Atoi(v); err != nil || days < 1 || days > 365 {
+			http.Error(w, "days must be 1..365", http.StatusBadRequest)
+			return
+		}
+	}
+	usage, err := s.st.APIKeyUsage(r.Context(), days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if usage == nil {
+		usage = []*model.APIKeyUsage{}
+	}
+	writeJSON(w, usage)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,400 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// fakeStore records the last query and serves canned rows.
+type fakeStore struct {
+	offers     []*model.Offer
+	pools      []*model.Pool
+	pool       *model.Pool
+	trades     []*model.Trade
+	holders    map[string]int64
+	markets    []*model.MarketStats
+	tracked    []*model.TrackedMarket
+	page       *model.AccountOffers
+	usage      []*model.APIKeyUsage
+	identities map[string]*model.Identity
+	scores     []*model.ValidatorScore
+
+	lastBase, lastQuote, lastSide string
+	lastLimit, lastOffset         int
+	lastFunded                    bool
+	lastAccount, lastStatus       string
+	lastFromLedger                uint64
+	lastCursor                    string
+}
+
+func (f *fakeStore) GetOpenOffers(_ context.Context, base, quote, side string, funded bool, limit, offset int) ([]*model.Offer, error) {
+	f.lastBase, f.lastQuote, f.lastSide = base, quote, side
+	f.lastFunded = funded
+	f.lastLimit, f.lastOffset = limit, offset
+	return f.offers, nil
+}
+
+func (f *fakeStore) GetAMMPools(_ context.Context, limit, offset int) ([]*model.Pool, error) {
+	f.lastLimit, f.lastOffset = limit, offset
+	return f.pools, nil
+}
+
+func (f *fakeStore) GetPoolByAssets(_ context.Context, asset1, asset2 string) (*model.Pool, error) {
+	f.lastBase, f.lastQuote = asset1, asset2
+	return f.pool, nil
+}
+
+func (f *fakeStore) GetTrades(_ context.Context, base, quote string, limit, offset int) ([]*model.Trade, error) {
+	f.lastBase, f.lastQuote = base, quote
+	f.lastLimit, f.lastOffset = limit, offset
+	return f.trades, nil
+}
+
+func (f *fakeStore) GetHolderCount(_ context.Context, currency, issuer string) (int64, error) {
+	return f.holders[currency+"."+issuer], nil
+}
+
+func (f *fakeStore) ListMarketStats(context.Context) ([]*model.MarketStats, error) {
+	return f.markets, nil
+}
+
+func (f *fakeStore) ListTrackedMarkets(context.Context) ([]*model.TrackedMarket, error) {
+	return f.tracked, nil
+}
+
+func (f *fakeStore) GetOffersByAccount(_ context.Context, account, status string, fromLedger uint64, cursor string, limit int) (*model.AccountOffers, error) {
+	f.lastAccount, f.lastStatus = account, status
+	f.lastFromLedger, f.lastCursor = fromLedger, cursor
+	f.lastLimit = limit
+	return f.page, nil
+}
+
+func (f *fakeStore) APIKeyUsage(_ context.Context, days int) ([]*model.APIKeyUsage, error) {
+	f.lastLimit = days
+	return f.usage, nil
+}
+
+func (f *fakeStore) GetIdentities(_ context.Context, accounts []string) (map[string]*model.Identity, error) {
+	ids := map[string]*model.Identity{}
+	for _, a := range accounts {
+		if id, ok := f.identities[a]; ok {
+			ids[a] = id
+		}
+	}
+	return ids, nil
+}
+
+func (f *fakeStore) GetValidatorScores(context.Context) ([]*model.ValidatorScore, error) {
+	return f.scores, nil
+}
+
+func get(t *testing.T, srv *Server, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestOffersEndpoint(t *testing.T) {
+	st := &fakeStore{offers: []*model.Offer{{
+		Account: "rMaker", Sequence: 7, Status: model.OfferStatusOpen,
+		TakerGets: xrpl.Amount{Currency: "XRP", Value: "10"},
+		TakerPays: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "5"},
+	}}}
+	srv := NewServer(st)
+
+	rec := get(t, srv, "/offers?base=XRP&quote=USD.rIss&side=sell&limit=10&offset=20")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var offers []*model.Offer
+	if err := json.Unmarshal(rec.Body.Bytes(), &offers); err != nil {
+		t.Fatal(err)
+	}
+	if len(offers) != 1 || offers[0].Account != "rMaker" {
+		t.Errorf("offers = %+v", offers)
+	}
+	if st.lastBase != "XRP" || st.lastQuote != "USD.rIss" || st.lastSide != "sell" ||
+		st.lastLimit != 10 || st.lastOffset != 20 {
+		t.Errorf("store saw %q %q %q limit=%d offset=%d",
+			st.lastBase, st.lastQuote, st.lastSide, st.lastLimit, st.lastOffset)
+	}
+	if st.lastFunded {
+		t.Error("funded filter applied without funded=true")
+	}
+
+	if rec := get(t, srv, "/offers?base=XRP&quote=USD.rIss&funded=true"); rec.Code != http.StatusOK {
+		t.Fatalf("funded=true status = %d: %s", rec.Code, rec.Body)
+	}
+	if !st.lastFunded {
+		t.Error("funded=true did not reach the store")
+	}
+}
+
+func TestOffersValidation(t *testing.T) {
+	srv := NewServer(&fakeStore{})
+	for _, path := range []string{
+		"/offers",                      // base missing
+		"/offers?base=XRP",             // quote missing
+		"/offers?base=bogus&quote=XRP", // malformed asset
+		"/offers?base=XRP&quote=USD.rIss&side=short",   // unknown side
+		"/offers?base=XRP&quote=USD.rIss&limit=0",      // limit out of range
+		"/offers?base=XRP&quote=USD.rIss&limit=2000",   // limit out of range
+		"/offers?base=XRP&quote=USD.rIss&offset=-1",    // negative offset
+		"/offers?base=XRP&quote=USD.rIss&limit=twelve", // not a number
+		"/offers?base=XRP&quote=USD.rIss&funded=yes-please",
+	} {
+		if rec := get(t, srv, path); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want 400", path, rec.Code)
+		}
+	}
+}
+
+func TestPoolsEndpoints(t *testing.T) {
+	pool := &model.Pool{Account: "rPool",
+		Asset1: xrpl.Amount{Currency: "XRP", Value: "1000"},
+		Asset2: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "500"}}
+	st := &fakeStore{pools: []*model.Pool{pool}, pool: pool}
+	srv := NewServer(st)
+
+	if rec := get(t, srv, "/pools"); rec.Code != http.StatusOK {
+		t.Errorf("GET /pools = %d", rec.Code)
+	}
+	rec := get(t, srv, "/pools/XRP/USD.rIss")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /pools/XRP/USD.rIss = %d: %s", rec.Code, rec.Body)
+	}
+	var got model.Pool
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Account != "rPool" {
+		t.Errorf("pool = %+v", got)
+	}
+
+	st.pool = nil
+	if rec := get(t, srv, "/pools/XRP/EUR.rIss"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown pair = %d, want 404", rec.Code)
+	}
+	if rec := get(t, srv, "/pools/garbage/XRP"); rec.Code != http.StatusBadRequest {
+		t.Errorf("malformed asset = %d, want 400", rec.Code)
+	}
+}
+
+func TestTradesEndpoint(t *testing.T) {
+	st := &fakeStore{}
+	srv := NewServer(st)
+
+	rec := get(t, srv, "/trades?pair=XRP/USD.rIss")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	// A nil store result still renders as an empty array, not null.
+	if body := rec.Body.String(); body != "[]\n" {
+		t.Errorf("empty trades rendered %q", body)
+	}
+	if st.lastBase != "XRP" || st.lastQuote != "USD.rIss" {
+		t.Errorf("store saw pair %q/%q", st.lastBase, st.lastQuote)
+	}
+	if rec := get(t, srv, "/trades?pair=XRPUSD"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unseparated pair = %d, want 400", rec.Code)
+	}
+}
+
+func TestOnlyGET(t *testing.T) {
+	srv := NewServer(&fakeStore{})
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/offers?base=XRP&quote=USD.rIss", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST = %d, want 405", rec.Code)
+	}
+}
+
+func TestAccountOffersEndpoint(t *testing.T) {
+	account := "rMakerAccount11111111111111111"
+	st := &fakeStore{page: &model.AccountOffers{
+		Offers: []*model.OfferHistory{{
+			Offer: &model.Offer{Account: account, Sequence: 7, Status: model.OfferStatusFilled},
+			Fills: []*model.Trade{{Maker: account, Taker: "rTaker", OfferSequence: 7}},
+		}},
+		StatusTotals: map[string]int64{model.OfferStatusFilled: 1},
+		NextCursor:   "90000100-7",
+	}}
+	srv := NewServer(st)
+
+	rec := get(t, srv, "/accounts/"+account+"/offers?status=filled&from=90000000&cursor=90000050-3&limit=1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var page model.AccountOffers
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Offers) != 1 || page.Offers[0].Fills[0].Taker != "rTaker" || page.NextCursor != "90000100-7" {
+		t.Errorf("page = %+v", page)
+	}
+	if st.lastAccount != account || st.lastStatus != "filled" ||
+		st.lastFromLedger != 90000000 || st.lastCursor != "90000050-3" || st.lastLimit != 1 {
+		t.Errorf("store saw %s/%s/%d/%s/%d", st.lastAccount, st.lastStatus,
+			st.lastFromLedger, st.lastCursor, st.lastLimit)
+	}
+
+	// Bad inputs are the caller's problem, not the store's.
+	for path, want := range map[string]int{
+		"/accounts/" + account + "/trades":                http.StatusNotFound,
+		"/accounts/notanaddress/offers":                   http.StatusBadRequest,
+		"/accounts/" + account + "/offers?status=weird":   http.StatusBadRequest,
+		"/accounts/" + account + "/offers?cursor=mangled": http.StatusBadRequest,
+		"/accounts/" + account + "/offers?limit=0":        http.StatusBadRequest,
+	} {
+		if rec := get(t, srv, path); rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
+
+func TestMarketsEndpoint(t *testing.T) {
+	st := &fakeStore{markets: []*model.MarketStats{{
+		Pair: "USD.rIss/XRP", Volume: "8", High: "3", Low: "1.5",
+		LastPrice: "2.5", TradeCount: 3,
+	}}}
+	srv := NewServer(st)
+
+	rec := get(t, srv, "/markets")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var markets []*model.MarketStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &markets); err != nil {
+		t.Fatal(err)
+	}
+	if len(markets) != 1 || markets[0].Pair != "USD.rIss/XRP" || markets[0].Volume != "8" {
+		t.Errorf("markets = %+v", markets)
+	}
+
+	// An empty store serves [], not null.
+	rec = get(t, NewServer(&fakeStore{}), "/markets")
+	if body := rec.Body.String(); body != "[]\n" {
+		t.Errorf("empty markets body = %q", body)
+	}
+}
+
+func TestTrackedMarketsEndpoint(t *testing.T) {
+	st := &fakeStore{tracked: []*model.TrackedMarket{{
+		Pair: "USD.rIss/XRP", DiscoveredLedger: 90001100, DiscoveredCloseTime: 700009900,
+		LastActiveLedger: 90001200, LastActiveCloseTime: 700010300, Demoted: true,
+	}}}
+
+	rec := get(t, NewServer(st), "/markets/tracked")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var markets []*model.TrackedMarket
+	if err := json.Unmarshal(rec.Body.Bytes(), &markets); err != nil {
+		t.Fatal(err)
+	}
+	if len(markets) != 1 || markets[0].Pair != "USD.rIss/XRP" ||
+		markets[0].DiscoveredLedger != 90001100 || !markets[0].Demoted {
+		t.Errorf("tracked = %+v", markets)
+	}
+
+	rec = get(t, NewServer(&fakeStore{}), "/markets/tracked")
+	if body := rec.Body.String(); body != "[]\n" {
+		t.Errorf("empty tracked body = %q", body)
+	}
+}
+
+func TestValidatorsEndpoint(t *testing.T) {
+	st := &fakeStore{
+		scores: []*model.ValidatorScore{
+			{Validator: "nHKnown", Agreed: 90, Missed: 10, Agreement: 0.9},
+			{Validator: "nHAnon", Agreed: 100, Agreement: 1},
+		},
+		identities: map[string]*model.Identity{
+			"nHKnown": {Account: "nHKnown", Domain: "val.example",
+				Name: "Known Validator", Status: model.IdentityVerified},
+		},
+	}
+	rec := get(t, NewServer(st), "/validators")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var views []struct {
+		Validator string
+		Agreement float64
+		Identity  *model.Identity
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &views); err != nil {
+		t.Fatal(err)
+	}
+	if len(views) != 2 {
+		t.Fatalf("validators = %+v", views)
+	}
+	if views[0].Identity == nil || views[0].Identity.Name != "Known Validator" ||
+		views[0].Identity.Status != model.IdentityVerified {
+		t.Errorf("enriched validator = %+v", views[0])
+	}
+	if views[1].Identity != nil {
+		t.Errorf("unenriched validator carries identity %+v", views[1].Identity)
+	}
+
+	rec = get(t, NewServer(&fakeStore{}), "/validators")
+	if body := rec.Body.String(); body != "[]\n" {
+		t.Errorf("empty validators body = %q", body)
+	}
+}
+
+func TestIdentityOnPoolAndMarkets(t *testing.T) {
+	pool := &model.Pool{Account: "rPool",
+		Asset1: xrpl.Amount{Currency: "XRP", Value: "1000"},
+		Asset2: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "500"}}
+	st := &fakeStore{
+		pool:    pool,
+		markets: []*model.MarketStats{{Pair: "USD.rIss/XRP", Volume: "8"}},
+		identities: map[string]*model.Identity{
+			"rIss": {Account: "rIss", Domain: "iss.example",
+				Name: "Issuer Inc", Status: model.IdentityVerified},
+		},
+	}
+	srv := NewServer(st)
+
+	rec := get(t, srv, "/pools/XRP/USD.rIss")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var pv struct {
+		Asset1Identity, Asset2Identity *model.Identity
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &pv); err != nil {
+		t.Fatal(err)
+	}
+	if pv.Asset1Identity != nil {
+		t.Errorf("XRP leg carries identity %+v", pv.Asset1Identity)
+	}
+	if pv.Asset2Identity == nil || pv.Asset2Identity.Name != "Issuer Inc" {
+		t.Errorf("issuer leg identity = %+v", pv.Asset2Identity)
+	}
+
+	rec = get(t, srv, "/markets")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+	var mv []struct {
+		Pair                        string
+		BaseIdentity, QuoteIdentity *model.Identity
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &mv); err != nil {
+		t.Fatal(err)
+	}
+	// The pair key sorts "USD.rIss" before "XRP", so the IOU is the base.
+	if len(mv) != 1 || mv[0].BaseIdentity == nil || mv[0].BaseIdentity.Name != "Issuer Inc" ||
+		mv[0].QuoteIdentity != nil {
+		t.Errorf("markets = %+v", mv)
+	}
+}
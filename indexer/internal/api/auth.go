@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// KeyStore is the slice of the persistent store the auth middleware
+// needs: resolve a presented key, or (nil, nil) when it is unknown or
+// revoked. Implementations are expected to account the request.
+type KeyStore interface {
+	ValidateAPIKey(ctx context.Context, key string) (*model.APIKey, error)
+}
+
+// KeyAuth guards a handler behind API keys from an Authorization: Bearer
+// header and rate-limits each key with its own token bucket, so one
+// partner's burst cannot starve another's. Bucket state is in memory
+// per instance: replicas each grant the full limit, and a restart
+// refills every bucket — acceptable for per-minute granularity.
+type KeyAuth struct {
+	ks           KeyStore
+	defaultLimit int
+	next         http.Handler
+	now          func() time.Time
+	anonPublic   bool
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket // keyed by the presented key
+}
+
+// tokenBucket holds limit tokens and refills at limit per minute; each
+// request spends one.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RequireKey wraps next so only requests carrying a valid, enabled API
+// key get through: 401 for missing or unknown keys, 429 with a
+// Retry-After header over the key's rate limit. Keys without their own
+// limit get defaultLimit requests per minute; 0 means unlimited.
+func RequireKey(ks KeyStore, defaultLimit int, next http.Handler) *KeyAuth {
+	return &KeyAuth{
+		ks:           ks,
+		defaultLimit: defaultLimit,
+		next:         next,
+		now:          time.Now,
+		buckets:      make(map[string]*tokenBucket),
+	}
+}
+
+// adminScope is the scope a key must carry to reach /admin/ endpoints;
+// anonymous access never extends to them.
+const adminScope = "admin"
+
+// globalScope exempts a key from account scoping: it may query
+// account-scoped endpoints for any account, not just its mapped ones.
+// The admin scope implies the same.
+const globalScope = "global"
+
+// keyContextKey carries the validated API key through the request
+// context to the handlers that enforce account scoping.
+type keyContextKey struct{}
+
+// APIKeyFromContext returns the key the auth middleware validated for
+// this request, or nil for anonymous requests.
+func APIKeyFromContext(ctx context.Context) *model.APIKey {
+	k, _ := ctx.Value(keyContextKey{}).(*model.APIKey)
+	return k
+}
+
+// accountAllowed says whether this request may read data scoped to the
+// given account. Anonymous requests are not account-scoped — whether
+// they get in at all is the AllowAnonymousPublic deployment choice —
+// and global or admin keys see everything; any other key is confined
+// to its mapped accounts.
+func accountAllowed(ctx context.Context, account string) bool {
+	k := APIKeyFromContext(ctx)
+	if k == nil || k.HasScope(globalScope) || k.HasScope(adminScope) {
+		return true
+	}
+	for _, a := range k.Accounts {
+		if a == account {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowAnonymousPublic lets requests without a key through to the
+// public market data endpoints. Admin endpoints keep requiring a key
+// with the admin scope, and presented keys are still validated and
+// rate-limited.
+func (a *KeyAuth) AllowAnonymousPublic() { a.anonPublic = true }
+
+func (a *KeyAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	admin := strings.HasPrefix(r.URL.Path, "/admin/")
+	key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || key == "" {
+		if a.anonPublic && !admin {
+			a.next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "missing API key", http.StatusUnauthorized)
+		return
+	}
+	k, err := a.ks.ValidateAPIKey(r.Context(), key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if k == nil {
+		http.Error(w, "invalid API key", http.StatusUnauthorized)
+		return
+	}
+	if admin && !k.HasScope(adminScope) {
+		http.Error(w, "admin scope required", http.StatusForbidden)
+		return
+	}
+	limit := k.RateLimit
+	if limit == 0 {
+		a.mu.Lock()
+		limit = a.defaultLimit
+		a.mu.Unlock()
+	}
+	if retryAfter, ok := a.take(key, limit); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+	a.next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), keyContextKey{}, k)))
+}
+
+// SetDefaultLimit swaps the fallback rate limit at runtime (SIGHUP
+// reload); keys with their own limit are unaffected.
+func (a *KeyAuth) SetDefaultLimit(limit int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.defaultLimit = limit
+}
+
+// take spends one token from the key's bucket, refilling for the time
+// since the last request first. When the bucket is dry it reports how
+// many whole seconds until a token is available again.
+func (a *KeyAuth) take(key string, limit int) (retryAfter int, ok bool) {
+	if limit <= 0 {
+		return 0, true
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	b := a.buckets[key]
+	if b == nil {
+		b = &tokenBucket{tokens: float64(limit), last: now}
+		a.buckets[key] = b
+	}
+	perSecond := float64(limit) / 60
+	b.tokens = math.Min(float64(limit), b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	return int(math.Ceil((1 - b.tokens) / perSecond)), false
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+type fakeKeyStore struct {
+	keys map[string]*model.APIKey
+}
+
+func (f *fakeKeyStore) ValidateAPIKey(ctx context.Context, key string) (*model.APIKey, error) {
+	k := f.keys[key]
+	if k == nil || !k.Enabled {
+		return nil, nil
+	}
+	return k, nil
+}
+
+func authedGet(t *testing.T, h http.Handler, key string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/pools", nil)
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRequireKey(t *testing.T) {
+	ks := &fakeKeyStore{keys: map[string]*model.APIKey{
+		"good":    {Partner: "acme", RateLimit: 0, Enabled: true},
+		"revoked": {Partner: "acme", RateLimit: 0, Enabled: false},
+	}}
+	h := RequireKey(ks, 0, NewServer(&fakeStore{}))
+
+	if rec := authedGet(t, h, "good"); rec.Code != http.StatusOK {
+		t.Errorf("valid key: status = %d, want 200", rec.Code)
+	}
+	if rec := authedGet(t, h, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("missing key: status = %d, want 401", rec.Code)
+	}
+	if rec := authedGet(t, h, "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("unknown key: status = %d, want 401", rec.Code)
+	}
+	if rec := authedGet(t, h, "revoked"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key: status = %d, want 401", rec.Code)
+	}
+}
+
+func TestRequireKeyRateLimit(t *testing.T) {
+	ks := &fakeKeyStore{keys: map[string]*model.APIKey{
+		"metered": {Partner: "acme", RateLimit: 60, Enabled: true},
+	}}
+	h := RequireKey(ks, 0, NewServer(&fakeStore{}))
+	now := time.Now()
+	h.now = func() time.Time { return now }
+
+	// The bucket starts full: the whole per-minute allowance can burst.
+	for i := 0; i < 60; i++ {
+		if rec := authedGet(t, h, "metered"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i+1, rec.Code)
+		}
+	}
+	rec := authedGet(t, h, "metered")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("over limit: status = %d, want 429", rec.Code)
+	}
+	// 60/minute refills one token per second.
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want \"1\"", got)
+	}
+
+	// One second later exactly one token is back.
+	now = now.Add(time.Second)
+	if rec := authedGet(t, h, "metered"); rec.Code != http.StatusOK {
+		t.Errorf("after refill: status = %d, want 200", rec.Code)
+	}
+	if rec := authedGet(t, h, "metered"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("dry again: status = %d, want 429", rec.Code)
+	}
+}
+
+func authedPath(t *testing.T, h http.Handler, key, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRequireKeyAdminScope(t *testing.T) {
+	ks := &fakeKeyStore{keys: map[string]*model.APIKey{
+		"plain": {Partner: "acme", Enabled: true},
+		"admin": {Partner: "ops", Enabled: true, Scopes: []string{"admin"}},
+	}}
+	h := RequireKey(ks, 0, NewServer(&fakeStore{}))
+
+	if rec := authedPath(t, h, "plain", "/admin/usage"); rec.Code != http.StatusForbidden {
+		t.Errorf("unscoped key on /admin/usage: status = %d, want 403", rec.Code)
+	}
+	if rec := authedPath(t, h, "admin", "/admin/usage"); rec.Code != http.StatusOK {
+		t.Errorf("admin key on /admin/usage: status = %d, want 200", rec.Code)
+	}
+	// The admin scope grants extra access, it does not take any away.
+	if rec := authedPath(t, h, "plain", "/pools"); rec.Code != http.StatusOK {
+		t.Errorf("unscoped key on /pools: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestRequireKeyAnonymousPublic(t *testing.T) {
+	ks := &fakeKeyStore{keys: map[string]*model.APIKey{
+		"metered": {Partner: "acme", RateLimit: 1, Enabled: true},
+	}}
+	h := RequireKey(ks, 0, NewServer(&fakeStore{}))
+	h.AllowAnonymousPublic()
+	now := time.Now()
+	h.now = func() time.Time { return now }
+
+	// Public endpoints are open without a key...
+	if rec := authedPath(t, h, "", "/pools"); rec.Code != http.StatusOK {
+		t.Errorf("anonymous /pools: status = %d, want 200", rec.Code)
+	}
+	// ...but /admin/ still demands one, and a bad key stays a 401.
+	if rec := authedPath(t, h, "", "/admin/usage"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous /admin/usage: status = %d, want 401", rec.Code)
+	}
+	if rec := authedPath(t, h, "wrong", "/pools"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("bad key with anonymous access: status = %d, want 401", rec.Code)
+	}
+	// Presented keys are still metered even when anonymity is allowed.
+	if rec := authedGet(t, h, "metered"); rec.Code != http.StatusOK {
+		t.Fatalf("metered key: status = %d, want 200", rec.Code)
+	}
+	if rec := authedGet(t, h, "metered"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("metered key over limit: status = %d, want 429", rec.Code)
+	}
+}
+
+func TestAccountScoping(t *testing.T) {
+	mapped := "rMappedAccount1111111111111111"
+	other := "rSomeoneElse111111111111111111"
+	ks := &fakeKeyStore{keys: map[string]*model.APIKey{
+		"scoped": {Partner: "acme", Enabled: true, Accounts: []string{mapped}},
+		"global": {Partner: "desk", Enabled: true, Scopes: []string{"global"}},
+		"admin":  {Partner: "ops", Enabled: true, Scopes: []string{"admin"}},
+	}}
+	h := RequireKey(ks, 0, NewServer(&fakeStore{page: &model.AccountOffers{}}))
+	h.AllowAnonymousPublic()
+
+	if rec := authedPath(t, h, "scoped", "/accounts/"+mapped+"/offers"); rec.Code != http.StatusOK {
+		t.Errorf("scoped key on its own account: status = %d, want 200", rec.Code)
+	}
+	if rec := authedPath(t, h, "scoped", "/accounts/"+other+"/offers"); rec.Code != http.StatusForbidden {
+		t.Errorf("scoped key on an unmapped account: status = %d, want 403", rec.Code)
+	}
+	// Global and admin keys are exempt from account scoping.
+	if rec := authedPath(t, h, "global", "/accounts/"+other+"/offers"); rec.Code != http.StatusOK {
+		t.Errorf("global key: status = %d, want 200", rec.Code)
+	}
+	if rec := authedPath(t, h, "admin", "/accounts/"+other+"/offers"); rec.Code != http.StatusOK {
+		t.Errorf("admin key: status = %d, want 200", rec.Code)
+	}
+	// Anonymous requests are not account-scoped; whether they get in at
+	// all is the AllowAnonymousPublic choice.
+	if rec := authedPath(t, h, "", "/accounts/"+other+"/offers"); rec.Code != http.StatusOK {
+		t.Errorf("anonymous: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestRequireKeyDefaultLimit(t *testing.T) {
+	ks := &fakeKeyStore{keys: map[string]*model.APIKey{
+		"plain": {Partner: "acme", RateLimit: 0, Enabled: true},
+	}}
+	h := RequireKey(ks, 2, NewServer(&fakeStore{}))
+	now := time.Now()
+	h.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if rec := authedGet(t, h, "plain"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i+1, rec.Code)
+		}
+	}
+	rec := authedGet(t, h, "plain")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("over default limit: status = %d, want 429", rec.Code)
+	}
+	// 2/minute refills a token every 30 seconds.
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want \"30\"", got)
+	}
+}
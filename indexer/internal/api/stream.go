@@ -0,0 +1,226 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/internal/ws"
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// StreamUpdate is one push message on the /stream WebSocket: an offer
+// book change, a fill, or an AMM reserve change at the given ledger.
+type StreamUpdate struct {
+	Type        string                `json:"type"` // "offer", "trade", or "pool"
+	Pair        string                `json:"pair"`
+	LedgerIndex uint64                `json:"ledger_index"`
+	Offer       *model.Offer          `json:"offer,omitempty"`
+	Trade       *model.Trade          `json:"trade,omitempty"`
+	Pool        *model.PoolAdjustment `json:"pool,omitempty"`
+}
+
+// subBuffer is how many undelivered messages a client may fall behind
+// before it is dropped instead of blocking the pipeline.
+const subBuffer = 64
+
+// Stream fans ledger-derived updates out to WebSocket subscribers by
+// trading pair. Publishing never blocks: a subscriber whose buffer is
+// full is disconnected, because a slow trading UI must not stall
+// indexing for everyone else.
+type Stream struct {
+	mu   sync.Mutex
+	subs map[*streamSub]struct{}
+}
+
+type streamSub struct {
+	pairs map[string]bool // canonical pair keys
+	// accounts, when non-nil, confines offer and trade pushes to ones
+	// touching these accounts — the delivery half of partner account
+	// scoping. Pool adjustments are market-wide state and pass through.
+	accounts map[string]bool
+	ch       chan []byte
+}
+
+func newStream() *Stream {
+	return &Stream{subs: make(map[*streamSub]struct{})}
+}
+
+// canonicalPair joins two asset keys orientation-independently, so
+// "XRP/USD.r1" and "USD.r1/XRP" subscribe to the same book.
+func canonicalPair(a, b string) string {
+	if b < a {
+		a, b = b, a
+	}
+	return a + "/" + b
+}
+
+func (s *Stream) subscribe(pairs, accounts []string) *streamSub {
+	sub := &streamSub{pairs: make(map[string]bool, len(pairs)), ch: make(chan []byte, subBuffer)}
+	for _, p := range pairs {
+		sub.pairs[p] = true
+	}
+	if accounts != nil {
+		sub.accounts = make(map[string]bool, len(accounts))
+		for _, a := range accounts {
+			sub.accounts[a] = true
+		}
+	}
+	s.mu.Lock()
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+	return sub
+}
+
+// wants applies the subscriber's account scope to one update: an
+// unrestricted subscriber takes everything, a scoped one only offers it
+// owns and trades it took part in.
+func (sub *streamSub) wants(u *StreamUpdate) bool {
+	if sub.accounts == nil {
+		return true
+	}
+	switch u.Type {
+	case "offer":
+		return sub.accounts[u.Offer.Account]
+	case "trade":
+		return sub.accounts[u.Trade.Maker] || sub.accounts[u.Trade.Taker]
+	}
+	return true
+}
+
+func (s *Stream) unsubscribe(sub *streamSub) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[sub]; ok {
+		delete(s.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// PublishOffer pushes an offer book change to subscribers of its pair.
+func (s *Stream) PublishOffer(o *model.Offer) {
+	pair := canonicalPair(model.AssetKey(o.TakerGets), model.AssetKey(o.TakerPays))
+	s.send(pair, &StreamUpdate{Type: "offer", Pair: pair, LedgerIndex: o.LedgerIndex, Offer: o})
+}
+
+// PublishTrade pushes a fill to subscribers of its pair.
+func (s *Stream) PublishTrade(t *model.Trade) {
+	pair := canonicalPair(model.AssetKey(t.Gets), model.AssetKey(t.Pays))
+	s.send(pair, &StreamUpdate{Type: "trade", Pair: pair, LedgerIndex: t.LedgerIndex, Trade: t})
+}
+
+// PublishPool pushes an AMM reserve change to every subscriber whose
+// pair has the adjusted asset as one of its legs; the adjustment alone
+// does not identify the pool's other side.
+func (s *Stream) PublishPool(adj *model.PoolAdjustment) {
+	leg := model.AssetKey(xrpl.Amount{Currency: adj.Currency, Issuer: adj.Issuer})
+	msg, err := json.Marshal(&StreamUpdate{Type: "pool", Pair: leg, LedgerIndex: adj.LedgerIndex, Pool: adj})
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subs {
+		for pair := range sub.pairs {
+			a, b, _ := strings.Cut(pair, "/")
+			if a == leg || b == leg {
+				s.deliverLocked(sub, msg)
+				break
+			}
+		}
+	}
+}
+
+func (s *Stream) send(pair string, u *StreamUpdate) {
+	msg, err := json.Marshal(u)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subs {
+		if sub.pairs[pair] && sub.wants(u) {
+			s.deliverLocked(sub, msg)
+		}
+	}
+}
+
+// deliverLocked queues one message, dropping the subscriber when its
+// buffer is already full.
+func (s *Stream) deliverLocked(sub *streamSub, msg []byte) {
+	select {
+	case sub.ch <- msg:
+	default:
+		delete(s.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// handleStream upgrades the connection and streams updates for the
+// requested pairs (repeatable pair=BASE/QUOTE parameters) until either
+// side closes or the client falls too far behind.
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	var pairs []string
+	for _, raw := range r.URL.Query()["pair"] {
+		base, quote, ok := strings.Cut(raw, "/")
+		if !ok {
+			http.Error(w, "pair must be two asset keys joined by /", http.StatusBadRequest)
+			return
+		}
+		for _, key := range []string{base, quote} {
+			if _, err := model.ParseAssetKey(key); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		pairs = append(pairs, canonicalPair(base, quote))
+	}
+	if len(pairs) == 0 {
+		http.Error(w, "at least one pair is required", http.StatusBadRequest)
+		return
+	}
+	// A key without the global or admin scope only receives offer and
+	// trade pushes touching its mapped accounts, mirroring the REST
+	// enforcement; the scope is fixed at subscribe time, so remapped
+	// keys pick up changes on their next connection.
+	var accounts []string
+	if k := APIKeyFromContext(r.Context()); k != nil &&
+		!k.HasScope(globalScope) && !k.HasScope(adminScope) {
+		accounts = k.Accounts
+		if accounts == nil {
+			accounts = []string{}
+		}
+	}
+	conn, err := ws.Upgrade(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer conn.Close()
+	sub := s.stream.subscribe(pairs, accounts)
+	defer s.stream.unsubscribe(sub)
+
+	// The read loop only notices the client going away; inbound frames
+	// carry no protocol.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case msg, ok := <-sub.ch:
+			if !ok || conn.WriteMessage(msg) != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
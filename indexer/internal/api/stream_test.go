@@ -0,0 +1,183 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/internal/ws"
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func dialStream(t *testing.T, httpURL, query string) *ws.Conn {
+	t.Helper()
+	conn, err := ws.Dial(strings.Replace(httpURL, "http://", "ws://", 1) + "/stream?" + query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func waitForSubscribers(t *testing.T, s *Stream) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		s.mu.Lock()
+		n := len(s.subs)
+		s.mu.Unlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("no subscriber registered")
+}
+
+func TestStreamDeliversOfferUpdate(t *testing.T) {
+	srv := NewServer(&fakeStore{})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	// Orientation must not matter: subscribe quote/base.
+	conn := dialStream(t, ts.URL, "pair=USD.rIss/XRP")
+	defer conn.Close()
+	waitForSubscribers(t, srv.Stream())
+
+	srv.Stream().PublishOffer(&model.Offer{
+		Account: "rMaker", Sequence: 7, Status: model.OfferStatusOpen, LedgerIndex: 900,
+		TakerGets: xrpl.Amount{Currency: "XRP", Value: "10"},
+		TakerPays: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "5"},
+	})
+	// An update for an unrelated pair must not arrive.
+	srv.Stream().PublishTrade(&model.Trade{
+		LedgerIndex: 901,
+		Gets:        xrpl.Amount{Currency: "EUR", Issuer: "rOther", Value: "1"},
+		Pays:        xrpl.Amount{Currency: "XRP", Value: "2"},
+	})
+	srv.Stream().PublishTrade(&model.Trade{
+		Maker: "rMaker", Taker: "rTaker", LedgerIndex: 902,
+		Gets: xrpl.Amount{Currency: "XRP", Value: "10"},
+		Pays: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "5"},
+	})
+
+	var u StreamUpdate
+	msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(msg, &u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Type != "offer" || u.LedgerIndex != 900 || u.Offer == nil || u.Offer.Account != "rMaker" {
+		t.Fatalf("first update = %+v", u)
+	}
+	if u.Pair != canonicalPair("XRP", "USD.rIss") {
+		t.Errorf("pair = %q", u.Pair)
+	}
+	if msg, err = conn.ReadMessage(); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(msg, &u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Type != "trade" || u.LedgerIndex != 902 {
+		t.Fatalf("second update = %+v (unrelated pair leaked?)", u)
+	}
+}
+
+func TestStreamDropsSlowClient(t *testing.T) {
+	srv := NewServer(&fakeStore{})
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	conn := dialStream(t, ts.URL, "pair=XRP/USD.rIss")
+	defer conn.Close()
+	waitForSubscribers(t, srv.Stream())
+
+	// Without reading, overflow the subscriber buffer; the publisher
+	// must drop the client instead of blocking.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < subBuffer*3; i++ {
+			srv.Stream().PublishTrade(&model.Trade{
+				LedgerIndex: uint64(i),
+				Gets:        xrpl.Amount{Currency: "XRP", Value: "1"},
+				Pays:        xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "1"},
+			})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("publishing blocked on a slow client")
+	}
+
+	// The server closes the connection; draining ends in an error with
+	// part of the published messages never delivered.
+	received := 0
+	for {
+		if _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+		received++
+	}
+	if received >= subBuffer*3 {
+		t.Errorf("received all %d messages, want the overflow dropped", received)
+	}
+	srv.Stream().mu.Lock()
+	n := len(srv.Stream().subs)
+	srv.Stream().mu.Unlock()
+	if n != 0 {
+		t.Errorf("%d subscribers still registered after drop", n)
+	}
+}
+
+func TestStreamAccountScope(t *testing.T) {
+	s := newStream()
+	pair := canonicalPair("XRP", "USD.rIss")
+	scoped := s.subscribe([]string{pair}, []string{"rMine"})
+	open := s.subscribe([]string{pair}, nil)
+
+	s.PublishOffer(&model.Offer{
+		Account: "rOther", Sequence: 1, LedgerIndex: 900,
+		TakerGets: xrpl.Amount{Currency: "XRP", Value: "1"},
+		TakerPays: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "1"},
+	})
+	s.PublishTrade(&model.Trade{
+		Maker: "rOther", Taker: "rMine", LedgerIndex: 901,
+		Gets: xrpl.Amount{Currency: "XRP", Value: "1"},
+		Pays: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "1"},
+	})
+
+	// The scoped subscriber only gets the trade it took part in; the
+	// unrestricted one gets both.
+	if got := len(scoped.ch); got != 1 {
+		t.Fatalf("scoped subscriber queued %d updates, want 1", got)
+	}
+	var u StreamUpdate
+	if err := json.Unmarshal(<-scoped.ch, &u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Type != "trade" || u.Trade.Taker != "rMine" {
+		t.Errorf("scoped update = %+v", u)
+	}
+	if got := len(open.ch); got != 2 {
+		t.Errorf("unrestricted subscriber queued %d updates, want 2", got)
+	}
+}
+
+func TestStreamValidation(t *testing.T) {
+	srv := NewServer(&fakeStore{})
+	for _, path := range []string{
+		"/stream",
+		"/stream?pair=XRP",
+		"/stream?pair=XRP/bogus",
+	} {
+		if rec := get(t, srv, path); rec.Code != 400 {
+			t.Errorf("GET %s: status = %d, want 400", path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package binarycodec
+
+import (
+	"crypto/sha256"
+	"math/big"
+)
+
+// rippleAlphabet is the base58 dictionary used for XRPL addresses.
+const rippleAlphabet = "rpshnaf39wBUDNEGHJKLM4PQRST7VWXYZ2bcdeCg65jkm8oFqi1tuvAxyz"
+
+// encodeAccountID renders a 20-byte account ID as a classic r-address:
+// base58check with a 0x00 version byte and a double-SHA256 checksum.
+func encodeAccountID(id []byte) string {
+	payload := make([]byte, 0, 25)
+	payload = append(payload, 0x00)
+	payload = append(payload, id...)
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	payload = append(payload, second[:4]...)
+
+	x := new(big.Int).SetBytes(payload)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for x.Sign() > 0 {
+		x.DivMod(x, radix, mod)
+		out = append(out, rippleAlphabet[mod.Int64()])
+	}
+	for _, b := range payload {
+		if b != 0 {
+			break
+		}
+		out = append(out, rippleAlphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
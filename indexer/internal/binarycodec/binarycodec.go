@@ -0,0 +1,602 @@
+// Package binarycodec decodes the XRPL canonical binary format (tx_blob
+// and binary metadata) into the JSON wire form the rest of the indexer
+// already understands. Coverage is limited to the fields the parsers
+// consume; unknown fields are skipped according to their type's length
+// rules so the decoder never desynchronizes.
+package binarycodec
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Type codes from the XRPL serialization spec.
+const (
+	typeUInt16    = 1
+	typeUInt32    = 2
+	typeUInt64    = 3
+	typeHash128   = 4
+	typeHash256   = 5
+	typeAmount    = 6
+	typeBlob      = 7
+	typeAccountID = 8
+	typeObject    = 14
+	typeArray     = 15
+	typeUInt8     = 16
+	typeHash160   = 17
+	typePathSet   = 18
+	typeVector256 = 19
+	typeIssue     = 24
+)
+
+// Decode parses one serialized STObject (a transaction or a metadata blob)
+// into its JSON wire representation.
+func Decode(blob []byte) (json.RawMessage, error) {
+	r := &reader{data: blob}
+	obj, err := r.object(false)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(obj)
+}
+
+// DecodeHex is Decode for the hex strings rippled sends in binary ledger
+// responses.
+func DecodeHex(blob string) (json.RawMessage, error) {
+	data, err := hex.DecodeString(blob)
+	if err != nil {
+		return nil, fmt.Errorf("binarycodec: bad hex blob: %w", err)
+	}
+	return Decode(data)
+}
+
+type fieldKey struct {
+	typeCode  int
+	fieldCode int
+}
+
+// fieldNames covers the fields the parsers read. Anything absent here is
+// decoded for its length and dropped.
+var fieldNames = map[fieldKey]string{
+	{typeUInt16, 1}: "LedgerEntryType",
+	{typeUInt16, 2}: "TransactionType",
+
+	{typeUInt32, 2}:  "Flags",
+	{typeUInt32, 4}:  "Sequence",
+	{typeUInt32, 10}: "Expiration",
+	{typeUInt32, 25}: "OfferSequence",
+	{typeUInt32, 28}: "TransactionIndex",
+	{typeUInt32, 33}: "SetFlag",
+	{typeUInt32, 34}: "ClearFlag",
+
+	{typeUInt8, 3}: "TransactionResult",
+
+	{typeHash256, 5}:  "PreviousTxnID",
+	{typeHash256, 6}:  "LedgerIndex",
+	{typeHash256, 16}: "BookDirectory",
+
+	{typeAmount, 1}: "Amount",
+	{typeAmount, 2}: "Balance",
+	{typeAmount, 3}: "LimitAmount",
+	{typeAmount, 4}: "TakerPays",
+	{typeAmount, 5}: "TakerGets",
+	{typeAmount, 6}: "LowLimit",
+	{typeAmount, 7}: "HighLimit",
+	{typeAmount, 8}: "Fee",
+
+	{typeAccountID, 1}: "Account",
+	{typeAccountID, 2}: "Owner",
+	{typeAccountID, 3}: "Destination",
+	{typeAccountID, 4}: "Issuer",
+
+	{typeObject, 2}: "TransactionMetaData",
+	{typeObject, 3}: "CreatedNode",
+	{typeObject, 4}: "DeletedNode",
+	{typeObject, 5}: "ModifiedNode",
+	{typeObject, 6}: "PreviousFields",
+	{typeObject, 7}: "FinalFields",
+	{typeObject, 8}: "NewFields",
+
+	{typeArray, 8}: "AffectedNodes",
+}
+
+// txTypeNames covers the transaction types rippled serializes on
+// mainnet. A code missing here decodes to an UNKNOWN(n) placeholder —
+// never an error — so a ledger carrying a type this table has not
+// caught up with stays indexable; the parsers ignore types they do not
+// handle anyway.
+var txTypeNames = map[uint16]string{
+	0:   "Payment",
+	1:   "EscrowCreate",
+	2:   "EscrowFinish",
+	3:   "AccountSet",
+	4:   "EscrowCancel",
+	5:   "SetRegularKey",
+	7:   "OfferCreate",
+	8:   "OfferCancel",
+	10:  "TicketCreate",
+	12:  "SignerListSet",
+	13:  "PaymentChannelCreate",
+	14:  "PaymentChannelFund",
+	15:  "PaymentChannelClaim",
+	16:  "CheckCreate",
+	17:  "CheckCash",
+	18:  "CheckCancel",
+	19:  "DepositPreauth",
+	20:  "TrustSet",
+	21:  "AccountDelete",
+	25:  "NFTokenMint",
+	26:  "NFTokenBurn",
+	27:  "NFTokenCreateOffer",
+	28:  "NFTokenCancelOffer",
+	29:  "NFTokenAcceptOffer",
+	30:  "Clawback",
+	35:  "AMMCreate",
+	36:  "AMMDeposit",
+	37:  "AMMWithdraw",
+	38:  "AMMVote",
+	39:  "AMMBid",
+	40:  "AMMDelete",
+	41:  "XChainCreateClaimID",
+	42:  "XChainCommit",
+	43:  "XChainClaim",
+	44:  "XChainAccountCreateCommit",
+	45:  "XChainAddClaimAttestation",
+	46:  "XChainAddAccountCreateAttestation",
+	47:  "XChainModifyBridge",
+	48:  "XChainCreateBridge",
+	49:  "DIDSet",
+	50:  "DIDDelete",
+	51:  "OracleSet",
+	52:  "OracleDelete",
+	53:  "LedgerStateFix",
+	54:  "MPTokenIssuanceCreate",
+	55:  "MPTokenIssuanceDestroy",
+	56:  "MPTokenIssuanceSet",
+	57:  "MPTokenAuthorize",
+	100: "EnableAmendment",
+	101: "SetFee",
+	102: "UNLModify",
+}
+
+var ledgerEntryNames = map[uint16]string{
+	0x61: "AccountRoot",
+	0x64: "DirectoryNode",
+	0x6F: "Offer",
+	0x72: "RippleState",
+	0x79: "AMM",
+}
+
+// txResultNames maps the result codes validated metadata can carry.
+// Only the tes and tec classes ever reach a closed ledger — ter, tef,
+// tel, and tem are negative provisional codes that keep a transaction
+// out of the ledger entirely — so this is the full table a binary
+// ledger needs for its results to decode to the same names a JSON
+// ledger reports. A code past the table decodes to UNKNOWN(n).
+var txResultNames = map[uint8]string{
+	0:   "tesSUCCESS",
+	100: "tecCLAIM",
+	101: "tecPATH_PARTIAL",
+	102: "tecUNFUNDED_ADD",
+	103: "tecUNFUNDED_OFFER",
+	104: "tecUNFUNDED_PAYMENT",
+	105: "tecFAILED_PROCESSING",
+	121: "tecDIR_FULL",
+	122: "tecINSUF_RESERVE_LINE",
+	123: "tecINSUF_RESERVE_OFFER",
+	124: "tecNO_DST",
+	125: "tecNO_DST_INSUF_XRP",
+	126: "tecNO_LINE_INSUF_RESERVE",
+	127: "tecNO_LINE_REDUNDANT",
+	128: "tecPATH_DRY",
+	129: "tecUNFUNDED",
+	130: "tecNO_ALTERNATIVE_KEY",
+	131: "tecNO_REGULAR_KEY",
+	132: "tecOWNERS",
+	133: "tecNO_ISSUER",
+	134: "tecNO_AUTH",
+	135: "tecNO_LINE",
+	136: "tecINSUFF_FEE",
+	137: "tecFROZEN",
+	138: "tecNO_TARGET",
+	139: "tecNO_PERMISSION",
+	140: "tecNO_ENTRY",
+	141: "tecINSUFFICIENT_RESERVE",
+	142: "tecNEED_MASTER_KEY",
+	143: "tecDST_TAG_NEEDED",
+	144: "tecINTERNAL",
+	145: "tecOVERSIZE",
+	146: "tecCRYPTOCONDITION_ERROR",
+	147: "tecINVARIANT_FAILED",
+	148: "tecEXPIRED",
+	149: "tecDUPLICATE",
+	150: "tecKILLED",
+	151: "tecHAS_OBLIGATIONS",
+	152: "tecTOO_SOON",
+	154: "tecMAX_SEQUENCE_REACHED",
+	155: "tecNO_SUITABLE_NFTOKEN_PAGE",
+	156: "tecNFTOKEN_BUY_SELL_MISMATCH",
+	157: "tecNFTOKEN_OFFER_TYPE_MISMATCH",
+	158: "tecCANT_ACCEPT_OWN_NFTOKEN_OFFER",
+	159: "tecINSUFFICIENT_FUNDS",
+	160: "tecOBJECT_NOT_FOUND",
+	161: "tecINSUFFICIENT_PAYMENT",
+	162: "tecUNFUNDED_AMM",
+	163: "tecAMM_BALANCE",
+	164: "tecAMM_FAILED",
+	165: "tecAMM_INVALID_TOKENS",
+	166: "tecAMM_EMPTY",
+	167: "tecAMM_NOT_EMPTY",
+	168: "tecAMM_ACCOUNT",
+}
+
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) take(n int) ([]byte, error) {
+	if r.pos+n > len(r.data) {
+		return nil, fmt.Errorf("binarycodec: truncated at offset %d (need %d bytes)", r.pos, n)
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *reader) byte() (byte, error) {
+	b, err := r.take(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// fieldHeader reads a 1-3 byte field ID.
+func (r *reader) fieldHeader() (typeCode, fieldCode int, err error) {
+	b, err := r.byte()
+	if err != nil {
+		return 0, 0, err
+	}
+	typeCode = int(b >> 4)
+	fieldCode = int(b & 0x0F)
+	if typeCode == 0 {
+		tb, err := r.byte()
+		if err != nil {
+			return 0, 0, err
+		}
+		typeCode = int(tb)
+	}
+	if fieldCode == 0 {
+		fb, err := r.byte()
+		if err != nil {
+			return 0, 0, err
+		}
+		fieldCode = int(fb)
+	}
+	return typeCode, fieldCode, nil
+}
+
+// vl reads a variable-length prefix.
+func (r *reader) vl() (int, error) {
+	b1, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case b1 <= 192:
+		return int(b1), nil
+	case b1 <= 240:
+		b2, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		return 193 + (int(b1)-193)*256 + int(b2), nil
+	case b1 <= 254:
+		b2, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		b3, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		return 12481 + (int(b1)-241)*65536 + int(b2)*256 + int(b3), nil
+	}
+	return 0, fmt.Errorf("binarycodec: invalid length prefix %#x", b1)
+}
+
+// object reads fields until end of input, or until an ObjectEndMarker when
+// nested.
+func (r *reader) object(nested bool) (map[string]any, error) {
+	obj := make(map[string]any)
+	for r.pos < len(r.data) {
+		typeCode, fieldCode, err := r.fieldHeader()
+		if err != nil {
+			return nil, err
+		}
+		if nested && typeCode == typeObject && fieldCode == 1 { // ObjectEndMarker
+			return obj, nil
+		}
+		value, err := r.value(typeCode, fieldCode)
+		if err != nil {
+			return nil, err
+		}
+		if name := fieldNames[fieldKey{typeCode, fieldCode}]; name != "" && value != nil {
+			obj[name] = value
+		}
+	}
+	if nested {
+		return nil, fmt.Errorf("binarycodec: unterminated object")
+	}
+	return obj, nil
+}
+
+// array reads wrapper objects until an ArrayEndMarker.
+func (r *reader) array() ([]any, error) {
+	var items []any
+	for {
+		typeCode, fieldCode, err := r.fieldHeader()
+		if err != nil {
+			return nil, err
+		}
+		if typeCode == typeArray && fieldCode == 1 { // ArrayEndMarker
+			return items, nil
+		}
+		if typeCode != typeObject {
+			return nil, fmt.Errorf("binarycodec: array element with type %d", typeCode)
+		}
+		inner, err := r.object(true)
+		if err != nil {
+			return nil, err
+		}
+		if name := fieldNames[fieldKey{typeCode, fieldCode}]; name != "" {
+			items = append(items, map[string]any{name: inner})
+		}
+	}
+}
+
+func (r *reader) value(typeCode, fieldCode int) (any, error) {
+	switch typeCode {
+	case typeUInt8:
+		b, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if fieldCode == 3 { // TransactionResult
+			if name, ok := txResultNames[b]; ok {
+				return name, nil
+			}
+			return fmt.Sprintf("UNKNOWN(%d)", b), nil
+		}
+		return uint64(b), nil
+	case typeUInt16:
+		b, err := r.take(2)
+		if err != nil {
+			return nil, err
+		}
+		v := binary.BigEndian.Uint16(b)
+		switch fieldCode {
+		case 1:
+			if name, ok := ledgerEntryNames[v]; ok {
+				return name, nil
+			}
+		case 2:
+			if name, ok := txTypeNames[v]; ok {
+				return name, nil
+			}
+			// An unrecognized type must not fail the ledger: decode to a
+			// placeholder and let the parsers skip it like any other type
+			// they do not handle.
+			return fmt.Sprintf("UNKNOWN(%d)", v), nil
+		}
+		return uint64(v), nil
+	case typeUInt32:
+		b, err := r.take(4)
+		if err != nil {
+			return nil, err
+		}
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	case typeUInt64:
+		b, err := r.take(8)
+		if err != nil {
+			return nil, err
+		}
+		return strings.ToUpper(hex.EncodeToString(b)), nil
+	case typeHash128:
+		return r.hashHex(16)
+	case typeHash160:
+		return r.hashHex(20)
+	case typeHash256:
+		return r.hashHex(32)
+	case typeAmount:
+		return r.amount()
+	case typeBlob, typeVector256:
+		n, err := r.vl()
+		if err != nil {
+			return nil, err
+		}
+		return r.hashHex(n)
+	case typeAccountID:
+		n, err := r.vl()
+		if err != nil {
+			return nil, err
+		}
+		if n != 20 {
+			return nil, fmt.Errorf("binarycodec: AccountID of length %d", n)
+		}
+		b, err := r.take(20)
+		if err != nil {
+			return nil, err
+		}
+		return encodeAccountID(b), nil
+	case typeObject:
+		return r.object(true)
+	case typeArray:
+		return r.array()
+	case typePathSet:
+		return nil, r.skipPathSet()
+	case typeIssue:
+		return r.issue()
+	}
+	return nil, fmt.Errorf("binarycodec: unsupported field type %d", typeCode)
+}
+
+func (r *reader) hashHex(n int) (string, error) {
+	b, err := r.take(n)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+// amount decodes the 8-byte XRP or 48-byte issued-currency form into the
+// JSON wire shape: a drops string or a currency/issuer/value object.
+func (r *reader) amount() (any, error) {
+	b, err := r.take(8)
+	if err != nil {
+		return nil, err
+	}
+	raw := binary.BigEndian.Uint64(b)
+	if raw&(1<<63) == 0 { // native XRP
+		drops := raw & ((1 << 62) - 1)
+		s := fmt.Sprintf("%d", drops)
+		if raw&(1<<62) == 0 && drops != 0 { // negative (metadata deltas)
+			s = "-" + s
+		}
+		return s, nil
+	}
+	currency, err := r.currency()
+	if err != nil {
+		return nil, err
+	}
+	issuerBytes, err := r.take(20)
+	if err != nil {
+		return nil, err
+	}
+	value := iouValue(raw)
+	return map[string]any{
+		"currency": currency,
+		"issuer":   encodeAccountID(issuerBytes),
+		"value":    value,
+	}, nil
+}
+
+// iouValue renders the 64-bit issued-amount encoding: sign bit, exponent
+// biased by 97, 54-bit mantissa.
+func iouValue(raw uint64) string {
+	mantissa := raw & ((1 << 54) - 1)
+	if mantissa == 0 {
+		return "0"
+	}
+	exponent := int((raw>>54)&0xFF) - 97
+	v := new(big.Rat).SetUint64(mantissa)
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exponent))), nil)
+	if exponent >= 0 {
+		v.Mul(v, new(big.Rat).SetInt(pow))
+	} else {
+		v.Quo(v, new(big.Rat).SetInt(pow))
+	}
+	if raw&(1<<62) == 0 {
+		v.Neg(v)
+	}
+	return ratDecimal(v)
+}
+
+// currency decodes a 160-bit currency field: the zero code is XRP,
+// standard codes hold 3 ASCII bytes at offset 12, anything else stays hex.
+func (r *reader) currency() (string, error) {
+	b, err := r.take(20)
+	if err != nil {
+		return "", err
+	}
+	allZero := true
+	standard := true
+	for i, c := range b {
+		if c != 0 {
+			allZero = false
+			if i < 12 || i > 14 {
+				standard = false
+			}
+		}
+	}
+	if allZero {
+		return "XRP", nil
+	}
+	if standard {
+		return string(b[12:15]), nil
+	}
+	return strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+// issue decodes an STIssue (AMM Asset fields): a currency, plus an issuer
+// unless the currency is XRP.
+func (r *reader) issue() (any, error) {
+	currency, err := r.currency()
+	if err != nil {
+		return nil, err
+	}
+	if currency == "XRP" {
+		return map[string]any{"currency": currency}, nil
+	}
+	issuerBytes, err := r.take(20)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"currency": currency, "issuer": encodeAccountID(issuerBytes)}, nil
+}
+
+// skipPathSet walks a PathSet without decoding it: steps carry 20-byte
+// segments per flag bit, 0xFF starts another path, 0x00 ends the set.
+func (r *reader) skipPathSet() error {
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return err
+		}
+		switch b {
+		case 0x00:
+			return nil
+		case 0xFF:
+			continue
+		}
+		if b&0x01 != 0 { // account
+			if _, err := r.take(20); err != nil {
+				return err
+			}
+		}
+		if b&0x10 != 0 { // currency
+			if _, err := r.take(20); err != nil {
+				return err
+			}
+		}
+		if b&0x20 != 0 { // issuer
+			if _, err := r.take(20); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func ratDecimal(r *big.Rat) string {
+	s := r.FloatString(20)
+	i := len(s)
+	for i > 0 && s[i-1] == '0' {
+		i--
+	}
+	if i > 0 && s[i-1] == '.' {
+		i--
+	}
+	return s[:i]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
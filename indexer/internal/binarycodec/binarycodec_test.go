@@ -0,0 +1,278 @@
+package binarycodec_test
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/internal/binarycodec"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// The well-known account for the "masterpassphrase" seed: its 20-byte ID
+// and classic address are a published pair, which pins the base58check
+// encoding independently of this codebase.
+const (
+	genesisAccountHex  = "B5F762798A53D543A014CAF8B297CFF8F2F937E8"
+	genesisAccountAddr = "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh"
+)
+
+// Encoding helpers mirroring the canonical binary format, so fixtures stay
+// readable.
+
+func header(typeCode, fieldCode int) []byte {
+	switch {
+	case typeCode < 16 && fieldCode < 16:
+		return []byte{byte(typeCode<<4 | fieldCode)}
+	case typeCode >= 16 && fieldCode < 16:
+		return []byte{byte(fieldCode), byte(typeCode)}
+	case typeCode < 16:
+		return []byte{byte(typeCode << 4), byte(fieldCode)}
+	}
+	return []byte{0x00, byte(typeCode), byte(fieldCode)}
+}
+
+func uint16Field(fieldCode int, v uint16) []byte {
+	b := header(1, fieldCode)
+	return binary.BigEndian.AppendUint16(b, v)
+}
+
+func uint32Field(fieldCode int, v uint32) []byte {
+	b := header(2, fieldCode)
+	return binary.BigEndian.AppendUint32(b, v)
+}
+
+func xrpAmount(fieldCode int, drops uint64) []byte {
+	b := header(6, fieldCode)
+	return binary.BigEndian.AppendUint64(b, 1<<62|drops)
+}
+
+// iouAmount encodes mantissa*10^exponent of a standard 3-letter currency.
+func iouAmount(fieldCode int, mantissa uint64, exponent int, currency, issuerHex string) []byte {
+	b := header(6, fieldCode)
+	raw := uint64(1)<<63 | uint64(1)<<62 | uint64(exponent+97)<<54 | mantissa
+	b = binary.BigEndian.AppendUint64(b, raw)
+	cur := make([]byte, 20)
+	copy(cur[12:], currency)
+	b = append(b, cur...)
+	issuer, _ := hex.DecodeString(issuerHex)
+	return append(b, issuer...)
+}
+
+func accountField(fieldCode int, idHex string) []byte {
+	b := header(8, fieldCode)
+	id, _ := hex.DecodeString(idHex)
+	b = append(b, byte(len(id)))
+	return append(b, id...)
+}
+
+func TestDecodeOfferCreateBlob(t *testing.T) {
+	issuerHex := "0000000000000000000000000000000000000001"
+	var blob []byte
+	blob = append(blob, uint16Field(2, 7)...)                                          // TransactionType: OfferCreate
+	blob = append(blob, uint32Field(2, 0x00080000)...)                                 // Flags: tfSell
+	blob = append(blob, uint32Field(4, 7012)...)                                       // Sequence
+	blob = append(blob, uint32Field(10, 760000000)...)                                 // Expiration
+	blob = append(blob, xrpAmount(4, 200_000_000)...)                                  // TakerPays: 200 XRP
+	blob = append(blob, iouAmount(5, 1_000_000_000_000_000, -13, "USD", issuerHex)...) // TakerGets: 100 USD
+	blob = append(blob, accountField(1, genesisAccountHex)...)
+
+	raw, err := binarycodec.Decode(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tx xrpl.Transaction
+	if err := json.Unmarshal(raw, &tx); err != nil {
+		t.Fatal(err)
+	}
+	if tx.TransactionType != "OfferCreate" || tx.Sequence != 7012 {
+		t.Errorf("tx = %+v", tx)
+	}
+	if tx.Account != genesisAccountAddr {
+		t.Errorf("Account = %q, want %q", tx.Account, genesisAccountAddr)
+	}
+	if tx.Flags != 0x00080000 {
+		t.Errorf("Flags = %#x", tx.Flags)
+	}
+	if !tx.TakerPays.IsXRP() || tx.TakerPays.Value != "200" {
+		t.Errorf("TakerPays = %v, want 200 XRP", tx.TakerPays)
+	}
+	if tx.TakerGets.Currency != "USD" || tx.TakerGets.Value != "100" {
+		t.Errorf("TakerGets = %v, want 100 USD", tx.TakerGets)
+	}
+}
+
+func TestDecodeMetaBlob(t *testing.T) {
+	issuerHex := "0000000000000000000000000000000000000002"
+	var inner []byte
+	inner = append(inner, uint16Field(1, 0x6F)...) // LedgerEntryType: Offer
+	// FinalFields object.
+	inner = append(inner, header(14, 7)...)
+	inner = append(inner, accountField(1, genesisAccountHex)...)
+	inner = append(inner, uint32Field(4, 311)...)                                        // Sequence
+	inner = append(inner, iouAmount(5, 2_000_000_000_000_000, -14, "USD", issuerHex)...) // TakerGets: 20 USD
+	inner = append(inner, xrpAmount(4, 2_000_000)...)                                    // TakerPays: 2 XRP
+	inner = append(inner, header(14, 1)...)                                              // end FinalFields
+	// PreviousFields object.
+	inner = append(inner, header(14, 6)...)
+	inner = append(inner, iouAmount(5, 4_000_000_000_000_000, -14, "USD", issuerHex)...) // TakerGets: 40 USD
+	inner = append(inner, xrpAmount(4, 10_000_000)...)                                   // TakerPays: 10 XRP
+	inner = append(inner, header(14, 1)...)                                              // end PreviousFields
+
+	var blob []byte
+	blob = append(blob, header(16, 3)...) // TransactionResult
+	blob = append(blob, 0x00)             // tesSUCCESS
+	blob = append(blob, uint32Field(28, 4)...)
+	blob = append(blob, header(15, 8)...) // AffectedNodes
+	blob = append(blob, header(14, 5)...) // ModifiedNode wrapper
+	blob = append(blob, inner...)
+	blob = append(blob, header(14, 1)...) // end ModifiedNode
+	blob = append(blob, header(15, 1)...) // end array
+
+	raw, err := binarycodec.Decode(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var meta xrpl.Meta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta.TransactionResult != "tesSUCCESS" || meta.TransactionIndex != 4 {
+		t.Errorf("meta = %+v", meta)
+	}
+	if len(meta.AffectedNodes) != 1 {
+		t.Fatalf("AffectedNodes = %d, want 1", len(meta.AffectedNodes))
+	}
+	node, kind := meta.AffectedNodes[0].Node()
+	if kind != xrpl.NodeModified || node.LedgerEntryType != "Offer" {
+		t.Fatalf("node = %+v kind %d", node, kind)
+	}
+	var final, prev xrpl.OfferFields
+	if err := json.Unmarshal(node.FinalFields, &final); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(node.PreviousFields, &prev); err != nil {
+		t.Fatal(err)
+	}
+	if final.TakerGets.Value != "20" || prev.TakerGets.Value != "40" {
+		t.Errorf("TakerGets final/prev = %s/%s, want 20/40", final.TakerGets.Value, prev.TakerGets.Value)
+	}
+	if final.TakerPays.Value != "2" || prev.TakerPays.Value != "10" {
+		t.Errorf("TakerPays final/prev = %s/%s, want 2/10", final.TakerPays.Value, prev.TakerPays.Value)
+	}
+}
+
+func TestUnknownFieldsAreSkipped(t *testing.T) {
+	var blob []byte
+	blob = append(blob, uint16Field(2, 8)...) // TransactionType: OfferCancel
+	// An unknown Hash256 field (EmailHash-adjacent, field 9) must be
+	// skipped by length without desynchronizing.
+	blob = append(blob, header(5, 9)...)
+	blob = append(blob, make([]byte, 32)...)
+	// An unknown Blob field (TxnSignature) with a VL prefix.
+	blob = append(blob, header(7, 4)...)
+	blob = append(blob, 3, 0xAA, 0xBB, 0xCC)
+	blob = append(blob, uint32Field(25, 7012)...) // OfferSequence
+	blob = append(blob, accountField(1, genesisAccountHex)...)
+
+	raw, err := binarycodec.Decode(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tx xrpl.Transaction
+	if err := json.Unmarshal(raw, &tx); err != nil {
+		t.Fatal(err)
+	}
+	if tx.TransactionType != "OfferCancel" || tx.OfferSequence != 7012 || tx.Account != genesisAccountAddr {
+		t.Errorf("tx = %+v", tx)
+	}
+}
+
+// Non-success results must decode to their tec names, not a placeholder,
+// so binary- and JSON-sourced ledgers bucket results identically.
+func TestTransactionResultNames(t *testing.T) {
+	decode := func(code byte) string {
+		t.Helper()
+		var blob []byte
+		blob = append(blob, header(16, 3)...) // TransactionResult
+		blob = append(blob, code)
+		blob = append(blob, uint32Field(28, 1)...) // TransactionIndex
+		raw, err := binarycodec.Decode(blob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var meta xrpl.Meta
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			t.Fatal(err)
+		}
+		return meta.TransactionResult
+	}
+	for code, want := range map[byte]string{
+		0:   "tesSUCCESS",
+		103: "tecUNFUNDED_OFFER",
+		128: "tecPATH_DRY",
+		148: "tecEXPIRED",
+		150: "tecKILLED",
+		164: "tecAMM_FAILED",
+		200: "UNKNOWN(200)", // past the table, never an error
+	} {
+		if got := decode(code); got != want {
+			t.Errorf("result %d = %q, want %q", code, got, want)
+		}
+	}
+}
+
+// A transaction type the name table has not caught up with decodes to a
+// placeholder instead of failing the ledger it sits in.
+func TestUnknownTransactionTypeDecodes(t *testing.T) {
+	var blob []byte
+	blob = append(blob, uint16Field(2, 77)...) // TransactionType: unassigned code
+	blob = append(blob, uint32Field(4, 42)...) // Sequence
+	blob = append(blob, accountField(1, genesisAccountHex)...)
+
+	raw, err := binarycodec.Decode(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tx xrpl.Transaction
+	if err := json.Unmarshal(raw, &tx); err != nil {
+		t.Fatal(err)
+	}
+	if tx.TransactionType != "UNKNOWN(77)" || tx.Sequence != 42 {
+		t.Errorf("tx = %+v, want TransactionType UNKNOWN(77)", tx)
+	}
+	if tx.Account != genesisAccountAddr {
+		t.Errorf("Account = %q, want %q", tx.Account, genesisAccountAddr)
+	}
+}
+
+func TestTransactionFromBlobMatchesJSON(t *testing.T) {
+	var blob []byte
+	blob = append(blob, uint16Field(2, 7)...)
+	blob = append(blob, uint32Field(4, 1)...)
+	blob = append(blob, xrpAmount(4, 1_000_000)...)
+	blob = append(blob, iouAmount(5, 5_000_000_000_000_000, -15, "EUR", genesisAccountHex)...)
+	blob = append(blob, accountField(1, genesisAccountHex)...)
+
+	tx, err := xrpl.TransactionFromBlob(hex.EncodeToString(blob), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonForm := []byte(`{
+		"TransactionType": "OfferCreate",
+		"Sequence": 1,
+		"TakerPays": "1000000",
+		"TakerGets": {"currency": "EUR", "issuer": "` + genesisAccountAddr + `", "value": "5"},
+		"Account": "` + genesisAccountAddr + `"
+	}`)
+	var want xrpl.Transaction
+	if err := json.Unmarshal(jsonForm, &want); err != nil {
+		t.Fatal(err)
+	}
+	if tx.TransactionType != want.TransactionType || tx.Sequence != want.Sequence ||
+		tx.Account != want.Account || tx.TakerPays != want.TakerPays || tx.TakerGets != want.TakerGets {
+		t.Errorf("binary decode = %+v, json decode = %+v", tx, want)
+	}
+}
@@ -0,0 +1,58 @@
+// Package eventsink carries parsed ledger events to external systems.
+//
+// The durable half of the path is the Postgres outbox: the pipeline
+// appends each ledger's offers, trades, and pools before the ledger
+// checkpoints, and the outbox dispatcher hands them to registered
+// emitters in ledger order, each emitter on its own cursor. An
+// EventSink is an emitter's far end — a message bus, a log, a test
+// recorder — so adding a transport never touches the pipeline:
+// implement Publish, register it with the dispatcher, and the
+// at-least-once, per-ledger-ordered delivery comes from the outbox.
+package eventsink
+
+import (
+	"context"
+	"sync"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// EventSink publishes one event at a time, in the order handed to it.
+type EventSink interface {
+	// Publish hands over one event. An error leaves the dispatcher's
+	// cursor where it was, so the event comes around again —
+	// implementations must tolerate redelivery.
+	Publish(ctx context.Context, ev *model.OutboxEvent) error
+	// Close releases the transport.
+	Close() error
+}
+
+// Memory records published events; tests substitute it for a real bus.
+type Memory struct {
+	mu     sync.Mutex
+	events []*model.OutboxEvent
+}
+
+// NewMemory returns an empty recording sink.
+func NewMemory() *Memory { return &Memory{} }
+
+// Publish appends a copy of the event.
+func (m *Memory) Publish(_ context.Context, ev *model.OutboxEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp := *ev
+	m.events = append(m.events, &cp)
+	return nil
+}
+
+// Close is a no-op.
+func (m *Memory) Close() error { return nil }
+
+// Events returns a snapshot of everything published, in order.
+func (m *Memory) Events() []*model.OutboxEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]*model.OutboxEvent, len(m.events))
+	copy(out, m.events)
+	return out
+}
@@ -0,0 +1,138 @@
+package eventsink
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// natsDialTimeout bounds the TCP dial and the server greeting; a bus
+// that takes longer than this to say INFO is down.
+const natsDialTimeout = 5 * time.Second
+
+// NATS publishes events to a NATS server over its plain text protocol.
+// Only the publishing half is spoken — INFO, CONNECT, PUB, and the
+// PING/PONG heartbeat — which keeps the dependency surface at net.Dial,
+// like the hand-rolled WebSocket client in internal/ws. Events go to
+// the subject "<prefix>.<topic>". The connection is established lazily
+// and re-established after a failure; Publish returns the error in
+// between and the outbox dispatcher's retry brings the event back.
+type NATS struct {
+	addr   string
+	prefix string
+
+	mu   sync.Mutex // guards conn and every write to it
+	conn net.Conn
+}
+
+// NewNATS validates a nats://host:port URL and returns an unconnected
+// publisher; the first Publish dials.
+func NewNATS(rawURL, prefix string) (*NATS, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("eventsink: nats url %q: %w", rawURL, err)
+	}
+	if u.Scheme != "nats" || u.Host == "" || u.Path != "" {
+		return nil, fmt.Errorf("eventsink: nats url %q: want nats://host:port", rawURL)
+	}
+	return &NATS{addr: u.Host, prefix: prefix}, nil
+}
+
+// Publish sends one PUB frame, dialing first if the connection is down.
+func (n *NATS) Publish(_ context.Context, ev *model.OutboxEvent) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		if err := n.connectLocked(); err != nil {
+			return err
+		}
+	}
+	subject := ev.Topic
+	if n.prefix != "" {
+		subject = n.prefix + "." + ev.Topic
+	}
+	if _, err := fmt.Fprintf(n.conn, "PUB %s %d\r\n%s\r\n", subject, len(ev.Payload), ev.Payload); err != nil {
+		n.conn.Close()
+		n.conn = nil
+		return fmt.Errorf("eventsink: nats publish %s: %w", subject, err)
+	}
+	return nil
+}
+
+// Close drops the connection; a later Publish would re-dial.
+func (n *NATS) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+	return nil
+}
+
+// connectLocked dials, waits for the server's INFO greeting, and
+// introduces the client. Called with mu held.
+func (n *NATS) connectLocked() error {
+	conn, err := net.DialTimeout("tcp", n.addr, natsDialTimeout)
+	if err != nil {
+		return fmt.Errorf("eventsink: nats dial %s: %w", n.addr, err)
+	}
+	br := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(natsDialTimeout))
+	line, err := br.ReadString('\n')
+	if err == nil && !strings.HasPrefix(line, "INFO ") {
+		err = fmt.Errorf("greeted with %q", strings.TrimSpace(line))
+	}
+	if err == nil {
+		conn.SetReadDeadline(time.Time{})
+		_, err = fmt.Fprintf(conn, "CONNECT {\"verbose\":false,\"pedantic\":false,\"name\":\"lucendex-indexer\"}\r\n")
+	}
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("eventsink: nats handshake with %s: %w", n.addr, err)
+	}
+	n.conn = conn
+	go n.serve(conn, br)
+	return nil
+}
+
+// serve answers the server's heartbeat and notices a dropped
+// connection. -ERR and +OK lines need no reply; a fatal -ERR is
+// followed by the server closing the connection, which lands in the
+// read-error branch and lets the next Publish re-dial.
+func (n *NATS) serve(conn net.Conn, br *bufio.Reader) {
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			n.drop(conn)
+			return
+		}
+		if strings.TrimSpace(line) == "PING" {
+			n.mu.Lock()
+			_, err := conn.Write([]byte("PONG\r\n"))
+			n.mu.Unlock()
+			if err != nil {
+				n.drop(conn)
+				return
+			}
+		}
+	}
+}
+
+// drop closes a failed connection and forgets it, unless Publish has
+// already replaced it.
+func (n *NATS) drop(conn net.Conn) {
+	conn.Close()
+	n.mu.Lock()
+	if n.conn == conn {
+		n.conn = nil
+	}
+	n.mu.Unlock()
+}
@@ -0,0 +1,158 @@
+package eventsink
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+type pubFrame struct {
+	subject string
+	payload string
+}
+
+// fakeNATS speaks just enough of the server side of the protocol to
+// exercise the client: it greets with INFO, pings once after CONNECT,
+// and forwards every PUB frame.
+type fakeNATS struct {
+	ln     net.Listener
+	frames chan pubFrame
+	ponged chan struct{}
+}
+
+func newFakeNATS(t *testing.T) *fakeNATS {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	srv := &fakeNATS{ln: ln, frames: make(chan pubFrame, 16), ponged: make(chan struct{}, 16)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go srv.serve(conn)
+		}
+	}()
+	return srv
+}
+
+func (srv *fakeNATS) serve(conn net.Conn) {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	fmt.Fprint(conn, "INFO {\"server_id\":\"fake\"}\r\n")
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "CONNECT "):
+			fmt.Fprint(conn, "PING\r\n")
+		case line == "PONG":
+			srv.ponged <- struct{}{}
+		case strings.HasPrefix(line, "PUB "):
+			parts := strings.Fields(line)
+			size, _ := strconv.Atoi(parts[2])
+			payload := make([]byte, size+2) // payload plus trailing CRLF
+			if _, err := io.ReadFull(br, payload); err != nil {
+				return
+			}
+			srv.frames <- pubFrame{subject: parts[1], payload: string(payload[:size])}
+		}
+	}
+}
+
+func (srv *fakeNATS) url() string { return "nats://" + srv.ln.Addr().String() }
+
+func TestNATSPublish(t *testing.T) {
+	srv := newFakeNATS(t)
+	n, err := NewNATS(srv.url(), "dex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	events := []*model.OutboxEvent{
+		{Topic: "trade", Payload: `{"maker":"rM"}`},
+		{Topic: "offer", Payload: `{"account":"rA"}`},
+	}
+	for _, ev := range events {
+		if err := n.Publish(context.Background(), ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range events {
+		select {
+		case frame := <-srv.frames:
+			if frame.subject != "dex."+want.Topic || frame.payload != want.Payload {
+				t.Errorf("frame = %+v, want %s on dex.%s", frame, want.Payload, want.Topic)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("PUB frame never arrived")
+		}
+	}
+	// The heartbeat must be answered or the server would drop us.
+	select {
+	case <-srv.ponged:
+	case <-time.After(2 * time.Second):
+		t.Fatal("PING went unanswered")
+	}
+}
+
+func TestNATSReconnects(t *testing.T) {
+	srv := newFakeNATS(t)
+	n, err := NewNATS(srv.url(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	if err := n.Publish(context.Background(), &model.OutboxEvent{Topic: "trade", Payload: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	<-srv.frames
+	// Drop the connection; the next Publish must re-dial on its own.
+	// The dispatcher retries failed publishes, so the test does the
+	// same.
+	n.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := n.Publish(context.Background(), &model.OutboxEvent{Topic: "trade", Payload: "2"})
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("publish never recovered: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case frame := <-srv.frames:
+		if frame.subject != "trade" || frame.payload != "2" {
+			t.Errorf("frame after reconnect = %+v", frame)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("PUB frame never arrived after reconnect")
+	}
+}
+
+func TestNewNATSRejectsBadURLs(t *testing.T) {
+	for _, raw := range []string{"", "localhost:4222", "http://localhost:4222", "nats://"} {
+		if _, err := NewNATS(raw, "dex"); err == nil {
+			t.Errorf("NewNATS(%q) accepted", raw)
+		}
+	}
+}
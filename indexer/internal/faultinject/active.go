@@ -0,0 +1,100 @@
+//go:build faultinject
+
+package faultinject
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	mu     sync.Mutex
+	counts = map[string]int{}
+	delays = map[string]time.Duration{}
+)
+
+var knownPoints = map[string]bool{
+	LedgerDrop:      true,
+	LedgerDuplicate: true,
+	UpsertError:     true,
+	WSReadDelay:     true,
+	WSKill:          true,
+}
+
+// Enabled reports whether the chaos hooks are compiled in.
+func Enabled() bool { return true }
+
+// Load arms points from a spec of comma- or newline-separated
+// point=value entries — a count for counter points, a duration for
+// delay points. Blank entries and #-comment lines are skipped; unknown
+// point names are rejected so a typo cannot silently test nothing.
+func Load(spec string) error {
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		point, value, ok := strings.Cut(entry, "=")
+		point, value = strings.TrimSpace(point), strings.TrimSpace(value)
+		if !ok || !knownPoints[point] {
+			return fmt.Errorf("faultinject: unknown point in %q", entry)
+		}
+		if n, err := strconv.Atoi(value); err == nil {
+			Set(point, n)
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("faultinject: %q: %w", entry, err)
+		}
+		SetDelay(point, d)
+	}
+	return nil
+}
+
+// Set arms a counter point to fire n more times.
+func Set(point string, n int) {
+	mu.Lock()
+	counts[point] = n
+	mu.Unlock()
+}
+
+// SetDelay configures a delay point; zero clears it.
+func SetDelay(point string, d time.Duration) {
+	mu.Lock()
+	delays[point] = d
+	mu.Unlock()
+}
+
+// Fire reports whether the point injects its fault now, consuming one
+// armed count when it does.
+func Fire(point string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	if counts[point] <= 0 {
+		return false
+	}
+	counts[point]--
+	return true
+}
+
+// Sleep blocks for the point's configured delay, if any.
+func Sleep(point string) {
+	mu.Lock()
+	d := delays[point]
+	mu.Unlock()
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// Reset disarms every point.
+func Reset() {
+	mu.Lock()
+	counts = map[string]int{}
+	delays = map[string]time.Duration{}
+	mu.Unlock()
+}
@@ -0,0 +1,48 @@
+//go:build faultinject
+
+package faultinject
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadArmsPoints(t *testing.T) {
+	t.Cleanup(Reset)
+	if !Enabled() {
+		t.Fatal("Enabled() = false in a faultinject build")
+	}
+	err := Load("ledger.drop=2, ws.read.delay=5ms\n# a comment\nstore.upsert.error=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Fire(LedgerDrop) || !Fire(LedgerDrop) {
+		t.Error("armed counter did not fire twice")
+	}
+	if Fire(LedgerDrop) {
+		t.Error("counter fired past its count")
+	}
+	if !Fire(UpsertError) {
+		t.Error("second counter not armed")
+	}
+	start := time.Now()
+	Sleep(WSReadDelay)
+	if time.Since(start) < 5*time.Millisecond {
+		t.Error("configured delay did not sleep")
+	}
+
+	Reset()
+	if Fire(UpsertError) {
+		t.Error("Reset left a counter armed")
+	}
+}
+
+func TestLoadRejectsUnknownPoint(t *testing.T) {
+	t.Cleanup(Reset)
+	if err := Load("ledger.dorp=3"); err == nil {
+		t.Error("typoed point was accepted")
+	}
+	if err := Load("ws.read.delay=sometimes"); err == nil {
+		t.Error("unparseable value was accepted")
+	}
+}
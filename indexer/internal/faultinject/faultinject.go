@@ -0,0 +1,33 @@
+// Package faultinject provides chaos hooks at defined points in the
+// WebSocket client, the ingest loop, and the store, for rehearsing the
+// failure modes that keep surprising us: half-closed sockets, duplicate
+// stream messages, transient write errors. In a normal build every hook
+// compiles to a constant no-op; building with -tags faultinject
+// compiles the real implementation, which stays inert until armed
+// through Load (fed by the -fault-config file) or Set. A production
+// binary therefore cannot fire a fault no matter what it is given.
+package faultinject
+
+import "errors"
+
+// Injection points. Counter points fire the configured number of times
+// and then go quiet; delay points apply on every pass until reset.
+const (
+	// LedgerDrop swallows the next N ledgerClosed stream messages
+	// before the ingest loop acts on them.
+	LedgerDrop = "ledger.drop"
+	// LedgerDuplicate delivers the next N ledgerClosed messages twice.
+	LedgerDuplicate = "ledger.duplicate"
+	// UpsertError fails the next N offer upserts with ErrInjected.
+	UpsertError = "store.upsert.error"
+	// WSReadDelay sleeps for the configured duration before every
+	// WebSocket read.
+	WSReadDelay = "ws.read.delay"
+	// WSKill fails the next N WebSocket reads, like a socket the far
+	// end half-closed.
+	WSKill = "ws.kill"
+)
+
+// ErrInjected marks every injected failure, so tests and retry logic
+// can tell a simulated fault from a real one.
+var ErrInjected = errors.New("faultinject: injected fault")
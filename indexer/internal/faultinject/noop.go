@@ -0,0 +1,32 @@
+//go:build !faultinject
+
+package faultinject
+
+import (
+	"fmt"
+	"time"
+)
+
+// Enabled reports whether the chaos hooks are compiled in.
+func Enabled() bool { return false }
+
+// Load rejects any configuration: a binary built without the
+// faultinject tag has no hooks to arm.
+func Load(string) error {
+	return fmt.Errorf("faultinject: not compiled in; rebuild with -tags faultinject")
+}
+
+// Set is a no-op without the faultinject tag.
+func Set(string, int) {}
+
+// SetDelay is a no-op without the faultinject tag.
+func SetDelay(string, time.Duration) {}
+
+// Fire never fires in a normal build; calls inline to a constant false.
+func Fire(string) bool { return false }
+
+// Sleep is a no-op without the faultinject tag.
+func Sleep(string) {}
+
+// Reset is a no-op without the faultinject tag.
+func Reset() {}
@@ -0,0 +1,22 @@
+//go:build !faultinject
+
+package faultinject
+
+import "testing"
+
+func TestNormalBuildIsInert(t *testing.T) {
+	if Enabled() {
+		t.Fatal("Enabled() = true without the faultinject tag")
+	}
+	// Arming anything is ignored and Load refuses, so a stray chaos
+	// config cannot make a production binary misbehave.
+	Set(UpsertError, 100)
+	if Fire(UpsertError) {
+		t.Error("Fire fired in a normal build")
+	}
+	if err := Load("store.upsert.error=1"); err == nil {
+		t.Error("Load accepted a spec in a normal build")
+	}
+	Sleep(WSReadDelay) // must return immediately
+	Reset()
+}
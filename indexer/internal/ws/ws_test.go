@@ -0,0 +1,97 @@
+package ws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// echoServer upgrades and echoes every message back.
+func echoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+		for {
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(msg); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func wsURL(srv *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func TestEchoRoundTrip(t *testing.T) {
+	srv := echoServer(t)
+	conn, err := Dial(wsURL(srv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, msg := range []string{"hello", `{"command":"subscribe"}`, ""} {
+		if err := conn.WriteMessage([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		got, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != msg {
+			t.Errorf("echo = %q, want %q", got, msg)
+		}
+	}
+}
+
+func TestLargeMessage(t *testing.T) {
+	srv := echoServer(t)
+	conn, err := Dial(wsURL(srv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Forces the 16-bit and 64-bit length encodings.
+	for _, size := range []int{200, 70_000} {
+		msg := strings.Repeat("x", size)
+		if err := conn.WriteMessage([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		got, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != msg {
+			t.Errorf("echo of %d bytes mismatched (got %d bytes)", size, len(got))
+		}
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	srv := echoServer(t)
+	conn, err := Dial(wsURL(srv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// A second close is a no-op.
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
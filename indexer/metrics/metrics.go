@@ -0,0 +1,194 @@
+// Package metrics is a small dependency-free metrics layer with a
+// Prometheus text exposition. Labeled counters carry a hard cardinality
+// cap: each counter tracks at most maxSeries distinct label values and
+// folds everything beyond that into an "other" bucket, so unbounded
+// inputs (trading pairs, issuer addresses) cannot explode into thousands
+// of series and overwhelm the scraper.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// OtherBucket is the label value absorbing series beyond the cap.
+const OtherBucket = "other"
+
+// Counter is a monotonically increasing float64.
+type Counter struct {
+	bits atomic.Uint64
+}
+
+// Add increases the counter; negative deltas are ignored.
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+	for {
+		old := c.bits.Load()
+		next := math.Float64bits(math.Float64frombits(old) + delta)
+		if c.bits.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+// Value returns the current count.
+func (c *Counter) Value() float64 { return math.Float64frombits(c.bits.Load()) }
+
+// Gauge is a float64 that moves in both directions.
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+// Set replaces the current value.
+func (g *Gauge) Set(v float64) { g.bits.Store(math.Float64bits(v)) }
+
+// Value returns the current value.
+func (g *Gauge) Value() float64 { return math.Float64frombits(g.bits.Load()) }
+
+// LabeledCounter is a counter dimensioned by one label with bounded
+// cardinality.
+type LabeledCounter struct {
+	label string
+	max   int
+
+	mu     sync.Mutex
+	series map[string]*Counter
+}
+
+// Add increases the series for the given label value, or the "other"
+// bucket once the counter already tracks its maximum number of values.
+func (lc *LabeledCounter) Add(value string, delta float64) {
+	lc.mu.Lock()
+	c, ok := lc.series[value]
+	if !ok {
+		if len(lc.series) >= lc.max {
+			value = OtherBucket
+		}
+		if c, ok = lc.series[value]; !ok {
+			c = &Counter{}
+			lc.series[value] = c
+		}
+	}
+	lc.mu.Unlock()
+	c.Add(delta)
+}
+
+// Value returns the count for one label value.
+func (lc *LabeledCounter) Value(value string) float64 {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if c, ok := lc.series[value]; ok {
+		return c.Value()
+	}
+	return 0
+}
+
+// Registry holds the process's metrics and serves them in Prometheus
+// text format.
+type Registry struct {
+	mu       sync.Mutex
+	names    []string
+	help     map[string]string
+	counters map[string]*Counter
+	gauges   map[string]*Gauge
+	labeled  map[string]*LabeledCounter
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		help:     map[string]string{},
+		counters: map[string]*Counter{},
+		gauges:   map[string]*Gauge{},
+		labeled:  map[string]*LabeledCounter{},
+	}
+}
+
+// Counter registers (or returns the existing) plain counter.
+func (r *Registry) Counter(name, help string) *Counter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.counters[name]; ok {
+		return c
+	}
+	c := &Counter{}
+	r.counters[name] = c
+	r.register(name, help)
+	return c
+}
+
+// Gauge registers (or returns the existing) gauge.
+func (r *Registry) Gauge(name, help string) *Gauge {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if g, ok := r.gauges[name]; ok {
+		return g
+	}
+	g := &Gauge{}
+	r.gauges[name] = g
+	r.register(name, help)
+	return g
+}
+
+// LabeledCounter registers a counter dimensioned by one label, keeping at
+// most maxSeries distinct label values before folding into "other". The
+// "other" slot is reserved on top of maxSeries.
+func (r *Registry) LabeledCounter(name, help, label string, maxSeries int) *LabeledCounter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if lc, ok := r.labeled[name]; ok {
+		return lc
+	}
+	if maxSeries < 1 {
+		maxSeries = 1
+	}
+	lc := &LabeledCounter{label: label, max: maxSeries, series: map[string]*Counter{}}
+	r.labeled[name] = lc
+	r.register(name, help)
+	return lc
+}
+
+func (r *Registry) register(name, help string) {
+	r.names = append(r.names, name)
+	sort.Strings(r.names)
+	r.help[name] = help
+}
+
+// ServeHTTP writes the Prometheus text exposition.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, name := range r.names {
+		kind := "counter"
+		if _, ok := r.gauges[name]; ok {
+			kind = "gauge"
+		}
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, r.help[name], name, kind)
+		if g, ok := r.gauges[name]; ok {
+			fmt.Fprintf(w, "%s %g\n", name, g.Value())
+			continue
+		}
+		if c, ok := r.counters[name]; ok {
+			fmt.Fprintf(w, "%s %g\n", name, c.Value())
+			continue
+		}
+		lc := r.labeled[name]
+		lc.mu.Lock()
+		values := make([]string, 0, len(lc.series))
+		for value := range lc.series {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		for _, value := range values {
+			fmt.Fprintf(w, "%s{%s=%q} %g\n", name, lc.label, value, lc.series[value].Value())
+		}
+		lc.mu.Unlock()
+	}
+}
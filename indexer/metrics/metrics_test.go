@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLabeledCounterFoldsBeyondCap(t *testing.T) {
+	reg := NewRegistry()
+	lc := reg.LabeledCounter("offers_by_pair_total", "offers per pair", "pair", 2)
+
+	lc.Add("USD.rA/XRP", 1)
+	lc.Add("EUR.rB/XRP", 2)
+	lc.Add("USD.rA/XRP", 1)
+	// The cap is reached; new label values land in "other".
+	lc.Add("GBP.rC/XRP", 5)
+	lc.Add("JPY.rD/XRP", 1)
+	// Established series keep counting normally.
+	lc.Add("EUR.rB/XRP", 1)
+
+	if got := lc.Value("USD.rA/XRP"); got != 2 {
+		t.Errorf("USD pair = %g, want 2", got)
+	}
+	if got := lc.Value("EUR.rB/XRP"); got != 3 {
+		t.Errorf("EUR pair = %g, want 3", got)
+	}
+	if got := lc.Value(OtherBucket); got != 6 {
+		t.Errorf("other bucket = %g, want 6", got)
+	}
+	if got := lc.Value("GBP.rC/XRP"); got != 0 {
+		t.Errorf("capped pair tracked its own series: %g", got)
+	}
+}
+
+func TestExposition(t *testing.T) {
+	reg := NewRegistry()
+	reg.Counter("ledgers_total", "processed ledgers").Add(3)
+	reg.LabeledCounter("pool_updates_total", "updates per pool", "pool", 4).Add("rPool1", 2)
+
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE ledgers_total counter",
+		"ledgers_total 3",
+		`pool_updates_total{pool="rPool1"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("exposition missing %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestGaugeExposition(t *testing.T) {
+	reg := NewRegistry()
+	g := reg.Gauge("ledger_lag_count", "fetched ledgers not yet processed")
+	g.Set(42)
+	g.Set(7)
+	if got := g.Value(); got != 7 {
+		t.Errorf("gauge = %g, want 7", got)
+	}
+
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, nil)
+	body := rec.Body.String()
+	if !strings.Contains(body, "# TYPE ledger_lag_count gauge") {
+		t.Errorf("exposition misses the gauge TYPE line:\n%s", body)
+	}
+	if !strings.Contains(body, "ledger_lag_count 7") {
+		t.Errorf("exposition misses the gauge sample:\n%s", body)
+	}
+}
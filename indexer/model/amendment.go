@@ -0,0 +1,14 @@
+package model
+
+// Amendment is a protocol amendment the chain has enabled.
+type Amendment struct {
+	// Hash is the amendment id (SHA-512Half of the amendment name for
+	// classically named amendments).
+	Hash string
+	// Name is the human-readable name, or "" when unknown to this build.
+	Name string
+	// LedgerIndex is the ledger that enabled it; zero means it was
+	// already active before indexing began (seeded from the server).
+	LedgerIndex uint64
+	TxHash      string
+}
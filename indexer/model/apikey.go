@@ -0,0 +1,37 @@
+package model
+
+// APIKey is one partner's credential for the Partner API. Only the
+// SHA-256 hash of the key is stored; the plaintext exists nowhere but
+// with the partner.
+type APIKey struct {
+	Partner string `json:"partner"`
+	// RateLimit is the allowed requests per minute; 0 falls back to the
+	// server-wide default.
+	RateLimit int  `json:"rate_limit"`
+	Enabled   bool `json:"enabled"`
+	// Scopes grants access beyond the public market data endpoints;
+	// recognized scopes are "admin" and "global".
+	Scopes []string `json:"scopes,omitempty"`
+	// Accounts are the XRPL accounts this key is scoped to. Keys
+	// without the global or admin scope may only query account-scoped
+	// data for accounts listed here.
+	Accounts []string `json:"accounts,omitempty"`
+}
+
+// HasScope reports whether the key was granted the named scope.
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyUsage is one key's request count for one day, identified by
+// partner name because the key itself is only ever stored hashed.
+type APIKeyUsage struct {
+	Partner  string `json:"partner"`
+	Day      string `json:"day"`
+	Requests int64  `json:"requests"`
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// AssetKey renders an amount's asset as a stable identifier: the
+// canonical xrpl.Asset string form, "XRP" or "CUR.issuer". Metrics
+// labels and market lookups share this form.
+func AssetKey(a xrpl.Amount) string {
+	return a.Asset().String()
+}
+
+// ParseAssetKey is the inverse of AssetKey: the canonical asset string
+// back into an amount with no value. API parameters arrive in this
+// form; malformed strings are parse errors, not weird pairs.
+func ParseAssetKey(key string) (xrpl.Amount, error) {
+	asset, err := xrpl.ParseAsset(key)
+	if err != nil {
+		return xrpl.Amount{}, err
+	}
+	return xrpl.Amount{Currency: asset.Currency, Issuer: asset.Issuer}, nil
+}
+
+// PairKey names the trading pair of two assets independent of direction,
+// so buys and sells of the same market count together. The two asset keys
+// are joined in lexical order.
+func PairKey(a, b xrpl.Amount) string {
+	keys := []string{AssetKey(a), AssetKey(b)}
+	sort.Strings(keys)
+	return keys[0] + "/" + keys[1]
+}
+
+// ParsePairKey splits a PairKey back into its two assets, in the key's
+// lexical order.
+func ParsePairKey(pair string) (xrpl.Asset, xrpl.Asset, error) {
+	first, second, ok := strings.Cut(pair, "/")
+	if !ok {
+		return xrpl.Asset{}, xrpl.Asset{}, fmt.Errorf("model: malformed pair %q", pair)
+	}
+	a, err := xrpl.ParseAsset(first)
+	if err != nil {
+		return xrpl.Asset{}, xrpl.Asset{}, err
+	}
+	b, err := xrpl.ParseAsset(second)
+	if err != nil {
+		return xrpl.Asset{}, xrpl.Asset{}, err
+	}
+	return a, b, nil
+}
@@ -0,0 +1,20 @@
+package model
+
+// Asset flag names recorded in the asset_flags table.
+const (
+	// FlagGlobalFreeze marks every currency of an issuer as frozen.
+	FlagGlobalFreeze = "global_freeze"
+)
+
+// AssetFlagChange records an issuer setting or clearing a flag that affects
+// how its assets trade. Currency is empty for issuer-wide flags such as a
+// global freeze.
+type AssetFlagChange struct {
+	Issuer   string
+	Currency string
+	Flag     string
+	Set      bool
+
+	LedgerIndex uint64
+	TxHash      string
+}
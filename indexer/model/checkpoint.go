@@ -0,0 +1,35 @@
+package model
+
+// Checkpoint marks a ledger the indexer fully processed. One row is
+// written per ledger, so MIN/MAX over the table bound the contiguous-ish
+// indexed range and gaps show up as missing indexes.
+type Checkpoint struct {
+	LedgerIndex uint64
+	LedgerHash  string
+	// CloseTime is in seconds since the Ripple epoch, as reported in the
+	// ledger header.
+	CloseTime int64
+	// Timings is the ledger's per-stage processing breakdown in
+	// milliseconds (a total_ms key plus one per stage); nil when the
+	// pipeline did not measure it.
+	Timings map[string]float64
+	// TxStats is the ledger's transaction composition; nil when the
+	// pipeline did not record it (empty ledgers included).
+	TxStats *TxStats
+}
+
+// TxStats counts a ledger's transactions by TransactionType and by
+// engine result, the "what was in it" companion to the Timings
+// breakdown.
+type TxStats struct {
+	Types   map[string]int64 `json:"types"`
+	Results map[string]int64 `json:"results"`
+}
+
+// SlowLedger is one checkpoint ranked by its recorded processing time,
+// for the status report's worst-offenders list.
+type SlowLedger struct {
+	LedgerIndex uint64             `json:"ledger_index"`
+	TotalMS     float64            `json:"total_ms"`
+	Stages      map[string]float64 `json:"stages"`
+}
@@ -0,0 +1,12 @@
+package model
+
+// Gap is a ledger range the indexer knows it is missing, with the
+// reason it was skipped.
+type Gap struct {
+	From   uint64 `json:"from"`
+	To     uint64 `json:"to"`
+	Reason string `json:"reason"`
+}
+
+// Size is the number of ledgers the gap covers.
+func (g *Gap) Size() uint64 { return g.To - g.From + 1 }
@@ -0,0 +1,37 @@
+package model
+
+// Identity verification statuses. Negative outcomes are stored too, so
+// the enrichment job can rate-limit re-fetching dead domains.
+const (
+	// IdentityVerified: the account's domain serves an xrp-ledger.toml
+	// that claims the account back.
+	IdentityVerified = "verified"
+	// IdentityUnverified: the domain resolved and served a toml, but the
+	// toml does not list this account.
+	IdentityUnverified = "unverified"
+	// IdentityNoDomain: the account has no Domain field on-ledger (or is
+	// a validator key with no backing account).
+	IdentityNoDomain = "no_domain"
+	// IdentityUnreachable: the domain's toml could not be fetched.
+	IdentityUnreachable = "unreachable"
+)
+
+// Identity is the human-readable identity behind an on-ledger subject —
+// an issuing account or a validator master key — resolved through the
+// subject's Domain field and the domain's xrp-ledger.toml. Name, Logo
+// and Twitter are hints taken from the toml as-is; the indexer never
+// fetches the logo itself.
+type Identity struct {
+	// Account is the subject: an account address or a validator master
+	// public key.
+	Account string `json:"account"`
+	Domain  string `json:"domain,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Logo    string `json:"logo,omitempty"`
+	Twitter string `json:"twitter,omitempty"`
+	// Status is one of the Identity* constants above.
+	Status string `json:"status"`
+	// RefreshedAt is when the identity was last resolved, in Unix
+	// seconds.
+	RefreshedAt int64 `json:"refreshed_at"`
+}
@@ -0,0 +1,60 @@
+package model
+
+// The offer lifecycle. Offers enter as open, snapshot or invalid_parse
+// and only ever move forward:
+//
+//	open, snapshot ─→ partially_filled ─→ filled
+//	       │                 │             cancelled
+//	       └─────────────────┴──────────→  expired
+//	                                       removed_unfunded
+//
+// The four right-hand states are terminal. invalid_parse may move
+// anywhere, since a fixed parser replaying the raw transaction learns
+// the real state. The store enforces the same rules in SQL, keyed by
+// ledger index so an older event replayed out of order can never move a
+// row backwards; these helpers are the single source for its status
+// sets.
+
+var offerTransitions = map[string]map[string]bool{
+	OfferStatusSnapshot: statusSet(OfferStatusOpen, OfferStatusPartiallyFilled,
+		OfferStatusFilled, OfferStatusCancelled, OfferStatusExpired, OfferStatusRemovedUnfunded),
+	OfferStatusOpen: statusSet(OfferStatusPartiallyFilled,
+		OfferStatusFilled, OfferStatusCancelled, OfferStatusExpired, OfferStatusRemovedUnfunded),
+	OfferStatusPartiallyFilled: statusSet(
+		OfferStatusFilled, OfferStatusCancelled, OfferStatusExpired, OfferStatusRemovedUnfunded),
+	OfferStatusInvalidParse: statusSet(OfferStatusOpen, OfferStatusSnapshot, OfferStatusPartiallyFilled,
+		OfferStatusFilled, OfferStatusCancelled, OfferStatusExpired, OfferStatusRemovedUnfunded),
+}
+
+func statusSet(statuses ...string) map[string]bool {
+	set := make(map[string]bool, len(statuses))
+	for _, s := range statuses {
+		set[s] = true
+	}
+	return set
+}
+
+// CanTransition reports whether the lifecycle permits moving an offer
+// from one status to another. Equal statuses are always legal, so
+// replays are idempotent; an empty from means the offer's first sighting
+// and accepts any status.
+func CanTransition(from, to string) bool {
+	if from == to {
+		return true
+	}
+	if from == "" {
+		return to != ""
+	}
+	return offerTransitions[from][to]
+}
+
+// TerminalOfferStatuses lists the statuses an offer can never leave.
+func TerminalOfferStatuses() []string {
+	return []string{OfferStatusFilled, OfferStatusCancelled, OfferStatusExpired, OfferStatusRemovedUnfunded}
+}
+
+// ActiveOfferStatuses lists the statuses of offers still on the book —
+// the ones cancels and fills apply to.
+func ActiveOfferStatuses() []string {
+	return []string{OfferStatusOpen, OfferStatusSnapshot, OfferStatusPartiallyFilled}
+}
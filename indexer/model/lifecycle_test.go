@@ -0,0 +1,49 @@
+package model
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	allowed := [][2]string{
+		{"", OfferStatusOpen},
+		{"", OfferStatusInvalidParse},
+		{OfferStatusOpen, OfferStatusOpen}, // replays are idempotent
+		{OfferStatusOpen, OfferStatusPartiallyFilled},
+		{OfferStatusOpen, OfferStatusCancelled},
+		{OfferStatusSnapshot, OfferStatusOpen},
+		{OfferStatusPartiallyFilled, OfferStatusFilled},
+		{OfferStatusPartiallyFilled, OfferStatusRemovedUnfunded},
+		{OfferStatusInvalidParse, OfferStatusFilled}, // fixed parser replay
+	}
+	for _, tc := range allowed {
+		if !CanTransition(tc[0], tc[1]) {
+			t.Errorf("%q -> %q rejected, want allowed", tc[0], tc[1])
+		}
+	}
+
+	forbidden := [][2]string{
+		{OfferStatusOpen, OfferStatusSnapshot}, // never backwards to an entry state
+		{OfferStatusPartiallyFilled, OfferStatusOpen},
+		{OfferStatusFilled, OfferStatusOpen}, // terminal states never move
+		{OfferStatusCancelled, OfferStatusPartiallyFilled},
+		{OfferStatusExpired, OfferStatusFilled},
+		{OfferStatusRemovedUnfunded, OfferStatusOpen},
+		{OfferStatusOpen, OfferStatusInvalidParse},
+		{OfferStatusOpen, "teleported"},
+	}
+	for _, tc := range forbidden {
+		if CanTransition(tc[0], tc[1]) {
+			t.Errorf("%q -> %q allowed, want rejected", tc[0], tc[1])
+		}
+	}
+
+	for _, terminal := range TerminalOfferStatuses() {
+		for _, active := range ActiveOfferStatuses() {
+			if CanTransition(terminal, active) {
+				t.Errorf("terminal %q may move to %q", terminal, active)
+			}
+			if !CanTransition(active, terminal) {
+				t.Errorf("active %q may not reach %q", active, terminal)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package model
+
+// MarketStatsUpdate is one ledger's trade activity on one market,
+// folded by the pipeline into a single hourly bucket. Pair is the
+// direction-independent PairKey; the market's base asset is the
+// lexically first of the pair, volumes are denominated in it and prices
+// are quote per base.
+type MarketStatsUpdate struct {
+	Pair string
+
+	// Bucket is the ledger close time truncated to the hour
+	// (Ripple-epoch seconds / 3600).
+	Bucket int64
+
+	// Volume is the base-side amount that changed hands in this ledger.
+	Volume string
+
+	// High, Low and Last bound and close the ledger's prices; all empty
+	// when no fill in the ledger had a computable price.
+	High string
+	Low  string
+	Last string
+
+	TradeCount  int64
+	LedgerIndex uint64
+}
+
+// MarketStats is the sliding 24-hour rollup of one market, summed from
+// its hourly buckets. High, Low and LastPrice are empty when the window
+// saw no priced fill; Volume is "0" for markets listed only because
+// they have open offers or a pool.
+type MarketStats struct {
+	Pair       string
+	Volume     string
+	High       string
+	Low        string
+	LastPrice  string
+	TradeCount int64
+}
+
+// TrackedMarket is a pair promoted by market discovery: its recent
+// activity crossed the tracking thresholds, so it gets a one-time book
+// snapshot and, optionally, a books subscription. Demotion stops the
+// special treatment but keeps the row — and all indexed data — so a
+// returning market re-promotes in place.
+type TrackedMarket struct {
+	Pair             string
+	DiscoveredLedger uint64
+	// DiscoveredCloseTime and LastActiveCloseTime are in seconds since
+	// the Ripple epoch.
+	DiscoveredCloseTime int64
+	LastActiveLedger    uint64
+	LastActiveCloseTime int64
+	Demoted             bool
+}
@@ -0,0 +1,64 @@
+package model
+
+import "github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+
+// NFT offer statuses as recorded in the nft_offers table. The lifecycle
+// is simpler than DEX offers': an offer stays open until a single accept
+// or cancel deletes it from the ledger, so no partial states exist.
+const (
+	NFTOfferStatusOpen      = "open"
+	NFTOfferStatusAccepted  = "accepted"
+	NFTOfferStatusCancelled = "cancelled"
+)
+
+// NFTOffer is an NFTokenOffer ledger entry — a bid on or an asking price
+// for one token. Unlike DEX offers, which key on (account, sequence),
+// accepts and cancels reference NFT offers only by their ledger entry
+// index, so OfferIndex is the identity.
+type NFTOffer struct {
+	// OfferIndex is the NFTokenOffer entry's ledger index.
+	OfferIndex string
+	TokenID    string
+	// Owner is the account that placed the offer; for a sell offer that
+	// is also the token's holder.
+	Owner string
+	// Destination, when set, is the only account allowed to accept.
+	Destination string
+	Amount      xrpl.Amount
+	// Sell reports the offer's direction: the owner sells the token for
+	// Amount rather than bidding Amount for it.
+	Sell bool
+	// Expiration is Ripple epoch seconds after which the offer is void;
+	// zero means it never expires.
+	Expiration int64
+
+	Status      string
+	LedgerIndex uint64
+	// CloseTime is the producing ledger's close time (Ripple epoch
+	// seconds); the pipeline fills it in from the ledger header.
+	CloseTime int64
+	TxHash    string
+}
+
+// NFTTrade is a completed NFT sale: an NFTokenAcceptOffer matching a buy
+// offer, a sell offer, or — brokered — one of each.
+type NFTTrade struct {
+	TokenID string
+	Seller  string
+	Buyer   string
+	// Amount is the sale price, taken from the buy offer when the accept
+	// was brokered.
+	Amount xrpl.Amount
+	// BrokerFee is the broker's cut of a brokered accept; zero value for
+	// direct accepts.
+	BrokerFee xrpl.Amount
+
+	// SellOfferIndex and BuyOfferIndex are the consumed offer entries;
+	// a direct accept fills in only the side that existed.
+	SellOfferIndex string
+	BuyOfferIndex  string
+
+	LedgerIndex uint64
+	CloseTime   int64
+	TxHash      string
+}
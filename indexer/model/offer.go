@@ -0,0 +1,192 @@
+// Package model holds the domain types the indexer extracts from the
+// ledger stream and hands to the store and the API layers.
+package model
+
+import (
+	"math/big"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// Offer statuses as recorded in the offers table; the legal moves
+// between them are defined in lifecycle.go.
+const (
+	OfferStatusOpen            = "open"
+	OfferStatusPartiallyFilled = "partially_filled"
+	OfferStatusFilled          = "filled"
+	OfferStatusCancelled       = "cancelled"
+	OfferStatusExpired         = "expired"
+	// OfferStatusRemovedUnfunded marks an offer the ledger deleted because
+	// its owner could no longer fund it.
+	OfferStatusRemovedUnfunded = "removed_unfunded"
+	// OfferStatusSnapshot marks an offer seeded from a full-book snapshot
+	// rather than observed at creation; its history before the snapshot is
+	// unknown.
+	OfferStatusSnapshot = "snapshot"
+	// OfferStatusInvalidParse marks an offer whose transaction the parser
+	// could not make sense of; the row carries the error and the raw
+	// transaction stays in raw_transactions for triage and replay.
+	OfferStatusInvalidParse = "invalid_parse"
+)
+
+// Offer is a DEX offer as the indexer tracks it.
+//
+// Price semantics: an offer carries two distinct prices and they must not be
+// conflated. LimitPrice is the quality the offer was placed at — the worst
+// exchange rate the owner accepts — derived from the ledger Offer entry
+// (TakerPays/TakerGets, or the BookDirectory quality when the entry carries
+// one, which survives partial fills exactly). ExecutedPrice is the
+// volume-weighted average rate the offer actually traded at, reconstructed
+// from metadata fills; it is empty until the offer has traded. Orderbook
+// views quote LimitPrice; trade history quotes ExecutedPrice.
+//
+// For tfSell offers the owner commits to selling all of TakerGets, so fills
+// can land at a better rate than LimitPrice; the naive TakerPays/TakerGets
+// division only ever describes the limit, never the execution.
+type Offer struct {
+	Account  string
+	Sequence uint32
+	Flags    uint32
+
+	TakerGets xrpl.Amount
+	TakerPays xrpl.Amount
+
+	// LimitPrice is TakerPays per unit TakerGets as a decimal string.
+	LimitPrice string
+	// ExecutedPrice is the volume-weighted average fill price, empty if
+	// the offer has not traded.
+	ExecutedPrice string
+
+	Status      string
+	LedgerIndex uint64
+	// CloseTime is the producing ledger's close time (Ripple epoch
+	// seconds); the pipeline fills it in from the ledger header.
+	CloseTime int64
+	TxHash    string
+
+	// ReplacesSequence is the prior offer this OfferCreate cancelled via
+	// its OfferSequence field; zero when the offer replaced nothing.
+	ReplacesSequence uint32
+	// ReplacedBy is the sequence of the offer that superseded this one;
+	// zero while the offer stands on its own.
+	ReplacedBy uint32
+
+	// FundedTakerGets is how much of TakerGets the owner's last observed
+	// balance could actually deliver; empty until -compute-funded has
+	// seen a balance for the owner. Each offer is capped independently,
+	// so several offers backed by one balance can still sum past it.
+	// Unfunded marks an owner whose observed balance was zero or
+	// negative. FundedCheckedLedger is the ledger the observation came
+	// from — the staleness bound consumers of funded depth should apply.
+	FundedTakerGets     string
+	Unfunded            bool
+	FundedCheckedLedger uint64
+
+	// ParserVersion is the parser revision that produced this row; see
+	// parser.Version.
+	ParserVersion int
+
+	// ParseError is why parsing failed; set only on invalid_parse rows.
+	ParseError string
+
+	// Provisional marks a row written by phase one of priority catch-up,
+	// whose ledger's full parse is still queued; the phase-two replay
+	// rewrites the row and clears the mark.
+	Provisional bool
+}
+
+// StatusTransition is one step in an offer's lifecycle: the status it
+// entered, the ledger and transaction that moved it there, and that
+// ledger's close time (seconds since the Ripple epoch).
+type StatusTransition struct {
+	Status      string
+	LedgerIndex uint64
+	CloseTime   int64
+	TxHash      string
+}
+
+// OfferHistory pairs an offer's current row with the transitions that got
+// it there, in ledger order.
+type OfferHistory struct {
+	Offer       *Offer
+	Transitions []StatusTransition
+
+	// Fills are the trades that consumed the offer, oldest first, each
+	// naming the counterparty. Populated by the account listing; nil
+	// elsewhere.
+	Fills []*Trade
+}
+
+// AccountOffers is one page of an account's offer listing: the offers
+// with the lifecycle behind each, the account's offer counts per status
+// across the whole window, and the cursor that resumes the next page
+// (empty on the last one).
+type AccountOffers struct {
+	Offers       []*OfferHistory
+	StatusTotals map[string]int64
+	NextCursor   string
+}
+
+// Sell reports whether the offer carries the tfSell flag.
+func (o *Offer) Sell() bool { return o.Flags&xrpl.TfSell != 0 }
+
+// ImmediateOrCancel and FillOrKill report the execution-only flags; such
+// offers never rest on the book.
+func (o *Offer) ImmediateOrCancel() bool { return o.Flags&xrpl.TfImmediateOrCancel != 0 }
+func (o *Offer) FillOrKill() bool        { return o.Flags&xrpl.TfFillOrKill != 0 }
+
+// FlagNames renders the semantic offer flags as labels for the API and
+// audit rows.
+func (o *Offer) FlagNames() []string {
+	var names []string
+	if o.Sell() {
+		names = append(names, "sell")
+	}
+	if o.ImmediateOrCancel() {
+		names = append(names, "immediate_or_cancel")
+	}
+	if o.FillOrKill() {
+		names = append(names, "fill_or_kill")
+	}
+	if o.Flags&xrpl.TfPassive != 0 {
+		names = append(names, "passive")
+	}
+	return names
+}
+
+// LimitPriceRat computes TakerPays/TakerGets from a pair of amounts,
+// returning nil if either side is missing or zero.
+func LimitPriceRat(pays, gets xrpl.Amount) *big.Rat {
+	p, err := pays.Rat()
+	if err != nil {
+		return nil
+	}
+	g, err := gets.Rat()
+	if err != nil || g.Sign() == 0 {
+		return nil
+	}
+	return p.Quo(p, g)
+}
+
+// PriceString renders a price rational with the same formatting the rest of
+// the model uses for decimal values. Returns "" for nil.
+func PriceString(r *big.Rat) string {
+	if r == nil {
+		return ""
+	}
+	return RatDecimal(r)
+}
+
+// RatDecimal formats a rational as a plain decimal string with trailing
+// zeros trimmed.
+func RatDecimal(r *big.Rat) string {
+	s := r.FloatString(20)
+	i := len(s)
+	for i > 0 && s[i-1] == '0' {
+		i--
+	}
+	if i > 0 && s[i-1] == '.' {
+		i--
+	}
+	return s[:i]
+}
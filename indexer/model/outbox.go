@@ -0,0 +1,15 @@
+package model
+
+// OutboxEvent is one externally publishable event, written in the same
+// per-ledger pass as the data it describes and dispatched later. (Seq)
+// orders events within their ledger; (LedgerIndex, Seq) is the identity
+// a replayed ledger converges on, so reprocessing never duplicates
+// rows. ID is assigned by the store and orders the global dispatch
+// stream.
+type OutboxEvent struct {
+	ID          int64
+	LedgerIndex uint64
+	Seq         int
+	Topic       string
+	Payload     string
+}
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// Pool is an AMM instance with its current reserves. Reserves live in the
+// AMM account's trust lines (and XRP balance), so they are reconstructed
+// from balance-affecting transactions rather than read off one ledger
+// entry.
+type Pool struct {
+	// Account is the AMM's dedicated account that holds the reserves.
+	Account string
+
+	Asset1 xrpl.Amount
+	Asset2 xrpl.Amount
+
+	// TradingFee is in units of 1/100000 (an AMM fee of 500 = 0.5%).
+	TradingFee uint32
+
+	// CreatedLedger is the ledger whose AMMCreate made the pool; zero for
+	// pools discovered before creation tracking existed.
+	CreatedLedger uint64
+
+	// LPTokenCurrency is the 40-hex currency code of the pool's LP token,
+	// issued by the AMM account itself.
+	LPTokenCurrency string
+
+	LedgerIndex uint64
+}
+
+// FeeBasisPoints converts a trading fee from its on-ledger units of
+// 1/100000 to basis points ("60" for a fee of 600, "0.5" for 5).
+func FeeBasisPoints(fee uint32) string {
+	return feeScaled(fee, 10)
+}
+
+// FeePercent converts a trading fee from units of 1/100000 to a percent
+// string ("0.6" for a fee of 600). The ledger caps fees at 1000 (1%).
+func FeePercent(fee uint32) string {
+	return feeScaled(fee, 1000)
+}
+
+// feeScaled divides fee by div and renders the exact decimal.
+func feeScaled(fee, div uint32) string {
+	whole := fee / div
+	frac := fee % div
+	if frac == 0 {
+		return strconv.FormatUint(uint64(whole), 10)
+	}
+	width := len(strconv.FormatUint(uint64(div), 10)) - 1
+	s := strconv.FormatUint(uint64(whole), 10) + "." +
+		strings.TrimRight(padLeft(strconv.FormatUint(uint64(frac), 10), width), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func padLeft(s string, width int) string {
+	for len(s) < width {
+		s = "0" + s
+	}
+	return s
+}
+
+// FeeChange records the effective trading fee of a pool at a ledger, so
+// the fee history survives later votes. The effective fee is the
+// vote-weighted value rippled writes to the AMM entry, not any single
+// vote's proposal.
+type FeeChange struct {
+	// PoolAccount is the AMM account whose fee changed.
+	PoolAccount string
+	// TradingFee is the new effective fee in units of 1/100000.
+	TradingFee uint32
+
+	LedgerIndex uint64
+	CloseTime   int64
+	TxHash      string
+}
+
+// PoolAdjustment is a signed change to one reserve of a pool, produced by
+// transactions that move funds in or out of an AMM account outside normal
+// swaps — clawbacks in particular.
+type PoolAdjustment struct {
+	// Account is the AMM account whose reserve changed.
+	Account  string
+	Currency string
+	Issuer   string
+	// Delta is the signed decimal change to the reserve.
+	Delta string
+
+	LedgerIndex uint64
+	CloseTime   int64
+	TxHash      string
+}
+
+// PoolReserveSnapshot is one row of a pool's reserve history: the pool's
+// state right after an update at the given ledger. TVLXRP is only set for
+// pools with an XRP leg, valuing the whole pool at twice its XRP reserve.
+type PoolReserveSnapshot struct {
+	Account     string `json:"account"`
+	LedgerIndex uint64 `json:"ledger_index"`
+	CloseTime   int64  `json:"close_time"`
+
+	Asset1Value string `json:"asset1_value"`
+	Asset2Value string `json:"asset2_value"`
+	LPSupply    string `json:"lp_supply"`
+	TradingFee  uint32 `json:"trading_fee"`
+	TVLXRP      string `json:"tvl_xrp,omitempty"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import "github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+
+// Trade is a single fill against a resting offer, reconstructed from the
+// metadata of the transaction that consumed it. Price is the actual
+// execution rate of this fill — Pays per unit Gets — never the resting
+// offer's limit price.
+type Trade struct {
+	// Maker is the owner of the resting offer that was consumed;
+	// Taker is the account whose transaction consumed it.
+	Maker string
+	Taker string
+
+	// OfferSequence identifies the maker's offer.
+	OfferSequence uint32
+
+	// Gets and Pays are the amounts that changed hands in this fill,
+	// oriented from the maker's offer: the maker gave Gets and received
+	// Pays.
+	Gets xrpl.Amount
+	Pays xrpl.Amount
+
+	// Price is Pays per unit Gets for this fill, as a decimal string.
+	Price string
+
+	LedgerIndex uint64
+	TxHash      string
+
+	// ParserVersion is the parser revision that produced this row; see
+	// parser.Version.
+	ParserVersion int
+}
@@ -0,0 +1,31 @@
+package model
+
+// TrustLine is one account's side of a RippleState entry: the holder's
+// trust in an issuer for one currency. Rows key on (account, currency,
+// issuer); a deleted RippleState removes the row rather than marking it,
+// because a line with no entry holds nothing and counts no holder.
+type TrustLine struct {
+	// Account is the holder; Issuer the account it extends trust to.
+	Account  string
+	Currency string
+	Issuer   string
+
+	// Limit is the holder's trust limit as a decimal string.
+	Limit string
+
+	// NoRipple and Frozen are the holder-side entry flags.
+	NoRipple bool
+	Frozen   bool
+
+	LedgerIndex uint64
+	CloseTime   int64
+	TxHash      string
+}
+
+// TrustLineRemoval identifies a trust line a deleted RippleState entry
+// took with it. Each deletion removes both accounts' lines.
+type TrustLineRemoval struct {
+	Account  string
+	Currency string
+	Issuer   string
+}
@@ -0,0 +1,54 @@
+package model
+
+// Validation is one validator's vote for a ledger, keyed by the master
+// public key after manifest resolution. Repeated votes for the same
+// (validator, ledger) deduplicate on insert.
+type Validation struct {
+	Validator   string `json:"validator"` // master public key
+	LedgerIndex uint64 `json:"ledger_index"`
+	LedgerHash  string `json:"ledger_hash"`
+	// SigningTime is in seconds since the Ripple epoch.
+	SigningTime int64 `json:"signing_time"`
+}
+
+// ValidatorScore is one validator's agreement over a scoring window:
+// the fraction of our validated ledgers it voted for with the matching
+// hash, counted only from the ledger it was first seen at.
+type ValidatorScore struct {
+	Validator   string `json:"validator"`
+	WindowStart int64  `json:"window_start"` // close time, Ripple epoch
+	WindowEnd   int64  `json:"window_end"`
+	Agreed      int64  `json:"agreed"`
+	Missed      int64  `json:"missed"`
+	Disagreed   int64  `json:"disagreed"`
+	// Agreement is agreed over all ledgers the validator was expected to
+	// validate in the window.
+	Agreement float64 `json:"agreement"`
+	// AvgSkewSeconds is the mean signing-time minus close-time over the
+	// agreed validations.
+	AvgSkewSeconds float64 `json:"avg_skew_seconds"`
+}
+
+// UNLParticipation is one UNL validator's coverage of a checkpointed
+// ledger range: ledgers it validated with the canonical hash, missed
+// entirely, or signed with a different hash.
+type UNLParticipation struct {
+	Validator string `json:"validator"`
+	Expected  int64  `json:"expected"`
+	Agreed    int64  `json:"agreed"`
+	Missed    int64  `json:"missed"`
+	Disagreed int64  `json:"disagreed"`
+	// Participation is agreed over expected; zero when the range holds
+	// no checkpointed ledgers.
+	Participation float64 `json:"participation"`
+}
+
+// ValidationDisagreement is one side of a split vote: the validators
+// that signed this hash for a ledger index other validators signed
+// differently. A fork produces one row per competing hash.
+type ValidationDisagreement struct {
+	LedgerIndex uint64   `json:"ledger_index"`
+	LedgerHash  string   `json:"ledger_hash"`
+	Votes       int      `json:"votes"`
+	Validators  []string `json:"validators"`
+}
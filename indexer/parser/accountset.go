@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// asfGlobalFreeze is the AccountSet flag number for freezing every trust
+// line of the issuer.
+const asfGlobalFreeze = 7
+
+// ParseAccountSet inspects an AccountSet transaction for changes the DEX
+// cares about. Currently that is the global freeze flag: setting it freezes
+// every currency of the issuer, which the store records in asset_flags so
+// affected offers can be surfaced as frozen. Returns nil for AccountSets
+// that don't touch a tracked flag.
+func ParseAccountSet(tx *xrpl.Transaction, ledgerIndex uint64) (*model.AssetFlagChange, error) {
+	if tx.TransactionType != "AccountSet" {
+		return nil, fmt.Errorf("parser: not an AccountSet: %s", tx.TransactionType)
+	}
+	if tx.Meta == nil || tx.Meta.TransactionResult != tesSUCCESS {
+		return nil, nil
+	}
+	switch {
+	case tx.SetFlag == asfGlobalFreeze:
+		return flagChange(tx, ledgerIndex, true), nil
+	case tx.ClearFlag == asfGlobalFreeze:
+		return flagChange(tx, ledgerIndex, false), nil
+	}
+	return nil, nil
+}
+
+// DomainChanged reports whether a successful AccountSet set or cleared
+// the account's Domain. AccountSet only carries the field when it is
+// being changed, so its presence is the signal; the cached identity
+// behind the old domain is stale either way.
+func DomainChanged(tx *xrpl.Transaction) bool {
+	return tx.TransactionType == "AccountSet" && tx.Meta != nil &&
+		tx.Meta.TransactionResult == tesSUCCESS && tx.Domain != nil
+}
+
+func flagChange(tx *xrpl.Transaction, ledgerIndex uint64, set bool) *model.AssetFlagChange {
+	return &model.AssetFlagChange{
+		Issuer:      tx.Account,
+		Flag:        model.FlagGlobalFreeze,
+		Set:         set,
+		LedgerIndex: ledgerIndex,
+		TxHash:      tx.Hash,
+	}
+}
@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func TestDomainChanged(t *testing.T) {
+	domain := "6578616D706C652E636F6D" // "example.com"
+	empty := ""
+	cases := []struct {
+		name string
+		tx   *xrpl.Transaction
+		want bool
+	}{
+		{"set", &xrpl.Transaction{TransactionType: "AccountSet", Domain: &domain,
+			Meta: &xrpl.Meta{TransactionResult: "tesSUCCESS"}}, true},
+		{"cleared", &xrpl.Transaction{TransactionType: "AccountSet", Domain: &empty,
+			Meta: &xrpl.Meta{TransactionResult: "tesSUCCESS"}}, true},
+		{"untouched", &xrpl.Transaction{TransactionType: "AccountSet",
+			Meta: &xrpl.Meta{TransactionResult: "tesSUCCESS"}}, false},
+		{"failed", &xrpl.Transaction{TransactionType: "AccountSet", Domain: &domain,
+			Meta: &xrpl.Meta{TransactionResult: "tecINSUFFICIENT_RESERVE"}}, false},
+		{"no meta", &xrpl.Transaction{TransactionType: "AccountSet", Domain: &domain}, false},
+		{"wrong type", &xrpl.Transaction{TransactionType: "Payment", Domain: &domain,
+			Meta: &xrpl.Meta{TransactionResult: "tesSUCCESS"}}, false},
+	}
+	for _, tc := range cases {
+		if got := DomainChanged(tc.tx); got != tc.want {
+			t.Errorf("%s: DomainChanged = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
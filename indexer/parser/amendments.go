@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// knownAmendments are the amendment names this build can label. The
+// amendment id is SHA-512Half of the name, so the hash table is computed
+// rather than maintained by hand; unknown ids simply record with an empty
+// name.
+var knownAmendments = []string{
+	"AMM",
+	"Checks",
+	"Clawback",
+	"DID",
+	"DeletableAccounts",
+	"DepositAuth",
+	"DepositPreauth",
+	"DisallowIncoming",
+	"ExpandedSignerList",
+	"FlowCross",
+	"ImmediateOfferKilled",
+	"MultiSignReserve",
+	"NegativeUNL",
+	"NonFungibleTokensV1_1",
+	"PayChan",
+	"RequireFullyCanonicalSig",
+	"TicketBatch",
+	"XRPFees",
+	"fixAMMOverflowOffer",
+	"fixNFTokenRemint",
+	"fixUniversalNumber",
+}
+
+var amendmentNames = func() map[string]string {
+	names := make(map[string]string, len(knownAmendments))
+	for _, name := range knownAmendments {
+		sum := sha512.Sum512([]byte(name))
+		names[strings.ToUpper(hex.EncodeToString(sum[:32]))] = name
+	}
+	return names
+}()
+
+// AmendmentName returns the known name for an amendment id, or "".
+func AmendmentName(hash string) string {
+	return amendmentNames[strings.ToUpper(hash)]
+}
+
+// EnableAmendment flag bits: a flagged pseudo-transaction only tracks
+// majority status; only the unflagged form actually enables.
+const (
+	tfGotMajority  = 0x00010000
+	tfLostMajority = 0x00020000
+)
+
+// ParseEnableAmendment extracts an activation from an EnableAmendment
+// pseudo-transaction. Majority-tracking variants return nil: the
+// amendment is not active yet.
+func ParseEnableAmendment(tx *xrpl.Transaction, ledgerIndex uint64) (*model.Amendment, error) {
+	if tx.TransactionType != "EnableAmendment" {
+		return nil, fmt.Errorf("parser: not an EnableAmendment: %s", tx.TransactionType)
+	}
+	if tx.Meta != nil && tx.Meta.TransactionResult != tesSUCCESS {
+		return nil, nil
+	}
+	if tx.Flags&(tfGotMajority|tfLostMajority) != 0 || tx.Amendment == "" {
+		return nil, nil
+	}
+	return &model.Amendment{
+		Hash:        strings.ToUpper(tx.Amendment),
+		Name:        AmendmentName(tx.Amendment),
+		LedgerIndex: ledgerIndex,
+		TxHash:      tx.Hash,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func amendmentID(name string) string {
+	sum := sha512.Sum512([]byte(name))
+	return strings.ToUpper(hex.EncodeToString(sum[:32]))
+}
+
+func TestAmendmentNameLookup(t *testing.T) {
+	if got := AmendmentName(amendmentID("AMM")); got != "AMM" {
+		t.Errorf("AMM id resolved to %q", got)
+	}
+	// Lookup is case-insensitive on the hash.
+	if got := AmendmentName(strings.ToLower(amendmentID("Clawback"))); got != "Clawback" {
+		t.Errorf("lowercased Clawback id resolved to %q", got)
+	}
+	if got := AmendmentName("00FF"); got != "" {
+		t.Errorf("unknown id resolved to %q", got)
+	}
+}
+
+func TestParseEnableAmendment(t *testing.T) {
+	tx := &xrpl.Transaction{
+		Hash:            "FEED00000000000000000000000000000000000000000000000000000000BEEF",
+		TransactionType: "EnableAmendment",
+		Amendment:       amendmentID("DID"),
+		Meta:            &xrpl.Meta{TransactionResult: "tesSUCCESS"},
+	}
+	a, err := ParseEnableAmendment(tx, 90000060)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == nil {
+		t.Fatal("expected an activation")
+	}
+	if a.Name != "DID" || a.Hash != amendmentID("DID") || a.LedgerIndex != 90000060 {
+		t.Errorf("amendment = %+v", a)
+	}
+
+	// Majority-tracking variants are not activations.
+	tx.Flags = tfGotMajority
+	if a, err = ParseEnableAmendment(tx, 90000061); err != nil || a != nil {
+		t.Errorf("tfGotMajority parsed as activation: %+v, %v", a, err)
+	}
+	tx.Flags = tfLostMajority
+	if a, err = ParseEnableAmendment(tx, 90000062); err != nil || a != nil {
+		t.Errorf("tfLostMajority parsed as activation: %+v, %v", a, err)
+	}
+}
@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// ParseAMMCreate extracts a new pool from an AMMCreate: the AMM account,
+// trading fee and LP token currency come off the created AMM ledger
+// entry, the initial reserves from the transaction's two deposits. Nil is
+// returned when the transaction failed or created no AMM entry.
+func ParseAMMCreate(tx *xrpl.Transaction, ledgerIndex uint64) (*model.Pool, error) {
+	if tx.TransactionType != "AMMCreate" {
+		return nil, fmt.Errorf("parser: not an AMMCreate: %s", tx.TransactionType)
+	}
+	if tx.Meta == nil || tx.Meta.TransactionResult != tesSUCCESS {
+		return nil, nil
+	}
+	for _, an := range tx.Meta.AffectedNodes {
+		node, kind := an.Node()
+		if node == nil || kind != xrpl.NodeCreated || node.LedgerEntryType != "AMM" {
+			continue
+		}
+		var fields xrpl.AMMFields
+		if err := unmarshalFields(node.NewFields, &fields); err != nil {
+			return nil, err
+		}
+		return &model.Pool{
+			Account:         fields.Account,
+			Asset1:          tx.Amount,
+			Asset2:          tx.Amount2,
+			TradingFee:      fields.TradingFee,
+			CreatedLedger:   ledgerIndex,
+			LPTokenCurrency: fields.LPTokenBalance.Currency,
+			LedgerIndex:     ledgerIndex,
+		}, nil
+	}
+	return nil, nil
+}
+
+// ParseAMMVote extracts the effective trading fee after a vote. The fee on
+// an AMM is the vote-weighted outcome across LP token holders, so a vote
+// only sometimes moves it; nil is returned when the metadata shows no fee
+// change.
+func ParseAMMVote(tx *xrpl.Transaction, ledgerIndex uint64) (*model.FeeChange, error) {
+	if tx.TransactionType != "AMMVote" {
+		return nil, fmt.Errorf("parser: not an AMMVote: %s", tx.TransactionType)
+	}
+	if tx.Meta == nil || tx.Meta.TransactionResult != tesSUCCESS {
+		return nil, nil
+	}
+	for _, an := range tx.Meta.AffectedNodes {
+		node, kind := an.Node()
+		if node == nil || kind != xrpl.NodeModified || node.LedgerEntryType != "AMM" {
+			continue
+		}
+		var final, prev xrpl.AMMFields
+		if err := unmarshalFields(node.FinalFields, &final); err != nil {
+			return nil, err
+		}
+		if err := unmarshalFields(node.PreviousFields, &prev); err != nil {
+			return nil, err
+		}
+		// PreviousFields only carries TradingFee when it changed; an
+		// unchanged fee means the vote didn't tip the weighted average.
+		if len(node.PreviousFields) == 0 || final.TradingFee == prev.TradingFee {
+			continue
+		}
+		return &model.FeeChange{
+			PoolAccount: final.Account,
+			TradingFee:  final.TradingFee,
+			LedgerIndex: ledgerIndex,
+			TxHash:      tx.Hash,
+		}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+func TestParseAMMCreate(t *testing.T) {
+	tx := loadTxFixture(t, "amm_create.json")
+	pool, err := ParseAMMCreate(tx, 90000030)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool == nil {
+		t.Fatal("expected a pool, the metadata created an AMM entry")
+	}
+	if pool.Account != "rAMMPoolAccount2222222222222222222" {
+		t.Errorf("pool account = %s", pool.Account)
+	}
+	if pool.Asset1.Currency != "XRP" || pool.Asset1.Value != "250" {
+		t.Errorf("asset1 = %+v, want 250 XRP", pool.Asset1)
+	}
+	if pool.Asset2.Currency != "USD" || pool.Asset2.Issuer != "rIssuerUSD111111111111111111111111" || pool.Asset2.Value != "500" {
+		t.Errorf("asset2 = %+v, want 500 USD", pool.Asset2)
+	}
+	if pool.TradingFee != 500 {
+		t.Errorf("trading fee = %d, want 500", pool.TradingFee)
+	}
+	if pool.LPTokenCurrency != "03AB1C2D3E4F5A6B7C8D9E0F1A2B3C4D5E6F7A8B" {
+		t.Errorf("lp token currency = %s", pool.LPTokenCurrency)
+	}
+	if pool.CreatedLedger != 90000030 || pool.LedgerIndex != 90000030 {
+		t.Errorf("provenance = %d/%d", pool.CreatedLedger, pool.LedgerIndex)
+	}
+}
+
+func TestParseAMMCreateFailed(t *testing.T) {
+	// A failed AMMCreate leaves no AMM entry behind; there is no pool.
+	tx := loadTxFixture(t, "amm_create.json")
+	tx.Meta.TransactionResult = "tecDUPLICATE"
+	pool, err := ParseAMMCreate(tx, 90000031)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool != nil {
+		t.Errorf("unexpected pool %+v", pool)
+	}
+}
+
+func TestParseAMMVoteFeeChange(t *testing.T) {
+	tx := loadTxFixture(t, "amm_vote.json")
+	fc, err := ParseAMMVote(tx, 90000020)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc == nil {
+		t.Fatal("expected a fee change, the metadata moved TradingFee 500 -> 600")
+	}
+	if fc.PoolAccount != "rAMMPoolAccount1111111111111111111" {
+		t.Errorf("pool account = %s", fc.PoolAccount)
+	}
+	if fc.TradingFee != 600 {
+		t.Errorf("trading fee = %d, want 600", fc.TradingFee)
+	}
+	if fc.LedgerIndex != 90000020 || fc.TxHash != tx.Hash {
+		t.Errorf("provenance = %d/%s", fc.LedgerIndex, fc.TxHash)
+	}
+}
+
+func TestParseAMMVoteNoFeeMovement(t *testing.T) {
+	// A vote that doesn't tip the weighted average leaves TradingFee out
+	// of PreviousFields; there is nothing to record.
+	tx := loadTxFixture(t, "amm_vote.json")
+	tx.Meta.AffectedNodes[0].Modified.PreviousFields = nil
+	fc, err := ParseAMMVote(tx, 90000021)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc != nil {
+		t.Errorf("unexpected fee change %+v", fc)
+	}
+}
+
+func TestFeeConversions(t *testing.T) {
+	cases := []struct {
+		fee     uint32
+		bp, pct string
+	}{
+		{0, "0", "0"},
+		{5, "0.5", "0.005"},
+		{500, "50", "0.5"},
+		{600, "60", "0.6"},
+		{1000, "100", "1"},
+	}
+	for _, c := range cases {
+		if got := model.FeeBasisPoints(c.fee); got != c.bp {
+			t.Errorf("FeeBasisPoints(%d) = %s, want %s", c.fee, got, c.bp)
+		}
+		if got := model.FeePercent(c.fee); got != c.pct {
+			t.Errorf("FeePercent(%d) = %s, want %s", c.fee, got, c.pct)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// Clawback is a parsed XLS-39 Clawback transaction. Amount is what was
+// actually clawed according to metadata, which can be less than the amount
+// stated in the transaction when the holder's balance was smaller.
+type Clawback struct {
+	Issuer string
+	Holder string
+	Amount xrpl.Amount
+
+	LedgerIndex uint64
+	TxHash      string
+}
+
+// ParseClawback extracts a Clawback and, when the holder is an AMM
+// account, the reserve adjustment the pool needs. isPool reports whether an
+// account is a known AMM account; the store supplies it.
+func ParseClawback(tx *xrpl.Transaction, ledgerIndex uint64, isPool func(account string) bool) (*Clawback, *model.PoolAdjustment, error) {
+	if tx.TransactionType != "Clawback" {
+		return nil, nil, fmt.Errorf("parser: not a Clawback: %s", tx.TransactionType)
+	}
+	if tx.Meta == nil || tx.Meta.TransactionResult != tesSUCCESS {
+		return nil, nil, nil
+	}
+
+	// In a Clawback the Amount's issuer field names the holder being
+	// clawed from, not the token issuer (that is the sending account).
+	cb := &Clawback{
+		Issuer: tx.Account,
+		Holder: tx.Amount.Issuer,
+		Amount: xrpl.Amount{
+			Currency: tx.Amount.Currency,
+			Issuer:   tx.Account,
+			Value:    tx.Amount.Value,
+		},
+		LedgerIndex: ledgerIndex,
+		TxHash:      tx.Hash,
+	}
+
+	if clawed := clawedFromMeta(tx.Meta, cb.Issuer, cb.Holder); clawed != nil {
+		cb.Amount.Value = model.RatDecimal(clawed)
+	}
+
+	if isPool == nil || !isPool(cb.Holder) {
+		return cb, nil, nil
+	}
+	v, err := cb.Amount.Rat()
+	if err != nil {
+		return nil, nil, err
+	}
+	adj := &model.PoolAdjustment{
+		Account:     cb.Holder,
+		Currency:    cb.Amount.Currency,
+		Issuer:      cb.Issuer,
+		Delta:       model.RatDecimal(new(big.Rat).Neg(v)),
+		LedgerIndex: ledgerIndex,
+		TxHash:      tx.Hash,
+	}
+	return cb, adj, nil
+}
+
+// clawedFromMeta finds the trust line between issuer and holder in the
+// metadata and returns the absolute balance change, or nil if no such line
+// changed.
+func clawedFromMeta(meta *xrpl.Meta, issuer, holder string) *big.Rat {
+	for _, an := range meta.AffectedNodes {
+		node, kind := an.Node()
+		if node == nil || kind != xrpl.NodeModified || node.LedgerEntryType != "RippleState" {
+			continue
+		}
+		var final, prev xrpl.RippleStateFields
+		if err := unmarshalFields(node.FinalFields, &final); err != nil {
+			continue
+		}
+		if err := unmarshalFields(node.PreviousFields, &prev); err != nil {
+			continue
+		}
+		if prev.Balance.Value == "" {
+			continue
+		}
+		low, high := final.LowLimit.Issuer, final.HighLimit.Issuer
+		if !(low == issuer && high == holder) && !(low == holder && high == issuer) {
+			continue
+		}
+		f, err1 := final.Balance.Rat()
+		p, err2 := prev.Balance.Rat()
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		d := new(big.Rat).Sub(p, f)
+		return d.Abs(d)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package parser
+
+import "testing"
+
+func TestParseClawbackFromAMM(t *testing.T) {
+	tx := loadTxFixture(t, "clawback_from_amm.json")
+	isPool := func(account string) bool {
+		return account == "rAMMPoolAccount1111111111111111111"
+	}
+	cb, adj, err := ParseClawback(tx, 90000010, isPool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cb.Issuer != "rIssuerUSD111111111111111111111111" {
+		t.Errorf("issuer = %s", cb.Issuer)
+	}
+	if cb.Holder != "rAMMPoolAccount1111111111111111111" {
+		t.Errorf("holder = %s", cb.Holder)
+	}
+	// The transaction asked for 150 but the metadata shows only 100
+	// actually moved off the trust line.
+	if cb.Amount.Value != "100" || cb.Amount.Currency != "USD" {
+		t.Errorf("clawed amount = %v, want 100 USD", cb.Amount)
+	}
+	if adj == nil {
+		t.Fatal("expected a pool adjustment for an AMM holder")
+	}
+	if adj.Account != cb.Holder || adj.Delta != "-100" {
+		t.Errorf("adjustment = %+v, want -100 on the pool account", adj)
+	}
+}
+
+func TestParseClawbackNonPoolHolder(t *testing.T) {
+	tx := loadTxFixture(t, "clawback_from_amm.json")
+	cb, adj, err := ParseClawback(tx, 90000010, func(string) bool { return false })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cb == nil {
+		t.Fatal("clawback itself should still parse")
+	}
+	if adj != nil {
+		t.Errorf("unexpected pool adjustment %+v for a plain holder", adj)
+	}
+}
+
+func TestParseAccountSetGlobalFreezeToggle(t *testing.T) {
+	tx := loadTxFixture(t, "account_set_global_freeze.json")
+	change, err := ParseAccountSet(tx, 90000011)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change == nil {
+		t.Fatal("expected a flag change")
+	}
+	if !change.Set || change.Flag != "global_freeze" || change.Issuer != tx.Account {
+		t.Errorf("change = %+v", change)
+	}
+	if change.Currency != "" {
+		t.Errorf("global freeze should apply issuer-wide, got currency %q", change.Currency)
+	}
+
+	// The same transaction with ClearFlag instead of SetFlag unfreezes.
+	tx.SetFlag, tx.ClearFlag = 0, 7
+	change, err = ParseAccountSet(tx, 90000012)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change == nil || change.Set {
+		t.Fatalf("clear toggle = %+v, want Set=false", change)
+	}
+}
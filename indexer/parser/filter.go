@@ -0,0 +1,164 @@
+package parser
+
+import (
+	"sync"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// IssuerFilter restricts indexing to configured issuers. XRP always
+// passes; an IOU passes when its issuer is not denied and, if an
+// allowlist is set, listed on it. A watch list adds the opposite
+// quantifier: where the allowlist demands every IOU leg be listed, the
+// watch list keeps a write when any leg involves a watched issuer —
+// XRP-paired markets of a watched token included — and drops writes
+// that involve none. The filter is safe to Update while ledgers are
+// being processed, so a SIGHUP config reload can widen or narrow it
+// without a restart.
+type IssuerFilter struct {
+	mu    sync.RWMutex
+	allow map[string]bool // empty means every issuer is allowed
+	deny  map[string]bool
+	watch map[string]bool // empty means nothing is required
+}
+
+// NewIssuerFilter builds a filter from allow, deny and watch issuer
+// lists; all empty yields a filter that passes everything.
+func NewIssuerFilter(allow, deny, watch []string) *IssuerFilter {
+	f := &IssuerFilter{}
+	f.Update(allow, deny, watch)
+	return f
+}
+
+// Update atomically replaces all three lists.
+func (f *IssuerFilter) Update(allow, deny, watch []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.allow = toSet(allow)
+	f.deny = toSet(deny)
+	f.watch = toSet(watch)
+}
+
+func toSet(issuers []string) map[string]bool {
+	set := make(map[string]bool, len(issuers))
+	for _, issuer := range issuers {
+		if issuer != "" {
+			set[issuer] = true
+		}
+	}
+	return set
+}
+
+// Blocked returns the issuer the filter rejects the amounts for, or ""
+// when they pass. Per-leg rules reject on the offending leg's issuer;
+// the watch rule, which looks at the set as a whole, reports the first
+// IOU issuer when no leg is watched. Amounts that are all XRP carry no
+// issuer to judge and always pass.
+func (f *IssuerFilter) Blocked(amounts ...xrpl.Amount) string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	firstIOU, watched := "", false
+	for _, a := range amounts {
+		// No issuer means native XRP; only issued currencies are filtered.
+		if a.Issuer == "" {
+			continue
+		}
+		if f.deny[a.Issuer] || (len(f.allow) > 0 && !f.allow[a.Issuer]) {
+			return a.Issuer
+		}
+		if firstIOU == "" {
+			firstIOU = a.Issuer
+		}
+		if f.watch[a.Issuer] {
+			watched = true
+		}
+	}
+	if len(f.watch) > 0 && firstIOU != "" && !watched {
+		return firstIOU
+	}
+	return ""
+}
+
+// filteredSink drops writes whose assets the filter rejects before they
+// reach the wrapped sink, counting each drop by issuer. Cancels and
+// replacements pass through untouched: for a filtered offer the store
+// never saw, they are no-ops there, which is exactly the silent skip
+// OfferCancel of a filtered offer should be.
+type filteredSink struct {
+	Sink
+	f          *IssuerFilter
+	onFiltered func(issuer string)
+}
+
+// NewFilteredSink wraps sink with f; onFiltered, if non-nil, is called
+// with the blocking issuer for every dropped write.
+func NewFilteredSink(sink Sink, f *IssuerFilter, onFiltered func(issuer string)) Sink {
+	return &filteredSink{Sink: sink, f: f, onFiltered: onFiltered}
+}
+
+func (s *filteredSink) drop(issuer string) bool {
+	if issuer == "" {
+		return false
+	}
+	if s.onFiltered != nil {
+		s.onFiltered(issuer)
+	}
+	return true
+}
+
+func (s *filteredSink) UpsertOffer(o *model.Offer) error {
+	if s.drop(s.f.Blocked(o.TakerGets, o.TakerPays)) {
+		return nil
+	}
+	return s.Sink.UpsertOffer(o)
+}
+
+func (s *filteredSink) InsertTrade(t *model.Trade) error {
+	if s.drop(s.f.Blocked(t.Gets, t.Pays)) {
+		return nil
+	}
+	return s.Sink.InsertTrade(t)
+}
+
+func (s *filteredSink) UpdateOfferFill(t *model.Trade, closeTime int64) error {
+	if s.drop(s.f.Blocked(t.Gets, t.Pays)) {
+		return nil
+	}
+	return s.Sink.UpdateOfferFill(t, closeTime)
+}
+
+func (s *filteredSink) UpsertNFTOffer(o *model.NFTOffer) error {
+	if s.drop(s.f.Blocked(o.Amount)) {
+		return nil
+	}
+	return s.Sink.UpsertNFTOffer(o)
+}
+
+func (s *filteredSink) InsertNFTTrade(t *model.NFTTrade) error {
+	if s.drop(s.f.Blocked(t.Amount, t.BrokerFee)) {
+		return nil
+	}
+	return s.Sink.InsertNFTTrade(t)
+}
+
+func (s *filteredSink) UpsertTrustLine(l *model.TrustLine) error {
+	if s.drop(s.f.Blocked(xrpl.Amount{Currency: l.Currency, Issuer: l.Issuer, Value: "0"})) {
+		return nil
+	}
+	return s.Sink.UpsertTrustLine(l)
+}
+
+func (s *filteredSink) UpsertPool(p *model.Pool) error {
+	if s.drop(s.f.Blocked(p.Asset1, p.Asset2)) {
+		return nil
+	}
+	return s.Sink.UpsertPool(p)
+}
+
+func (s *filteredSink) AdjustPoolReserve(adj *model.PoolAdjustment) error {
+	if s.drop(s.f.Blocked(xrpl.Amount{Currency: adj.Currency, Issuer: adj.Issuer, Value: "0"})) {
+		return nil
+	}
+	return s.Sink.AdjustPoolReserve(adj)
+}
@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func TestIssuerFilterBlocked(t *testing.T) {
+	xrp := xrpl.Amount{Currency: "XRP", Value: "1"}
+	usd := xrpl.Amount{Currency: "USD", Issuer: "rGood", Value: "1"}
+	spam := xrpl.Amount{Currency: "404F4F4E", Issuer: "rSpam", Value: "1"}
+
+	empty := NewIssuerFilter(nil, nil, nil)
+	if got := empty.Blocked(xrp, usd, spam); got != "" {
+		t.Errorf("empty filter blocked %q", got)
+	}
+
+	deny := NewIssuerFilter(nil, []string{"rSpam"}, nil)
+	if got := deny.Blocked(xrp, usd); got != "" {
+		t.Errorf("denylist blocked %q", got)
+	}
+	if got := deny.Blocked(usd, spam); got != "rSpam" {
+		t.Errorf("denylist passed rSpam, blocked %q", got)
+	}
+
+	allow := NewIssuerFilter([]string{"rGood"}, nil, nil)
+	if got := allow.Blocked(xrp, usd); got != "" {
+		t.Errorf("allowlisted pair blocked %q", got)
+	}
+	if got := allow.Blocked(spam); got != "rSpam" {
+		t.Errorf("non-allowed issuer passed, blocked %q", got)
+	}
+
+	// Update swaps the lists without a new filter.
+	allow.Update([]string{"rGood", "rSpam"}, nil, nil)
+	if got := allow.Blocked(spam); got != "" {
+		t.Errorf("widened filter still blocks %q", got)
+	}
+}
+
+func TestIssuerFilterWatchList(t *testing.T) {
+	xrp := xrpl.Amount{Currency: "XRP", Value: "1"}
+	watched := xrpl.Amount{Currency: "USD", Issuer: "rWatched", Value: "1"}
+	other := xrpl.Amount{Currency: "EUR", Issuer: "rOther", Value: "1"}
+	another := xrpl.Amount{Currency: "GBP", Issuer: "rAnother", Value: "1"}
+
+	f := NewIssuerFilter(nil, nil, []string{"rWatched"})
+	// A watched issuer's markets pass, XRP-paired ones included, and one
+	// watched leg is enough.
+	if got := f.Blocked(watched, xrp); got != "" {
+		t.Errorf("watched/XRP blocked %q", got)
+	}
+	if got := f.Blocked(watched, other); got != "" {
+		t.Errorf("watched/other blocked %q", got)
+	}
+	// A market between two unwatched issuers is skipped, reported on its
+	// first IOU leg.
+	if got := f.Blocked(other, another); got != "rOther" {
+		t.Errorf("unwatched pair blocked %q, want rOther", got)
+	}
+	if got := f.Blocked(xrp, other); got != "rOther" {
+		t.Errorf("unwatched/XRP blocked %q, want rOther", got)
+	}
+	// All-XRP amounts carry no issuer to judge and pass.
+	if got := f.Blocked(xrp); got != "" {
+		t.Errorf("pure XRP blocked %q", got)
+	}
+	// The deny list still wins over a watched issuer.
+	f.Update(nil, []string{"rWatched"}, []string{"rWatched"})
+	if got := f.Blocked(watched, xrp); got != "rWatched" {
+		t.Errorf("denied watched issuer blocked %q, want rWatched", got)
+	}
+}
+
+func TestFilteredSinkDropsDeniedWrites(t *testing.T) {
+	inner := &recordingSink{}
+	var dropped []string
+	sink := NewFilteredSink(inner, NewIssuerFilter(nil, []string{"rSpam"}, nil),
+		func(issuer string) { dropped = append(dropped, issuer) })
+
+	good := &model.Offer{
+		TakerGets: xrpl.Amount{Currency: "XRP", Value: "1"},
+		TakerPays: xrpl.Amount{Currency: "USD", Issuer: "rGood", Value: "2"},
+	}
+	bad := &model.Offer{
+		TakerGets: xrpl.Amount{Currency: "XRP", Value: "1"},
+		TakerPays: xrpl.Amount{Currency: "XXX", Issuer: "rSpam", Value: "2"},
+	}
+	if err := sink.UpsertOffer(good); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.UpsertOffer(bad); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.offers) != 1 || inner.offers[0] != good {
+		t.Fatalf("stored offers = %+v, want only the allowed one", inner.offers)
+	}
+	if len(dropped) != 1 || dropped[0] != "rSpam" {
+		t.Fatalf("dropped = %v", dropped)
+	}
+
+	// Cancels pass through; the store silently no-ops for offers it
+	// never indexed.
+	if err := sink.CancelOffer("rAnyone", 7, 1, 0, "CANCELTX"); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.cancels) != 1 {
+		t.Fatalf("cancels = %v", inner.cancels)
+	}
+}
@@ -0,0 +1,283 @@
+package parser
+
+import (
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// DefaultHandlers returns the standard handler set in dispatch order.
+func DefaultHandlers() []Handler {
+	return []Handler{
+		offerCreateHandler{},
+		offerCancelHandler{},
+		nftOfferCreateHandler{},
+		nftOfferAcceptHandler{},
+		nftOfferCancelHandler{},
+		trustSetHandler{},
+		trustLineSweepHandler{},
+		clawbackHandler{},
+		ammCreateHandler{},
+		ammVoteHandler{},
+		enableAmendmentHandler{},
+		accountSetHandler{},
+	}
+}
+
+type offerCreateHandler struct{}
+
+func (offerCreateHandler) Name() string               { return "offer_create" }
+func (offerCreateHandler) Matches(txType string) bool { return txType == "OfferCreate" }
+
+func (offerCreateHandler) Handle(tx *xrpl.Transaction, lg *xrpl.Ledger, sink Sink) error {
+	offer, fills, err := ParseOfferCreate(tx, lg.Index)
+	if err != nil {
+		// Store the failure instead of failing the ledger: the raw
+		// transaction is already persisted, so a fixed parser can replay
+		// it, and GetInvalidOffers surfaces the row for triage.
+		return sink.UpsertOffer(invalidOffer(tx, lg, err))
+	}
+	if offer == nil {
+		return nil
+	}
+	if offer.ReplacesSequence != 0 {
+		if err := sink.MarkOfferReplaced(offer.Account, offer.ReplacesSequence, offer.Sequence, lg.Index, lg.CloseTime, tx.Hash); err != nil {
+			return err
+		}
+	}
+	offer.CloseTime = lg.CloseTime
+	if err := sink.UpsertOffer(offer); err != nil {
+		return err
+	}
+	for i := range fills {
+		if err := sink.InsertTrade(&fills[i]); err != nil {
+			return err
+		}
+		if err := sink.UpdateOfferFill(&fills[i], lg.CloseTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// invalidOffer builds the invalid_parse row for a transaction the parser
+// rejected, keeping whatever amounts the transaction itself carried.
+func invalidOffer(tx *xrpl.Transaction, lg *xrpl.Ledger, cause error) *model.Offer {
+	offer := &model.Offer{
+		Account:       tx.Account,
+		Sequence:      tx.Sequence,
+		Flags:         tx.Flags,
+		TakerGets:     tx.TakerGets,
+		TakerPays:     tx.TakerPays,
+		Status:        model.OfferStatusInvalidParse,
+		LedgerIndex:   lg.Index,
+		CloseTime:     lg.CloseTime,
+		TxHash:        tx.Hash,
+		ParserVersion: Version,
+		ParseError:    cause.Error(),
+	}
+	if offer.TakerGets.Value == "" {
+		offer.TakerGets.Value = "0"
+	}
+	if offer.TakerPays.Value == "" {
+		offer.TakerPays.Value = "0"
+	}
+	return offer
+}
+
+type offerCancelHandler struct{}
+
+func (offerCancelHandler) Name() string               { return "offer_cancel" }
+func (offerCancelHandler) Matches(txType string) bool { return txType == "OfferCancel" }
+
+func (offerCancelHandler) Handle(tx *xrpl.Transaction, lg *xrpl.Ledger, sink Sink) error {
+	if tx.Meta == nil || tx.Meta.TransactionResult != tesSUCCESS || tx.OfferSequence == 0 {
+		return nil
+	}
+	return sink.CancelOffer(tx.Account, tx.OfferSequence, lg.Index, lg.CloseTime, tx.Hash)
+}
+
+type nftOfferCreateHandler struct{}
+
+func (nftOfferCreateHandler) Name() string               { return "nft_offer_create" }
+func (nftOfferCreateHandler) Matches(txType string) bool { return txType == "NFTokenCreateOffer" }
+
+func (nftOfferCreateHandler) Handle(tx *xrpl.Transaction, lg *xrpl.Ledger, sink Sink) error {
+	offer, err := ParseNFTokenCreateOffer(tx, lg.Index)
+	if err != nil {
+		return err
+	}
+	if offer == nil {
+		return nil
+	}
+	offer.CloseTime = lg.CloseTime
+	return sink.UpsertNFTOffer(offer)
+}
+
+type nftOfferAcceptHandler struct{}
+
+func (nftOfferAcceptHandler) Name() string               { return "nft_offer_accept" }
+func (nftOfferAcceptHandler) Matches(txType string) bool { return txType == "NFTokenAcceptOffer" }
+
+func (nftOfferAcceptHandler) Handle(tx *xrpl.Transaction, lg *xrpl.Ledger, sink Sink) error {
+	trade, err := ParseNFTokenAcceptOffer(tx, lg.Index)
+	if err != nil {
+		return err
+	}
+	if trade == nil {
+		return nil
+	}
+	trade.CloseTime = lg.CloseTime
+	// A brokered accept consumes both offers; both must transition.
+	for _, index := range []string{trade.SellOfferIndex, trade.BuyOfferIndex} {
+		if index == "" {
+			continue
+		}
+		if err := sink.AcceptNFTOffer(index, lg.Index, lg.CloseTime, tx.Hash); err != nil {
+			return err
+		}
+	}
+	return sink.InsertNFTTrade(trade)
+}
+
+type nftOfferCancelHandler struct{}
+
+func (nftOfferCancelHandler) Name() string               { return "nft_offer_cancel" }
+func (nftOfferCancelHandler) Matches(txType string) bool { return txType == "NFTokenCancelOffer" }
+
+func (nftOfferCancelHandler) Handle(tx *xrpl.Transaction, lg *xrpl.Ledger, sink Sink) error {
+	cancelled, err := ParseNFTokenCancelOffer(tx)
+	if err != nil {
+		return err
+	}
+	for _, index := range cancelled {
+		if err := sink.CancelNFTOffer(index, lg.Index, lg.CloseTime, tx.Hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type trustSetHandler struct{}
+
+func (trustSetHandler) Name() string               { return "trust_set" }
+func (trustSetHandler) Matches(txType string) bool { return txType == "TrustSet" }
+
+func (trustSetHandler) Handle(tx *xrpl.Transaction, lg *xrpl.Ledger, sink Sink) error {
+	line, err := ParseTrustSet(tx, lg.Index)
+	if err != nil {
+		return err
+	}
+	if line == nil {
+		return nil
+	}
+	line.CloseTime = lg.CloseTime
+	return sink.UpsertTrustLine(line)
+}
+
+// trustLineSweepHandler matches every transaction type: trust lines are
+// deleted by payments and offer crossings too, not just TrustSet, and
+// holder counts go stale if those deletions slip past.
+type trustLineSweepHandler struct{}
+
+func (trustLineSweepHandler) Name() string        { return "trust_line_sweep" }
+func (trustLineSweepHandler) Matches(string) bool { return true }
+
+func (trustLineSweepHandler) Handle(tx *xrpl.Transaction, lg *xrpl.Ledger, sink Sink) error {
+	removals, err := ParseTrustLineRemovals(tx)
+	if err != nil {
+		return err
+	}
+	for _, rm := range removals {
+		if err := sink.DeleteTrustLine(rm.Account, rm.Currency, rm.Issuer, lg.Index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type clawbackHandler struct{}
+
+func (clawbackHandler) Name() string               { return "clawback" }
+func (clawbackHandler) Matches(txType string) bool { return txType == "Clawback" }
+
+func (clawbackHandler) Handle(tx *xrpl.Transaction, lg *xrpl.Ledger, sink Sink) error {
+	_, adj, err := ParseClawback(tx, lg.Index, sink.IsPoolAccount)
+	if err != nil {
+		return err
+	}
+	if adj == nil {
+		return nil
+	}
+	adj.CloseTime = lg.CloseTime
+	return sink.AdjustPoolReserve(adj)
+}
+
+type ammCreateHandler struct{}
+
+func (ammCreateHandler) Name() string               { return "amm_create" }
+func (ammCreateHandler) Matches(txType string) bool { return txType == "AMMCreate" }
+
+func (ammCreateHandler) Handle(tx *xrpl.Transaction, lg *xrpl.Ledger, sink Sink) error {
+	pool, err := ParseAMMCreate(tx, lg.Index)
+	if err != nil {
+		return err
+	}
+	if pool == nil {
+		return nil
+	}
+	return sink.UpsertPool(pool)
+}
+
+type ammVoteHandler struct{}
+
+func (ammVoteHandler) Name() string               { return "amm_vote" }
+func (ammVoteHandler) Matches(txType string) bool { return txType == "AMMVote" }
+
+func (ammVoteHandler) Handle(tx *xrpl.Transaction, lg *xrpl.Ledger, sink Sink) error {
+	fc, err := ParseAMMVote(tx, lg.Index)
+	if err != nil {
+		return err
+	}
+	if fc == nil {
+		return nil
+	}
+	fc.CloseTime = lg.CloseTime
+	return sink.ApplyFeeChange(fc)
+}
+
+type enableAmendmentHandler struct{}
+
+func (enableAmendmentHandler) Name() string               { return "enable_amendment" }
+func (enableAmendmentHandler) Matches(txType string) bool { return txType == "EnableAmendment" }
+
+func (enableAmendmentHandler) Handle(tx *xrpl.Transaction, lg *xrpl.Ledger, sink Sink) error {
+	amendment, err := ParseEnableAmendment(tx, lg.Index)
+	if err != nil {
+		return err
+	}
+	if amendment == nil {
+		return nil
+	}
+	return sink.UpsertAmendment(amendment)
+}
+
+type accountSetHandler struct{}
+
+func (accountSetHandler) Name() string               { return "account_set" }
+func (accountSetHandler) Matches(txType string) bool { return txType == "AccountSet" }
+
+func (accountSetHandler) Handle(tx *xrpl.Transaction, lg *xrpl.Ledger, sink Sink) error {
+	if DomainChanged(tx) {
+		if err := sink.InvalidateIdentity(tx.Account); err != nil {
+			return err
+		}
+	}
+	change, err := ParseAccountSet(tx, lg.Index)
+	if err != nil {
+		return err
+	}
+	if change == nil {
+		return nil
+	}
+	return sink.ApplyAssetFlag(change)
+}
@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// ParseNFTokenCreateOffer extracts the marketplace offer an
+// NFTokenCreateOffer placed. The offer's identity is the ledger index of
+// the created NFTokenOffer entry, which only the metadata knows.
+func ParseNFTokenCreateOffer(tx *xrpl.Transaction, ledgerIndex uint64) (*model.NFTOffer, error) {
+	if tx.TransactionType != "NFTokenCreateOffer" {
+		return nil, fmt.Errorf("parser: not an NFTokenCreateOffer: %s", tx.TransactionType)
+	}
+	if tx.Meta == nil || tx.Meta.TransactionResult != tesSUCCESS {
+		return nil, nil
+	}
+	for _, an := range tx.Meta.AffectedNodes {
+		node, kind := an.Node()
+		if node == nil || kind != xrpl.NodeCreated || node.LedgerEntryType != "NFTokenOffer" {
+			continue
+		}
+		return &model.NFTOffer{
+			OfferIndex:  node.LedgerIndex,
+			TokenID:     tx.NFTokenID,
+			Owner:       tx.Account,
+			Destination: tx.Destination,
+			Amount:      tx.Amount,
+			Sell:        tx.Flags&xrpl.TfSellNFToken != 0,
+			Expiration:  int64(tx.Expiration),
+			Status:      model.NFTOfferStatusOpen,
+			LedgerIndex: ledgerIndex,
+			TxHash:      tx.Hash,
+		}, nil
+	}
+	return nil, fmt.Errorf("parser: NFTokenCreateOffer created no NFTokenOffer entry")
+}
+
+// ParseNFTokenAcceptOffer reconstructs the sale an NFTokenAcceptOffer
+// completed. A direct accept consumes the one offer the transaction
+// names and the accepting account takes the other side; a brokered
+// accept names both a sell and a buy offer, the metadata deletes both,
+// and the sale settles at the buy offer's amount minus the broker's fee.
+func ParseNFTokenAcceptOffer(tx *xrpl.Transaction, ledgerIndex uint64) (*model.NFTTrade, error) {
+	if tx.TransactionType != "NFTokenAcceptOffer" {
+		return nil, fmt.Errorf("parser: not an NFTokenAcceptOffer: %s", tx.TransactionType)
+	}
+	if tx.Meta == nil || tx.Meta.TransactionResult != tesSUCCESS {
+		return nil, nil
+	}
+	deleted := map[string]xrpl.NFTokenOfferFields{}
+	for _, an := range tx.Meta.AffectedNodes {
+		node, kind := an.Node()
+		if node == nil || kind != xrpl.NodeDeleted || node.LedgerEntryType != "NFTokenOffer" {
+			continue
+		}
+		fields, err := decodeNFTokenOfferFields(node.FinalFields)
+		if err != nil {
+			return nil, err
+		}
+		deleted[node.LedgerIndex] = fields
+	}
+
+	trade := &model.NFTTrade{LedgerIndex: ledgerIndex, TxHash: tx.Hash}
+	if tx.NFTokenSellOffer != "" && tx.NFTokenBuyOffer != "" {
+		sell, okSell := deleted[tx.NFTokenSellOffer]
+		buy, okBuy := deleted[tx.NFTokenBuyOffer]
+		if !okSell || !okBuy {
+			return nil, fmt.Errorf("parser: brokered accept %s did not delete both offers", tx.Hash)
+		}
+		trade.TokenID = sell.NFTokenID
+		trade.Seller = sell.Owner
+		trade.Buyer = buy.Owner
+		trade.Amount = buy.Amount
+		trade.BrokerFee = tx.NFTokenBrokerFee
+		trade.SellOfferIndex = tx.NFTokenSellOffer
+		trade.BuyOfferIndex = tx.NFTokenBuyOffer
+		return trade, nil
+	}
+
+	index := tx.NFTokenSellOffer
+	if index == "" {
+		index = tx.NFTokenBuyOffer
+	}
+	fields, ok := deleted[index]
+	if !ok {
+		return nil, fmt.Errorf("parser: accept %s did not delete offer %s", tx.Hash, index)
+	}
+	trade.TokenID = fields.NFTokenID
+	trade.Amount = fields.Amount
+	if fields.Flags&xrpl.LsfSellNFToken != 0 {
+		trade.Seller = fields.Owner
+		trade.Buyer = tx.Account
+		trade.SellOfferIndex = index
+	} else {
+		trade.Seller = tx.Account
+		trade.Buyer = fields.Owner
+		trade.BuyOfferIndex = index
+	}
+	return trade, nil
+}
+
+// ParseNFTokenCancelOffer returns the ledger indexes of the offers an
+// NFTokenCancelOffer actually removed. The metadata is authoritative:
+// the transaction's NFTokenOffers list may name entries that were
+// already gone.
+func ParseNFTokenCancelOffer(tx *xrpl.Transaction) ([]string, error) {
+	if tx.TransactionType != "NFTokenCancelOffer" {
+		return nil, fmt.Errorf("parser: not an NFTokenCancelOffer: %s", tx.TransactionType)
+	}
+	if tx.Meta == nil || tx.Meta.TransactionResult != tesSUCCESS {
+		return nil, nil
+	}
+	var cancelled []string
+	for _, an := range tx.Meta.AffectedNodes {
+		node, kind := an.Node()
+		if node == nil || kind != xrpl.NodeDeleted || node.LedgerEntryType != "NFTokenOffer" {
+			continue
+		}
+		cancelled = append(cancelled, node.LedgerIndex)
+	}
+	return cancelled, nil
+}
+
+func decodeNFTokenOfferFields(raw []byte) (xrpl.NFTokenOfferFields, error) {
+	var fields xrpl.NFTokenOfferFields
+	if err := unmarshalFields(raw, &fields); err != nil {
+		return fields, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func TestParseNFTokenCreateOffer(t *testing.T) {
+	tx := loadTxFixture(t, "nft_create_offer_sell.json")
+	offer, err := ParseNFTokenCreateOffer(tx, 90000030)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offer.OfferIndex != "AA11BB22CC33DD44EE55FF6600112233445566778899AABBCCDDEEFF00112233" {
+		t.Errorf("offer index = %s, want the created NFTokenOffer entry's index", offer.OfferIndex)
+	}
+	if offer.TokenID != tx.NFTokenID {
+		t.Errorf("token id = %s", offer.TokenID)
+	}
+	if offer.Owner != "rNFTSeller111111111111111111111111" {
+		t.Errorf("owner = %s", offer.Owner)
+	}
+	if !offer.Sell {
+		t.Error("tfSellNFToken offer not marked as sell")
+	}
+	// The drops amount decodes to whole XRP.
+	if offer.Amount.Currency != "XRP" || offer.Amount.Value != "25" {
+		t.Errorf("amount = %v", offer.Amount)
+	}
+	if offer.Status != "open" {
+		t.Errorf("status = %q, want open", offer.Status)
+	}
+	if offer.LedgerIndex != 90000030 || offer.TxHash != tx.Hash {
+		t.Errorf("provenance = %d/%s", offer.LedgerIndex, offer.TxHash)
+	}
+}
+
+func TestParseNFTokenCreateOfferFailedTx(t *testing.T) {
+	tx := loadTxFixture(t, "nft_create_offer_sell.json")
+	tx.Meta.TransactionResult = "tecUNFUNDED_OFFER"
+	offer, err := ParseNFTokenCreateOffer(tx, 90000031)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offer != nil {
+		t.Errorf("failed transaction produced offer %+v", offer)
+	}
+}
+
+func TestParseNFTokenAcceptOfferBrokered(t *testing.T) {
+	tx := loadTxFixture(t, "nft_accept_brokered.json")
+	trade, err := ParseNFTokenAcceptOffer(tx, 90000032)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trade.Seller != "rNFTSeller111111111111111111111111" || trade.Buyer != "rNFTBuyer1111111111111111111111111" {
+		t.Errorf("seller/buyer = %s/%s", trade.Seller, trade.Buyer)
+	}
+	// The sale settles at the buy offer's amount, not the sell offer's ask.
+	if trade.Amount.Value != "100" {
+		t.Errorf("amount = %v, want the buy offer's 100", trade.Amount)
+	}
+	if trade.BrokerFee.Value != "5" {
+		t.Errorf("broker fee = %v", trade.BrokerFee)
+	}
+	if trade.SellOfferIndex != tx.NFTokenSellOffer || trade.BuyOfferIndex != tx.NFTokenBuyOffer {
+		t.Errorf("offer indexes = %s/%s", trade.SellOfferIndex, trade.BuyOfferIndex)
+	}
+}
+
+func TestNFTOfferAcceptHandlerTransitionsBothOffers(t *testing.T) {
+	tx := loadTxFixture(t, "nft_accept_brokered.json")
+	sink := &recordingSink{}
+	if err := (nftOfferAcceptHandler{}).Handle(tx, &xrpl.Ledger{Index: 90000033}, sink); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.nftAccepts) != 2 {
+		t.Fatalf("accepted %d offers, want both sides of the brokered accept", len(sink.nftAccepts))
+	}
+	if sink.nftAccepts[0] != tx.NFTokenSellOffer || sink.nftAccepts[1] != tx.NFTokenBuyOffer {
+		t.Errorf("accepted offers = %v", sink.nftAccepts)
+	}
+	if len(sink.nftTrades) != 1 {
+		t.Errorf("recorded %d trades, want 1", len(sink.nftTrades))
+	}
+}
+
+func TestParseNFTokenAcceptOfferDirect(t *testing.T) {
+	// Accepting only the sell offer: the accepting account is the buyer
+	// and the sale settles at the offer's asking price.
+	tx := loadTxFixture(t, "nft_accept_brokered.json")
+	tx.NFTokenBuyOffer = ""
+	tx.NFTokenBrokerFee = xrpl.Amount{}
+	tx.Account = "rNFTBuyer1111111111111111111111111"
+	trade, err := ParseNFTokenAcceptOffer(tx, 90000034)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trade.Seller != "rNFTSeller111111111111111111111111" || trade.Buyer != tx.Account {
+		t.Errorf("seller/buyer = %s/%s", trade.Seller, trade.Buyer)
+	}
+	if trade.Amount.Value != "95" {
+		t.Errorf("amount = %v, want the sell offer's 95", trade.Amount)
+	}
+	if trade.BrokerFee.Value != "" {
+		t.Errorf("direct accept carries broker fee %v", trade.BrokerFee)
+	}
+	if trade.SellOfferIndex != tx.NFTokenSellOffer || trade.BuyOfferIndex != "" {
+		t.Errorf("offer indexes = %s/%s", trade.SellOfferIndex, trade.BuyOfferIndex)
+	}
+}
+
+func TestParseNFTokenCancelOffer(t *testing.T) {
+	tx := loadTxFixture(t, "nft_cancel_offer.json")
+	cancelled, err := ParseNFTokenCancelOffer(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The transaction names three offers but the metadata only deleted
+	// two; the third was already gone.
+	want := []string{
+		"F40516273800112233445566778899AABBCCDDEEFF00112233445566778899AA",
+		"0516273800112233445566778899AABBCCDDEEFF00112233445566778899AABB",
+	}
+	if len(cancelled) != len(want) {
+		t.Fatalf("cancelled = %v, want %v", cancelled, want)
+	}
+	for i := range want {
+		if cancelled[i] != want[i] {
+			t.Errorf("cancelled[%d] = %s, want %s", i, cancelled[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Package parser turns validated XRPL transactions and their metadata into
+// the indexer's domain model.
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// tesSUCCESS is the only transaction result that changes DEX state.
+const tesSUCCESS = "tesSUCCESS"
+
+// ParseOfferCreate extracts the taker's offer and the fills it caused from
+// an OfferCreate transaction and its metadata.
+//
+// The returned Offer's LimitPrice is the quality the offer was placed at:
+// when the remainder rests on the book we decode it from the created Offer
+// entry's BookDirectory, because rippled preserves the original quality
+// there while the literal TakerGets/TakerPays fields shrink with each fill.
+// ExecutedPrice is the volume-weighted average over the metadata fills and
+// is independent of the limit; for tfSell offers the two routinely differ.
+func ParseOfferCreate(tx *xrpl.Transaction, ledgerIndex uint64) (*model.Offer, []model.Trade, error) {
+	if tx.TransactionType != "OfferCreate" {
+		return nil, nil, fmt.Errorf("parser: not an OfferCreate: %s", tx.TransactionType)
+	}
+	if tx.Meta == nil || tx.Meta.TransactionResult != tesSUCCESS {
+		return nil, nil, nil
+	}
+
+	offer := &model.Offer{
+		Account:       tx.Account,
+		Sequence:      tx.Sequence,
+		Flags:         tx.Flags,
+		TakerGets:     tx.TakerGets,
+		TakerPays:     tx.TakerPays,
+		LedgerIndex:   ledgerIndex,
+		TxHash:        tx.Hash,
+		Status:        model.OfferStatusFilled,
+		ParserVersion: Version,
+		// An OfferCreate naming an OfferSequence atomically cancels that
+		// prior offer while creating this one.
+		ReplacesSequence: tx.OfferSequence,
+	}
+	offer.LimitPrice = model.PriceString(model.LimitPriceRat(tx.TakerPays, tx.TakerGets))
+
+	var trades []model.Trade
+	// Totals from the taker's perspective: what the taker received is what
+	// the consumed makers gave, and vice versa.
+	received := new(big.Rat)
+	given := new(big.Rat)
+
+	for _, an := range tx.Meta.AffectedNodes {
+		node, kind := an.Node()
+		if node == nil || node.LedgerEntryType != "Offer" {
+			continue
+		}
+		switch kind {
+		case xrpl.NodeCreated:
+			fields, err := decodeOfferFields(node.NewFields)
+			if err != nil {
+				return nil, nil, err
+			}
+			if fields.Account != tx.Account {
+				continue
+			}
+			// The taker's remainder rests on the book.
+			offer.Status = model.OfferStatusOpen
+			if limit := limitFromBookDirectory(fields); limit != nil {
+				offer.LimitPrice = model.PriceString(limit)
+			}
+		case xrpl.NodeModified, xrpl.NodeDeleted:
+			trade, ok, err := fillFromNode(node, tx, ledgerIndex)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !ok {
+				continue
+			}
+			trades = append(trades, trade)
+			g, _ := trade.Gets.Rat()
+			p, _ := trade.Pays.Rat()
+			received.Add(received, g)
+			given.Add(given, p)
+		}
+	}
+
+	if given.Sign() != 0 {
+		offer.ExecutedPrice = model.RatDecimal(new(big.Rat).Quo(received, given))
+	} else if offer.Status == model.OfferStatusFilled {
+		// Nothing rested and nothing traded: IoC/FoK that did not
+		// execute leaves no offer behind.
+		if tx.Flags&(xrpl.TfImmediateOrCancel|xrpl.TfFillOrKill) != 0 {
+			offer.Status = model.OfferStatusCancelled
+		}
+	}
+	return offer, trades, nil
+}
+
+// fillFromNode reconstructs one fill from a modified or deleted maker
+// offer. The second return value is false when the node is not a fill
+// (the taker's own entry, or an unfunded offer swept without trading).
+func fillFromNode(node *xrpl.NodeData, tx *xrpl.Transaction, ledgerIndex uint64) (model.Trade, bool, error) {
+	final, err := decodeOfferFields(node.FinalFields)
+	if err != nil {
+		return model.Trade{}, false, err
+	}
+	if final.Account == tx.Account || len(node.PreviousFields) == 0 {
+		return model.Trade{}, false, nil
+	}
+	prev, err := decodeOfferFields(node.PreviousFields)
+	if err != nil {
+		return model.Trade{}, false, err
+	}
+	gets, err := amountDelta(prev.TakerGets, final.TakerGets)
+	if err != nil {
+		return model.Trade{}, false, err
+	}
+	pays, err := amountDelta(prev.TakerPays, final.TakerPays)
+	if err != nil {
+		return model.Trade{}, false, err
+	}
+	if gets == nil || pays == nil {
+		return model.Trade{}, false, nil
+	}
+	trade := model.Trade{
+		Maker:         final.Account,
+		Taker:         tx.Account,
+		OfferSequence: final.Sequence,
+		Gets:          *gets,
+		Pays:          *pays,
+		LedgerIndex:   ledgerIndex,
+		TxHash:        tx.Hash,
+		ParserVersion: Version,
+	}
+	g, _ := gets.Rat()
+	p, _ := pays.Rat()
+	if g.Sign() != 0 {
+		trade.Price = model.RatDecimal(new(big.Rat).Quo(p, g))
+	}
+	return trade, true, nil
+}
+
+// limitFromBookDirectory decodes the original placement quality from the
+// offer's BookDirectory, scaling drops to XRP for native legs. Returns nil
+// when the entry carries no directory.
+func limitFromBookDirectory(fields xrpl.OfferFields) *big.Rat {
+	if fields.BookDirectory == "" {
+		return nil
+	}
+	q, err := xrpl.QualityFromBookDirectory(fields.BookDirectory)
+	if err != nil {
+		return nil
+	}
+	drops := big.NewRat(1_000_000, 1)
+	if fields.TakerPays.IsXRP() {
+		q.Quo(q, drops)
+	}
+	if fields.TakerGets.IsXRP() {
+		q.Mul(q, drops)
+	}
+	return q
+}
+
+// amountDelta returns prev-final as an amount in prev's currency, or nil
+// if prev is unset (the field did not change).
+func amountDelta(prev, final xrpl.Amount) (*xrpl.Amount, error) {
+	if prev.Value == "" {
+		return nil, nil
+	}
+	p, err := prev.Rat()
+	if err != nil {
+		return nil, err
+	}
+	f := new(big.Rat)
+	if final.Value != "" {
+		if f, err = final.Rat(); err != nil {
+			return nil, err
+		}
+	}
+	d := new(big.Rat).Sub(p, f)
+	if d.Sign() <= 0 {
+		return nil, nil
+	}
+	return &xrpl.Amount{Currency: prev.Currency, Issuer: prev.Issuer, Value: model.RatDecimal(d)}, nil
+}
+
+func decodeOfferFields(raw json.RawMessage) (xrpl.OfferFields, error) {
+	var fields xrpl.OfferFields
+	if err := unmarshalFields(raw, &fields); err != nil {
+		return fields, err
+	}
+	return fields, nil
+}
+
+// unmarshalFields decodes a node field set, treating an absent set as
+// zero values.
+func unmarshalFields(raw json.RawMessage, out any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("parser: decoding node fields: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func loadTxFixture(t *testing.T, name string) *xrpl.Transaction {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+	var tx xrpl.Transaction
+	if err := json.Unmarshal(data, &tx); err != nil {
+		t.Fatalf("decoding fixture %s: %v", name, err)
+	}
+	tx.Raw = data
+	return &tx
+}
+
+func TestParseOfferCreatePartialFill(t *testing.T) {
+	tx := loadTxFixture(t, "offer_create_partial_fill.json")
+	offer, trades, err := ParseOfferCreate(tx, 90000001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offer.Status != "open" {
+		t.Errorf("status = %q, want open", offer.Status)
+	}
+	// The created node's literal fields give 80/42 ≈ 1.904, but the
+	// BookDirectory preserves the placement quality of exactly 2 USD/XRP.
+	if offer.LimitPrice != "2" {
+		t.Errorf("LimitPrice = %q, want 2 (from BookDirectory quality)", offer.LimitPrice)
+	}
+	// 20 USD received for 8 XRP given.
+	if offer.ExecutedPrice != "2.5" {
+		t.Errorf("ExecutedPrice = %q, want 2.5", offer.ExecutedPrice)
+	}
+	if len(trades) != 1 {
+		t.Fatalf("got %d trades, want 1", len(trades))
+	}
+	tr := trades[0]
+	if tr.Maker != "rMakerPartialFill11111111111111111" || tr.Taker != tx.Account {
+		t.Errorf("trade parties = %s/%s", tr.Maker, tr.Taker)
+	}
+	if tr.Gets.Value != "20" || tr.Gets.Currency != "USD" {
+		t.Errorf("trade gets = %v, want 20 USD", tr.Gets)
+	}
+	if tr.Pays.Value != "8" || !tr.Pays.IsXRP() {
+		t.Errorf("trade pays = %v, want 8 XRP", tr.Pays)
+	}
+	if tr.Price != "0.4" {
+		t.Errorf("trade price = %q, want 0.4", tr.Price)
+	}
+}
+
+func TestParseOfferCreateSellFullyFilled(t *testing.T) {
+	tx := loadTxFixture(t, "offer_create_sell_filled.json")
+	offer, trades, err := ParseOfferCreate(tx, 90000002)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !offer.Sell() {
+		t.Fatal("fixture should carry tfSell")
+	}
+	if offer.Status != "filled" {
+		t.Errorf("status = %q, want filled", offer.Status)
+	}
+	// Limit: 200 XRP asked for 100 USD sold.
+	if offer.LimitPrice != "2" {
+		t.Errorf("LimitPrice = %q, want 2", offer.LimitPrice)
+	}
+	// tfSell executed the full 100 USD at a better rate: 220 XRP
+	// received. A naive TakerPays/TakerGets display inverts this to
+	// ~0.4545; the executed price must come from the fills.
+	if offer.ExecutedPrice != "2.2" {
+		t.Errorf("ExecutedPrice = %q, want 2.2", offer.ExecutedPrice)
+	}
+	if len(trades) != 2 {
+		t.Fatalf("got %d trades, want 2", len(trades))
+	}
+	wantPrices := map[string]string{
+		"rMakerDeleted11111111111111111111": "0.41666666666666666667",
+		"rMakerModified1111111111111111111": "0.5",
+	}
+	for _, tr := range trades {
+		if want := wantPrices[tr.Maker]; tr.Price != want {
+			t.Errorf("maker %s price = %q, want %q", tr.Maker, tr.Price, want)
+		}
+	}
+}
+
+func TestParseOfferCreateWithOfferSequence(t *testing.T) {
+	tx := loadTxFixture(t, "offer_create_partial_fill.json")
+	tx.OfferSequence = 4990
+	offer, _, err := ParseOfferCreate(tx, 90000070)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offer.ReplacesSequence != 4990 {
+		t.Errorf("ReplacesSequence = %d, want 4990", offer.ReplacesSequence)
+	}
+
+	tx.OfferSequence = 0
+	if offer, _, err = ParseOfferCreate(tx, 90000071); err != nil {
+		t.Fatal(err)
+	}
+	if offer.ReplacesSequence != 0 {
+		t.Errorf("ReplacesSequence = %d without OfferSequence", offer.ReplacesSequence)
+	}
+}
+
+func TestParseOfferCreateFlagSemantics(t *testing.T) {
+	// A transaction whose metadata shows no fills and no created entry:
+	// with an execution-only flag the offer must come back cancelled, not
+	// resting.
+	base := func(flags uint32) *xrpl.Transaction {
+		return &xrpl.Transaction{
+			Hash:            "AB01000000000000000000000000000000000000000000000000000000000001",
+			TransactionType: "OfferCreate",
+			Account:         "rTakerFlagTest11111111111111111111",
+			Sequence:        42,
+			Flags:           flags,
+			TakerGets:       xrpl.Amount{Value: "10000000"},
+			TakerPays:       xrpl.Amount{Currency: "USD", Issuer: "rIssuerUSD111111111111111111111111", Value: "20"},
+			Meta:            &xrpl.Meta{TransactionResult: "tesSUCCESS"},
+		}
+	}
+	cases := []struct {
+		flags  uint32
+		status string
+		names  []string
+	}{
+		{xrpl.TfImmediateOrCancel, model.OfferStatusCancelled, []string{"immediate_or_cancel"}},
+		{xrpl.TfFillOrKill, model.OfferStatusCancelled, []string{"fill_or_kill"}},
+		{xrpl.TfSell | xrpl.TfImmediateOrCancel, model.OfferStatusCancelled, []string{"sell", "immediate_or_cancel"}},
+		{xrpl.TfPassive, model.OfferStatusFilled, []string{"passive"}},
+	}
+	for _, c := range cases {
+		offer, trades, err := ParseOfferCreate(base(c.flags), 90000072)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(trades) != 0 {
+			t.Errorf("flags %#x: unexpected trades", c.flags)
+		}
+		if offer.Status != c.status {
+			t.Errorf("flags %#x: status = %s, want %s", c.flags, offer.Status, c.status)
+		}
+		if !reflect.DeepEqual(offer.FlagNames(), c.names) {
+			t.Errorf("flags %#x: names = %v, want %v", c.flags, offer.FlagNames(), c.names)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// Sink is the slice of store writes transaction handlers need. The
+// pipeline passes its store (or a dry-run stand-in) through Dispatch.
+type Sink interface {
+	UpsertOffer(*model.Offer) error
+	CancelOffer(account string, sequence uint32, ledgerIndex uint64, closeTime int64, txHash string) error
+	MarkOfferReplaced(account string, sequence, replacedBy uint32, ledgerIndex uint64, closeTime int64, txHash string) error
+	InsertTrade(*model.Trade) error
+	UpdateOfferFill(*model.Trade, int64) error
+	UpsertNFTOffer(*model.NFTOffer) error
+	AcceptNFTOffer(offerIndex string, ledgerIndex uint64, closeTime int64, txHash string) error
+	CancelNFTOffer(offerIndex string, ledgerIndex uint64, closeTime int64, txHash string) error
+	InsertNFTTrade(*model.NFTTrade) error
+	UpsertTrustLine(*model.TrustLine) error
+	DeleteTrustLine(account, currency, issuer string, ledgerIndex uint64) error
+	UpsertPool(*model.Pool) error
+	AdjustPoolReserve(*model.PoolAdjustment) error
+	ApplyFeeChange(*model.FeeChange) error
+	ApplyAssetFlag(*model.AssetFlagChange) error
+	UpsertAmendment(*model.Amendment) error
+	InvalidateIdentity(account string) error
+	IsPoolAccount(string) bool
+}
+
+// Handler parses and persists one family of transactions. New transaction
+// types plug in by registering a Handler; the pipeline never needs to
+// learn about them.
+type Handler interface {
+	// Name labels the handler in metrics and error messages.
+	Name() string
+	// Matches reports whether the handler wants this transaction type.
+	Matches(txType string) bool
+	// Handle parses the transaction and writes the results to the sink.
+	Handle(tx *xrpl.Transaction, lg *xrpl.Ledger, sink Sink) error
+}
+
+// Outcomes reported to a registry observer.
+const (
+	OutcomeMatched   = "matched"
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+)
+
+// Registry dispatches transactions to every matching handler.
+type Registry struct {
+	handlers []Handler
+	observe  func(handler, outcome string)
+}
+
+// NewRegistry builds a registry over the given handlers.
+func NewRegistry(handlers ...Handler) *Registry {
+	return &Registry{handlers: handlers}
+}
+
+// Register appends a handler; later handlers see the transaction too, so
+// several can match the same type.
+func (r *Registry) Register(h Handler) { r.handlers = append(r.handlers, h) }
+
+// SetObserver installs a per-handler outcome callback for metrics.
+func (r *Registry) SetObserver(fn func(handler, outcome string)) { r.observe = fn }
+
+// Dispatch runs the transaction through every matching handler. A failing
+// handler does not stop the others; all failures come back joined into
+// one error.
+func (r *Registry) Dispatch(tx *xrpl.Transaction, lg *xrpl.Ledger, sink Sink) error {
+	var errs []error
+	for _, h := range r.handlers {
+		if !h.Matches(tx.TransactionType) {
+			continue
+		}
+		r.report(h, OutcomeMatched)
+		if err := h.Handle(tx, lg, sink); err != nil {
+			r.report(h, OutcomeFailed)
+			errs = append(errs, fmt.Errorf("parser: handler %s on tx %s: %w", h.Name(), tx.Hash, err))
+			continue
+		}
+		r.report(h, OutcomeSucceeded)
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Registry) report(h Handler, outcome string) {
+	if r.observe != nil {
+		r.observe(h.Name(), outcome)
+	}
+}
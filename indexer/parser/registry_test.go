@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// recordingSink implements Sink, remembering what handlers wrote.
+type recordingSink struct {
+	offers     []*model.Offer
+	cancels    []uint32
+	nftOffers  []*model.NFTOffer
+	nftAccepts []string
+	nftCancels []string
+	nftTrades  []*model.NFTTrade
+	trustLines []*model.TrustLine
+	trustDrops []model.TrustLineRemoval
+}
+
+func (r *recordingSink) UpsertOffer(o *model.Offer) error { r.offers = append(r.offers, o); return nil }
+func (r *recordingSink) CancelOffer(account string, sequence uint32, ledgerIndex uint64, closeTime int64, txHash string) error {
+	r.cancels = append(r.cancels, sequence)
+	return nil
+}
+func (r *recordingSink) MarkOfferReplaced(string, uint32, uint32, uint64, int64, string) error {
+	return nil
+}
+func (r *recordingSink) InsertTrade(*model.Trade) error { return nil }
+func (r *recordingSink) UpsertNFTOffer(o *model.NFTOffer) error {
+	r.nftOffers = append(r.nftOffers, o)
+	return nil
+}
+func (r *recordingSink) AcceptNFTOffer(offerIndex string, _ uint64, _ int64, _ string) error {
+	r.nftAccepts = append(r.nftAccepts, offerIndex)
+	return nil
+}
+func (r *recordingSink) CancelNFTOffer(offerIndex string, _ uint64, _ int64, _ string) error {
+	r.nftCancels = append(r.nftCancels, offerIndex)
+	return nil
+}
+func (r *recordingSink) InsertNFTTrade(t *model.NFTTrade) error {
+	r.nftTrades = append(r.nftTrades, t)
+	return nil
+}
+func (r *recordingSink) UpsertTrustLine(l *model.TrustLine) error {
+	r.trustLines = append(r.trustLines, l)
+	return nil
+}
+func (r *recordingSink) DeleteTrustLine(account, currency, issuer string, _ uint64) error {
+	r.trustDrops = append(r.trustDrops, model.TrustLineRemoval{Account: account, Currency: currency, Issuer: issuer})
+	return nil
+}
+func (r *recordingSink) UpdateOfferFill(*model.Trade, int64) error     { return nil }
+func (r *recordingSink) UpsertPool(*model.Pool) error                  { return nil }
+func (r *recordingSink) AdjustPoolReserve(*model.PoolAdjustment) error { return nil }
+func (r *recordingSink) ApplyFeeChange(*model.FeeChange) error         { return nil }
+func (r *recordingSink) ApplyAssetFlag(*model.AssetFlagChange) error   { return nil }
+func (r *recordingSink) UpsertAmendment(*model.Amendment) error        { return nil }
+func (r *recordingSink) InvalidateIdentity(string) error               { return nil }
+func (r *recordingSink) IsPoolAccount(string) bool                     { return false }
+
+// scriptedHandler matches everything and fails on demand.
+type scriptedHandler struct {
+	name string
+	fail error
+	hits *int
+}
+
+func (h scriptedHandler) Name() string        { return h.name }
+func (h scriptedHandler) Matches(string) bool { return true }
+func (h scriptedHandler) Handle(*xrpl.Transaction, *xrpl.Ledger, Sink) error {
+	*h.hits++
+	return h.fail
+}
+
+func TestRegistryIsolatesHandlerFailures(t *testing.T) {
+	var first, second int
+	reg := NewRegistry(
+		scriptedHandler{name: "boom", fail: errors.New("scripted failure"), hits: &first},
+		scriptedHandler{name: "fine", hits: &second},
+	)
+	outcomes := map[string]int{}
+	reg.SetObserver(func(handler, outcome string) { outcomes[handler+":"+outcome]++ })
+
+	tx := &xrpl.Transaction{Hash: "REGTX", TransactionType: "OfferCreate"}
+	err := reg.Dispatch(tx, &xrpl.Ledger{Index: 1}, &recordingSink{})
+	if err == nil || !strings.Contains(err.Error(), "scripted failure") {
+		t.Fatalf("dispatch error = %v", err)
+	}
+	if first != 1 || second != 1 {
+		t.Errorf("handler runs = %d, %d; the failure must not stop later handlers", first, second)
+	}
+	for _, want := range []string{"boom:matched", "boom:failed", "fine:matched", "fine:succeeded"} {
+		if outcomes[want] != 1 {
+			t.Errorf("outcome %s seen %d times, want 1; all = %v", want, outcomes[want], outcomes)
+		}
+	}
+}
+
+func TestOfferCreateHandlerStoresInvalidParse(t *testing.T) {
+	sink := &recordingSink{}
+	reg := NewRegistry(DefaultHandlers()...)
+	tx := &xrpl.Transaction{
+		Hash: "BADTX", TransactionType: "OfferCreate",
+		Account: "rBroken", Sequence: 7,
+		Meta: &xrpl.Meta{TransactionResult: tesSUCCESS, AffectedNodes: []xrpl.AffectedNode{{
+			Created: &xrpl.NodeData{
+				LedgerEntryType: "Offer",
+				NewFields:       []byte(`{"Sequence":"not-a-number"}`),
+			},
+		}}},
+	}
+	if err := reg.Dispatch(tx, &xrpl.Ledger{Index: 9, CloseTime: 700000000}, sink); err != nil {
+		t.Fatalf("invalid parse must be stored, not failed: %v", err)
+	}
+	if len(sink.offers) != 1 {
+		t.Fatalf("offers stored = %d", len(sink.offers))
+	}
+	offer := sink.offers[0]
+	if offer.Status != model.OfferStatusInvalidParse || offer.ParseError == "" || offer.TxHash != "BADTX" {
+		t.Fatalf("invalid offer = %+v", offer)
+	}
+}
+
+func TestOfferCancelHandler(t *testing.T) {
+	sink := &recordingSink{}
+	reg := NewRegistry(DefaultHandlers()...)
+	lg := &xrpl.Ledger{Index: 5, CloseTime: 700000000}
+
+	cancel := &xrpl.Transaction{
+		Hash: "CANCELTX", TransactionType: "OfferCancel",
+		Account: "rC", OfferSequence: 42,
+		Meta: &xrpl.Meta{TransactionResult: tesSUCCESS},
+	}
+	if err := reg.Dispatch(cancel, lg, sink); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.cancels) != 1 || sink.cancels[0] != 42 {
+		t.Fatalf("cancels = %v", sink.cancels)
+	}
+
+	// A failed OfferCancel changes nothing.
+	cancel.Meta.TransactionResult = "tecNO_ENTRY"
+	if err := reg.Dispatch(cancel, lg, sink); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.cancels) != 1 {
+		t.Fatalf("failed cancel was applied: %v", sink.cancels)
+	}
+}
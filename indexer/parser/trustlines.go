@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// ParseTrustSet extracts the holder's trust line state after a TrustSet.
+// The RippleState entry the metadata created or modified is
+// authoritative: the holder's limit and flags are read from whichever
+// side of the entry belongs to the transaction's account. A TrustSet
+// that deleted its line returns nil — deletions are handled by
+// ParseTrustLineRemovals, which catches them regardless of transaction
+// type.
+func ParseTrustSet(tx *xrpl.Transaction, ledgerIndex uint64) (*model.TrustLine, error) {
+	if tx.TransactionType != "TrustSet" {
+		return nil, fmt.Errorf("parser: not a TrustSet: %s", tx.TransactionType)
+	}
+	if tx.Meta == nil || tx.Meta.TransactionResult != tesSUCCESS {
+		return nil, nil
+	}
+	for _, an := range tx.Meta.AffectedNodes {
+		node, kind := an.Node()
+		if node == nil || node.LedgerEntryType != "RippleState" {
+			continue
+		}
+		if kind != xrpl.NodeCreated && kind != xrpl.NodeModified {
+			continue
+		}
+		raw := node.NewFields
+		if kind == xrpl.NodeModified {
+			raw = node.FinalFields
+		}
+		fields, err := decodeRippleStateFields(raw)
+		if err != nil {
+			return nil, err
+		}
+		if fields.Balance.Currency != tx.LimitAmount.Currency {
+			continue
+		}
+		line := &model.TrustLine{
+			Account:     tx.Account,
+			Currency:    tx.LimitAmount.Currency,
+			Issuer:      tx.LimitAmount.Issuer,
+			LedgerIndex: ledgerIndex,
+			TxHash:      tx.Hash,
+		}
+		switch tx.Account {
+		case fields.LowLimit.Issuer:
+			line.Limit = fields.LowLimit.Value
+			line.NoRipple = fields.Flags&xrpl.LsfLowNoRipple != 0
+			line.Frozen = fields.Flags&xrpl.LsfLowFreeze != 0
+		case fields.HighLimit.Issuer:
+			line.Limit = fields.HighLimit.Value
+			line.NoRipple = fields.Flags&xrpl.LsfHighNoRipple != 0
+			line.Frozen = fields.Flags&xrpl.LsfHighFreeze != 0
+		default:
+			continue
+		}
+		return line, nil
+	}
+	return nil, nil
+}
+
+// ParseTrustLineRemovals returns the trust lines any transaction's
+// metadata deleted. RippleState entries vanish not only through TrustSet
+// — payments and offer crossings delete lines that end at zero balance
+// and zero limits — so the removal scan runs on every transaction type.
+// Each deleted entry removes both accounts' lines.
+func ParseTrustLineRemovals(tx *xrpl.Transaction) ([]model.TrustLineRemoval, error) {
+	if tx.Meta == nil || tx.Meta.TransactionResult != tesSUCCESS {
+		return nil, nil
+	}
+	var removals []model.TrustLineRemoval
+	for _, an := range tx.Meta.AffectedNodes {
+		node, kind := an.Node()
+		if node == nil || kind != xrpl.NodeDeleted || node.LedgerEntryType != "RippleState" {
+			continue
+		}
+		fields, err := decodeRippleStateFields(node.FinalFields)
+		if err != nil {
+			return nil, err
+		}
+		low, high := fields.LowLimit.Issuer, fields.HighLimit.Issuer
+		currency := fields.Balance.Currency
+		removals = append(removals,
+			model.TrustLineRemoval{Account: low, Currency: currency, Issuer: high},
+			model.TrustLineRemoval{Account: high, Currency: currency, Issuer: low},
+		)
+	}
+	return removals, nil
+}
+
+func decodeRippleStateFields(raw []byte) (xrpl.RippleStateFields, error) {
+	var fields xrpl.RippleStateFields
+	if err := unmarshalFields(raw, &fields); err != nil {
+		return fields, err
+	}
+	return fields, nil
+}
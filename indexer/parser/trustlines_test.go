@@ -0,0 +1,82 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func TestParseTrustSetCreate(t *testing.T) {
+	tx := loadTxFixture(t, "trust_set_create.json")
+	line, err := ParseTrustSet(tx, 90000050)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line == nil {
+		t.Fatal("expected a trust line, the metadata created a RippleState")
+	}
+	if line.Account != "rTrustHolder1111111111111111111111" || line.Issuer != "rIssuerUSD111111111111111111111111" {
+		t.Errorf("account/issuer = %s/%s", line.Account, line.Issuer)
+	}
+	if line.Currency != "USD" || line.Limit != "500" {
+		t.Errorf("currency/limit = %s/%s", line.Currency, line.Limit)
+	}
+	// The holder is the high side; lsfHighNoRipple is set, no freeze.
+	if !line.NoRipple || line.Frozen {
+		t.Errorf("flags = no-ripple %v, frozen %v", line.NoRipple, line.Frozen)
+	}
+	if line.LedgerIndex != 90000050 || line.TxHash != tx.Hash {
+		t.Errorf("provenance = %d/%s", line.LedgerIndex, line.TxHash)
+	}
+}
+
+func TestParseTrustSetFailedTx(t *testing.T) {
+	tx := loadTxFixture(t, "trust_set_create.json")
+	tx.Meta.TransactionResult = "tecNO_LINE_INSUF_RESERVE"
+	line, err := ParseTrustSet(tx, 90000051)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != nil {
+		t.Errorf("failed transaction produced trust line %+v", line)
+	}
+}
+
+func TestParseTrustLineRemovals(t *testing.T) {
+	// A Payment that zeroes out a line deletes its RippleState; both
+	// accounts' lines must come out.
+	tx := loadTxFixture(t, "payment_removes_trust_line.json")
+	removals, err := ParseTrustLineRemovals(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removals) != 2 {
+		t.Fatalf("removals = %v, want both sides of the deleted entry", removals)
+	}
+	if removals[0].Account != "rIssuerUSD111111111111111111111111" || removals[0].Issuer != "rTrustHolder1111111111111111111111" {
+		t.Errorf("low-side removal = %+v", removals[0])
+	}
+	if removals[1].Account != "rTrustHolder1111111111111111111111" || removals[1].Issuer != "rIssuerUSD111111111111111111111111" {
+		t.Errorf("high-side removal = %+v", removals[1])
+	}
+	for _, rm := range removals {
+		if rm.Currency != "USD" {
+			t.Errorf("removal currency = %s", rm.Currency)
+		}
+	}
+}
+
+func TestTrustLineSweepHandlerMatchesEverything(t *testing.T) {
+	tx := loadTxFixture(t, "payment_removes_trust_line.json")
+	sink := &recordingSink{}
+	h := trustLineSweepHandler{}
+	if !h.Matches(tx.TransactionType) || !h.Matches("OfferCreate") {
+		t.Fatal("sweep handler must match every transaction type")
+	}
+	if err := h.Handle(tx, &xrpl.Ledger{Index: 90000052}, sink); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.trustDrops) != 2 {
+		t.Errorf("sweep recorded %d removals, want 2", len(sink.trustDrops))
+	}
+}
@@ -0,0 +1,180 @@
+package rpcapi
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/gob"
+	"fmt"
+	"net"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// Client calls an rpcapi server. Each call dials its own connection, so
+// a Client is safe for concurrent use and never holds a connection that
+// can go stale between calls.
+type Client struct {
+	addr    string
+	tlsConf *tls.Config
+
+	// dial is the connection factory; tests swap it for net.Pipe.
+	dial func(ctx context.Context) (net.Conn, error)
+}
+
+// NewClient returns a client for the server at addr. A nil tlsConf
+// means plain TCP; for mutual TLS set the client certificate and the
+// server's CA on it.
+func NewClient(addr string, tlsConf *tls.Config) *Client {
+	c := &Client{addr: addr, tlsConf: tlsConf}
+	c.dial = c.dialTCP
+	return c
+}
+
+func (c *Client) dialTCP(ctx context.Context) (net.Conn, error) {
+	d := &net.Dialer{}
+	if c.tlsConf != nil {
+		return (&tls.Dialer{NetDialer: d, Config: c.tlsConf}).DialContext(ctx, "tcp", c.addr)
+	}
+	return d.DialContext(ctx, "tcp", c.addr)
+}
+
+// OpenOffers mirrors GET /offers: the book's open offers for a pair,
+// optionally one side only or funded ones only. A zero limit means the
+// server default of 100.
+func (c *Client) OpenOffers(ctx context.Context, base, quote, side string, funded bool, limit, offset int) ([]*model.Offer, error) {
+	resp, err := c.roundTrip(ctx, &request{Method: methodOpenOffers,
+		Base: base, Quote: quote, Side: side, Funded: funded, Limit: limit, Offset: offset})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Offers, nil
+}
+
+// Pools mirrors GET /pools.
+func (c *Client) Pools(ctx context.Context, limit, offset int) ([]*model.Pool, error) {
+	resp, err := c.roundTrip(ctx, &request{Method: methodPools, Limit: limit, Offset: offset})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Pools, nil
+}
+
+// Trades mirrors GET /trades for one pair.
+func (c *Client) Trades(ctx context.Context, base, quote string, limit, offset int) ([]*model.Trade, error) {
+	resp, err := c.roundTrip(ctx, &request{Method: methodTrades,
+		Base: base, Quote: quote, Limit: limit, Offset: offset})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Trades, nil
+}
+
+// Markets mirrors GET /markets: every market's sliding 24h statistics.
+func (c *Client) Markets(ctx context.Context) ([]*model.MarketStats, error) {
+	resp, err := c.roundTrip(ctx, &request{Method: methodMarkets})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Markets, nil
+}
+
+// Checkpoint returns one ledger's checkpoint, or nil when the ledger is
+// not indexed.
+func (c *Client) Checkpoint(ctx context.Context, index uint64) (*model.Checkpoint, error) {
+	resp, err := c.roundTrip(ctx, &request{Method: methodCheckpoint, LedgerIndex: index})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Checkpoint, nil
+}
+
+// LastCheckpoint returns the highest checkpoint, or nil on an empty
+// store.
+func (c *Client) LastCheckpoint(ctx context.Context) (*model.Checkpoint, error) {
+	resp, err := c.roundTrip(ctx, &request{Method: methodLastCheckpoint})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Checkpoint, nil
+}
+
+// SubscribeEvents streams outbox events whose ledger is at or past
+// fromLedger, oldest first, calling fn for each until fn returns an
+// error (which SubscribeEvents returns) or the context ends (returning
+// ctx.Err()). The server delivers an event only once its ledger is
+// checkpointed; across reconnects the caller resumes from the last
+// event's LedgerIndex and must tolerate seeing that ledger's events
+// again.
+func (c *Client) SubscribeEvents(ctx context.Context, fromLedger uint64, fn func(*model.OutboxEvent) error) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("rpcapi: dial %s: %w", c.addr, err)
+	}
+	defer conn.Close()
+	stop := closeOnDone(ctx, conn)
+	defer stop()
+	if err := gob.NewEncoder(conn).Encode(&request{Method: methodSubscribe, LedgerIndex: fromLedger}); err != nil {
+		return fmt.Errorf("rpcapi: subscribe-events: %w", err)
+	}
+	dec := gob.NewDecoder(conn)
+	for {
+		var resp response
+		if err := dec.Decode(&resp); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("rpcapi: subscribe-events: %w", err)
+		}
+		if resp.Err != "" {
+			return fmt.Errorf("rpcapi: subscribe-events: %s", resp.Err)
+		}
+		if resp.Event == nil {
+			continue
+		}
+		if err := fn(resp.Event); err != nil {
+			return err
+		}
+	}
+}
+
+// roundTrip performs one unary call on a fresh connection.
+func (c *Client) roundTrip(ctx context.Context, req *request) (*response, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("rpcapi: dial %s: %w", c.addr, err)
+	}
+	defer conn.Close()
+	stop := closeOnDone(ctx, conn)
+	defer stop()
+	if dl, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(dl)
+	}
+	if err := gob.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("rpcapi: %s: %w", req.Method, err)
+	}
+	var resp response
+	if err := gob.NewDecoder(conn).Decode(&resp); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("rpcapi: %s: %w", req.Method, err)
+	}
+	if resp.Err != "" {
+		return nil, fmt.Errorf("rpcapi: %s: %s", req.Method, resp.Err)
+	}
+	return &resp, nil
+}
+
+// closeOnDone closes the connection when the context ends, unblocking
+// any read in flight; the returned stop function releases the watcher.
+func closeOnDone(ctx context.Context, conn net.Conn) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,96 @@
+// Package rpcapi is the typed read API for internal Go services: the
+// same store queries the Partner API serves as JSON over HTTP, exchanged
+// as Go values instead. It fills the role a gRPC service would, but this
+// tree builds with the standard library only — no protoc, no generated
+// stubs — so, like the WebSocket client in internal/ws and the NATS
+// publisher in internal/eventsink, the transport is hand-rolled: one TCP
+// connection per call (plain, TLS, or mutual TLS), a gob-encoded request
+// frame, and one gob response — or, for SubscribeEvents, a response
+// frame per event until either side hangs up.
+//
+// SubscribeEvents bridges the transactional outbox, so it inherits the
+// outbox's guarantees: events appear only once their ledger is
+// checkpointed, in dispatch order, and a client resumes from a ledger of
+// its choosing. Redelivery across reconnects is the client's to
+// tolerate, exactly as with the bus emitters.
+package rpcapi
+
+import (
+	"fmt"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// Methods a request can name.
+const (
+	methodOpenOffers     = "open-offers"
+	methodPools          = "pools"
+	methodTrades         = "trades"
+	methodMarkets        = "markets"
+	methodCheckpoint     = "checkpoint"
+	methodLastCheckpoint = "last-checkpoint"
+	methodSubscribe      = "subscribe-events"
+)
+
+// request is one call's wire frame; fields a method does not use stay at
+// their zero values and are ignored.
+type request struct {
+	Method string
+
+	// Asset parameters, as the "XRP" / "CUR.issuer" keys the HTTP API
+	// takes; Base and Quote for offers and trades, Side and Funded for
+	// offers only.
+	Base   string
+	Quote  string
+	Side   string
+	Funded bool
+
+	// Paging; a zero Limit means the server default of 100.
+	Limit  int
+	Offset int
+
+	// LedgerIndex selects the checkpoint lookup's ledger, and doubles as
+	// SubscribeEvents' resume point: the first event delivered is the
+	// oldest one at or past it.
+	LedgerIndex uint64
+}
+
+// response is one reply frame. Err is set instead of a payload when the
+// call failed; on the SubscribeEvents stream each frame carries one
+// Event.
+type response struct {
+	Err string
+
+	Offers     []*model.Offer
+	Pools      []*model.Pool
+	Trades     []*model.Trade
+	Markets    []*model.MarketStats
+	Checkpoint *model.Checkpoint
+	Event      *model.OutboxEvent
+}
+
+// pageBounds applies the same paging rules as the HTTP API: default 100
+// rows, capped at 1000, so one call cannot drag a whole table over.
+func pageBounds(limit, offset int) (int, int, error) {
+	if limit == 0 {
+		limit = 100
+	}
+	if limit < 1 || limit > 1000 {
+		return 0, 0, fmt.Errorf("limit must be 1..1000")
+	}
+	if offset < 0 {
+		return 0, 0, fmt.Errorf("offset must be >= 0")
+	}
+	return limit, offset, nil
+}
+
+// assetKey validates one asset-key parameter ("XRP" or "CUR.issuer").
+func assetKey(name, key string) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("%s is required", name)
+	}
+	if _, err := model.ParseAssetKey(key); err != nil {
+		return "", fmt.Errorf("%s: %v", name, err)
+	}
+	return key, nil
+}
@@ -0,0 +1,197 @@
+package rpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// fakeStore serves canned rows; the outbox half is mutable so the
+// subscription test can append mid-stream.
+type fakeStore struct {
+	offers  []*model.Offer
+	pools   []*model.Pool
+	trades  []*model.Trade
+	markets []*model.MarketStats
+	cps     map[uint64]*model.Checkpoint
+
+	mu     sync.Mutex
+	events []*model.OutboxEvent
+}
+
+func (f *fakeStore) GetOpenOffers(_ context.Context, base, quote, side string, funded bool, limit, offset int) ([]*model.Offer, error) {
+	return f.offers, nil
+}
+func (f *fakeStore) GetAMMPools(_ context.Context, limit, offset int) ([]*model.Pool, error) {
+	return f.pools, nil
+}
+func (f *fakeStore) GetTrades(_ context.Context, base, quote string, limit, offset int) ([]*model.Trade, error) {
+	return f.trades, nil
+}
+func (f *fakeStore) ListMarketStats(_ context.Context) ([]*model.MarketStats, error) {
+	return f.markets, nil
+}
+func (f *fakeStore) GetCheckpoint(_ context.Context, index uint64) (*model.Checkpoint, error) {
+	return f.cps[index], nil
+}
+func (f *fakeStore) GetLastCheckpoint(_ context.Context) (*model.Checkpoint, error) {
+	var last *model.Checkpoint
+	for _, cp := range f.cps {
+		if last == nil || cp.LedgerIndex > last.LedgerIndex {
+			last = cp
+		}
+	}
+	return last, nil
+}
+
+func (f *fakeStore) ReadOutboxFrom(_ context.Context, fromLedger uint64, afterID int64, limit int) ([]*model.OutboxEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []*model.OutboxEvent
+	for _, ev := range f.events {
+		if ev.ID > afterID && ev.LedgerIndex >= fromLedger {
+			out = append(out, ev)
+			if len(out) == limit {
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) append(ev *model.OutboxEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, ev)
+}
+
+// pipeClient wires a client straight to the server's connection handler
+// over net.Pipe — an in-memory listener, no sockets.
+func pipeClient(s *Server) *Client {
+	c := NewClient("pipe", nil)
+	c.dial = func(ctx context.Context) (net.Conn, error) {
+		client, server := net.Pipe()
+		go s.handleConn(ctx, server)
+		return client, nil
+	}
+	return c
+}
+
+func TestUnaryCalls(t *testing.T) {
+	st := &fakeStore{
+		offers:  []*model.Offer{{Account: "rRPCMaker", Sequence: 7}},
+		pools:   []*model.Pool{{Account: "rRPCPool"}},
+		trades:  []*model.Trade{{Maker: "rRPCMaker", TxHash: "RPCTX1"}},
+		markets: []*model.MarketStats{{Pair: "XRP|USD/rRPCIssuer", TradeCount: 3}},
+		cps: map[uint64]*model.Checkpoint{
+			90013000: {LedgerIndex: 90013000, LedgerHash: "RPCCP"},
+		},
+	}
+	c := pipeClient(NewServer(st))
+	ctx := context.Background()
+
+	offers, err := c.OpenOffers(ctx, "XRP", "USD.rRPCIssuer", "buy", false, 0, 0)
+	if err != nil || len(offers) != 1 || offers[0].Account != "rRPCMaker" {
+		t.Fatalf("OpenOffers = %+v, %v", offers, err)
+	}
+	pools, err := c.Pools(ctx, 10, 0)
+	if err != nil || len(pools) != 1 || pools[0].Account != "rRPCPool" {
+		t.Fatalf("Pools = %+v, %v", pools, err)
+	}
+	trades, err := c.Trades(ctx, "XRP", "USD.rRPCIssuer", 0, 0)
+	if err != nil || len(trades) != 1 || trades[0].TxHash != "RPCTX1" {
+		t.Fatalf("Trades = %+v, %v", trades, err)
+	}
+	markets, err := c.Markets(ctx)
+	if err != nil || len(markets) != 1 || markets[0].TradeCount != 3 {
+		t.Fatalf("Markets = %+v, %v", markets, err)
+	}
+	cp, err := c.Checkpoint(ctx, 90013000)
+	if err != nil || cp == nil || cp.LedgerHash != "RPCCP" {
+		t.Fatalf("Checkpoint = %+v, %v", cp, err)
+	}
+	if cp, err := c.Checkpoint(ctx, 90013999); err != nil || cp != nil {
+		t.Fatalf("missing checkpoint = %+v, %v; want nil, nil", cp, err)
+	}
+	if cp, err := c.LastCheckpoint(ctx); err != nil || cp == nil || cp.LedgerIndex != 90013000 {
+		t.Fatalf("LastCheckpoint = %+v, %v", cp, err)
+	}
+}
+
+func TestUnaryValidation(t *testing.T) {
+	c := pipeClient(NewServer(&fakeStore{}))
+	ctx := context.Background()
+
+	if _, err := c.OpenOffers(ctx, "", "USD.rRPCIssuer", "", false, 0, 0); err == nil {
+		t.Error("missing base asset accepted")
+	}
+	if _, err := c.OpenOffers(ctx, "XRP", "USD.rRPCIssuer", "sideways", false, 0, 0); err == nil {
+		t.Error("bad side accepted")
+	}
+	if _, err := c.Trades(ctx, "XRP", "USD.rRPCIssuer", 5000, 0); err == nil {
+		t.Error("oversized limit accepted")
+	}
+	if _, err := c.roundTrip(ctx, &request{Method: "no-such-method"}); err == nil {
+		t.Error("unknown method accepted")
+	}
+}
+
+func TestSubscribeEventsResumesAndFollows(t *testing.T) {
+	st := &fakeStore{}
+	for i := int64(1); i <= 4; i++ {
+		st.append(&model.OutboxEvent{ID: i, LedgerIndex: 90013100 + uint64(i),
+			Topic: "offers", Payload: fmt.Sprintf(`{"n":%d}`, i)})
+	}
+	srv := NewServer(st)
+	srv.poll = 10 * time.Millisecond
+	c := pipeClient(srv)
+
+	// Resume from ledger ...103: only the last two canned events qualify;
+	// an event appended while the stream idles follows in order.
+	stopAfter := errors.New("enough")
+	var got []int64
+	err := c.SubscribeEvents(context.Background(), 90013103, func(ev *model.OutboxEvent) error {
+		got = append(got, ev.ID)
+		if ev.ID == 5 {
+			return stopAfter
+		}
+		if ev.ID == 4 {
+			st.append(&model.OutboxEvent{ID: 5, LedgerIndex: 90013106, Topic: "trades"})
+		}
+		return nil
+	})
+	if !errors.Is(err, stopAfter) {
+		t.Fatalf("SubscribeEvents = %v, want the callback's error", err)
+	}
+	if len(got) != 3 || got[0] != 3 || got[1] != 4 || got[2] != 5 {
+		t.Errorf("event ids = %v, want [3 4 5]", got)
+	}
+}
+
+func TestSubscribeEventsHonorsContext(t *testing.T) {
+	srv := NewServer(&fakeStore{})
+	srv.poll = 10 * time.Millisecond
+	c := pipeClient(srv)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	err := c.SubscribeEvents(ctx, 0, func(*model.OutboxEvent) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("SubscribeEvents after cancel = %v, want context.Canceled", err)
+	}
+}
+
+func TestServerTLSRejectsBadFiles(t *testing.T) {
+	if _, err := ServerTLS("no-such.crt", "no-such.key", ""); err == nil {
+		t.Error("missing certificate accepted")
+	}
+}
@@ -0,0 +1,208 @@
+package rpcapi
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/gob"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// requestTimeout bounds how long the server waits for a connection's
+// request frame; a client that dials and says nothing is dropped.
+const requestTimeout = 30 * time.Second
+
+// Store is the slice of the persistent store this API reads;
+// *store.Store satisfies it.
+type Store interface {
+	GetOpenOffers(ctx context.Context, base, quote, side string, funded bool, limit, offset int) ([]*model.Offer, error)
+	GetAMMPools(ctx context.Context, limit, offset int) ([]*model.Pool, error)
+	GetTrades(ctx context.Context, base, quote string, limit, offset int) ([]*model.Trade, error)
+	ListMarketStats(ctx context.Context) ([]*model.MarketStats, error)
+	GetCheckpoint(ctx context.Context, index uint64) (*model.Checkpoint, error)
+	GetLastCheckpoint(ctx context.Context) (*model.Checkpoint, error)
+	ReadOutboxFrom(ctx context.Context, fromLedger uint64, afterID int64, limit int) ([]*model.OutboxEvent, error)
+}
+
+// Server answers rpcapi calls. Callers choose the listener, so TLS and
+// mutual TLS are a matter of wrapping it; see ServerTLS.
+type Server struct {
+	st Store
+	// poll is how often an idle event subscription re-reads the outbox.
+	poll time.Duration
+}
+
+// NewServer returns a server reading from st.
+func NewServer(st Store) *Server { return &Server{st: st, poll: time.Second} }
+
+// Serve accepts connections until the context ends or the listener
+// fails, one call per connection.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("rpcapi: accept: %w", err)
+		}
+		go s.handleConn(ctx, conn)
+	}
+}
+
+// handleConn reads the connection's one request and answers it. Errors
+// go back as response frames where possible; a connection that cannot
+// even deliver a request is just dropped.
+func (s *Server) handleConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(requestTimeout))
+	var req request
+	if err := gob.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+	enc := gob.NewEncoder(conn)
+	if req.Method == methodSubscribe {
+		s.subscribe(ctx, enc, &req)
+		return
+	}
+	enc.Encode(s.call(ctx, &req))
+}
+
+// call answers one unary request, validating parameters the way the
+// HTTP handlers do before the store sees them.
+func (s *Server) call(ctx context.Context, req *request) *response {
+	fail := func(err error) *response { return &response{Err: err.Error()} }
+	switch req.Method {
+	case methodOpenOffers:
+		base, err := assetKey("base", req.Base)
+		if err != nil {
+			return fail(err)
+		}
+		quote, err := assetKey("quote", req.Quote)
+		if err != nil {
+			return fail(err)
+		}
+		switch req.Side {
+		case "", "buy", "sell":
+		default:
+			return fail(fmt.Errorf("side must be buy or sell"))
+		}
+		limit, offset, err := pageBounds(req.Limit, req.Offset)
+		if err != nil {
+			return fail(err)
+		}
+		offers, err := s.st.GetOpenOffers(ctx, base, quote, req.Side, req.Funded, limit, offset)
+		if err != nil {
+			return fail(err)
+		}
+		return &response{Offers: offers}
+	case methodPools:
+		limit, offset, err := pageBounds(req.Limit, req.Offset)
+		if err != nil {
+			return fail(err)
+		}
+		pools, err := s.st.GetAMMPools(ctx, limit, offset)
+		if err != nil {
+			return fail(err)
+		}
+		return &response{Pools: pools}
+	case methodTrades:
+		base, err := assetKey("base", req.Base)
+		if err != nil {
+			return fail(err)
+		}
+		quote, err := assetKey("quote", req.Quote)
+		if err != nil {
+			return fail(err)
+		}
+		limit, offset, err := pageBounds(req.Limit, req.Offset)
+		if err != nil {
+			return fail(err)
+		}
+		trades, err := s.st.GetTrades(ctx, base, quote, limit, offset)
+		if err != nil {
+			return fail(err)
+		}
+		return &response{Trades: trades}
+	case methodMarkets:
+		markets, err := s.st.ListMarketStats(ctx)
+		if err != nil {
+			return fail(err)
+		}
+		return &response{Markets: markets}
+	case methodCheckpoint:
+		cp, err := s.st.GetCheckpoint(ctx, req.LedgerIndex)
+		if err != nil {
+			return fail(err)
+		}
+		return &response{Checkpoint: cp}
+	case methodLastCheckpoint:
+		cp, err := s.st.GetLastCheckpoint(ctx)
+		if err != nil {
+			return fail(err)
+		}
+		return &response{Checkpoint: cp}
+	}
+	return fail(fmt.Errorf("unknown method %q", req.Method))
+}
+
+// subscribe streams outbox events from the requested ledger onward, one
+// response frame each, polling for new ones once caught up. It returns
+// when the client hangs up, the context ends, or a read fails.
+func (s *Server) subscribe(ctx context.Context, enc *gob.Encoder, req *request) {
+	var lastID int64
+	for {
+		events, err := s.st.ReadOutboxFrom(ctx, req.LedgerIndex, lastID, 100)
+		if err != nil {
+			enc.Encode(&response{Err: err.Error()})
+			return
+		}
+		for _, ev := range events {
+			if err := enc.Encode(&response{Event: ev}); err != nil {
+				return
+			}
+			lastID = ev.ID
+		}
+		if len(events) == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(s.poll):
+			}
+		}
+	}
+}
+
+// ServerTLS builds the listener's TLS config: the server certificate
+// always, and with a client CA file, mutual TLS — only clients
+// presenting a certificate signed by that CA get in.
+func ServerTLS(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("rpcapi: loading server certificate: %w", err)
+	}
+	conf := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if clientCAFile != "" {
+		pem, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("rpcapi: loading client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("rpcapi: client CA %s holds no certificates", clientCAFile)
+		}
+		conf.ClientCAs = pool
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return conf, nil
+}
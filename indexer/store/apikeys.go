@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// hashAPIKey is the at-rest form of a Partner API key; the plaintext
+// never reaches the database.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey registers a key for a partner. rateLimit is requests per
+// minute; 0 defers to the server-wide default. scopes grants access
+// beyond the public endpoints. Registering an existing key again fails
+// rather than silently re-assigning it.
+func (s *Store) CreateAPIKey(ctx context.Context, key, partner string, rateLimit int, scopes []string) error {
+	if key == "" {
+		return fmt.Errorf("store: api key must not be empty")
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO api_keys (key_hash, partner, rate_limit, scopes)
+		VALUES ($1, $2, $3, $4)`, hashAPIKey(key), partner, rateLimit, strings.Join(scopes, ","))
+	if err != nil {
+		return fmt.Errorf("store: creating api key for %s: %w", partner, err)
+	}
+	return nil
+}
+
+// ValidateAPIKey resolves a presented key and counts the request
+// against it — both the lifetime counter and today's row in
+// api_key_usage — in the same statement. Unknown and revoked keys both
+// come back as (nil, nil) so callers cannot tell the cases apart.
+func (s *Store) ValidateAPIKey(ctx context.Context, key string) (*model.APIKey, error) {
+	var k model.APIKey
+	var scopes string
+	err := s.db.QueryRowContext(ctx, `
+		WITH hit AS (
+			UPDATE api_keys
+			SET request_count = request_count + 1, last_used_at = now()
+			WHERE key_hash = $1 AND enabled
+			RETURNING key_hash, partner, rate_limit, enabled, scopes
+		), counted AS (
+			INSERT INTO api_key_usage (key_hash, day, requests)
+			SELECT key_hash, current_date, 1 FROM hit
+			ON CONFLICT (key_hash, day)
+			DO UPDATE SET requests = api_key_usage.requests + 1
+		)
+		SELECT partner, rate_limit, enabled, scopes FROM hit`, hashAPIKey(key)).
+		Scan(&k.Partner, &k.RateLimit, &k.Enabled, &scopes)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: validating api key: %w", err)
+	}
+	if scopes != "" {
+		k.Scopes = strings.Split(scopes, ",")
+	}
+	// The account scope rides along on every validation, so a mapping
+	// change is live on the next request without any restart.
+	if k.Accounts, err = s.partnerAccounts(ctx, hashAPIKey(key)); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// RevokeAPIKey disables a key. Revoking an unknown key is an error so
+// operators notice typos.
+func (s *Store) RevokeAPIKey(ctx context.Context, key string) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE api_keys SET enabled = FALSE WHERE key_hash = $1`, hashAPIKey(key))
+	if err != nil {
+		return fmt.Errorf("store: revoking api key: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("store: revoking api key: no such key")
+	}
+	return nil
+}
+
+// APIKeyUsage returns per-key daily request counts over the last days
+// days, newest first and busiest partner first within a day.
+func (s *Store) APIKeyUsage(ctx context.Context, days int) ([]*model.APIKeyUsage, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT k.partner, u.day::text, u.requests
+		FROM api_key_usage u
+		JOIN api_keys k ON k.key_hash = u.key_hash
+		WHERE u.day > current_date - $1::int
+		ORDER BY u.day DESC, u.requests DESC, k.partner`, days)
+	if err != nil {
+		return nil, fmt.Errorf("store: reading api key usage: %w", err)
+	}
+	defer rows.Close()
+	var usage []*model.APIKeyUsage
+	for rows.Next() {
+		u := &model.APIKeyUsage{}
+		if err := rows.Scan(&u.Partner, &u.Day, &u.Requests); err != nil {
+			return nil, fmt.Errorf("store: scanning api key usage: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: reading api key usage: %w", err)
+	}
+	return usage, nil
+}
@@ -0,0 +1,228 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// archiveBatch caps how many checkpoint ledgers (and audit rows) one
+// forwarding transaction copies, so catching up after archive downtime
+// happens in bounded steps instead of one giant transaction.
+const archiveBatch = 1000
+
+// Archiver asynchronously copies the append-only compliance tables —
+// checkpoints, trades, and the connection audit trail — from the primary
+// store into a second database. It tails committed ledgers by checkpoint
+// and keeps its own progress marker in the archive, so it never blocks
+// the primary write path and catches up on its own after archive
+// downtime. Every copy is idempotent (inserts land ON CONFLICT DO
+// NOTHING against the primary's keys), so replaying the last in-flight
+// batch after a crash is harmless.
+type Archiver struct {
+	primary  *Store
+	archive  *Store
+	migrated bool
+
+	onError    func(error)
+	onProgress func(rows int, lastLedger uint64)
+}
+
+// NewArchiver opens the archive database; the connection is only
+// exercised once Run starts, so an archive that is down at startup just
+// delays forwarding.
+func NewArchiver(primary *Store, driver, dsn string) (*Archiver, error) {
+	archive, err := Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("store: archive: %w", err)
+	}
+	archive.SetNetwork(primary.network)
+	return &Archiver{primary: primary, archive: archive}, nil
+}
+
+// Close releases the archive connection.
+func (a *Archiver) Close() error { return a.archive.Close() }
+
+// OnError registers a callback for forwarding failures; the Archiver
+// also records each failure on the primary's connection audit trail as
+// service "postgres-archive".
+func (a *Archiver) OnError(fn func(error)) { a.onError = fn }
+
+// OnProgress registers a callback invoked after each committed batch
+// with the rows copied and the last fully archived ledger.
+func (a *Archiver) OnProgress(fn func(rows int, lastLedger uint64)) { a.onProgress = fn }
+
+// Run forwards until the context ends, polling the primary on the given
+// interval. Failures are reported and retried on the next tick; a batch
+// either commits whole (rows plus progress marker) or not at all.
+func (a *Archiver) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := a.runOnce(ctx); err != nil && ctx.Err() == nil {
+			if a.onError != nil {
+				a.onError(err)
+			}
+			// The archive being down is exactly when the audit trail on
+			// the primary must say so; its own write failing is not
+			// actionable beyond the callback above.
+			_ = a.primary.LogConnectionEvent(ctx, "postgres-archive", "forward-failed", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce catches the archive up to the primary's current checkpoint,
+// batch by batch.
+func (a *Archiver) runOnce(ctx context.Context) error {
+	if !a.migrated {
+		if err := a.archive.Migrate(ctx); err != nil {
+			return err
+		}
+		if err := a.archive.VerifyNetwork(ctx); err != nil {
+			return err
+		}
+		a.migrated = true
+	}
+	for {
+		advanced, err := a.step(ctx)
+		if err != nil || !advanced {
+			return err
+		}
+	}
+}
+
+// step copies one batch inside a single archive transaction.
+func (a *Archiver) step(ctx context.Context) (bool, error) {
+	tx, err := a.archive.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("store: archive: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	var last, connID, eventID int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT ledger_index, connections_id, events_id FROM archive_progress WHERE id = 1`).
+		Scan(&last, &connID, &eventID)
+	if err != nil && err != sql.ErrNoRows {
+		return false, fmt.Errorf("store: archive: progress: %w", err)
+	}
+
+	nCheckpoints, maxLedger, err := a.copyRows(ctx, tx, `
+		SELECT ledger_index, ledger_hash, close_time, network, indexed_at, first_indexed_at
+		FROM checkpoints WHERE ledger_index > $1 ORDER BY ledger_index LIMIT $2`,
+		[]any{last, archiveBatch}, `
+		INSERT INTO checkpoints (ledger_index, ledger_hash, close_time, network, indexed_at, first_indexed_at)
+		VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (ledger_index) DO NOTHING`, 6)
+	if err != nil {
+		return false, fmt.Errorf("store: archive: checkpoints: %w", err)
+	}
+	newLast := last
+	if maxLedger > newLast {
+		newLast = maxLedger
+	}
+	// Trades only up to the newest archived checkpoint: a checkpoint is
+	// what marks its ledger's rows as committed on the primary.
+	var nTrades int
+	if newLast > last {
+		nTrades, _, err = a.copyRows(ctx, tx, `
+			SELECT id, maker, taker, offer_sequence,
+				gets_currency, gets_issuer, gets_value::text,
+				pays_currency, pays_issuer, pays_value::text,
+				price::text, ledger_index, tx_hash, parser_version
+			FROM trades WHERE ledger_index > $1 AND ledger_index <= $2 ORDER BY id`,
+			[]any{last, newLast}, `
+			INSERT INTO trades (id, maker, taker, offer_sequence,
+				gets_currency, gets_issuer, gets_value,
+				pays_currency, pays_issuer, pays_value,
+				price, ledger_index, tx_hash, parser_version)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			ON CONFLICT (id) DO NOTHING`, 14)
+		if err != nil {
+			return false, fmt.Errorf("store: archive: trades: %w", err)
+		}
+	}
+	nConns, maxConn, err := a.copyRows(ctx, tx, `
+		SELECT id, url, server_type, version, network_id, connected_at
+		FROM connections WHERE id > $1 ORDER BY id LIMIT $2`,
+		[]any{connID, archiveBatch}, `
+		INSERT INTO connections (id, url, server_type, version, network_id, connected_at)
+		VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (id) DO NOTHING`, 6)
+	if err != nil {
+		return false, fmt.Errorf("store: archive: connections: %w", err)
+	}
+	if maxConn > connID {
+		connID = maxConn
+	}
+	nEvents, maxEvent, err := a.copyRows(ctx, tx, `
+		SELECT id, service, event, detail, recorded_at
+		FROM connection_events WHERE id > $1 ORDER BY id LIMIT $2`,
+		[]any{eventID, archiveBatch}, `
+		INSERT INTO connection_events (id, service, event, detail, recorded_at)
+		VALUES ($1, $2, $3, $4, $5) ON CONFLICT (id) DO NOTHING`, 5)
+	if err != nil {
+		return false, fmt.Errorf("store: archive: connection events: %w", err)
+	}
+	if maxEvent > eventID {
+		eventID = maxEvent
+	}
+
+	rows := nCheckpoints + nTrades + nConns + nEvents
+	if rows == 0 && newLast == last {
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO archive_progress (id, ledger_index, connections_id, events_id)
+		VALUES (1, $1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET ledger_index = EXCLUDED.ledger_index,
+			connections_id = EXCLUDED.connections_id,
+			events_id = EXCLUDED.events_id,
+			updated_at = now()`, newLast, connID, eventID); err != nil {
+		return false, fmt.Errorf("store: archive: progress: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("store: archive: commit: %w", err)
+	}
+	if a.onProgress != nil {
+		a.onProgress(rows, uint64(newLast))
+	}
+	return true, nil
+}
+
+// copyRows streams one primary query's rows into the archive transaction.
+// The select and insert column lists must line up; NULLs pass through
+// untouched. The first column is the cursor (ledger_index or id) and its
+// maximum is returned alongside the row count.
+func (a *Archiver) copyRows(ctx context.Context, tx *sql.Tx, selectSQL string, args []any, insertSQL string, cols int) (copied int, maxCursor int64, err error) {
+	rows, err := a.primary.db.QueryContext(ctx, selectSQL, args...)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	vals := make([]any, cols)
+	ptrs := make([]any, cols)
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return copied, maxCursor, err
+		}
+		if _, err := tx.ExecContext(ctx, insertSQL, vals...); err != nil {
+			return copied, maxCursor, err
+		}
+		if cursor, ok := vals[0].(int64); ok && cursor > maxCursor {
+			maxCursor = cursor
+		}
+		copied++
+	}
+	return copied, maxCursor, rows.Err()
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/balancechanges"
+)
+
+// InsertBalanceChanges writes every balance change of one transaction in a
+// single batched statement. Replaying a ledger overwrites the deltas in
+// place, so a fixed extractor converges instead of duplicating rows.
+func (s *Store) InsertBalanceChanges(ctx context.Context, ledgerIndex uint64, closeTime int64, txHash string, changes []balancechanges.BalanceChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	defer s.observeQuery("insert balance changes", time.Now())
+	var (
+		sb   strings.Builder
+		args []any
+	)
+	sb.WriteString(`
+		INSERT INTO balance_changes (account, currency, issuer, delta, ledger_index, close_time, tx_hash)
+		VALUES `)
+	for i, c := range changes {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, "($%d,$%d,$%d,$%d,$%d,$%d,$%d)",
+			len(args)+1, len(args)+2, len(args)+3, len(args)+4, len(args)+5, len(args)+6, len(args)+7)
+		args = append(args, c.Account, c.Currency, c.Issuer, c.Delta, ledgerIndex, closeTime, txHash)
+	}
+	sb.WriteString(`
+		ON CONFLICT (tx_hash, account, currency, issuer) DO UPDATE SET
+			delta = EXCLUDED.delta,
+			ledger_index = EXCLUDED.ledger_index,
+			close_time = EXCLUDED.close_time`)
+	if _, err := s.db.ExecContext(ctx, sb.String(), args...); err != nil {
+		return fmt.Errorf("store: balance changes %s: %w", txHash, err)
+	}
+	return nil
+}
+
+// GetBalanceChanges returns an account's balance changes whose ledger falls
+// in [from, to], in ledger order. A zero `to` means no upper bound; a
+// non-positive limit defaults to 100.
+func (s *Store) GetBalanceChanges(ctx context.Context, account string, from, to uint64, limit int) ([]*balancechanges.Record, error) {
+	if to == 0 {
+		to = 1<<63 - 1
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT account, currency, issuer, delta::text, ledger_index, close_time, tx_hash
+		FROM balance_changes
+		WHERE account = $1 AND ledger_index BETWEEN $2 AND $3
+		ORDER BY ledger_index, tx_hash, currency, issuer
+		LIMIT $4`,
+		account, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: balance changes %s: %w", account, err)
+	}
+	defer rows.Close()
+	var records []*balancechanges.Record
+	for rows.Next() {
+		var rec balancechanges.Record
+		if err := rows.Scan(&rec.Account, &rec.Currency, &rec.Issuer, &rec.Delta,
+			&rec.LedgerIndex, &rec.CloseTime, &rec.TxHash); err != nil {
+			return nil, fmt.Errorf("store: scanning balance change: %w", err)
+		}
+		records = append(records, &rec)
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// ListBookOffers returns every active offer in a market, both
+// directions, including snapshot-status rows the public API omits — the
+// consistency checker compares the whole assembled book, not just the
+// consumer-facing slice of it.
+func (s *Store) ListBookOffers(ctx context.Context, base, quote string) ([]*model.Offer, error) {
+	b, err := model.ParseAssetKey(base)
+	if err != nil {
+		return nil, fmt.Errorf("store: book offers: %w", err)
+	}
+	q, err := model.ParseAssetKey(quote)
+	if err != nil {
+		return nil, fmt.Errorf("store: book offers: %w", err)
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT o.tx_hash, o.account, o.sequence, o.flags,
+			o.gets_currency, o.gets_issuer, o.gets_value,
+			o.pays_currency, o.pays_issuer, o.pays_value,
+			COALESCE(o.limit_price::text, ''), COALESCE(o.executed_price::text, ''),
+			o.status, o.ledger_index, COALESCE(o.replaced_by, 0), COALESCE(o.parse_error, ''),
+			COALESCE(o.funded_gets_value::text, ''), o.unfunded, o.funded_checked_ledger
+		FROM offers o
+		WHERE o.status IN `+activeStatuses+`
+		  AND ((o.gets_currency = $1 AND o.gets_issuer = $2
+		            AND o.pays_currency = $3 AND o.pays_issuer = $4)
+		    OR (o.gets_currency = $3 AND o.gets_issuer = $4
+		            AND o.pays_currency = $1 AND o.pays_issuer = $2))
+		ORDER BY o.account, o.sequence`,
+		b.Currency, b.Issuer, q.Currency, q.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("store: book offers: %w", err)
+	}
+	defer rows.Close()
+	return scanOffers(rows)
+}
+
+// RecordBookDiscrepancy appends one mismatch between the assembled book
+// and the ledger's book_offers view. fixed says the caller already
+// reconciled the row, so the log doubles as the audit trail of -fix.
+func (s *Store) RecordBookDiscrepancy(ctx context.Context, pair, kind, account string, sequence uint32, stored, actual string, ledgerIndex uint64, fixed bool) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO book_discrepancies (pair, kind, account, sequence, stored, actual, ledger_index, fixed)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`,
+		pair, kind, account, sequence, stored, actual, ledgerIndex, fixed)
+	if err != nil {
+		return fmt.Errorf("store: record book discrepancy %s %s/%d: %w", kind, account, sequence, err)
+	}
+	return nil
+}
+
+// ReconcileOfferAmounts overwrites an active offer's remaining amounts
+// with the ledger's view of them; the lifecycle guard keeps it from
+// touching rows the stream has since moved past ledgerIndex.
+func (s *Store) ReconcileOfferAmounts(ctx context.Context, account string, sequence uint32, gets, pays string, ledgerIndex uint64) error {
+	s.evictOffer(account, sequence)
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE offers SET gets_value = $3::numeric, pays_value = $4::numeric
+		WHERE account = $1 AND sequence = $2
+		  AND status IN `+activeStatuses+` AND ledger_index <= $5`,
+		account, sequence, gets, pays, ledgerIndex)
+	if err != nil {
+		return fmt.Errorf("store: reconcile offer %s/%d: %w", account, sequence, err)
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+package store
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// In-process caches for the hot lookups on the processing path. They live
+// inside the Store because the Store is the single writer (the leader
+// lock guarantees it), which makes coherence trivial: everything cached
+// was either written through this Store or read from the database it
+// alone writes to, and every write invalidates what it touches.
+//
+// Both caches are off until SetCacheSizes enables them. Reprocessing
+// paths must leave them off — a cache populated before a parser fix
+// could hand back the very rows the replay is correcting.
+
+// CacheStats is a point-in-time hit/miss snapshot for one cache.
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// SetCacheSizes enables the checkpoint and offer caches with the given
+// capacities; zero or negative disables that cache. Only the long-running
+// ingest command enables them — one-shot commands and replays read the
+// database directly.
+func (s *Store) SetCacheSizes(checkpoints, offers int) {
+	if checkpoints > 0 {
+		s.cpCache = newCheckpointCache(checkpoints)
+	} else {
+		s.cpCache = nil
+	}
+	if offers > 0 {
+		s.offerCache = newOfferCache(offers)
+	} else {
+		s.offerCache = nil
+	}
+}
+
+// CacheStats returns a snapshot per enabled cache, keyed "checkpoints"
+// and "offers"; disabled caches are absent.
+func (s *Store) CacheStats() map[string]CacheStats {
+	stats := map[string]CacheStats{}
+	if s.cpCache != nil {
+		stats["checkpoints"] = s.cpCache.stats()
+	}
+	if s.offerCache != nil {
+		stats["offers"] = s.offerCache.stats()
+	}
+	return stats
+}
+
+// checkpointCache keeps the last K checkpoints by index, plus the highest
+// one for GetLastCheckpoint and LatestLedger. The highest entry is only
+// primed from a database read: SaveCheckpoint alone cannot establish it,
+// because a backfill writes descending indexes below rows that may
+// already be there.
+type checkpointCache struct {
+	mu      sync.Mutex
+	size    int
+	byIndex map[uint64]model.Checkpoint
+	order   []uint64 // insertion order; the front is evicted first
+
+	latest    model.Checkpoint
+	hasLatest bool
+
+	hits, misses uint64
+}
+
+func newCheckpointCache(size int) *checkpointCache {
+	return &checkpointCache{size: size, byIndex: map[uint64]model.Checkpoint{}}
+}
+
+func (c *checkpointCache) put(cp model.Checkpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.byIndex[cp.LedgerIndex]; !ok {
+		c.order = append(c.order, cp.LedgerIndex)
+		for len(c.order) > c.size {
+			delete(c.byIndex, c.order[0])
+			c.order = c.order[1:]
+		}
+	}
+	c.byIndex[cp.LedgerIndex] = cp
+	if c.hasLatest && cp.LedgerIndex >= c.latest.LedgerIndex {
+		c.latest = cp
+	}
+}
+
+func (c *checkpointCache) primeLatest(cp model.Checkpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.hasLatest || cp.LedgerIndex >= c.latest.LedgerIndex {
+		c.latest, c.hasLatest = cp, true
+	}
+}
+
+func (c *checkpointCache) get(index uint64) (model.Checkpoint, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cp, ok := c.byIndex[index]
+	c.count(ok)
+	return cp, ok
+}
+
+func (c *checkpointCache) getLatest() (model.Checkpoint, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count(c.hasLatest)
+	return c.latest, c.hasLatest
+}
+
+func (c *checkpointCache) count(hit bool) {
+	if hit {
+		c.hits++
+	} else {
+		c.misses++
+	}
+}
+
+func (c *checkpointCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Hits: c.hits, Misses: c.misses}
+}
+
+// offerCache is a plain LRU over offers by natural key. Entries are
+// value copies, so a cached row handed to one caller cannot be mutated
+// under another.
+type offerCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[offerKey]*list.Element
+	lru     *list.List // front is most recently used
+
+	hits, misses uint64
+}
+
+type offerEntry struct {
+	key   offerKey
+	offer model.Offer
+}
+
+func newOfferCache(size int) *offerCache {
+	return &offerCache{size: size, entries: map[offerKey]*list.Element{}, lru: list.New()}
+}
+
+func (c *offerCache) get(key offerKey) (model.Offer, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return model.Offer{}, false
+	}
+	c.hits++
+	c.lru.MoveToFront(el)
+	return el.Value.(*offerEntry).offer, true
+}
+
+func (c *offerCache) put(key offerKey, o model.Offer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*offerEntry).offer = o
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&offerEntry{key: key, offer: o})
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*offerEntry).key)
+	}
+}
+
+func (c *offerCache) invalidate(key offerKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+	}
+}
+
+// flush drops everything; bulk writes that cannot enumerate the rows
+// they touched (RefreshFundedOffers) use it instead of guessing keys.
+func (c *offerCache) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[offerKey]*list.Element{}
+	c.lru.Init()
+}
+
+func (c *offerCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Hits: c.hits, Misses: c.misses}
+}
+
+// GetCheckpoint returns one checkpoint by index, or nil when the ledger
+// has not been checkpointed. Recently saved checkpoints come from memory.
+func (s *Store) GetCheckpoint(ctx context.Context, index uint64) (*model.Checkpoint, error) {
+	if s.cpCache != nil {
+		if cp, ok := s.cpCache.get(index); ok {
+			return &cp, nil
+		}
+	}
+	var cp model.Checkpoint
+	err := s.db.QueryRowContext(ctx, `
+		SELECT ledger_index, ledger_hash, close_time
+		FROM checkpoints WHERE ledger_index = $1`, index).
+		Scan(&cp.LedgerIndex, &cp.LedgerHash, &cp.CloseTime)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: checkpoint %d: %w", index, err)
+	}
+	if s.cpCache != nil {
+		s.cpCache.put(cp)
+	}
+	return &cp, nil
+}
+
+// GetOfferByKey returns one offer by its natural key, or nil for an
+// unknown offer. Reads go through the offer cache when enabled; every
+// write to a row evicts its entry first, so a hit is never staler than
+// the last write made through this Store.
+func (s *Store) GetOfferByKey(ctx context.Context, account string, sequence uint32) (*model.Offer, error) {
+	key := offerKey{account: account, sequence: sequence}
+	if s.offerCache != nil {
+		if o, ok := s.offerCache.get(key); ok {
+			return &o, nil
+		}
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT tx_hash, account, sequence, flags,
+			gets_currency, gets_issuer, gets_value,
+			pays_currency, pays_issuer, pays_value,
+			COALESCE(limit_price::text, ''), COALESCE(executed_price::text, ''),
+			status, ledger_index, COALESCE(replaced_by, 0), COALESCE(parse_error, ''),
+			COALESCE(funded_gets_value::text, ''), unfunded, funded_checked_ledger
+		FROM offers WHERE account = $1 AND sequence = $2`,
+		account, sequence)
+	if err != nil {
+		return nil, fmt.Errorf("store: offer %s/%d: %w", account, sequence, err)
+	}
+	defer rows.Close()
+	offers, err := scanOffers(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(offers) == 0 {
+		return nil, nil
+	}
+	if s.offerCache != nil {
+		s.offerCache.put(key, *offers[0])
+	}
+	return offers[0], nil
+}
+
+func (s *Store) evictOffer(account string, sequence uint32) {
+	if s.offerCache != nil {
+		s.offerCache.invalidate(offerKey{account: account, sequence: sequence})
+	}
+}
+
+// CacheSnapshot is the serializable image of the in-process caches,
+// captured for the cold-start snapshot file. Entries are value copies in
+// age order — oldest first — so restoring them as ordinary puts rebuilds
+// the same eviction order.
+type CacheSnapshot struct {
+	Checkpoints []model.Checkpoint
+	Latest      *model.Checkpoint
+	Offers      []model.Offer
+}
+
+// SnapshotCaches copies the enabled caches' contents; nil when both
+// caches are empty or disabled.
+func (s *Store) SnapshotCaches() *CacheSnapshot {
+	snap := &CacheSnapshot{}
+	if c := s.cpCache; c != nil {
+		c.mu.Lock()
+		for _, index := range c.order {
+			snap.Checkpoints = append(snap.Checkpoints, c.byIndex[index])
+		}
+		if c.hasLatest {
+			latest := c.latest
+			snap.Latest = &latest
+		}
+		c.mu.Unlock()
+	}
+	if c := s.offerCache; c != nil {
+		c.mu.Lock()
+		for el := c.lru.Back(); el != nil; el = el.Prev() {
+			snap.Offers = append(snap.Offers, el.Value.(*offerEntry).offer)
+		}
+		c.mu.Unlock()
+	}
+	if len(snap.Checkpoints) == 0 && snap.Latest == nil && len(snap.Offers) == 0 {
+		return nil
+	}
+	return snap
+}
+
+// RestoreCaches primes the enabled caches by replaying a snapshot's
+// entries as puts; a disabled cache ignores its share. The caller is
+// responsible for only restoring a snapshot whose ledger is still
+// checkpointed in the database — the single-writer coherence argument
+// above holds for restored entries exactly as far as that is true.
+func (s *Store) RestoreCaches(snap *CacheSnapshot) {
+	if snap == nil {
+		return
+	}
+	if s.cpCache != nil {
+		for _, cp := range snap.Checkpoints {
+			s.cpCache.put(cp)
+		}
+		if snap.Latest != nil {
+			s.cpCache.primeLatest(*snap.Latest)
+		}
+	}
+	if s.offerCache != nil {
+		for _, o := range snap.Offers {
+			s.offerCache.put(offerKey{account: o.Account, sequence: o.Sequence}, o)
+		}
+	}
+}
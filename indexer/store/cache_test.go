@@ -0,0 +1,232 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func TestOfferCacheLRU(t *testing.T) {
+	c := newOfferCache(2)
+	keyA := offerKey{account: "rA", sequence: 1}
+	keyB := offerKey{account: "rB", sequence: 2}
+	keyC := offerKey{account: "rC", sequence: 3}
+
+	c.put(keyA, model.Offer{Account: "rA", Sequence: 1})
+	c.put(keyB, model.Offer{Account: "rB", Sequence: 2})
+	if _, ok := c.get(keyA); !ok {
+		t.Fatal("fresh entry missing")
+	}
+	// keyB is now least recently used; a third entry evicts it, not keyA.
+	c.put(keyC, model.Offer{Account: "rC", Sequence: 3})
+	if _, ok := c.get(keyB); ok {
+		t.Error("least recently used entry survived eviction")
+	}
+	if _, ok := c.get(keyA); !ok {
+		t.Error("recently used entry was evicted")
+	}
+
+	c.invalidate(keyA)
+	if _, ok := c.get(keyA); ok {
+		t.Error("invalidated entry still cached")
+	}
+	if s := c.stats(); s.Hits != 2 || s.Misses != 2 {
+		t.Errorf("stats = %+v, want 2 hits / 2 misses", s)
+	}
+
+	c.flush()
+	if _, ok := c.get(keyC); ok {
+		t.Error("flushed entry still cached")
+	}
+}
+
+func TestCheckpointCacheLatest(t *testing.T) {
+	c := newCheckpointCache(2)
+	if _, ok := c.getLatest(); ok {
+		t.Fatal("cold cache claims a latest checkpoint")
+	}
+	// A save alone must not establish the latest: a backfill writes
+	// descending indexes below rows the database may already hold.
+	c.put(model.Checkpoint{LedgerIndex: 100})
+	if _, ok := c.getLatest(); ok {
+		t.Fatal("save established latest without a database read")
+	}
+	c.primeLatest(model.Checkpoint{LedgerIndex: 500, LedgerHash: "H500"})
+	if cp, ok := c.getLatest(); !ok || cp.LedgerIndex != 500 {
+		t.Fatalf("latest = %+v, %v", cp, ok)
+	}
+	// A descending save keeps latest put; an ascending one advances it.
+	c.put(model.Checkpoint{LedgerIndex: 99})
+	if cp, _ := c.getLatest(); cp.LedgerIndex != 500 {
+		t.Errorf("descending save moved latest to %d", cp.LedgerIndex)
+	}
+	c.put(model.Checkpoint{LedgerIndex: 501, LedgerHash: "H501"})
+	if cp, _ := c.getLatest(); cp.LedgerIndex != 501 || cp.LedgerHash != "H501" {
+		t.Errorf("ascending save did not advance latest: %+v", cp)
+	}
+
+	// Capacity two: the oldest by-index entry is gone, the newest are not.
+	if _, ok := c.get(100); ok {
+		t.Error("oldest entry survived eviction")
+	}
+	if _, ok := c.get(501); !ok {
+		t.Error("newest entry missing")
+	}
+}
+
+func TestCachedReadsAndInvalidation(t *testing.T) {
+	st := openTestStore(t)
+	st.SetCacheSizes(8, 8)
+	ctx := context.Background()
+	mustExec(t, st, `DELETE FROM offers WHERE account = 'rCacheTest'`)
+	mustExec(t, st, `DELETE FROM offer_events WHERE account = 'rCacheTest'`)
+
+	offer := &model.Offer{
+		Account: "rCacheTest", Sequence: 1, TxHash: "CACHE1",
+		TakerGets: xrpl.Amount{Currency: "XRP", Value: "100"},
+		TakerPays: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "50"},
+		Status:    model.OfferStatusOpen, LedgerIndex: 98004000,
+	}
+	if err := st.UpsertOffer(ctx, offer); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		got, err := st.GetOfferByKey(ctx, "rCacheTest", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got == nil || got.Status != model.OfferStatusOpen {
+			t.Fatalf("read %d = %+v", i, got)
+		}
+	}
+	if s := st.CacheStats()["offers"]; s.Hits != 1 || s.Misses != 1 {
+		t.Errorf("offer stats = %+v, want 1 hit / 1 miss", s)
+	}
+
+	// A write through the store evicts the entry, so the next read sees
+	// the new row rather than the cached one.
+	if ok, err := st.CancelOffer(ctx, "rCacheTest", 1, 98004001, 0, "CACHE2"); err != nil || !ok {
+		t.Fatalf("cancel = %v, %v", ok, err)
+	}
+	got, err := st.GetOfferByKey(ctx, "rCacheTest", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != model.OfferStatusCancelled {
+		t.Errorf("post-write read = %s, want cancelled", got.Status)
+	}
+
+	// The latest checkpoint is primed by the first database read and
+	// served from memory after, advancing as checkpoints are saved.
+	if err := st.SaveCheckpoint(ctx, &model.Checkpoint{LedgerIndex: 98004001, LedgerHash: "CACHEH"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.GetLastCheckpoint(ctx); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := st.LatestLedger(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest < 98004001 {
+		t.Errorf("latest = %d, want at least the saved checkpoint", latest)
+	}
+	if s := st.CacheStats()["checkpoints"]; s.Hits == 0 {
+		t.Errorf("checkpoint stats = %+v, want the second read served from memory", s)
+	}
+	if cp, err := st.GetCheckpoint(ctx, 98004001); err != nil || cp == nil || cp.LedgerHash != "CACHEH" {
+		t.Errorf("GetCheckpoint = %+v, %v", cp, err)
+	}
+
+	mustExec(t, st, `DELETE FROM offers WHERE account = 'rCacheTest'`)
+	mustExec(t, st, `DELETE FROM offer_events WHERE account = 'rCacheTest'`)
+	mustExec(t, st, `DELETE FROM checkpoints WHERE ledger_index = 98004001`)
+}
+
+// The benchmarks put a number on what the caches save: the cached run
+// should sit near memory speed while the uncached one pays a database
+// round trip per lookup.
+func BenchmarkGetOfferByKey(b *testing.B) {
+	st := openTestStore(b)
+	ctx := context.Background()
+	if _, err := st.DB().Exec(`DELETE FROM offers WHERE account = 'rCacheBench'`); err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		err := st.UpsertOffer(ctx, &model.Offer{
+			Account: "rCacheBench", Sequence: uint32(i + 1),
+			TxHash:    fmt.Sprintf("CACHEB%03d", i),
+			TakerGets: xrpl.Amount{Currency: "XRP", Value: "100"},
+			TakerPays: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "50"},
+			Status:    model.OfferStatusOpen, LedgerIndex: 98004100,
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	for _, bench := range []struct {
+		name             string
+		checkpoints, off int
+	}{{"uncached", 0, 0}, {"cached", 64, 4096}} {
+		b.Run(bench.name, func(b *testing.B) {
+			st.SetCacheSizes(bench.checkpoints, bench.off)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := st.GetOfferByKey(ctx, "rCacheBench", uint32(i%100+1)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+	st.SetCacheSizes(0, 0)
+	if _, err := st.DB().Exec(`DELETE FROM offers WHERE account = 'rCacheBench'`); err != nil {
+		b.Fatal(err)
+	}
+	if _, err := st.DB().Exec(`DELETE FROM offer_events WHERE account = 'rCacheBench'`); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func TestCacheSnapshotRoundTrip(t *testing.T) {
+	s := &Store{}
+	s.SetCacheSizes(2, 2)
+	s.cpCache.put(model.Checkpoint{LedgerIndex: 100, LedgerHash: "H100"})
+	s.cpCache.put(model.Checkpoint{LedgerIndex: 101, LedgerHash: "H101"})
+	s.cpCache.primeLatest(model.Checkpoint{LedgerIndex: 101, LedgerHash: "H101"})
+	s.offerCache.put(offerKey{"rA", 1}, model.Offer{Account: "rA", Sequence: 1})
+	s.offerCache.put(offerKey{"rB", 2}, model.Offer{Account: "rB", Sequence: 2})
+	s.offerCache.get(offerKey{"rA", 1}) // rB is now least recently used
+
+	snap := s.SnapshotCaches()
+	if snap == nil || len(snap.Checkpoints) != 2 || len(snap.Offers) != 2 || snap.Latest == nil {
+		t.Fatalf("snapshot = %+v", snap)
+	}
+
+	fresh := &Store{}
+	fresh.SetCacheSizes(2, 2)
+	fresh.RestoreCaches(snap)
+	if cp, ok := fresh.cpCache.get(101); !ok || cp.LedgerHash != "H101" {
+		t.Errorf("restored checkpoint = %+v, %v", cp, ok)
+	}
+	if cp, ok := fresh.cpCache.getLatest(); !ok || cp.LedgerIndex != 101 {
+		t.Errorf("restored latest = %+v, %v", cp, ok)
+	}
+	// Recency carried over: a third offer evicts rB, not rA.
+	fresh.offerCache.put(offerKey{"rC", 3}, model.Offer{Account: "rC", Sequence: 3})
+	if _, ok := fresh.offerCache.get(offerKey{"rB", 2}); ok {
+		t.Error("restored LRU evicted the wrong entry")
+	}
+	if _, ok := fresh.offerCache.get(offerKey{"rA", 1}); !ok {
+		t.Error("recently used entry lost across the round trip")
+	}
+
+	// Disabled caches have nothing to capture and ignore a restore.
+	off := &Store{}
+	off.RestoreCaches(snap)
+	if off.SnapshotCaches() != nil {
+		t.Error("disabled caches produced a snapshot")
+	}
+}
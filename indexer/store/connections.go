@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// RecordConnection appends one connection-audit row: which server kind
+// and version this process connected to, so indexed data can later be
+// traced to the server that supplied it.
+func (s *Store) RecordConnection(ctx context.Context, url, serverType, version string, networkID uint32) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO connections (url, server_type, version, network_id)
+		VALUES ($1, $2, $3, $4)`, url, serverType, version, networkID)
+	if err != nil {
+		return fmt.Errorf("store: recording connection: %w", err)
+	}
+	return nil
+}
+
+// LogConnectionEvent appends one free-form service event to the
+// connection audit trail — reconnects, failovers, archive trouble — so
+// operational incidents are queryable next to the data they affected.
+func (s *Store) LogConnectionEvent(ctx context.Context, service, event, detail string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO connection_events (service, event, detail)
+		VALUES ($1, $2, $3)`, service, event, detail)
+	if err != nil {
+		return fmt.Errorf("store: logging %s event: %w", service, err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+func TestStreamCheckpointsOncePerRow(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	mustExec(t, st, `DELETE FROM checkpoints WHERE ledger_index BETWEEN 98000000 AND 98000499`)
+	mustExec(t, st, `
+		INSERT INTO checkpoints (ledger_index, ledger_hash, close_time)
+		SELECT i, 'STREAMCP', 700004000 FROM generate_series(98000000, 98000499) i`)
+
+	// Every row arrives exactly once, in ledger order.
+	var seen int
+	next := uint64(98000000)
+	err := st.StreamCheckpoints(ctx, 98000000, 98000499, func(cp *model.Checkpoint) error {
+		if cp.LedgerIndex != next {
+			return fmt.Errorf("row out of order: got %d, want %d", cp.LedgerIndex, next)
+		}
+		next++
+		seen++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen != 500 {
+		t.Fatalf("callback ran %d times, want 500", seen)
+	}
+
+	// A failing callback stops the walk immediately.
+	seen = 0
+	stop := fmt.Errorf("enough")
+	err = st.StreamCheckpoints(ctx, 98000000, 98000499, func(*model.Checkpoint) error {
+		seen++
+		if seen == 10 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Fatalf("early stop returned %v, want the callback's error", err)
+	}
+	if seen != 10 {
+		t.Errorf("callback ran %d times after stopping, want 10", seen)
+	}
+
+	// A cancelled context ends the walk with its error.
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = st.StreamCheckpoints(cancelled, 98000000, 98000499, func(*model.Checkpoint) error {
+		return nil
+	})
+	if err == nil {
+		t.Error("cancelled stream reported no error")
+	}
+
+	mustExec(t, st, `DELETE FROM checkpoints WHERE ledger_index BETWEEN 98000000 AND 98000499`)
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrFailpoint is what a write returns when an armed failpoint fires.
+// The replay command treats it as a simulated crash; nothing in a real
+// deployment ever produces it.
+var ErrFailpoint = errors.New("store: injected failpoint")
+
+// Failpoint names accepted by ArmFailpoint. Each one sits directly in
+// front of a write a real crash could interrupt: FailpointPreCheckpoint
+// fails the checkpoint write after a ledger's data rows have landed,
+// leaving the ledger indexed but unacknowledged, and FailpointMidUpserts
+// fails an offer upsert, leaving the ledger half-applied.
+const (
+	FailpointPreCheckpoint = "pre-checkpoint"
+	FailpointMidUpserts    = "mid-upserts"
+)
+
+var (
+	failpointMu sync.Mutex
+	armed       string
+)
+
+// ArmFailpoint makes the next write that reaches the named failpoint
+// return ErrFailpoint instead of executing. A failpoint fires once and
+// disarms itself; arming the empty name disarms without firing. Only the
+// replay command's crash simulation arms failpoints.
+func ArmFailpoint(name string) {
+	failpointMu.Lock()
+	armed = name
+	failpointMu.Unlock()
+}
+
+// failpoint reports whether name is armed, disarming it in the same
+// step so each armed failpoint fires exactly once.
+func failpoint(name string) bool {
+	failpointMu.Lock()
+	defer failpointMu.Unlock()
+	if armed != name {
+		return false
+	}
+	armed = ""
+	return true
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func TestFailpointFiresOnce(t *testing.T) {
+	ArmFailpoint(FailpointPreCheckpoint)
+	if failpoint(FailpointMidUpserts) {
+		t.Error("wrong failpoint fired")
+	}
+	if !failpoint(FailpointPreCheckpoint) {
+		t.Fatal("armed failpoint did not fire")
+	}
+	if failpoint(FailpointPreCheckpoint) {
+		t.Error("failpoint fired twice")
+	}
+
+	ArmFailpoint(FailpointMidUpserts)
+	ArmFailpoint("")
+	if failpoint(FailpointMidUpserts) {
+		t.Error("disarmed failpoint fired")
+	}
+}
+
+func TestReplayConvergesAfterInjectedCrash(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	mustExec(t, st, `DELETE FROM offers WHERE account = 'rCrashTest'`)
+	mustExec(t, st, `DELETE FROM offer_events WHERE account = 'rCrashTest'`)
+	mustExec(t, st, `DELETE FROM checkpoints WHERE ledger_index = 98001000`)
+	defer ArmFailpoint("")
+
+	offer := &model.Offer{
+		TxHash: "CRASHTX1", Account: "rCrashTest", Sequence: 31,
+		TakerGets:   xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "10"},
+		TakerPays:   xrpl.Amount{Currency: "XRP", Value: "5"},
+		Status:      model.OfferStatusOpen,
+		LedgerIndex: 98001000,
+	}
+	cp := &model.Checkpoint{LedgerIndex: 98001000, LedgerHash: "CRASHCP", CloseTime: 700006000}
+
+	// The crash: the offer lands, the checkpoint write is interrupted.
+	if err := st.UpsertOffer(ctx, offer); err != nil {
+		t.Fatal(err)
+	}
+	ArmFailpoint(FailpointPreCheckpoint)
+	if err := st.SaveCheckpoint(ctx, cp); !errors.Is(err, ErrFailpoint) {
+		t.Fatalf("armed checkpoint write returned %v, want ErrFailpoint", err)
+	}
+
+	// The recovery replay: the same writes run again and complete.
+	if err := st.UpsertOffer(ctx, offer); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SaveCheckpoint(ctx, cp); err != nil {
+		t.Fatal(err)
+	}
+	sums, err := st.TableChecksums(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second full replay over the recovered state changes nothing.
+	if err := st.UpsertOffer(ctx, offer); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SaveCheckpoint(ctx, cp); err != nil {
+		t.Fatal(err)
+	}
+	again, err := st.TableChecksums(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for table, sum := range sums {
+		if again[table] != sum {
+			t.Errorf("%s checksum drifted across replay: %s -> %s", table, sum, again[table])
+		}
+	}
+	if sums["offers"] == "empty" {
+		t.Error("offers checksum ignored the seeded row")
+	}
+
+	// The mid-upsert crash point interrupts the offer write itself.
+	ArmFailpoint(FailpointMidUpserts)
+	if err := st.UpsertOffer(ctx, offer); !errors.Is(err, ErrFailpoint) {
+		t.Fatalf("armed offer write returned %v, want ErrFailpoint", err)
+	}
+
+	mustExec(t, st, `DELETE FROM offers WHERE account = 'rCrashTest'`)
+	mustExec(t, st, `DELETE FROM offer_events WHERE account = 'rCrashTest'`)
+	mustExec(t, st, `DELETE FROM checkpoints WHERE ledger_index = 98001000`)
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// InsertNetworkFee records one fee command sample keyed by its ledger, so
+// re-polling the same open ledger refreshes the row instead of stacking
+// duplicates.
+func (s *Store) InsertNetworkFee(ctx context.Context, fee *xrpl.FeeInfo) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO fee_history (ledger_index, base_fee, open_ledger_fee, expected_ledger_size, load_factor)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (ledger_index) DO UPDATE SET
+			base_fee = EXCLUDED.base_fee,
+			open_ledger_fee = EXCLUDED.open_ledger_fee,
+			expected_ledger_size = EXCLUDED.expected_ledger_size,
+			load_factor = EXCLUDED.load_factor`,
+		fee.LedgerIndex, fee.BaseFee, fee.OpenLedgerFee, fee.ExpectedLedgerSize, fee.LoadFactor)
+	if err != nil {
+		return fmt.Errorf("store: insert network fee %d: %w", fee.LedgerIndex, err)
+	}
+	return nil
+}
+
+// GetLatestNetworkFee returns the newest fee sample, or nil when none has
+// been recorded yet.
+func (s *Store) GetLatestNetworkFee(ctx context.Context) (*xrpl.FeeInfo, error) {
+	var fee xrpl.FeeInfo
+	err := s.db.QueryRowContext(ctx, `
+		SELECT ledger_index, base_fee, open_ledger_fee, expected_ledger_size, load_factor
+		FROM fee_history ORDER BY ledger_index DESC LIMIT 1`).
+		Scan(&fee.LedgerIndex, &fee.BaseFee, &fee.OpenLedgerFee, &fee.ExpectedLedgerSize, &fee.LoadFactor)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: latest network fee: %w", err)
+	}
+	return &fee, nil
+}
+
+// GetNetworkFeeHistory returns fee samples with ledger index in
+// [from, to], oldest first. A zero to means no upper bound; a
+// non-positive limit defaults to 500.
+func (s *Store) GetNetworkFeeHistory(ctx context.Context, from, to uint64, limit int) ([]*xrpl.FeeInfo, error) {
+	if to == 0 {
+		to = math.MaxInt64
+	}
+	if limit <= 0 {
+		limit = 500
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT ledger_index, base_fee, open_ledger_fee, expected_ledger_size, load_factor
+		FROM fee_history
+		WHERE ledger_index >= $1 AND ledger_index <= $2
+		ORDER BY ledger_index
+		LIMIT $3`, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: network fee history: %w", err)
+	}
+	defer rows.Close()
+	var fees []*xrpl.FeeInfo
+	for rows.Next() {
+		var fee xrpl.FeeInfo
+		if err := rows.Scan(&fee.LedgerIndex, &fee.BaseFee, &fee.OpenLedgerFee,
+			&fee.ExpectedLedgerSize, &fee.LoadFactor); err != nil {
+			return nil, fmt.Errorf("store: scanning network fee: %w", err)
+		}
+		fees = append(fees, &fee)
+	}
+	return fees, rows.Err()
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RefreshFundedOffers re-derives the funded slice of the owner's open
+// offers in one asset from an absolute balance observed in metadata. Each
+// offer is capped independently at the balance (floored at zero), so the
+// funded depth of one owner with several offers in the same asset can
+// still sum past what they hold; unfunded marks an owner whose observed
+// balance was empty. The ledger guard keeps replays from moving the
+// funding check backwards.
+func (s *Store) RefreshFundedOffers(ctx context.Context, owner, currency, issuer, balance string, ledgerIndex uint64) error {
+	defer s.observeQuery("refresh funded offers", time.Now())
+	// The update touches an unknown set of the owner's offers, so the
+	// offer cache cannot invalidate by key; drop it wholesale.
+	if s.offerCache != nil {
+		s.offerCache.flush()
+	}
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE offers
+		SET funded_gets_value = LEAST(gets_value, GREATEST($4::numeric, 0)),
+		    unfunded = $4::numeric <= 0,
+		    funded_checked_ledger = $5
+		WHERE account = $1 AND gets_currency = $2 AND gets_issuer = $3
+		  AND status IN ('open', 'partially_filled')
+		  AND funded_checked_ledger <= $5`,
+		owner, currency, issuer, balance, ledgerIndex)
+	if err != nil {
+		return fmt.Errorf("store: refresh funded offers for %s: %w", owner, err)
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// recordOfferEvent appends one status transition to the offer's lifecycle
+// log, stamped with the ledger's close time and the transaction that
+// caused it. Replaying the same ledger is idempotent.
+func (s *Store) recordOfferEvent(ctx context.Context, account string, sequence uint32, status string, ledgerIndex uint64, closeTime int64, txHash string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO offer_events (account, sequence, status, ledger_index, close_time, tx_hash)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		ON CONFLICT (account, sequence, ledger_index, status) DO NOTHING`,
+		account, sequence, status, ledgerIndex, closeTime, txHash)
+	if err != nil {
+		return fmt.Errorf("store: record offer event %s/%d: %w", account, sequence, err)
+	}
+	return nil
+}
+
+// GetOfferHistory returns an account's offers whose last activity falls in
+// [from, to], ordered by ledger index, each with the status transitions it
+// went through. A zero `to` means no upper bound; a non-positive limit
+// defaults to 100. Terminal offers are kept rather than deleted, so the
+// history covers open, filled and cancelled states alike.
+func (s *Store) GetOfferHistory(ctx context.Context, account string, from, to uint64, limit int) ([]*model.OfferHistory, error) {
+	if to == 0 {
+		to = 1<<63 - 1
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT o.tx_hash, o.account, o.sequence, o.flags,
+			o.gets_currency, o.gets_issuer, o.gets_value,
+			o.pays_currency, o.pays_issuer, o.pays_value,
+			COALESCE(o.limit_price::text, ''), COALESCE(o.executed_price::text, ''),
+			o.status, o.ledger_index, COALESCE(o.replaced_by, 0), COALESCE(o.parse_error, ''),
+			COALESCE(o.funded_gets_value::text, ''), o.unfunded, o.funded_checked_ledger
+		FROM offers o
+		WHERE o.account = $1 AND o.ledger_index BETWEEN $2 AND $3
+		ORDER BY o.ledger_index, o.sequence
+		LIMIT $4`,
+		account, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: offer history %s: %w", account, err)
+	}
+	offers, err := func() ([]*model.Offer, error) {
+		defer rows.Close()
+		return scanOffers(rows)
+	}()
+	if err != nil {
+		return nil, err
+	}
+	if len(offers) == 0 {
+		return nil, nil
+	}
+
+	transitions := make(map[uint32][]model.StatusTransition, len(offers))
+	events, err := s.db.QueryContext(ctx, `
+		SELECT sequence, status, ledger_index, close_time, tx_hash FROM offer_events
+		WHERE account = $1 ORDER BY ledger_index`, account)
+	if err != nil {
+		return nil, fmt.Errorf("store: offer events %s: %w", account, err)
+	}
+	defer events.Close()
+	for events.Next() {
+		var sequence uint32
+		var tr model.StatusTransition
+		if err := events.Scan(&sequence, &tr.Status, &tr.LedgerIndex, &tr.CloseTime, &tr.TxHash); err != nil {
+			return nil, fmt.Errorf("store: scanning offer event: %w", err)
+		}
+		transitions[sequence] = append(transitions[sequence], tr)
+	}
+	if err := events.Err(); err != nil {
+		return nil, err
+	}
+
+	history := make([]*model.OfferHistory, len(offers))
+	for i, o := range offers {
+		history[i] = &model.OfferHistory{Offer: o, Transitions: transitions[o.Sequence]}
+	}
+	return history, nil
+}
+
+// decodeOfferCursor splits a "<ledger>-<sequence>" page cursor; the empty
+// cursor means start from the beginning.
+func decodeOfferCursor(cursor string) (uint64, uint32, error) {
+	if cursor == "" {
+		return 0, 0, nil
+	}
+	l, s, ok := strings.Cut(cursor, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("bad cursor %q", cursor)
+	}
+	ledger, err := strconv.ParseUint(l, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad cursor %q", cursor)
+	}
+	seq, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad cursor %q", cursor)
+	}
+	return ledger, uint32(seq), nil
+}
+
+// GetOffersByAccount returns one page of an account's offers, each with
+// the status transitions and fills (counterparty included) behind it.
+// status narrows the page to one status, "" keeps all; fromLedger drops
+// offers last touched before it. Pages follow the cursor echoed with the
+// previous one — keyset pagination over (ledger_index, sequence), which
+// seeks straight to the page where an OFFSET would rescan everything
+// before it. StatusTotals always covers the whole window, not the page.
+func (s *Store) GetOffersByAccount(ctx context.Context, account, status string, fromLedger uint64, cursor string, limit int) (*model.AccountOffers, error) {
+	curLedger, curSeq, err := decodeOfferCursor(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("store: offers by account: %w", err)
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	page := &model.AccountOffers{
+		Offers:       []*model.OfferHistory{},
+		StatusTotals: map[string]int64{},
+	}
+
+	totals, err := s.db.QueryContext(ctx, `
+		SELECT status, count(*) FROM offers
+		WHERE account = $1 AND ledger_index >= $2
+		GROUP BY status`, account, fromLedger)
+	if err != nil {
+		return nil, fmt.Errorf("store: offers by account %s: %w", account, err)
+	}
+	defer totals.Close()
+	for totals.Next() {
+		var st string
+		var n int64
+		if err := totals.Scan(&st, &n); err != nil {
+			return nil, fmt.Errorf("store: scanning status total: %w", err)
+		}
+		page.StatusTotals[st] = n
+	}
+	if err := totals.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT o.tx_hash, o.account, o.sequence, o.flags,
+			o.gets_currency, o.gets_issuer, o.gets_value,
+			o.pays_currency, o.pays_issuer, o.pays_value,
+			COALESCE(o.limit_price::text, ''), COALESCE(o.executed_price::text, ''),
+			o.status, o.ledger_index, COALESCE(o.replaced_by, 0), COALESCE(o.parse_error, ''),
+			COALESCE(o.funded_gets_value::text, ''), o.unfunded, o.funded_checked_ledger
+		FROM offers o
+		WHERE o.account = $1
+		  AND ($2 = '' OR o.status = $2)
+		  AND o.ledger_index >= $3
+		  AND (o.ledger_index, o.sequence) > ($4, $5)
+		ORDER BY o.ledger_index, o.sequence
+		LIMIT $6`,
+		account, status, fromLedger, curLedger, curSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: offers by account %s: %w", account, err)
+	}
+	offers, err := func() ([]*model.Offer, error) {
+		defer rows.Close()
+		return scanOffers(rows)
+	}()
+	if err != nil {
+		return nil, err
+	}
+	if len(offers) == 0 {
+		return page, nil
+	}
+
+	// Events and fills are fetched for the page's sequences only, so a
+	// busy account never drags its whole lifecycle log along.
+	args := make([]any, 0, len(offers)+1)
+	args = append(args, account)
+	placeholders := make([]string, len(offers))
+	for i, o := range offers {
+		placeholders[i] = "$" + strconv.Itoa(i+2)
+		args = append(args, int64(o.Sequence))
+	}
+	in := strings.Join(placeholders, ",")
+
+	transitions := make(map[uint32][]model.StatusTransition, len(offers))
+	events, err := s.db.QueryContext(ctx, `
+		SELECT sequence, status, ledger_index, close_time, tx_hash FROM offer_events
+		WHERE account = $1 AND sequence IN (`+in+`)
+		ORDER BY ledger_index`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("store: offer events %s: %w", account, err)
+	}
+	defer events.Close()
+	for events.Next() {
+		var sequence uint32
+		var tr model.StatusTransition
+		if err := events.Scan(&sequence, &tr.Status, &tr.LedgerIndex, &tr.CloseTime, &tr.TxHash); err != nil {
+			return nil, fmt.Errorf("store: scanning offer event: %w", err)
+		}
+		transitions[sequence] = append(transitions[sequence], tr)
+	}
+	if err := events.Err(); err != nil {
+		return nil, err
+	}
+
+	fills := make(map[uint32][]*model.Trade, len(offers))
+	trades, err := s.db.QueryContext(ctx, `
+		SELECT offer_sequence, taker,
+			gets_currency, gets_issuer, gets_value::text,
+			pays_currency, pays_issuer, pays_value::text,
+			COALESCE(price::text, ''), ledger_index, tx_hash
+		FROM trades
+		WHERE maker = $1 AND offer_sequence IN (`+in+`)
+		ORDER BY ledger_index, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("store: offer fills %s: %w", account, err)
+	}
+	defer trades.Close()
+	for trades.Next() {
+		t := model.Trade{Maker: account}
+		if err := trades.Scan(&t.OfferSequence, &t.Taker,
+			&t.Gets.Currency, &t.Gets.Issuer, &t.Gets.Value,
+			&t.Pays.Currency, &t.Pays.Issuer, &t.Pays.Value,
+			&t.Price, &t.LedgerIndex, &t.TxHash); err != nil {
+			return nil, fmt.Errorf("store: scanning fill: %w", err)
+		}
+		fills[t.OfferSequence] = append(fills[t.OfferSequence], &t)
+	}
+	if err := trades.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, o := range offers {
+		page.Offers = append(page.Offers, &model.OfferHistory{
+			Offer:       o,
+			Transitions: transitions[o.Sequence],
+			Fills:       fills[o.Sequence],
+		})
+	}
+	if len(offers) == limit {
+		last := offers[len(offers)-1]
+		page.NextCursor = fmt.Sprintf("%d-%d", last.LedgerIndex, last.Sequence)
+	}
+	return page, nil
+}
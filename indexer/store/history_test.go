@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func TestGetOfferHistoryLifecycle(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	const account = "rHistoryAccount"
+	for _, table := range []string{"offers", "offer_events"} {
+		if _, err := st.db.Exec(`DELETE FROM `+table+` WHERE account = $1`, account); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Created open at ledger 300.
+	offer := &model.Offer{
+		TxHash: "HIST1", Account: account, Sequence: 11,
+		TakerGets:   xrpl.Amount{Value: "100"},
+		TakerPays:   xrpl.Amount{Currency: "USD", Issuer: "rI", Value: "200"},
+		Status:      model.OfferStatusOpen,
+		LedgerIndex: 90000300, CloseTime: 700001000, ParserVersion: 1,
+	}
+	if err := st.UpsertOffer(ctx, offer); err != nil {
+		t.Fatal(err)
+	}
+	// Partially filled at ledger 310: still open, amounts shrink.
+	fill := &model.Trade{
+		Maker: account, Taker: "rTakerSide", OfferSequence: 11,
+		Gets:        xrpl.Amount{Value: "40"},
+		Pays:        xrpl.Amount{Currency: "USD", Issuer: "rI", Value: "80"},
+		LedgerIndex: 90000310, TxHash: "HIST2",
+	}
+	if err := st.UpdateOfferFill(ctx, fill, 700001010); err != nil {
+		t.Fatal(err)
+	}
+	// Cancelled at ledger 320.
+	if _, err := st.CancelOffer(ctx, account, 11, 90000320, 700001020, "HISTCANCEL"); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := st.GetOfferHistory(ctx, account, 0, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("history rows = %d, want 1", len(history))
+	}
+	entry := history[0]
+	if entry.Offer.Status != model.OfferStatusCancelled || entry.Offer.LedgerIndex != 90000320 {
+		t.Errorf("final offer = %s at %d", entry.Offer.Status, entry.Offer.LedgerIndex)
+	}
+	want := []model.StatusTransition{
+		{Status: model.OfferStatusOpen, LedgerIndex: 90000300, CloseTime: 700001000},
+		{Status: model.OfferStatusOpen, LedgerIndex: 90000310, CloseTime: 700001010},
+		{Status: model.OfferStatusCancelled, LedgerIndex: 90000320, CloseTime: 700001020},
+	}
+	if len(entry.Transitions) != len(want) {
+		t.Fatalf("transitions = %+v, want %+v", entry.Transitions, want)
+	}
+	for i, tr := range want {
+		if entry.Transitions[i] != tr {
+			t.Errorf("transition %d = %+v, want %+v", i, entry.Transitions[i], tr)
+		}
+	}
+
+	// A range that misses the offer's last activity returns nothing.
+	if history, err = st.GetOfferHistory(ctx, account, 90000000, 90000310, 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 0 {
+		t.Errorf("out-of-range query returned %d rows", len(history))
+	}
+}
+
+func TestGetOffersByAccountPaging(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	const account = "rPagingAccount"
+	for _, table := range []string{"offers", "offer_events"} {
+		if _, err := st.db.Exec(`DELETE FROM `+table+` WHERE account = $1`, account); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := st.db.Exec(`DELETE FROM trades WHERE maker = $1`, account); err != nil {
+		t.Fatal(err)
+	}
+
+	// Three offers; the first is fully consumed by one trade.
+	for i, seq := range []uint32{21, 22, 23} {
+		if err := st.UpsertOffer(ctx, &model.Offer{
+			TxHash: "PAGE" + string(rune('A'+i)), Account: account, Sequence: seq,
+			TakerGets:   xrpl.Amount{Value: "100"},
+			TakerPays:   xrpl.Amount{Currency: "USD", Issuer: "rI", Value: "200"},
+			Status:      model.OfferStatusOpen,
+			LedgerIndex: 90000400 + uint64(10*i), CloseTime: 700002000, ParserVersion: 1,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fill := &model.Trade{
+		Maker: account, Taker: "rTakerSide", OfferSequence: 21,
+		Gets:        xrpl.Amount{Value: "100"},
+		Pays:        xrpl.Amount{Currency: "USD", Issuer: "rI", Value: "200"},
+		LedgerIndex: 90000405, TxHash: "PAGEFILL",
+	}
+	if err := st.InsertTrade(ctx, fill); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.UpdateOfferFill(ctx, fill, 700002005); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := st.GetOffersByAccount(ctx, account, "", 0, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Offers) != 2 || page.Offers[0].Offer.Sequence != 21 || page.Offers[1].Offer.Sequence != 22 {
+		t.Fatalf("first page = %+v", page.Offers)
+	}
+	if page.NextCursor != "90000410-22" {
+		t.Errorf("next cursor = %q", page.NextCursor)
+	}
+	if page.StatusTotals[model.OfferStatusFilled] != 1 || page.StatusTotals[model.OfferStatusOpen] != 2 {
+		t.Errorf("status totals = %+v", page.StatusTotals)
+	}
+	first := page.Offers[0]
+	if first.Offer.Status != model.OfferStatusFilled || len(first.Transitions) != 2 {
+		t.Errorf("filled offer lifecycle = %s with %d transitions", first.Offer.Status, len(first.Transitions))
+	}
+	if len(first.Fills) != 1 || first.Fills[0].Taker != "rTakerSide" || first.Fills[0].Gets.Value != "100" {
+		t.Errorf("fills = %+v", first.Fills)
+	}
+
+	// The cursor resumes exactly after the last row of the previous page.
+	page, err = st.GetOffersByAccount(ctx, account, "", 0, page.NextCursor, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Offers) != 1 || page.Offers[0].Offer.Sequence != 23 || page.NextCursor != "" {
+		t.Fatalf("second page = %+v, cursor %q", page.Offers, page.NextCursor)
+	}
+
+	// A status filter narrows the page but never the totals.
+	page, err = st.GetOffersByAccount(ctx, account, model.OfferStatusFilled, 0, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Offers) != 1 || page.Offers[0].Offer.Sequence != 21 {
+		t.Fatalf("filtered page = %+v", page.Offers)
+	}
+	if page.StatusTotals[model.OfferStatusOpen] != 2 {
+		t.Errorf("filtered totals = %+v", page.StatusTotals)
+	}
+
+	if _, err := st.GetOffersByAccount(ctx, account, "", 0, "mangled", 10); err == nil {
+		t.Error("mangled cursor was accepted")
+	}
+}
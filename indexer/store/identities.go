@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// negativeIdentityTTL is how long a no_domain or unreachable result is
+// honored before the subject becomes due again, regardless of how short
+// the configured refresh interval is: dead domains are not worth
+// hammering more than daily.
+const negativeIdentityTTL = 24 * time.Hour
+
+// UpsertIdentity records one subject's resolved identity, stamping the
+// refresh time; re-resolving a subject overwrites its row.
+func (s *Store) UpsertIdentity(ctx context.Context, id *model.Identity) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO identities (account, domain, name, logo, twitter, status, refreshed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, now())
+		ON CONFLICT (account) DO UPDATE SET
+			domain = EXCLUDED.domain,
+			name = EXCLUDED.name,
+			logo = EXCLUDED.logo,
+			twitter = EXCLUDED.twitter,
+			status = EXCLUDED.status,
+			refreshed_at = EXCLUDED.refreshed_at`,
+		id.Account, id.Domain, id.Name, id.Logo, id.Twitter, id.Status)
+	if err != nil {
+		return fmt.Errorf("store: upsert identity %s: %w", id.Account, err)
+	}
+	return nil
+}
+
+// GetIdentities returns the cached identities of the given subjects,
+// keyed by subject. Subjects never enriched are simply absent from the
+// map.
+func (s *Store) GetIdentities(ctx context.Context, accounts []string) (map[string]*model.Identity, error) {
+	if len(accounts) == 0 {
+		return map[string]*model.Identity{}, nil
+	}
+	placeholders := make([]string, len(accounts))
+	args := make([]any, len(accounts))
+	for i, a := range accounts {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+		args[i] = a
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT account, domain, name, logo, twitter, status,
+			EXTRACT(EPOCH FROM refreshed_at)::bigint
+		FROM identities WHERE account IN (`+strings.Join(placeholders, ",")+`)`,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("store: identities: %w", err)
+	}
+	defer rows.Close()
+	ids := map[string]*model.Identity{}
+	for rows.Next() {
+		var id model.Identity
+		if err := rows.Scan(&id.Account, &id.Domain, &id.Name, &id.Logo,
+			&id.Twitter, &id.Status, &id.RefreshedAt); err != nil {
+			return nil, fmt.Errorf("store: scanning identity: %w", err)
+		}
+		ids[id.Account] = &id
+	}
+	return ids, rows.Err()
+}
+
+// IdentitySubjectsDue lists the subjects the enrichment job should
+// resolve next: every distinct issuer seen in offers or pools and every
+// validator master key, minus those with a fresh enough identity row.
+// Resolved identities go stale after refreshAfter; negative results keep
+// their daily floor. Never-resolved subjects sort first.
+func (s *Store) IdentitySubjectsDue(ctx context.Context, refreshAfter time.Duration, limit int) ([]string, error) {
+	negative := refreshAfter
+	if negative < negativeIdentityTTL {
+		negative = negativeIdentityTTL
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		WITH subjects(account) AS (
+			SELECT DISTINCT gets_issuer FROM offers WHERE gets_issuer <> ''
+			UNION SELECT DISTINCT pays_issuer FROM offers WHERE pays_issuer <> ''
+			UNION SELECT DISTINCT asset1_issuer FROM pools WHERE asset1_issuer <> ''
+			UNION SELECT DISTINCT asset2_issuer FROM pools WHERE asset2_issuer <> ''
+			UNION SELECT DISTINCT validator FROM validations
+		)
+		SELECT s.account FROM subjects s
+		LEFT JOIN identities i ON i.account = s.account
+		WHERE i.account IS NULL
+			OR (i.status IN ($3, $4) AND i.refreshed_at < now() - make_interval(secs => $1))
+			OR (i.status IN ($5, $6) AND i.refreshed_at < now() - make_interval(secs => $2))
+		ORDER BY i.refreshed_at ASC NULLS FIRST, s.account
+		LIMIT $7`,
+		refreshAfter.Seconds(), negative.Seconds(),
+		model.IdentityVerified, model.IdentityUnverified,
+		model.IdentityNoDomain, model.IdentityUnreachable,
+		limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: identity subjects due: %w", err)
+	}
+	defer rows.Close()
+	var subjects []string
+	for rows.Next() {
+		var account string
+		if err := rows.Scan(&account); err != nil {
+			return nil, fmt.Errorf("store: scanning identity subject: %w", err)
+		}
+		subjects = append(subjects, account)
+	}
+	return subjects, rows.Err()
+}
+
+// InvalidateIdentity drops a subject's cached identity; the next
+// enrichment sweep resolves it afresh. The pipeline calls this when an
+// AccountSet changes the subject's Domain on-ledger.
+func (s *Store) InvalidateIdentity(ctx context.Context, account string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM identities WHERE account = $1`, account)
+	if err != nil {
+		return fmt.Errorf("store: invalidate identity %s: %w", account, err)
+	}
+	return nil
+}
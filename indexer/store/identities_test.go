@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func TestIdentities(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	mustExec(t, st, `DELETE FROM identities WHERE account LIKE 'rIdent%' OR account LIKE 'nHIdent%'`)
+	mustExec(t, st, `DELETE FROM offers WHERE account = 'rIdentMaker'`)
+	mustExec(t, st, `DELETE FROM validations WHERE validator = 'nHIdentVal'`)
+
+	// Subjects come from indexed state: an offer's IOU leg and a
+	// validator key.
+	if err := st.UpsertOffer(ctx, &model.Offer{
+		TxHash: "IDENT1", Account: "rIdentMaker", Sequence: 1,
+		TakerGets: xrpl.Amount{Currency: "USD", Issuer: "rIdentIssuer", Value: "10"},
+		TakerPays: xrpl.Amount{Value: "5"},
+		Status:    model.OfferStatusOpen, LedgerIndex: 90005000,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.InsertValidation(ctx, &model.Validation{
+		Validator: "nHIdentVal", LedgerIndex: 90005000, LedgerHash: "IDENTHASH", SigningTime: 700020000,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	due, err := st.IdentitySubjectsDue(ctx, time.Hour, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"rIdentIssuer": true, "nHIdentVal": true}
+	for _, s := range due {
+		delete(want, s)
+	}
+	if len(want) != 0 {
+		t.Fatalf("due list %v missing %v", due, want)
+	}
+
+	if err := st.UpsertIdentity(ctx, &model.Identity{
+		Account: "rIdentIssuer", Domain: "ident.example", Name: "Ident Labs",
+		Logo: "https://ident.example/logo.png", Twitter: "identlabs",
+		Status: model.IdentityVerified,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.UpsertIdentity(ctx, &model.Identity{
+		Account: "nHIdentVal", Domain: "dead.example", Status: model.IdentityUnreachable,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := st.GetIdentities(ctx, []string{"rIdentIssuer", "nHIdentVal", "rIdentNever"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("identities = %v, want 2", ids)
+	}
+	if id := ids["rIdentIssuer"]; id.Name != "Ident Labs" || id.Status != model.IdentityVerified ||
+		id.Twitter != "identlabs" || id.RefreshedAt == 0 {
+		t.Errorf("rIdentIssuer = %+v", id)
+	}
+	if id := ids["nHIdentVal"]; id.Status != model.IdentityUnreachable {
+		t.Errorf("nHIdentVal = %+v", id)
+	}
+
+	// However short the interval, the negative-cached row is protected
+	// by its daily floor; the fresh verified row is simply fresh.
+	due, err = st.IdentitySubjectsDue(ctx, time.Hour, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range due {
+		if s == "rIdentIssuer" || s == "nHIdentVal" {
+			t.Errorf("fresh subject %s reported due", s)
+		}
+	}
+
+	// Backdating past the threshold makes the verified row due again.
+	mustExec(t, st, `UPDATE identities SET refreshed_at = now() - interval '2 hours' WHERE account = 'rIdentIssuer'`)
+	due, err = st.IdentitySubjectsDue(ctx, time.Hour, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, s := range due {
+		found = found || s == "rIdentIssuer"
+	}
+	if !found {
+		t.Errorf("stale subject not due: %v", due)
+	}
+
+	// Invalidation (an on-ledger Domain change) drops the row entirely.
+	if err := st.InvalidateIdentity(ctx, "rIdentIssuer"); err != nil {
+		t.Fatal(err)
+	}
+	ids, err = st.GetIdentities(ctx, []string{"rIdentIssuer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 0 {
+		t.Errorf("identity survived invalidation: %v", ids)
+	}
+
+	mustExec(t, st, `DELETE FROM identities WHERE account LIKE 'rIdent%' OR account LIKE 'nHIdent%'`)
+	mustExec(t, st, `DELETE FROM offers WHERE account = 'rIdentMaker'`)
+	mustExec(t, st, `DELETE FROM validations WHERE validator = 'nHIdentVal'`)
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// leaderLockKey is the advisory lock id all indexer replicas contend on;
+// it spells "lucendex" in hex so it is recognizable in pg_locks.
+const leaderLockKey int64 = 0x6C7563656E646578
+
+// TryAcquireLeaderLock attempts to become the leader via a Postgres
+// advisory lock. Advisory locks are session-scoped, so the lock is held
+// on a dedicated pooled connection that stays pinned until release; if
+// the leader's connection dies, Postgres frees the lock and a standby's
+// next retry succeeds.
+func (s *Store) TryAcquireLeaderLock(ctx context.Context) (bool, error) {
+	if s.leaderConn != nil {
+		return true, nil
+	}
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("store: leader lock: %w", err)
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx,
+		`SELECT pg_try_advisory_lock($1)`, leaderLockKey).Scan(&acquired); err != nil {
+		conn.Close()
+		return false, fmt.Errorf("store: leader lock: %w", err)
+	}
+	if !acquired {
+		conn.Close()
+		return false, nil
+	}
+	s.leaderConn = conn
+	return true, nil
+}
+
+// ReleaseLeaderLock gives up leadership; a no-op when not the leader.
+func (s *Store) ReleaseLeaderLock(ctx context.Context) error {
+	if s.leaderConn == nil {
+		return nil
+	}
+	_, err := s.leaderConn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, leaderLockKey)
+	closeErr := s.leaderConn.Close()
+	s.leaderConn = nil
+	if err != nil {
+		return fmt.Errorf("store: release leader lock: %w", err)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("store: release leader lock: %w", closeErr)
+	}
+	return nil
+}
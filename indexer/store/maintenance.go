@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// Database upkeep invoked by the maintenance scheduler in cmd/indexer.
+// Everything here must be safe to run while the pipeline writes.
+
+// maintainedTables are the tables that churn enough to accumulate dead
+// rows and stale planner statistics under continuous ingestion.
+var maintainedTables = []string{
+	"checkpoints", "offers", "offer_events", "trades", "pools",
+	"outbox", "connections", "connection_events",
+}
+
+// VacuumAnalyze runs VACUUM (ANALYZE) over the hot tables, reclaiming
+// dead row versions and refreshing statistics. VACUUM cannot run inside
+// a transaction block, so each table gets its own plain statement.
+func (s *Store) VacuumAnalyze(ctx context.Context) error {
+	for _, table := range maintainedTables {
+		if _, err := s.db.ExecContext(ctx, "VACUUM (ANALYZE) "+table); err != nil {
+			return fmt.Errorf("store: vacuum %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// DeleteOffersBefore prunes offers that reached a terminal status in a
+// ledger before cutoffLedger, returning how many rows went. onlyClosed
+// restricts the sweep to cancelled and fully filled offers; with it
+// false the other dead-end statuses — expired, removed_unfunded and
+// invalid_parse — go too. Open, partially filled and snapshot offers
+// are live order-book state and are never deleted, whatever the cutoff,
+// so a misconfigured retention cannot eat the book. The offer_events
+// audit trail is untouched either way.
+func (s *Store) DeleteOffersBefore(ctx context.Context, cutoffLedger uint64, onlyClosed bool) (int64, error) {
+	statuses := []string{model.OfferStatusCancelled, model.OfferStatusFilled}
+	if !onlyClosed {
+		statuses = append(statuses,
+			model.OfferStatusExpired, model.OfferStatusRemovedUnfunded, model.OfferStatusInvalidParse)
+	}
+	placeholders := make([]string, len(statuses))
+	args := []any{cutoffLedger}
+	for i, status := range statuses {
+		placeholders[i] = "$" + strconv.Itoa(i+2)
+		args = append(args, status)
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		DELETE FROM offers
+		WHERE ledger_index < $1 AND status IN (`+strings.Join(placeholders, ",")+`)
+		RETURNING account, sequence`,
+		args...)
+	if err != nil {
+		return 0, fmt.Errorf("store: delete offers before %d: %w", cutoffLedger, err)
+	}
+	defer rows.Close()
+	// The lifecycle cache may still hold rows closed long ago; evicting
+	// what was deleted keeps cache and table telling the same story.
+	var n int64
+	for rows.Next() {
+		var account string
+		var sequence uint32
+		if err := rows.Scan(&account, &sequence); err != nil {
+			return n, fmt.Errorf("store: delete offers before %d: %w", cutoffLedger, err)
+		}
+		s.evictOffer(account, sequence)
+		n++
+	}
+	return n, rows.Err()
+}
+
+// PruneConnectionAudit deletes connection-audit rows older than keep,
+// returning how many went. The audit trail is operational history, not
+// ledger data; it only needs to outlive any incident investigation.
+func (s *Store) PruneConnectionAudit(ctx context.Context, keep time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-keep)
+	var total int64
+	for _, q := range []string{
+		`DELETE FROM connections WHERE connected_at < $1`,
+		`DELETE FROM connection_events WHERE recorded_at < $1`,
+	} {
+		res, err := s.db.ExecContext(ctx, q, cutoff)
+		if err != nil {
+			return total, fmt.Errorf("store: prune connection audit: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	return total, nil
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func TestDeleteOffersBefore(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	mustExec(t, st, `DELETE FROM offers WHERE account = 'rPruneMaker'`)
+
+	// A mix of statuses straddling the cutoff at 90006100. Only the
+	// terminal rows on the old side may ever go.
+	seed := []struct {
+		seq    uint32
+		status string
+		ledger uint64
+	}{
+		{1, model.OfferStatusOpen, 90006000},
+		{2, model.OfferStatusPartiallyFilled, 90006000},
+		{3, model.OfferStatusSnapshot, 90006000},
+		{4, model.OfferStatusCancelled, 90006000},
+		{5, model.OfferStatusFilled, 90006000},
+		{6, model.OfferStatusExpired, 90006000},
+		{7, model.OfferStatusCancelled, 90006200},
+		{8, model.OfferStatusFilled, 90006200},
+	}
+	for _, o := range seed {
+		if err := st.UpsertOffer(ctx, &model.Offer{
+			TxHash: fmt.Sprintf("PRUNE%d", o.seq), Account: "rPruneMaker", Sequence: o.seq,
+			TakerGets: xrpl.Amount{Currency: "USD", Issuer: "rPruneIssuer", Value: "10"},
+			TakerPays: xrpl.Amount{Value: "5"},
+			Status:    o.status, LedgerIndex: o.ledger, ParserVersion: 1,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	remaining := func() map[uint32]bool {
+		t.Helper()
+		rows, err := st.db.Query(`SELECT sequence FROM offers WHERE account = 'rPruneMaker'`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		got := map[uint32]bool{}
+		for rows.Next() {
+			var seq uint32
+			if err := rows.Scan(&seq); err != nil {
+				t.Fatal(err)
+			}
+			got[seq] = true
+		}
+		return got
+	}
+
+	n, err := st.DeleteOffersBefore(ctx, 90006100, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("onlyClosed removed %d rows, want 2", n)
+	}
+	got := remaining()
+	for _, seq := range []uint32{1, 2, 3, 6, 7, 8} {
+		if !got[seq] {
+			t.Errorf("sequence %d deleted, want kept", seq)
+		}
+	}
+	for _, seq := range []uint32{4, 5} {
+		if got[seq] {
+			t.Errorf("sequence %d kept, want deleted", seq)
+		}
+	}
+
+	// Without onlyClosed the old expired row goes too; the live rows and
+	// everything at or past the cutoff still stay.
+	n, err = st.DeleteOffersBefore(ctx, 90006100, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("full sweep removed %d rows, want 1", n)
+	}
+	got = remaining()
+	for _, seq := range []uint32{1, 2, 3, 7, 8} {
+		if !got[seq] {
+			t.Errorf("sequence %d deleted by full sweep, want kept", seq)
+		}
+	}
+
+	mustExec(t, st, `DELETE FROM offers WHERE account = 'rPruneMaker'`)
+}
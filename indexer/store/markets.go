@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// TrackMarket registers a discovered market, reporting whether it was
+// promoted by this call — either brand new or previously demoted. A pair
+// that is already actively tracked is left untouched, so the caller's
+// one-time promotion work (book snapshot, subscription) fires exactly
+// once per promotion.
+func (s *Store) TrackMarket(ctx context.Context, m *model.TrackedMarket) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO tracked_markets
+			(pair, discovered_ledger, discovered_close_time,
+			 last_active_ledger, last_active_close_time)
+		VALUES ($1,$2,$3,$4,$5)
+		ON CONFLICT (pair) DO UPDATE SET
+			demoted = FALSE,
+			last_active_ledger = EXCLUDED.last_active_ledger,
+			last_active_close_time = EXCLUDED.last_active_close_time
+		WHERE tracked_markets.demoted`,
+		m.Pair, m.DiscoveredLedger, m.DiscoveredCloseTime,
+		m.LastActiveLedger, m.LastActiveCloseTime)
+	if err != nil {
+		return false, fmt.Errorf("store: track market %s: %w", m.Pair, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("store: track market %s: %w", m.Pair, err)
+	}
+	return n > 0, nil
+}
+
+// TouchMarkets advances the activity columns of actively tracked pairs.
+// Demoted pairs are deliberately skipped: trickle activity must not undo
+// a demotion — only crossing the discovery threshold again does, through
+// TrackMarket.
+func (s *Store) TouchMarkets(ctx context.Context, pairs []string, ledgerIndex uint64, closeTime int64) error {
+	if len(pairs) == 0 {
+		return nil
+	}
+	placeholders := make([]string, len(pairs))
+	args := make([]any, 0, len(pairs)+2)
+	args = append(args, ledgerIndex, closeTime)
+	for i, pair := range pairs {
+		placeholders[i] = "$" + strconv.Itoa(i+3)
+		args = append(args, pair)
+	}
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE tracked_markets SET
+			last_active_ledger = GREATEST(last_active_ledger, $1),
+			last_active_close_time = GREATEST(last_active_close_time, $2)
+		WHERE NOT demoted AND pair IN (`+strings.Join(placeholders, ",")+`)`, args...)
+	if err != nil {
+		return fmt.Errorf("store: touch markets: %w", err)
+	}
+	return nil
+}
+
+// DemoteInactiveMarkets demotes every actively tracked pair whose last
+// activity predates the cutoff close time, returning the demoted pairs
+// so the caller can drop them from its promotion cache.
+func (s *Store) DemoteInactiveMarkets(ctx context.Context, cutoffCloseTime int64) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE tracked_markets SET demoted = TRUE
+		WHERE NOT demoted AND last_active_close_time < $1
+		RETURNING pair`, cutoffCloseTime)
+	if err != nil {
+		return nil, fmt.Errorf("store: demote markets: %w", err)
+	}
+	defer rows.Close()
+	var pairs []string
+	for rows.Next() {
+		var pair string
+		if err := rows.Scan(&pair); err != nil {
+			return nil, fmt.Errorf("store: scanning demoted market: %w", err)
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, rows.Err()
+}
+
+// ListTrackedMarkets returns every market discovery has ever promoted,
+// demoted ones included, ordered by pair.
+func (s *Store) ListTrackedMarkets(ctx context.Context) ([]*model.TrackedMarket, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT pair, discovered_ledger, discovered_close_time,
+			last_active_ledger, last_active_close_time, demoted
+		FROM tracked_markets ORDER BY pair`)
+	if err != nil {
+		return nil, fmt.Errorf("store: list tracked markets: %w", err)
+	}
+	defer rows.Close()
+	var markets []*model.TrackedMarket
+	for rows.Next() {
+		var m model.TrackedMarket
+		if err := rows.Scan(&m.Pair, &m.DiscoveredLedger, &m.DiscoveredCloseTime,
+			&m.LastActiveLedger, &m.LastActiveCloseTime, &m.Demoted); err != nil {
+			return nil, fmt.Errorf("store: scanning tracked market: %w", err)
+		}
+		markets = append(markets, &m)
+	}
+	return markets, rows.Err()
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+func TestTrackedMarketLifecycle(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	mustExec(t, st, `DELETE FROM tracked_markets WHERE pair LIKE 'TRK.%'`)
+
+	m := &model.TrackedMarket{
+		Pair:                "TRK.rIss/XRP",
+		DiscoveredLedger:    98002000,
+		DiscoveredCloseTime: 700009000,
+		LastActiveLedger:    98002000,
+		LastActiveCloseTime: 700009000,
+	}
+	promoted, err := st.TrackMarket(ctx, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !promoted {
+		t.Fatal("first TrackMarket did not promote")
+	}
+	// Tracking an already-active pair is a no-op, not a re-promotion.
+	if promoted, err = st.TrackMarket(ctx, m); err != nil {
+		t.Fatal(err)
+	} else if promoted {
+		t.Error("second TrackMarket re-promoted an active pair")
+	}
+
+	// Touch advances activity; demotion then spares the touched pair.
+	if err := st.TouchMarkets(ctx, []string{"TRK.rIss/XRP"}, 98002050, 700009200); err != nil {
+		t.Fatal(err)
+	}
+	demoted, err := st.DemoteInactiveMarkets(ctx, 700009100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pair := range demoted {
+		if pair == "TRK.rIss/XRP" {
+			t.Error("recently touched market was demoted")
+		}
+	}
+
+	// A later cutoff demotes it; trickle touches must not revive it.
+	if demoted, err = st.DemoteInactiveMarkets(ctx, 700009300); err != nil {
+		t.Fatal(err)
+	} else if len(demoted) != 1 || demoted[0] != "TRK.rIss/XRP" {
+		t.Fatalf("demoted = %v", demoted)
+	}
+	if err := st.TouchMarkets(ctx, []string{"TRK.rIss/XRP"}, 98002060, 700009400); err != nil {
+		t.Fatal(err)
+	}
+	markets, err := st.ListTrackedMarkets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got *model.TrackedMarket
+	for _, tm := range markets {
+		if tm.Pair == "TRK.rIss/XRP" {
+			got = tm
+		}
+	}
+	if got == nil || !got.Demoted {
+		t.Fatalf("market = %+v, want still demoted after touch", got)
+	}
+	if got.LastActiveLedger != 98002050 {
+		t.Errorf("demoted market's activity moved: %+v", got)
+	}
+
+	// Only TrackMarket re-promotes, keeping the original discovery row.
+	if promoted, err = st.TrackMarket(ctx, &model.TrackedMarket{
+		Pair:                "TRK.rIss/XRP",
+		DiscoveredLedger:    98002100,
+		DiscoveredCloseTime: 700009500,
+		LastActiveLedger:    98002100,
+		LastActiveCloseTime: 700009500,
+	}); err != nil {
+		t.Fatal(err)
+	} else if !promoted {
+		t.Fatal("TrackMarket did not re-promote a demoted pair")
+	}
+	if markets, err = st.ListTrackedMarkets(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, tm := range markets {
+		if tm.Pair != "TRK.rIss/XRP" {
+			continue
+		}
+		if tm.Demoted || tm.DiscoveredLedger != 98002000 || tm.LastActiveLedger != 98002100 {
+			t.Errorf("re-promoted market = %+v", tm)
+		}
+	}
+
+	mustExec(t, st, `DELETE FROM tracked_markets WHERE pair LIKE 'TRK.%'`)
+}
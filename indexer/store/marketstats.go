@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// ApplyMarketStats folds one ledger's activity on one market into its
+// hourly bucket. The last_ledger guard makes the fold idempotent:
+// replaying a ledger after a crash finds its contribution already
+// recorded and changes nothing.
+func (s *Store) ApplyMarketStats(ctx context.Context, u *model.MarketStatsUpdate) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO market_stats_hourly
+			(pair, bucket, volume, high, low, last_price, last_ledger, trade_count)
+		VALUES ($1, $2, $3::numeric, $4::numeric, $5::numeric, $6::numeric, $7, $8)
+		ON CONFLICT (pair, bucket) DO UPDATE SET
+			volume = market_stats_hourly.volume + EXCLUDED.volume,
+			high = GREATEST(market_stats_hourly.high, EXCLUDED.high),
+			low = LEAST(market_stats_hourly.low, EXCLUDED.low),
+			last_price = COALESCE(EXCLUDED.last_price, market_stats_hourly.last_price),
+			last_ledger = EXCLUDED.last_ledger,
+			trade_count = market_stats_hourly.trade_count + EXCLUDED.trade_count
+		WHERE market_stats_hourly.last_ledger < EXCLUDED.last_ledger`,
+		u.Pair, u.Bucket, u.Volume, nullable(u.High), nullable(u.Low),
+		nullable(u.Last), u.LedgerIndex, u.TradeCount)
+	if err != nil {
+		return fmt.Errorf("store: market stats %s: %w", u.Pair, err)
+	}
+	return nil
+}
+
+// windowAnchor bounds the rolling window: buckets after it are inside
+// the last 24 hours of indexed time. The anchor is the latest
+// checkpointed close time — the data's clock, not the wall clock — so a
+// catching-up indexer reports the 24 hours ending at what it has
+// actually indexed.
+const windowAnchor = `(SELECT COALESCE(MAX(close_time), 0) / 3600 FROM checkpoints) - 24`
+
+// GetMarketStats returns the 24h rollup for one market. A market with
+// no bucket in the window comes back all-zero, not nil — absence of
+// trades is a statistic too.
+func (s *Store) GetMarketStats(ctx context.Context, pair string) (*model.MarketStats, error) {
+	base, quote, ok := strings.Cut(pair, "/")
+	if !ok {
+		return nil, fmt.Errorf("store: market stats: pair %q must be two asset keys joined by /", pair)
+	}
+	for _, key := range []string{base, quote} {
+		if _, err := model.ParseAssetKey(key); err != nil {
+			return nil, fmt.Errorf("store: market stats: %w", err)
+		}
+	}
+	st := &model.MarketStats{Pair: pair}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(volume), 0)::text,
+			COALESCE(MAX(high)::text, ''), COALESCE(MIN(low)::text, ''),
+			COALESCE((array_agg(last_price ORDER BY bucket DESC)
+				FILTER (WHERE last_price IS NOT NULL))[1]::text, ''),
+			COALESCE(SUM(trade_count), 0)
+		FROM market_stats_hourly
+		WHERE pair = $1 AND bucket > `+windowAnchor,
+		pair).Scan(&st.Volume, &st.High, &st.Low, &st.LastPrice, &st.TradeCount)
+	if err != nil {
+		return nil, fmt.Errorf("store: market stats %s: %w", pair, err)
+	}
+	return st, nil
+}
+
+// ListMarketStats returns every market's 24h rollup, busiest first.
+// Markets with zero window activity are still listed when they have an
+// open offer or a pool, so discovery surfaces quiet pairs too.
+func (s *Store) ListMarketStats(ctx context.Context) ([]*model.MarketStats, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		WITH stats AS (
+			SELECT pair, SUM(volume) AS volume, MAX(high) AS high, MIN(low) AS low,
+				(array_agg(last_price ORDER BY bucket DESC)
+					FILTER (WHERE last_price IS NOT NULL))[1] AS last_price,
+				SUM(trade_count) AS trade_count
+			FROM market_stats_hourly
+			WHERE bucket > `+windowAnchor+`
+			GROUP BY pair
+		), listed AS (
+			SELECT DISTINCT LEAST(a, b) || '/' || GREATEST(a, b) AS pair FROM (
+				SELECT CASE WHEN gets_currency = 'XRP' AND gets_issuer = ''
+						THEN 'XRP' ELSE gets_currency || '.' || gets_issuer END AS a,
+					CASE WHEN pays_currency = 'XRP' AND pays_issuer = ''
+						THEN 'XRP' ELSE pays_currency || '.' || pays_issuer END AS b
+				FROM offers WHERE status IN `+activeStatuses+`
+				UNION ALL
+				SELECT CASE WHEN asset1_currency = 'XRP' AND asset1_issuer = ''
+						THEN 'XRP' ELSE asset1_currency || '.' || asset1_issuer END,
+					CASE WHEN asset2_currency = 'XRP' AND asset2_issuer = ''
+						THEN 'XRP' ELSE asset2_currency || '.' || asset2_issuer END
+				FROM pools
+			) legs
+		)
+		SELECT p.pair, COALESCE(s.volume, 0)::text,
+			COALESCE(s.high::text, ''), COALESCE(s.low::text, ''),
+			COALESCE(s.last_price::text, ''), COALESCE(s.trade_count, 0)
+		FROM (SELECT pair FROM listed UNION SELECT pair FROM stats) p
+		LEFT JOIN stats s ON s.pair = p.pair
+		ORDER BY COALESCE(s.volume, 0) DESC, p.pair`)
+	if err != nil {
+		return nil, fmt.Errorf("store: listing markets: %w", err)
+	}
+	defer rows.Close()
+	var markets []*model.MarketStats
+	for rows.Next() {
+		var st model.MarketStats
+		if err := rows.Scan(&st.Pair, &st.Volume, &st.High, &st.Low,
+			&st.LastPrice, &st.TradeCount); err != nil {
+			return nil, fmt.Errorf("store: scanning market: %w", err)
+		}
+		markets = append(markets, &st)
+	}
+	return markets, rows.Err()
+}
@@ -0,0 +1,831 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/balancechanges"
+	"github.com/2pk03/lucendex-validator-verification/indexer/internal/faultinject"
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// MemoryStore keeps indexed state in maps, mirroring the SQL store's write
+// contract — the natural keys, upsert convergence, and lifecycle guards —
+// without a database. Tests, demos and dry runs use it to exercise the
+// pipeline end-to-end deterministically. All methods are safe for
+// concurrent use.
+type MemoryStore struct {
+	mu sync.Mutex
+
+	offers      map[offerKey]*model.Offer
+	trades      []*model.Trade
+	tradeSeen   map[tradeFillKey]bool
+	marketStats map[marketBucketKey]*model.MarketStatsUpdate
+	outbox      []*model.OutboxEvent
+	outboxSeen  map[outboxSeqKey]bool
+	nftOffers   map[string]*model.NFTOffer
+	nftTrades   map[string]*model.NFTTrade
+	trustLines  map[trustLineKey]*model.TrustLine
+	pools       map[string]*model.Pool
+	checkpoints map[uint64]*model.Checkpoint
+	amendments  map[string]*model.Amendment
+	flags       map[string]bool
+	raw         map[string]*xrpl.Transaction
+	balances    map[string]balancechanges.Record
+	failed      map[uint64]string
+	identities  map[string]*model.Identity
+}
+
+type offerKey struct {
+	account  string
+	sequence uint32
+}
+
+type tradeFillKey struct {
+	txHash   string
+	maker    string
+	sequence uint32
+}
+
+type trustLineKey struct {
+	account  string
+	currency string
+	issuer   string
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		offers:      map[offerKey]*model.Offer{},
+		tradeSeen:   map[tradeFillKey]bool{},
+		marketStats: map[marketBucketKey]*model.MarketStatsUpdate{},
+		outboxSeen:  map[outboxSeqKey]bool{},
+		nftOffers:   map[string]*model.NFTOffer{},
+		nftTrades:   map[string]*model.NFTTrade{},
+		trustLines:  map[trustLineKey]*model.TrustLine{},
+		pools:       map[string]*model.Pool{},
+		checkpoints: map[uint64]*model.Checkpoint{},
+		amendments:  map[string]*model.Amendment{},
+		flags:       map[string]bool{},
+		raw:         map[string]*xrpl.Transaction{},
+		balances:    map[string]balancechanges.Record{},
+		failed:      map[uint64]string{},
+		identities:  map[string]*model.Identity{},
+	}
+}
+
+// UpsertOffer applies the same conflict rules as the SQL upsert: offers
+// converge on (account, sequence), an older parser version never clobbers
+// a newer one, and an out-of-order replay can't move a row backwards past
+// the lifecycle guards.
+func (m *MemoryStore) UpsertOffer(_ context.Context, o *model.Offer) error {
+	// The same fault point as the SQL store, so resilience scenarios can
+	// run against the in-memory double.
+	if faultinject.Fire(faultinject.UpsertError) {
+		return fmt.Errorf("store: upsert offer %s/%d: %w", o.Account, o.Sequence, faultinject.ErrInjected)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := offerKey{o.Account, o.Sequence}
+	cur, ok := m.offers[key]
+	if ok {
+		if cur.ParserVersion > o.ParserVersion {
+			return nil
+		}
+		if isTerminal(cur.Status) && o.LedgerIndex < cur.LedgerIndex {
+			return nil
+		}
+		if cur.Status == model.OfferStatusPartiallyFilled &&
+			(o.Status == model.OfferStatusOpen || o.Status == model.OfferStatusSnapshot) &&
+			o.LedgerIndex < cur.LedgerIndex {
+			return nil
+		}
+	}
+	next := *o
+	if ok {
+		next.ReplacedBy = cur.ReplacedBy
+		if cur.LedgerIndex > next.LedgerIndex {
+			next.LedgerIndex = cur.LedgerIndex
+		}
+	}
+	m.offers[key] = &next
+	return nil
+}
+
+// CancelOffer matches the SQL contract: unknown offers and cancels older
+// than the row's last activity are no-ops, terminal rows never move. The
+// bool reports whether a row actually moved.
+func (m *MemoryStore) CancelOffer(_ context.Context, account string, sequence uint32, ledgerIndex uint64, closeTime int64, txHash string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cancelLocked(account, sequence, 0, ledgerIndex), nil
+}
+
+// MarkOfferReplaced cancels the superseded offer and records its
+// replacement, with CancelOffer's guards.
+func (m *MemoryStore) MarkOfferReplaced(_ context.Context, account string, sequence, replacedBy uint32, ledgerIndex uint64, closeTime int64, txHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cancelLocked(account, sequence, replacedBy, ledgerIndex)
+	return nil
+}
+
+func (m *MemoryStore) cancelLocked(account string, sequence, replacedBy uint32, ledgerIndex uint64) bool {
+	cur, ok := m.offers[offerKey{account, sequence}]
+	if !ok || !isActive(cur.Status) || cur.LedgerIndex > ledgerIndex {
+		return false
+	}
+	cur.Status = model.OfferStatusCancelled
+	cur.LedgerIndex = ledgerIndex
+	if replacedBy != 0 {
+		cur.ReplacedBy = replacedBy
+	}
+	return true
+}
+
+// InsertTrade appends the fill, dropping redelivered ones on the same
+// (tx_hash, maker, offer_sequence) key the SQL constraint enforces.
+func (m *MemoryStore) InsertTrade(_ context.Context, t *model.Trade) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := tradeFillKey{t.TxHash, t.Maker, t.OfferSequence}
+	if m.tradeSeen[key] {
+		return nil
+	}
+	m.tradeSeen[key] = true
+	cp := *t
+	m.trades = append(m.trades, &cp)
+	return nil
+}
+
+// UpdateOfferFill shrinks the maker's resting offer like the SQL path:
+// remaining amounts clamp at zero, a fully consumed offer moves to filled,
+// unknown or out-of-order fills are no-ops.
+func (m *MemoryStore) UpdateOfferFill(_ context.Context, t *model.Trade, closeTime int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur, ok := m.offers[offerKey{t.Maker, t.OfferSequence}]
+	if !ok || !isActive(cur.Status) || cur.LedgerIndex > t.LedgerIndex {
+		return nil
+	}
+	gets, err := subClamped(cur.TakerGets.Value, t.Gets.Value)
+	if err != nil {
+		return fmt.Errorf("store: memory fill offer %s/%d: %w", t.Maker, t.OfferSequence, err)
+	}
+	pays, err := subClamped(cur.TakerPays.Value, t.Pays.Value)
+	if err != nil {
+		return fmt.Errorf("store: memory fill offer %s/%d: %w", t.Maker, t.OfferSequence, err)
+	}
+	cur.TakerGets.Value = gets
+	cur.TakerPays.Value = pays
+	cur.LedgerIndex = t.LedgerIndex
+	if gets == "0" {
+		cur.Status = model.OfferStatusFilled
+	} else {
+		cur.Status = model.OfferStatusPartiallyFilled
+	}
+	return nil
+}
+
+// RefreshFundedOffers mirrors the SQL funding refresh: every open offer
+// the owner holds in the asset is funded at min(TakerGets, balance),
+// floored at zero, unless a later ledger's observation already applied.
+func (m *MemoryStore) RefreshFundedOffers(_ context.Context, owner, currency, issuer, balance string, ledgerIndex uint64) error {
+	bal, ok := new(big.Rat).SetString(balance)
+	if !ok {
+		return fmt.Errorf("store: memory refresh funded offers for %s: bad balance %q", owner, balance)
+	}
+	unfunded := bal.Sign() <= 0
+	if bal.Sign() < 0 {
+		bal = new(big.Rat)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, o := range m.offers {
+		if o.Account != owner || o.TakerGets.Currency != currency || o.TakerGets.Issuer != issuer {
+			continue
+		}
+		if !isActive(o.Status) || o.FundedCheckedLedger > ledgerIndex {
+			continue
+		}
+		funded := bal
+		if gets, err := o.TakerGets.Rat(); err == nil && gets.Cmp(bal) < 0 {
+			funded = gets
+		}
+		o.FundedTakerGets = model.RatDecimal(funded)
+		o.Unfunded = unfunded
+		o.FundedCheckedLedger = ledgerIndex
+	}
+	return nil
+}
+
+// AppendOutbox mirrors the SQL outbox: events converge on
+// (ledger_index, seq) so a replayed ledger appends nothing new, and a
+// positive limit returns ErrOutboxFull instead of growing without bound.
+func (m *MemoryStore) AppendOutbox(_ context.Context, ledgerIndex uint64, events []model.OutboxEvent, limit int) error {
+	if len(events) == 0 {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if limit > 0 && len(m.outbox) >= limit {
+		return fmt.Errorf("store: outbox: ledger %d: %d rows pending: %w", ledgerIndex, len(m.outbox), ErrOutboxFull)
+	}
+	for i, ev := range events {
+		id := outboxSeqKey{ledgerIndex, i}
+		if m.outboxSeen[id] {
+			continue
+		}
+		m.outboxSeen[id] = true
+		e := ev
+		e.LedgerIndex = ledgerIndex
+		e.Seq = i
+		e.ID = int64(len(m.outbox) + 1)
+		m.outbox = append(m.outbox, &e)
+	}
+	return nil
+}
+
+type outboxSeqKey struct {
+	ledger uint64
+	seq    int
+}
+
+// OutboxEvents returns the appended events in order, for tests.
+func (m *MemoryStore) OutboxEvents() []*model.OutboxEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*model.OutboxEvent(nil), m.outbox...)
+}
+
+// ApplyMarketStats mirrors the SQL fold: a ledger's activity accumulates
+// onto its (pair, bucket) row unless that row already saw this ledger or
+// a later one, so replays change nothing.
+func (m *MemoryStore) ApplyMarketStats(_ context.Context, u *model.MarketStatsUpdate) error {
+	vol, ok := new(big.Rat).SetString(u.Volume)
+	if !ok {
+		return fmt.Errorf("store: memory market stats %s: bad volume %q", u.Pair, u.Volume)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := marketBucketKey{u.Pair, u.Bucket}
+	cur, exists := m.marketStats[key]
+	if exists && cur.LedgerIndex >= u.LedgerIndex {
+		return nil
+	}
+	if !exists {
+		cur = &model.MarketStatsUpdate{Pair: u.Pair, Bucket: u.Bucket}
+		m.marketStats[key] = cur
+	}
+	curVol, _ := new(big.Rat).SetString(nullableZero(cur.Volume))
+	cur.Volume = model.RatDecimal(curVol.Add(curVol, vol))
+	cur.High = ratExtreme(cur.High, u.High, 1)
+	cur.Low = ratExtreme(cur.Low, u.Low, -1)
+	if u.Last != "" {
+		cur.Last = u.Last
+	}
+	cur.TradeCount += u.TradeCount
+	cur.LedgerIndex = u.LedgerIndex
+	return nil
+}
+
+type marketBucketKey struct {
+	pair   string
+	bucket int64
+}
+
+// ratExtreme keeps whichever of two decimal strings compares further in
+// direction sign (1 = higher wins, -1 = lower wins); empty means unset.
+func ratExtreme(cur, next string, sign int) string {
+	if next == "" {
+		return cur
+	}
+	if cur == "" {
+		return next
+	}
+	a, aok := new(big.Rat).SetString(cur)
+	b, bok := new(big.Rat).SetString(next)
+	if !aok || !bok {
+		return cur
+	}
+	if b.Cmp(a) == sign {
+		return next
+	}
+	return cur
+}
+
+// MarketBucket reports the accumulated hourly bucket for a pair, for
+// tests.
+func (m *MemoryStore) MarketBucket(pair string, bucket int64) (model.MarketStatsUpdate, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur, ok := m.marketStats[marketBucketKey{pair, bucket}]
+	if !ok {
+		return model.MarketStatsUpdate{}, false
+	}
+	return *cur, true
+}
+
+// UpsertNFTOffer mirrors the SQL upsert: rows key on the offer's ledger
+// entry index and an older replay never moves a row backwards.
+func (m *MemoryStore) UpsertNFTOffer(_ context.Context, o *model.NFTOffer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur, ok := m.nftOffers[o.OfferIndex]; ok && cur.LedgerIndex > o.LedgerIndex {
+		return nil
+	}
+	cp := *o
+	m.nftOffers[o.OfferIndex] = &cp
+	return nil
+}
+
+// AcceptNFTOffer marks an open offer accepted; unknown or out-of-order
+// accepts are no-ops, like the SQL path.
+func (m *MemoryStore) AcceptNFTOffer(_ context.Context, offerIndex string, ledgerIndex uint64, closeTime int64, txHash string) error {
+	m.closeNFTOffer(offerIndex, model.NFTOfferStatusAccepted, ledgerIndex, closeTime, txHash)
+	return nil
+}
+
+// CancelNFTOffer marks an open offer cancelled, with the same no-op
+// semantics as AcceptNFTOffer.
+func (m *MemoryStore) CancelNFTOffer(_ context.Context, offerIndex string, ledgerIndex uint64, closeTime int64, txHash string) error {
+	m.closeNFTOffer(offerIndex, model.NFTOfferStatusCancelled, ledgerIndex, closeTime, txHash)
+	return nil
+}
+
+func (m *MemoryStore) closeNFTOffer(offerIndex, status string, ledgerIndex uint64, closeTime int64, txHash string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur, ok := m.nftOffers[offerIndex]
+	if !ok || cur.Status != model.NFTOfferStatusOpen || cur.LedgerIndex > ledgerIndex {
+		return
+	}
+	cur.Status = status
+	cur.LedgerIndex = ledgerIndex
+	cur.CloseTime = closeTime
+	cur.TxHash = txHash
+}
+
+// InsertNFTTrade appends one sale, keyed on tx_hash so replays are no-ops.
+func (m *MemoryStore) InsertNFTTrade(_ context.Context, t *model.NFTTrade) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.nftTrades[t.TxHash]; ok {
+		return nil
+	}
+	cp := *t
+	m.nftTrades[t.TxHash] = &cp
+	return nil
+}
+
+// GetNFTOffer reads one NFT offer back for assertions.
+func (m *MemoryStore) GetNFTOffer(offerIndex string) (model.NFTOffer, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if o, ok := m.nftOffers[offerIndex]; ok {
+		return *o, true
+	}
+	return model.NFTOffer{}, false
+}
+
+// UpsertTrustLine mirrors the SQL upsert: rows key on (account,
+// currency, issuer) and an older replay never moves a row backwards.
+func (m *MemoryStore) UpsertTrustLine(_ context.Context, l *model.TrustLine) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := trustLineKey{l.Account, l.Currency, l.Issuer}
+	if cur, ok := m.trustLines[key]; ok && cur.LedgerIndex > l.LedgerIndex {
+		return nil
+	}
+	cp := *l
+	m.trustLines[key] = &cp
+	return nil
+}
+
+// DeleteTrustLine removes the line unless a newer ledger refreshed it.
+func (m *MemoryStore) DeleteTrustLine(_ context.Context, account, currency, issuer string, ledgerIndex uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := trustLineKey{account, currency, issuer}
+	if cur, ok := m.trustLines[key]; ok && cur.LedgerIndex <= ledgerIndex {
+		delete(m.trustLines, key)
+	}
+	return nil
+}
+
+// GetHolderCount counts holders of the issuer's currency.
+func (m *MemoryStore) GetHolderCount(_ context.Context, currency, issuer string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for key := range m.trustLines {
+		if key.currency == currency && key.issuer == issuer {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// UpsertPool registers or refreshes a pool.
+func (m *MemoryStore) UpsertPool(_ context.Context, p *model.Pool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp := *p
+	m.pools[p.Account] = &cp
+	return nil
+}
+
+// IsPoolAccount reports whether the account is a registered pool.
+func (m *MemoryStore) IsPoolAccount(_ context.Context, account string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.pools[account]
+	return ok
+}
+
+// AdjustPoolReserve applies the delta to whichever leg holds the asset;
+// like the SQL store, an unknown pool is an error.
+func (m *MemoryStore) AdjustPoolReserve(_ context.Context, adj *model.PoolAdjustment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pool, ok := m.pools[adj.Account]
+	if !ok {
+		return fmt.Errorf("store: adjust pool %s: unknown pool", adj.Account)
+	}
+	for _, leg := range []*xrpl.Amount{&pool.Asset1, &pool.Asset2} {
+		if leg.Currency != adj.Currency || leg.Issuer != adj.Issuer {
+			continue
+		}
+		sum, err := addDecimal(leg.Value, adj.Delta)
+		if err != nil {
+			return fmt.Errorf("store: adjust pool %s: %w", adj.Account, err)
+		}
+		leg.Value = sum
+	}
+	pool.LedgerIndex = adj.LedgerIndex
+	return nil
+}
+
+// ApplyFeeChange moves the pool to its new fee; unknown pools are a no-op
+// like the SQL UPDATE.
+func (m *MemoryStore) ApplyFeeChange(_ context.Context, fc *model.FeeChange) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if pool, ok := m.pools[fc.PoolAccount]; ok && pool.LedgerIndex <= fc.LedgerIndex {
+		pool.TradingFee = fc.TradingFee
+		pool.LedgerIndex = fc.LedgerIndex
+	}
+	return nil
+}
+
+// ApplyAssetFlag records or clears an asset flag.
+func (m *MemoryStore) ApplyAssetFlag(_ context.Context, c *model.AssetFlagChange) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := c.Issuer + "/" + c.Currency + "/" + c.Flag
+	if c.Set {
+		m.flags[key] = true
+	} else {
+		delete(m.flags, key)
+	}
+	return nil
+}
+
+// UpsertAmendment mirrors the SQL rule: only rows seeded without an
+// activation ledger are upgraded in place.
+func (m *MemoryStore) UpsertAmendment(_ context.Context, a *model.Amendment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cur, ok := m.amendments[a.Hash]; ok && cur.LedgerIndex != 0 {
+		return nil
+	}
+	cp := *a
+	m.amendments[a.Hash] = &cp
+	return nil
+}
+
+// UpsertIdentity records one subject's resolved identity.
+func (m *MemoryStore) UpsertIdentity(_ context.Context, id *model.Identity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp := *id
+	m.identities[id.Account] = &cp
+	return nil
+}
+
+// InvalidateIdentity drops a subject's cached identity, like the SQL
+// store does on an on-ledger Domain change.
+func (m *MemoryStore) InvalidateIdentity(_ context.Context, account string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.identities, account)
+	return nil
+}
+
+// GetIdentity returns a subject's cached identity, for tests.
+func (m *MemoryStore) GetIdentity(account string) (model.Identity, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, ok := m.identities[account]
+	if !ok {
+		return model.Identity{}, false
+	}
+	return *id, true
+}
+
+// SaveCheckpoint records a fully processed ledger.
+func (m *MemoryStore) SaveCheckpoint(_ context.Context, cp *model.Checkpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := *cp
+	m.checkpoints[cp.LedgerIndex] = &c
+	return nil
+}
+
+// GetCheckpoint returns a copy of one checkpoint by index, or nil when
+// the ledger has not been checkpointed.
+func (m *MemoryStore) GetCheckpoint(_ context.Context, index uint64) (*model.Checkpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp, ok := m.checkpoints[index]
+	if !ok {
+		return nil, nil
+	}
+	c := *cp
+	return &c, nil
+}
+
+// SnapshotCaches and RestoreCaches satisfy the snapshotter's store
+// interface; the memory store reads its own maps, so there is nothing
+// to capture or prime.
+func (m *MemoryStore) SnapshotCaches() *CacheSnapshot { return nil }
+func (m *MemoryStore) RestoreCaches(_ *CacheSnapshot) {}
+
+// SaveRawTransaction keeps the raw transaction keyed like the SQL table.
+func (m *MemoryStore) SaveRawTransaction(_ context.Context, ledgerIndex uint64, tx *xrpl.Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.raw[fmt.Sprintf("%d/%s", ledgerIndex, tx.Hash)] = tx
+	return nil
+}
+
+// InsertBalanceChanges overwrites each transaction's deltas in place, like
+// the batched SQL upsert.
+func (m *MemoryStore) InsertBalanceChanges(_ context.Context, ledgerIndex uint64, closeTime int64, txHash string, changes []balancechanges.BalanceChange) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range changes {
+		key := txHash + "/" + c.Account + "/" + c.Currency + "/" + c.Issuer
+		m.balances[key] = balancechanges.Record{
+			BalanceChange: c, LedgerIndex: ledgerIndex, CloseTime: closeTime, TxHash: txHash,
+		}
+	}
+	return nil
+}
+
+// RecordFailedLedger and ClearFailedLedger mirror the failed-ledger set.
+func (m *MemoryStore) RecordFailedLedger(_ context.Context, index uint64, cause error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failed[index] = cause.Error()
+	return nil
+}
+
+func (m *MemoryStore) ClearFailedLedger(_ context.Context, index uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.failed, index)
+	return nil
+}
+
+// GetOffer returns a copy of the stored offer, if any.
+func (m *MemoryStore) GetOffer(account string, sequence uint32) (model.Offer, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if o, ok := m.offers[offerKey{account, sequence}]; ok {
+		return *o, true
+	}
+	return model.Offer{}, false
+}
+
+// Trades returns a snapshot of the trade log.
+func (m *MemoryStore) Trades() []*model.Trade {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]*model.Trade, len(m.trades))
+	for i, t := range m.trades {
+		cp := *t
+		out[i] = &cp
+	}
+	return out
+}
+
+// The Stream* cursors mirror the SQL store's: rows in [from, to] in
+// ledger order, a zero `to` meaning no upper bound. Each row is a copy
+// taken under the lock, so fn may hold it — and write back to the store —
+// without racing.
+
+// StreamCheckpoints walks checkpoints in [from, to] in ledger order.
+func (m *MemoryStore) StreamCheckpoints(ctx context.Context, from, to uint64, fn func(*model.Checkpoint) error) error {
+	if to == 0 {
+		to = 1<<63 - 1
+	}
+	m.mu.Lock()
+	var rows []*model.Checkpoint
+	for _, cp := range m.checkpoints {
+		if cp.LedgerIndex >= from && cp.LedgerIndex <= to {
+			c := *cp
+			rows = append(rows, &c)
+		}
+	}
+	m.mu.Unlock()
+	sort.Slice(rows, func(i, j int) bool { return rows[i].LedgerIndex < rows[j].LedgerIndex })
+	for _, cp := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(cp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StreamOffers walks offers whose last activity falls in [from, to].
+func (m *MemoryStore) StreamOffers(ctx context.Context, from, to uint64, fn func(*model.Offer) error) error {
+	if to == 0 {
+		to = 1<<63 - 1
+	}
+	m.mu.Lock()
+	var rows []*model.Offer
+	for _, o := range m.offers {
+		if o.LedgerIndex >= from && o.LedgerIndex <= to {
+			cp := *o
+			rows = append(rows, &cp)
+		}
+	}
+	m.mu.Unlock()
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].LedgerIndex != rows[j].LedgerIndex {
+			return rows[i].LedgerIndex < rows[j].LedgerIndex
+		}
+		if rows[i].Account != rows[j].Account {
+			return rows[i].Account < rows[j].Account
+		}
+		return rows[i].Sequence < rows[j].Sequence
+	})
+	for _, o := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StreamTrades walks trades executed in [from, to].
+func (m *MemoryStore) StreamTrades(ctx context.Context, from, to uint64, fn func(*model.Trade) error) error {
+	if to == 0 {
+		to = 1<<63 - 1
+	}
+	for _, t := range m.Trades() {
+		if t.LedgerIndex < from || t.LedgerIndex > to {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StreamPools walks pools whose last update falls in [from, to].
+func (m *MemoryStore) StreamPools(ctx context.Context, from, to uint64, fn func(*model.Pool) error) error {
+	if to == 0 {
+		to = 1<<63 - 1
+	}
+	m.mu.Lock()
+	var rows []*model.Pool
+	for _, p := range m.pools {
+		if p.LedgerIndex >= from && p.LedgerIndex <= to {
+			cp := *p
+			rows = append(rows, &cp)
+		}
+	}
+	m.mu.Unlock()
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].LedgerIndex != rows[j].LedgerIndex {
+			return rows[i].LedgerIndex < rows[j].LedgerIndex
+		}
+		return rows[i].Account < rows[j].Account
+	})
+	for _, p := range rows {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetLastCheckpoint returns the highest checkpoint, or nil when empty.
+func (m *MemoryStore) GetLastCheckpoint(_ context.Context) (*model.Checkpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var last *model.Checkpoint
+	for _, cp := range m.checkpoints {
+		if last == nil || cp.LedgerIndex > last.LedgerIndex {
+			last = cp
+		}
+	}
+	if last == nil {
+		return nil, nil
+	}
+	cp := *last
+	return &cp, nil
+}
+
+func isTerminal(status string) bool {
+	for _, s := range model.TerminalOfferStatuses() {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func isActive(status string) bool {
+	for _, s := range model.ActiveOfferStatuses() {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// subClamped returns max(a-b, 0) over decimal strings.
+func subClamped(a, b string) (string, error) {
+	ra, err := decimal(a)
+	if err != nil {
+		return "", err
+	}
+	rb, err := decimal(b)
+	if err != nil {
+		return "", err
+	}
+	ra.Sub(ra, rb)
+	if ra.Sign() <= 0 {
+		return "0", nil
+	}
+	return ratText(ra), nil
+}
+
+func addDecimal(a, b string) (string, error) {
+	ra, err := decimal(a)
+	if err != nil {
+		return "", err
+	}
+	rb, err := decimal(b)
+	if err != nil {
+		return "", err
+	}
+	return ratText(ra.Add(ra, rb)), nil
+}
+
+func decimal(s string) (*big.Rat, error) {
+	if s == "" {
+		return new(big.Rat), nil
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	return r, nil
+}
+
+// ratText renders a rational as a plain decimal; values only ever carry
+// power-of-ten denominators.
+func ratText(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	s := r.FloatString(30)
+	for len(s) > 0 && s[len(s)-1] == '0' {
+		s = s[:len(s)-1]
+	}
+	if len(s) > 0 && s[len(s)-1] == '.' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
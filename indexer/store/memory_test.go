@@ -0,0 +1,291 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func memOffer(sequence uint32, status string, ledgerIndex uint64) *model.Offer {
+	return &model.Offer{
+		TxHash: "MEMTX", Account: "rMem", Sequence: sequence,
+		TakerGets:     xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "10"},
+		TakerPays:     xrpl.Amount{Value: "5"},
+		Status:        status,
+		LedgerIndex:   ledgerIndex,
+		ParserVersion: 2,
+	}
+}
+
+func TestMemoryUpsertOfferIdempotent(t *testing.T) {
+	m := NewMemoryStore()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := m.UpsertOffer(ctx, memOffer(1, model.OfferStatusOpen, 90000800)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, ok := m.GetOffer("rMem", 1)
+	if !ok || got.Status != model.OfferStatusOpen || got.LedgerIndex != 90000800 {
+		t.Fatalf("offer = %+v, %v", got, ok)
+	}
+
+	// An older parser version never clobbers a newer row.
+	stale := memOffer(1, model.OfferStatusFilled, 90000900)
+	stale.ParserVersion = 1
+	if err := m.UpsertOffer(ctx, stale); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = m.GetOffer("rMem", 1); got.Status != model.OfferStatusOpen {
+		t.Fatalf("stale parser overwrote the row: %+v", got)
+	}
+
+	// A same-version newer ledger does apply, and the ledger index never
+	// moves backwards afterwards.
+	if err := m.UpsertOffer(ctx, memOffer(1, model.OfferStatusCancelled, 90000900)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UpsertOffer(ctx, memOffer(1, model.OfferStatusOpen, 90000850)); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = m.GetOffer("rMem", 1)
+	if got.Status != model.OfferStatusCancelled || got.LedgerIndex != 90000900 {
+		t.Fatalf("terminal row moved backwards: %+v", got)
+	}
+}
+
+func TestMemoryCancelContract(t *testing.T) {
+	m := NewMemoryStore()
+	ctx := context.Background()
+
+	// Cancelling an unknown offer is a no-op that reports false, like
+	// the SQL UPDATE touching zero rows.
+	cancelled, err := m.CancelOffer(ctx, "rMem", 99, 90000800, 0, "MEMCANCEL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled {
+		t.Error("cancel of an unknown offer reported a cancelled row")
+	}
+
+	if err := m.UpsertOffer(ctx, memOffer(2, model.OfferStatusOpen, 90000800)); err != nil {
+		t.Fatal(err)
+	}
+	// A cancel older than the row's last activity is forbidden.
+	cancelled, err = m.CancelOffer(ctx, "rMem", 2, 90000790, 0, "MEMCANCEL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled {
+		t.Error("out-of-order cancel reported a cancelled row")
+	}
+	if got, _ := m.GetOffer("rMem", 2); got.Status != model.OfferStatusOpen {
+		t.Fatalf("out-of-order cancel applied: %+v", got)
+	}
+
+	cancelled, err = m.CancelOffer(ctx, "rMem", 2, 90000810, 0, "MEMCANCEL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cancelled {
+		t.Error("cancel of an open offer reported no cancelled row")
+	}
+	got, _ := m.GetOffer("rMem", 2)
+	if got.Status != model.OfferStatusCancelled || got.LedgerIndex != 90000810 {
+		t.Fatalf("cancel = %+v", got)
+	}
+	// Terminal rows stay put: a later fill attempt is a no-op.
+	if err := m.UpdateOfferFill(ctx, &model.Trade{
+		Maker: "rMem", OfferSequence: 2, Gets: xrpl.Amount{Value: "4"},
+		Pays: xrpl.Amount{Value: "2"}, LedgerIndex: 90000820, TxHash: "MEMFILL",
+	}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = m.GetOffer("rMem", 2); got.Status != model.OfferStatusCancelled {
+		t.Fatalf("fill moved a cancelled offer: %+v", got)
+	}
+}
+
+func TestMemoryFillAndReplace(t *testing.T) {
+	m := NewMemoryStore()
+	ctx := context.Background()
+
+	if err := m.UpsertOffer(ctx, memOffer(3, model.OfferStatusOpen, 90000800)); err != nil {
+		t.Fatal(err)
+	}
+	fill := &model.Trade{
+		Maker: "rMem", OfferSequence: 3,
+		Gets: xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "4"},
+		Pays: xrpl.Amount{Value: "2"}, LedgerIndex: 90000810, TxHash: "MEMFILL",
+	}
+	if err := m.UpdateOfferFill(ctx, fill, 0); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := m.GetOffer("rMem", 3)
+	if got.Status != model.OfferStatusPartiallyFilled || got.TakerGets.Value != "6" || got.TakerPays.Value != "3" {
+		t.Fatalf("partial fill = %+v", got)
+	}
+
+	fill.Gets.Value, fill.Pays.Value, fill.LedgerIndex = "6", "3", 90000820
+	if err := m.UpdateOfferFill(ctx, fill, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = m.GetOffer("rMem", 3); got.Status != model.OfferStatusFilled || got.TakerGets.Value != "0" {
+		t.Fatalf("full fill = %+v", got)
+	}
+
+	if err := m.UpsertOffer(ctx, memOffer(4, model.OfferStatusOpen, 90000830)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.MarkOfferReplaced(ctx, "rMem", 4, 7, 90000840, 0, "MEMREPL"); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = m.GetOffer("rMem", 4)
+	if got.Status != model.OfferStatusCancelled || got.ReplacedBy != 7 {
+		t.Fatalf("replace = %+v", got)
+	}
+}
+
+func TestMemoryFundedRefresh(t *testing.T) {
+	m := NewMemoryStore()
+	ctx := context.Background()
+
+	if err := m.UpsertOffer(ctx, memOffer(5, model.OfferStatusOpen, 90002000)); err != nil {
+		t.Fatal(err)
+	}
+	// A balance smaller than the offer funds only that much.
+	if err := m.RefreshFundedOffers(ctx, "rMem", "USD", "rIss", "4", 90002010); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := m.GetOffer("rMem", 5)
+	if got.FundedTakerGets != "4" || got.Unfunded || got.FundedCheckedLedger != 90002010 {
+		t.Fatalf("partial funding = %+v", got)
+	}
+	// A stale observation never moves the funding check backwards.
+	if err := m.RefreshFundedOffers(ctx, "rMem", "USD", "rIss", "9", 90002005); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = m.GetOffer("rMem", 5); got.FundedTakerGets != "4" {
+		t.Fatalf("stale refresh applied: %+v", got)
+	}
+	// A balance past the offer caps at its TakerGets.
+	if err := m.RefreshFundedOffers(ctx, "rMem", "USD", "rIss", "25", 90002020); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = m.GetOffer("rMem", 5); got.FundedTakerGets != "10" {
+		t.Fatalf("capped funding = %+v", got)
+	}
+	// An emptied balance marks the offer unfunded.
+	if err := m.RefreshFundedOffers(ctx, "rMem", "USD", "rIss", "-2", 90002030); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = m.GetOffer("rMem", 5)
+	if got.FundedTakerGets != "0" || !got.Unfunded {
+		t.Fatalf("unfunded = %+v", got)
+	}
+	// Other assets and owners stay untouched.
+	if err := m.UpsertOffer(ctx, &model.Offer{
+		TxHash: "MEMTX2", Account: "rOther", Sequence: 6,
+		TakerGets:   xrpl.Amount{Currency: "USD", Issuer: "rIss", Value: "3"},
+		TakerPays:   xrpl.Amount{Value: "1"},
+		Status:      model.OfferStatusOpen,
+		LedgerIndex: 90002000,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RefreshFundedOffers(ctx, "rMem", "USD", "rIss", "1", 90002040); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = m.GetOffer("rOther", 6); got.FundedTakerGets != "" || got.FundedCheckedLedger != 0 {
+		t.Fatalf("another owner's offer refreshed: %+v", got)
+	}
+}
+
+func TestMemoryTrustLineContract(t *testing.T) {
+	m := NewMemoryStore()
+	ctx := context.Background()
+
+	line := &model.TrustLine{Account: "rHolder", Currency: "USD", Issuer: "rIss",
+		Limit: "500", LedgerIndex: 90001000, TxHash: "TRUST1"}
+	if err := m.UpsertTrustLine(ctx, line); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := m.GetHolderCount(ctx, "USD", "rIss"); n != 1 {
+		t.Fatalf("holder count = %d, want 1", n)
+	}
+
+	// A deletion older than the row's last activity is superseded.
+	if err := m.DeleteTrustLine(ctx, "rHolder", "USD", "rIss", 90000990); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := m.GetHolderCount(ctx, "USD", "rIss"); n != 1 {
+		t.Fatalf("stale delete removed the line; holder count = %d", n)
+	}
+
+	if err := m.DeleteTrustLine(ctx, "rHolder", "USD", "rIss", 90001010); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := m.GetHolderCount(ctx, "USD", "rIss"); n != 0 {
+		t.Fatalf("holder count after delete = %d, want 0", n)
+	}
+}
+
+func TestMemoryStreamCheckpoints(t *testing.T) {
+	m := NewMemoryStore()
+	ctx := context.Background()
+	for i := uint64(0); i < 3; i++ {
+		if err := m.SaveCheckpoint(ctx, &model.Checkpoint{
+			LedgerIndex: 90004000 + i, LedgerHash: "STREAM", CloseTime: 700004000,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []uint64
+	if err := m.StreamCheckpoints(ctx, 0, 0, func(cp *model.Checkpoint) error {
+		got = append(got, cp.LedgerIndex)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0] != 90004000 || got[2] != 90004002 {
+		t.Fatalf("streamed %v, want the three checkpoints in order", got)
+	}
+
+	stop := errors.New("enough")
+	var calls int
+	if err := m.StreamCheckpoints(ctx, 0, 0, func(*model.Checkpoint) error {
+		calls++
+		return stop
+	}); !errors.Is(err, stop) {
+		t.Errorf("early stop returned %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("callback ran %d times after stopping, want 1", calls)
+	}
+}
+
+func TestMemoryOutboxReplayConverges(t *testing.T) {
+	m := NewMemoryStore()
+	ctx := context.Background()
+	events := []model.OutboxEvent{
+		{Topic: "trade", Payload: `{"n":1}`},
+		{Topic: "trade", Payload: `{"n":2}`},
+	}
+	if err := m.AppendOutbox(ctx, 90003000, events, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AppendOutbox(ctx, 90003000, events, 0); err != nil {
+		t.Fatal(err)
+	}
+	got := m.OutboxEvents()
+	if len(got) != 2 || got[0].Seq != 0 || got[1].Seq != 1 {
+		t.Fatalf("events after replay = %+v, want the two originals", got)
+	}
+	if err := m.AppendOutbox(ctx, 90003001, events, 2); !errors.Is(err, ErrOutboxFull) {
+		t.Errorf("append over limit = %v, want ErrOutboxFull", err)
+	}
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// UpsertNFTOffer records a marketplace offer. NFT offers key on the
+// ledger entry index — the only identity accepts and cancels carry — and
+// the ledger-index guard keeps a replayed create from resurrecting a row
+// an accept or cancel already moved on.
+func (s *Store) UpsertNFTOffer(ctx context.Context, o *model.NFTOffer) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO nft_offers (offer_index, token_id, owner, destination,
+			amount_currency, amount_issuer, amount_value,
+			sell, expiration, status, ledger_index, close_time, tx_hash)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+		ON CONFLICT (offer_index) DO UPDATE SET
+			status = EXCLUDED.status,
+			ledger_index = EXCLUDED.ledger_index,
+			close_time = EXCLUDED.close_time,
+			tx_hash = EXCLUDED.tx_hash
+		WHERE nft_offers.ledger_index <= EXCLUDED.ledger_index`,
+		o.OfferIndex, o.TokenID, o.Owner, o.Destination,
+		o.Amount.Currency, o.Amount.Issuer, o.Amount.Value,
+		o.Sell, o.Expiration, o.Status, o.LedgerIndex, o.CloseTime, o.TxHash)
+	if err != nil {
+		return fmt.Errorf("store: upsert nft offer %s: %w", o.OfferIndex, err)
+	}
+	return nil
+}
+
+// AcceptNFTOffer marks an offer consumed by an NFTokenAcceptOffer. An
+// unknown offer is a no-op — it may predate the indexed range — as is an
+// accept older than the row's last activity.
+func (s *Store) AcceptNFTOffer(ctx context.Context, offerIndex string, ledgerIndex uint64, closeTime int64, txHash string) error {
+	return s.closeNFTOffer(ctx, offerIndex, model.NFTOfferStatusAccepted, ledgerIndex, closeTime, txHash)
+}
+
+// CancelNFTOffer marks an offer removed by an NFTokenCancelOffer, with
+// the same no-op semantics as AcceptNFTOffer.
+func (s *Store) CancelNFTOffer(ctx context.Context, offerIndex string, ledgerIndex uint64, closeTime int64, txHash string) error {
+	return s.closeNFTOffer(ctx, offerIndex, model.NFTOfferStatusCancelled, ledgerIndex, closeTime, txHash)
+}
+
+func (s *Store) closeNFTOffer(ctx context.Context, offerIndex, status string, ledgerIndex uint64, closeTime int64, txHash string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE nft_offers SET status = $2, ledger_index = $3, close_time = $4, tx_hash = $5
+		WHERE offer_index = $1 AND status = $6 AND ledger_index <= $3`,
+		offerIndex, status, ledgerIndex, closeTime, txHash, model.NFTOfferStatusOpen)
+	if err != nil {
+		return fmt.Errorf("store: %s nft offer %s: %w", status, offerIndex, err)
+	}
+	return nil
+}
+
+// InsertNFTTrade appends one completed sale. An NFTokenAcceptOffer
+// settles exactly one sale, so tx_hash is the natural key and replays
+// are no-ops.
+func (s *Store) InsertNFTTrade(ctx context.Context, t *model.NFTTrade) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO nft_trades (tx_hash, token_id, seller, buyer,
+			amount_currency, amount_issuer, amount_value,
+			broker_fee_currency, broker_fee_issuer, broker_fee_value,
+			sell_offer_index, buy_offer_index, ledger_index, close_time)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)
+		ON CONFLICT (tx_hash) DO NOTHING`,
+		t.TxHash, t.TokenID, t.Seller, t.Buyer,
+		t.Amount.Currency, t.Amount.Issuer, t.Amount.Value,
+		t.BrokerFee.Currency, t.BrokerFee.Issuer, nullableZero(t.BrokerFee.Value),
+		t.SellOfferIndex, t.BuyOfferIndex, t.LedgerIndex, t.CloseTime)
+	if err != nil {
+		return fmt.Errorf("store: insert nft trade %s: %w", t.TxHash, err)
+	}
+	return nil
+}
+
+// nullableZero maps an unset amount value to 0 so zero-value broker fees
+// insert cleanly.
+func nullableZero(s string) string {
+	if s == "" {
+		return "0"
+	}
+	return s
+}
+
+// GetNFTOffers returns still-open NFT offers, newest ledger first,
+// optionally narrowed by offer owner and/or token id. Pagination is
+// limit/offset; a non-positive limit defaults to 100.
+func (s *Store) GetNFTOffers(ctx context.Context, owner, token string, limit, offset int) ([]*model.NFTOffer, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT offer_index, token_id, owner, destination,
+			amount_currency, amount_issuer, amount_value,
+			sell, expiration, status, ledger_index, close_time, tx_hash
+		FROM nft_offers
+		WHERE status = 'open'
+		  AND ($1 = '' OR owner = $1)
+		  AND ($2 = '' OR token_id = $2)
+		ORDER BY ledger_index DESC, offer_index
+		LIMIT $3 OFFSET $4`,
+		owner, token, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("store: nft offers: %w", err)
+	}
+	defer rows.Close()
+	var offers []*model.NFTOffer
+	for rows.Next() {
+		o := &model.NFTOffer{}
+		if err := rows.Scan(&o.OfferIndex, &o.TokenID, &o.Owner, &o.Destination,
+			&o.Amount.Currency, &o.Amount.Issuer, &o.Amount.Value,
+			&o.Sell, &o.Expiration, &o.Status, &o.LedgerIndex, &o.CloseTime, &o.TxHash); err != nil {
+			return nil, fmt.Errorf("store: nft offers: %w", err)
+		}
+		offers = append(offers, o)
+	}
+	return offers, rows.Err()
+}
@@ -0,0 +1,242 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// outboxBatch caps how many events one dispatch cycle hands to each
+// emitter, so a long outage drains in bounded steps.
+const outboxBatch = 100
+
+// ErrOutboxFull is returned by AppendOutbox when the outbox holds more
+// rows than the configured limit. The caller fails the ledger, which the
+// usual retry machinery brings back later — backpressure on ledger
+// processing, not silent event loss.
+var ErrOutboxFull = errors.New("store: outbox is over its row limit")
+
+// AppendOutbox writes one ledger's events in a single transaction,
+// before the ledger is checkpointed. Replays converge on
+// (ledger_index, seq), so appending the same ledger twice leaves one
+// copy. A positive limit bounds the table; see ErrOutboxFull.
+func (s *Store) AppendOutbox(ctx context.Context, ledgerIndex uint64, events []model.OutboxEvent, limit int) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if limit > 0 {
+		var pending int64
+		if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM outbox`).Scan(&pending); err != nil {
+			return fmt.Errorf("store: outbox: counting: %w", err)
+		}
+		if pending >= int64(limit) {
+			return fmt.Errorf("store: outbox: ledger %d: %d rows pending: %w", ledgerIndex, pending, ErrOutboxFull)
+		}
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("store: outbox: begin: %w", err)
+	}
+	defer tx.Rollback()
+	for i, ev := range events {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO outbox (ledger_index, seq, topic, payload)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (ledger_index, seq) DO NOTHING`,
+			ledgerIndex, i, ev.Topic, ev.Payload); err != nil {
+			return fmt.Errorf("store: outbox: ledger %d event %d: %w", ledgerIndex, i, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("store: outbox: commit: %w", err)
+	}
+	return nil
+}
+
+// ReadOutbox returns the oldest events past the emitter's cursor, in
+// dispatch order. Only events whose ledger is checkpointed are visible:
+// an event never leaves the outbox before the data it describes is.
+func (s *Store) ReadOutbox(ctx context.Context, emitter string, limit int) ([]*model.OutboxEvent, error) {
+	if limit <= 0 {
+		limit = outboxBatch
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, ledger_index, seq, topic, payload
+		FROM outbox
+		WHERE id > COALESCE((SELECT last_id FROM outbox_cursors WHERE emitter = $1), 0)
+		  AND ledger_index <= (SELECT COALESCE(MAX(ledger_index), 0) FROM checkpoints)
+		ORDER BY id LIMIT $2`, emitter, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: outbox: reading for %s: %w", emitter, err)
+	}
+	defer rows.Close()
+	var events []*model.OutboxEvent
+	for rows.Next() {
+		var ev model.OutboxEvent
+		if err := rows.Scan(&ev.ID, &ev.LedgerIndex, &ev.Seq, &ev.Topic, &ev.Payload); err != nil {
+			return nil, fmt.Errorf("store: outbox: scanning event: %w", err)
+		}
+		events = append(events, &ev)
+	}
+	return events, rows.Err()
+}
+
+// ReadOutboxFrom returns up to limit checkpointed events whose ledger is
+// at or past fromLedger and whose id is past afterID, in dispatch order.
+// It reads without an emitter cursor — subscribers that track their own
+// position (the rpcapi event stream) page with the last id they saw.
+// Events every registered emitter has consumed are pruned, so a resume
+// point older than the outbox's tail yields only what is still held.
+func (s *Store) ReadOutboxFrom(ctx context.Context, fromLedger uint64, afterID int64, limit int) ([]*model.OutboxEvent, error) {
+	if limit <= 0 {
+		limit = outboxBatch
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, ledger_index, seq, topic, payload
+		FROM outbox
+		WHERE id > $2 AND ledger_index >= $1
+		  AND ledger_index <= (SELECT COALESCE(MAX(ledger_index), 0) FROM checkpoints)
+		ORDER BY id LIMIT $3`, fromLedger, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: outbox: reading from ledger %d: %w", fromLedger, err)
+	}
+	defer rows.Close()
+	var events []*model.OutboxEvent
+	for rows.Next() {
+		var ev model.OutboxEvent
+		if err := rows.Scan(&ev.ID, &ev.LedgerIndex, &ev.Seq, &ev.Topic, &ev.Payload); err != nil {
+			return nil, fmt.Errorf("store: outbox: scanning event: %w", err)
+		}
+		events = append(events, &ev)
+	}
+	return events, rows.Err()
+}
+
+// MarkOutboxSent advances an emitter's cursor: everything at or below
+// lastID has been handed over at least once. A stale lastID never moves
+// the cursor backwards.
+func (s *Store) MarkOutboxSent(ctx context.Context, emitter string, lastID int64) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO outbox_cursors (emitter, last_id) VALUES ($1, $2)
+		ON CONFLICT (emitter) DO UPDATE SET last_id = EXCLUDED.last_id, updated_at = now()
+		WHERE outbox_cursors.last_id < EXCLUDED.last_id`, emitter, lastID)
+	if err != nil {
+		return fmt.Errorf("store: outbox: cursor for %s: %w", emitter, err)
+	}
+	return nil
+}
+
+// PruneOutbox deletes events every registered emitter has been handed,
+// returning how many rows went. With no cursors nothing is prunable —
+// better an explicitly growing table than silently discarded events.
+func (s *Store) PruneOutbox(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM outbox WHERE id <= (SELECT MIN(last_id) FROM outbox_cursors)`)
+	if err != nil {
+		return 0, fmt.Errorf("store: outbox: pruning: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
+
+// OutboxPending counts events not yet handed to every emitter.
+func (s *Store) OutboxPending(ctx context.Context) (int64, error) {
+	var n int64
+	if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM outbox`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("store: outbox: counting: %w", err)
+	}
+	return n, nil
+}
+
+// Emitter hands one event to an external system; returning an error
+// leaves the cursor where it was, so the event comes around again —
+// at-least-once, never lost.
+type Emitter func(ctx context.Context, ev *model.OutboxEvent) error
+
+// OutboxDispatcher drains the outbox to registered emitters from its own
+// goroutine. Each emitter has an independent cursor: a slow webhook does
+// not hold back a fast log emitter, and each sees every event in ledger
+// order at least once.
+type OutboxDispatcher struct {
+	st       *Store
+	emitters map[string]Emitter
+
+	onError func(error)
+	onSent  func(emitter string, n int)
+}
+
+// NewOutboxDispatcher returns a dispatcher reading from st; register
+// emitters before Run.
+func NewOutboxDispatcher(st *Store) *OutboxDispatcher {
+	return &OutboxDispatcher{st: st, emitters: map[string]Emitter{}}
+}
+
+// Register adds a named emitter. The name keys the cursor, so it must
+// stay stable across restarts.
+func (d *OutboxDispatcher) Register(name string, emit Emitter) { d.emitters[name] = emit }
+
+// OnError registers a callback for dispatch failures.
+func (d *OutboxDispatcher) OnError(fn func(error)) { d.onError = fn }
+
+// OnSent registers a callback invoked after each emitter's batch with
+// the number of events handed over.
+func (d *OutboxDispatcher) OnSent(fn func(emitter string, n int)) { d.onSent = fn }
+
+// Run dispatches until the context ends, polling on the given interval.
+// Failures are reported and retried on the next tick.
+func (d *OutboxDispatcher) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := d.runOnce(ctx); err != nil && ctx.Err() == nil && d.onError != nil {
+			d.onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce gives every emitter one batch and prunes what everyone has
+// seen. The cursor only advances past events that were actually handed
+// over, and only after the hand-over returned — a crash between the two
+// re-delivers, never skips.
+func (d *OutboxDispatcher) runOnce(ctx context.Context) error {
+	var errs []error
+	for name, emit := range d.emitters {
+		events, err := d.st.ReadOutbox(ctx, name, outboxBatch)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		sent := 0
+		for _, ev := range events {
+			if err := emit(ctx, ev); err != nil {
+				errs = append(errs, fmt.Errorf("store: outbox: emitter %s at event %d: %w", name, ev.ID, err))
+				break
+			}
+			if err := d.st.MarkOutboxSent(ctx, name, ev.ID); err != nil {
+				errs = append(errs, err)
+				break
+			}
+			sent++
+		}
+		if sent > 0 && d.onSent != nil {
+			d.onSent(name, sent)
+		}
+	}
+	if len(d.emitters) > 0 {
+		if _, err := d.st.PruneOutbox(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+func TestOutboxExactlyOnceAcrossCrash(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	mustExec(t, st, `DELETE FROM outbox`)
+	mustExec(t, st, `DELETE FROM outbox_cursors`)
+	mustExec(t, st, `DELETE FROM checkpoints WHERE ledger_index = 99990000`)
+
+	events := []model.OutboxEvent{
+		{Topic: "trade", Payload: `{"n":1}`},
+		{Topic: "trade", Payload: `{"n":2}`},
+		{Topic: "trade", Payload: `{"n":3}`},
+	}
+	if err := st.AppendOutbox(ctx, 99990000, events, 0); err != nil {
+		t.Fatal(err)
+	}
+	// A crash before the checkpoint replays the ledger: the second append
+	// must converge, not duplicate.
+	if err := st.AppendOutbox(ctx, 99990000, events, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Until the ledger is checkpointed its events are invisible — nothing
+	// is emitted before its data is.
+	if got, err := st.ReadOutbox(ctx, "test", 0); err != nil || len(got) != 0 {
+		t.Fatalf("events before checkpoint = %d, %v, want none", len(got), err)
+	}
+	if err := st.SaveCheckpoint(ctx, &model.Checkpoint{
+		LedgerIndex: 99990000, LedgerHash: "OUTCP", CloseTime: 700005000,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// An emitter that dies mid-batch: the first pass delivers event 1 and
+	// fails on 2; the next pass resumes at 2, so every event arrives at
+	// least once and in order.
+	d := NewOutboxDispatcher(st)
+	var delivered []string
+	failOnce := true
+	d.Register("test", func(_ context.Context, ev *model.OutboxEvent) error {
+		if ev.Seq == 1 && failOnce {
+			failOnce = false
+			return fmt.Errorf("emitter crashed")
+		}
+		delivered = append(delivered, ev.Payload)
+		return nil
+	})
+	if err := d.runOnce(ctx); err == nil {
+		t.Fatal("first pass swallowed the emitter failure")
+	}
+	if len(delivered) != 1 {
+		t.Fatalf("delivered after failure = %v, want just the first event", delivered)
+	}
+	if err := d.runOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`{"n":1}`, `{"n":2}`, `{"n":3}`}
+	if len(delivered) != 3 {
+		t.Fatalf("delivered = %v, want all three", delivered)
+	}
+	for i, p := range want {
+		if delivered[i] != p {
+			t.Errorf("delivered[%d] = %s, want %s", i, delivered[i], p)
+		}
+	}
+
+	// Everything is behind the only cursor, so the second pass pruned it.
+	if pending, err := st.OutboxPending(ctx); err != nil || pending != 0 {
+		t.Errorf("pending after prune = %d, %v, want 0", pending, err)
+	}
+
+	// Backpressure: past the limit the append fails instead of growing
+	// the table, so the ledger retries later.
+	if err := st.AppendOutbox(ctx, 99990001, events, 0); err != nil {
+		t.Fatal(err)
+	}
+	err := st.AppendOutbox(ctx, 99990002, events, 2)
+	if !errors.Is(err, ErrOutboxFull) {
+		t.Errorf("append over limit = %v, want ErrOutboxFull", err)
+	}
+	mustExec(t, st, `DELETE FROM outbox`)
+	mustExec(t, st, `DELETE FROM outbox_cursors`)
+}
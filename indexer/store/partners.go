@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// AddPartnerAccount maps an XRPL account to a Partner API key, widening
+// what the key may query. Mapping the same pair again is a no-op;
+// mapping to a key that was never created is an error so operators
+// notice typos.
+func (s *Store) AddPartnerAccount(ctx context.Context, key, account string) error {
+	if account == "" {
+		return fmt.Errorf("store: partner account must not be empty")
+	}
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO partner_accounts (key_hash, account)
+		SELECT key_hash, $2 FROM api_keys WHERE key_hash = $1
+		ON CONFLICT (key_hash, account) DO NOTHING`, hashAPIKey(key), account)
+	if err != nil {
+		return fmt.Errorf("store: mapping account %s: %w", account, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		var exists bool
+		if err := s.db.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM api_keys WHERE key_hash = $1)`,
+			hashAPIKey(key)).Scan(&exists); err != nil {
+			return fmt.Errorf("store: mapping account %s: %w", account, err)
+		}
+		if !exists {
+			return fmt.Errorf("store: mapping account %s: no such key", account)
+		}
+	}
+	return nil
+}
+
+// RemovePartnerAccount drops an account from a key's scope. Removing a
+// mapping that does not exist is an error for the same reason adding to
+// an unknown key is.
+func (s *Store) RemovePartnerAccount(ctx context.Context, key, account string) error {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM partner_accounts WHERE key_hash = $1 AND account = $2`,
+		hashAPIKey(key), account)
+	if err != nil {
+		return fmt.Errorf("store: unmapping account %s: %w", account, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("store: unmapping account %s: not mapped", account)
+	}
+	return nil
+}
+
+// ListPartnerAccounts returns the accounts mapped to a key, in order.
+func (s *Store) ListPartnerAccounts(ctx context.Context, key string) ([]string, error) {
+	return s.partnerAccounts(ctx, hashAPIKey(key))
+}
+
+// AllPartnerAccounts returns every account any Partner API key is mapped
+// to, deduplicated — the watch set priority catch-up front-runs the
+// backlog for.
+func (s *Store) AllPartnerAccounts(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT account FROM partner_accounts ORDER BY account`)
+	if err != nil {
+		return nil, fmt.Errorf("store: reading partner accounts: %w", err)
+	}
+	defer rows.Close()
+	var accounts []string
+	for rows.Next() {
+		var account string
+		if err := rows.Scan(&account); err != nil {
+			return nil, fmt.Errorf("store: scanning partner account: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// partnerAccounts loads a key's account scope by hash; ValidateAPIKey
+// calls it on every request, which is what makes ownership changes take
+// effect without a restart.
+func (s *Store) partnerAccounts(ctx context.Context, keyHash string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT account FROM partner_accounts WHERE key_hash = $1 ORDER BY account`, keyHash)
+	if err != nil {
+		return nil, fmt.Errorf("store: reading partner accounts: %w", err)
+	}
+	defer rows.Close()
+	var accounts []string
+	for rows.Next() {
+		var account string
+		if err := rows.Scan(&account); err != nil {
+			return nil, fmt.Errorf("store: scanning partner account: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// SaveRawTransaction keeps a transaction's original JSON and metadata so
+// the reprocess command can replay parsing without refetching ledgers.
+func (s *Store) SaveRawTransaction(ctx context.Context, ledgerIndex uint64, tx *xrpl.Transaction) error {
+	defer s.observeQuery("save raw transaction", time.Now())
+	raw, meta, err := rawTransactionPayload(tx)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO raw_transactions (ledger_index, tx_hash, tx, meta)
+		VALUES ($1,$2,$3,$4)
+		ON CONFLICT (ledger_index, tx_hash) DO UPDATE SET
+			tx = EXCLUDED.tx, meta = EXCLUDED.meta`,
+		ledgerIndex, tx.Hash, raw, meta)
+	if err != nil {
+		return fmt.Errorf("store: raw tx %s: %w", tx.Hash, err)
+	}
+	return nil
+}
+
+// rawTransactionPayload renders the tx and meta columns, passing the bytes
+// rippled sent straight through whenever the transaction still carries
+// them; only transactions without raw JSON (e.g. decoded from a binary
+// blob) pay for an encode.
+func rawTransactionPayload(tx *xrpl.Transaction) (string, any, error) {
+	raw := tx.Raw
+	if len(raw) == 0 {
+		var err error
+		if raw, err = json.Marshal(tx); err != nil {
+			return "", nil, fmt.Errorf("store: raw tx %s: %w", tx.Hash, err)
+		}
+	}
+	var meta any
+	switch {
+	case len(tx.RawMeta) > 0:
+		meta = string(tx.RawMeta)
+	case tx.Meta != nil:
+		metaJSON, err := json.Marshal(tx.Meta)
+		if err != nil {
+			return "", nil, fmt.Errorf("store: raw tx %s meta: %w", tx.Hash, err)
+		}
+		meta = string(metaJSON)
+	}
+	return string(raw), meta, nil
+}
+
+// LoadRawLedger rebuilds a ledger from its checkpoint header and stored
+// raw transactions, ordered by their position in the ledger.
+func (s *Store) LoadRawLedger(ctx context.Context, index uint64) (*xrpl.Ledger, error) {
+	lg := &xrpl.Ledger{Index: index}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT ledger_hash, close_time FROM checkpoints WHERE ledger_index = $1`,
+		index).Scan(&lg.Hash, &lg.CloseTime)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("store: ledger %d was never indexed", index)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: load ledger %d: %w", index, err)
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT tx, meta FROM raw_transactions WHERE ledger_index = $1`, index)
+	if err != nil {
+		return nil, fmt.Errorf("store: load ledger %d: %w", index, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rawTx string
+		var rawMeta sql.NullString
+		if err := rows.Scan(&rawTx, &rawMeta); err != nil {
+			return nil, fmt.Errorf("store: scanning raw tx: %w", err)
+		}
+		var tx xrpl.Transaction
+		if err := json.Unmarshal([]byte(rawTx), &tx); err != nil {
+			return nil, fmt.Errorf("store: decoding raw tx: %w", err)
+		}
+		tx.Raw = json.RawMessage(rawTx)
+		if rawMeta.Valid {
+			tx.RawMeta = json.RawMessage(rawMeta.String)
+			if tx.Meta == nil {
+				var meta xrpl.Meta
+				if err := json.Unmarshal(tx.RawMeta, &meta); err != nil {
+					return nil, fmt.Errorf("store: decoding raw tx meta: %w", err)
+				}
+				tx.Meta = &meta
+			}
+		}
+		lg.Transactions = append(lg.Transactions, &tx)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(lg.Transactions, func(i, j int) bool {
+		a, b := lg.Transactions[i], lg.Transactions[j]
+		if a.Meta == nil || b.Meta == nil {
+			return a.Meta != nil
+		}
+		return a.Meta.TransactionIndex < b.Meta.TransactionIndex
+	})
+	return lg, nil
+}
+
+// LedgerParserVersion reports the newest parser version among a ledger's
+// offers, or -1 when the ledger produced none.
+func (s *Store) LedgerParserVersion(ctx context.Context, index uint64) (int, error) {
+	var version int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(parser_version), -1) FROM offers WHERE ledger_index = $1`,
+		index).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("store: parser version of ledger %d: %w", index, err)
+	}
+	return version, nil
+}
+
+// DeleteTradesForLedger clears a ledger's trades ahead of a replay, since
+// trades are append-only and would otherwise duplicate.
+func (s *Store) DeleteTradesForLedger(ctx context.Context, index uint64) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM trades WHERE ledger_index = $1`, index); err != nil {
+		return fmt.Errorf("store: delete trades of ledger %d: %w", index, err)
+	}
+	return nil
+}
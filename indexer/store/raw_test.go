@@ -0,0 +1,84 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func benchTransaction() *xrpl.Transaction {
+	return &xrpl.Transaction{
+		Hash: "BENCH", TransactionType: "OfferCreate",
+		Account: "rBench", Sequence: 42,
+		TakerGets: xrpl.Amount{Value: "100"},
+		TakerPays: xrpl.Amount{Currency: "USD", Issuer: "rI", Value: "200"},
+		Meta: &xrpl.Meta{TransactionResult: "tesSUCCESS", AffectedNodes: []xrpl.AffectedNode{{
+			Created: &xrpl.NodeData{
+				LedgerEntryType: "Offer",
+				NewFields:       json.RawMessage(`{"Account":"rBench","Sequence":42}`),
+			},
+		}}},
+	}
+}
+
+func TestRawTransactionPayloadPassesBytesThrough(t *testing.T) {
+	tx := benchTransaction()
+	tx.Raw = json.RawMessage(`{"hash":"BENCH","TransactionType":"OfferCreate"}`)
+	tx.RawMeta = json.RawMessage(`{"TransactionResult":"tesSUCCESS"}`)
+	raw, meta, err := rawTransactionPayload(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw != string(tx.Raw) {
+		t.Errorf("tx column = %q", raw)
+	}
+	if meta != string(tx.RawMeta) {
+		t.Errorf("meta column = %v", meta)
+	}
+}
+
+func TestRawTransactionPayloadEncodesWhenRawMissing(t *testing.T) {
+	raw, meta, err := rawTransactionPayload(benchTransaction())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tx xrpl.Transaction
+	if err := json.Unmarshal([]byte(raw), &tx); err != nil {
+		t.Fatal(err)
+	}
+	if tx.Hash != "BENCH" || tx.Sequence != 42 {
+		t.Errorf("re-encoded tx = %+v", tx)
+	}
+	if meta == nil {
+		t.Error("meta column missing")
+	}
+}
+
+func BenchmarkRawTransactionPayload(b *testing.B) {
+	passthrough := benchTransaction()
+	var err error
+	if passthrough.Raw, err = json.Marshal(passthrough); err != nil {
+		b.Fatal(err)
+	}
+	if passthrough.RawMeta, err = json.Marshal(passthrough.Meta); err != nil {
+		b.Fatal(err)
+	}
+	b.Run("passthrough", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := rawTransactionPayload(passthrough); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	reencode := benchTransaction()
+	b.Run("reencode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := rawTransactionPayload(reencode); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,223 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// Sides an offer query can filter on, relative to the base asset: a
+// "sell" offer gives the base away (TakerGets = base), a "buy" offer
+// wants it (TakerPays = base).
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+)
+
+// GetOpenOffers returns still-active offers on the base/quote market,
+// newest ledger first. base and quote are asset keys ("XRP" or
+// "CUR.issuer"); side narrows to one direction or, when empty, returns
+// both. funded keeps only offers whose owner's last observed balance
+// covered at least part of them — offers never checked are dropped too,
+// since their funding is unknown. Pagination is limit/offset; a
+// non-positive limit defaults to 100.
+func (s *Store) GetOpenOffers(ctx context.Context, base, quote, side string, funded bool, limit, offset int) ([]*model.Offer, error) {
+	defer s.observeQuery("open offers", time.Now())
+	b, err := model.ParseAssetKey(base)
+	if err != nil {
+		return nil, fmt.Errorf("store: open offers: %w", err)
+	}
+	q, err := model.ParseAssetKey(quote)
+	if err != nil {
+		return nil, fmt.Errorf("store: open offers: %w", err)
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	sells := side == SideSell || side == ""
+	buys := side == SideBuy || side == ""
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT o.tx_hash, o.account, o.sequence, o.flags,
+			o.gets_currency, o.gets_issuer, o.gets_value,
+			o.pays_currency, o.pays_issuer, o.pays_value,
+			COALESCE(o.limit_price::text, ''), COALESCE(o.executed_price::text, ''),
+			o.status, o.ledger_index, COALESCE(o.replaced_by, 0), COALESCE(o.parse_error, ''),
+			COALESCE(o.funded_gets_value::text, ''), o.unfunded, o.funded_checked_ledger
+		FROM offers o
+		WHERE o.status IN ('open', 'partially_filled')
+		  AND (($5 AND o.gets_currency = $1 AND o.gets_issuer = $2
+		            AND o.pays_currency = $3 AND o.pays_issuer = $4)
+		    OR ($6 AND o.gets_currency = $3 AND o.gets_issuer = $4
+		            AND o.pays_currency = $1 AND o.pays_issuer = $2))
+		  AND (NOT $7 OR (NOT o.unfunded AND o.funded_checked_ledger > 0))
+		ORDER BY o.ledger_index DESC, o.tx_hash
+		LIMIT $8 OFFSET $9`,
+		b.Currency, b.Issuer, q.Currency, q.Issuer, sells, buys, funded, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("store: open offers: %w", err)
+	}
+	defer rows.Close()
+	return scanOffers(rows)
+}
+
+// GetAMMPools returns pools most recently updated first, paged by
+// limit/offset; a non-positive limit defaults to 100.
+func (s *Store) GetAMMPools(ctx context.Context, limit, offset int) ([]*model.Pool, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT account, asset1_currency, asset1_issuer, asset1_value::text,
+			asset2_currency, asset2_issuer, asset2_value::text,
+			trading_fee, created_ledger, lp_token_currency, ledger_index
+		FROM pools ORDER BY ledger_index DESC, account LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("store: pools: %w", err)
+	}
+	defer rows.Close()
+	var pools []*model.Pool
+	for rows.Next() {
+		var p model.Pool
+		if err := rows.Scan(&p.Account,
+			&p.Asset1.Currency, &p.Asset1.Issuer, &p.Asset1.Value,
+			&p.Asset2.Currency, &p.Asset2.Issuer, &p.Asset2.Value,
+			&p.TradingFee, &p.CreatedLedger, &p.LPTokenCurrency, &p.LedgerIndex); err != nil {
+			return nil, fmt.Errorf("store: scanning pool: %w", err)
+		}
+		pools = append(pools, &p)
+	}
+	return pools, rows.Err()
+}
+
+// GetPoolByAssets returns the pool for an asset pair in either
+// orientation, or nil when no such pool is indexed.
+func (s *Store) GetPoolByAssets(ctx context.Context, asset1, asset2 string) (*model.Pool, error) {
+	a, err := model.ParseAssetKey(asset1)
+	if err != nil {
+		return nil, fmt.Errorf("store: pool lookup: %w", err)
+	}
+	b, err := model.ParseAssetKey(asset2)
+	if err != nil {
+		return nil, fmt.Errorf("store: pool lookup: %w", err)
+	}
+	var p model.Pool
+	err = s.db.QueryRowContext(ctx, `
+		SELECT account, asset1_currency, asset1_issuer, asset1_value::text,
+			asset2_currency, asset2_issuer, asset2_value::text,
+			trading_fee, created_ledger, lp_token_currency, ledger_index
+		FROM pools
+		WHERE (asset1_currency = $1 AND asset1_issuer = $2
+		       AND asset2_currency = $3 AND asset2_issuer = $4)
+		   OR (asset1_currency = $3 AND asset1_issuer = $4
+		       AND asset2_currency = $1 AND asset2_issuer = $2)`,
+		a.Currency, a.Issuer, b.Currency, b.Issuer).Scan(&p.Account,
+		&p.Asset1.Currency, &p.Asset1.Issuer, &p.Asset1.Value,
+		&p.Asset2.Currency, &p.Asset2.Issuer, &p.Asset2.Value,
+		&p.TradingFee, &p.CreatedLedger, &p.LPTokenCurrency, &p.LedgerIndex)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: pool lookup: %w", err)
+	}
+	return &p, nil
+}
+
+// GetPoolsByAsset returns every pool holding the given asset on either
+// side, most recently updated first; a non-positive limit defaults to
+// 100. The currency goes through the canonical normalization, so hex
+// currency codes match however the caller cased them.
+func (s *Store) GetPoolsByAsset(ctx context.Context, currency, issuer string, limit int) ([]*model.Pool, error) {
+	asset, err := xrpl.ParseAsset(xrpl.Asset{Currency: currency, Issuer: issuer}.String())
+	if err != nil {
+		return nil, fmt.Errorf("store: pools by asset: %w", err)
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT account, asset1_currency, asset1_issuer, asset1_value::text,
+			asset2_currency, asset2_issuer, asset2_value::text,
+			trading_fee, created_ledger, lp_token_currency, ledger_index
+		FROM pools
+		WHERE (asset1_currency = $1 AND asset1_issuer = $2)
+		   OR (asset2_currency = $1 AND asset2_issuer = $2)
+		ORDER BY ledger_index DESC, account LIMIT $3`,
+		asset.Currency, asset.Issuer, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: pools by asset: %w", err)
+	}
+	defer rows.Close()
+	var pools []*model.Pool
+	for rows.Next() {
+		var p model.Pool
+		if err := rows.Scan(&p.Account,
+			&p.Asset1.Currency, &p.Asset1.Issuer, &p.Asset1.Value,
+			&p.Asset2.Currency, &p.Asset2.Issuer, &p.Asset2.Value,
+			&p.TradingFee, &p.CreatedLedger, &p.LPTokenCurrency, &p.LedgerIndex); err != nil {
+			return nil, fmt.Errorf("store: scanning pool: %w", err)
+		}
+		pools = append(pools, &p)
+	}
+	return pools, rows.Err()
+}
+
+// GetTrades returns fills on the base/quote market in either direction,
+// newest ledger first. Pagination is limit/offset; a non-positive limit
+// defaults to 100.
+func (s *Store) GetTrades(ctx context.Context, base, quote string, limit, offset int) ([]*model.Trade, error) {
+	defer s.observeQuery("trades", time.Now())
+	b, err := model.ParseAssetKey(base)
+	if err != nil {
+		return nil, fmt.Errorf("store: trades: %w", err)
+	}
+	q, err := model.ParseAssetKey(quote)
+	if err != nil {
+		return nil, fmt.Errorf("store: trades: %w", err)
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT maker, taker, offer_sequence,
+			gets_currency, gets_issuer, gets_value::text,
+			pays_currency, pays_issuer, pays_value::text,
+			COALESCE(price::text, ''), ledger_index, tx_hash
+		FROM trades
+		WHERE ((gets_currency = $1 AND gets_issuer = $2
+		        AND pays_currency = $3 AND pays_issuer = $4)
+		    OR (gets_currency = $3 AND gets_issuer = $4
+		        AND pays_currency = $1 AND pays_issuer = $2))
+		ORDER BY ledger_index DESC, id DESC
+		LIMIT $5 OFFSET $6`,
+		b.Currency, b.Issuer, q.Currency, q.Issuer, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("store: trades: %w", err)
+	}
+	defer rows.Close()
+	var trades []*model.Trade
+	for rows.Next() {
+		var tr model.Trade
+		if err := rows.Scan(&tr.Maker, &tr.Taker, &tr.OfferSequence,
+			&tr.Gets.Currency, &tr.Gets.Issuer, &tr.Gets.Value,
+			&tr.Pays.Currency, &tr.Pays.Issuer, &tr.Pays.Value,
+			&tr.Price, &tr.LedgerIndex, &tr.TxHash); err != nil {
+			return nil, fmt.Errorf("store: scanning trade: %w", err)
+		}
+		trades = append(trades, &tr)
+	}
+	return trades, rows.Err()
+}
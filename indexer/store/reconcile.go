@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// ListPools returns up to limit pools, most recently updated first, so a
+// sampled consistency check covers the pools that actually move. A
+// non-positive limit defaults to 100.
+func (s *Store) ListPools(ctx context.Context, limit int) ([]*model.Pool, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT account, asset1_currency, asset1_issuer, asset1_value::text,
+			asset2_currency, asset2_issuer, asset2_value::text,
+			trading_fee, ledger_index
+		FROM pools ORDER BY ledger_index DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: list pools: %w", err)
+	}
+	defer rows.Close()
+	var pools []*model.Pool
+	for rows.Next() {
+		var p model.Pool
+		if err := rows.Scan(&p.Account,
+			&p.Asset1.Currency, &p.Asset1.Issuer, &p.Asset1.Value,
+			&p.Asset2.Currency, &p.Asset2.Issuer, &p.Asset2.Value,
+			&p.TradingFee, &p.LedgerIndex); err != nil {
+			return nil, fmt.Errorf("store: scanning pool: %w", err)
+		}
+		pools = append(pools, &p)
+	}
+	return pools, rows.Err()
+}
+
+// RecordPoolDiscrepancy appends one field-level mismatch between stored
+// pool state and the live AMM object to the reconciliation log.
+func (s *Store) RecordPoolDiscrepancy(ctx context.Context, account, field, stored, actual string, ledgerIndex uint64) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO pool_reconciliations (pool_account, field, stored, actual, ledger_index)
+		VALUES ($1,$2,$3,$4,$5)`,
+		account, field, stored, actual, ledgerIndex)
+	if err != nil {
+		return fmt.Errorf("store: record discrepancy %s/%s: %w", account, field, err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// Fork recovery. When validators disagree and a bad ledger slipped in,
+// the operator rolls the checkpoints back to a known-good ledger —
+// usually identified by its hash, not its index — and reprocesses
+// forward. Derived rows (offers, trades, pools) are deliberately NOT
+// deleted here: they are not reversible row-by-row, but every write path
+// is idempotent or guarded by ledger_index, so replaying the range
+// forward from the rollback point converges them onto the canonical
+// chain. What the rollback does delete is the checkpoints, the one
+// record that claims those ledgers are done; the removed range lands in
+// failed_ledgers so the existing reprocess tooling targets exactly it.
+
+// GetCheckpointByHash returns the checkpoint carrying the given ledger
+// hash, or nil when no indexed ledger has it. If reprocessing ever left
+// the same hash on two indexes, the highest one wins.
+func (s *Store) GetCheckpointByHash(ctx context.Context, hash string) (*model.Checkpoint, error) {
+	var cp model.Checkpoint
+	err := s.db.QueryRowContext(ctx, `
+		SELECT ledger_index, ledger_hash, close_time
+		FROM checkpoints WHERE ledger_hash = $1
+		ORDER BY ledger_index DESC LIMIT 1`, hash).
+		Scan(&cp.LedgerIndex, &cp.LedgerHash, &cp.CloseTime)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: checkpoint by hash %s: %w", hash, err)
+	}
+	return &cp, nil
+}
+
+// RollbackTo deletes every checkpoint above the given ledger and records
+// each removed index in failed_ledgers, in one transaction, returning
+// how many checkpoints were removed. Rows at or below the target are
+// untouched. The caller then reprocesses the failed range forward to
+// rebuild clean state.
+func (s *Store) RollbackTo(ctx context.Context, ledgerIndex uint64) (int64, error) {
+	defer s.observeQuery("rollback", time.Now())
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("store: rollback to %d: %w", ledgerIndex, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO failed_ledgers (ledger_index, error)
+		SELECT ledger_index, 'rolled back past ledger ' || $1
+		FROM checkpoints WHERE ledger_index > $1
+		ON CONFLICT (ledger_index) DO UPDATE SET
+			error = EXCLUDED.error, failed_at = now()`, ledgerIndex); err != nil {
+		return 0, fmt.Errorf("store: rollback to %d: marking range: %w", ledgerIndex, err)
+	}
+	res, err := tx.ExecContext(ctx,
+		`DELETE FROM checkpoints WHERE ledger_index > $1`, ledgerIndex)
+	if err != nil {
+		return 0, fmt.Errorf("store: rollback to %d: %w", ledgerIndex, err)
+	}
+	removed, _ := res.RowsAffected()
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("store: rollback to %d: commit: %w", ledgerIndex, err)
+	}
+
+	// The in-process caches may hold rows the rollback just disowned;
+	// drop them wholesale rather than guessing keys.
+	if s.cpCache != nil {
+		s.cpCache = newCheckpointCache(s.cpCache.size)
+	}
+	if s.offerCache != nil {
+		s.offerCache.flush()
+	}
+	return removed, nil
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+func TestRollbackToDeletesOnlyAfterTarget(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	cleanup := func() {
+		mustExec(t, st, `DELETE FROM checkpoints WHERE ledger_index BETWEEN 90012000 AND 90012005`)
+		mustExec(t, st, `DELETE FROM failed_ledgers WHERE ledger_index BETWEEN 90012000 AND 90012005`)
+	}
+	cleanup()
+	defer cleanup()
+
+	for i := uint64(90012000); i <= 90012005; i++ {
+		if err := st.SaveCheckpoint(ctx, &model.Checkpoint{
+			LedgerIndex: i, LedgerHash: fmt.Sprintf("RB%d", i-90012000), CloseTime: 700003000 + int64(i),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := st.RollbackTo(ctx, 90012002)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 3 {
+		t.Errorf("removed = %d, want 3", removed)
+	}
+
+	// Everything at or below the target survives; everything above is gone.
+	for i := uint64(90012000); i <= 90012005; i++ {
+		cp, err := st.GetCheckpoint(ctx, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := i <= 90012002; (cp != nil) != want {
+			t.Errorf("checkpoint %d present = %v, want %v", i, cp != nil, want)
+		}
+	}
+
+	// The removed range, and only it, is queued for reprocessing.
+	failed, err := st.ListFailedLedgers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inRange []uint64
+	for _, index := range failed {
+		if index >= 90012000 && index <= 90012005 {
+			inRange = append(inRange, index)
+		}
+	}
+	want := []uint64{90012003, 90012004, 90012005}
+	if len(inRange) != len(want) || inRange[0] != want[0] || inRange[2] != want[2] {
+		t.Errorf("failed ledgers after rollback = %v, want %v", inRange, want)
+	}
+
+	// A second rollback to the same point is a no-op.
+	if removed, err := st.RollbackTo(ctx, 90012002); err != nil || removed != 0 {
+		t.Errorf("repeated rollback = %d, %v; want 0, nil", removed, err)
+	}
+}
+
+func TestGetCheckpointByHash(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	mustExec(t, st, `DELETE FROM checkpoints WHERE ledger_index = 90012100`)
+	defer mustExec(t, st, `DELETE FROM checkpoints WHERE ledger_index = 90012100`)
+
+	if err := st.SaveCheckpoint(ctx, &model.Checkpoint{
+		LedgerIndex: 90012100, LedgerHash: "RBHASH", CloseTime: 700003100,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := st.GetCheckpointByHash(ctx, "RBHASH")
+	if err != nil || cp == nil || cp.LedgerIndex != 90012100 {
+		t.Fatalf("checkpoint by hash = %+v, %v", cp, err)
+	}
+	if cp, err := st.GetCheckpointByHash(ctx, "RBUNKNOWN"); err != nil || cp != nil {
+		t.Fatalf("unknown hash = %+v, %v; want nil, nil", cp, err)
+	}
+}
@@ -0,0 +1,521 @@
+-- Schema for the indexer's Postgres store. Statements are idempotent so
+-- Migrate can run on every start.
+
+CREATE TABLE IF NOT EXISTS offers (
+    tx_hash        TEXT PRIMARY KEY,
+    account        TEXT    NOT NULL,
+    sequence       BIGINT  NOT NULL,
+    flags          BIGINT  NOT NULL DEFAULT 0,
+    gets_currency  TEXT    NOT NULL,
+    gets_issuer    TEXT    NOT NULL DEFAULT '',
+    gets_value     NUMERIC NOT NULL,
+    pays_currency  TEXT    NOT NULL,
+    pays_issuer    TEXT    NOT NULL DEFAULT '',
+    pays_value     NUMERIC NOT NULL,
+    limit_price    NUMERIC,
+    executed_price NUMERIC,
+    status         TEXT    NOT NULL,
+    ledger_index   BIGINT  NOT NULL,
+    parser_version INT     NOT NULL DEFAULT 0,
+    replaced_by    BIGINT,
+    parse_error    TEXT,
+    -- Funding enrichment (-compute-funded): how much of gets_value the
+    -- owner's last observed balance covered, whether that balance was
+    -- empty, and the ledger the observation came from.
+    funded_gets_value     NUMERIC,
+    unfunded              BOOLEAN NOT NULL DEFAULT FALSE,
+    funded_checked_ledger BIGINT  NOT NULL DEFAULT 0,
+    -- Phase one of -priority-catchup wrote this row ahead of its
+    -- ledger's full parse; the phase-two replay clears it.
+    provisional           BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS parser_version INT NOT NULL DEFAULT 0;
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS replaced_by BIGINT;
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS parse_error TEXT;
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS funded_gets_value NUMERIC;
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS unfunded BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS funded_checked_ledger BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS provisional BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- RefreshFundedOffers updates by owner and TakerGets asset.
+CREATE INDEX IF NOT EXISTS offers_funding_idx ON offers (account, gets_currency, gets_issuer)
+    WHERE status IN ('open', 'partially_filled');
+
+-- The account listing pages by a keyset cursor over this exact order, so
+-- large accounts seek straight to their page instead of scanning it.
+CREATE INDEX IF NOT EXISTS offers_account_ledger_idx ON offers (account, ledger_index, sequence);
+
+-- The natural key of an offer is (account, sequence): replacements and
+-- re-submitted transactions must converge on one row. Dedupe anything
+-- older migrations let through, keeping the newest row per key.
+DELETE FROM offers a USING offers b
+ WHERE a.account = b.account AND a.sequence = b.sequence
+   AND (a.ledger_index < b.ledger_index
+     OR (a.ledger_index = b.ledger_index AND a.tx_hash < b.tx_hash));
+
+DROP INDEX IF EXISTS offers_account_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS offers_account_sequence_key ON offers (account, sequence);
+
+-- Every status an offer passes through, with the ledger that moved it
+-- there. Rows are never deleted; GetOfferHistory reads them back for
+-- partner audits.
+CREATE TABLE IF NOT EXISTS offer_events (
+    account      TEXT   NOT NULL,
+    sequence     BIGINT NOT NULL,
+    status       TEXT   NOT NULL,
+    ledger_index BIGINT NOT NULL,
+    close_time   BIGINT NOT NULL DEFAULT 0,
+    tx_hash      TEXT   NOT NULL DEFAULT '',
+    PRIMARY KEY (account, sequence, ledger_index, status)
+);
+
+ALTER TABLE offer_events ADD COLUMN IF NOT EXISTS close_time BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE offer_events ADD COLUMN IF NOT EXISTS tx_hash TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS offer_events_account_idx ON offer_events (account, ledger_index);
+
+CREATE TABLE IF NOT EXISTS trades (
+    id             BIGSERIAL PRIMARY KEY,
+    maker          TEXT    NOT NULL,
+    taker          TEXT    NOT NULL,
+    offer_sequence BIGINT  NOT NULL,
+    gets_currency  TEXT    NOT NULL,
+    gets_issuer    TEXT    NOT NULL DEFAULT '',
+    gets_value     NUMERIC NOT NULL,
+    pays_currency  TEXT    NOT NULL,
+    pays_issuer    TEXT    NOT NULL DEFAULT '',
+    pays_value     NUMERIC NOT NULL,
+    price          NUMERIC,
+    ledger_index   BIGINT  NOT NULL,
+    tx_hash        TEXT    NOT NULL,
+    parser_version INT     NOT NULL DEFAULT 0
+);
+
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS parser_version INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS trades_ledger_idx ON trades (ledger_index);
+
+-- The account listing attaches fills by maker and offer sequence.
+CREATE INDEX IF NOT EXISTS trades_maker_idx ON trades (maker, offer_sequence);
+
+-- One row per fill: redelivered fills (a re-subscribe replaying a
+-- ledger) insert with ON CONFLICT DO NOTHING against this key, making
+-- the table the final arbiter against double-counted trades.
+CREATE UNIQUE INDEX IF NOT EXISTS trades_fill_key ON trades (tx_hash, maker, offer_sequence);
+
+-- One row per market per hour: each ledger's fills folded down so the
+-- 24h statistics sum the last 24 buckets instead of scanning trades.
+-- last_ledger guards the fold — re-applying a replayed ledger finds its
+-- contribution already recorded and changes nothing.
+CREATE TABLE IF NOT EXISTS market_stats_hourly (
+    pair        TEXT    NOT NULL,
+    bucket      BIGINT  NOT NULL,
+    volume      NUMERIC NOT NULL DEFAULT 0,
+    high        NUMERIC,
+    low         NUMERIC,
+    last_price  NUMERIC,
+    last_ledger BIGINT  NOT NULL DEFAULT 0,
+    trade_count BIGINT  NOT NULL DEFAULT 0,
+    PRIMARY KEY (pair, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS market_stats_bucket_idx ON market_stats_hourly (bucket);
+
+-- Markets promoted by activity discovery. Demotion flips the flag and
+-- nothing else: the discovery timestamps survive, and a market whose
+-- activity returns re-promotes in place.
+CREATE TABLE IF NOT EXISTS tracked_markets (
+    pair                   TEXT PRIMARY KEY,
+    discovered_ledger      BIGINT  NOT NULL,
+    discovered_close_time  BIGINT  NOT NULL,
+    last_active_ledger     BIGINT  NOT NULL,
+    last_active_close_time BIGINT  NOT NULL,
+    demoted                BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- Transactional outbox: events land here in the same per-ledger pass as
+-- the data they describe and are only dispatched once their ledger is
+-- checkpointed, so nothing is ever emitted before its data is visible.
+-- (ledger_index, seq) makes replayed ledgers converge instead of
+-- duplicating; id orders the global dispatch stream.
+CREATE TABLE IF NOT EXISTS outbox (
+    id           BIGSERIAL PRIMARY KEY,
+    ledger_index BIGINT NOT NULL,
+    seq          INT    NOT NULL,
+    topic        TEXT   NOT NULL,
+    payload      TEXT   NOT NULL,
+    UNIQUE (ledger_index, seq)
+);
+
+-- One cursor per registered emitter: everything at or below last_id has
+-- been handed over at least once. Rows behind every cursor are prunable.
+CREATE TABLE IF NOT EXISTS outbox_cursors (
+    emitter    TEXT   PRIMARY KEY,
+    last_id    BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Raw transactions keep the original rippled JSON so parser fixes can be
+-- replayed over history without refetching ledgers.
+CREATE TABLE IF NOT EXISTS raw_transactions (
+    ledger_index BIGINT NOT NULL,
+    tx_hash      TEXT   NOT NULL,
+    tx           TEXT   NOT NULL,
+    meta         TEXT,
+    PRIMARY KEY (ledger_index, tx_hash)
+);
+
+CREATE TABLE IF NOT EXISTS pools (
+    account        TEXT PRIMARY KEY,
+    asset1_currency TEXT    NOT NULL,
+    asset1_issuer   TEXT    NOT NULL DEFAULT '',
+    asset1_value    NUMERIC NOT NULL,
+    asset2_currency TEXT    NOT NULL,
+    asset2_issuer   TEXT    NOT NULL DEFAULT '',
+    asset2_value    NUMERIC NOT NULL,
+    trading_fee     INT     NOT NULL DEFAULT 0,
+    lp_supply       NUMERIC NOT NULL DEFAULT 0,
+    created_ledger  BIGINT  NOT NULL DEFAULT 0,
+    lp_token_currency TEXT  NOT NULL DEFAULT '',
+    ledger_index    BIGINT  NOT NULL
+);
+
+ALTER TABLE pools ADD COLUMN IF NOT EXISTS lp_supply NUMERIC NOT NULL DEFAULT 0;
+ALTER TABLE pools ADD COLUMN IF NOT EXISTS created_ledger BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE pools ADD COLUMN IF NOT EXISTS lp_token_currency TEXT NOT NULL DEFAULT '';
+
+-- Asset-side lookups ("all pools containing USD.rIssuer") scan whichever
+-- side holds the asset, so both sides get an index.
+CREATE INDEX IF NOT EXISTS pools_asset1_idx ON pools (asset1_currency, asset1_issuer);
+CREATE INDEX IF NOT EXISTS pools_asset2_idx ON pools (asset2_currency, asset2_issuer);
+
+-- One row per pool update: the pool's reserves right after the change.
+-- tvl_xrp values pools with an XRP leg at twice their XRP reserve; rows
+-- older than the downsample horizon are thinned to hourly by PrunePoolHistory.
+CREATE TABLE IF NOT EXISTS pool_reserves_history (
+    pool_account TEXT    NOT NULL,
+    ledger_index BIGINT  NOT NULL,
+    close_time   BIGINT  NOT NULL DEFAULT 0,
+    asset1_value NUMERIC NOT NULL,
+    asset2_value NUMERIC NOT NULL,
+    lp_supply    NUMERIC NOT NULL DEFAULT 0,
+    trading_fee  INT     NOT NULL DEFAULT 0,
+    tvl_xrp      NUMERIC,
+    PRIMARY KEY (pool_account, ledger_index)
+);
+
+CREATE INDEX IF NOT EXISTS pool_reserves_history_time_idx ON pool_reserves_history (pool_account, close_time);
+
+CREATE TABLE IF NOT EXISTS checkpoints (
+    ledger_index BIGINT PRIMARY KEY,
+    ledger_hash  TEXT        NOT NULL,
+    close_time   BIGINT      NOT NULL,
+    network      TEXT        NOT NULL DEFAULT 'mainnet',
+    -- indexed_at follows the latest processing of the ledger;
+    -- first_indexed_at is stamped on insert and never updated, so a
+    -- backfill overlap or reprocess keeps the original indexing time.
+    indexed_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    first_indexed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- Per-stage processing milliseconds for the ledger (total_ms plus
+    -- one key per pipeline stage); NULL for ledgers indexed before the
+    -- instrumentation existed.
+    timings      JSONB,
+    -- The ledger's transaction composition: counts keyed by
+    -- TransactionType under "types" and by TransactionResult under
+    -- "results"; NULL for empty ledgers and rows predating the column.
+    tx_stats     JSONB
+);
+
+ALTER TABLE checkpoints ADD COLUMN IF NOT EXISTS network TEXT NOT NULL DEFAULT 'mainnet';
+ALTER TABLE checkpoints ADD COLUMN IF NOT EXISTS timings JSONB;
+ALTER TABLE checkpoints ADD COLUMN IF NOT EXISTS tx_stats JSONB;
+ALTER TABLE checkpoints ADD COLUMN IF NOT EXISTS first_indexed_at TIMESTAMPTZ NOT NULL DEFAULT now();
+-- Rows predating the column got stamped with the migration time; their
+-- true first sighting is the indexed_at the old schema never updated.
+UPDATE checkpoints SET first_indexed_at = indexed_at WHERE first_indexed_at > indexed_at;
+
+CREATE TABLE IF NOT EXISTS amm_fee_history (
+    pool_account TEXT   NOT NULL,
+    trading_fee  INT    NOT NULL,
+    ledger_index BIGINT NOT NULL,
+    tx_hash      TEXT   NOT NULL,
+    PRIMARY KEY (pool_account, ledger_index)
+);
+
+-- Field-level mismatches found by the pool consistency check: what the
+-- store said versus what rippled's amm_info reported at check time.
+CREATE TABLE IF NOT EXISTS pool_reconciliations (
+    id           BIGSERIAL PRIMARY KEY,
+    pool_account TEXT        NOT NULL,
+    field        TEXT        NOT NULL,
+    stored       TEXT        NOT NULL,
+    actual       TEXT        NOT NULL,
+    ledger_index BIGINT      NOT NULL DEFAULT 0,
+    checked_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Book drift found by `indexer check-books`: stored open offers against
+-- the live book_offers view, both pinned to one ledger. fixed records
+-- whether -fix reconciled the row to the ledger's view.
+CREATE TABLE IF NOT EXISTS book_discrepancies (
+    id           BIGSERIAL PRIMARY KEY,
+    pair         TEXT        NOT NULL,
+    kind         TEXT        NOT NULL,
+    account      TEXT        NOT NULL,
+    sequence     BIGINT      NOT NULL,
+    stored       TEXT        NOT NULL DEFAULT '',
+    actual       TEXT        NOT NULL DEFAULT '',
+    ledger_index BIGINT      NOT NULL,
+    fixed        BOOLEAN     NOT NULL DEFAULT FALSE,
+    checked_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS failed_ledgers (
+    ledger_index BIGINT PRIMARY KEY,
+    error        TEXT        NOT NULL,
+    failed_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS amendments (
+    hash         TEXT PRIMARY KEY,
+    name         TEXT   NOT NULL DEFAULT '',
+    ledger_index BIGINT NOT NULL DEFAULT 0,
+    tx_hash      TEXT   NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS gaps (
+    from_ledger BIGINT NOT NULL,
+    to_ledger   BIGINT NOT NULL,
+    reason      TEXT        NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (from_ledger, to_ledger)
+);
+
+-- Per-transaction balance deltas extracted from metadata when
+-- -track-balances is on; the reconciliation surface for partner reporting.
+CREATE TABLE IF NOT EXISTS balance_changes (
+    account      TEXT    NOT NULL,
+    currency     TEXT    NOT NULL,
+    issuer       TEXT    NOT NULL DEFAULT '',
+    delta        NUMERIC NOT NULL,
+    ledger_index BIGINT  NOT NULL,
+    close_time   BIGINT  NOT NULL DEFAULT 0,
+    tx_hash      TEXT    NOT NULL,
+    PRIMARY KEY (tx_hash, account, currency, issuer)
+);
+
+CREATE INDEX IF NOT EXISTS balance_changes_account_idx ON balance_changes (account, ledger_index);
+
+CREATE TABLE IF NOT EXISTS asset_flags (
+    issuer       TEXT   NOT NULL,
+    currency     TEXT   NOT NULL DEFAULT '',
+    flag         TEXT   NOT NULL,
+    ledger_index BIGINT NOT NULL,
+    tx_hash      TEXT   NOT NULL,
+    PRIMARY KEY (issuer, currency, flag)
+);
+
+-- Connection audit: one row per successful connect, recording which kind
+-- of server (rippled or Clio) and version supplied the data after it.
+CREATE TABLE IF NOT EXISTS connections (
+    id           BIGSERIAL PRIMARY KEY,
+    url          TEXT        NOT NULL,
+    server_type  TEXT        NOT NULL,
+    version      TEXT        NOT NULL,
+    network_id   BIGINT      NOT NULL DEFAULT 0,
+    connected_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Free-form service events on the same audit trail: reconnects,
+-- failovers, archive forwarding trouble.
+CREATE TABLE IF NOT EXISTS connection_events (
+    id          BIGSERIAL PRIMARY KEY,
+    service     TEXT        NOT NULL,
+    event       TEXT        NOT NULL,
+    detail      TEXT        NOT NULL DEFAULT '',
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- The archive forwarder's progress marker, kept in the archive database
+-- itself: the last primary ledger whose rows were copied, plus the audit
+-- table cursors. Committed atomically with each copied batch.
+CREATE TABLE IF NOT EXISTS archive_progress (
+    id             INT PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    ledger_index   BIGINT      NOT NULL DEFAULT 0,
+    connections_id BIGINT      NOT NULL DEFAULT 0,
+    events_id      BIGINT      NOT NULL DEFAULT 0,
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Ephemeral-to-master key mapping published in validator manifests; the
+-- highest sequence wins per master key.
+CREATE TABLE IF NOT EXISTS manifests (
+    signing_key TEXT   NOT NULL PRIMARY KEY,
+    master_key  TEXT   NOT NULL,
+    sequence    BIGINT NOT NULL DEFAULT 0
+);
+
+-- One row per (validator, ledger) vote, keyed by master key; repeated
+-- votes for the same ledger deduplicate here.
+CREATE TABLE IF NOT EXISTS validations (
+    validator    TEXT   NOT NULL,
+    ledger_index BIGINT NOT NULL,
+    ledger_hash  TEXT   NOT NULL,
+    signing_time BIGINT NOT NULL,
+    PRIMARY KEY (validator, ledger_index)
+);
+
+-- Per-validator agreement over a scoring window, one row per window end.
+CREATE TABLE IF NOT EXISTS validator_scores (
+    validator        TEXT   NOT NULL,
+    window_end       BIGINT NOT NULL,
+    window_start     BIGINT NOT NULL,
+    agreed           BIGINT NOT NULL,
+    missed           BIGINT NOT NULL,
+    disagreed        BIGINT NOT NULL,
+    agreement        DOUBLE PRECISION NOT NULL,
+    avg_skew_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    PRIMARY KEY (validator, window_end)
+);
+
+-- Split votes the fork detector caught: one row per competing hash per
+-- ledger index, with the validators (comma-joined master keys) that
+-- signed that side. Normal operation writes nothing here.
+CREATE TABLE IF NOT EXISTS validation_disagreements (
+    ledger_index BIGINT NOT NULL,
+    ledger_hash  TEXT   NOT NULL,
+    votes        INT    NOT NULL,
+    validators   TEXT   NOT NULL,
+    PRIMARY KEY (ledger_index, ledger_hash)
+);
+
+-- Network fee and load per ledger, sampled from the fee command; the
+-- historical record partners read during congestion post-mortems.
+CREATE TABLE IF NOT EXISTS fee_history (
+    ledger_index         BIGINT PRIMARY KEY,
+    base_fee             BIGINT NOT NULL,
+    open_ledger_fee      BIGINT NOT NULL,
+    expected_ledger_size BIGINT NOT NULL DEFAULT 0,
+    load_factor          DOUBLE PRECISION NOT NULL DEFAULT 1,
+    recorded_at          TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Assets are stored in xrpl.Asset canonical form: hex currency codes
+-- uppercase. Backfill rows written before canonicalization existed.
+UPDATE offers SET gets_currency = upper(gets_currency) WHERE gets_currency ~ '^[0-9a-f]*[a-f][0-9a-f]*$' AND length(gets_currency) = 40;
+UPDATE offers SET pays_currency = upper(pays_currency) WHERE pays_currency ~ '^[0-9a-f]*[a-f][0-9a-f]*$' AND length(pays_currency) = 40;
+UPDATE trades SET gets_currency = upper(gets_currency) WHERE gets_currency ~ '^[0-9a-f]*[a-f][0-9a-f]*$' AND length(gets_currency) = 40;
+UPDATE trades SET pays_currency = upper(pays_currency) WHERE pays_currency ~ '^[0-9a-f]*[a-f][0-9a-f]*$' AND length(pays_currency) = 40;
+UPDATE pools SET asset1_currency = upper(asset1_currency) WHERE asset1_currency ~ '^[0-9a-f]*[a-f][0-9a-f]*$' AND length(asset1_currency) = 40;
+UPDATE pools SET asset2_currency = upper(asset2_currency) WHERE asset2_currency ~ '^[0-9a-f]*[a-f][0-9a-f]*$' AND length(asset2_currency) = 40;
+
+-- Partner API credentials. Keys are stored as SHA-256 hashes only;
+-- request_count and last_used_at double as per-key accounting.
+CREATE TABLE IF NOT EXISTS api_keys (
+    key_hash      TEXT PRIMARY KEY,
+    partner       TEXT NOT NULL,
+    rate_limit    INTEGER NOT NULL DEFAULT 60,
+    enabled       BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at  TIMESTAMPTZ,
+    request_count BIGINT NOT NULL DEFAULT 0,
+    scopes        TEXT NOT NULL DEFAULT ''
+);
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS scopes TEXT NOT NULL DEFAULT '';
+
+-- Per-key daily request counts, bumped alongside api_keys.request_count
+-- on every authenticated request; the admin usage endpoint reads it.
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    key_hash TEXT NOT NULL,
+    day      DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_hash, day)
+);
+
+-- Accounts a Partner API key is scoped to. Keys without the "global" or
+-- "admin" scope may only query account-scoped endpoints for accounts
+-- mapped here; the mapping is read on every key validation, so changes
+-- apply without a restart.
+CREATE TABLE IF NOT EXISTS partner_accounts (
+    key_hash TEXT NOT NULL,
+    account  TEXT NOT NULL,
+    added_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (key_hash, account)
+);
+
+-- NFT marketplace offers. Accepts and cancels reference offers only by
+-- their NFTokenOffer ledger entry index, so that is the row's key.
+CREATE TABLE IF NOT EXISTS nft_offers (
+    offer_index     TEXT PRIMARY KEY,
+    token_id        TEXT    NOT NULL,
+    owner           TEXT    NOT NULL,
+    destination     TEXT    NOT NULL DEFAULT '',
+    amount_currency TEXT    NOT NULL,
+    amount_issuer   TEXT    NOT NULL DEFAULT '',
+    amount_value    NUMERIC NOT NULL,
+    sell            BOOLEAN NOT NULL,
+    expiration      BIGINT  NOT NULL DEFAULT 0,
+    status          TEXT    NOT NULL,
+    ledger_index    BIGINT  NOT NULL,
+    close_time      BIGINT  NOT NULL DEFAULT 0,
+    tx_hash         TEXT    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS nft_offers_owner_idx ON nft_offers (owner, ledger_index);
+CREATE INDEX IF NOT EXISTS nft_offers_token_idx ON nft_offers (token_id, ledger_index);
+
+-- Completed NFT sales. One NFTokenAcceptOffer settles exactly one sale,
+-- so tx_hash is the natural key.
+CREATE TABLE IF NOT EXISTS nft_trades (
+    tx_hash             TEXT PRIMARY KEY,
+    token_id            TEXT    NOT NULL,
+    seller              TEXT    NOT NULL,
+    buyer               TEXT    NOT NULL,
+    amount_currency     TEXT    NOT NULL,
+    amount_issuer       TEXT    NOT NULL DEFAULT '',
+    amount_value        NUMERIC NOT NULL,
+    broker_fee_currency TEXT    NOT NULL DEFAULT '',
+    broker_fee_issuer   TEXT    NOT NULL DEFAULT '',
+    broker_fee_value    NUMERIC NOT NULL DEFAULT 0,
+    sell_offer_index    TEXT    NOT NULL DEFAULT '',
+    buy_offer_index     TEXT    NOT NULL DEFAULT '',
+    ledger_index        BIGINT  NOT NULL,
+    close_time          BIGINT  NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS nft_trades_token_idx ON nft_trades (token_id, ledger_index);
+
+-- Trust lines from the holder's perspective, one row per account and
+-- issued currency. Deleted RippleState entries remove rows, so counting
+-- rows per (currency, issuer) gives the holder count.
+CREATE TABLE IF NOT EXISTS trust_lines (
+    account      TEXT    NOT NULL,
+    currency     TEXT    NOT NULL,
+    issuer       TEXT    NOT NULL,
+    limit_value  NUMERIC NOT NULL DEFAULT 0,
+    no_ripple    BOOLEAN NOT NULL DEFAULT FALSE,
+    frozen       BOOLEAN NOT NULL DEFAULT FALSE,
+    ledger_index BIGINT  NOT NULL,
+    close_time   BIGINT  NOT NULL DEFAULT 0,
+    tx_hash      TEXT    NOT NULL,
+    PRIMARY KEY (account, currency, issuer)
+);
+
+CREATE INDEX IF NOT EXISTS trust_lines_issuer_idx ON trust_lines (currency, issuer);
+
+-- Human-readable identities behind issuers and validator master keys,
+-- resolved from the subject's on-ledger Domain and the domain's
+-- xrp-ledger.toml. Negative outcomes (no_domain, unreachable) are rows
+-- too, so the enrichment job can cache them and leave dead domains
+-- alone for at least a day. An on-ledger Domain change deletes the row,
+-- forcing a fresh resolution on the next sweep.
+CREATE TABLE IF NOT EXISTS identities (
+    account      TEXT PRIMARY KEY,
+    domain       TEXT NOT NULL DEFAULT '',
+    name         TEXT NOT NULL DEFAULT '',
+    logo         TEXT NOT NULL DEFAULT '',
+    twitter      TEXT NOT NULL DEFAULT '',
+    status       TEXT NOT NULL,
+    refreshed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
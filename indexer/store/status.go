@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// ListGaps returns every recorded gap, lowest range first.
+func (s *Store) ListGaps(ctx context.Context) ([]*model.Gap, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT from_ledger, to_ledger, reason
+		FROM gaps ORDER BY from_ledger`)
+	if err != nil {
+		return nil, fmt.Errorf("store: listing gaps: %w", err)
+	}
+	defer rows.Close()
+	var gaps []*model.Gap
+	for rows.Next() {
+		var g model.Gap
+		if err := rows.Scan(&g.From, &g.To, &g.Reason); err != nil {
+			return nil, fmt.Errorf("store: scanning gap: %w", err)
+		}
+		gaps = append(gaps, &g)
+	}
+	return gaps, rows.Err()
+}
+
+// CountOpenOffers counts offers still on the book, including partial
+// fills.
+func (s *Store) CountOpenOffers(ctx context.Context) (int64, error) {
+	var n int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT count(*) FROM offers WHERE status IN ($1, $2)`,
+		model.OfferStatusOpen, model.OfferStatusPartiallyFilled).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("store: counting open offers: %w", err)
+	}
+	return n, nil
+}
+
+// CountPools counts tracked AMM pools.
+func (s *Store) CountPools(ctx context.Context) (int64, error) {
+	var n int64
+	if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM pools`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("store: counting pools: %w", err)
+	}
+	return n, nil
+}
+
+// CountFailedLedgersSince counts ledgers that failed processing after
+// the given time and are still unresolved.
+func (s *Store) CountFailedLedgersSince(ctx context.Context, since time.Time) (int64, error) {
+	var n int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT count(*) FROM failed_ledgers WHERE failed_at >= $1`, since).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("store: counting failed ledgers: %w", err)
+	}
+	return n, nil
+}
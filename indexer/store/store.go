@@ -0,0 +1,752 @@
+// Package store persists parsed ledger data to Postgres.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	_ "embed"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/internal/faultinject"
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+
+	// The schema, placeholders, and advisory locks in this package are
+	// Postgres-specific, so the package registers the "postgres" driver
+	// itself rather than leaving every binary and test to remember to.
+	_ "github.com/lib/pq"
+)
+
+//go:embed schema.sql
+var schema string
+
+// SQL fragments for the lifecycle status sets, derived from the state
+// machine in the model package so the guards below cannot drift from it.
+var (
+	terminalStatuses = statusListSQL(model.TerminalOfferStatuses())
+	activeStatuses   = statusListSQL(model.ActiveOfferStatuses())
+)
+
+func statusListSQL(statuses []string) string {
+	return "('" + strings.Join(statuses, "','") + "')"
+}
+
+// Store wraps the SQL database holding indexed DEX state. Every query
+// takes a context and honors its deadline, so callers can bound each
+// write — the pipeline uses this to put a processing timeout on whole
+// ledgers.
+type Store struct {
+	db      *sql.DB
+	network string
+
+	// leaderConn pins the session holding the leader advisory lock.
+	leaderConn *sql.Conn
+
+	// Hot-path caches; nil until SetCacheSizes enables them (cache.go).
+	cpCache    *checkpointCache
+	offerCache *offerCache
+
+	// Slow-statement logging; set before serving, like the network.
+	slowThreshold time.Duration
+	slowLogf      func(format string, args ...any)
+}
+
+// Open connects to the database. The "postgres" driver is registered by
+// this package's lib/pq import; the driver name stays a parameter so a
+// wire-compatible driver can be swapped in from the config.
+func Open(driver, dsn string) (*Store, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("store: open: %w", err)
+	}
+	return &Store{db: db, network: "mainnet"}, nil
+}
+
+// SetNetwork names the chain whose data this store holds; checkpoints are
+// tagged with it.
+func (s *Store) SetNetwork(network string) { s.network = network }
+
+// VerifyNetwork refuses a database whose existing checkpoints belong to a
+// different network, preventing a mainnet database from silently mixing
+// in testnet data (or vice versa).
+func (s *Store) VerifyNetwork(ctx context.Context) error {
+	var other string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT network FROM checkpoints WHERE network <> $1 LIMIT 1`,
+		s.network).Scan(&other)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("store: network check: %w", err)
+	}
+	return fmt.Errorf("store: database holds %s checkpoints, refusing to write %s data", other, s.network)
+}
+
+// SetSlowQueryLog makes the instrumented hot-path statements report
+// themselves through logf when they run for at least threshold, so "the
+// ledger was slow" can be split into "which statement was slow". A nil
+// logf or non-positive threshold disables it. Call before serving; the
+// fields are not guarded.
+func (s *Store) SetSlowQueryLog(threshold time.Duration, logf func(format string, args ...any)) {
+	s.slowThreshold = threshold
+	s.slowLogf = logf
+}
+
+// observeQuery is deferred with time.Now() at the top of instrumented
+// statements.
+func (s *Store) observeQuery(op string, start time.Time) {
+	if s.slowLogf == nil || s.slowThreshold <= 0 {
+		return
+	}
+	if d := time.Since(start); d >= s.slowThreshold {
+		s.slowLogf("store: slow query: %s took %s", op, d)
+	}
+}
+
+// Migrate applies the embedded schema; every statement is idempotent.
+func (s *Store) Migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("store: migrate: %w", err)
+	}
+	return nil
+}
+
+// schemaTables lists the tables the embedded schema creates, extracted
+// from schema.sql once at startup so MissingTables can never drift from
+// what Migrate would apply.
+var schemaTables = func() []string {
+	re := regexp.MustCompile(`(?m)^CREATE TABLE IF NOT EXISTS (\w+)`)
+	var tables []string
+	for _, m := range re.FindAllStringSubmatch(schema, -1) {
+		tables = append(tables, m[1])
+	}
+	return tables
+}()
+
+// MissingTables reports which schema tables the connected database lacks,
+// in schema order, without applying anything — the read-only half of
+// Migrate, for preflight checks like the selftest command.
+func (s *Store) MissingTables(ctx context.Context) ([]string, error) {
+	var missing []string
+	for _, table := range schemaTables {
+		var reg sql.NullString
+		if err := s.db.QueryRowContext(ctx, `SELECT to_regclass($1)`, table).Scan(&reg); err != nil {
+			return nil, fmt.Errorf("store: checking table %s: %w", table, err)
+		}
+		if !reg.Valid {
+			missing = append(missing, table)
+		}
+	}
+	return missing, nil
+}
+
+// Close releases the underlying connections.
+func (s *Store) Close() error { return s.db.Close() }
+
+// DB exposes the underlying handle for integration tests and ad-hoc
+// queries; production code goes through the typed methods.
+func (s *Store) DB() *sql.DB { return s.db }
+
+// UpsertOffer writes an offer keyed by its natural key (account, sequence),
+// so a re-submitted transaction converges on the row it logically updates.
+// On conflict the amounts, status and last-seen ledger are refreshed, with
+// the lifecycle guards from model.CanTransition applied keyed by ledger
+// index: a replay with an old binary cannot clobber rows a fixed parser
+// already rewrote, and an older ledger replayed out of order can never
+// move a row backwards — neither a terminal status to open nor
+// partially_filled back to its entry state.
+func (s *Store) UpsertOffer(ctx context.Context, o *model.Offer) error {
+	defer s.observeQuery("upsert offer", time.Now())
+	if failpoint(FailpointMidUpserts) {
+		return fmt.Errorf("store: upsert offer %s/%d: %w", o.Account, o.Sequence, ErrFailpoint)
+	}
+	if faultinject.Fire(faultinject.UpsertError) {
+		return fmt.Errorf("store: upsert offer %s/%d: %w", o.Account, o.Sequence, faultinject.ErrInjected)
+	}
+	s.evictOffer(o.Account, o.Sequence)
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO offers (tx_hash, account, sequence, flags,
+			gets_currency, gets_issuer, gets_value,
+			pays_currency, pays_issuer, pays_value,
+			limit_price, executed_price, status, ledger_index, parser_version, parse_error,
+			provisional)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17)
+		ON CONFLICT (account, sequence) DO UPDATE SET
+			tx_hash = EXCLUDED.tx_hash,
+			flags = EXCLUDED.flags,
+			gets_value = EXCLUDED.gets_value,
+			pays_value = EXCLUDED.pays_value,
+			limit_price = EXCLUDED.limit_price,
+			executed_price = EXCLUDED.executed_price,
+			status = EXCLUDED.status,
+			ledger_index = GREATEST(offers.ledger_index, EXCLUDED.ledger_index),
+			parser_version = EXCLUDED.parser_version,
+			parse_error = EXCLUDED.parse_error,
+			provisional = EXCLUDED.provisional
+		WHERE offers.parser_version <= EXCLUDED.parser_version
+		  AND NOT (offers.status IN `+terminalStatuses+`
+		       AND EXCLUDED.ledger_index < offers.ledger_index)
+		  AND NOT (offers.status = 'partially_filled'
+		       AND EXCLUDED.status IN ('open','snapshot')
+		       AND EXCLUDED.ledger_index < offers.ledger_index)`,
+		o.TxHash, o.Account, o.Sequence, o.Flags,
+		o.TakerGets.Currency, o.TakerGets.Issuer, o.TakerGets.Value,
+		o.TakerPays.Currency, o.TakerPays.Issuer, o.TakerPays.Value,
+		nullable(o.LimitPrice), nullable(o.ExecutedPrice), o.Status, o.LedgerIndex,
+		o.ParserVersion, nullable(o.ParseError), o.Provisional)
+	if err != nil {
+		return fmt.Errorf("store: upsert offer %s/%d: %w", o.Account, o.Sequence, err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return s.recordOfferEvent(ctx, o.Account, o.Sequence, o.Status, o.LedgerIndex, o.CloseTime, o.TxHash)
+	}
+	return nil
+}
+
+// CancelOffer marks an account's active offer as cancelled, recording the
+// cancellation ledger rather than removing the row so the offer's history
+// stays queryable. Unknown offers are a no-op — the prior offer may
+// predate the indexed range — as are cancels older than the row's last
+// activity, which the lifecycle forbids from overriding newer state. The
+// bool reports whether a row actually moved: false flags a cancel for an
+// offer the store never held, which the pipeline counts separately as a
+// coverage signal.
+func (s *Store) CancelOffer(ctx context.Context, account string, sequence uint32, ledgerIndex uint64, closeTime int64, txHash string) (bool, error) {
+	defer s.observeQuery("cancel offer", time.Now())
+	s.evictOffer(account, sequence)
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE offers SET status = $4, ledger_index = $3
+		WHERE account = $1 AND sequence = $2
+		  AND status IN `+activeStatuses+` AND ledger_index <= $3`,
+		account, sequence, ledgerIndex, model.OfferStatusCancelled)
+	if err != nil {
+		return false, fmt.Errorf("store: cancel offer %s/%d: %w", account, sequence, err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return true, s.recordOfferEvent(ctx, account, sequence, model.OfferStatusCancelled, ledgerIndex, closeTime, txHash)
+	}
+	return false, nil
+}
+
+// MarkOfferReplaced cancels the offer an OfferCreate superseded via its
+// OfferSequence field and records which sequence replaced it. Like
+// CancelOffer, an unknown or newer prior offer is a no-op.
+func (s *Store) MarkOfferReplaced(ctx context.Context, account string, sequence, replacedBy uint32, ledgerIndex uint64, closeTime int64, txHash string) error {
+	s.evictOffer(account, sequence)
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE offers SET status = $5, replaced_by = $3, ledger_index = $4
+		WHERE account = $1 AND sequence = $2
+		  AND status IN `+activeStatuses+` AND ledger_index <= $4`,
+		account, sequence, replacedBy, ledgerIndex, model.OfferStatusCancelled)
+	if err != nil {
+		return fmt.Errorf("store: mark offer %s/%d replaced: %w", account, sequence, err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return s.recordOfferEvent(ctx, account, sequence, model.OfferStatusCancelled, ledgerIndex, closeTime, txHash)
+	}
+	return nil
+}
+
+// UpdateOfferFill applies a metadata-driven fill to the maker's resting
+// offer: the remaining amounts shrink by what the trade consumed, a
+// partially consumed offer moves to partially_filled and a fully consumed
+// one to filled. The maker's offer may predate the indexed range or the
+// fill may be older than the row's last activity; both are no-ops.
+func (s *Store) UpdateOfferFill(ctx context.Context, t *model.Trade, closeTime int64) error {
+	defer s.observeQuery("fill offer", time.Now())
+	s.evictOffer(t.Maker, t.OfferSequence)
+	var status string
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE offers SET
+			gets_value = GREATEST(gets_value - $3::numeric, 0),
+			pays_value = GREATEST(pays_value - $4::numeric, 0),
+			status = CASE WHEN gets_value - $3::numeric <= 0
+				THEN 'filled' ELSE 'partially_filled' END,
+			ledger_index = $5
+		WHERE account = $1 AND sequence = $2
+		  AND status IN `+activeStatuses+` AND ledger_index <= $5
+		RETURNING status`,
+		t.Maker, t.OfferSequence, t.Gets.Value, t.Pays.Value,
+		t.LedgerIndex).Scan(&status)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("store: fill offer %s/%d: %w", t.Maker, t.OfferSequence, err)
+	}
+	return s.recordOfferEvent(ctx, t.Maker, t.OfferSequence, status, t.LedgerIndex, closeTime, t.TxHash)
+}
+
+// InsertTrade appends one fill to the trades table. A fill's natural
+// key is (tx_hash, maker, offer_sequence) — one taker transaction can
+// consume several resting offers — and a redelivered fill lands on that
+// key and is dropped, so a replayed ledger cannot double-count volume.
+func (s *Store) InsertTrade(ctx context.Context, t *model.Trade) error {
+	defer s.observeQuery("insert trade", time.Now())
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO trades (maker, taker, offer_sequence,
+			gets_currency, gets_issuer, gets_value,
+			pays_currency, pays_issuer, pays_value,
+			price, ledger_index, tx_hash, parser_version)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+		ON CONFLICT (tx_hash, maker, offer_sequence) DO NOTHING`,
+		t.Maker, t.Taker, t.OfferSequence,
+		t.Gets.Currency, t.Gets.Issuer, t.Gets.Value,
+		t.Pays.Currency, t.Pays.Issuer, t.Pays.Value,
+		nullable(t.Price), t.LedgerIndex, t.TxHash, t.ParserVersion)
+	if err != nil {
+		return fmt.Errorf("store: insert trade %s: %w", t.TxHash, err)
+	}
+	return nil
+}
+
+// UpsertPool registers a pool at its creation, or refreshes it when the
+// creation ledger is replayed; rows already past the ledger stay put.
+func (s *Store) UpsertPool(ctx context.Context, p *model.Pool) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO pools (account, asset1_currency, asset1_issuer, asset1_value,
+			asset2_currency, asset2_issuer, asset2_value, trading_fee,
+			created_ledger, lp_token_currency, ledger_index)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+		ON CONFLICT (account) DO UPDATE SET
+			asset1_currency = EXCLUDED.asset1_currency,
+			asset1_issuer = EXCLUDED.asset1_issuer,
+			asset1_value = EXCLUDED.asset1_value,
+			asset2_currency = EXCLUDED.asset2_currency,
+			asset2_issuer = EXCLUDED.asset2_issuer,
+			asset2_value = EXCLUDED.asset2_value,
+			trading_fee = EXCLUDED.trading_fee,
+			created_ledger = EXCLUDED.created_ledger,
+			lp_token_currency = EXCLUDED.lp_token_currency,
+			ledger_index = EXCLUDED.ledger_index
+		WHERE pools.ledger_index <= EXCLUDED.ledger_index`,
+		p.Account, p.Asset1.Currency, p.Asset1.Issuer, p.Asset1.Value,
+		p.Asset2.Currency, p.Asset2.Issuer, p.Asset2.Value, p.TradingFee,
+		p.CreatedLedger, p.LPTokenCurrency, p.LedgerIndex)
+	if err != nil {
+		return fmt.Errorf("store: upsert pool %s: %w", p.Account, err)
+	}
+	return s.recordPoolHistory(ctx, p.Account, p.LedgerIndex, 0)
+}
+
+// IsPoolAccount reports whether the account is a known AMM account.
+func (s *Store) IsPoolAccount(ctx context.Context, account string) bool {
+	var one int
+	err := s.db.QueryRowContext(ctx, `SELECT 1 FROM pools WHERE account = $1`, account).Scan(&one)
+	return err == nil
+}
+
+// AdjustPoolReserve applies a signed reserve delta to whichever side of the
+// pool holds the adjusted asset.
+func (s *Store) AdjustPoolReserve(ctx context.Context, adj *model.PoolAdjustment) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE pools SET
+			asset1_value = CASE WHEN asset1_currency = $2 AND asset1_issuer = $3
+				THEN asset1_value + $4::numeric ELSE asset1_value END,
+			asset2_value = CASE WHEN asset2_currency = $2 AND asset2_issuer = $3
+				THEN asset2_value + $4::numeric ELSE asset2_value END,
+			ledger_index = $5
+		WHERE account = $1`,
+		adj.Account, adj.Currency, adj.Issuer, adj.Delta, adj.LedgerIndex)
+	if err != nil {
+		return fmt.Errorf("store: adjust pool %s: %w", adj.Account, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("store: adjust pool %s: unknown pool", adj.Account)
+	}
+	return s.recordPoolHistory(ctx, adj.Account, adj.LedgerIndex, adj.CloseTime)
+}
+
+// SaveCheckpoint records that a ledger has been fully processed. Saving
+// the same ledger again — a backfill overlap or reprocess — updates the
+// row in place, moving indexed_at to the latest processing while
+// first_indexed_at keeps the original one; a row's operational history
+// starts when the ledger was first seen, not when it was last redone.
+func (s *Store) SaveCheckpoint(ctx context.Context, cp *model.Checkpoint) error {
+	if failpoint(FailpointPreCheckpoint) {
+		return fmt.Errorf("store: save checkpoint %d: %w", cp.LedgerIndex, ErrFailpoint)
+	}
+	defer s.observeQuery("save checkpoint", time.Now())
+	var timings any
+	if len(cp.Timings) > 0 {
+		raw, err := json.Marshal(cp.Timings)
+		if err != nil {
+			return fmt.Errorf("store: save checkpoint %d: %w", cp.LedgerIndex, err)
+		}
+		timings = string(raw)
+	}
+	var txStats any
+	if cp.TxStats != nil {
+		raw, err := json.Marshal(cp.TxStats)
+		if err != nil {
+			return fmt.Errorf("store: save checkpoint %d: %w", cp.LedgerIndex, err)
+		}
+		txStats = string(raw)
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO checkpoints (ledger_index, ledger_hash, close_time, network, timings, tx_stats)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		ON CONFLICT (ledger_index) DO UPDATE SET
+			ledger_hash = EXCLUDED.ledger_hash,
+			close_time = EXCLUDED.close_time,
+			network = EXCLUDED.network,
+			indexed_at = now(),
+			timings = COALESCE(EXCLUDED.timings, checkpoints.timings),
+			tx_stats = COALESCE(EXCLUDED.tx_stats, checkpoints.tx_stats)`,
+		cp.LedgerIndex, cp.LedgerHash, cp.CloseTime, s.network, timings, txStats)
+	if err != nil {
+		return fmt.Errorf("store: save checkpoint %d: %w", cp.LedgerIndex, err)
+	}
+	if s.cpCache != nil {
+		s.cpCache.put(*cp)
+	}
+	return nil
+}
+
+// GetLastCheckpoint returns the highest checkpoint row, or nil when
+// nothing has been indexed yet. Callers that only need the index should
+// use the cheaper LatestLedger.
+func (s *Store) GetLastCheckpoint(ctx context.Context) (*model.Checkpoint, error) {
+	if s.cpCache != nil {
+		if cp, ok := s.cpCache.getLatest(); ok {
+			return &cp, nil
+		}
+	}
+	var cp model.Checkpoint
+	err := s.db.QueryRowContext(ctx, `
+		SELECT ledger_index, ledger_hash, close_time
+		FROM checkpoints ORDER BY ledger_index DESC LIMIT 1`).
+		Scan(&cp.LedgerIndex, &cp.LedgerHash, &cp.CloseTime)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: last checkpoint: %w", err)
+	}
+	if s.cpCache != nil {
+		s.cpCache.primeLatest(cp)
+	}
+	return &cp, nil
+}
+
+// LatestLedger returns the highest indexed ledger, or zero when the store
+// is empty. It is a single aggregate read, cheap enough for the lag gauge
+// and per-request staleness checks.
+func (s *Store) LatestLedger(ctx context.Context) (int64, error) {
+	if s.cpCache != nil {
+		if cp, ok := s.cpCache.getLatest(); ok {
+			return int64(cp.LedgerIndex), nil
+		}
+	}
+	return s.ledgerBound(ctx, "MAX")
+}
+
+// GetSlowLedgers returns the checkpoints with the highest recorded
+// processing time, worst first, so the status report can show whether
+// slow ledgers spend their time parsing or in Postgres. Ledgers indexed
+// before the timing instrumentation have no breakdown and never rank.
+func (s *Store) GetSlowLedgers(ctx context.Context, limit int) ([]*model.SlowLedger, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT ledger_index, timings::text FROM checkpoints
+		WHERE timings->>'total_ms' IS NOT NULL
+		ORDER BY (timings->>'total_ms')::numeric DESC
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: slow ledgers: %w", err)
+	}
+	defer rows.Close()
+	var slow []*model.SlowLedger
+	for rows.Next() {
+		sl := &model.SlowLedger{}
+		var raw string
+		if err := rows.Scan(&sl.LedgerIndex, &raw); err != nil {
+			return nil, fmt.Errorf("store: scanning slow ledger: %w", err)
+		}
+		if err := json.Unmarshal([]byte(raw), &sl.Stages); err != nil {
+			return nil, fmt.Errorf("store: slow ledger %d timings: %w", sl.LedgerIndex, err)
+		}
+		sl.TotalMS = sl.Stages["total_ms"]
+		delete(sl.Stages, "total_ms")
+		slow = append(slow, sl)
+	}
+	return slow, rows.Err()
+}
+
+// EarliestLedger returns the lowest indexed ledger, or zero when the
+// store is empty; the gap scan uses it as its lower bound.
+func (s *Store) EarliestLedger(ctx context.Context) (int64, error) {
+	return s.ledgerBound(ctx, "MIN")
+}
+
+func (s *Store) ledgerBound(ctx context.Context, agg string) (int64, error) {
+	var index sql.NullInt64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT `+agg+`(ledger_index) FROM checkpoints`).Scan(&index)
+	if err != nil {
+		return 0, fmt.Errorf("store: %s ledger: %w", strings.ToLower(agg), err)
+	}
+	return index.Int64, nil
+}
+
+// ApplyFeeChange moves a pool to its new effective trading fee and
+// appends the change to the fee history. Reprocessing the same ledger
+// overwrites the history row rather than duplicating it.
+func (s *Store) ApplyFeeChange(ctx context.Context, fc *model.FeeChange) error {
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE pools SET trading_fee = $2, ledger_index = $3
+		WHERE account = $1 AND ledger_index <= $3`,
+		fc.PoolAccount, fc.TradingFee, fc.LedgerIndex); err != nil {
+		return fmt.Errorf("store: fee change pool %s: %w", fc.PoolAccount, err)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO amm_fee_history (pool_account, trading_fee, ledger_index, tx_hash)
+		VALUES ($1,$2,$3,$4)
+		ON CONFLICT (pool_account, ledger_index) DO UPDATE SET
+			trading_fee = EXCLUDED.trading_fee,
+			tx_hash = EXCLUDED.tx_hash`,
+		fc.PoolAccount, fc.TradingFee, fc.LedgerIndex, fc.TxHash); err != nil {
+		return fmt.Errorf("store: fee history pool %s: %w", fc.PoolAccount, err)
+	}
+	return s.recordPoolHistory(ctx, fc.PoolAccount, fc.LedgerIndex, fc.CloseTime)
+}
+
+// GetFeeHistory returns a pool's fee changes in ledger order.
+func (s *Store) GetFeeHistory(ctx context.Context, poolAccount string) ([]*model.FeeChange, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT pool_account, trading_fee, ledger_index, tx_hash
+		FROM amm_fee_history WHERE pool_account = $1
+		ORDER BY ledger_index`, poolAccount)
+	if err != nil {
+		return nil, fmt.Errorf("store: fee history %s: %w", poolAccount, err)
+	}
+	defer rows.Close()
+	var changes []*model.FeeChange
+	for rows.Next() {
+		var fc model.FeeChange
+		if err := rows.Scan(&fc.PoolAccount, &fc.TradingFee, &fc.LedgerIndex, &fc.TxHash); err != nil {
+			return nil, fmt.Errorf("store: scanning fee change: %w", err)
+		}
+		changes = append(changes, &fc)
+	}
+	return changes, rows.Err()
+}
+
+// RecordFailedLedger remembers a ledger the backfill gave up on, so a later
+// run can target exactly the failed set.
+func (s *Store) RecordFailedLedger(ctx context.Context, index uint64, cause error) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO failed_ledgers (ledger_index, error)
+		VALUES ($1, $2)
+		ON CONFLICT (ledger_index) DO UPDATE SET
+			error = EXCLUDED.error, failed_at = now()`,
+		index, cause.Error())
+	if err != nil {
+		return fmt.Errorf("store: record failed ledger %d: %w", index, err)
+	}
+	return nil
+}
+
+// ClearFailedLedger removes a ledger from the failed set after a successful
+// re-run.
+func (s *Store) ClearFailedLedger(ctx context.Context, index uint64) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM failed_ledgers WHERE ledger_index = $1`, index); err != nil {
+		return fmt.Errorf("store: clear failed ledger %d: %w", index, err)
+	}
+	return nil
+}
+
+// ListFailedLedgers returns the ledgers recorded as failed, in order.
+func (s *Store) ListFailedLedgers(ctx context.Context) ([]uint64, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT ledger_index FROM failed_ledgers ORDER BY ledger_index`)
+	if err != nil {
+		return nil, fmt.Errorf("store: list failed ledgers: %w", err)
+	}
+	defer rows.Close()
+	var indexes []uint64
+	for rows.Next() {
+		var index uint64
+		if err := rows.Scan(&index); err != nil {
+			return nil, fmt.Errorf("store: scanning failed ledger: %w", err)
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, rows.Err()
+}
+
+// UpsertAmendment records an enabled amendment. A row seeded from the
+// server (activation ledger 0) is upgraded in place once the stream
+// reveals the real activation ledger.
+func (s *Store) UpsertAmendment(ctx context.Context, a *model.Amendment) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO amendments (hash, name, ledger_index, tx_hash)
+		VALUES ($1,$2,$3,$4)
+		ON CONFLICT (hash) DO UPDATE SET
+			name = EXCLUDED.name,
+			ledger_index = EXCLUDED.ledger_index,
+			tx_hash = EXCLUDED.tx_hash
+		WHERE amendments.ledger_index = 0`,
+		a.Hash, a.Name, a.LedgerIndex, a.TxHash)
+	if err != nil {
+		return fmt.Errorf("store: upsert amendment %s: %w", a.Hash, err)
+	}
+	return nil
+}
+
+// ListAmendments returns recorded amendments ordered by activation.
+func (s *Store) ListAmendments(ctx context.Context) ([]*model.Amendment, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT hash, name, ledger_index, tx_hash FROM amendments
+		ORDER BY ledger_index, hash`)
+	if err != nil {
+		return nil, fmt.Errorf("store: list amendments: %w", err)
+	}
+	defer rows.Close()
+	var amendments []*model.Amendment
+	for rows.Next() {
+		var a model.Amendment
+		if err := rows.Scan(&a.Hash, &a.Name, &a.LedgerIndex, &a.TxHash); err != nil {
+			return nil, fmt.Errorf("store: scanning amendment: %w", err)
+		}
+		amendments = append(amendments, &a)
+	}
+	return amendments, rows.Err()
+}
+
+// RecordGap remembers a ledger range the indexer knowingly does not have,
+// e.g. because the server's history doesn't reach it.
+func (s *Store) RecordGap(ctx context.Context, from, to uint64, reason string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO gaps (from_ledger, to_ledger, reason)
+		VALUES ($1,$2,$3)
+		ON CONFLICT (from_ledger, to_ledger) DO UPDATE SET
+			reason = EXCLUDED.reason, recorded_at = now()`,
+		from, to, reason)
+	if err != nil {
+		return fmt.Errorf("store: record gap %d..%d: %w", from, to, err)
+	}
+	return nil
+}
+
+// ApplyAssetFlag records or clears an asset flag such as a global freeze.
+func (s *Store) ApplyAssetFlag(ctx context.Context, c *model.AssetFlagChange) error {
+	var err error
+	if c.Set {
+		_, err = s.db.ExecContext(ctx, `
+			INSERT INTO asset_flags (issuer, currency, flag, ledger_index, tx_hash)
+			VALUES ($1,$2,$3,$4,$5)
+			ON CONFLICT (issuer, currency, flag) DO UPDATE SET
+				ledger_index = EXCLUDED.ledger_index,
+				tx_hash = EXCLUDED.tx_hash`,
+			c.Issuer, c.Currency, c.Flag, c.LedgerIndex, c.TxHash)
+	} else {
+		_, err = s.db.ExecContext(ctx, `
+			DELETE FROM asset_flags WHERE issuer = $1 AND currency = $2 AND flag = $3`,
+			c.Issuer, c.Currency, c.Flag)
+	}
+	if err != nil {
+		return fmt.Errorf("store: asset flag %s/%s %s: %w", c.Issuer, c.Currency, c.Flag, err)
+	}
+	return nil
+}
+
+// IsAssetFrozen reports whether trading in the given asset is currently
+// frozen, either by an issuer-wide global freeze or a currency-level flag.
+func (s *Store) IsAssetFrozen(ctx context.Context, currency, issuer string) (bool, error) {
+	var one int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT 1 FROM asset_flags
+		WHERE issuer = $1 AND flag = $2 AND currency IN ('', $3)
+		LIMIT 1`,
+		issuer, model.FlagGlobalFreeze, currency).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("store: frozen check %s.%s: %w", currency, issuer, err)
+	}
+	return true, nil
+}
+
+// GetFrozenOffers returns open offers in which either leg is a currently
+// frozen asset. They remain on the ledger, but the API surfaces them as
+// frozen rather than tradeable.
+func (s *Store) GetFrozenOffers(ctx context.Context) ([]*model.Offer, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT o.tx_hash, o.account, o.sequence, o.flags,
+			o.gets_currency, o.gets_issuer, o.gets_value,
+			o.pays_currency, o.pays_issuer, o.pays_value,
+			COALESCE(o.limit_price::text, ''), COALESCE(o.executed_price::text, ''),
+			o.status, o.ledger_index, COALESCE(o.replaced_by, 0), COALESCE(o.parse_error, ''),
+			COALESCE(o.funded_gets_value::text, ''), o.unfunded, o.funded_checked_ledger
+		FROM offers o
+		WHERE o.status = 'open' AND EXISTS (
+			SELECT 1 FROM asset_flags f
+			WHERE f.flag = 'global_freeze'
+			  AND ((f.issuer = o.gets_issuer AND f.currency IN ('', o.gets_currency))
+			    OR (f.issuer = o.pays_issuer AND f.currency IN ('', o.pays_currency))))
+		ORDER BY o.ledger_index`)
+	if err != nil {
+		return nil, fmt.Errorf("store: frozen offers: %w", err)
+	}
+	defer rows.Close()
+	return scanOffers(rows)
+}
+
+// GetInvalidOffers returns offers the parser gave up on at or after the
+// given ledger, each carrying its parse error and the hash of the raw
+// transaction still sitting in raw_transactions — the triage surface for
+// parser bugs against real data.
+func (s *Store) GetInvalidOffers(ctx context.Context, since uint64, limit int) ([]*model.Offer, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT o.tx_hash, o.account, o.sequence, o.flags,
+			o.gets_currency, o.gets_issuer, o.gets_value,
+			o.pays_currency, o.pays_issuer, o.pays_value,
+			COALESCE(o.limit_price::text, ''), COALESCE(o.executed_price::text, ''),
+			o.status, o.ledger_index, COALESCE(o.replaced_by, 0), COALESCE(o.parse_error, ''),
+			COALESCE(o.funded_gets_value::text, ''), o.unfunded, o.funded_checked_ledger
+		FROM offers o
+		WHERE o.status = $1 AND o.ledger_index >= $2
+		ORDER BY o.ledger_index
+		LIMIT $3`,
+		model.OfferStatusInvalidParse, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store: invalid offers: %w", err)
+	}
+	defer rows.Close()
+	return scanOffers(rows)
+}
+
+func scanOffers(rows *sql.Rows) ([]*model.Offer, error) {
+	var offers []*model.Offer
+	for rows.Next() {
+		var o model.Offer
+		if err := rows.Scan(&o.TxHash, &o.Account, &o.Sequence, &o.Flags,
+			&o.TakerGets.Currency, &o.TakerGets.Issuer, &o.TakerGets.Value,
+			&o.TakerPays.Currency, &o.TakerPays.Issuer, &o.TakerPays.Value,
+			&o.LimitPrice, &o.ExecutedPrice, &o.Status, &o.LedgerIndex, &o.ReplacedBy, &o.ParseError,
+			&o.FundedTakerGets, &o.Unfunded, &o.FundedCheckedLedger); err != nil {
+			return nil, fmt.Errorf("store: scanning offer: %w", err)
+		}
+		offers = append(offers, &o)
+	}
+	return offers, rows.Err()
+}
+
+// nullable maps "" to NULL for optional numeric columns.
+func nullable(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
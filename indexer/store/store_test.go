@@ -0,0 +1,957 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// openTestStore connects to the database named by INDEXER_TEST_DSN and
+// migrates it. Tests that need a real database skip when the variable is
+// unset, so the suite stays runnable without infrastructure. It takes a
+// testing.TB so benchmarks can share it.
+func openTestStore(t testing.TB) *Store {
+	t.Helper()
+	dsn := os.Getenv("INDEXER_TEST_DSN")
+	if dsn == "" {
+		t.Skip("INDEXER_TEST_DSN not set; skipping database-backed test")
+	}
+	st, err := Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	// Fail with the connection error itself when the variable points at
+	// nothing, rather than whatever query happens to run first.
+	if err := st.db.PingContext(context.Background()); err != nil {
+		t.Fatalf("INDEXER_TEST_DSN is set but the database is unreachable: %v", err)
+	}
+	if err := st.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestLeaderLockContention(t *testing.T) {
+	first := openTestStore(t)
+	second := openTestStore(t)
+	ctx := context.Background()
+
+	leader, err := first.TryAcquireLeaderLock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !leader {
+		t.Fatal("first replica failed to take the free lock")
+	}
+	// Re-acquiring on the same store is a no-op success.
+	if leader, err = first.TryAcquireLeaderLock(ctx); err != nil || !leader {
+		t.Fatalf("re-acquire = %v, %v", leader, err)
+	}
+
+	if leader, err = second.TryAcquireLeaderLock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if leader {
+		t.Fatal("second replica acquired a held lock")
+	}
+
+	if err := first.ReleaseLeaderLock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if leader, err = second.TryAcquireLeaderLock(ctx); err != nil || !leader {
+		t.Fatalf("takeover after release = %v, %v", leader, err)
+	}
+	if err := second.ReleaseLeaderLock(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpsertOfferParserVersioning(t *testing.T) {
+	st := openTestStore(t)
+	if _, err := st.db.Exec(`DELETE FROM offers WHERE tx_hash = 'VERSIONTEST'`); err != nil {
+		t.Fatal(err)
+	}
+	offer := &model.Offer{
+		TxHash: "VERSIONTEST", Account: "rA", Sequence: 1,
+		TakerGets:   xrpl.Amount{Value: "1"},
+		TakerPays:   xrpl.Amount{Currency: "USD", Issuer: "rI", Value: "2"},
+		Status:      model.OfferStatusOpen,
+		LedgerIndex: 90000050, ParserVersion: 1,
+	}
+	if err := st.UpsertOffer(context.Background(), offer); err != nil {
+		t.Fatal(err)
+	}
+	// A newer parser replaces the row.
+	offer.Status = model.OfferStatusFilled
+	offer.ParserVersion = 2
+	if err := st.UpsertOffer(context.Background(), offer); err != nil {
+		t.Fatal(err)
+	}
+	var status string
+	var version int
+	readBack := func() {
+		err := st.db.QueryRow(`SELECT status, parser_version FROM offers WHERE tx_hash = 'VERSIONTEST'`).
+			Scan(&status, &version)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	readBack()
+	if status != model.OfferStatusFilled || version != 2 {
+		t.Fatalf("after newer upsert: status=%s version=%d", status, version)
+	}
+	// An older parser must not clobber it.
+	offer.Status = model.OfferStatusCancelled
+	offer.ParserVersion = 1
+	if err := st.UpsertOffer(context.Background(), offer); err != nil {
+		t.Fatal(err)
+	}
+	readBack()
+	if status != model.OfferStatusFilled || version != 2 {
+		t.Fatalf("stale upsert overwrote the row: status=%s version=%d", status, version)
+	}
+}
+
+func TestUpsertOfferNaturalKey(t *testing.T) {
+	st := openTestStore(t)
+	if _, err := st.db.Exec(`DELETE FROM offers WHERE account = 'rNaturalKey'`); err != nil {
+		t.Fatal(err)
+	}
+	offer := &model.Offer{
+		TxHash: "NATKEY1", Account: "rNaturalKey", Sequence: 7,
+		TakerGets:   xrpl.Amount{Value: "10"},
+		TakerPays:   xrpl.Amount{Currency: "USD", Issuer: "rI", Value: "20"},
+		Status:      model.OfferStatusOpen,
+		LedgerIndex: 90000100, ParserVersion: 1,
+	}
+	if err := st.UpsertOffer(context.Background(), offer); err != nil {
+		t.Fatal(err)
+	}
+	// A re-submitted transaction (new hash, same account+sequence) updates
+	// the existing row instead of creating a second one.
+	offer.TxHash = "NATKEY2"
+	offer.Status = model.OfferStatusFilled
+	offer.LedgerIndex = 90000110
+	if err := st.UpsertOffer(context.Background(), offer); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	var status string
+	var ledger uint64
+	readBack := func() {
+		err := st.db.QueryRow(`
+			SELECT COUNT(*), MAX(status), MAX(ledger_index)
+			FROM offers WHERE account = 'rNaturalKey' AND sequence = 7`).
+			Scan(&count, &status, &ledger)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	readBack()
+	if count != 1 || status != model.OfferStatusFilled || ledger != 90000110 {
+		t.Fatalf("after re-submit: count=%d status=%s ledger=%d", count, status, ledger)
+	}
+	// An older ledger replayed out of order must not reopen a filled offer.
+	offer.TxHash = "NATKEY1"
+	offer.Status = model.OfferStatusOpen
+	offer.LedgerIndex = 90000105
+	if err := st.UpsertOffer(context.Background(), offer); err != nil {
+		t.Fatal(err)
+	}
+	readBack()
+	if count != 1 || status != model.OfferStatusFilled || ledger != 90000110 {
+		t.Fatalf("stale replay regressed the row: count=%d status=%s ledger=%d", count, status, ledger)
+	}
+}
+
+func TestMarkOfferReplaced(t *testing.T) {
+	st := openTestStore(t)
+	if _, err := st.db.Exec(`DELETE FROM offers WHERE account = 'rReplacer'`); err != nil {
+		t.Fatal(err)
+	}
+	offer := &model.Offer{
+		TxHash: "REPLACED1", Account: "rReplacer", Sequence: 3,
+		TakerGets:   xrpl.Amount{Value: "1"},
+		TakerPays:   xrpl.Amount{Currency: "EUR", Issuer: "rI", Value: "2"},
+		Status:      model.OfferStatusOpen,
+		LedgerIndex: 90000200, ParserVersion: 1,
+	}
+	if err := st.UpsertOffer(context.Background(), offer); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.MarkOfferReplaced(context.Background(), "rReplacer", 3, 9, 90000210, 700000210, "REPLTX"); err != nil {
+		t.Fatal(err)
+	}
+	var status string
+	var replacedBy uint32
+	err := st.db.QueryRow(`
+		SELECT status, COALESCE(replaced_by, 0) FROM offers
+		WHERE account = 'rReplacer' AND sequence = 3`).Scan(&status, &replacedBy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != model.OfferStatusCancelled || replacedBy != 9 {
+		t.Fatalf("replaced offer: status=%s replaced_by=%d", status, replacedBy)
+	}
+	// A prior offer outside the indexed range is a no-op, not an error.
+	if err := st.MarkOfferReplaced(context.Background(), "rReplacer", 999, 9, 90000210, 700000210, "REPLTX"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetInvalidOffers(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	if _, err := st.db.Exec(`DELETE FROM offers WHERE account = 'rInvalid'`); err != nil {
+		t.Fatal(err)
+	}
+	for i, hash := range []string{"INVALID1", "INVALID2"} {
+		offer := &model.Offer{
+			TxHash: hash, Account: "rInvalid", Sequence: uint32(i + 1),
+			TakerGets:   xrpl.Amount{Value: "0"},
+			TakerPays:   xrpl.Amount{Value: "0"},
+			Status:      model.OfferStatusInvalidParse,
+			ParseError:  "parser: decoding node fields: boom",
+			LedgerIndex: 90000400 + uint64(i), ParserVersion: 1,
+		}
+		if err := st.UpsertOffer(context.Background(), offer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	invalid, err := st.GetInvalidOffers(ctx, 90000400, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invalid) != 2 {
+		t.Fatalf("invalid offers = %d, want 2", len(invalid))
+	}
+	if invalid[0].TxHash != "INVALID1" || invalid[0].ParseError == "" {
+		t.Errorf("first invalid offer = %+v", invalid[0])
+	}
+	// The since bound excludes older failures.
+	if invalid, err = st.GetInvalidOffers(ctx, 90000401, 10); err != nil || len(invalid) != 1 {
+		t.Errorf("since-filtered = %d, %v, want 1", len(invalid), err)
+	}
+}
+
+func TestReplayOrderConvergence(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	const account = "rLifecycle"
+
+	// The offer's real history: created, partially filled, cancelled.
+	create := func() error {
+		return st.UpsertOffer(ctx, &model.Offer{
+			TxHash: "LIFE1", Account: account, Sequence: 5,
+			TakerGets:   xrpl.Amount{Value: "10"},
+			TakerPays:   xrpl.Amount{Currency: "USD", Issuer: "rI", Value: "20"},
+			Status:      model.OfferStatusOpen,
+			LedgerIndex: 90000600, ParserVersion: 1,
+		})
+	}
+	fill := func() error {
+		return st.UpdateOfferFill(ctx, &model.Trade{
+			Maker: account, OfferSequence: 5, TxHash: "LIFE2",
+			Gets:        xrpl.Amount{Value: "4"},
+			Pays:        xrpl.Amount{Currency: "USD", Issuer: "rI", Value: "8"},
+			LedgerIndex: 90000610,
+		}, 700002010)
+	}
+	cancel := func() error {
+		_, err := st.CancelOffer(ctx, account, 5, 90000620, 700002020, "LIFE3")
+		return err
+	}
+
+	readStatus := func() (status string, ledger uint64) {
+		err := st.db.QueryRow(`
+			SELECT status, ledger_index FROM offers
+			WHERE account = $1 AND sequence = 5`, account).Scan(&status, &ledger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return status, ledger
+	}
+
+	// Every replay order of the already-indexed history must converge on
+	// the same final state: older events cannot move the row backwards.
+	orders := [][]func() error{
+		{create, fill, cancel},
+		{cancel, fill, create},
+		{fill, create, cancel},
+		{fill, cancel, create},
+	}
+	for i, order := range orders {
+		if i == 0 {
+			if _, err := st.db.Exec(`DELETE FROM offers WHERE account = $1`, account); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, step := range order {
+			if err := step(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		status, ledger := readStatus()
+		if status != model.OfferStatusCancelled || ledger != 90000620 {
+			t.Fatalf("order %d diverged: status=%s ledger=%d", i, status, ledger)
+		}
+	}
+}
+
+func TestSaveCheckpointHonorsDeadline(t *testing.T) {
+	st := openTestStore(t)
+	// An already-expired deadline must abort the write promptly instead
+	// of blocking on the database.
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	start := time.Now()
+	err := st.SaveCheckpoint(ctx, &model.Checkpoint{LedgerIndex: 90000500, LedgerHash: "DEAD"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expired context took %v to abort", elapsed)
+	}
+}
+
+func TestSaveCheckpointKeepsFirstIndexedAt(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	mustExec(t, st, `DELETE FROM checkpoints WHERE ledger_index = 90010000`)
+
+	if err := st.SaveCheckpoint(ctx, &model.Checkpoint{
+		LedgerIndex: 90010000, LedgerHash: "FIRSTCP", CloseTime: 700006000,
+		TxStats: &model.TxStats{Types: map[string]int64{"Payment": 1}, Results: map[string]int64{"tesSUCCESS": 1}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	read := func() (hash, stats string, first, last time.Time) {
+		t.Helper()
+		if err := st.db.QueryRow(`
+			SELECT ledger_hash, COALESCE(tx_stats::text, ''), first_indexed_at, indexed_at
+			FROM checkpoints WHERE ledger_index = 90010000`).Scan(&hash, &stats, &first, &last); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	_, stats0, first0, _ := read()
+
+	// A backfill overlap reprocesses the ledger: the mutable fields move,
+	// the first sighting does not.
+	time.Sleep(10 * time.Millisecond)
+	if err := st.SaveCheckpoint(ctx, &model.Checkpoint{
+		LedgerIndex: 90010000, LedgerHash: "SECONDCP", CloseTime: 700006000,
+		TxStats: &model.TxStats{Types: map[string]int64{"Payment": 2}, Results: map[string]int64{"tesSUCCESS": 2}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	hash, stats1, first1, last1 := read()
+	if hash != "SECONDCP" || stats1 == stats0 {
+		t.Errorf("reprocess left hash=%q stats=%q", hash, stats1)
+	}
+	if !first1.Equal(first0) {
+		t.Errorf("first_indexed_at moved from %v to %v", first0, first1)
+	}
+	if !last1.After(first1) {
+		t.Errorf("indexed_at %v did not advance past first_indexed_at %v", last1, first1)
+	}
+	mustExec(t, st, `DELETE FROM checkpoints WHERE ledger_index = 90010000`)
+}
+
+func TestLedgerBounds(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	if _, err := st.db.ExecContext(ctx, `DELETE FROM checkpoints`); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := st.LatestLedger(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest != 0 {
+		t.Fatalf("latest on empty table = %d, want 0", latest)
+	}
+
+	for _, index := range []uint64{90000005, 90000001, 90000003} {
+		err := st.SaveCheckpoint(ctx, &model.Checkpoint{
+			LedgerIndex: index,
+			LedgerHash:  "ABCD",
+			CloseTime:   700000000,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if latest, err = st.LatestLedger(ctx); err != nil || latest != 90000005 {
+		t.Errorf("latest = %d, %v, want 90000005", latest, err)
+	}
+	earliest, err := st.EarliestLedger(ctx)
+	if err != nil || earliest != 90000001 {
+		t.Errorf("earliest = %d, %v, want 90000001", earliest, err)
+	}
+	cp, err := st.GetLastCheckpoint(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp == nil || cp.LedgerIndex != 90000005 {
+		t.Errorf("last checkpoint = %+v", cp)
+	}
+}
+
+func TestPoolReserveHistory(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	mustExec(t, st, `DELETE FROM pool_reserves_history WHERE pool_account = 'rHistPool'`)
+	mustExec(t, st, `DELETE FROM pools WHERE account = 'rHistPool'`)
+	mustExec(t, st, `
+		INSERT INTO pools (account, asset1_currency, asset1_issuer, asset1_value,
+			asset2_currency, asset2_issuer, asset2_value, trading_fee, lp_supply, ledger_index)
+		VALUES ('rHistPool', 'XRP', '', 1000, 'USD', 'rIss', 500, 600, 100, 90000700)`)
+
+	for i := 0; i < 4; i++ {
+		adj := &model.PoolAdjustment{
+			Account: "rHistPool", Currency: "XRP", Delta: "100",
+			LedgerIndex: uint64(90000710 + 10*i), CloseTime: int64(700001000 + 10*i),
+			TxHash: "POOLHIST",
+		}
+		if err := st.AdjustPoolReserve(ctx, adj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := st.GetPoolHistory(ctx, "rHistPool", 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 4 {
+		t.Fatalf("history rows = %d, want one per update", len(history))
+	}
+	last := history[3]
+	if last.Asset1Value != "1400" || last.TVLXRP != "2800" {
+		t.Errorf("last snapshot = %+v, want XRP reserve 1400 and TVL 2800", last)
+	}
+	if last.LPSupply != "100" || last.TradingFee != 600 {
+		t.Errorf("last snapshot carried %+v", last)
+	}
+
+	// Downsampling strides evenly instead of truncating the range.
+	sampled, err := st.GetPoolHistory(ctx, "rHistPool", 0, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sampled) != 2 || sampled[0].LedgerIndex != 90000710 || sampled[1].LedgerIndex != 90000730 {
+		t.Fatalf("sampled = %+v, want strided rows", sampled)
+	}
+
+	// A fee change snapshots too, and replaying a ledger stays one row.
+	fc := &model.FeeChange{PoolAccount: "rHistPool", TradingFee: 700,
+		LedgerIndex: 90000750, CloseTime: 700001040, TxHash: "POOLFEE"}
+	if err := st.ApplyFeeChange(ctx, fc); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.ApplyFeeChange(ctx, fc); err != nil {
+		t.Fatal(err)
+	}
+	history, err = st.GetPoolHistory(ctx, "rHistPool", 90000750, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].TradingFee != 700 {
+		t.Fatalf("fee snapshot = %+v", history)
+	}
+
+	// All five rows share one hour; pruning them keeps only the earliest.
+	pruned, err := st.PrunePoolHistory(ctx, 700002000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned < 4 {
+		t.Fatalf("pruned = %d, want the non-hourly rows gone", pruned)
+	}
+	history, err = st.GetPoolHistory(ctx, "rHistPool", 0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].LedgerIndex != 90000710 {
+		t.Fatalf("surviving rows = %+v, want the first of the hour", history)
+	}
+}
+
+// mustExec runs raw setup SQL for tests.
+func mustExec(t *testing.T, st *Store, query string) {
+	t.Helper()
+	if _, err := st.DB().Exec(query); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidatorScores(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	mustExec(t, st, `DELETE FROM validator_scores`)
+	mustExec(t, st, `DELETE FROM validations`)
+	mustExec(t, st, `DELETE FROM manifests WHERE signing_key = 'nSignA'`)
+	mustExec(t, st, `DELETE FROM checkpoints WHERE ledger_index BETWEEN 90000900 AND 90000904`)
+
+	// Five checkpointed ledgers, four seconds apart, inside the window.
+	for i := uint64(0); i < 5; i++ {
+		cp := &model.Checkpoint{
+			LedgerIndex: 90000900 + i,
+			LedgerHash:  fmt.Sprintf("LH%d", i),
+			CloseTime:   700001000 + int64(i*4),
+		}
+		if err := st.SaveCheckpoint(ctx, cp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.UpsertManifest(ctx, "nSignA", "nMasterA", 3); err != nil {
+		t.Fatal(err)
+	}
+	vote := func(key string, ledger uint64, hash string, skew int64) {
+		t.Helper()
+		v := &model.Validation{Validator: key, LedgerIndex: ledger,
+			LedgerHash: hash, SigningTime: 700001000 + int64(ledger-90000900)*4 + skew}
+		if err := st.InsertValidation(ctx, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A signs through its ephemeral key: agrees on 0,1,3, disagrees on 2,
+	// misses 4. The duplicate vote for ledger 0 must not double-count.
+	vote("nSignA", 90000900, "LH0", 2)
+	vote("nSignA", 90000900, "LH0-dup", 9)
+	vote("nSignA", 90000901, "LH1", 2)
+	vote("nSignA", 90000902, "WRONG", 1)
+	vote("nSignA", 90000903, "LH3", 2)
+	// B appears mid-window at ledger 2 and agrees on everything after.
+	vote("nMasterB", 90000902, "LH2", 1)
+	vote("nMasterB", 90000903, "LH3", 1)
+	vote("nMasterB", 90000904, "LH4", 1)
+
+	if err := st.ComputeValidatorScores(ctx, 700000999, 700001020); err != nil {
+		t.Fatal(err)
+	}
+	// Recomputing the same window must replace, not duplicate.
+	if err := st.ComputeValidatorScores(ctx, 700000999, 700001020); err != nil {
+		t.Fatal(err)
+	}
+	scores, err := st.GetValidatorScores(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != 2 {
+		t.Fatalf("scores = %+v, want 2 validators", scores)
+	}
+	a, b := scores[0], scores[1]
+	if a.Validator != "nMasterA" || b.Validator != "nMasterB" {
+		a, b = b, a
+	}
+	if a.Validator != "nMasterA" || a.Agreed != 3 || a.Disagreed != 1 || a.Missed != 1 {
+		t.Errorf("A = %+v", a)
+	}
+	if a.Agreement < 0.59 || a.Agreement > 0.61 {
+		t.Errorf("A agreement = %g, want 0.6", a.Agreement)
+	}
+	if a.AvgSkewSeconds != 2 {
+		t.Errorf("A skew = %g, want 2", a.AvgSkewSeconds)
+	}
+	if b.Validator != "nMasterB" || b.Agreed != 3 || b.Missed != 0 || b.Disagreed != 0 || b.Agreement != 1 {
+		t.Errorf("B = %+v", b)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	st := openTestStore(t)
+	mustExec(t, st, `DELETE FROM api_key_usage`)
+	mustExec(t, st, `DELETE FROM api_keys`)
+	ctx := context.Background()
+
+	if err := st.CreateAPIKey(ctx, "sekrit", "acme", 60, []string{"admin"}); err != nil {
+		t.Fatal(err)
+	}
+	// Only the hash reaches the table.
+	var n int
+	if err := st.db.QueryRow(`SELECT count(*) FROM api_keys WHERE key_hash = 'sekrit'`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Error("api key stored in plaintext")
+	}
+
+	for i := 0; i < 3; i++ {
+		k, err := st.ValidateAPIKey(ctx, "sekrit")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k == nil || k.Partner != "acme" || k.RateLimit != 60 || !k.HasScope("admin") {
+			t.Fatalf("ValidateAPIKey = %+v", k)
+		}
+	}
+	if err := st.db.QueryRow(`SELECT request_count FROM api_keys WHERE partner = 'acme'`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("request_count = %d, want 3", n)
+	}
+	// Each validation also lands in today's usage row.
+	usage, err := st.APIKeyUsage(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 1 || usage[0].Partner != "acme" || usage[0].Requests != 3 {
+		t.Errorf("APIKeyUsage = %+v, want one acme row with 3 requests", usage)
+	}
+
+	if k, err := st.ValidateAPIKey(ctx, "wrong"); err != nil || k != nil {
+		t.Errorf("unknown key: got %+v, %v", k, err)
+	}
+	if err := st.RevokeAPIKey(ctx, "sekrit"); err != nil {
+		t.Fatal(err)
+	}
+	if k, err := st.ValidateAPIKey(ctx, "sekrit"); err != nil || k != nil {
+		t.Errorf("revoked key: got %+v, %v", k, err)
+	}
+	if err := st.RevokeAPIKey(ctx, "missing"); err == nil {
+		t.Error("revoking an unknown key should error")
+	}
+}
+
+func TestUpsertOfferKeyIsAccountSequence(t *testing.T) {
+	st := openTestStore(t)
+	mustExec(t, st, `DELETE FROM offers WHERE account IN ('rKeyAuditA', 'rKeyAuditB')`)
+	ctx := context.Background()
+
+	// Two accounts legitimately reuse the same sequence number; the
+	// upsert key is (account, sequence), so both rows must persist.
+	for i, account := range []string{"rKeyAuditA", "rKeyAuditB"} {
+		offer := &model.Offer{
+			TxHash: fmt.Sprintf("KEYAUDIT%d", i), Account: account, Sequence: 42,
+			TakerGets:   xrpl.Amount{Currency: "XRP", Value: "1"},
+			TakerPays:   xrpl.Amount{Currency: "USD", Issuer: "rI", Value: "2"},
+			Status:      model.OfferStatusOpen,
+			LedgerIndex: 90000060,
+		}
+		if err := st.UpsertOffer(ctx, offer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var n int
+	if err := st.db.QueryRow(`SELECT count(*) FROM offers WHERE sequence = 42 AND account IN ('rKeyAuditA', 'rKeyAuditB')`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("offers with shared sequence = %d rows, want 2 (cross-account collision?)", n)
+	}
+
+	// The same (account, sequence) seen again converges on its row
+	// instead of duplicating across ledgers.
+	if err := st.UpsertOffer(ctx, &model.Offer{
+		TxHash: "KEYAUDIT0B", Account: "rKeyAuditA", Sequence: 42,
+		TakerGets:   xrpl.Amount{Currency: "XRP", Value: "1"},
+		TakerPays:   xrpl.Amount{Currency: "USD", Issuer: "rI", Value: "2"},
+		Status:      model.OfferStatusPartiallyFilled,
+		LedgerIndex: 90000061,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var status string
+	if err := st.db.QueryRow(`SELECT count(*), min(status) FROM offers WHERE account = 'rKeyAuditA' AND sequence = 42`).Scan(&n, &status); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || status != model.OfferStatusPartiallyFilled {
+		t.Fatalf("re-upsert left %d rows with status %s", n, status)
+	}
+}
+
+func TestGetPoolsByAsset(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	mustExec(t, st, `DELETE FROM pools WHERE account IN ('rPoolSide1', 'rPoolSide2', 'rPoolOther')`)
+
+	// USD sits on asset1 in one pool and asset2 in the other; both must
+	// come back. The EUR pool must not.
+	mustExec(t, st, `
+		INSERT INTO pools (account, asset1_currency, asset1_issuer, asset1_value,
+			asset2_currency, asset2_issuer, asset2_value, trading_fee, ledger_index)
+		VALUES ('rPoolSide1', 'USD', 'rPoolIss', 500, 'XRP', '', 1000, 600, 90000800),
+		       ('rPoolSide2', 'XRP', '', 2000, 'USD', 'rPoolIss', 900, 500, 90000801),
+		       ('rPoolOther', 'EUR', 'rPoolIss', 100, 'XRP', '', 300, 600, 90000802)`)
+
+	pools, err := st.GetPoolsByAsset(ctx, "USD", "rPoolIss", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, p := range pools {
+		found[p.Account] = true
+	}
+	if !found["rPoolSide1"] || !found["rPoolSide2"] {
+		t.Fatalf("pools = %v, want both USD pools regardless of side", found)
+	}
+	if found["rPoolOther"] {
+		t.Fatal("EUR pool returned for a USD query")
+	}
+
+	if _, err := st.GetPoolsByAsset(ctx, "US", "rPoolIss", 0); err == nil {
+		t.Fatal("malformed currency accepted")
+	}
+}
+
+func TestMarketStatsRollup(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	mustExec(t, st, `DELETE FROM market_stats_hourly WHERE pair IN ('USD.rStatIss/XRP', 'EUR.rStatIss/XRP')`)
+	mustExec(t, st, `DELETE FROM pools WHERE account = 'rStatPool'`)
+	mustExec(t, st, `DELETE FROM checkpoints WHERE ledger_index = 90001000`)
+
+	// The rolling window anchors on the latest checkpointed close time.
+	if err := st.SaveCheckpoint(ctx, &model.Checkpoint{
+		LedgerIndex: 90001000, LedgerHash: "STATCP", CloseTime: 700003600,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	u := &model.MarketStatsUpdate{
+		Pair: "USD.rStatIss/XRP", Bucket: 700003600 / 3600,
+		Volume: "5", High: "2", Low: "1.5", Last: "1.5",
+		TradeCount: 2, LedgerIndex: 90001000,
+	}
+	if err := st.ApplyMarketStats(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	// Replaying the same ledger must not double-count.
+	if err := st.ApplyMarketStats(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	// A later ledger in the same bucket accumulates.
+	if err := st.ApplyMarketStats(ctx, &model.MarketStatsUpdate{
+		Pair: "USD.rStatIss/XRP", Bucket: 700003600 / 3600,
+		Volume: "3", High: "3", Low: "2.5", Last: "2.5",
+		TradeCount: 1, LedgerIndex: 90001001,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := st.GetMarketStats(ctx, "USD.rStatIss/XRP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Volume != "8" || stats.TradeCount != 3 {
+		t.Errorf("volume/count = %s/%d, want 8/3", stats.Volume, stats.TradeCount)
+	}
+	if stats.High != "3" || stats.Low != "1.5" || stats.LastPrice != "2.5" {
+		t.Errorf("high/low/last = %s/%s/%s", stats.High, stats.Low, stats.LastPrice)
+	}
+
+	// A pool with no window activity still lists its pair, after the
+	// traded markets.
+	mustExec(t, st, `
+		INSERT INTO pools (account, asset1_currency, asset1_issuer, asset1_value,
+			asset2_currency, asset2_issuer, asset2_value, trading_fee, ledger_index)
+		VALUES ('rStatPool', 'EUR', 'rStatIss', 100, 'XRP', '', 300, 600, 90001000)`)
+	markets, err := st.ListMarketStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPair := map[string]*model.MarketStats{}
+	for _, m := range markets {
+		byPair[m.Pair] = m
+	}
+	if m := byPair["USD.rStatIss/XRP"]; m == nil || m.Volume != "8" {
+		t.Fatalf("traded market = %+v", m)
+	}
+	if m := byPair["EUR.rStatIss/XRP"]; m == nil || m.Volume != "0" || m.TradeCount != 0 {
+		t.Fatalf("quiet pool market = %+v", m)
+	}
+}
+
+func TestRecordDisagreement(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	mustExec(t, st, `DELETE FROM validation_disagreements WHERE ledger_index = 98003000`)
+
+	d := &model.ValidationDisagreement{
+		LedgerIndex: 98003000, LedgerHash: "FORKA", Votes: 2,
+		Validators: []string{"nHOne", "nHTwo"},
+	}
+	if err := st.RecordDisagreement(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	// A late validation grows the side; re-recording overwrites.
+	d.Votes = 3
+	d.Validators = append(d.Validators, "nHFour")
+	if err := st.RecordDisagreement(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+
+	var votes int
+	var validators string
+	err := st.db.QueryRow(`
+		SELECT votes, validators FROM validation_disagreements
+		WHERE ledger_index = 98003000 AND ledger_hash = 'FORKA'`).Scan(&votes, &validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if votes != 3 || validators != "nHOne,nHTwo,nHFour" {
+		t.Errorf("side = %d votes, validators %q", votes, validators)
+	}
+
+	mustExec(t, st, `DELETE FROM validation_disagreements WHERE ledger_index = 98003000`)
+}
+
+func TestUNLParticipation(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	mustExec(t, st, `DELETE FROM validations WHERE ledger_index BETWEEN 98005000 AND 98005004`)
+	mustExec(t, st, `DELETE FROM checkpoints WHERE ledger_index BETWEEN 98005000 AND 98005004`)
+
+	for i := uint64(0); i < 5; i++ {
+		cp := &model.Checkpoint{
+			LedgerIndex: 98005000 + i,
+			LedgerHash:  fmt.Sprintf("UH%d", i),
+			CloseTime:   710001000 + int64(i*4),
+		}
+		if err := st.SaveCheckpoint(ctx, cp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	vote := func(key string, ledger uint64, hash string) {
+		t.Helper()
+		v := &model.Validation{Validator: key, LedgerIndex: ledger,
+			LedgerHash: hash, SigningTime: 710001000}
+		if err := st.InsertValidation(ctx, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Steady validates everything, Lagging agrees on three, signs the
+	// wrong hash once and misses once; Gone never shows up. A validator
+	// off the UNL must not appear in the report.
+	for i := uint64(0); i < 5; i++ {
+		vote("nHUnlSteady", 98005000+i, fmt.Sprintf("UH%d", i))
+	}
+	vote("nHUnlLagging", 98005000, "UH0")
+	vote("nHUnlLagging", 98005001, "UH1")
+	vote("nHUnlLagging", 98005002, "WRONG")
+	vote("nHUnlLagging", 98005003, "UH3")
+	vote("nHUnlOffList", 98005000, "UH0")
+
+	unl := []string{"nHUnlSteady", "nHUnlLagging", "nHUnlGone"}
+	parts, err := st.UNLParticipation(ctx, unl, 98005000, 98005004)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 3 {
+		t.Fatalf("parts = %+v, want one per UNL validator", parts)
+	}
+	byName := map[string]*model.UNLParticipation{}
+	for _, p := range parts {
+		byName[p.Validator] = p
+	}
+	if p := byName["nHUnlSteady"]; p.Expected != 5 || p.Agreed != 5 || p.Participation != 1.0 {
+		t.Errorf("steady = %+v", p)
+	}
+	if p := byName["nHUnlLagging"]; p.Agreed != 3 || p.Disagreed != 1 || p.Missed != 1 || p.Participation != 0.6 {
+		t.Errorf("lagging = %+v", p)
+	}
+	if p := byName["nHUnlGone"]; p.Expected != 5 || p.Missed != 5 || p.Participation != 0 {
+		t.Errorf("gone = %+v", p)
+	}
+
+	// A range with no checkpoints still yields a row per validator.
+	parts, err = st.UNLParticipation(ctx, unl, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 3 || parts[0].Expected != 0 {
+		t.Errorf("empty range parts = %+v", parts)
+	}
+	if parts, err = st.UNLParticipation(ctx, nil, 1, 2); err != nil || parts != nil {
+		t.Errorf("empty unl = %v, %v", parts, err)
+	}
+
+	mustExec(t, st, `DELETE FROM validations WHERE ledger_index BETWEEN 98005000 AND 98005004`)
+	mustExec(t, st, `DELETE FROM checkpoints WHERE ledger_index BETWEEN 98005000 AND 98005004`)
+}
+
+func TestSlowQueryLogThreshold(t *testing.T) {
+	var logged []string
+	s := &Store{}
+	s.SetSlowQueryLog(time.Millisecond, func(format string, args ...any) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	})
+
+	s.observeQuery("fast op", time.Now())
+	if len(logged) != 0 {
+		t.Fatalf("fast query logged: %v", logged)
+	}
+	s.observeQuery("slow op", time.Now().Add(-10*time.Millisecond))
+	if len(logged) != 1 || !strings.Contains(logged[0], "slow op") {
+		t.Fatalf("logged = %v, want one entry naming the slow op", logged)
+	}
+
+	// Without a threshold nothing is reported, however slow.
+	s.SetSlowQueryLog(0, func(format string, args ...any) {
+		t.Errorf("logged with zero threshold: "+format, args...)
+	})
+	s.observeQuery("slow op", time.Now().Add(-time.Second))
+}
+
+func TestSlowLedgers(t *testing.T) {
+	st := openTestStore(t)
+	ctx := context.Background()
+	mustExec(t, st, `DELETE FROM checkpoints WHERE ledger_index BETWEEN 90004000 AND 90004003`)
+
+	for i, timings := range []map[string]float64{
+		{"dispatch": 3.5, "raw": 1.0, "total_ms": 5.0},
+		{"dispatch": 40.0, "raw": 2.0, "total_ms": 45.5},
+		{"dispatch": 9.0, "total_ms": 12.0},
+		nil, // indexed before the instrumentation existed
+	} {
+		err := st.SaveCheckpoint(ctx, &model.Checkpoint{
+			LedgerIndex: 90004000 + uint64(i),
+			LedgerHash:  "ABCD",
+			CloseTime:   700000000,
+			Timings:     timings,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	slow, err := st.GetSlowLedgers(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slow) != 2 || slow[0].LedgerIndex != 90004001 || slow[1].LedgerIndex != 90004002 {
+		t.Fatalf("slow ledgers = %+v, want 90004001 then 90004002", slow)
+	}
+	if slow[0].TotalMS != 45.5 || slow[0].Stages["dispatch"] != 40.0 {
+		t.Errorf("worst ledger = %+v, want total 45.5 with dispatch 40", slow[0])
+	}
+	if _, ok := slow[0].Stages["total_ms"]; ok {
+		t.Errorf("total_ms left inside the stage breakdown: %+v", slow[0].Stages)
+	}
+
+	// Re-saving the same checkpoint without timings (a replay that did
+	// not measure) keeps the recorded breakdown.
+	err = st.SaveCheckpoint(ctx, &model.Checkpoint{
+		LedgerIndex: 90004001, LedgerHash: "ABCD", CloseTime: 700000000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slow, err = st.GetSlowLedgers(ctx, 1); err != nil || len(slow) != 1 || slow[0].TotalMS != 45.5 {
+		t.Errorf("after replay: slow = %+v, %v, want 90004001 still at 45.5", slow, err)
+	}
+
+	mustExec(t, st, `DELETE FROM checkpoints WHERE ledger_index BETWEEN 90004000 AND 90004003`)
+}
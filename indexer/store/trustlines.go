@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// UpsertTrustLine records the holder's side of a RippleState entry,
+// keyed on (account, currency, issuer). The ledger-index guard keeps an
+// out-of-order replay from moving a row backwards.
+func (s *Store) UpsertTrustLine(ctx context.Context, l *model.TrustLine) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO trust_lines (account, currency, issuer,
+			limit_value, no_ripple, frozen, ledger_index, close_time, tx_hash)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+		ON CONFLICT (account, currency, issuer) DO UPDATE SET
+			limit_value = EXCLUDED.limit_value,
+			no_ripple = EXCLUDED.no_ripple,
+			frozen = EXCLUDED.frozen,
+			ledger_index = EXCLUDED.ledger_index,
+			close_time = EXCLUDED.close_time,
+			tx_hash = EXCLUDED.tx_hash
+		WHERE trust_lines.ledger_index <= EXCLUDED.ledger_index`,
+		l.Account, l.Currency, l.Issuer,
+		l.Limit, l.NoRipple, l.Frozen, l.LedgerIndex, l.CloseTime, l.TxHash)
+	if err != nil {
+		return fmt.Errorf("store: upsert trust line %s %s.%s: %w", l.Account, l.Currency, l.Issuer, err)
+	}
+	return nil
+}
+
+// DeleteTrustLine removes a line whose RippleState entry the ledger
+// deleted. A line refreshed by a newer ledger stays — the deletion was
+// superseded — and unknown lines are a no-op.
+func (s *Store) DeleteTrustLine(ctx context.Context, account, currency, issuer string, ledgerIndex uint64) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM trust_lines
+		WHERE account = $1 AND currency = $2 AND issuer = $3 AND ledger_index <= $4`,
+		account, currency, issuer, ledgerIndex)
+	if err != nil {
+		return fmt.Errorf("store: delete trust line %s %s.%s: %w", account, currency, issuer, err)
+	}
+	return nil
+}
+
+// GetHolderCount returns how many accounts hold a trust line to the
+// issuer for the currency. The issuer's own mirror rows never exist —
+// lines are stored from the holder's perspective only — so a plain count
+// is the holder count.
+func (s *Store) GetHolderCount(ctx context.Context, currency, issuer string) (int64, error) {
+	var n int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT count(*) FROM trust_lines WHERE currency = $1 AND issuer = $2`,
+		currency, issuer).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("store: holder count %s.%s: %w", currency, issuer, err)
+	}
+	return n, nil
+}
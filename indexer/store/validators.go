@@ -0,0 +1,201 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/model"
+)
+
+// UpsertManifest records an ephemeral-to-master key mapping from a
+// validator manifest; a lower-sequence manifest never overwrites a newer
+// one for the same signing key.
+func (s *Store) UpsertManifest(ctx context.Context, signingKey, masterKey string, sequence uint64) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO manifests (signing_key, master_key, sequence)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (signing_key) DO UPDATE SET
+			master_key = EXCLUDED.master_key,
+			sequence = EXCLUDED.sequence
+		WHERE manifests.sequence <= EXCLUDED.sequence`,
+		signingKey, masterKey, sequence)
+	if err != nil {
+		return fmt.Errorf("store: upsert manifest: %w", err)
+	}
+	return nil
+}
+
+// InsertValidation records one validator vote, resolving the key through
+// the manifests table to the master key so one validator's rotating
+// signing keys score as one identity. A repeated vote for the same
+// (validator, ledger) is a no-op.
+func (s *Store) InsertValidation(ctx context.Context, v *model.Validation) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO validations (validator, ledger_index, ledger_hash, signing_time)
+		VALUES (COALESCE((SELECT master_key FROM manifests WHERE signing_key = $1), $1), $2, $3, $4)
+		ON CONFLICT (validator, ledger_index) DO NOTHING`,
+		v.Validator, v.LedgerIndex, v.LedgerHash, v.SigningTime)
+	if err != nil {
+		return fmt.Errorf("store: insert validation %d: %w", v.LedgerIndex, err)
+	}
+	return nil
+}
+
+// ComputeValidatorScores scores every validator over the window of
+// checkpointed ledgers whose close time falls in (windowStart,
+// windowEnd], writing one validator_scores row per validator keyed by
+// the window end. A validator first seen mid-window is only scored
+// against ledgers from its first validation onward. Recomputing the same
+// window replaces its rows, so the job is idempotent.
+func (s *Store) ComputeValidatorScores(ctx context.Context, windowStart, windowEnd int64) error {
+	_, err := s.db.ExecContext(ctx, `
+		WITH ledgers AS (
+			SELECT ledger_index, ledger_hash, close_time FROM checkpoints
+			WHERE close_time > $1 AND close_time <= $2
+		),
+		firsts AS (
+			SELECT validator, MIN(ledger_index) AS first_seen
+			FROM validations GROUP BY validator
+		),
+		expected AS (
+			SELECT f.validator, l.ledger_index, l.ledger_hash, l.close_time,
+				v.ledger_hash AS voted_hash, v.signing_time
+			FROM firsts f
+			JOIN ledgers l ON l.ledger_index >= f.first_seen
+			LEFT JOIN validations v
+				ON v.validator = f.validator AND v.ledger_index = l.ledger_index
+		)
+		INSERT INTO validator_scores
+			(validator, window_end, window_start, agreed, missed, disagreed, agreement, avg_skew_seconds)
+		SELECT validator, $2, $1,
+			COUNT(*) FILTER (WHERE voted_hash = ledger_hash),
+			COUNT(*) FILTER (WHERE voted_hash IS NULL),
+			COUNT(*) FILTER (WHERE voted_hash IS NOT NULL AND voted_hash <> ledger_hash),
+			COUNT(*) FILTER (WHERE voted_hash = ledger_hash)::double precision / COUNT(*),
+			COALESCE(AVG(signing_time - close_time) FILTER (WHERE voted_hash = ledger_hash), 0)
+		FROM expected
+		GROUP BY validator
+		ON CONFLICT (validator, window_end) DO UPDATE SET
+			window_start = EXCLUDED.window_start,
+			agreed = EXCLUDED.agreed,
+			missed = EXCLUDED.missed,
+			disagreed = EXCLUDED.disagreed,
+			agreement = EXCLUDED.agreement,
+			avg_skew_seconds = EXCLUDED.avg_skew_seconds`,
+		windowStart, windowEnd)
+	if err != nil {
+		return fmt.Errorf("store: computing validator scores: %w", err)
+	}
+	return nil
+}
+
+// GetValidatorScores returns each validator's most recent score, in
+// validator key order.
+func (s *Store) GetValidatorScores(ctx context.Context) ([]*model.ValidatorScore, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (validator)
+			validator, window_start, window_end, agreed, missed, disagreed,
+			agreement, avg_skew_seconds
+		FROM validator_scores
+		ORDER BY validator, window_end DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("store: validator scores: %w", err)
+	}
+	defer rows.Close()
+	var scores []*model.ValidatorScore
+	for rows.Next() {
+		var sc model.ValidatorScore
+		if err := rows.Scan(&sc.Validator, &sc.WindowStart, &sc.WindowEnd,
+			&sc.Agreed, &sc.Missed, &sc.Disagreed, &sc.Agreement, &sc.AvgSkewSeconds); err != nil {
+			return nil, fmt.Errorf("store: scanning validator score: %w", err)
+		}
+		scores = append(scores, &sc)
+	}
+	return scores, rows.Err()
+}
+
+// UNLParticipation scores each given UNL validator against every
+// checkpointed ledger in [from, to]: agreed when it signed the canonical
+// hash, disagreed when it signed another, missed when it signed nothing.
+// Unlike ComputeValidatorScores the expected set is the caller's UNL, so
+// a validator that never showed up at all still gets a row — all misses.
+func (s *Store) UNLParticipation(ctx context.Context, unl []string, from, to uint64) ([]*model.UNLParticipation, error) {
+	if len(unl) == 0 {
+		return nil, nil
+	}
+	values := make([]string, len(unl))
+	args := []any{from, to}
+	for i, v := range unl {
+		values[i] = "($" + strconv.Itoa(i+3) + "::text)"
+		args = append(args, v)
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		WITH unl(validator) AS (VALUES `+strings.Join(values, ",")+`),
+		ledgers AS (
+			SELECT ledger_index, ledger_hash FROM checkpoints
+			WHERE ledger_index BETWEEN $1 AND $2
+		),
+		expected AS (
+			SELECT u.validator, l.ledger_hash, v.ledger_hash AS voted_hash
+			FROM unl u
+			CROSS JOIN ledgers l
+			LEFT JOIN validations v
+				ON v.validator = u.validator AND v.ledger_index = l.ledger_index
+		)
+		SELECT validator,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE voted_hash = ledger_hash),
+			COUNT(*) FILTER (WHERE voted_hash IS NULL),
+			COUNT(*) FILTER (WHERE voted_hash IS NOT NULL AND voted_hash <> ledger_hash)
+		FROM expected
+		GROUP BY validator`,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("store: unl participation: %w", err)
+	}
+	defer rows.Close()
+	byValidator := map[string]*model.UNLParticipation{}
+	for rows.Next() {
+		var p model.UNLParticipation
+		if err := rows.Scan(&p.Validator, &p.Expected, &p.Agreed, &p.Missed, &p.Disagreed); err != nil {
+			return nil, fmt.Errorf("store: scanning unl participation: %w", err)
+		}
+		if p.Expected > 0 {
+			p.Participation = float64(p.Agreed) / float64(p.Expected)
+		}
+		byValidator[p.Validator] = &p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// An empty ledger range produces no expected rows at all; the report
+	// still lists every UNL validator, in UNL order.
+	parts := make([]*model.UNLParticipation, 0, len(unl))
+	for _, v := range unl {
+		p, ok := byValidator[v]
+		if !ok {
+			p = &model.UNLParticipation{Validator: v}
+		}
+		parts = append(parts, p)
+	}
+	return parts, nil
+}
+
+// RecordDisagreement persists one side of a split vote. Re-detection of
+// the same (ledger, hash) — a replayed window, a late validation pushing
+// the count up — overwrites the side with the fuller picture.
+func (s *Store) RecordDisagreement(ctx context.Context, d *model.ValidationDisagreement) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO validation_disagreements (ledger_index, ledger_hash, votes, validators)
+		VALUES ($1,$2,$3,$4)
+		ON CONFLICT (ledger_index, ledger_hash) DO UPDATE SET
+			votes = EXCLUDED.votes,
+			validators = EXCLUDED.validators`,
+		d.LedgerIndex, d.LedgerHash, d.Votes, strings.Join(d.Validators, ","))
+	if err != nil {
+		return fmt.Errorf("store: record disagreement %d: %w", d.LedgerIndex, err)
+	}
+	return nil
+}
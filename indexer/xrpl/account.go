@@ -0,0 +1,36 @@
+package xrpl
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// AccountDomain fetches an account's Domain field via account_info,
+// decoded from its on-ledger hex form and lowercased. An account
+// without a Domain returns ""; a missing account surfaces as
+// ErrAccountNotFound, which callers treat the same way — a validator
+// master key, for instance, has no backing account at all.
+func (c *Client) AccountDomain(ctx context.Context, account string) (string, error) {
+	params := map[string]any{
+		"account":      account,
+		"ledger_index": "validated",
+	}
+	var result struct {
+		AccountData struct {
+			Domain string `json:"Domain"`
+		} `json:"account_data"`
+	}
+	if err := c.Call(ctx, "account_info", params, &result); err != nil {
+		return "", err
+	}
+	if result.AccountData.Domain == "" {
+		return "", nil
+	}
+	raw, err := hex.DecodeString(result.AccountData.Domain)
+	if err != nil {
+		return "", fmt.Errorf("xrpl: account %s domain: %w", account, err)
+	}
+	return strings.ToLower(strings.TrimSpace(string(raw))), nil
+}
@@ -0,0 +1,46 @@
+package xrpl
+
+import (
+	"context"
+	"fmt"
+)
+
+// AMMInfo is the subset of an amm_info response the indexer reads: the
+// pool's account, both reserves, the outstanding LP tokens and the
+// effective trading fee.
+type AMMInfo struct {
+	Account    string `json:"account"`
+	Amount     Amount `json:"amount"`
+	Amount2    Amount `json:"amount2"`
+	LPToken    Amount `json:"lp_token"`
+	TradingFee uint32 `json:"trading_fee"`
+}
+
+// GetAMMInfo fetches the live AMM object for an asset pair via rippled's
+// amm_info command. The assets only need currency and issuer; values are
+// ignored.
+func (c *Client) GetAMMInfo(ctx context.Context, asset1, asset2 Amount) (*AMMInfo, error) {
+	params := map[string]any{
+		"asset":  assetParam(asset1),
+		"asset2": assetParam(asset2),
+	}
+	var result struct {
+		AMM AMMInfo `json:"amm"`
+	}
+	if err := c.Call(ctx, "amm_info", params, &result); err != nil {
+		return nil, err
+	}
+	if result.AMM.Account == "" {
+		return nil, fmt.Errorf("xrpl: amm_info: no amm for %s/%s", asset1.Currency, asset2.Currency)
+	}
+	return &result.AMM, nil
+}
+
+// assetParam renders an asset the way amm_info wants it: XRP as a bare
+// currency, issued assets with their issuer.
+func assetParam(a Amount) map[string]string {
+	if a.Issuer == "" {
+		return map[string]string{"currency": "XRP"}
+	}
+	return map[string]string{"currency": a.Currency, "issuer": a.Issuer}
+}
@@ -0,0 +1,132 @@
+package xrpl
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// dropsPerXRP is the number of drops in one XRP.
+const dropsPerXRP = 1_000_000
+
+// Amount is a currency amount as it appears in rippled JSON. Native XRP
+// amounts arrive as a bare string of drops; issued currencies arrive as an
+// object with currency, issuer and value. Value always holds the decimal
+// value in the currency's natural unit (XRP, not drops).
+type Amount struct {
+	Currency string `json:"currency"`
+	Issuer   string `json:"issuer,omitempty"`
+	Value    string `json:"value"`
+}
+
+// IsXRP reports whether the amount is native XRP.
+func (a Amount) IsXRP() bool {
+	return a.Currency == "XRP" && a.Issuer == ""
+}
+
+// Asset returns the amount's asset, canonicalized: XRP loses any stray
+// issuer and hex currency codes are uppercased.
+func (a Amount) Asset() Asset {
+	if a.IsXRP() {
+		return XRP
+	}
+	currency := a.Currency
+	if len(currency) == 40 && isHex(currency) {
+		currency = strings.ToUpper(currency)
+	}
+	return Asset{Currency: currency, Issuer: a.Issuer}
+}
+
+// Rat returns the amount's value as an arbitrary-precision rational, or an
+// error if the value is not a valid decimal.
+func (a Amount) Rat() (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(a.Value)
+	if !ok {
+		return nil, fmt.Errorf("xrpl: invalid amount value %q", a.Value)
+	}
+	return r, nil
+}
+
+// String renders the amount for logs and debugging.
+func (a Amount) String() string {
+	if a.IsXRP() {
+		return a.Value + " XRP"
+	}
+	return fmt.Sprintf("%s %s.%s", a.Value, a.Currency, a.Issuer)
+}
+
+// UnmarshalJSON accepts both wire forms: a drops string for XRP and an
+// object for issued currencies.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var drops string
+		if err := json.Unmarshal(data, &drops); err != nil {
+			return err
+		}
+		xrp, err := dropsToXRP(drops)
+		if err != nil {
+			return err
+		}
+		*a = Amount{Currency: "XRP", Value: xrp}
+		return nil
+	}
+	type wire Amount
+	var w wire
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	*a = Amount(w)
+	return nil
+}
+
+// MarshalJSON emits the rippled wire form: a drops string for XRP, an
+// object otherwise.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	if a.IsXRP() {
+		drops, err := xrpToDrops(a.Value)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(drops)
+	}
+	type wire Amount
+	return json.Marshal(wire(a))
+}
+
+func dropsToXRP(drops string) (string, error) {
+	d, ok := new(big.Rat).SetString(drops)
+	if !ok {
+		return "", fmt.Errorf("xrpl: invalid drops amount %q", drops)
+	}
+	d.Quo(d, big.NewRat(dropsPerXRP, 1))
+	return ratDecimalString(d), nil
+}
+
+func xrpToDrops(xrp string) (string, error) {
+	v, ok := new(big.Rat).SetString(xrp)
+	if !ok {
+		return "", fmt.Errorf("xrpl: invalid XRP amount %q", xrp)
+	}
+	v.Mul(v, big.NewRat(dropsPerXRP, 1))
+	if !v.IsInt() {
+		return "", fmt.Errorf("xrpl: XRP amount %q is not a whole number of drops", xrp)
+	}
+	return v.Num().String(), nil
+}
+
+// ratDecimalString formats a rational as a plain decimal string, trimming
+// trailing zeros. XRPL issued amounts carry at most 15 significant digits,
+// so 20 decimal places is always enough to round-trip.
+func ratDecimalString(r *big.Rat) string {
+	s := r.FloatString(20)
+	// Trim trailing zeros, then a trailing dot.
+	i := len(s)
+	for i > 0 && s[i-1] == '0' {
+		i--
+	}
+	if i > 0 && s[i-1] == '.' {
+		i--
+	}
+	return s[:i]
+}
@@ -0,0 +1,90 @@
+package xrpl
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Asset identifies one leg of a market: a currency and, for issued
+// currencies, the issuing account. XRP is Asset{Currency: "XRP"} with
+// no issuer. ParseAsset canonicalizes (hex currency codes are
+// uppercased), so parsed assets compare correctly with ==; Equal exists
+// for call sites that read better with a named method.
+type Asset struct {
+	Currency string `json:"currency"`
+	Issuer   string `json:"issuer,omitempty"`
+}
+
+// XRP is the native asset.
+var XRP = Asset{Currency: "XRP"}
+
+// IsXRP reports whether the asset is native XRP.
+func (a Asset) IsXRP() bool {
+	return a.Currency == "XRP" && a.Issuer == ""
+}
+
+// Equal reports whether two assets name the same currency and issuer.
+func (a Asset) Equal(b Asset) bool { return a == b }
+
+// String renders the canonical form: "XRP", or "CUR.issuer".
+func (a Asset) String() string {
+	if a.IsXRP() {
+		return "XRP"
+	}
+	return a.Currency + "." + a.Issuer
+}
+
+// ParseAsset parses the canonical form back into an asset: "XRP", or a
+// currency code joined to its issuer with a dot. Currency codes are the
+// ledger's two shapes — three ASCII characters or 40 hex digits — and
+// hex codes come out uppercased so equal assets always parse equal.
+func ParseAsset(s string) (Asset, error) {
+	if s == "XRP" {
+		return XRP, nil
+	}
+	currency, issuer, ok := strings.Cut(s, ".")
+	if !ok || currency == "" || issuer == "" {
+		return Asset{}, fmt.Errorf("xrpl: asset %q is neither XRP nor CUR.issuer", s)
+	}
+	switch {
+	case len(currency) == 40 && isHex(currency):
+		currency = strings.ToUpper(currency)
+	case len(currency) == 3 && currency != "XRP":
+	default:
+		return Asset{}, fmt.Errorf("xrpl: asset %q: currency must be three characters or 40 hex digits", s)
+	}
+	if !strings.HasPrefix(issuer, "r") || strings.Contains(issuer, ".") {
+		return Asset{}, fmt.Errorf("xrpl: asset %q: issuer is not an account address", s)
+	}
+	return Asset{Currency: currency, Issuer: issuer}, nil
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalJSON emits the canonical string form.
+func (a Asset) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON parses the canonical string form.
+func (a *Asset) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseAsset(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
@@ -0,0 +1,89 @@
+package xrpl
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseAssetRoundTrip(t *testing.T) {
+	hex := "0158415500000000C1F76FF6ECB0BAC600000000"
+	for _, s := range []string{
+		"XRP",
+		"USD.rvYAfWj5gh67oV6fW32ZzP3Aw4Eubs59B",
+		hex + ".rvYAfWj5gh67oV6fW32ZzP3Aw4Eubs59B",
+	} {
+		a, err := ParseAsset(s)
+		if err != nil {
+			t.Errorf("ParseAsset(%q): %v", s, err)
+			continue
+		}
+		if a.String() != s {
+			t.Errorf("ParseAsset(%q).String() = %q", s, a.String())
+		}
+	}
+}
+
+func TestParseAssetCanonicalizesHex(t *testing.T) {
+	lower := "0158415500000000c1f76ff6ecb0bac600000000"
+	a, err := ParseAsset(lower + ".rIssuerIssuerIssuerIssuer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upper, _ := ParseAsset("0158415500000000C1F76FF6ECB0BAC600000000.rIssuerIssuerIssuerIssuer")
+	if !a.Equal(upper) || a != upper {
+		t.Errorf("lowercase hex parsed to %v, uppercase to %v", a, upper)
+	}
+}
+
+func TestParseAssetErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"bogus",
+		"XRP.rIssuer",                        // XRP takes no issuer
+		".rIssuer",                           // empty currency
+		"USD.",                               // empty issuer
+		"USDT.rIssuer",                       // not 3 chars, not 40 hex
+		"USD.xNotAnAddress",                  // issuer shape
+		"USD.rIss.uer",                       // dot inside issuer
+		"0158415500000000C1F76FF6ECB0BAC6.r", // hex too short
+	} {
+		if _, err := ParseAsset(s); err == nil {
+			t.Errorf("ParseAsset(%q) accepted a malformed asset", s)
+		}
+	}
+}
+
+func TestAssetJSON(t *testing.T) {
+	a := Asset{Currency: "USD", Issuer: "rIssuer"}
+	data, err := json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"USD.rIssuer"` {
+		t.Errorf("marshalled to %s", data)
+	}
+	var back Asset
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back != a {
+		t.Errorf("round-trip = %v", back)
+	}
+	if err := json.Unmarshal([]byte(`"not an asset"`), &back); err == nil {
+		t.Error("unmarshalling a malformed asset did not error")
+	}
+	xrp, _ := json.Marshal(XRP)
+	if string(xrp) != `"XRP"` {
+		t.Errorf("XRP marshalled to %s", xrp)
+	}
+}
+
+func TestAmountAsset(t *testing.T) {
+	if got := (Amount{Currency: "XRP", Value: "10"}).Asset(); got != XRP {
+		t.Errorf("XRP amount asset = %v", got)
+	}
+	got := Amount{Currency: "0158415500000000c1f76ff6ecb0bac600000000", Issuer: "rIss", Value: "1"}.Asset()
+	if got.Currency != "0158415500000000C1F76FF6ECB0BAC600000000" {
+		t.Errorf("hex currency not canonicalized: %q", got.Currency)
+	}
+}
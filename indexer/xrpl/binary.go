@@ -0,0 +1,36 @@
+package xrpl
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/internal/binarycodec"
+)
+
+// TransactionFromBlob decodes a binary transaction (tx_blob) and its binary
+// metadata into the same Transaction struct JSON ledgers produce. Some
+// history servers only serve binary ledgers; after this conversion the
+// parsers cannot tell the difference.
+func TransactionFromBlob(txBlob, metaBlob string) (*Transaction, error) {
+	rawTx, err := binarycodec.DecodeHex(txBlob)
+	if err != nil {
+		return nil, fmt.Errorf("xrpl: decoding tx_blob: %w", err)
+	}
+	var tx Transaction
+	if err := json.Unmarshal(rawTx, &tx); err != nil {
+		return nil, fmt.Errorf("xrpl: binary tx: %w", err)
+	}
+	tx.Raw = rawTx
+	if metaBlob != "" {
+		rawMeta, err := binarycodec.DecodeHex(metaBlob)
+		if err != nil {
+			return nil, fmt.Errorf("xrpl: decoding binary meta: %w", err)
+		}
+		var meta Meta
+		if err := json.Unmarshal(rawMeta, &meta); err != nil {
+			return nil, fmt.Errorf("xrpl: binary meta: %w", err)
+		}
+		tx.Meta = &meta
+	}
+	return &tx, nil
+}
@@ -0,0 +1,50 @@
+package xrpl
+
+import (
+	"context"
+)
+
+// BookOffer is one Offer ledger entry from a book_offers response. Index
+// is the entry's ledger object id — stable for the offer's lifetime, so
+// it doubles as an identifier for offers seen only through a snapshot.
+type BookOffer struct {
+	Index     string `json:"index"`
+	Account   string `json:"Account"`
+	Sequence  uint32 `json:"Sequence"`
+	Flags     uint32 `json:"Flags"`
+	TakerGets Amount `json:"TakerGets"`
+	TakerPays Amount `json:"TakerPays"`
+}
+
+// BookOffers fetches one side of the validated order book for a pair via
+// rippled's book_offers command, best quality first. A non-positive
+// limit leaves the server's default in place; either way only one page
+// is fetched — snapshots want the top of the book, not its long tail.
+func (c *Client) BookOffers(ctx context.Context, takerGets, takerPays Asset, limit int) ([]*BookOffer, error) {
+	return c.bookOffers(ctx, takerGets, takerPays, "validated", limit)
+}
+
+// BookOffersAt is BookOffers pinned to one historical ledger, so both
+// sides of a comparison — and both directions of a book — can see the
+// same instant instead of racing the validation stream.
+func (c *Client) BookOffersAt(ctx context.Context, takerGets, takerPays Asset, ledgerIndex uint64, limit int) ([]*BookOffer, error) {
+	return c.bookOffers(ctx, takerGets, takerPays, ledgerIndex, limit)
+}
+
+func (c *Client) bookOffers(ctx context.Context, takerGets, takerPays Asset, ledger any, limit int) ([]*BookOffer, error) {
+	params := map[string]any{
+		"taker_gets":   takerGets,
+		"taker_pays":   takerPays,
+		"ledger_index": ledger,
+	}
+	if limit > 0 {
+		params["limit"] = limit
+	}
+	var result struct {
+		Offers []*BookOffer `json:"offers"`
+	}
+	if err := c.Call(ctx, "book_offers", params, &result); err != nil {
+		return nil, err
+	}
+	return result.Offers, nil
+}
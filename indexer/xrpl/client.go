@@ -0,0 +1,393 @@
+package xrpl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Client talks to a rippled server over its JSON-RPC HTTP endpoint. All
+// methods take a context so in-flight requests die with shutdown; a
+// per-request timeout bounds calls whose context carries no deadline of
+// its own.
+type Client struct {
+	url     string
+	http    *http.Client
+	timeout time.Duration
+
+	// serverType is learned from the first server_info answer; it lets
+	// the client skip calls the detected server cannot serve.
+	serverType string
+}
+
+// DefaultRequestTimeout bounds a single rippled call unless the caller's
+// context is stricter.
+const DefaultRequestTimeout = 10 * time.Second
+
+// NewClient returns a client for the given rippled JSON-RPC URL,
+// e.g. "http://localhost:5005".
+func NewClient(url string) *Client {
+	return &Client{url: url, http: &http.Client{}, timeout: DefaultRequestTimeout}
+}
+
+// SetTimeout overrides the default per-request timeout; zero disables it.
+func (c *Client) SetTimeout(d time.Duration) { c.timeout = d }
+
+type rpcRequest struct {
+	Method string `json:"method"`
+	Params []any  `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+}
+
+// Call invokes a rippled method with a single params object and decodes the
+// result into out. It returns as soon as ctx is cancelled.
+func (c *Client) Call(ctx context.Context, method string, params any, out any) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	req := rpcRequest{Method: method}
+	if params != nil {
+		req.Params = []any{params}
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("xrpl: %s: %w", method, err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("xrpl: %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("xrpl: %s: unexpected HTTP status %s", method, resp.Status)
+	}
+	var rr rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rr); err != nil {
+		return fmt.Errorf("xrpl: %s: decoding response: %w", method, err)
+	}
+	var status struct {
+		Status       string `json:"status"`
+		Error        string `json:"error"`
+		ErrorMessage string `json:"error_message"`
+	}
+	if err := json.Unmarshal(rr.Result, &status); err != nil {
+		return fmt.Errorf("xrpl: %s: decoding result: %w", method, err)
+	}
+	if status.Status == "error" {
+		return fmt.Errorf("xrpl: %s: %w", method, &RippledError{Code: status.Error, Message: status.ErrorMessage})
+	}
+	return json.Unmarshal(rr.Result, out)
+}
+
+// RippledError is an error response from rippled's JSON-RPC layer: the
+// machine-readable code ("lgrNotFound", "tooBusy", ...) plus the human
+// message when the server sent one. Callers reach it with errors.As, or
+// with errors.Is against the not-found sentinels below.
+type RippledError struct {
+	Code    string
+	Message string
+}
+
+func (e *RippledError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("rippled error: %s: %s", e.Code, e.Message)
+	}
+	return "rippled error: " + e.Code
+}
+
+// Is lets errors.Is match the well-known sentinels without unwrapping to
+// the concrete type.
+func (e *RippledError) Is(target error) bool {
+	switch target {
+	case ErrAMMNotFound:
+		return e.Code == "ammNotFound"
+	case ErrAccountNotFound:
+		return e.Code == "actNotFound"
+	}
+	return false
+}
+
+// Retryable reports whether the same call may succeed on a later attempt.
+// Server-load and server-state codes are worth retrying; codes that say
+// the request itself is bad, or the data is gone (a pruned ledger, a
+// missing account), never improve with repetition. Unknown codes count as
+// retryable so a new rippled release cannot silently turn retries off.
+func (e *RippledError) Retryable() bool {
+	switch e.Code {
+	case "lgrNotFound", "txnNotFound", "actNotFound", "ammNotFound",
+		"actMalformed", "lgrIdxMalformed", "lgrIdxsInvalid",
+		"invalidParams", "unknownCmd", "forbidden", "noPermission",
+		// Clio's spellings for data outside its range.
+		"ledgerNotFound", "transactionNotFound", "ledgerRangeInvalid":
+		return false
+	}
+	return true
+}
+
+// Well-known rippled error responses callers branch on with errors.Is.
+var (
+	ErrAMMNotFound     = errors.New("rippled error: ammNotFound")
+	ErrAccountNotFound = errors.New("rippled error: actNotFound")
+)
+
+// Ledger fetches a validated ledger header and its transaction hashes.
+// Transactions are fetched separately with Tx.
+func (c *Client) Ledger(ctx context.Context, index uint64) (*Ledger, []string, error) {
+	params := map[string]any{
+		"ledger_index": index,
+		"transactions": true,
+	}
+	var result struct {
+		Ledger struct {
+			Hash         string      `json:"ledger_hash"`
+			Index        json.Number `json:"ledger_index"`
+			CloseTime    int64       `json:"close_time"`
+			Transactions []string    `json:"transactions"`
+		} `json:"ledger"`
+		Validated bool `json:"validated"`
+	}
+	if err := c.Call(ctx, "ledger", params, &result); err != nil {
+		return nil, nil, err
+	}
+	if !result.Validated {
+		return nil, nil, fmt.Errorf("xrpl: ledger %d not yet validated", index)
+	}
+	idx, err := result.Ledger.Index.Int64()
+	if err != nil {
+		return nil, nil, fmt.Errorf("xrpl: bad ledger_index %q", result.Ledger.Index)
+	}
+	lg := &Ledger{
+		Index:     uint64(idx),
+		Hash:      result.Ledger.Hash,
+		CloseTime: result.Ledger.CloseTime,
+		TxnCount:  len(result.Ledger.Transactions),
+	}
+	return lg, result.Ledger.Transactions, nil
+}
+
+// GetServerInfo fetches the server_info result and remembers the
+// detected server type (rippled or Clio) for later calls.
+func (c *Client) GetServerInfo(ctx context.Context) (*ServerInfo, error) {
+	var result struct {
+		Info ServerInfo `json:"info"`
+	}
+	if err := c.Call(ctx, "server_info", nil, &result); err != nil {
+		return nil, err
+	}
+	c.serverType = result.Info.ServerType()
+	return &result.Info, nil
+}
+
+// ServerType is the kind of server detected by the last GetServerInfo;
+// empty before the first call.
+func (c *Client) ServerType() string { return c.serverType }
+
+// Feature is one entry of the admin "feature" command's result.
+type Feature struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// Features fetches amendment status via the admin "feature" command,
+// keyed by amendment id. Callers should treat errors as non-fatal: the
+// command is admin-only and many servers refuse it.
+func (c *Client) Features(ctx context.Context) (map[string]Feature, error) {
+	// Clio serves no admin commands at all; fail without the round trip.
+	if c.serverType == ServerTypeClio {
+		return nil, errors.New("xrpl: feature is an admin command, Clio does not serve it")
+	}
+	var result struct {
+		Features map[string]Feature `json:"features"`
+	}
+	if err := c.Call(ctx, "feature", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Features, nil
+}
+
+// FetchLedger fetches a ledger and all of its transactions in one call,
+// requesting transactions expanded so every transaction arrives with its
+// full metadata — AffectedNodes, TransactionResult, delivered_amount —
+// which the fill and reserve parsers depend on. Servers that answer with
+// bare hashes or binary blobs anyway get the slow path: one tx call per
+// hash. (The "transactions" subscription stream already carries metadata;
+// see decodeStreamMessage.)
+func (c *Client) FetchLedger(ctx context.Context, index uint64) (*Ledger, error) {
+	params := map[string]any{
+		"ledger_index": index,
+		"transactions": true,
+		"expand":       true,
+	}
+	var result struct {
+		Ledger struct {
+			Hash         string            `json:"ledger_hash"`
+			Index        json.Number       `json:"ledger_index"`
+			CloseTime    int64             `json:"close_time"`
+			Transactions []json.RawMessage `json:"transactions"`
+		} `json:"ledger"`
+		Validated bool `json:"validated"`
+	}
+	if err := c.Call(ctx, "ledger", params, &result); err != nil {
+		return nil, err
+	}
+	if !result.Validated {
+		return nil, fmt.Errorf("xrpl: ledger %d not yet validated", index)
+	}
+	idx, err := result.Ledger.Index.Int64()
+	if err != nil {
+		return nil, fmt.Errorf("xrpl: bad ledger_index %q", result.Ledger.Index)
+	}
+	lg := &Ledger{
+		Index:     uint64(idx),
+		Hash:      result.Ledger.Hash,
+		CloseTime: result.Ledger.CloseTime,
+		TxnCount:  len(result.Ledger.Transactions),
+	}
+	for i, raw := range result.Ledger.Transactions {
+		tx, hash, err := decodeLedgerTransaction(raw)
+		if err != nil {
+			return nil, fmt.Errorf("xrpl: ledger %d transaction %d: %w", index, i, err)
+		}
+		if tx == nil {
+			if tx, err = c.Tx(ctx, hash); err != nil {
+				return nil, err
+			}
+		}
+		lg.Transactions = append(lg.Transactions, tx)
+	}
+	return lg, nil
+}
+
+// decodeLedgerTransaction decodes one entry of an expanded ledger's
+// transactions array. An entry is normally a full transaction object with
+// its metadata inline under metaData; servers that ignore expand send bare
+// hash strings, and binary-only servers send tx_blob objects. For a hash
+// entry tx is nil and the caller must fall back to a tx call.
+func decodeLedgerTransaction(raw json.RawMessage) (tx *Transaction, hash string, err error) {
+	if len(raw) > 0 && raw[0] == '"' {
+		if err := json.Unmarshal(raw, &hash); err != nil {
+			return nil, "", err
+		}
+		return nil, hash, nil
+	}
+	var aux struct {
+		TxBlob   string          `json:"tx_blob"`
+		Hash     string          `json:"hash"`
+		MetaData json.RawMessage `json:"metaData"`
+		Meta     json.RawMessage `json:"meta"`
+	}
+	if err := json.Unmarshal(raw, &aux); err != nil {
+		return nil, "", err
+	}
+	if aux.TxBlob != "" {
+		var metaHex string
+		if len(aux.Meta) > 0 && aux.Meta[0] == '"' {
+			if err := json.Unmarshal(aux.Meta, &metaHex); err != nil {
+				return nil, "", fmt.Errorf("binary meta: %w", err)
+			}
+		}
+		tx, err := TransactionFromBlob(aux.TxBlob, metaHex)
+		if err != nil {
+			return nil, "", err
+		}
+		if tx.Hash == "" {
+			tx.Hash = aux.Hash
+		}
+		return tx, "", nil
+	}
+	tx = new(Transaction)
+	if err := json.Unmarshal(raw, tx); err != nil {
+		return nil, "", err
+	}
+	tx.RawMeta = aux.MetaData
+	if len(aux.Meta) > 0 {
+		tx.RawMeta = aux.Meta
+	}
+	if tx.Meta == nil && len(aux.Meta) > 0 {
+		var meta Meta
+		if err := json.Unmarshal(aux.Meta, &meta); err != nil {
+			return nil, "", fmt.Errorf("meta: %w", err)
+		}
+		tx.Meta = &meta
+	}
+	tx.Raw = raw
+	return tx, "", nil
+}
+
+// FetchLedgerSync is FetchLedger without cancellation, kept for callers
+// that have no context of their own.
+func (c *Client) FetchLedgerSync(index uint64) (*Ledger, error) {
+	return c.FetchLedger(context.Background(), index)
+}
+
+// Tx fetches a single validated transaction with its metadata.
+func (c *Client) Tx(ctx context.Context, hash string) (*Transaction, error) {
+	params := map[string]any{"transaction": hash}
+	var raw json.RawMessage
+	if err := c.Call(ctx, "tx", params, &raw); err != nil {
+		return nil, err
+	}
+	// Binary-only servers return the transaction as a tx blob.
+	var bin struct {
+		TxBlob string          `json:"tx"`
+		Meta   json.RawMessage `json:"meta"`
+		Hash   string          `json:"hash"`
+	}
+	if err := json.Unmarshal(raw, &bin); err == nil && bin.TxBlob != "" {
+		var metaHex string
+		if len(bin.Meta) > 0 && bin.Meta[0] == '"' {
+			if err := json.Unmarshal(bin.Meta, &metaHex); err != nil {
+				return nil, fmt.Errorf("xrpl: tx %s binary meta: %w", hash, err)
+			}
+		}
+		tx, err := TransactionFromBlob(bin.TxBlob, metaHex)
+		if err != nil {
+			return nil, fmt.Errorf("xrpl: tx %s: %w", hash, err)
+		}
+		tx.Hash = bin.Hash
+		if tx.Hash == "" {
+			tx.Hash = hash
+		}
+		return tx, nil
+	}
+	var tx Transaction
+	if err := json.Unmarshal(raw, &tx); err != nil {
+		return nil, fmt.Errorf("xrpl: decoding tx %s: %w", hash, err)
+	}
+	// The tx method nests metadata under "meta", not "metaData"; keep the
+	// raw bytes of whichever is present so the store never re-encodes.
+	var aux struct {
+		MetaData json.RawMessage `json:"metaData"`
+		Meta     json.RawMessage `json:"meta"`
+	}
+	if err := json.Unmarshal(raw, &aux); err != nil {
+		return nil, fmt.Errorf("xrpl: decoding tx %s meta: %w", hash, err)
+	}
+	tx.RawMeta = aux.MetaData
+	if len(aux.Meta) > 0 {
+		tx.RawMeta = aux.Meta
+	}
+	if tx.Meta == nil && len(aux.Meta) > 0 {
+		var meta Meta
+		if err := json.Unmarshal(aux.Meta, &meta); err != nil {
+			return nil, fmt.Errorf("xrpl: decoding tx %s meta: %w", hash, err)
+		}
+		tx.Meta = &meta
+	}
+	tx.Raw = raw
+	return &tx, nil
+}
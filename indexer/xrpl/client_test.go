@@ -0,0 +1,273 @@
+package xrpl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCallCancelledContextReturnsPromptly(t *testing.T) {
+	// A server that never answers.
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	client := NewClient(srv.URL)
+	client.SetTimeout(0) // rely on the caller's context alone
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, _, err := client.Ledger(ctx, 1)
+	if err == nil {
+		t.Fatal("Ledger succeeded against a server that never answers")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("cancellation took %v, want prompt return", elapsed)
+	}
+}
+
+func TestCallDefaultTimeout(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	client := NewClient(srv.URL)
+	client.SetTimeout(30 * time.Millisecond)
+
+	start := time.Now()
+	_, err := client.GetServerInfo(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("timeout took %v", elapsed)
+	}
+}
+
+func TestFetchLedgerExpandsTransactionMeta(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "ledger_expanded.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sawParams map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string           `json:"method"`
+			Params []map[string]any `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if req.Method != "ledger" {
+			t.Errorf("unexpected %s call; the expanded fetch must not need tx", req.Method)
+		}
+		if len(req.Params) > 0 {
+			sawParams = req.Params[0]
+		}
+		json.NewEncoder(w).Encode(map[string]any{"result": json.RawMessage(fixture)})
+	}))
+	defer srv.Close()
+
+	lg, err := NewClient(srv.URL).FetchLedger(context.Background(), 90000100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sawParams["transactions"] != true || sawParams["expand"] != true {
+		t.Errorf("ledger params = %v, want transactions and expand", sawParams)
+	}
+	if lg.Index != 90000100 || len(lg.Transactions) != 2 {
+		t.Fatalf("ledger = %+v", lg)
+	}
+
+	pay := lg.Transactions[0]
+	if pay.Meta == nil {
+		t.Fatal("payment meta not populated from the expanded ledger")
+	}
+	if pay.Meta.TransactionResult != "tesSUCCESS" || len(pay.Meta.AffectedNodes) != 1 {
+		t.Errorf("payment meta = %+v", pay.Meta)
+	}
+	if got := pay.Meta.DeliveredAmount; got.Value != "99.5" || got.Currency != "USD" {
+		t.Errorf("delivered_amount = %+v", got)
+	}
+	node, kind := pay.Meta.AffectedNodes[0].Node()
+	if kind != NodeModified || node.LedgerEntryType != "Offer" {
+		t.Errorf("affected node = %+v kind %d", node, kind)
+	}
+	if len(pay.Raw) == 0 || len(pay.RawMeta) == 0 {
+		t.Error("raw transaction and meta bytes must be retained")
+	}
+
+	cancel := lg.Transactions[1]
+	if cancel.TransactionType != "OfferCancel" || cancel.OfferSequence != 5 {
+		t.Errorf("second transaction = %+v", cancel)
+	}
+	if cancel.Meta == nil || cancel.Meta.TransactionResult != "tesSUCCESS" {
+		t.Errorf("cancel meta = %+v", cancel.Meta)
+	}
+}
+
+func TestFetchLedgerHashFallback(t *testing.T) {
+	// A server that ignores expand and answers with hashes must still work
+	// through per-transaction tx calls.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string           `json:"method"`
+			Params []map[string]any `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var result any
+		switch req.Method {
+		case "ledger":
+			result = map[string]any{
+				"validated": true,
+				"ledger": map[string]any{
+					"ledger_hash":  "AB01",
+					"ledger_index": "90000101",
+					"close_time":   700000410,
+					"transactions": []string{"FALLBACKTX"},
+				},
+			}
+		case "tx":
+			result = map[string]any{
+				"hash":            "FALLBACKTX",
+				"TransactionType": "OfferCancel",
+				"Account":         "rFallback",
+				"Sequence":        3,
+				"OfferSequence":   2,
+				"meta":            map[string]any{"TransactionResult": "tesSUCCESS"},
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"result": result})
+	}))
+	defer srv.Close()
+
+	lg, err := NewClient(srv.URL).FetchLedger(context.Background(), 90000101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lg.Transactions) != 1 {
+		t.Fatalf("transactions = %d", len(lg.Transactions))
+	}
+	tx := lg.Transactions[0]
+	if tx.Hash != "FALLBACKTX" || tx.Meta == nil || tx.Meta.TransactionResult != "tesSUCCESS" {
+		t.Errorf("fallback tx = %+v meta %+v", tx, tx.Meta)
+	}
+}
+
+func TestRippledErrorClassification(t *testing.T) {
+	cases := []struct {
+		code      string
+		retryable bool
+	}{
+		{"lgrNotFound", false},
+		{"txnNotFound", false},
+		{"actNotFound", false},
+		{"ammNotFound", false},
+		{"invalidParams", false},
+		{"unknownCmd", false},
+		{"tooBusy", true},
+		{"slowDown", true},
+		{"noNetwork", true},
+		{"noCurrent", true},
+		{"noClosed", true},
+		// Clio's out-of-range spellings are terminal too.
+		{"ledgerNotFound", false},
+		{"ledgerRangeInvalid", false},
+		// An unknown code keeps the old always-retry behavior.
+		{"someFutureCode", true},
+	}
+	var code string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"result": map[string]any{
+			"status": "error", "error": code, "error_message": "because " + code,
+		}})
+	}))
+	defer srv.Close()
+	client := NewClient(srv.URL)
+
+	for _, tc := range cases {
+		code = tc.code
+		_, err := client.GetServerInfo(context.Background())
+		if err == nil {
+			t.Fatalf("%s: no error surfaced", tc.code)
+		}
+		var rerr *RippledError
+		if !errors.As(err, &rerr) {
+			t.Fatalf("%s: err = %v, want a RippledError", tc.code, err)
+		}
+		if rerr.Code != tc.code || rerr.Message != "because "+tc.code {
+			t.Errorf("%s: parsed %+v", tc.code, rerr)
+		}
+		if rerr.Retryable() != tc.retryable {
+			t.Errorf("%s: Retryable() = %v, want %v", tc.code, rerr.Retryable(), tc.retryable)
+		}
+	}
+}
+
+func TestGetServerInfoDetectsClio(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		json.NewEncoder(w).Encode(map[string]any{"result": map[string]any{
+			"status": "success",
+			"info": map[string]any{
+				"clio_version":     "2.1.0",
+				"complete_ledgers": "32570-90000000",
+				"network_id":       0,
+				"validated_ledger": map[string]any{"seq": 90000000},
+			},
+		}})
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+	si, err := client.GetServerInfo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if si.ServerType() != ServerTypeClio || client.ServerType() != ServerTypeClio {
+		t.Errorf("server type = %s / %s, want clio", si.ServerType(), client.ServerType())
+	}
+	if si.Version() != "2.1.0" {
+		t.Errorf("version = %s, want the clio version", si.Version())
+	}
+
+	// Admin commands never reach a Clio server.
+	before := calls
+	if _, err := client.Features(context.Background()); err == nil {
+		t.Error("Features succeeded against Clio")
+	}
+	if calls != before {
+		t.Error("Features still called the Clio server")
+	}
+}
+
+func TestServerInfoRippledDefaults(t *testing.T) {
+	si := &ServerInfo{BuildVersion: "2.2.0"}
+	if si.ServerType() != ServerTypeRippled {
+		t.Errorf("server type = %s, want rippled", si.ServerType())
+	}
+	if si.Version() != "2.2.0" {
+		t.Errorf("version = %s, want the build version", si.Version())
+	}
+}
@@ -0,0 +1,62 @@
+package xrpl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// FeeInfo is the fee command's view of the open ledger: fees in drops
+// plus how loaded the server currently is.
+type FeeInfo struct {
+	LedgerIndex        uint64 `json:"ledger_index"`
+	BaseFee            uint64 `json:"base_fee"`             // drops
+	OpenLedgerFee      uint64 `json:"open_ledger_fee"`      // drops
+	ExpectedLedgerSize uint64 `json:"expected_ledger_size"` // transactions
+	// LoadFactor is the open-ledger fee level over the reference level;
+	// 1 means an unloaded server.
+	LoadFactor float64 `json:"load_factor"`
+}
+
+// GetFee polls the fee command. rippled reports most of the numbers as
+// decimal strings; they come back here as integers.
+func (c *Client) GetFee(ctx context.Context) (*FeeInfo, error) {
+	var result struct {
+		CurrentLedgerIndex json.Number `json:"ledger_current_index"`
+		ExpectedLedgerSize string      `json:"expected_ledger_size"`
+		Drops              struct {
+			BaseFee       string `json:"base_fee"`
+			OpenLedgerFee string `json:"open_ledger_fee"`
+		} `json:"drops"`
+		Levels struct {
+			OpenLedgerLevel string `json:"open_ledger_level"`
+			ReferenceLevel  string `json:"reference_level"`
+		} `json:"levels"`
+	}
+	if err := c.Call(ctx, "fee", nil, &result); err != nil {
+		return nil, err
+	}
+	fee := &FeeInfo{LoadFactor: 1}
+	if idx, err := result.CurrentLedgerIndex.Int64(); err == nil {
+		fee.LedgerIndex = uint64(idx)
+	}
+	var err error
+	if fee.BaseFee, err = strconv.ParseUint(result.Drops.BaseFee, 10, 64); err != nil {
+		return nil, fmt.Errorf("xrpl: fee: bad base_fee %q", result.Drops.BaseFee)
+	}
+	if fee.OpenLedgerFee, err = strconv.ParseUint(result.Drops.OpenLedgerFee, 10, 64); err != nil {
+		return nil, fmt.Errorf("xrpl: fee: bad open_ledger_fee %q", result.Drops.OpenLedgerFee)
+	}
+	if result.ExpectedLedgerSize != "" {
+		if fee.ExpectedLedgerSize, err = strconv.ParseUint(result.ExpectedLedgerSize, 10, 64); err != nil {
+			return nil, fmt.Errorf("xrpl: fee: bad expected_ledger_size %q", result.ExpectedLedgerSize)
+		}
+	}
+	open, err1 := strconv.ParseFloat(result.Levels.OpenLedgerLevel, 64)
+	ref, err2 := strconv.ParseFloat(result.Levels.ReferenceLevel, 64)
+	if err1 == nil && err2 == nil && ref > 0 {
+		fee.LoadFactor = open / ref
+	}
+	return fee, nil
+}
@@ -0,0 +1,70 @@
+package xrpl
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetFeeParsesDropsAndLevels(t *testing.T) {
+	srv := cannedServer(t, map[string]func(map[string]any) any{
+		"fee": func(params map[string]any) any {
+			return map[string]any{
+				"status":               "success",
+				"ledger_current_index": 91234567,
+				"expected_ledger_size": "175",
+				"drops": map[string]any{
+					"base_fee":        "10",
+					"open_ledger_fee": "2653937",
+				},
+				"levels": map[string]any{
+					"open_ledger_level": "67890000",
+					"reference_level":   "256",
+				},
+			}
+		},
+	})
+	defer srv.Close()
+
+	fee, err := NewClient(srv.URL).GetFee(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee.LedgerIndex != 91234567 {
+		t.Errorf("LedgerIndex = %d, want 91234567", fee.LedgerIndex)
+	}
+	if fee.BaseFee != 10 {
+		t.Errorf("BaseFee = %d, want 10", fee.BaseFee)
+	}
+	if fee.OpenLedgerFee != 2653937 {
+		t.Errorf("OpenLedgerFee = %d, want 2653937", fee.OpenLedgerFee)
+	}
+	if fee.ExpectedLedgerSize != 175 {
+		t.Errorf("ExpectedLedgerSize = %d, want 175", fee.ExpectedLedgerSize)
+	}
+	if want := 67890000.0 / 256; fee.LoadFactor != want {
+		t.Errorf("LoadFactor = %v, want %v", fee.LoadFactor, want)
+	}
+}
+
+func TestGetFeeDefaultsLoadFactor(t *testing.T) {
+	srv := cannedServer(t, map[string]func(map[string]any) any{
+		"fee": func(params map[string]any) any {
+			return map[string]any{
+				"status": "success",
+				"drops": map[string]any{
+					"base_fee":        "10",
+					"open_ledger_fee": "10",
+				},
+			}
+		},
+	})
+	defer srv.Close()
+
+	fee, err := NewClient(srv.URL).GetFee(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fee.LoadFactor != 1 {
+		t.Errorf("LoadFactor = %v, want 1", fee.LoadFactor)
+	}
+}
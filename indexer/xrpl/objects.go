@@ -0,0 +1,58 @@
+package xrpl
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// AccountObject is one ledger entry owned by an account. Raw keeps the
+// full entry so callers can decode the type-specific fields themselves
+// (OfferFields, RippleStateFields, AMMFields, ...).
+type AccountObject struct {
+	LedgerEntryType string          `json:"LedgerEntryType"`
+	Index           string          `json:"index"`
+	Raw             json.RawMessage `json:"-"`
+}
+
+// GetAccountObjects fetches the validated ledger entries owned by an
+// account via rippled's account_objects command, following markers until
+// the listing is complete. objectType filters server-side ("offer",
+// "state", "amm", ...); empty returns everything. An unknown account is
+// ErrAccountNotFound.
+func (c *Client) GetAccountObjects(ctx context.Context, account, objectType string) ([]AccountObject, error) {
+	var (
+		objects []AccountObject
+		marker  json.RawMessage
+	)
+	for {
+		params := map[string]any{
+			"account":      account,
+			"ledger_index": "validated",
+		}
+		if objectType != "" {
+			params["type"] = objectType
+		}
+		if marker != nil {
+			params["marker"] = marker
+		}
+		var result struct {
+			AccountObjects []json.RawMessage `json:"account_objects"`
+			Marker         json.RawMessage   `json:"marker"`
+		}
+		if err := c.Call(ctx, "account_objects", params, &result); err != nil {
+			return nil, err
+		}
+		for _, raw := range result.AccountObjects {
+			var obj AccountObject
+			if err := json.Unmarshal(raw, &obj); err != nil {
+				return nil, err
+			}
+			obj.Raw = raw
+			objects = append(objects, obj)
+		}
+		if len(result.Marker) == 0 || string(result.Marker) == "null" {
+			return objects, nil
+		}
+		marker = result.Marker
+	}
+}
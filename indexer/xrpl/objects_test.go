@@ -0,0 +1,124 @@
+package xrpl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// cannedServer answers every method with a scripted result object.
+func cannedServer(t *testing.T, results map[string]func(params map[string]any) any) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string           `json:"method"`
+			Params []map[string]any `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		handler, ok := results[req.Method]
+		if !ok {
+			t.Errorf("unexpected method %q", req.Method)
+			handler = func(map[string]any) any {
+				return map[string]any{"status": "error", "error": "unknownCmd"}
+			}
+		}
+		var params map[string]any
+		if len(req.Params) > 0 {
+			params = req.Params[0]
+		}
+		json.NewEncoder(w).Encode(map[string]any{"result": handler(params)})
+	}))
+}
+
+func TestGetAccountObjectsFollowsMarkers(t *testing.T) {
+	pageOne := map[string]any{
+		"status":  "success",
+		"account": "rPoolAcct",
+		"account_objects": []map[string]any{
+			{"LedgerEntryType": "Offer", "index": "AAAA", "Account": "rPoolAcct", "Sequence": 7},
+		},
+		"marker": "page2",
+	}
+	pageTwo := map[string]any{
+		"status":  "success",
+		"account": "rPoolAcct",
+		"account_objects": []map[string]any{
+			{"LedgerEntryType": "RippleState", "index": "BBBB"},
+		},
+	}
+	srv := cannedServer(t, map[string]func(map[string]any) any{
+		"account_objects": func(params map[string]any) any {
+			if params["account"] != "rPoolAcct" {
+				t.Errorf("account = %v", params["account"])
+			}
+			if params["type"] != "offer" {
+				t.Errorf("type = %v", params["type"])
+			}
+			if marker, ok := params["marker"]; ok {
+				if marker != "page2" {
+					t.Errorf("marker = %v, want page2", marker)
+				}
+				return pageTwo
+			}
+			return pageOne
+		},
+	})
+	defer srv.Close()
+
+	objects, err := NewClient(srv.URL).GetAccountObjects(context.Background(), "rPoolAcct", "offer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("got %d objects, want 2", len(objects))
+	}
+	if objects[0].LedgerEntryType != "Offer" || objects[0].Index != "AAAA" {
+		t.Errorf("first object = %+v", objects[0])
+	}
+	var offer struct {
+		Sequence uint32 `json:"Sequence"`
+	}
+	if err := json.Unmarshal(objects[0].Raw, &offer); err != nil {
+		t.Fatal(err)
+	}
+	if offer.Sequence != 7 {
+		t.Errorf("Sequence = %d from raw entry, want 7", offer.Sequence)
+	}
+	if objects[1].LedgerEntryType != "RippleState" {
+		t.Errorf("second object = %+v", objects[1])
+	}
+}
+
+func TestGetAccountObjectsAccountNotFound(t *testing.T) {
+	srv := cannedServer(t, map[string]func(map[string]any) any{
+		"account_objects": func(map[string]any) any {
+			return map[string]any{"status": "error", "error": "actNotFound"}
+		},
+	})
+	defer srv.Close()
+
+	_, err := NewClient(srv.URL).GetAccountObjects(context.Background(), "rGone", "")
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Fatalf("err = %v, want ErrAccountNotFound", err)
+	}
+}
+
+func TestGetAMMInfoNotFound(t *testing.T) {
+	srv := cannedServer(t, map[string]func(map[string]any) any{
+		"amm_info": func(map[string]any) any {
+			return map[string]any{"status": "error", "error": "ammNotFound"}
+		},
+	})
+	defer srv.Close()
+
+	_, err := NewClient(srv.URL).GetAMMInfo(context.Background(),
+		Amount{Currency: "XRP"}, Amount{Currency: "USD", Issuer: "rIssuer"})
+	if !errors.Is(err, ErrAMMNotFound) {
+		t.Fatalf("err = %v, want ErrAMMNotFound", err)
+	}
+}
@@ -0,0 +1,46 @@
+package xrpl
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// QualityFromBookDirectory decodes the exchange quality packed into the low
+// 64 bits of an Offer's BookDirectory index. The encoding is the standard
+// XRPL "quality" format: the top byte is a base-10 exponent biased by 100
+// and the remaining 56 bits are the mantissa, i.e.
+//
+//	quality = mantissa * 10^(exponent-100)
+//
+// The decoded value is TakerPays per TakerGets in each side's wire unit,
+// so callers must still scale by drops for any XRP leg.
+func QualityFromBookDirectory(dir string) (*big.Rat, error) {
+	if len(dir) != 64 {
+		return nil, fmt.Errorf("xrpl: bad BookDirectory length %d", len(dir))
+	}
+	raw, err := strconv.ParseUint(dir[48:], 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("xrpl: bad BookDirectory quality: %w", err)
+	}
+	exponent := int(raw>>56) - 100
+	mantissa := raw & 0x00FFFFFFFFFFFFFF
+	if mantissa == 0 {
+		return nil, fmt.Errorf("xrpl: zero quality in BookDirectory")
+	}
+	q := new(big.Rat).SetUint64(mantissa)
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exponent))), nil)
+	if exponent >= 0 {
+		q.Mul(q, new(big.Rat).SetInt(pow))
+	} else {
+		q.Quo(q, new(big.Rat).SetInt(pow))
+	}
+	return q, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
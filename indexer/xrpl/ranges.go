@@ -0,0 +1,89 @@
+package xrpl
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LedgerRange is an inclusive range of ledger indexes.
+type LedgerRange struct {
+	From uint64
+	To   uint64
+}
+
+func (r LedgerRange) String() string {
+	if r.From == r.To {
+		return strconv.FormatUint(r.From, 10)
+	}
+	return fmt.Sprintf("%d-%d", r.From, r.To)
+}
+
+// LedgerRanges is a sorted, non-overlapping set of ledger ranges.
+type LedgerRanges []LedgerRange
+
+// ParseLedgerRanges parses rippled's complete_ledgers form: comma-
+// separated ranges or single indexes ("32570-61000000,62000000-99000000").
+// A non-full-history server reporting "empty" yields an empty set.
+func ParseLedgerRanges(s string) (LedgerRanges, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "empty" {
+		return nil, nil
+	}
+	var ranges LedgerRanges
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		from, to, found := strings.Cut(part, "-")
+		if !found {
+			to = from
+		}
+		r := LedgerRange{}
+		var err error
+		if r.From, err = strconv.ParseUint(from, 10, 64); err != nil {
+			return nil, fmt.Errorf("xrpl: bad ledger range %q", part)
+		}
+		if r.To, err = strconv.ParseUint(to, 10, 64); err != nil || r.To < r.From {
+			return nil, fmt.Errorf("xrpl: bad ledger range %q", part)
+		}
+		ranges = append(ranges, r)
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].From < ranges[j].From })
+	// Merge overlapping or touching ranges so callers can assume disjoint,
+	// ordered entries.
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.From <= last.To+1 {
+			if r.To > last.To {
+				last.To = r.To
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged, nil
+}
+
+// Contains reports whether the set covers the given ledger.
+func (rs LedgerRanges) Contains(index uint64) bool {
+	for _, r := range rs {
+		if index >= r.From && index <= r.To {
+			return true
+		}
+	}
+	return false
+}
+
+// Earliest returns the lowest ledger in the set, or zero when empty.
+func (rs LedgerRanges) Earliest() uint64 {
+	if len(rs) == 0 {
+		return 0
+	}
+	return rs[0].From
+}
+
+// Ranges parses the server's complete_ledgers report.
+func (si *ServerInfo) Ranges() (LedgerRanges, error) {
+	return ParseLedgerRanges(si.CompleteLedgers)
+}
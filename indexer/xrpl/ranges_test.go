@@ -0,0 +1,51 @@
+package xrpl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLedgerRanges(t *testing.T) {
+	cases := []struct {
+		in   string
+		want LedgerRanges
+	}{
+		{"empty", nil},
+		{"", nil},
+		{"32570-61000000", LedgerRanges{{32570, 61000000}}},
+		{"32570-61000000,62000000-99000000", LedgerRanges{{32570, 61000000}, {62000000, 99000000}}},
+		{"5,7-9", LedgerRanges{{5, 5}, {7, 9}}},
+		// Out of order and touching ranges come back sorted and merged.
+		{"20-30,5-10,11-15", LedgerRanges{{5, 15}, {20, 30}}},
+	}
+	for _, c := range cases {
+		got, err := ParseLedgerRanges(c.in)
+		if err != nil {
+			t.Errorf("ParseLedgerRanges(%q): %v", c.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ParseLedgerRanges(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+	for _, bad := range []string{"x", "9-5", "1-2-3"} {
+		if _, err := ParseLedgerRanges(bad); err == nil {
+			t.Errorf("ParseLedgerRanges(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestLedgerRangesContains(t *testing.T) {
+	rs := LedgerRanges{{10, 20}, {30, 30}}
+	for index, want := range map[uint64]bool{9: false, 10: true, 20: true, 25: false, 30: true, 31: false} {
+		if got := rs.Contains(index); got != want {
+			t.Errorf("Contains(%d) = %v, want %v", index, got, want)
+		}
+	}
+	if rs.Earliest() != 10 {
+		t.Errorf("Earliest = %d", rs.Earliest())
+	}
+	if (LedgerRanges)(nil).Earliest() != 0 {
+		t.Error("Earliest on empty set should be 0")
+	}
+}
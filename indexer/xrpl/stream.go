@@ -0,0 +1,377 @@
+package xrpl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/internal/ws"
+)
+
+// LedgerClosed is a message from the "ledger" stream announcing a newly
+// validated ledger.
+type LedgerClosed struct {
+	Index     uint64 `json:"ledger_index"`
+	Hash      string `json:"ledger_hash"`
+	CloseTime int64  `json:"ledger_time"`
+	TxnCount  int    `json:"txn_count"`
+}
+
+// TxMessage is a validated transaction from the "transactions" stream,
+// already carrying its metadata.
+type TxMessage struct {
+	Tx          *Transaction
+	LedgerIndex uint64
+	Validated   bool
+}
+
+// ValidationMessage is one vote from the "validations" stream: a
+// validator asserting a ledger hash. PublicKey is the signing key the
+// vote arrived under; MasterKey is the validator's stable identity when
+// rippled has the manifest to resolve it, empty otherwise. A vote with
+// Full false is a partial validation and asserts nothing about the hash.
+type ValidationMessage struct {
+	PublicKey   string
+	MasterKey   string
+	LedgerHash  string
+	LedgerIndex uint64
+	// SigningTime is in seconds since the Ripple epoch.
+	SigningTime int64
+	Full        bool
+}
+
+// StreamMessage is one message from a rippled subscription; exactly one
+// field is set.
+type StreamMessage struct {
+	LedgerClosed *LedgerClosed
+	Transaction  *TxMessage
+	Validation   *ValidationMessage
+}
+
+// DefaultMaxInflight bounds how many commands may be outstanding on one
+// WebSocket connection at a time.
+const DefaultMaxInflight = 8
+
+// DefaultStreamBuffer is how many stream messages the subscription buffers
+// between the read loop and Next before backpressure sets in.
+const DefaultStreamBuffer = 64
+
+// Subscriber holds a WebSocket connection to rippled. Besides the stream
+// subscription it multiplexes request/response commands: outgoing commands
+// get incrementing ids, a single read loop routes each response to the
+// waiter that sent it, and stream messages (which carry no id) flow to
+// Next untouched. Any number of goroutines may issue Calls concurrently.
+//
+// Backpressure policy: when the stream buffer fills, the read loop blocks
+// until Next drains a slot — a message is never dropped. Blocking the
+// read loop also stalls command responses on the same connection, so
+// while subscribed to streams some goroutine must keep draining Next.
+// StreamBacklog and StreamHighWater expose how full the buffer gets, for
+// tuning its size against the workload.
+type Subscriber struct {
+	conn     *ws.Conn
+	stream   chan *StreamMessage
+	done     chan struct{} // closed when the read loop exits
+	closing  chan struct{} // closed by Close
+	inflight chan struct{} // semaphore over outstanding commands
+
+	mu        sync.Mutex
+	nextID    uint64
+	pending   map[uint64]chan json.RawMessage
+	readErr   error
+	highWater int
+}
+
+// DialSubscriber connects to rippled's WebSocket endpoint and subscribes
+// to the given streams (e.g. "ledger", "transactions"). An empty stream
+// list opens a request/response-only connection.
+func DialSubscriber(wsURL string, streams []string) (*Subscriber, error) {
+	return DialSubscriberBuffered(wsURL, streams, DefaultStreamBuffer)
+}
+
+// DialSubscriberBuffered is DialSubscriber with an explicit stream buffer
+// size; a non-positive buffer falls back to DefaultStreamBuffer. Larger
+// buffers absorb longer processing stalls before the read loop blocks.
+func DialSubscriberBuffered(wsURL string, streams []string, buffer int) (*Subscriber, error) {
+	if buffer < 1 {
+		buffer = DefaultStreamBuffer
+	}
+	conn, err := ws.Dial(wsURL)
+	if err != nil {
+		return nil, err
+	}
+	sub := &Subscriber{
+		conn:     conn,
+		stream:   make(chan *StreamMessage, buffer),
+		done:     make(chan struct{}),
+		closing:  make(chan struct{}),
+		inflight: make(chan struct{}, DefaultMaxInflight),
+		pending:  make(map[uint64]chan json.RawMessage),
+	}
+	go sub.readLoop()
+	if len(streams) > 0 {
+		if _, err := sub.Call(context.Background(), "subscribe", map[string]any{
+			"streams": streams,
+		}); err != nil {
+			sub.Close()
+			return nil, fmt.Errorf("xrpl: subscribe: %w", err)
+		}
+	}
+	return sub, nil
+}
+
+// SetMaxInflight resizes the outstanding-command limit. Call it before
+// issuing concurrent requests; it does not resize under load.
+func (s *Subscriber) SetMaxInflight(n int) {
+	if n < 1 {
+		n = 1
+	}
+	s.inflight = make(chan struct{}, n)
+}
+
+// Close tears down the connection, failing outstanding Calls.
+func (s *Subscriber) Close() error {
+	select {
+	case <-s.closing:
+	default:
+		close(s.closing)
+	}
+	return s.conn.Close()
+}
+
+// Call sends one command over the connection and waits for its response,
+// returning the result object. It blocks while the connection already has
+// the maximum number of commands outstanding.
+func (s *Subscriber) Call(ctx context.Context, command string, params map[string]any) (json.RawMessage, error) {
+	select {
+	case s.inflight <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.done:
+		return nil, s.err()
+	}
+	defer func() { <-s.inflight }()
+
+	msg := make(map[string]any, len(params)+2)
+	for k, v := range params {
+		msg[k] = v
+	}
+	s.mu.Lock()
+	s.nextID++
+	id := s.nextID
+	ch := make(chan json.RawMessage, 1)
+	s.pending[id] = ch
+	s.mu.Unlock()
+	msg["id"] = id
+	msg["command"] = command
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		s.forget(id)
+		return nil, err
+	}
+	if err := s.conn.WriteMessage(payload); err != nil {
+		s.forget(id)
+		return nil, fmt.Errorf("xrpl: %s: %w", command, err)
+	}
+
+	var raw json.RawMessage
+	select {
+	case raw = <-ch:
+	case <-ctx.Done():
+		s.forget(id)
+		return nil, ctx.Err()
+	case <-s.done:
+		return nil, s.err()
+	}
+	var envelope struct {
+		Status string          `json:"status"`
+		Error  string          `json:"error"`
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return nil, fmt.Errorf("xrpl: %s: decoding response: %w", command, err)
+	}
+	if envelope.Status != "success" {
+		return nil, fmt.Errorf("xrpl: %s: rippled error: %s", command, envelope.Error)
+	}
+	return envelope.Result, nil
+}
+
+// Next blocks for the next stream message.
+func (s *Subscriber) Next() (*StreamMessage, error) {
+	msg, ok := <-s.stream
+	if !ok {
+		return nil, s.err()
+	}
+	return msg, nil
+}
+
+// readLoop is the single reader: responses go to their waiters by id,
+// everything else is decoded as a stream message.
+func (s *Subscriber) readLoop() {
+	for {
+		raw, err := s.conn.ReadMessage()
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		var head struct {
+			ID *uint64 `json:"id"`
+		}
+		if err := json.Unmarshal(raw, &head); err != nil {
+			s.fail(fmt.Errorf("xrpl: decoding message: %w", err))
+			return
+		}
+		if head.ID != nil {
+			s.mu.Lock()
+			ch := s.pending[*head.ID]
+			delete(s.pending, *head.ID)
+			s.mu.Unlock()
+			if ch != nil {
+				ch <- raw
+			}
+			continue
+		}
+		msg, err := decodeStreamMessage(raw)
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		if msg == nil {
+			continue
+		}
+		// Block-don't-drop: a full buffer stalls this loop until Next
+		// frees a slot, so no stream message is ever lost.
+		select {
+		case s.stream <- msg:
+			s.mu.Lock()
+			if n := len(s.stream); n > s.highWater {
+				s.highWater = n
+			}
+			s.mu.Unlock()
+		case <-s.closing:
+			s.fail(ws.ErrClosed)
+			return
+		}
+	}
+}
+
+// StreamBacklog reports how many stream messages are waiting for Next and
+// the buffer's capacity.
+func (s *Subscriber) StreamBacklog() (occupied, capacity int) {
+	return len(s.stream), cap(s.stream)
+}
+
+// StreamHighWater reports the most messages that have ever waited in the
+// buffer at once. A high-water mark hugging the capacity means the read
+// loop has been blocking and the buffer (or the consumer) is undersized.
+func (s *Subscriber) StreamHighWater() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.highWater
+}
+
+// fail records the terminal error and unblocks everyone.
+func (s *Subscriber) fail(err error) {
+	s.mu.Lock()
+	if s.readErr == nil {
+		s.readErr = err
+	}
+	s.pending = map[uint64]chan json.RawMessage{}
+	s.mu.Unlock()
+	close(s.done)
+	close(s.stream)
+}
+
+func (s *Subscriber) err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readErr
+}
+
+func (s *Subscriber) forget(id uint64) {
+	s.mu.Lock()
+	delete(s.pending, id)
+	s.mu.Unlock()
+}
+
+func decodeStreamMessage(raw []byte) (*StreamMessage, error) {
+	// ledger_index is numeric on the transaction stream but a quoted
+	// string on validations; json.Number swallows both.
+	var envelope struct {
+		Type        string          `json:"type"`
+		Transaction json.RawMessage `json:"transaction"`
+		Meta        json.RawMessage `json:"meta"`
+		LedgerIndex json.Number     `json:"ledger_index"`
+		Validated   bool            `json:"validated"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return nil, fmt.Errorf("xrpl: decoding stream message: %w", err)
+	}
+	switch envelope.Type {
+	case "ledgerClosed":
+		var lc LedgerClosed
+		if err := json.Unmarshal(raw, &lc); err != nil {
+			return nil, fmt.Errorf("xrpl: decoding ledgerClosed: %w", err)
+		}
+		return &StreamMessage{LedgerClosed: &lc}, nil
+	case "transaction":
+		var tx Transaction
+		if err := json.Unmarshal(envelope.Transaction, &tx); err != nil {
+			return nil, fmt.Errorf("xrpl: decoding stream transaction: %w", err)
+		}
+		if len(envelope.Meta) > 0 {
+			var meta Meta
+			if err := json.Unmarshal(envelope.Meta, &meta); err != nil {
+				return nil, fmt.Errorf("xrpl: decoding stream transaction meta: %w", err)
+			}
+			tx.Meta = &meta
+			tx.RawMeta = envelope.Meta
+		}
+		tx.Raw = envelope.Transaction
+		index, err := parseLedgerIndex(envelope.LedgerIndex)
+		if err != nil {
+			return nil, fmt.Errorf("xrpl: decoding stream transaction ledger_index: %w", err)
+		}
+		return &StreamMessage{Transaction: &TxMessage{
+			Tx:          &tx,
+			LedgerIndex: index,
+			Validated:   envelope.Validated,
+		}}, nil
+	case "validationReceived":
+		var v struct {
+			PublicKey   string `json:"validation_public_key"`
+			MasterKey   string `json:"master_key"`
+			LedgerHash  string `json:"ledger_hash"`
+			SigningTime int64  `json:"signing_time"`
+			Full        bool   `json:"full"`
+		}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, fmt.Errorf("xrpl: decoding validation: %w", err)
+		}
+		index, err := parseLedgerIndex(envelope.LedgerIndex)
+		if err != nil {
+			return nil, fmt.Errorf("xrpl: decoding validation ledger_index: %w", err)
+		}
+		return &StreamMessage{Validation: &ValidationMessage{
+			PublicKey:   v.PublicKey,
+			MasterKey:   v.MasterKey,
+			LedgerHash:  v.LedgerHash,
+			LedgerIndex: index,
+			SigningTime: v.SigningTime,
+			Full:        v.Full,
+		}}, nil
+	}
+	return nil, nil
+}
+
+// parseLedgerIndex reads a json.Number that may be absent or quoted.
+func parseLedgerIndex(n json.Number) (uint64, error) {
+	if n == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(n.String(), 10, 64)
+}
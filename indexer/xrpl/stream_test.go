@@ -0,0 +1,239 @@
+package xrpl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/internal/ws"
+)
+
+// mockRippledWS answers ledger commands with the requested index echoed
+// back and acks subscribe requests; responses are written from separate
+// goroutines so they interleave arbitrarily.
+func mockRippledWS(t *testing.T) (*httptest.Server, func(msg string)) {
+	t.Helper()
+	var (
+		mu    sync.Mutex
+		conns []*ws.Conn
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := ws.Upgrade(w, r)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		mu.Lock()
+		conns = append(conns, conn)
+		mu.Unlock()
+		for {
+			raw, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req struct {
+				ID          uint64 `json:"id"`
+				Command     string `json:"command"`
+				LedgerIndex uint64 `json:"ledger_index"`
+			}
+			if err := json.Unmarshal(raw, &req); err != nil {
+				t.Error(err)
+				return
+			}
+			go func() {
+				var resp string
+				switch req.Command {
+				case "subscribe":
+					resp = fmt.Sprintf(`{"id":%d,"status":"success","type":"response","result":{}}`, req.ID)
+				case "ledger":
+					resp = fmt.Sprintf(`{"id":%d,"status":"success","type":"response","result":{"ledger_index":%d}}`,
+						req.ID, req.LedgerIndex)
+				default:
+					resp = fmt.Sprintf(`{"id":%d,"status":"error","error":"unknownCmd"}`, req.ID)
+				}
+				if err := conn.WriteMessage([]byte(resp)); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+	}))
+	t.Cleanup(srv.Close)
+	push := func(msg string) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			if err := conn.WriteMessage([]byte(msg)); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+	return srv, push
+}
+
+func TestConcurrentCallsOverOneConnection(t *testing.T) {
+	srv, _ := mockRippledWS(t)
+	sub, err := DialSubscriber("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	sub.SetMaxInflight(16)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(index uint64) {
+			defer wg.Done()
+			raw, err := sub.Call(ctx, "ledger", map[string]any{"ledger_index": index})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			var result struct {
+				LedgerIndex uint64 `json:"ledger_index"`
+			}
+			if err := json.Unmarshal(raw, &result); err != nil {
+				t.Error(err)
+				return
+			}
+			if result.LedgerIndex != index {
+				t.Errorf("response for %d routed to caller %d", result.LedgerIndex, index)
+			}
+		}(uint64(i) + 1)
+	}
+	wg.Wait()
+}
+
+func TestStreamMessagesFlowDuringCalls(t *testing.T) {
+	srv, push := mockRippledWS(t)
+	sub, err := DialSubscriber("ws"+strings.TrimPrefix(srv.URL, "http"), []string{"ledger"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := sub.Call(ctx, "ledger", map[string]any{"ledger_index": 7}); err != nil {
+		t.Fatal(err)
+	}
+	push(`{"type":"ledgerClosed","ledger_index":90000030,"ledger_hash":"AB","ledger_time":700000000,"txn_count":2}`)
+	msg, err := sub.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.LedgerClosed == nil || msg.LedgerClosed.Index != 90000030 {
+		t.Fatalf("stream message = %+v, want ledgerClosed 90000030", msg)
+	}
+}
+
+func TestFullStreamBufferBlocksWithoutLoss(t *testing.T) {
+	srv, push := mockRippledWS(t)
+	sub, err := DialSubscriberBuffered("ws"+strings.TrimPrefix(srv.URL, "http"), []string{"ledger"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	// Push far more messages than the buffer holds without draining. The
+	// policy is block-don't-drop: the read loop must wedge against the
+	// full buffer and every message must still come out, in order.
+	const n = 10
+	for i := 1; i <= n; i++ {
+		push(fmt.Sprintf(`{"type":"ledgerClosed","ledger_index":%d,"ledger_hash":"AB","ledger_time":1}`, 90000100+i))
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if occupied, capacity := sub.StreamBacklog(); occupied == capacity {
+			break
+		}
+		if time.Now().After(deadline) {
+			occupied, capacity := sub.StreamBacklog()
+			t.Fatalf("buffer never filled: %d/%d", occupied, capacity)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for i := 1; i <= n; i++ {
+		msg, err := sub.Next()
+		if err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		if msg.LedgerClosed == nil || msg.LedgerClosed.Index != uint64(90000100+i) {
+			t.Fatalf("message %d = %+v, want ledgerClosed %d", i, msg, 90000100+i)
+		}
+	}
+	if hw := sub.StreamHighWater(); hw != 2 {
+		t.Errorf("high water = %d, want the buffer capacity 2", hw)
+	}
+}
+
+func TestCallAfterCloseFailsFast(t *testing.T) {
+	srv, _ := mockRippledWS(t)
+	sub, err := DialSubscriber("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub.Close()
+	<-sub.done
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := sub.Call(ctx, "ledger", map[string]any{"ledger_index": 1}); err == nil {
+		t.Fatal("Call succeeded on a closed connection")
+	}
+}
+
+func TestDecodeValidationMessage(t *testing.T) {
+	// ledger_index arrives quoted on this stream, unlike ledgerClosed.
+	msg, err := decodeStreamMessage([]byte(`{
+		"type": "validationReceived",
+		"validation_public_key": "n9KSigning",
+		"master_key": "nHMaster",
+		"ledger_hash": "VALHASH",
+		"ledger_index": "90000700",
+		"signing_time": 700007000,
+		"full": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := msg.Validation
+	if v == nil {
+		t.Fatal("no validation decoded")
+	}
+	if v.PublicKey != "n9KSigning" || v.MasterKey != "nHMaster" {
+		t.Errorf("keys = %s/%s", v.PublicKey, v.MasterKey)
+	}
+	if v.LedgerHash != "VALHASH" || v.LedgerIndex != 90000700 || v.SigningTime != 700007000 || !v.Full {
+		t.Errorf("vote = %+v", v)
+	}
+
+	// A vote without a resolved master key still decodes.
+	msg, err = decodeStreamMessage([]byte(`{
+		"type": "validationReceived",
+		"validation_public_key": "n9KSigning",
+		"ledger_hash": "VALHASH",
+		"ledger_index": "90000701",
+		"signing_time": 700007010}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Validation.MasterKey != "" || msg.Validation.Full {
+		t.Errorf("defaults = %+v", msg.Validation)
+	}
+
+	if _, err := decodeStreamMessage([]byte(`{
+		"type": "validationReceived",
+		"ledger_index": "not-a-number"}`)); err == nil {
+		t.Error("bad ledger_index decoded without error")
+	}
+}
@@ -0,0 +1,50 @@
+package xrpl
+
+import "time"
+
+// RippleEpochOffset is the gap in seconds between the Unix epoch and the
+// Ripple epoch (2000-01-01T00:00:00Z), which every on-ledger close time
+// counts from. Always convert through the helpers below; an off-by-offset
+// slip here corrupts every timestamp the indexer stores.
+const RippleEpochOffset int64 = 946684800
+
+// RippleTimeToUnix converts seconds since the Ripple epoch to a UTC
+// time.Time.
+func RippleTimeToUnix(closeTime int64) time.Time {
+	return time.Unix(closeTime+RippleEpochOffset, 0).UTC()
+}
+
+// UnixToRippleTime converts a time.Time to seconds since the Ripple
+// epoch; times before the epoch come out negative.
+func UnixToRippleTime(t time.Time) int64 {
+	return t.Unix() - RippleEpochOffset
+}
+
+// CloseTimeHuman renders a close time as RFC 3339 in UTC, the form used
+// wherever reports show ledger times to people.
+func CloseTimeHuman(closeTime int64) string {
+	return RippleTimeToUnix(closeTime).Format(time.RFC3339)
+}
+
+// MaxCloseTimeSkew is how far ahead of wall-clock time a close time may
+// sit before it counts as an anomaly. Validators agree on close times
+// within seconds; minutes of skew mean a bad decode or a bad local clock.
+const MaxCloseTimeSkew = 5 * time.Minute
+
+// CloseTimeAnomaly classifies a close time that cannot be right: one
+// before the Ripple epoch (a decoding bug — the field was read from the
+// wrong offset), one further than MaxCloseTimeSkew past now, or one
+// earlier than its predecessor's. A zero prevCloseTime means no
+// predecessor is known. The empty string means the close time is
+// plausible.
+func CloseTimeAnomaly(closeTime, prevCloseTime int64, now time.Time) string {
+	switch {
+	case closeTime < 0:
+		return "pre_epoch"
+	case RippleTimeToUnix(closeTime).After(now.Add(MaxCloseTimeSkew)):
+		return "future"
+	case prevCloseTime > 0 && closeTime < prevCloseTime:
+		return "backwards"
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package xrpl
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRippleTimeRoundTrip(t *testing.T) {
+	for _, closeTime := range []int64{0, 1, 700000000, 799654321} {
+		if got := UnixToRippleTime(RippleTimeToUnix(closeTime)); got != closeTime {
+			t.Errorf("round trip of %d = %d", closeTime, got)
+		}
+	}
+	now := time.Now().Truncate(time.Second)
+	if got := RippleTimeToUnix(UnixToRippleTime(now)); !got.Equal(now) {
+		t.Errorf("round trip of %v = %v", now, got)
+	}
+}
+
+func TestRippleEpochBoundary(t *testing.T) {
+	epoch := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if got := RippleTimeToUnix(0); !got.Equal(epoch) {
+		t.Errorf("ripple time 0 = %v, want %v", got, epoch)
+	}
+	if got := UnixToRippleTime(epoch); got != 0 {
+		t.Errorf("epoch converts to %d, want 0", got)
+	}
+}
+
+func TestCloseTimeAnomaly(t *testing.T) {
+	now := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+	nowRipple := UnixToRippleTime(now)
+	cases := []struct {
+		name            string
+		closeTime, prev int64
+		want            string
+	}{
+		{"plausible", nowRipple, nowRipple - 4, ""},
+		{"no predecessor", nowRipple, 0, ""},
+		{"epoch boundary", 0, 0, ""},
+		{"pre-2000 decode bug", -100, 0, "pre_epoch"},
+		{"within skew", nowRipple + 60, nowRipple - 4, ""},
+		{"far future", nowRipple + 600, nowRipple - 4, "future"},
+		{"backwards", nowRipple - 10, nowRipple - 4, "backwards"},
+	}
+	for _, c := range cases {
+		if got := CloseTimeAnomaly(c.closeTime, c.prev, now); got != c.want {
+			t.Errorf("%s: anomaly = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestCloseTimeHumanUTC(t *testing.T) {
+	if got := CloseTimeHuman(0); got != "2000-01-01T00:00:00Z" {
+		t.Errorf("CloseTimeHuman(0) = %q", got)
+	}
+	if got := CloseTimeHuman(700000000); got != "2022-03-07T20:26:40Z" {
+		t.Errorf("CloseTimeHuman(700000000) = %q", got)
+	}
+}
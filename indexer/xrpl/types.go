@@ -0,0 +1,242 @@
+package xrpl
+
+import "encoding/json"
+
+// Transaction flag bits shared by the parsers.
+const (
+	// TfSell marks an OfferCreate where the owner wants to sell the full
+	// TakerGets amount even if the market lets them do better than the
+	// stated quality.
+	TfSell = 0x00080000
+
+	// TfImmediateOrCancel and TfFillOrKill are execution-only offers that
+	// never rest on the book.
+	TfImmediateOrCancel = 0x00020000
+	TfFillOrKill        = 0x00040000
+
+	// TfPassive offers do not consume offers that exactly match.
+	TfPassive = 0x00010000
+
+	// TfSellNFToken marks an NFTokenCreateOffer as a sell offer: the
+	// owner offers the token for the Amount rather than bidding it.
+	TfSellNFToken = 0x00000001
+
+	// LsfSellNFToken is the same bit on the resulting NFTokenOffer
+	// ledger entry.
+	LsfSellNFToken = 0x00000001
+
+	// RippleState entry flags, per side: the low account is the
+	// lexicographically smaller of the two addresses.
+	LsfLowNoRipple  = 0x00100000
+	LsfHighNoRipple = 0x00200000
+	LsfLowFreeze    = 0x00400000
+	LsfHighFreeze   = 0x00800000
+)
+
+// Transaction is the decoded JSON form of a validated transaction, carrying
+// only the fields the indexer cares about plus the raw message for anything
+// a specific parser wants to pull out itself.
+type Transaction struct {
+	Hash            string `json:"hash"`
+	TransactionType string `json:"TransactionType"`
+	Account         string `json:"Account"`
+	Sequence        uint32 `json:"Sequence"`
+	Flags           uint32 `json:"Flags"`
+
+	// OfferCreate / OfferCancel fields.
+	TakerGets     Amount `json:"TakerGets"`
+	TakerPays     Amount `json:"TakerPays"`
+	OfferSequence uint32 `json:"OfferSequence"`
+
+	// Payment / Clawback amount. For Clawback the issuer field of the
+	// amount names the holder being clawed from. AMMCreate reuses Amount
+	// for its first deposit, with Amount2 as the second.
+	Amount  Amount `json:"Amount"`
+	Amount2 Amount `json:"Amount2"`
+
+	// AccountSet flag numbers. Domain is the account's new domain in
+	// hex; nil when the transaction leaves the domain unchanged, and
+	// empty when it clears it.
+	SetFlag   uint32  `json:"SetFlag"`
+	ClearFlag uint32  `json:"ClearFlag"`
+	Domain    *string `json:"Domain"`
+
+	// Amendment is the amendment id of an EnableAmendment
+	// pseudo-transaction.
+	Amendment string `json:"Amendment"`
+
+	// NFTokenCreateOffer fields. Owner names the token's holder on a buy
+	// offer; Destination restricts who may accept; Expiration is Ripple
+	// epoch seconds, zero for never.
+	NFTokenID   string `json:"NFTokenID"`
+	Owner       string `json:"Owner"`
+	Destination string `json:"Destination"`
+	Expiration  uint32 `json:"Expiration"`
+
+	// NFTokenAcceptOffer fields: the offer entries being accepted, by
+	// ledger index. A brokered accept sets both plus the broker's cut.
+	NFTokenSellOffer string `json:"NFTokenSellOffer"`
+	NFTokenBuyOffer  string `json:"NFTokenBuyOffer"`
+	NFTokenBrokerFee Amount `json:"NFTokenBrokerFee"`
+
+	// NFTokenOffers lists the offers an NFTokenCancelOffer removes.
+	NFTokenOffers []string `json:"NFTokenOffers"`
+
+	// LimitAmount is a TrustSet's requested trust line limit; its issuer
+	// field names the account being trusted.
+	LimitAmount Amount `json:"LimitAmount"`
+
+	Meta *Meta `json:"metaData"`
+
+	// Raw is the full transaction JSON as received from rippled, and
+	// RawMeta the metadata JSON when it arrived separately. Keeping both
+	// lets the store persist transactions without re-encoding them.
+	Raw     json.RawMessage `json:"-"`
+	RawMeta json.RawMessage `json:"-"`
+}
+
+// Meta is transaction metadata: the authoritative record of what a
+// transaction actually did to the ledger.
+type Meta struct {
+	AffectedNodes     []AffectedNode `json:"AffectedNodes"`
+	TransactionIndex  uint32         `json:"TransactionIndex"`
+	TransactionResult string         `json:"TransactionResult"`
+
+	// DeliveredAmount is what a Payment actually delivered, which can be
+	// less than Amount for partial payments. Zero value when absent.
+	DeliveredAmount Amount `json:"delivered_amount"`
+}
+
+// AffectedNode wraps exactly one of the three node change shapes.
+type AffectedNode struct {
+	Created  *NodeData `json:"CreatedNode,omitempty"`
+	Modified *NodeData `json:"ModifiedNode,omitempty"`
+	Deleted  *NodeData `json:"DeletedNode,omitempty"`
+}
+
+// Node returns whichever of the three variants is set, along with its kind.
+func (n AffectedNode) Node() (*NodeData, NodeKind) {
+	switch {
+	case n.Created != nil:
+		return n.Created, NodeCreated
+	case n.Modified != nil:
+		return n.Modified, NodeModified
+	case n.Deleted != nil:
+		return n.Deleted, NodeDeleted
+	}
+	return nil, NodeKind(0)
+}
+
+// NodeKind says how a ledger entry was affected.
+type NodeKind int
+
+const (
+	NodeCreated NodeKind = iota + 1
+	NodeModified
+	NodeDeleted
+)
+
+// NodeData is the common shape of Created/Modified/Deleted node entries.
+// Field sets are kept raw because their layout depends on LedgerEntryType.
+type NodeData struct {
+	LedgerEntryType string          `json:"LedgerEntryType"`
+	LedgerIndex     string          `json:"LedgerIndex"`
+	NewFields       json.RawMessage `json:"NewFields,omitempty"`
+	FinalFields     json.RawMessage `json:"FinalFields,omitempty"`
+	PreviousFields  json.RawMessage `json:"PreviousFields,omitempty"`
+}
+
+// OfferFields are the fields of an Offer ledger entry that the indexer
+// reads out of NewFields / FinalFields / PreviousFields.
+type OfferFields struct {
+	Account       string `json:"Account"`
+	Sequence      uint32 `json:"Sequence"`
+	TakerGets     Amount `json:"TakerGets"`
+	TakerPays     Amount `json:"TakerPays"`
+	BookDirectory string `json:"BookDirectory"`
+	Flags         uint32 `json:"Flags"`
+}
+
+// NFTokenOfferFields are the fields of an NFTokenOffer ledger entry that
+// the indexer reads out of NewFields / FinalFields.
+type NFTokenOfferFields struct {
+	Owner       string `json:"Owner"`
+	NFTokenID   string `json:"NFTokenID"`
+	Amount      Amount `json:"Amount"`
+	Destination string `json:"Destination"`
+	Expiration  uint32 `json:"Expiration"`
+	Flags       uint32 `json:"Flags"`
+}
+
+// ServerInfo is the subset of a server_info response the indexer reads.
+type ServerInfo struct {
+	BuildVersion    string `json:"build_version"`
+	CompleteLedgers string `json:"complete_ledgers"`
+	NetworkID       uint32 `json:"network_id"`
+	ValidatedLedger struct {
+		Seq uint64 `json:"seq"`
+	} `json:"validated_ledger"`
+
+	// ClioVersion is only present when the server is a Clio history
+	// server rather than rippled; Clio otherwise mirrors the rippled
+	// server_info shape closely enough to share this struct.
+	ClioVersion string `json:"clio_version"`
+}
+
+// Server kinds reported by ServerType.
+const (
+	ServerTypeRippled = "rippled"
+	ServerTypeClio    = "clio"
+)
+
+// ServerType reports which kind of server answered server_info.
+func (si *ServerInfo) ServerType() string {
+	if si.ClioVersion != "" {
+		return ServerTypeClio
+	}
+	return ServerTypeRippled
+}
+
+// Version is the server's own version: clio_version on Clio,
+// build_version on rippled.
+func (si *ServerInfo) Version() string {
+	if si.ClioVersion != "" {
+		return si.ClioVersion
+	}
+	return si.BuildVersion
+}
+
+// AMMFields are the fields of an AMM ledger entry the indexer reads.
+type AMMFields struct {
+	Account    string `json:"Account"`
+	TradingFee uint32 `json:"TradingFee"`
+
+	// LPTokenBalance carries the LP token's currency code, issued by the
+	// AMM account; on a fresh AMM entry its value is the initial supply.
+	LPTokenBalance Amount `json:"LPTokenBalance"`
+}
+
+// RippleStateFields are the trust-line fields the indexer reads from
+// RippleState nodes. Balance is from the low account's perspective; the
+// two parties are named by the limits' issuer fields.
+type RippleStateFields struct {
+	Balance   Amount `json:"Balance"`
+	LowLimit  Amount `json:"LowLimit"`
+	HighLimit Amount `json:"HighLimit"`
+	Flags     uint32 `json:"Flags"`
+}
+
+// Ledger is the header of a validated ledger.
+type Ledger struct {
+	Index     uint64 `json:"ledger_index"`
+	Hash      string `json:"ledger_hash"`
+	CloseTime int64  `json:"close_time"` // seconds since the Ripple epoch
+
+	// TxnCount is how many transactions the source said this ledger
+	// holds: txn_count from the ledgerClosed header on the stream, the
+	// transaction list length on a fetch. The pipeline reconciles it
+	// against len(Transactions); zero means empty or unstated.
+	TxnCount int `json:"-"`
+
+	Transactions []*Transaction `json:"-"`
+}
@@ -0,0 +1,17 @@
+package xrpl
+
+import "context"
+
+// TrustedValidators returns the master keys of the validators the
+// connected rippled currently trusts — its effective UNL. The
+// "validators" command is admin-only, so this works against an
+// operator's own node, not a public cluster.
+func (c *Client) TrustedValidators(ctx context.Context) ([]string, error) {
+	var result struct {
+		TrustedValidatorKeys []string `json:"trusted_validator_keys"`
+	}
+	if err := c.Call(ctx, "validators", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.TrustedValidatorKeys, nil
+}
@@ -0,0 +1,305 @@
+// Package xrpltest provides a scriptable stand-in for a rippled server,
+// speaking enough of the protocol for integration tests: subscribe acks,
+// ledgerClosed pushes, ledger/tx/server_info responses from fixtures, and
+// injectable disconnects and error responses. The same handlers answer
+// both transports rippled exposes — the WebSocket endpoint the subscriber
+// dials and the JSON-RPC HTTP endpoint the client posts to — so one
+// scripted server can back a whole pipeline run.
+package xrpltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/internal/ws"
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+// Request is a decoded command request; handlers read parameters from it.
+type Request map[string]any
+
+// Uint64 reads a numeric parameter, tolerating the float64 that generic
+// JSON decoding produces.
+func (r Request) Uint64(key string) uint64 {
+	switch v := r[key].(type) {
+	case float64:
+		return uint64(v)
+	case json.Number:
+		n, _ := v.Int64()
+		return uint64(n)
+	}
+	return 0
+}
+
+// Handler answers one command. Return a non-empty errCode to script an
+// error response instead of a result.
+type Handler func(req Request) (result any, errCode string)
+
+// Server is the mock rippled endpoint.
+type Server struct {
+	httpSrv *httptest.Server
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	conns    []*ws.Conn
+}
+
+// NewServer starts a mock server with subscribe already acked; script
+// further commands with Handle and its helpers, then Close when done.
+func NewServer() *Server {
+	s := &Server{handlers: map[string]Handler{}}
+	s.Handle("subscribe", func(Request) (any, string) { return map[string]any{}, "" })
+	s.httpSrv = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Close shuts the server down, dropping any live connections.
+func (s *Server) Close() {
+	s.Disconnect()
+	s.httpSrv.Close()
+}
+
+// URL is the HTTP JSON-RPC endpoint; WSURL the WebSocket one.
+func (s *Server) URL() string   { return s.httpSrv.URL }
+func (s *Server) WSURL() string { return "ws" + strings.TrimPrefix(s.httpSrv.URL, "http") }
+
+// Handle scripts the response for a command on both transports.
+func (s *Server) Handle(command string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[command] = h
+}
+
+// HandleResult scripts a fixed successful result for a command.
+func (s *Server) HandleResult(command string, result any) {
+	s.Handle(command, func(Request) (any, string) { return result, "" })
+}
+
+// HandleError scripts a rippled error response for a command.
+func (s *Server) HandleError(command, code string) {
+	s.Handle(command, func(Request) (any, string) { return nil, code })
+}
+
+// HandleFixture scripts a command's result from a JSON fixture file.
+func (s *Server) HandleFixture(command, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("xrpltest: fixture for %s: %w", command, err)
+	}
+	s.HandleResult(command, json.RawMessage(data))
+	return nil
+}
+
+// ServeLedgers scripts the ledger, tx and server_info commands from a set
+// of fixture ledgers, answering like a server whose complete history is
+// exactly that set. Unknown indexes and hashes get lgrNotFound/txnNotFound.
+func (s *Server) ServeLedgers(ledgers []*xrpl.Ledger) {
+	byIndex := make(map[uint64]*xrpl.Ledger, len(ledgers))
+	byHash := make(map[string]*xrpl.Transaction)
+	var lo, hi uint64
+	for _, lg := range ledgers {
+		byIndex[lg.Index] = lg
+		if lo == 0 || lg.Index < lo {
+			lo = lg.Index
+		}
+		if lg.Index > hi {
+			hi = lg.Index
+		}
+		for _, tx := range lg.Transactions {
+			byHash[tx.Hash] = tx
+		}
+	}
+	s.Handle("ledger", func(req Request) (any, string) {
+		lg, ok := byIndex[req.Uint64("ledger_index")]
+		if !ok {
+			return nil, "lgrNotFound"
+		}
+		// With expand the real server inlines full transaction objects;
+		// without it only hashes come back and the client must tx each one.
+		txs := make([]any, len(lg.Transactions))
+		expand, _ := req["expand"].(bool)
+		for i, tx := range lg.Transactions {
+			if expand {
+				txs[i] = txResult(tx)
+			} else {
+				txs[i] = tx.Hash
+			}
+		}
+		return map[string]any{
+			"validated": true,
+			"ledger": map[string]any{
+				"ledger_hash":  lg.Hash,
+				"ledger_index": fmt.Sprint(lg.Index),
+				"close_time":   lg.CloseTime,
+				"transactions": txs,
+			},
+		}, ""
+	})
+	s.Handle("tx", func(req Request) (any, string) {
+		hash, _ := req["transaction"].(string)
+		tx, ok := byHash[hash]
+		if !ok {
+			return nil, "txnNotFound"
+		}
+		return txResult(tx), ""
+	})
+	s.HandleResult("server_info", map[string]any{
+		"info": map[string]any{
+			"build_version":    "xrpltest",
+			"complete_ledgers": fmt.Sprintf("%d-%d", lo, hi),
+			"validated_ledger": map[string]any{"seq": hi},
+		},
+	})
+}
+
+// txResult renders a transaction the way the tx command would. The raw
+// JSON is used verbatim when the fixture carries it.
+func txResult(tx *xrpl.Transaction) any {
+	if len(tx.Raw) > 0 {
+		return tx.Raw
+	}
+	out := map[string]any{
+		"hash":            tx.Hash,
+		"TransactionType": tx.TransactionType,
+		"Account":         tx.Account,
+		"Sequence":        tx.Sequence,
+		"Flags":           tx.Flags,
+	}
+	for key, amount := range map[string]xrpl.Amount{
+		"TakerGets": tx.TakerGets, "TakerPays": tx.TakerPays, "Amount": tx.Amount,
+	} {
+		if amount.Value != "" {
+			out[key] = amount
+		}
+	}
+	if tx.OfferSequence != 0 {
+		out["OfferSequence"] = tx.OfferSequence
+	}
+	if tx.Meta != nil {
+		out["metaData"] = tx.Meta
+	}
+	return out
+}
+
+// PushLedgerClosed sends a ledgerClosed stream message to every connected
+// subscriber.
+func (s *Server) PushLedgerClosed(lg *xrpl.Ledger) error {
+	return s.Push(map[string]any{
+		"type":         "ledgerClosed",
+		"ledger_index": lg.Index,
+		"ledger_hash":  lg.Hash,
+		"ledger_time":  lg.CloseTime,
+	})
+}
+
+// Push sends an arbitrary stream message to every connected subscriber.
+func (s *Server) Push(msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	conns := append([]*ws.Conn(nil), s.conns...)
+	s.mu.Unlock()
+	for _, conn := range conns {
+		if err := conn.WriteMessage(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Disconnect drops every live WebSocket connection, simulating a rippled
+// restart. The server keeps listening so clients can reconnect.
+func (s *Server) Disconnect() {
+	s.mu.Lock()
+	conns := s.conns
+	s.conns = nil
+	s.mu.Unlock()
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		s.serveWS(w, r)
+		return
+	}
+	var req struct {
+		Method string    `json:"method"`
+		Params []Request `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params := Request{}
+	if len(req.Params) > 0 {
+		params = req.Params[0]
+	}
+	result := s.dispatch(req.Method, params)
+	json.NewEncoder(w).Encode(map[string]any{"result": result})
+}
+
+func (s *Server) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := ws.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.conns = append(s.conns, conn)
+	s.mu.Unlock()
+	for {
+		raw, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req Request
+		if err := json.Unmarshal(raw, &req); err != nil {
+			continue
+		}
+		command, _ := req["command"].(string)
+		resp := map[string]any{"type": "response"}
+		if id, ok := req["id"]; ok {
+			resp["id"] = id
+		}
+		result := s.dispatch(command, req)
+		if errResp, ok := result.(map[string]any); ok && errResp["status"] == "error" {
+			resp["status"] = "error"
+			resp["error"] = errResp["error"]
+		} else {
+			resp["status"] = "success"
+			resp["result"] = result
+		}
+		data, err := json.Marshal(resp)
+		if err != nil {
+			continue
+		}
+		if err := conn.WriteMessage(data); err != nil {
+			return
+		}
+	}
+}
+
+// dispatch runs the scripted handler; error responses come back in the
+// shape the HTTP transport embeds in its result and serveWS lifts to the
+// envelope.
+func (s *Server) dispatch(command string, req Request) any {
+	s.mu.Lock()
+	h, ok := s.handlers[command]
+	s.mu.Unlock()
+	if !ok {
+		return map[string]any{"status": "error", "error": "unknownCmd"}
+	}
+	result, errCode := h(req)
+	if errCode != "" {
+		return map[string]any{"status": "error", "error": errCode}
+	}
+	return result
+}
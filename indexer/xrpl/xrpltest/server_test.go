@@ -0,0 +1,84 @@
+package xrpltest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/2pk03/lucendex-validator-verification/indexer/xrpl"
+)
+
+func scriptedLedgers() []*xrpl.Ledger {
+	return []*xrpl.Ledger{
+		{Index: 100, Hash: "AAAA", CloseTime: 700000000},
+		{Index: 101, Hash: "BBBB", CloseTime: 700000010, Transactions: []*xrpl.Transaction{{
+			Hash: "TX1", TransactionType: "OfferCreate", Account: "rTaker", Sequence: 5,
+			TakerGets: xrpl.Amount{Value: "1"},
+			TakerPays: xrpl.Amount{Currency: "USD", Issuer: "rI", Value: "2"},
+			Meta:      &xrpl.Meta{TransactionResult: "tesSUCCESS"},
+		}}},
+	}
+}
+
+func TestServeLedgersOverHTTP(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.ServeLedgers(scriptedLedgers())
+
+	client := xrpl.NewClient(srv.URL())
+	ctx := context.Background()
+
+	lg, err := client.FetchLedger(ctx, 101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lg.Hash != "BBBB" || len(lg.Transactions) != 1 || lg.Transactions[0].Sequence != 5 {
+		t.Fatalf("fetched ledger = %+v", lg)
+	}
+	if _, err := client.FetchLedger(ctx, 999); err == nil || !strings.Contains(err.Error(), "lgrNotFound") {
+		t.Fatalf("missing ledger error = %v", err)
+	}
+
+	info, err := client.GetServerInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.CompleteLedgers != "100-101" {
+		t.Fatalf("complete_ledgers = %q", info.CompleteLedgers)
+	}
+}
+
+func TestSubscribePushAndDisconnect(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	ledgers := scriptedLedgers()
+	srv.ServeLedgers(ledgers)
+
+	sub, err := xrpl.DialSubscriber(srv.WSURL(), []string{"ledger"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	if err := srv.PushLedgerClosed(ledgers[0]); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := sub.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.LedgerClosed == nil || msg.LedgerClosed.Index != 100 {
+		t.Fatalf("pushed message = %+v", msg)
+	}
+
+	srv.HandleError("ledger", "slowDown")
+	if _, err := sub.Call(context.Background(), "ledger", map[string]any{"ledger_index": 100}); err == nil ||
+		!strings.Contains(err.Error(), "slowDown") {
+		t.Fatalf("injected error = %v", err)
+	}
+
+	srv.Disconnect()
+	if _, err := sub.Next(); err == nil {
+		t.Fatal("Next survived a server disconnect")
+	}
+}